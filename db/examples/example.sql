@@ -69,4 +69,4 @@ VALUES (1, 1, "Place ice cream in glass."),
 
 INSERT INTO recipe_locations (recipe_id, cookbook_id, section_id)
 VALUES (1, 2, NULL),
-       (2, NULL, 1);
+       (2, 1, 1);
@@ -0,0 +1,9 @@
+package db
+
+import "embed"
+
+// Migrations holds the versioned SQL scripts so the binary can migrate the
+// database without the files being present on disk.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
@@ -0,0 +1,14 @@
+-- A recipe is filed in a cookbook at most once, so moving it to another
+-- section can update its row in place. Duplicates saved before this key
+-- existed are dropped, keeping the latest row for each recipe and cookbook.
+
+DELETE rl
+FROM recipe_locations AS rl
+JOIN recipe_locations AS newer
+  ON newer.recipe_id = rl.recipe_id
+ AND newer.cookbook_id = rl.cookbook_id
+ AND newer.id > rl.id;
+
+ALTER TABLE recipe_locations
+  ADD CONSTRAINT UQ_recipe_locations_recipe_cookbook
+    UNIQUE (recipe_id, cookbook_id);
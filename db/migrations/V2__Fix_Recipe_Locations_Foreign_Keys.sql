@@ -0,0 +1,43 @@
+-- V1 pointed recipe_locations.cookbook_id and recipe_locations.section_id at
+-- recipes (id). Rebuild the table with the correct references and carry over
+-- every row that still points at a real cookbook or section.
+
+CREATE TABLE recipe_locations_fixed
+(
+  id          INT NOT NULL PRIMARY KEY AUTO_INCREMENT,
+  recipe_id   INT NOT NULL,
+  cookbook_id INT,
+  section_id  INT,
+
+  CONSTRAINT FK_recipe_locations_recipe
+    FOREIGN KEY (recipe_id)
+      REFERENCES recipes (id)
+      ON DELETE CASCADE,
+  CONSTRAINT FK_recipe_locations_cookbook
+    FOREIGN KEY (cookbook_id)
+      REFERENCES cookbooks (id)
+      ON DELETE CASCADE,
+  CONSTRAINT FK_recipe_locations_section
+    FOREIGN KEY (section_id)
+      REFERENCES sections (id)
+      ON DELETE CASCADE,
+  CONSTRAINT CK_recipe_locations_location
+    CHECK (cookbook_id IS NOT NULL OR section_id IS NOT NULL)
+) ENGINE = INNODB;
+
+-- A section always belongs to a cookbook, so rows that only recorded a
+-- section are filed under that section's cookbook as well.
+INSERT INTO recipe_locations_fixed (id, recipe_id, cookbook_id, section_id)
+SELECT rl.id,
+       rl.recipe_id,
+       COALESCE(s.cookbook_id, c.id),
+       s.id
+FROM recipe_locations AS rl
+LEFT JOIN cookbooks AS c ON c.id = rl.cookbook_id
+LEFT JOIN sections AS s ON s.id = rl.section_id
+WHERE c.id IS NOT NULL
+   OR s.id IS NOT NULL;
+
+DROP TABLE recipe_locations;
+
+RENAME TABLE recipe_locations_fixed TO recipe_locations;
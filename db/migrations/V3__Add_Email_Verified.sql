@@ -0,0 +1,7 @@
+-- Accounts created before email verification existed are treated as verified
+-- so turning on REQUIRE_EMAIL_VERIFICATION does not lock them out.
+
+ALTER TABLE users
+  ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE users SET email_verified = TRUE;
@@ -0,0 +1,26 @@
+-- secret is encrypted by the application. last_used_step is the most recent
+-- TOTP time step accepted so a code cannot be used twice.
+
+CREATE TABLE user_totp
+(
+  user_id        INT     NOT NULL PRIMARY KEY,
+  secret         TEXT    NOT NULL,
+  enabled        BOOLEAN NOT NULL DEFAULT FALSE,
+  last_used_step BIGINT  NOT NULL DEFAULT 0,
+
+  FOREIGN KEY (user_id)
+    REFERENCES users (id)
+    ON DELETE CASCADE
+) ENGINE = INNODB;
+
+CREATE TABLE user_recovery_codes
+(
+  id        INT      NOT NULL PRIMARY KEY AUTO_INCREMENT,
+  user_id   INT      NOT NULL,
+  code_hash CHAR(64) NOT NULL,
+
+  UNIQUE (user_id, code_hash),
+  FOREIGN KEY (user_id)
+    REFERENCES users (id)
+    ON DELETE CASCADE
+) ENGINE = INNODB;
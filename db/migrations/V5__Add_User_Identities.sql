@@ -0,0 +1,15 @@
+-- Links an account at an external identity provider to a local user. provider
+-- is the issuer URL and subject is the provider's stable ID for the account.
+
+CREATE TABLE user_identities
+(
+  id       INT          NOT NULL PRIMARY KEY AUTO_INCREMENT,
+  user_id  INT          NOT NULL,
+  provider VARCHAR(255) NOT NULL,
+  subject  VARCHAR(255) NOT NULL,
+
+  UNIQUE (provider, subject),
+  FOREIGN KEY (user_id)
+    REFERENCES users (id)
+    ON DELETE CASCADE
+) ENGINE = INNODB;
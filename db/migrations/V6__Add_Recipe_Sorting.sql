@@ -0,0 +1,12 @@
+-- total_minutes is total_time parsed by the application so recipes can be
+-- sorted by how long they take. It stays NULL for recipes saved before this
+-- migration, or whose total time could not be parsed, until they are updated.
+-- Existing recipes get the time of the migration as their created_at.
+
+ALTER TABLE recipes
+  ADD COLUMN created_at    TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  ADD COLUMN total_minutes INT;
+
+CREATE INDEX recipes_creator_name ON recipes (creator, name(191), id);
+CREATE INDEX recipes_creator_created_at ON recipes (creator, created_at, id);
+CREATE INDEX recipes_creator_total_minutes ON recipes (creator, total_minutes, id);
@@ -0,0 +1,7 @@
+-- Full text indexes for recipe search. Ingredient names are shared between
+-- recipes, so they are indexed once and joined to recipes through
+-- recipe_ingredients.
+
+CREATE FULLTEXT INDEX recipes_search ON recipes (name, description);
+CREATE FULLTEXT INDEX recipe_steps_search ON recipe_steps (instructions);
+CREATE FULLTEXT INDEX ingredients_search ON ingredients (name);
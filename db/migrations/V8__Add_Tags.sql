@@ -0,0 +1,31 @@
+-- Tags belong to a user and are shared between that user's recipes. Names are
+-- stored normalized, lower case with single spaces, so the unique key stops a
+-- user having the same tag twice.
+
+CREATE TABLE tags
+(
+  id      INT         NOT NULL PRIMARY KEY AUTO_INCREMENT,
+  user_id INT         NOT NULL,
+  name    VARCHAR(50) NOT NULL,
+
+  UNIQUE (user_id, name),
+  FOREIGN KEY (user_id)
+    REFERENCES users (id)
+    ON DELETE CASCADE
+) ENGINE = INNODB;
+
+CREATE TABLE recipe_tags
+(
+  recipe_id INT NOT NULL,
+  tag_id    INT NOT NULL,
+
+  PRIMARY KEY (recipe_id, tag_id),
+  INDEX recipe_tags_tag (tag_id, recipe_id),
+
+  FOREIGN KEY (recipe_id)
+    REFERENCES recipes (id)
+    ON DELETE CASCADE,
+  FOREIGN KEY (tag_id)
+    REFERENCES tags (id)
+    ON DELETE CASCADE
+) ENGINE = INNODB;
@@ -0,0 +1,8 @@
+-- Ingredients are looked up by name when recipes are saved. The column's
+-- collation already ignores case, so with the spacing collapsed here, as new
+-- names are saved, the lookup can compare the column directly and use this
+-- index.
+
+UPDATE ingredients SET name = TRIM(REGEXP_REPLACE(name, '[[:space:]]+', ' '));
+
+CREATE INDEX ingredients_name ON ingredients (name);
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net"
 	"os"
 	"os/signal"
 	"strconv"
 	"strings"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"cloud.google.com/go/cloudsqlconn/mysql/mysql"
@@ -15,20 +21,55 @@ import (
 	"github.com/go-redis/redis"
 
 	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/cookbooks"
 	"github.com/iplay88keys/my-recipe-library/pkg/api/recipes"
 	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/wellknown"
 	"github.com/iplay88keys/my-recipe-library/pkg/config"
+	"github.com/iplay88keys/my-recipe-library/pkg/logging"
+	"github.com/iplay88keys/my-recipe-library/pkg/mailer"
+	"github.com/iplay88keys/my-recipe-library/pkg/migrations"
+	"github.com/iplay88keys/my-recipe-library/pkg/oidc"
+	"github.com/iplay88keys/my-recipe-library/pkg/ratelimit"
 	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+	"github.com/iplay88keys/my-recipe-library/pkg/search"
 	"github.com/iplay88keys/my-recipe-library/pkg/services"
 	"github.com/iplay88keys/my-recipe-library/pkg/token"
+	"github.com/iplay88keys/my-recipe-library/pkg/totp"
 
 	_ "github.com/go-sql-driver/mysql"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrations(os.Args[2:]); err != nil {
+			fmt.Printf("Migration failed: %s\n", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "unlock" {
+		if err := runUnlock(os.Args[2:]); err != nil {
+			fmt.Printf("Unlock failed: %s\n", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
 	cfg := config.Config{
-		Port:   "8080",
-		Static: "ui/build",
+		Port:             "8080",
+		Static:           "ui/build",
+		TokenAlgorithm:   token.AlgorithmHS256,
+		MailFrom:         "no-reply@localhost",
+		PasswordResetURL: "http://localhost:8080/reset-password",
+		VerifyEmailURL:   "http://localhost:8080/verify-email",
+		OIDCRedirectURL:  "http://localhost:8080/oidc/callback",
+		RateLimitBackend: "redis",
+		SearchBackend:    "mysql",
+		LogFormat:        logging.FormatText,
+		LogLevel:         "info",
+		RequestTimeout:   api.DefaultRequestTimeout,
 	}
 
 	err := envstruct.Load(&cfg)
@@ -36,12 +77,25 @@ func main() {
 		panic(err)
 	}
 
+	logger, err := logging.New(os.Stdout, cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		panic(err)
+	}
+	slog.SetDefault(logger)
+
 	db, disconnectMySQL, err := connectToMySQL(cfg.MySQLCreds)
 	if err != nil {
 		panic(err)
 	}
 	defer disconnectMySQL()
 
+	if cfg.MigrateOnStart {
+		err = migrateUp(db)
+		if err != nil {
+			panic(err)
+		}
+	}
+
 	redisClient, err := connectToRedis(cfg.RedisURL)
 	if err != nil {
 		panic(err)
@@ -52,34 +106,364 @@ func main() {
 	recipesRepo := repositories.NewRecipesRepository(db)
 	ingredientsRepo := repositories.NewIngredientsRepository(db)
 	stepsRepo := repositories.NewStepsRepository(db)
+	tagsRepo := repositories.NewTagsRepository(db)
+	cookbooksRepo := repositories.NewCookbooksRepository(db)
 	usersRepo := repositories.NewUsersRepository(db)
 	redisRepo := repositories.NewRedisRepository(redisClient)
-	tokenService := token.NewService(cfg.AccessSecret, cfg.RefreshSecret)
+	twoFactorRepo := repositories.NewTwoFactorRepository(db)
+	identitiesRepo := repositories.NewIdentitiesRepository(db)
+
+	tokenService, err := newTokenService(cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	mail, err := newMailer(cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	totpCipher, err := newTOTPCipher(cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	searchIndex, err := newSearchIndex(cfg, db)
+	if err != nil {
+		panic(err)
+	}
 
 	// Create services
-	recipeService := services.NewRecipeService(recipesRepo, ingredientsRepo, stepsRepo, db)
-	userService := services.NewUserService(usersRepo, redisRepo, tokenService)
+	recipeService := services.NewRecipeService(recipesRepo, ingredientsRepo, stepsRepo, tagsRepo, searchIndex, db)
+	verificationService := services.NewVerificationService(usersRepo, redisRepo, tokenService, mail, cfg.VerifyEmailURL, cfg.RequireEmailVerification)
+	twoFactorService := services.NewTwoFactorService(twoFactorRepo, redisRepo, usersRepo, totpCipher)
+	userService := services.NewUserService(usersRepo, redisRepo, tokenService, verificationService, twoFactorService)
+	cookbookService := services.NewCookbookService(cookbooksRepo, recipesRepo)
+	passwordService := services.NewPasswordService(usersRepo, redisRepo, mail, cfg.PasswordResetURL)
+	loginThrottleService := services.NewLoginThrottleService(redisRepo, usersRepo)
+
+	endpoints := []*api.Endpoint{
+		recipes.CreateRecipe(recipeService),
+		recipes.ListRecipes(recipeService),
+		recipes.SearchRecipes(recipeService),
+		recipes.MatchPantry(recipeService),
+		recipes.GetRecipe(recipeService),
+		recipes.UpdateRecipe(recipeService),
+		recipes.DeleteRecipe(recipeService),
+		recipes.ListTags(recipeService),
+		cookbooks.ListCookbooks(cookbookService),
+		cookbooks.CreateCookbook(cookbookService),
+		cookbooks.RenameCookbook(cookbookService),
+		cookbooks.DeleteCookbook(cookbookService),
+		cookbooks.CreateSection(cookbookService),
+		cookbooks.RenameSection(cookbookService),
+		cookbooks.DeleteSection(cookbookService),
+		cookbooks.AddRecipe(cookbookService),
+		cookbooks.RemoveRecipe(cookbookService),
+		users.Register(userService),
+		users.Login(userService, loginThrottleService),
+		users.LoginTwoFactor(userService, loginThrottleService),
+		users.Logout(userService),
+		users.Refresh(userService),
+		users.ListSessions(userService),
+		users.RevokeSession(userService),
+		users.RevokeAllSessions(userService),
+		users.ChangePassword(passwordService),
+		users.RequestPasswordReset(passwordService),
+		users.ResetPassword(passwordService),
+		users.VerifyEmail(verificationService),
+		users.ResendVerification(verificationService),
+		wellknown.JWKS(tokenService),
+	}
+
+	if totpCipher != nil {
+		endpoints = append(endpoints,
+			users.EnrollTwoFactor(twoFactorService),
+			users.ConfirmTwoFactor(twoFactorService),
+		)
+	}
+
+	if cfg.OIDCIssuer != "" {
+		provider, err := oidc.NewProvider(context.Background(), oidc.Config{
+			Issuer:       cfg.OIDCIssuer,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+		}, nil)
+		if err != nil {
+			panic(err)
+		}
+
+		oidcService := services.NewOIDCService(provider, redisRepo, identitiesRepo, usersRepo)
+		endpoints = append(endpoints,
+			users.OIDCLogin(oidcService),
+			users.OIDCCallback(oidcService, userService),
+			users.OIDCLink(oidcService),
+			users.OIDCLinkCallback(oidcService),
+		)
+	}
+
+	rateLimiter, err := newRateLimiter(cfg, redisClient)
+	if err != nil {
+		panic(err)
+	}
+
+	httpMiddleware := []api.HTTPMiddleware{api.Gzip(api.DefaultGzipMinSize)}
+	if len(cfg.CORSAllowedOrigins) > 0 {
+		httpMiddleware = append(httpMiddleware, api.CORS(api.CORSOptions{
+			AllowedOrigins: cfg.CORSAllowedOrigins,
+			MaxAge:         10 * time.Minute,
+		}))
+	}
+
+	trustedProxies, err := api.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		panic(err)
+	}
 
 	a := api.New(tokenService, redisRepo, &api.Config{
-		Port:      cfg.Port,
-		StaticDir: cfg.Static,
-		Endpoints: []*api.Endpoint{
-			recipes.CreateRecipe(recipeService),
-			recipes.ListRecipes(recipeService),
-			recipes.GetRecipe(recipeService),
-			users.Register(userService),
-			users.Login(userService),
-			users.Logout(userService),
-		},
+		Port:           cfg.Port,
+		StaticDir:      cfg.Static,
+		Endpoints:      endpoints,
+		HTTPMiddleware: httpMiddleware,
+		RateLimiter:    rateLimiter,
+		RequestTimeout: cfg.RequestTimeout,
+		TrustedProxies: trustedProxies,
 	})
 
-	fmt.Printf("Serving at http://localhost:%s\n", cfg.Port)
+	slog.Info("Serving", "url", fmt.Sprintf("http://localhost:%s", cfg.Port))
 	stopApi := a.Start()
 	defer stopApi()
 
 	blockUntilSigterm()
 }
 
+func newTokenService(cfg config.Config) (*token.Service, error) {
+	accessKeys, err := token.LoadKeySet(cfg.TokenAlgorithm, cfg.AccessSecret, cfg.AccessKeyFiles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load access token keys: %s", err.Error())
+	}
+
+	refreshKeys, err := token.LoadKeySet(token.AlgorithmHS256, cfg.RefreshSecret, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load refresh token keys: %s", err.Error())
+	}
+
+	return token.NewService(accessKeys, refreshKeys)
+}
+
+// newMailer sends mail through SMTP when a host is configured. Otherwise mail
+// is printed to stdout so the reset flow can be used locally.
+func newMailer(cfg config.Config) (mailer.Mailer, error) {
+	if cfg.SMTPHost == "" {
+		return mailer.NewLogMailer(cfg.MailFrom, os.Stdout), nil
+	}
+
+	return mailer.NewSMTPMailer(mailer.SMTPConfig{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.MailFrom,
+	})
+}
+
+// newRateLimiter keeps rate limits in Redis so they are shared by every
+// instance, unless the memory backend is asked for.
+func newRateLimiter(cfg config.Config, redisClient redis.Cmdable) (ratelimit.Limiter, error) {
+	switch cfg.RateLimitBackend {
+	case "redis":
+		return ratelimit.NewRedisLimiter(redisClient), nil
+	case "memory":
+		return ratelimit.NewMemoryLimiter(), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend '%s', expected one of: redis, memory", cfg.RateLimitBackend)
+	}
+}
+
+// newSearchIndex searches with MySQL's full text indexes, unless the memory
+// backend is asked for, in which case every recipe is loaded into an index in
+// the process. The memory backend is only for running a single instance, since
+// an instance never sees recipes saved through the others.
+func newSearchIndex(cfg config.Config, db *sql.DB) (search.Index, error) {
+	switch cfg.SearchBackend {
+	case "mysql":
+		return search.NewMySQLIndex(db), nil
+	case "memory":
+		slog.Warn("Using the memory search backend, which only supports running a single instance")
+
+		docs, err := search.NewMySQLIndex(db).Documents(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		index := search.NewMemoryIndex()
+		for _, doc := range docs {
+			if err := index.Put(context.Background(), doc); err != nil {
+				return nil, err
+			}
+		}
+
+		return index, nil
+	default:
+		return nil, fmt.Errorf("unknown search backend '%s', expected one of: mysql, memory", cfg.SearchBackend)
+	}
+}
+
+// newTOTPCipher returns nil when no TOTP encryption key is configured, which
+// turns off setting up two factor authentication.
+func newTOTPCipher(cfg config.Config) (*totp.Cipher, error) {
+	if cfg.TOTPEncryptionKey == "" {
+		return nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(cfg.TOTPEncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the TOTP encryption key: %s", err.Error())
+	}
+
+	return totp.NewCipher(key)
+}
+
+func runMigrations(args []string) error {
+	mode := "up"
+	if len(args) > 0 {
+		mode = args[0]
+	}
+
+	if mode != "up" && mode != "status" && mode != "validate" {
+		return fmt.Errorf("unknown migrate mode '%s', expected one of: up, status, validate", mode)
+	}
+
+	var cfg config.MigrationConfig
+	err := envstruct.Load(&cfg)
+	if err != nil {
+		return err
+	}
+
+	db, disconnectMySQL, err := connectToMySQL(cfg.MySQLCreds)
+	if err != nil {
+		return err
+	}
+	defer disconnectMySQL()
+
+	switch mode {
+	case "up":
+		err = migrateUp(db)
+		if err != nil {
+			return err
+		}
+	case "status":
+		migrator, err := migrations.NewMigrator(db, migrations.Embedded())
+		if err != nil {
+			return err
+		}
+
+		statuses, err := migrator.Status(context.Background())
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tDESCRIPTION\tSTATE\tINSTALLED ON")
+		for _, status := range statuses {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", status.Version, status.Description, status.State, status.InstalledOn)
+		}
+		err = w.Flush()
+		if err != nil {
+			return err
+		}
+	case "validate":
+		migrator, err := migrations.NewMigrator(db, migrations.Embedded())
+		if err != nil {
+			return err
+		}
+
+		err = migrator.Validate(context.Background())
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("Migrations are valid")
+	}
+
+	return nil
+}
+
+// runUnlock lifts the lockout on an account, given by username or email, or on
+// an IP address after too many failed logins.
+func runUnlock(args []string) error {
+	if len(args) != 1 {
+		return errors.New("expected a username, email or IP address to unlock")
+	}
+
+	var cfg config.UnlockConfig
+	err := envstruct.Load(&cfg)
+	if err != nil {
+		return err
+	}
+
+	db, disconnectMySQL, err := connectToMySQL(cfg.MySQLCreds)
+	if err != nil {
+		return err
+	}
+	defer disconnectMySQL()
+
+	redisClient, err := connectToRedis(cfg.RedisURL)
+	if err != nil {
+		return err
+	}
+	defer disconnectFromRedis(redisClient)
+
+	throttle := services.NewLoginThrottleService(
+		repositories.NewRedisRepository(redisClient),
+		repositories.NewUsersRepository(db),
+	)
+
+	if net.ParseIP(args[0]) != nil {
+		err = throttle.UnlockIP(context.Background(), args[0])
+	} else {
+		err = throttle.Unlock(context.Background(), args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Unlocked %s\n", args[0])
+	return nil
+}
+
+func migrateUp(db *sql.DB) error {
+	migrator, err := migrations.NewMigrator(db, migrations.Embedded())
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up(context.Background())
+	for _, migration := range applied {
+		fmt.Printf("Applied migration %s: %s\n", migration.Version, migration.Description)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(applied) == 0 {
+		fmt.Println("Database is up to date")
+	}
+
+	backfill := services.NewTotalMinutesService(repositories.NewRecipesRepository(db))
+	backfilled, err := backfill.Backfill(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if backfilled > 0 {
+		fmt.Printf("Backfilled total minutes for %d recipes\n", backfilled)
+	}
+
+	return nil
+}
+
 func connectToMySQL(config config.MySQLCreds) (db *sql.DB, disconnect func(), err error) {
 	if config.URL != "" {
 		var unquotedURL string
@@ -154,7 +538,7 @@ func disconnectFromMySQL(db *sql.DB) {
 
 		stats = db.Stats()
 
-		fmt.Printf("Waiting on open mySQL connections: %d in use\n", stats.InUse)
+		slog.Info("Waiting on open mySQL connections", "in_use", stats.InUse)
 
 		maxCount += 1
 		time.Sleep(100 * time.Millisecond)
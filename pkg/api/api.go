@@ -1,29 +1,56 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+	"log/slog"
 	"net"
 	"net/http"
-	"strconv"
+	"net/netip"
 	"time"
 
+	"github.com/iplay88keys/my-recipe-library/pkg/ratelimit"
 	"github.com/iplay88keys/my-recipe-library/pkg/token"
 )
 
+// DefaultRequestTimeout leaves time to write the response before the
+// server's WriteTimeout.
+const DefaultRequestTimeout = 10 * time.Second
+
 type TokenValidator interface {
 	ValidateToken(r *http.Request) (*token.AccessDetails, error)
 }
 
 type AccessDetailsRetriever interface {
-	RetrieveTokenDetails(details *token.AccessDetails) (int64, error)
+	RetrieveTokenDetails(ctx context.Context, details *token.AccessDetails) (int64, error)
 }
 
 type Config struct {
 	Port      string
 	StaticDir string
 	Endpoints []*Endpoint
+
+	// Middleware wraps every endpoint, in order, inside the request ID,
+	// request logging, panic recovery and request deadline.
+	Middleware []Middleware
+
+	// HTTPMiddleware wraps the whole server, in order, before requests are
+	// routed to an endpoint.
+	HTTPMiddleware []HTTPMiddleware
+
+	// RateLimiter enforces the endpoints' rate limits. If it is not set
+	// limits are kept in memory.
+	RateLimiter ratelimit.Limiter
+
+	// RequestTimeout is the deadline for endpoints without their own
+	// Timeout. If it is not set DefaultRequestTimeout is used.
+	RequestTimeout time.Duration
+
+	// TrustedProxies are the reverse proxies in front of the API, whose
+	// X-Forwarded-For headers are used to find the client's IP.
+	TrustedProxies []netip.Prefix
 }
 
 type API struct {
@@ -45,14 +72,24 @@ func New(tokenValidator TokenValidator, accessDetailsRetriever AccessDetailsRetr
 		},
 	}
 
-	fmt.Println("Registering endpoints:")
+	if config.RateLimiter == nil {
+		config.RateLimiter = ratelimit.NewMemoryLimiter()
+	}
+
+	if config.RequestTimeout == 0 {
+		config.RequestTimeout = DefaultRequestTimeout
+	}
 
 	mux := http.NewServeMux()
 
 	// Register API endpoints
 	for _, endpoint := range config.Endpoints {
 		pattern := fmt.Sprintf("%s /api/v1/%s", endpoint.Method, endpoint.Path)
-		fmt.Printf("%s %s\n", endpoint.Method, pattern)
+		if endpoint.Unversioned {
+			pattern = fmt.Sprintf("%s /%s", endpoint.Method, endpoint.Path)
+		}
+
+		slog.Debug("Registering endpoint", "pattern", pattern)
 		mux.Handle(pattern, server.createHandler(endpoint))
 	}
 
@@ -67,80 +104,103 @@ func New(tokenValidator TokenValidator, accessDetailsRetriever AccessDetailsRetr
 
 	mux.Handle("/", spa)
 
-	server.Server.Handler = mux
+	server.Server.Handler = ChainHTTP(mux, config.HTTPMiddleware...)
 
 	return server
 }
 
 func (a *API) Start() (shutdown func()) {
-	go a.Server.ListenAndServe()
+	listener, err := net.Listen("tcp", a.Server.Addr)
+	if err != nil {
+		log.Fatalf("Unable to listen on %s: %s", a.Server.Addr, err)
+	}
+
+	go a.Server.Serve(listener)
 
 	return func() {
 		a.Server.Close()
 	}
 }
 
+// createHandler wraps an endpoint in the global middleware, then the
+// middleware for its Auth and RateLimit settings, then its own middleware.
 func (a *API) createHandler(e *Endpoint) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		resp := &Response{}
-		valid := true
-		userID := int64(-1)
+	timeout := e.Timeout
+	if timeout == 0 {
+		timeout = a.Config.RequestTimeout
+	}
 
-		req := &Request{
-			Req: r,
-		}
+	middleware := append([]Middleware{RequestID(), Logging(), Recovery(), Timeout(timeout)}, a.Config.Middleware...)
 
-		startTime := time.Now()
+	if e.Auth {
+		middleware = append(middleware, Authenticate(a.tokenValidator, a.accessDetailsRetriever))
+	}
 
-		if e.Auth {
-			userID, valid = a.ValidateUserToken(r)
-		}
+	if e.RateLimit != nil {
+		middleware = append(middleware, RateLimited(a.Config.RateLimiter, fmt.Sprintf("%s %s", e.Method, e.Path), e.RateLimit))
+	}
 
-		if valid {
-			req.UserID = userID
+	handle := Chain(e.Handle, append(middleware, e.Middleware...)...)
 
-			resp = e.Handle(req)
-		} else {
-			resp.StatusCode = http.StatusUnauthorized
-		}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := handle(&Request{
+			Req:            r,
+			UserID:         -1,
+			TrustedProxies: a.Config.TrustedProxies,
+		})
 
-		writeResponse(w, resp)
-		logRequest(startTime, time.Now(), req, resp)
+		writeResponse(r.Context(), w, resp)
 	})
 }
 
-func writeResponse(w http.ResponseWriter, resp *Response) {
+func writeResponse(ctx context.Context, w http.ResponseWriter, resp *Response) {
 	var body []byte
 	status := resp.StatusCode
 
-	if resp.Body != nil {
+	for key, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+
+	respBody := resp.Body
+	if respBody == nil && status >= http.StatusBadRequest {
+		respBody = errorForStatus(status)
+	}
+
+	if respBody != nil {
 		var err error
-		body, err = json.Marshal(resp.Body)
+		body, err = json.Marshal(respBody)
 		if err != nil {
-			fmt.Printf("Failed to marshal response body: %s\n", err.Error())
+			slog.ErrorContext(ctx, "Failed to marshal response body", "error", err)
 			status = http.StatusInternalServerError
-		} else {
-			w.Header().Set("Content-type", "application/json")
+			body, _ = json.Marshal(InternalError())
 		}
+
+		w.Header().Set("Content-type", "application/json")
 	}
 
 	w.WriteHeader(status)
 	_, err := w.Write(body)
 	if err != nil {
-		fmt.Printf("Error writing body: %s", err.Error())
+		slog.ErrorContext(ctx, "Error writing body", "error", err)
 	}
 }
 
 func (a *API) ValidateUserToken(r *http.Request) (int64, bool) {
-	details, err := a.tokenValidator.ValidateToken(r)
+	return validateUserToken(a.tokenValidator, a.accessDetailsRetriever, r)
+}
+
+func validateUserToken(tokenValidator TokenValidator, accessDetailsRetriever AccessDetailsRetriever, r *http.Request) (int64, bool) {
+	details, err := tokenValidator.ValidateToken(r)
 	if err != nil {
-		fmt.Printf("Failed to validate token: %s\n", err.Error())
+		slog.DebugContext(r.Context(), "Failed to validate token", "error", err)
 		return -1, false
 	}
 
-	userID, err := a.accessDetailsRetriever.RetrieveTokenDetails(details)
+	userID, err := accessDetailsRetriever.RetrieveTokenDetails(r.Context(), details)
 	if err != nil {
-		fmt.Printf("Failed to retrieve token details from redis: %s\n", err.Error())
+		slog.ErrorContext(r.Context(), "Failed to retrieve token details from redis", "error", err)
 		return -1, false
 	}
 
@@ -148,27 +208,18 @@ func (a *API) ValidateUserToken(r *http.Request) (int64, bool) {
 }
 
 func logRequest(startTime, endTime time.Time, req *Request, resp *Response) {
-	start := startTime.Format(time.RFC3339)
-	end := endTime.Format(time.RFC3339)
-
-	latency := endTime.Sub(startTime)
-
-	var userID string
-	if req.UserID == int64(-1) {
-		userID = "<none>"
-	} else {
-		userID = strconv.FormatInt(req.UserID, 10)
-	}
-
-	fmt.Printf("[API] %s [path=%s] [userID=%s] [start=%s] [end=%s] [latency=%s] [status=%d]\n",
-		req.Req.Method,
-		req.Req.URL.Path,
-		userID,
-		start,
-		end,
-		latency,
-		resp.StatusCode,
-	)
+	attrs := []any{
+		"method", req.Req.Method,
+		"path", req.Req.URL.Path,
+		"status", resp.StatusCode,
+		"latency", endTime.Sub(startTime),
+	}
+
+	if req.UserID != -1 {
+		attrs = append(attrs, "user_id", req.UserID)
+	}
+
+	slog.InfoContext(req.Req.Context(), "Request", attrs...)
 }
 
 func notFoundHandler(w http.ResponseWriter, r *http.Request) {
@@ -184,5 +235,5 @@ func notFoundHandler(w http.ResponseWriter, r *http.Request) {
 		resp,
 	)
 
-	writeResponse(w, resp)
+	writeResponse(r.Context(), w, resp)
 }
@@ -1,6 +1,7 @@
 package api_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -20,6 +21,7 @@ var _ = Describe("API", func() {
 	var (
 		server *api.API
 		port   string
+		calls  []string
 	)
 
 	BeforeEach(func() {
@@ -39,16 +41,30 @@ var _ = Describe("API", func() {
 			}
 		}
 
-		retrieveTokenDetails := func(details *token.AccessDetails) (int64, error) {
+		retrieveTokenDetails := func(ctx context.Context, details *token.AccessDetails) (int64, error) {
 			return 10, nil
 		}
 
+		calls = nil
+		record := func(name string) api.Middleware {
+			return func(next api.Handler) api.Handler {
+				return func(r *api.Request) *api.Response {
+					calls = append(calls, fmt.Sprintf("%s (user %d)", name, r.UserID))
+					return next(r)
+				}
+			}
+		}
+
 		server = api.New(
 			&mockTokenValidator{validateToken: validateToken},
 			&mockAccessDetailsRetriever{retrieveTokenDetails: retrieveTokenDetails},
 			&api.Config{
-				Port:      port,
-				StaticDir: "fixtures",
+				Port:       port,
+				StaticDir:  "fixtures",
+				Middleware: []api.Middleware{record("global")},
+				HTTPMiddleware: []api.HTTPMiddleware{
+					api.CORS(api.CORSOptions{AllowedOrigins: []string{"https://app.example.com"}}),
+				},
 				Endpoints: []*api.Endpoint{{
 					Path:   "test-unauthenticated-endpoint",
 					Method: http.MethodGet,
@@ -62,6 +78,50 @@ var _ = Describe("API", func() {
 					Handle: func(r *api.Request) *api.Response {
 						return api.NewResponse(http.StatusOK, nil)
 					},
+				}, {
+					Path:       "test-middleware-endpoint",
+					Method:     http.MethodGet,
+					Auth:       true,
+					Middleware: []api.Middleware{record("endpoint")},
+					Handle: func(r *api.Request) *api.Response {
+						return api.NewResponse(http.StatusOK, nil)
+					},
+				}, {
+					Path:   "test-header-endpoint",
+					Method: http.MethodGet,
+					Handle: func(r *api.Request) *api.Response {
+						resp := api.NewResponse(http.StatusTooManyRequests, nil)
+						resp.Header = http.Header{"Retry-After": []string{"30"}}
+						return resp
+					},
+				}, {
+					Path:      "test-rate-limited-endpoint",
+					Method:    http.MethodGet,
+					RateLimit: &api.RateLimit{Requests: 2, Window: time.Minute},
+					Handle: func(r *api.Request) *api.Response {
+						return api.NewResponse(http.StatusOK, nil)
+					},
+				}, {
+					Path:      "test-user-rate-limited-endpoint",
+					Method:    http.MethodGet,
+					Auth:      true,
+					RateLimit: &api.RateLimit{Requests: 1, Window: time.Minute, PerUser: true},
+					Handle: func(r *api.Request) *api.Response {
+						return api.NewResponse(http.StatusOK, nil)
+					},
+				}, {
+					Path:   "test-panic-endpoint",
+					Method: http.MethodGet,
+					Handle: func(r *api.Request) *api.Response {
+						panic("something broke")
+					},
+				}, {
+					Path:        ".well-known/test-unversioned-endpoint",
+					Method:      http.MethodGet,
+					Unversioned: true,
+					Handle: func(r *api.Request) *api.Response {
+						return api.NewResponse(http.StatusOK, map[string]string{"unversioned": "true"})
+					},
 				}},
 			})
 	})
@@ -119,6 +179,125 @@ var _ = Describe("API", func() {
 		Expect(resp.StatusCode).To(Equal(http.StatusOK))
 	})
 
+	It("runs the global middleware, then auth, then the endpoint's middleware", func() {
+		stop := server.Start()
+		defer stop()
+
+		client := &http.Client{
+			Timeout: 15 * time.Second,
+		}
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://localhost:%s/api/v1/test-middleware-endpoint", port), nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		req.Header.Set("Authorization", "bearer token")
+
+		resp, err := client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(calls).To(Equal([]string{"global (user -1)", "endpoint (user 10)"}))
+
+		calls = nil
+		req.Header.Del("Authorization")
+
+		resp, err = client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		Expect(calls).To(Equal([]string{"global (user -1)"}))
+	})
+
+	It("answers CORS preflights before routing and adds CORS headers to responses", func() {
+		stop := server.Start()
+		defer stop()
+
+		client := &http.Client{
+			Timeout: 15 * time.Second,
+		}
+
+		req, err := http.NewRequest(http.MethodOptions, fmt.Sprintf("http://localhost:%s/api/v1/test-authenticated-endpoint", port), nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		req.Header.Set("Access-Control-Request-Headers", "authorization")
+
+		resp, err := client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+		Expect(resp.Header.Get("Access-Control-Allow-Origin")).To(Equal("https://app.example.com"))
+		Expect(resp.Header.Get("Access-Control-Allow-Headers")).To(ContainSubstring("Authorization"))
+		Expect(calls).To(BeEmpty())
+
+		req, err = http.NewRequest(http.MethodGet, fmt.Sprintf("http://localhost:%s/api/v1/test-authenticated-endpoint", port), nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Authorization", "bearer token")
+
+		resp, err = client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Access-Control-Allow-Origin")).To(Equal("https://app.example.com"))
+	})
+
+	It("writes the headers set on the response", func() {
+		stop := server.Start()
+		defer stop()
+
+		client := &http.Client{
+			Timeout: 15 * time.Second,
+		}
+
+		resp, err := client.Get(fmt.Sprintf("http://localhost:%s/api/v1/test-header-endpoint", port))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusTooManyRequests))
+		Expect(resp.Header.Get("Retry-After")).To(Equal("30"))
+	})
+
+	It("returns the request ID with every response", func() {
+		stop := server.Start()
+		defer stop()
+
+		client := &http.Client{
+			Timeout: 15 * time.Second,
+		}
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://localhost:%s/api/v1/test-unauthenticated-endpoint", port), nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		req.Header.Set("X-Request-ID", "some-request-id")
+
+		resp, err := client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("X-Request-ID")).To(Equal("some-request-id"))
+
+		resp, err = client.Get(fmt.Sprintf("http://localhost:%s/api/v1/test-panic-endpoint", port))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Header.Get("X-Request-ID")).ToNot(BeEmpty())
+	})
+
+	It("serves unversioned endpoints from the root", func() {
+		stop := server.Start()
+		defer stop()
+
+		client := &http.Client{
+			Timeout: 15 * time.Second,
+		}
+
+		resp, err := client.Get(fmt.Sprintf("http://localhost:%s/.well-known/test-unversioned-endpoint", port))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(MatchJSON(`{"unversioned": "true"}`))
+
+		resp, err = client.Get(fmt.Sprintf("http://localhost:%s/api/v1/.well-known/test-unversioned-endpoint", port))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
 	It("serves the static files directly", func() {
 		stop := server.Start()
 		defer stop()
@@ -154,6 +333,101 @@ var _ = Describe("API", func() {
 		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
 	})
 
+	It("returns too many requests once a client is over an endpoint's rate limit", func() {
+		stop := server.Start()
+		defer stop()
+
+		client := &http.Client{
+			Timeout: 15 * time.Second,
+		}
+
+		url := fmt.Sprintf("http://localhost:%s/api/v1/test-rate-limited-endpoint", port)
+		for _, remaining := range []string{"1", "0"} {
+			resp, err := client.Get(url)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Header.Get("RateLimit-Limit")).To(Equal("2"))
+			Expect(resp.Header.Get("RateLimit-Remaining")).To(Equal(remaining))
+			Expect(resp.Header.Get("RateLimit-Reset")).ToNot(BeEmpty())
+		}
+
+		resp, err := client.Get(url)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusTooManyRequests))
+		Expect(resp.Header.Get("RateLimit-Remaining")).To(Equal("0"))
+		Expect(resp.Header.Get("Retry-After")).To(Equal(resp.Header.Get("RateLimit-Reset")))
+
+		resp, err = client.Get(fmt.Sprintf("http://localhost:%s/api/v1/test-unauthenticated-endpoint", port))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("RateLimit-Limit")).To(BeEmpty())
+	})
+
+	It("rate limits authenticated users by user", func() {
+		stop := server.Start()
+		defer stop()
+
+		client := &http.Client{
+			Timeout: 15 * time.Second,
+		}
+
+		get := func() int {
+			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://localhost:%s/api/v1/test-user-rate-limited-endpoint", port), nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			req.Header.Set("Authorization", "bearer token")
+
+			resp, err := client.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+
+			return resp.StatusCode
+		}
+
+		Expect(get()).To(Equal(http.StatusOK))
+		Expect(get()).To(Equal(http.StatusTooManyRequests))
+	})
+
+	It("recovers from a panic in an endpoint with an internal error", func() {
+		stop := server.Start()
+		defer stop()
+
+		client := &http.Client{
+			Timeout: 15 * time.Second,
+		}
+
+		resp, err := client.Get(fmt.Sprintf("http://localhost:%s/api/v1/test-panic-endpoint", port))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(MatchJSON(`{
+			"code": "internal_error",
+			"message": "Something went wrong, please try again later"
+		}`))
+
+		resp, err = client.Get(fmt.Sprintf("http://localhost:%s/api/v1/test-unauthenticated-endpoint", port))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	})
+
+	It("writes an error body for errors returned without one", func() {
+		stop := server.Start()
+		defer stop()
+
+		client := &http.Client{
+			Timeout: 15 * time.Second,
+		}
+
+		resp, err := client.Get(fmt.Sprintf("http://localhost:%s/api/v1/test-header-endpoint", port))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusTooManyRequests))
+
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(MatchJSON(`{"code": "too_many_requests", "message": "Too Many Requests"}`))
+	})
+
 	It("returns unauthorized if the user is not authenticated for an endpoint that requires auth", func() {
 		stop := server.Start()
 		defer stop()
@@ -168,6 +442,10 @@ var _ = Describe("API", func() {
 		resp, err := client.Do(req)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(MatchJSON(`{"code": "unauthorized", "message": "Please log in"}`))
 	})
 })
 
@@ -180,9 +458,9 @@ func (m *mockTokenValidator) ValidateToken(r *http.Request) (*token.AccessDetail
 }
 
 type mockAccessDetailsRetriever struct {
-	retrieveTokenDetails func(details *token.AccessDetails) (int64, error)
+	retrieveTokenDetails func(ctx context.Context, details *token.AccessDetails) (int64, error)
 }
 
-func (m *mockAccessDetailsRetriever) RetrieveTokenDetails(details *token.AccessDetails) (int64, error) {
-	return m.retrieveTokenDetails(details)
+func (m *mockAccessDetailsRetriever) RetrieveTokenDetails(ctx context.Context, details *token.AccessDetails) (int64, error) {
+	return m.retrieveTokenDetails(ctx, details)
 }
@@ -0,0 +1,52 @@
+package cookbooks
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+)
+
+type AddRecipeRequest struct {
+	RecipeID  int64  `json:"recipe_id"`
+	SectionID *int64 `json:"section_id,omitempty"`
+}
+
+type RecipeFiler interface {
+	AddRecipe(ctx context.Context, cookbookID, userID, recipeID int64, sectionID *int64) error
+}
+
+func AddRecipe(service RecipeFiler) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "cookbooks/{id}/recipes",
+		Method: http.MethodPost,
+		Auth:   true,
+		Handle: func(r *api.Request) *api.Response {
+			cookbookID, err := pathID(r, "id")
+			if err != nil {
+				slog.WarnContext(r.Req.Context(), "Add recipe to cookbook endpoint invalid id", "error", err)
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			var location AddRecipeRequest
+			if err := r.Decode(&location); err != nil {
+				slog.WarnContext(r.Req.Context(), "Error decoding json body for add recipe to cookbook", "error", err)
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			if location.RecipeID == 0 {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(map[string]string{
+					"recipe_id": "Required",
+				}))
+			}
+
+			err = service.AddRecipe(r.Req.Context(), cookbookID, r.UserID, location.RecipeID, location.SectionID)
+			if err != nil {
+				return errorResponse(r, "adding recipe to cookbook", err)
+			}
+
+			return api.NewResponse(http.StatusOK, nil)
+		},
+	}
+}
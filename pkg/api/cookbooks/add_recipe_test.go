@@ -0,0 +1,132 @@
+package cookbooks_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/cookbooks"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AddRecipe", func() {
+	newRequest := func(id, body string) *api.Request {
+		req := httptest.NewRequest(http.MethodPost, "/cookbooks/"+id+"/recipes", bytes.NewBufferString(body))
+		req.SetPathValue("id", id)
+
+		return &api.Request{
+			Req:    req,
+			UserID: 10,
+		}
+	}
+
+	It("files the recipe into the cookbook section", func() {
+		var (
+			filedCookbookID int64
+			filedUserID     int64
+			filedRecipeID   int64
+			filedSectionID  *int64
+		)
+
+		fakeService := &mockRecipeFiler{
+			addRecipe: func(ctx context.Context, cookbookID, userID, recipeID int64, sectionID *int64) error {
+				filedCookbookID = cookbookID
+				filedUserID = userID
+				filedRecipeID = recipeID
+				filedSectionID = sectionID
+				return nil
+			},
+		}
+
+		resp := cookbooks.AddRecipe(fakeService).Handle(newRequest("1", `{"recipe_id": 3, "section_id": 2}`))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(filedCookbookID).To(BeEquivalentTo(1))
+		Expect(filedUserID).To(BeEquivalentTo(10))
+		Expect(filedRecipeID).To(BeEquivalentTo(3))
+		Expect(*filedSectionID).To(BeEquivalentTo(2))
+	})
+
+	It("files the recipe without a section", func() {
+		var filedSectionID *int64
+
+		fakeService := &mockRecipeFiler{
+			addRecipe: func(ctx context.Context, cookbookID, userID, recipeID int64, sectionID *int64) error {
+				filedSectionID = sectionID
+				return nil
+			},
+		}
+
+		resp := cookbooks.AddRecipe(fakeService).Handle(newRequest("1", `{"recipe_id": 3}`))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(filedSectionID).To(BeNil())
+	})
+
+	It("requires a recipe id", func() {
+		fakeService := &mockRecipeFiler{}
+
+		resp := cookbooks.AddRecipe(fakeService).Handle(newRequest("1", `{}`))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(resp.Body).To(Equal(api.ValidationError(map[string]string{"recipe_id": "Required"})))
+	})
+
+	It("returns not found if the cookbook, section or recipe does not exist", func() {
+		fakeService := &mockRecipeFiler{
+			addRecipe: func(ctx context.Context, cookbookID, userID, recipeID int64, sectionID *int64) error {
+				return services.ErrNotFound
+			},
+		}
+
+		resp := cookbooks.AddRecipe(fakeService).Handle(newRequest("1", `{"recipe_id": 3}`))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("returns forbidden if the cookbook or recipe belongs to another user", func() {
+		fakeService := &mockRecipeFiler{
+			addRecipe: func(ctx context.Context, cookbookID, userID, recipeID int64, sectionID *int64) error {
+				return services.ErrForbidden
+			},
+		}
+
+		resp := cookbooks.AddRecipe(fakeService).Handle(newRequest("1", `{"recipe_id": 3}`))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	})
+
+	It("returns an error if the service call fails", func() {
+		fakeService := &mockRecipeFiler{
+			addRecipe: func(ctx context.Context, cookbookID, userID, recipeID int64, sectionID *int64) error {
+				return errors.New("some error")
+			},
+		}
+
+		resp := cookbooks.AddRecipe(fakeService).Handle(newRequest("1", `{"recipe_id": 3}`))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+
+	It("returns an error if the provided route variable is not a number", func() {
+		fakeService := &mockRecipeFiler{}
+
+		resp := cookbooks.AddRecipe(fakeService).Handle(newRequest("not-a-number", `{"recipe_id": 3}`))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
+
+type mockRecipeFiler struct {
+	addRecipe func(ctx context.Context, cookbookID, userID, recipeID int64, sectionID *int64) error
+}
+
+func (m *mockRecipeFiler) AddRecipe(ctx context.Context, cookbookID, userID, recipeID int64, sectionID *int64) error {
+	return m.addRecipe(ctx, cookbookID, userID, recipeID, sectionID)
+}
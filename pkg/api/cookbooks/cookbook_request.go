@@ -0,0 +1,29 @@
+package cookbooks
+
+import (
+	"fmt"
+	"strings"
+)
+
+const maxNameLength = 75
+
+type NameRequest struct {
+	Name string `json:"name"`
+}
+
+func (n *NameRequest) Validate() map[string]string {
+	errors := make(map[string]string)
+
+	n.Name = strings.TrimSpace(n.Name)
+	if n.Name == "" {
+		errors["name"] = "Required"
+	} else if len([]rune(n.Name)) > maxNameLength {
+		errors["name"] = fmt.Sprintf("Must be %d characters or less", maxNameLength)
+	}
+
+	return errors
+}
+
+type NameResponse struct {
+	ID int64 `json:"id,omitempty"`
+}
@@ -0,0 +1,36 @@
+package cookbooks_test
+
+import (
+	"io"
+	"log"
+	"os"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestCookbooks(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Cookbooks Suite")
+}
+
+var (
+	osStdout *os.File
+	osStderr *os.File
+)
+
+var _ = BeforeSuite(func() {
+	osStdout = os.Stdout
+	osStderr = os.Stderr
+
+	os.Stdout = nil
+	os.Stderr = nil
+	log.SetOutput(io.Discard)
+})
+
+var _ = AfterSuite(func() {
+	os.Stdout = osStdout
+	os.Stderr = osStderr
+	log.SetOutput(os.Stderr)
+})
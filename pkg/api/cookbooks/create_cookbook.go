@@ -0,0 +1,42 @@
+package cookbooks
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+)
+
+type CookbookCreator interface {
+	CreateCookbook(ctx context.Context, userID int64, name string) (int64, error)
+}
+
+func CreateCookbook(service CookbookCreator) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "cookbooks",
+		Method: http.MethodPost,
+		Auth:   true,
+		Handle: func(r *api.Request) *api.Response {
+			var cookbook NameRequest
+			if err := r.Decode(&cookbook); err != nil {
+				slog.WarnContext(r.Req.Context(), "Error decoding json body for create cookbook", "error", err)
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			validationErrors := cookbook.Validate()
+			if len(validationErrors) > 0 {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(validationErrors))
+			}
+
+			cookbookID, err := service.CreateCookbook(r.Req.Context(), r.UserID, cookbook.Name)
+			if err != nil {
+				return errorResponse(r, "creating cookbook", err)
+			}
+
+			return api.NewResponse(http.StatusCreated, &NameResponse{
+				ID: cookbookID,
+			})
+		},
+	}
+}
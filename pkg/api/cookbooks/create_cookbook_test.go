@@ -0,0 +1,121 @@
+package cookbooks_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/cookbooks"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CreateCookbook", func() {
+	It("creates a cookbook and returns its id", func() {
+		var (
+			createdUserID int64
+			createdName   string
+		)
+
+		fakeService := &mockCookbookCreator{
+			createCookbook: func(ctx context.Context, userID int64, name string) (int64, error) {
+				createdUserID = userID
+				createdName = name
+				return 5, nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/cookbooks", bytes.NewBufferString(`{"name": "  Desserts "}`))
+
+		resp := cookbooks.CreateCookbook(fakeService).Handle(&api.Request{
+			Req:    req,
+			UserID: 10,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(createdUserID).To(BeEquivalentTo(10))
+		Expect(createdName).To(Equal("Desserts"))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{"id": 5}`))
+	})
+
+	It("returns validation errors for a missing name", func() {
+		fakeService := &mockCookbookCreator{}
+
+		req := httptest.NewRequest(http.MethodPost, "/cookbooks", bytes.NewBufferString(`{"name": "   "}`))
+
+		resp := cookbooks.CreateCookbook(fakeService).Handle(&api.Request{
+			Req:    req,
+			UserID: 10,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{"code": "bad_request", "message": "The request has invalid fields", "errors": {"name": "Required"}}`))
+	})
+
+	It("returns validation errors for a name that is too long", func() {
+		fakeService := &mockCookbookCreator{}
+
+		req := httptest.NewRequest(http.MethodPost, "/cookbooks", bytes.NewBufferString(`{"name": "`+strings.Repeat("a", 76)+`"}`))
+
+		resp := cookbooks.CreateCookbook(fakeService).Handle(&api.Request{
+			Req:    req,
+			UserID: 10,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{"code": "bad_request", "message": "The request has invalid fields", "errors": {"name": "Must be 75 characters or less"}}`))
+	})
+
+	It("returns an error if the body cannot be decoded", func() {
+		fakeService := &mockCookbookCreator{}
+
+		req := httptest.NewRequest(http.MethodPost, "/cookbooks", bytes.NewBufferString(`not json`))
+
+		resp := cookbooks.CreateCookbook(fakeService).Handle(&api.Request{
+			Req:    req,
+			UserID: 10,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("returns an error if the service call fails", func() {
+		fakeService := &mockCookbookCreator{
+			createCookbook: func(ctx context.Context, userID int64, name string) (int64, error) {
+				return 0, errors.New("some error")
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/cookbooks", bytes.NewBufferString(`{"name": "Desserts"}`))
+
+		resp := cookbooks.CreateCookbook(fakeService).Handle(&api.Request{
+			Req:    req,
+			UserID: 10,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+})
+
+type mockCookbookCreator struct {
+	createCookbook func(ctx context.Context, userID int64, name string) (int64, error)
+}
+
+func (m *mockCookbookCreator) CreateCookbook(ctx context.Context, userID int64, name string) (int64, error) {
+	return m.createCookbook(ctx, userID, name)
+}
@@ -0,0 +1,48 @@
+package cookbooks
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+)
+
+type SectionCreator interface {
+	CreateSection(ctx context.Context, cookbookID, userID int64, name string) (int64, error)
+}
+
+func CreateSection(service SectionCreator) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "cookbooks/{id}/sections",
+		Method: http.MethodPost,
+		Auth:   true,
+		Handle: func(r *api.Request) *api.Response {
+			cookbookID, err := pathID(r, "id")
+			if err != nil {
+				slog.WarnContext(r.Req.Context(), "Create section endpoint invalid id", "error", err)
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			var section NameRequest
+			if err := r.Decode(&section); err != nil {
+				slog.WarnContext(r.Req.Context(), "Error decoding json body for create section", "error", err)
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			validationErrors := section.Validate()
+			if len(validationErrors) > 0 {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(validationErrors))
+			}
+
+			sectionID, err := service.CreateSection(r.Req.Context(), cookbookID, r.UserID, section.Name)
+			if err != nil {
+				return errorResponse(r, "creating section", err)
+			}
+
+			return api.NewResponse(http.StatusCreated, &NameResponse{
+				ID: sectionID,
+			})
+		},
+	}
+}
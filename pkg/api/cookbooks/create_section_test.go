@@ -0,0 +1,102 @@
+package cookbooks_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/cookbooks"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CreateSection", func() {
+	newRequest := func(id, body string) *api.Request {
+		req := httptest.NewRequest(http.MethodPost, "/cookbooks/"+id+"/sections", bytes.NewBufferString(body))
+		req.SetPathValue("id", id)
+
+		return &api.Request{
+			Req:    req,
+			UserID: 10,
+		}
+	}
+
+	It("creates a section in the cookbook and returns its id", func() {
+		var (
+			createdCookbookID int64
+			createdUserID     int64
+			createdName       string
+		)
+
+		fakeService := &mockSectionCreator{
+			createSection: func(ctx context.Context, cookbookID, userID int64, name string) (int64, error) {
+				createdCookbookID = cookbookID
+				createdUserID = userID
+				createdName = name
+				return 7, nil
+			},
+		}
+
+		resp := cookbooks.CreateSection(fakeService).Handle(newRequest("1", `{"name": "Frozen"}`))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(resp.Body).To(Equal(&cookbooks.NameResponse{ID: 7}))
+		Expect(createdCookbookID).To(BeEquivalentTo(1))
+		Expect(createdUserID).To(BeEquivalentTo(10))
+		Expect(createdName).To(Equal("Frozen"))
+	})
+
+	It("returns validation errors", func() {
+		fakeService := &mockSectionCreator{}
+
+		resp := cookbooks.CreateSection(fakeService).Handle(newRequest("1", `{}`))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(resp.Body).To(Equal(api.ValidationError(map[string]string{"name": "Required"})))
+	})
+
+	It("returns forbidden if the cookbook belongs to another user", func() {
+		fakeService := &mockSectionCreator{
+			createSection: func(ctx context.Context, cookbookID, userID int64, name string) (int64, error) {
+				return 0, services.ErrForbidden
+			},
+		}
+
+		resp := cookbooks.CreateSection(fakeService).Handle(newRequest("1", `{"name": "Frozen"}`))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	})
+
+	It("returns an error if the service call fails", func() {
+		fakeService := &mockSectionCreator{
+			createSection: func(ctx context.Context, cookbookID, userID int64, name string) (int64, error) {
+				return 0, errors.New("some error")
+			},
+		}
+
+		resp := cookbooks.CreateSection(fakeService).Handle(newRequest("1", `{"name": "Frozen"}`))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+
+	It("returns an error if the provided route variable is not a number", func() {
+		fakeService := &mockSectionCreator{}
+
+		resp := cookbooks.CreateSection(fakeService).Handle(newRequest("not-a-number", `{"name": "Frozen"}`))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
+
+type mockSectionCreator struct {
+	createSection func(ctx context.Context, cookbookID, userID int64, name string) (int64, error)
+}
+
+func (m *mockSectionCreator) CreateSection(ctx context.Context, cookbookID, userID int64, name string) (int64, error) {
+	return m.createSection(ctx, cookbookID, userID, name)
+}
@@ -0,0 +1,35 @@
+package cookbooks
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+)
+
+type CookbookDeleter interface {
+	DeleteCookbook(ctx context.Context, cookbookID, userID int64) error
+}
+
+func DeleteCookbook(service CookbookDeleter) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "cookbooks/{id}",
+		Method: http.MethodDelete,
+		Auth:   true,
+		Handle: func(r *api.Request) *api.Response {
+			cookbookID, err := pathID(r, "id")
+			if err != nil {
+				slog.WarnContext(r.Req.Context(), "Delete cookbook endpoint invalid id", "error", err)
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			err = service.DeleteCookbook(r.Req.Context(), cookbookID, r.UserID)
+			if err != nil {
+				return errorResponse(r, "deleting cookbook", err)
+			}
+
+			return api.NewResponse(http.StatusNoContent, nil)
+		},
+	}
+}
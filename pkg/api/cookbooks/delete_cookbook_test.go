@@ -0,0 +1,100 @@
+package cookbooks_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/cookbooks"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DeleteCookbook", func() {
+	newRequest := func(id string) *api.Request {
+		req := httptest.NewRequest(http.MethodDelete, "/cookbooks/"+id, nil)
+		req.SetPathValue("id", id)
+
+		return &api.Request{
+			Req:    req,
+			UserID: 10,
+		}
+	}
+
+	It("deletes the cookbook", func() {
+		var (
+			deletedID     int64
+			deletedUserID int64
+		)
+
+		fakeService := &mockCookbookDeleter{
+			deleteCookbook: func(ctx context.Context, cookbookID, userID int64) error {
+				deletedID = cookbookID
+				deletedUserID = userID
+				return nil
+			},
+		}
+
+		resp := cookbooks.DeleteCookbook(fakeService).Handle(newRequest("1"))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+		Expect(deletedID).To(BeEquivalentTo(1))
+		Expect(deletedUserID).To(BeEquivalentTo(10))
+	})
+
+	It("returns not found if the cookbook does not exist", func() {
+		fakeService := &mockCookbookDeleter{
+			deleteCookbook: func(ctx context.Context, cookbookID, userID int64) error {
+				return services.ErrNotFound
+			},
+		}
+
+		resp := cookbooks.DeleteCookbook(fakeService).Handle(newRequest("1"))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("returns forbidden if the cookbook belongs to another user", func() {
+		fakeService := &mockCookbookDeleter{
+			deleteCookbook: func(ctx context.Context, cookbookID, userID int64) error {
+				return services.ErrForbidden
+			},
+		}
+
+		resp := cookbooks.DeleteCookbook(fakeService).Handle(newRequest("1"))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	})
+
+	It("returns an error if the service call fails", func() {
+		fakeService := &mockCookbookDeleter{
+			deleteCookbook: func(ctx context.Context, cookbookID, userID int64) error {
+				return errors.New("some error")
+			},
+		}
+
+		resp := cookbooks.DeleteCookbook(fakeService).Handle(newRequest("1"))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+
+	It("returns an error if the provided route variable is not a number", func() {
+		fakeService := &mockCookbookDeleter{}
+
+		resp := cookbooks.DeleteCookbook(fakeService).Handle(newRequest("not-a-number"))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
+
+type mockCookbookDeleter struct {
+	deleteCookbook func(ctx context.Context, cookbookID, userID int64) error
+}
+
+func (m *mockCookbookDeleter) DeleteCookbook(ctx context.Context, cookbookID, userID int64) error {
+	return m.deleteCookbook(ctx, cookbookID, userID)
+}
@@ -0,0 +1,41 @@
+package cookbooks
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+)
+
+type SectionDeleter interface {
+	DeleteSection(ctx context.Context, cookbookID, sectionID, userID int64) error
+}
+
+func DeleteSection(service SectionDeleter) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "cookbooks/{id}/sections/{section_id}",
+		Method: http.MethodDelete,
+		Auth:   true,
+		Handle: func(r *api.Request) *api.Response {
+			cookbookID, err := pathID(r, "id")
+			if err != nil {
+				slog.WarnContext(r.Req.Context(), "Delete section endpoint invalid id", "error", err)
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			sectionID, err := pathID(r, "section_id")
+			if err != nil {
+				slog.WarnContext(r.Req.Context(), "Delete section endpoint invalid section id", "error", err)
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			err = service.DeleteSection(r.Req.Context(), cookbookID, sectionID, r.UserID)
+			if err != nil {
+				return errorResponse(r, "deleting section", err)
+			}
+
+			return api.NewResponse(http.StatusNoContent, nil)
+		},
+	}
+}
@@ -0,0 +1,106 @@
+package cookbooks_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/cookbooks"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DeleteSection", func() {
+	newRequest := func(id, sectionID string) *api.Request {
+		req := httptest.NewRequest(http.MethodDelete, "/cookbooks/"+id+"/sections/"+sectionID, nil)
+		req.SetPathValue("id", id)
+		req.SetPathValue("section_id", sectionID)
+
+		return &api.Request{
+			Req:    req,
+			UserID: 10,
+		}
+	}
+
+	It("deletes the section", func() {
+		var (
+			deletedCookbookID int64
+			deletedSectionID  int64
+			deletedUserID     int64
+		)
+
+		fakeService := &mockSectionDeleter{
+			deleteSection: func(ctx context.Context, cookbookID, sectionID, userID int64) error {
+				deletedCookbookID = cookbookID
+				deletedSectionID = sectionID
+				deletedUserID = userID
+				return nil
+			},
+		}
+
+		resp := cookbooks.DeleteSection(fakeService).Handle(newRequest("1", "2"))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+		Expect(deletedCookbookID).To(BeEquivalentTo(1))
+		Expect(deletedSectionID).To(BeEquivalentTo(2))
+		Expect(deletedUserID).To(BeEquivalentTo(10))
+	})
+
+	It("returns not found if the section does not exist", func() {
+		fakeService := &mockSectionDeleter{
+			deleteSection: func(ctx context.Context, cookbookID, sectionID, userID int64) error {
+				return services.ErrNotFound
+			},
+		}
+
+		resp := cookbooks.DeleteSection(fakeService).Handle(newRequest("1", "2"))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("returns forbidden if the cookbook belongs to another user", func() {
+		fakeService := &mockSectionDeleter{
+			deleteSection: func(ctx context.Context, cookbookID, sectionID, userID int64) error {
+				return services.ErrForbidden
+			},
+		}
+
+		resp := cookbooks.DeleteSection(fakeService).Handle(newRequest("1", "2"))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	})
+
+	It("returns an error if the service call fails", func() {
+		fakeService := &mockSectionDeleter{
+			deleteSection: func(ctx context.Context, cookbookID, sectionID, userID int64) error {
+				return errors.New("some error")
+			},
+		}
+
+		resp := cookbooks.DeleteSection(fakeService).Handle(newRequest("1", "2"))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+
+	It("returns an error if a route variable is not a number", func() {
+		fakeService := &mockSectionDeleter{}
+
+		resp := cookbooks.DeleteSection(fakeService).Handle(newRequest("not-a-number", "2"))
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		resp = cookbooks.DeleteSection(fakeService).Handle(newRequest("1", "not-a-number"))
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
+
+type mockSectionDeleter struct {
+	deleteSection func(ctx context.Context, cookbookID, sectionID, userID int64) error
+}
+
+func (m *mockSectionDeleter) DeleteSection(ctx context.Context, cookbookID, sectionID, userID int64) error {
+	return m.deleteSection(ctx, cookbookID, sectionID, userID)
+}
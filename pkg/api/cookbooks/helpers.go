@@ -0,0 +1,28 @@
+package cookbooks
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+)
+
+func pathID(r *api.Request, name string) (int64, error) {
+	return strconv.ParseInt(r.Req.PathValue(name), 10, 64)
+}
+
+func errorResponse(r *api.Request, action string, err error) *api.Response {
+	if errors.Is(err, services.ErrNotFound) {
+		return api.NewResponse(http.StatusNotFound, nil)
+	}
+
+	if errors.Is(err, services.ErrForbidden) {
+		return api.NewResponse(http.StatusForbidden, nil)
+	}
+
+	slog.ErrorContext(r.Req.Context(), "Error "+action, "error", err)
+	return api.NewResponse(http.StatusInternalServerError, nil)
+}
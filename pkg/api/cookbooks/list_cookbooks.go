@@ -0,0 +1,65 @@
+package cookbooks
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+)
+
+type CookbookListResponse struct {
+	Cookbooks []*CookbookResponse `json:"cookbooks"`
+}
+
+type CookbookResponse struct {
+	ID       int64              `json:"id"`
+	Name     string             `json:"name"`
+	Sections []*SectionResponse `json:"sections"`
+}
+
+type SectionResponse struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type CookbookLister interface {
+	ListCookbooks(ctx context.Context, userID int64) ([]*services.CookbookDetail, error)
+}
+
+func ListCookbooks(service CookbookLister) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "cookbooks",
+		Method: http.MethodGet,
+		Auth:   true,
+		Handle: func(r *api.Request) *api.Response {
+			details, err := service.ListCookbooks(r.Req.Context(), r.UserID)
+			if err != nil {
+				slog.ErrorContext(r.Req.Context(), "Error listing cookbooks", "error", err)
+				return api.NewResponse(http.StatusInternalServerError, nil)
+			}
+
+			cookbooks := make([]*CookbookResponse, len(details))
+			for i, detail := range details {
+				sections := make([]*SectionResponse, len(detail.Sections))
+				for j, section := range detail.Sections {
+					sections[j] = &SectionResponse{
+						ID:   section.ID,
+						Name: section.Name,
+					}
+				}
+
+				cookbooks[i] = &CookbookResponse{
+					ID:       detail.ID,
+					Name:     detail.Name,
+					Sections: sections,
+				}
+			}
+
+			return api.NewResponse(http.StatusOK, &CookbookListResponse{
+				Cookbooks: cookbooks,
+			})
+		},
+	}
+}
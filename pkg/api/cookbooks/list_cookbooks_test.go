@@ -0,0 +1,106 @@
+package cookbooks_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/cookbooks"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ListCookbooks", func() {
+	It("returns the user's cookbooks with their sections", func() {
+		var listedUserID int64
+		fakeService := &mockCookbookLister{
+			listCookbooks: func(ctx context.Context, userID int64) ([]*services.CookbookDetail, error) {
+				listedUserID = userID
+				return []*services.CookbookDetail{{
+					ID:   1,
+					Name: "Desserts",
+					Sections: []*services.SectionDetail{{
+						ID:   2,
+						Name: "Frozen",
+					}},
+				}, {
+					ID:       3,
+					Name:     "Drinks",
+					Sections: []*services.SectionDetail{},
+				}}, nil
+			},
+		}
+
+		resp := cookbooks.ListCookbooks(fakeService).Handle(&api.Request{
+			Req:    httptest.NewRequest(http.MethodGet, "/cookbooks", nil),
+			UserID: 10,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(listedUserID).To(BeEquivalentTo(10))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "cookbooks": [{
+                "id": 1,
+                "name": "Desserts",
+                "sections": [{
+                    "id": 2,
+                    "name": "Frozen"
+                }]
+            }, {
+                "id": 3,
+                "name": "Drinks",
+                "sections": []
+            }]
+        }`))
+	})
+
+	It("returns an empty list if the user has no cookbooks", func() {
+		fakeService := &mockCookbookLister{
+			listCookbooks: func(ctx context.Context, userID int64) ([]*services.CookbookDetail, error) {
+				return nil, nil
+			},
+		}
+
+		resp := cookbooks.ListCookbooks(fakeService).Handle(&api.Request{
+			Req:    httptest.NewRequest(http.MethodGet, "/cookbooks", nil),
+			UserID: 10,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{"cookbooks": []}`))
+	})
+
+	It("returns an error if the service call fails", func() {
+		fakeService := &mockCookbookLister{
+			listCookbooks: func(ctx context.Context, userID int64) ([]*services.CookbookDetail, error) {
+				return nil, errors.New("some error")
+			},
+		}
+
+		resp := cookbooks.ListCookbooks(fakeService).Handle(&api.Request{
+			Req:    httptest.NewRequest(http.MethodGet, "/cookbooks", nil),
+			UserID: 10,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+})
+
+type mockCookbookLister struct {
+	listCookbooks func(ctx context.Context, userID int64) ([]*services.CookbookDetail, error)
+}
+
+func (m *mockCookbookLister) ListCookbooks(ctx context.Context, userID int64) ([]*services.CookbookDetail, error) {
+	return m.listCookbooks(ctx, userID)
+}
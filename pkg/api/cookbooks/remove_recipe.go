@@ -0,0 +1,41 @@
+package cookbooks
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+)
+
+type RecipeUnfiler interface {
+	RemoveRecipe(ctx context.Context, cookbookID, userID, recipeID int64) error
+}
+
+func RemoveRecipe(service RecipeUnfiler) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "cookbooks/{id}/recipes/{recipe_id}",
+		Method: http.MethodDelete,
+		Auth:   true,
+		Handle: func(r *api.Request) *api.Response {
+			cookbookID, err := pathID(r, "id")
+			if err != nil {
+				slog.WarnContext(r.Req.Context(), "Remove recipe from cookbook endpoint invalid id", "error", err)
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			recipeID, err := pathID(r, "recipe_id")
+			if err != nil {
+				slog.WarnContext(r.Req.Context(), "Remove recipe from cookbook endpoint invalid recipe id", "error", err)
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			err = service.RemoveRecipe(r.Req.Context(), cookbookID, r.UserID, recipeID)
+			if err != nil {
+				return errorResponse(r, "removing recipe from cookbook", err)
+			}
+
+			return api.NewResponse(http.StatusNoContent, nil)
+		},
+	}
+}
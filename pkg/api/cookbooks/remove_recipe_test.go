@@ -0,0 +1,92 @@
+package cookbooks_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/cookbooks"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RemoveRecipe", func() {
+	newRequest := func(id, recipeID string) *api.Request {
+		req := httptest.NewRequest(http.MethodDelete, "/cookbooks/"+id+"/recipes/"+recipeID, nil)
+		req.SetPathValue("id", id)
+		req.SetPathValue("recipe_id", recipeID)
+
+		return &api.Request{
+			Req:    req,
+			UserID: 10,
+		}
+	}
+
+	It("removes the recipe from the cookbook", func() {
+		var (
+			removedCookbookID int64
+			removedUserID     int64
+			removedRecipeID   int64
+		)
+
+		fakeService := &mockRecipeUnfiler{
+			removeRecipe: func(ctx context.Context, cookbookID, userID, recipeID int64) error {
+				removedCookbookID = cookbookID
+				removedUserID = userID
+				removedRecipeID = recipeID
+				return nil
+			},
+		}
+
+		resp := cookbooks.RemoveRecipe(fakeService).Handle(newRequest("1", "3"))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+		Expect(removedCookbookID).To(BeEquivalentTo(1))
+		Expect(removedUserID).To(BeEquivalentTo(10))
+		Expect(removedRecipeID).To(BeEquivalentTo(3))
+	})
+
+	It("returns not found if the recipe is not in the cookbook", func() {
+		fakeService := &mockRecipeUnfiler{
+			removeRecipe: func(ctx context.Context, cookbookID, userID, recipeID int64) error {
+				return services.ErrNotFound
+			},
+		}
+
+		resp := cookbooks.RemoveRecipe(fakeService).Handle(newRequest("1", "3"))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("returns an error if the service call fails", func() {
+		fakeService := &mockRecipeUnfiler{
+			removeRecipe: func(ctx context.Context, cookbookID, userID, recipeID int64) error {
+				return errors.New("some error")
+			},
+		}
+
+		resp := cookbooks.RemoveRecipe(fakeService).Handle(newRequest("1", "3"))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+
+	It("returns an error if a route variable is not a number", func() {
+		fakeService := &mockRecipeUnfiler{}
+
+		resp := cookbooks.RemoveRecipe(fakeService).Handle(newRequest("1", "not-a-number"))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
+
+type mockRecipeUnfiler struct {
+	removeRecipe func(ctx context.Context, cookbookID, userID, recipeID int64) error
+}
+
+func (m *mockRecipeUnfiler) RemoveRecipe(ctx context.Context, cookbookID, userID, recipeID int64) error {
+	return m.removeRecipe(ctx, cookbookID, userID, recipeID)
+}
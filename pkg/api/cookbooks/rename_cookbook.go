@@ -0,0 +1,46 @@
+package cookbooks
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+)
+
+type CookbookRenamer interface {
+	RenameCookbook(ctx context.Context, cookbookID, userID int64, name string) error
+}
+
+func RenameCookbook(service CookbookRenamer) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "cookbooks/{id}",
+		Method: http.MethodPut,
+		Auth:   true,
+		Handle: func(r *api.Request) *api.Response {
+			cookbookID, err := pathID(r, "id")
+			if err != nil {
+				slog.WarnContext(r.Req.Context(), "Rename cookbook endpoint invalid id", "error", err)
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			var cookbook NameRequest
+			if err := r.Decode(&cookbook); err != nil {
+				slog.WarnContext(r.Req.Context(), "Error decoding json body for rename cookbook", "error", err)
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			validationErrors := cookbook.Validate()
+			if len(validationErrors) > 0 {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(validationErrors))
+			}
+
+			err = service.RenameCookbook(r.Req.Context(), cookbookID, r.UserID, cookbook.Name)
+			if err != nil {
+				return errorResponse(r, "renaming cookbook", err)
+			}
+
+			return api.NewResponse(http.StatusOK, nil)
+		},
+	}
+}
@@ -0,0 +1,113 @@
+package cookbooks_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/cookbooks"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RenameCookbook", func() {
+	newRequest := func(id, body string) *api.Request {
+		req := httptest.NewRequest(http.MethodPut, "/cookbooks/"+id, bytes.NewBufferString(body))
+		req.SetPathValue("id", id)
+
+		return &api.Request{
+			Req:    req,
+			UserID: 10,
+		}
+	}
+
+	It("renames the cookbook", func() {
+		var (
+			renamedID     int64
+			renamedUserID int64
+			renamedName   string
+		)
+
+		fakeService := &mockCookbookRenamer{
+			renameCookbook: func(ctx context.Context, cookbookID, userID int64, name string) error {
+				renamedID = cookbookID
+				renamedUserID = userID
+				renamedName = name
+				return nil
+			},
+		}
+
+		resp := cookbooks.RenameCookbook(fakeService).Handle(newRequest("1", `{"name": "Sweets"}`))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(renamedID).To(BeEquivalentTo(1))
+		Expect(renamedUserID).To(BeEquivalentTo(10))
+		Expect(renamedName).To(Equal("Sweets"))
+	})
+
+	It("returns validation errors", func() {
+		fakeService := &mockCookbookRenamer{}
+
+		resp := cookbooks.RenameCookbook(fakeService).Handle(newRequest("1", `{}`))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(resp.Body).To(Equal(api.ValidationError(map[string]string{"name": "Required"})))
+	})
+
+	It("returns not found if the cookbook does not exist", func() {
+		fakeService := &mockCookbookRenamer{
+			renameCookbook: func(ctx context.Context, cookbookID, userID int64, name string) error {
+				return services.ErrNotFound
+			},
+		}
+
+		resp := cookbooks.RenameCookbook(fakeService).Handle(newRequest("1", `{"name": "Sweets"}`))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("returns forbidden if the cookbook belongs to another user", func() {
+		fakeService := &mockCookbookRenamer{
+			renameCookbook: func(ctx context.Context, cookbookID, userID int64, name string) error {
+				return services.ErrForbidden
+			},
+		}
+
+		resp := cookbooks.RenameCookbook(fakeService).Handle(newRequest("1", `{"name": "Sweets"}`))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	})
+
+	It("returns an error if the service call fails", func() {
+		fakeService := &mockCookbookRenamer{
+			renameCookbook: func(ctx context.Context, cookbookID, userID int64, name string) error {
+				return errors.New("some error")
+			},
+		}
+
+		resp := cookbooks.RenameCookbook(fakeService).Handle(newRequest("1", `{"name": "Sweets"}`))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+
+	It("returns an error if the provided route variable is not a number", func() {
+		fakeService := &mockCookbookRenamer{}
+
+		resp := cookbooks.RenameCookbook(fakeService).Handle(newRequest("not-a-number", `{"name": "Sweets"}`))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
+
+type mockCookbookRenamer struct {
+	renameCookbook func(ctx context.Context, cookbookID, userID int64, name string) error
+}
+
+func (m *mockCookbookRenamer) RenameCookbook(ctx context.Context, cookbookID, userID int64, name string) error {
+	return m.renameCookbook(ctx, cookbookID, userID, name)
+}
@@ -0,0 +1,52 @@
+package cookbooks
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+)
+
+type SectionRenamer interface {
+	RenameSection(ctx context.Context, cookbookID, sectionID, userID int64, name string) error
+}
+
+func RenameSection(service SectionRenamer) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "cookbooks/{id}/sections/{section_id}",
+		Method: http.MethodPut,
+		Auth:   true,
+		Handle: func(r *api.Request) *api.Response {
+			cookbookID, err := pathID(r, "id")
+			if err != nil {
+				slog.WarnContext(r.Req.Context(), "Rename section endpoint invalid id", "error", err)
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			sectionID, err := pathID(r, "section_id")
+			if err != nil {
+				slog.WarnContext(r.Req.Context(), "Rename section endpoint invalid section id", "error", err)
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			var section NameRequest
+			if err := r.Decode(&section); err != nil {
+				slog.WarnContext(r.Req.Context(), "Error decoding json body for rename section", "error", err)
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			validationErrors := section.Validate()
+			if len(validationErrors) > 0 {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(validationErrors))
+			}
+
+			err = service.RenameSection(r.Req.Context(), cookbookID, sectionID, r.UserID, section.Name)
+			if err != nil {
+				return errorResponse(r, "renaming section", err)
+			}
+
+			return api.NewResponse(http.StatusOK, nil)
+		},
+	}
+}
@@ -0,0 +1,101 @@
+package cookbooks_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/cookbooks"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RenameSection", func() {
+	newRequest := func(id, sectionID, body string) *api.Request {
+		req := httptest.NewRequest(http.MethodPut, "/cookbooks/"+id+"/sections/"+sectionID, bytes.NewBufferString(body))
+		req.SetPathValue("id", id)
+		req.SetPathValue("section_id", sectionID)
+
+		return &api.Request{
+			Req:    req,
+			UserID: 10,
+		}
+	}
+
+	It("renames the section", func() {
+		var (
+			renamedCookbookID int64
+			renamedSectionID  int64
+			renamedName       string
+		)
+
+		fakeService := &mockSectionRenamer{
+			renameSection: func(ctx context.Context, cookbookID, sectionID, userID int64, name string) error {
+				renamedCookbookID = cookbookID
+				renamedSectionID = sectionID
+				renamedName = name
+				return nil
+			},
+		}
+
+		resp := cookbooks.RenameSection(fakeService).Handle(newRequest("1", "2", `{"name": "Cakes"}`))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(renamedCookbookID).To(BeEquivalentTo(1))
+		Expect(renamedSectionID).To(BeEquivalentTo(2))
+		Expect(renamedName).To(Equal("Cakes"))
+	})
+
+	It("returns validation errors", func() {
+		fakeService := &mockSectionRenamer{}
+
+		resp := cookbooks.RenameSection(fakeService).Handle(newRequest("1", "2", `{}`))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("returns not found if the section is not in the cookbook", func() {
+		fakeService := &mockSectionRenamer{
+			renameSection: func(ctx context.Context, cookbookID, sectionID, userID int64, name string) error {
+				return services.ErrNotFound
+			},
+		}
+
+		resp := cookbooks.RenameSection(fakeService).Handle(newRequest("1", "2", `{"name": "Cakes"}`))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("returns an error if the service call fails", func() {
+		fakeService := &mockSectionRenamer{
+			renameSection: func(ctx context.Context, cookbookID, sectionID, userID int64, name string) error {
+				return errors.New("some error")
+			},
+		}
+
+		resp := cookbooks.RenameSection(fakeService).Handle(newRequest("1", "2", `{"name": "Cakes"}`))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+
+	It("returns an error if the section route variable is not a number", func() {
+		fakeService := &mockSectionRenamer{}
+
+		resp := cookbooks.RenameSection(fakeService).Handle(newRequest("1", "not-a-number", `{"name": "Cakes"}`))
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
+
+type mockSectionRenamer struct {
+	renameSection func(ctx context.Context, cookbookID, sectionID, userID int64, name string) error
+}
+
+func (m *mockSectionRenamer) RenameSection(ctx context.Context, cookbookID, sectionID, userID int64, name string) error {
+	return m.renameSection(ctx, cookbookID, sectionID, userID, name)
+}
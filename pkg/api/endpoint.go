@@ -1,8 +1,39 @@
 package api
 
+import "time"
+
 type Endpoint struct {
 	Path   string
 	Method string
 	Auth   bool
 	Handle func(r *Request) *Response
+
+	// Unversioned endpoints are served from the root instead of under
+	// /api/v1, for paths that other services expect at a fixed location.
+	Unversioned bool
+
+	// RateLimit, if set, limits how often each client can call the endpoint.
+	RateLimit *RateLimit
+
+	// Timeout is how long the endpoint has to respond before its request's
+	// context is cancelled. Zero uses the Config's RequestTimeout.
+	Timeout time.Duration
+
+	// Middleware wraps just this endpoint, inside the global middleware and
+	// the middleware for Auth and RateLimit.
+	Middleware []Middleware
+}
+
+// RateLimit allows a number of requests in a sliding window. Clients are told
+// apart by IP address, or by user when PerUser is set and the request is
+// authenticated.
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+	PerUser  bool
+
+	// FailClosed refuses requests with 503 Service Unavailable when the
+	// limiter cannot be reached. Otherwise they are let through, without
+	// RateLimit headers, so the endpoint stays up.
+	FailClosed bool
 }
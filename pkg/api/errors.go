@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// Error is the body of every error response. Code is a stable name for the
+// error that clients can check, Message is meant for people, and Fields holds
+// a message for each request field that was invalid.
+type Error struct {
+	Status  int               `json:"-"`
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"errors,omitempty"`
+}
+
+func NewError(status int, code, message string) *Error {
+	return &Error{
+		Status:  status,
+		Code:    code,
+		Message: message,
+	}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// WithFields adds field errors to the error.
+func (e *Error) WithFields(fields map[string]string) *Error {
+	e.Fields = fields
+	return e
+}
+
+func BadRequest(message string) *Error {
+	return NewError(http.StatusBadRequest, "bad_request", message)
+}
+
+// ValidationError reports the fields of a request that were invalid.
+func ValidationError(fields map[string]string) *Error {
+	return BadRequest("The request has invalid fields").WithFields(fields)
+}
+
+func Unauthorized(message string) *Error {
+	return NewError(http.StatusUnauthorized, "unauthorized", message)
+}
+
+func Forbidden(message string) *Error {
+	return NewError(http.StatusForbidden, "forbidden", message)
+}
+
+func NotFound(message string) *Error {
+	return NewError(http.StatusNotFound, "not_found", message)
+}
+
+func Conflict(message string) *Error {
+	return NewError(http.StatusConflict, "conflict", message)
+}
+
+func TooManyRequests(message string) *Error {
+	return NewError(http.StatusTooManyRequests, "too_many_requests", message)
+}
+
+func InternalError() *Error {
+	return NewError(http.StatusInternalServerError, "internal_error", "Something went wrong, please try again later")
+}
+
+// ErrorResponse responds with an error. Errors that are not an *Error are
+// logged with the request's context and reported as an internal error, so
+// their details are not shown to clients.
+func ErrorResponse(ctx context.Context, err error) *Response {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		slog.ErrorContext(ctx, "Unexpected error", "error", err)
+		apiErr = InternalError()
+	}
+
+	return NewResponse(apiErr.Status, apiErr)
+}
+
+// errorForStatus is the body for an error response that a handler returned
+// without one.
+func errorForStatus(status int) *Error {
+	if status == http.StatusInternalServerError {
+		return InternalError()
+	}
+
+	text := http.StatusText(status)
+	if text == "" {
+		text = "Error"
+	}
+
+	return NewError(status, strings.ToLower(strings.ReplaceAll(text, " ", "_")), text)
+}
@@ -0,0 +1,433 @@
+package api
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/twinj/uuid"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/logging"
+	"github.com/iplay88keys/my-recipe-library/pkg/ratelimit"
+)
+
+const (
+	RequestIDHeader    = "X-Request-ID"
+	maxRequestIDLength = 128
+
+	// DefaultGzipMinSize is the smallest response worth compressing. Smaller
+	// responses gain little and can even grow.
+	DefaultGzipMinSize = 1024
+)
+
+// Handler handles a request to an endpoint.
+type Handler func(r *Request) *Response
+
+// Middleware wraps a Handler. It can act on the request before calling the
+// next handler, on the response after, or respond without calling it at all.
+type Middleware func(next Handler) Handler
+
+// Chain wraps a handler in middleware. The first middleware is the outermost,
+// so it sees the request first and the response last.
+func Chain(handle Handler, middleware ...Middleware) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handle = middleware[i](handle)
+	}
+
+	return handle
+}
+
+// HTTPMiddleware wraps the server's whole http.Handler, outside the routing,
+// so it sees every request, including ones that match no endpoint such as CORS
+// preflights, and can act on the raw response writer.
+type HTTPMiddleware func(next http.Handler) http.Handler
+
+// ChainHTTP wraps a handler in HTTP middleware. The first middleware is the
+// outermost, as with Chain.
+func ChainHTTP(handler http.Handler, middleware ...HTTPMiddleware) http.Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+
+	return handler
+}
+
+// CORSOptions configures CORS. AllowedOrigins are the origins allowed to call
+// the API, or "*" for any. Methods and headers default to those the API uses.
+type CORSOptions struct {
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	MaxAge         time.Duration
+}
+
+// CORS lets browsers call the API from the allowed origins. It answers
+// preflight requests itself, since no endpoint is registered for OPTIONS, and
+// adds the CORS headers to the responses to other requests from those
+// origins.
+func CORS(options CORSOptions) HTTPMiddleware {
+	methods := options.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+
+	headers := options.AllowedHeaders
+	if len(headers) == 0 {
+		headers = []string{"Authorization", "Content-Type", RequestIDHeader}
+	}
+
+	exposed := []string{RequestIDHeader, "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"}
+
+	allowed := func(origin string) bool {
+		for _, allowedOrigin := range options.AllowedOrigins {
+			if allowedOrigin == "*" || strings.EqualFold(allowedOrigin, origin) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Origin")
+
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			if !allowed(origin) {
+				if preflight {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+
+			if !preflight {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(exposed, ", "))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+			if options.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(options.MaxAge.Seconds())))
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// Gzip compresses responses for clients that accept gzip. Responses smaller
+// than minSize, partial responses and ones that already have a
+// Content-Encoding are sent as they are.
+func Gzip(minSize int) HTTPMiddleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			if !acceptsGzip(r.Header) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			gw := &gzipResponseWriter{ResponseWriter: w, minSize: minSize}
+			defer gw.close()
+
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+// acceptsGzip reports whether the Accept-Encoding header allows gzip, and
+// does not turn it off with q=0.
+func acceptsGzip(header http.Header) bool {
+	for _, value := range header.Values("Accept-Encoding") {
+		for _, coding := range strings.Split(value, ",") {
+			name, params, _ := strings.Cut(coding, ";")
+			if !strings.EqualFold(strings.TrimSpace(name), "gzip") {
+				continue
+			}
+
+			weight, found := strings.CutPrefix(strings.TrimSpace(params), "q=")
+			if !found {
+				return true
+			}
+
+			q, err := strconv.ParseFloat(weight, 64)
+			return err == nil && q > 0
+		}
+	}
+
+	return false
+}
+
+// gzipResponseWriter holds back the start of a response until it has minSize
+// bytes, or the response ends, to decide whether to compress it.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minSize int
+
+	status  int
+	buf     []byte
+	started bool
+	gz      *gzip.Writer
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.started || w.status != 0 {
+		return
+	}
+
+	w.status = status
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.buf = append(w.buf, p...)
+		if len(w.buf) < w.minSize {
+			return len(p), nil
+		}
+
+		err := w.start(true)
+		return len(p), err
+	}
+
+	if w.gz != nil {
+		return w.gz.Write(p)
+	}
+
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// start sends the status and what has been held back, compressed if the
+// response is big enough and not already encoded.
+func (w *gzipResponseWriter) start(compress bool) error {
+	w.started = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	header := w.ResponseWriter.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" ||
+		w.status == http.StatusNoContent || w.status == http.StatusNotModified || w.status == http.StatusPartialContent {
+		compress = false
+	}
+
+	if compress {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(w.buf)
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+
+	return err
+}
+
+// close ends the response, sending a response that stayed under minSize
+// uncompressed.
+func (w *gzipResponseWriter) close() {
+	if !w.started {
+		w.start(false)
+	}
+
+	if w.gz != nil {
+		w.gz.Close()
+	}
+}
+
+// RequestID gives every request an ID, taken from its X-Request-ID header or
+// generated, which is added to the request's context for logging and sent
+// back in the response's X-Request-ID header.
+func RequestID() Middleware {
+	return func(next Handler) Handler {
+		return func(r *Request) *Response {
+			requestID := r.Req.Header.Get(RequestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = uuid.NewV4().String()
+			}
+
+			r.Req = r.Req.WithContext(logging.WithRequestID(r.Req.Context(), requestID))
+
+			resp := next(r)
+			if resp.Header == nil {
+				resp.Header = http.Header{}
+			}
+			resp.Header.Set(RequestIDHeader, requestID)
+
+			return resp
+		}
+	}
+}
+
+// Logging logs every request with its user, latency and status.
+func Logging() Middleware {
+	return func(next Handler) Handler {
+		return func(r *Request) *Response {
+			startTime := time.Now()
+
+			resp := next(r)
+
+			logRequest(startTime, time.Now(), r, resp)
+			return resp
+		}
+	}
+}
+
+// Recovery turns a panic in a handler into an internal error response, and
+// logs the panic with its stack trace.
+func Recovery() Middleware {
+	return func(next Handler) Handler {
+		return func(r *Request) (resp *Response) {
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					slog.ErrorContext(r.Req.Context(), "Recovered from panic", "method", r.Req.Method, "path", r.Req.URL.Path, "panic", recovered, "stack", string(debug.Stack()))
+					resp = ErrorResponse(r.Req.Context(), InternalError())
+				}
+			}()
+
+			return next(r)
+		}
+	}
+}
+
+// Timeout cancels the request's context once the timeout has passed, so the
+// database work for a request does not outlive it. A handler that fails after
+// running out of time gets a service unavailable response, while one that
+// still answered with anything below a 500 keeps its response.
+func Timeout(timeout time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(r *Request) *Response {
+			ctx, cancel := context.WithTimeout(r.Req.Context(), timeout)
+			defer cancel()
+
+			r.Req = r.Req.WithContext(ctx)
+
+			resp := next(r)
+			if resp.StatusCode >= http.StatusInternalServerError && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				slog.WarnContext(ctx, "Request timed out", "timeout", timeout)
+				return ErrorResponse(ctx, NewError(http.StatusServiceUnavailable, "timeout", "The request took too long, please try again later"))
+			}
+
+			return resp
+		}
+	}
+}
+
+// Authenticate only lets through requests with a valid access token, and sets
+// the request's UserID to the user the token belongs to.
+func Authenticate(tokenValidator TokenValidator, accessDetailsRetriever AccessDetailsRetriever) Middleware {
+	return func(next Handler) Handler {
+		return func(r *Request) *Response {
+			userID, valid := validateUserToken(tokenValidator, accessDetailsRetriever, r.Req)
+			if !valid {
+				return ErrorResponse(r.Req.Context(), Unauthorized("Please log in"))
+			}
+
+			r.UserID = userID
+			return next(r)
+		}
+	}
+}
+
+// RateLimited only lets through requests within the limit, counted separately
+// for each endpoint name, and adds RateLimit headers to the response. If the
+// limiter fails the request is let through without the headers, rather than
+// taking the endpoint down with it, unless the limit is FailClosed.
+func RateLimited(limiter ratelimit.Limiter, name string, limit *RateLimit) Middleware {
+	return func(next Handler) Handler {
+		return func(r *Request) *Response {
+			key := fmt.Sprintf("%s:ip:%s", name, r.ClientIP())
+			if limit.PerUser && r.UserID != -1 {
+				key = fmt.Sprintf("%s:user:%d", name, r.UserID)
+			}
+
+			result, err := limiter.Allow(key, limit.Requests, limit.Window)
+			if err != nil {
+				slog.ErrorContext(r.Req.Context(), "Failed to check rate limit", "error", err)
+				if limit.FailClosed {
+					return ErrorResponse(r.Req.Context(), NewError(http.StatusServiceUnavailable, "rate_limit_unavailable", "Please try again later"))
+				}
+
+				return next(r)
+			}
+
+			var resp *Response
+			if result.Allowed {
+				resp = next(r)
+			} else {
+				resp = ErrorResponse(r.Req.Context(), TooManyRequests("Too many requests, please try again later"))
+			}
+
+			if resp.Header == nil {
+				resp.Header = http.Header{}
+			}
+
+			reset := strconv.Itoa(int(math.Ceil(result.Reset.Seconds())))
+			resp.Header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			resp.Header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			resp.Header.Set("RateLimit-Reset", reset)
+
+			if !result.Allowed {
+				resp.Header.Set("Retry-After", reset)
+			}
+
+			return resp
+		}
+	}
+}
+
+// validRequestID only accepts IDs from clients that are safe to put in logs
+// and headers.
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, ch := range requestID {
+		if !(ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || strings.ContainsRune("-_.:", ch)) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,462 @@
+package api_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/logging"
+	"github.com/iplay88keys/my-recipe-library/pkg/ratelimit"
+	"github.com/iplay88keys/my-recipe-library/pkg/token"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Middleware", func() {
+	var (
+		req *api.Request
+		ok  api.Handler
+	)
+
+	BeforeEach(func() {
+		httpReq, err := http.NewRequest(http.MethodGet, "/api/v1/test", nil)
+		Expect(err).ToNot(HaveOccurred())
+		httpReq.RemoteAddr = "192.0.2.10:54321"
+
+		req = &api.Request{Req: httpReq, UserID: -1}
+
+		ok = func(r *api.Request) *api.Response {
+			return api.NewResponse(http.StatusOK, nil)
+		}
+	})
+
+	Describe("Chain", func() {
+		It("runs the first middleware outermost", func() {
+			var calls []string
+			record := func(name string) api.Middleware {
+				return func(next api.Handler) api.Handler {
+					return func(r *api.Request) *api.Response {
+						calls = append(calls, "before "+name)
+						resp := next(r)
+						calls = append(calls, "after "+name)
+						return resp
+					}
+				}
+			}
+
+			handle := api.Chain(func(r *api.Request) *api.Response {
+				calls = append(calls, "handler")
+				return api.NewResponse(http.StatusOK, nil)
+			}, record("first"), record("second"))
+
+			resp := handle(req)
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(calls).To(Equal([]string{
+				"before first",
+				"before second",
+				"handler",
+				"after second",
+				"after first",
+			}))
+		})
+
+		It("returns the handler as is without middleware", func() {
+			resp := api.Chain(ok)(req)
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		})
+	})
+
+	Describe("ChainHTTP", func() {
+		It("runs the first middleware outermost", func() {
+			var calls []string
+			record := func(name string) api.HTTPMiddleware {
+				return func(next http.Handler) http.Handler {
+					return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						calls = append(calls, "before "+name)
+						next.ServeHTTP(w, r)
+						calls = append(calls, "after "+name)
+					})
+				}
+			}
+
+			handler := api.ChainHTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, "handler")
+			}), record("first"), record("second"))
+
+			handler.ServeHTTP(httptest.NewRecorder(), req.Req)
+			Expect(calls).To(Equal([]string{
+				"before first",
+				"before second",
+				"handler",
+				"after second",
+				"after first",
+			}))
+		})
+	})
+
+	Describe("CORS", func() {
+		var (
+			handler http.Handler
+			called  bool
+		)
+
+		BeforeEach(func() {
+			called = false
+			handler = api.CORS(api.CORSOptions{
+				AllowedOrigins: []string{"https://app.example.com"},
+				MaxAge:         10 * time.Minute,
+			})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusOK)
+			}))
+		})
+
+		serve := func(method, origin string, header http.Header) *httptest.ResponseRecorder {
+			r := httptest.NewRequest(method, "/api/v1/recipes", nil)
+			for key, values := range header {
+				r.Header[key] = values
+			}
+			if origin != "" {
+				r.Header.Set("Origin", origin)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			return w
+		}
+
+		It("answers preflight requests from allowed origins", func() {
+			w := serve(http.MethodOptions, "https://app.example.com", http.Header{
+				"Access-Control-Request-Method":  {http.MethodPut},
+				"Access-Control-Request-Headers": {"authorization, content-type"},
+			})
+
+			Expect(w.Code).To(Equal(http.StatusNoContent))
+			Expect(called).To(BeFalse())
+			Expect(w.Header().Get("Access-Control-Allow-Origin")).To(Equal("https://app.example.com"))
+			Expect(w.Header().Get("Access-Control-Allow-Methods")).To(ContainSubstring(http.MethodPut))
+			Expect(w.Header().Get("Access-Control-Allow-Headers")).To(Equal("Authorization, Content-Type, X-Request-ID"))
+			Expect(w.Header().Get("Access-Control-Max-Age")).To(Equal("600"))
+			Expect(w.Header().Values("Vary")).To(ContainElement("Origin"))
+		})
+
+		It("rejects preflight requests from other origins", func() {
+			w := serve(http.MethodOptions, "https://evil.example.com", http.Header{
+				"Access-Control-Request-Method": {http.MethodDelete},
+			})
+
+			Expect(w.Code).To(Equal(http.StatusForbidden))
+			Expect(called).To(BeFalse())
+			Expect(w.Header().Get("Access-Control-Allow-Origin")).To(BeEmpty())
+		})
+
+		It("adds the CORS headers to requests from allowed origins", func() {
+			w := serve(http.MethodGet, "https://app.example.com", nil)
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(called).To(BeTrue())
+			Expect(w.Header().Get("Access-Control-Allow-Origin")).To(Equal("https://app.example.com"))
+			Expect(w.Header().Get("Access-Control-Expose-Headers")).To(ContainSubstring("X-Request-ID"))
+		})
+
+		It("passes requests from other origins through without CORS headers", func() {
+			w := serve(http.MethodGet, "https://evil.example.com", nil)
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(called).To(BeTrue())
+			Expect(w.Header().Get("Access-Control-Allow-Origin")).To(BeEmpty())
+		})
+
+		It("passes requests without an origin through untouched", func() {
+			w := serve(http.MethodOptions, "", http.Header{
+				"Access-Control-Request-Method": {http.MethodGet},
+			})
+
+			Expect(called).To(BeTrue())
+			Expect(w.Header().Get("Vary")).To(BeEmpty())
+		})
+
+		It("allows any origin with *", func() {
+			handler = api.CORS(api.CORSOptions{AllowedOrigins: []string{"*"}})(http.NotFoundHandler())
+
+			w := serve(http.MethodOptions, "https://other.example.com", http.Header{
+				"Access-Control-Request-Method": {http.MethodGet},
+			})
+
+			Expect(w.Code).To(Equal(http.StatusNoContent))
+			Expect(w.Header().Get("Access-Control-Allow-Origin")).To(Equal("https://other.example.com"))
+			Expect(w.Header().Get("Access-Control-Max-Age")).To(BeEmpty())
+		})
+	})
+
+	Describe("Gzip", func() {
+		serve := func(body string, acceptEncoding string, header http.Header) *httptest.ResponseRecorder {
+			handler := api.Gzip(16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for key, values := range header {
+					w.Header()[key] = values
+				}
+				w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+				w.WriteHeader(http.StatusCreated)
+				io.WriteString(w, body)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/recipes", nil)
+			if acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", acceptEncoding)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			return w
+		}
+
+		gunzip := func(body []byte) string {
+			reader, err := gzip.NewReader(bytes.NewReader(body))
+			Expect(err).ToNot(HaveOccurred())
+
+			uncompressed, err := io.ReadAll(reader)
+			Expect(err).ToNot(HaveOccurred())
+			return string(uncompressed)
+		}
+
+		body := strings.Repeat("chocolate chip cookies ", 10)
+
+		It("compresses large responses for clients that accept gzip", func() {
+			w := serve(body, "deflate, gzip;q=0.8", nil)
+
+			Expect(w.Code).To(Equal(http.StatusCreated))
+			Expect(w.Header().Get("Content-Encoding")).To(Equal("gzip"))
+			Expect(w.Header().Get("Content-Length")).To(BeEmpty())
+			Expect(w.Header().Values("Vary")).To(ContainElement("Accept-Encoding"))
+			Expect(gunzip(w.Body.Bytes())).To(Equal(body))
+		})
+
+		It("leaves responses alone for clients that do not accept gzip", func() {
+			for _, acceptEncoding := range []string{"", "deflate", "gzip;q=0"} {
+				w := serve(body, acceptEncoding, nil)
+
+				Expect(w.Code).To(Equal(http.StatusCreated))
+				Expect(w.Header().Get("Content-Encoding")).To(BeEmpty())
+				Expect(w.Header().Values("Vary")).To(ContainElement("Accept-Encoding"))
+				Expect(w.Body.String()).To(Equal(body))
+			}
+		})
+
+		It("does not compress small responses", func() {
+			w := serve("{}", "gzip", nil)
+
+			Expect(w.Code).To(Equal(http.StatusCreated))
+			Expect(w.Header().Get("Content-Encoding")).To(BeEmpty())
+			Expect(w.Header().Get("Content-Length")).To(Equal("2"))
+			Expect(w.Header().Values("Vary")).To(ContainElement("Accept-Encoding"))
+			Expect(w.Body.String()).To(Equal("{}"))
+		})
+
+		It("does not compress responses that are already encoded", func() {
+			w := serve(body, "gzip", http.Header{"Content-Encoding": {"br"}})
+
+			Expect(w.Header().Get("Content-Encoding")).To(Equal("br"))
+			Expect(w.Body.String()).To(Equal(body))
+		})
+	})
+
+	Describe("RequestID", func() {
+		var requestID string
+
+		BeforeEach(func() {
+			requestID = ""
+			ok = func(r *api.Request) *api.Response {
+				requestID = logging.RequestID(r.Req.Context())
+				return api.NewResponse(http.StatusOK, nil)
+			}
+		})
+
+		It("uses the request ID sent by the client", func() {
+			req.Req.Header.Set("X-Request-ID", "client-request-id")
+
+			resp := api.RequestID()(ok)(req)
+			Expect(requestID).To(Equal("client-request-id"))
+			Expect(resp.Header.Get("X-Request-ID")).To(Equal("client-request-id"))
+		})
+
+		It("generates a request ID if the client did not send one", func() {
+			resp := api.RequestID()(ok)(req)
+			Expect(requestID).ToNot(BeEmpty())
+			Expect(resp.Header.Get("X-Request-ID")).To(Equal(requestID))
+		})
+
+		It("replaces a request ID that is not safe to log", func() {
+			req.Req.Header.Set("X-Request-ID", "bad id\nwith a new line")
+
+			resp := api.RequestID()(ok)(req)
+			Expect(requestID).ToNot(ContainSubstring("bad id"))
+			Expect(resp.Header.Get("X-Request-ID")).To(Equal(requestID))
+		})
+	})
+
+	Describe("Timeout", func() {
+		It("responds with service unavailable if the handler runs past the deadline", func() {
+			resp := api.Timeout(10 * time.Millisecond)(func(r *api.Request) *api.Response {
+				<-r.Req.Context().Done()
+				return api.ErrorResponse(r.Req.Context(), r.Req.Context().Err())
+			})(req)
+			Expect(resp.StatusCode).To(Equal(http.StatusServiceUnavailable))
+
+			apiErr, isAPIError := resp.Body.(*api.Error)
+			Expect(isAPIError).To(BeTrue())
+			Expect(apiErr.Code).To(Equal("timeout"))
+		})
+
+		It("keeps responses the handler finished before failing", func() {
+			resp := api.Timeout(10 * time.Millisecond)(func(r *api.Request) *api.Response {
+				<-r.Req.Context().Done()
+				return api.NewResponse(http.StatusCreated, nil)
+			})(req)
+			Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		})
+
+		It("sets a deadline on the request's context", func() {
+			var deadline time.Time
+			var hasDeadline bool
+			resp := api.Timeout(time.Minute)(func(r *api.Request) *api.Response {
+				deadline, hasDeadline = r.Req.Context().Deadline()
+				return api.NewResponse(http.StatusOK, nil)
+			})(req)
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(hasDeadline).To(BeTrue())
+			Expect(deadline).To(BeTemporally("~", time.Now().Add(time.Minute), time.Second))
+		})
+	})
+
+	Describe("Authenticate", func() {
+		var (
+			validator *mockTokenValidator
+			retriever *mockAccessDetailsRetriever
+		)
+
+		BeforeEach(func() {
+			validator = &mockTokenValidator{validateToken: func(r *http.Request) (*token.AccessDetails, error) {
+				return &token.AccessDetails{AccessUuid: "some-uuid", UserId: 10}, nil
+			}}
+			retriever = &mockAccessDetailsRetriever{retrieveTokenDetails: func(ctx context.Context, details *token.AccessDetails) (int64, error) {
+				Expect(details.AccessUuid).To(Equal("some-uuid"))
+				return 10, nil
+			}}
+		})
+
+		It("sets the user the token belongs to", func() {
+			var userID int64
+			handle := api.Authenticate(validator, retriever)(func(r *api.Request) *api.Response {
+				userID = r.UserID
+				return api.NewResponse(http.StatusOK, nil)
+			})
+
+			resp := handle(req)
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(userID).To(Equal(int64(10)))
+		})
+
+		It("returns unauthorized without calling the handler if the token is invalid", func() {
+			validator.validateToken = func(r *http.Request) (*token.AccessDetails, error) {
+				return nil, errors.New("auth error")
+			}
+
+			resp := api.Authenticate(validator, retriever)(func(r *api.Request) *api.Response {
+				Fail("the handler should not be called")
+				return nil
+			})(req)
+			Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("returns unauthorized if the token has been revoked", func() {
+			retriever.retrieveTokenDetails = func(ctx context.Context, details *token.AccessDetails) (int64, error) {
+				return -1, errors.New("not found")
+			}
+
+			resp := api.Authenticate(validator, retriever)(ok)(req)
+			Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		})
+	})
+
+	Describe("RateLimited", func() {
+		var limiter *ratelimit.MemoryLimiter
+
+		BeforeEach(func() {
+			now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+			limiter = ratelimit.NewMemoryLimiter()
+			limiter.Now = func() time.Time { return now }
+		})
+
+		It("counts users separately from their IP for per user limits", func() {
+			handle := api.RateLimited(limiter, "GET test", &api.RateLimit{Requests: 1, Window: time.Minute, PerUser: true})(ok)
+
+			Expect(handle(req).StatusCode).To(Equal(http.StatusOK))
+			Expect(handle(req).StatusCode).To(Equal(http.StatusTooManyRequests))
+
+			req.UserID = 10
+			resp := handle(req)
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Header.Get("RateLimit-Remaining")).To(Equal("0"))
+			Expect(resp.Header.Get("RateLimit-Reset")).To(Equal("60"))
+		})
+
+		It("counts clients behind a trusted proxy by their forwarded IP", func() {
+			proxies, err := api.ParseTrustedProxies([]string{"192.0.2.10"})
+			Expect(err).ToNot(HaveOccurred())
+			req.TrustedProxies = proxies
+
+			handle := api.RateLimited(limiter, "GET test", &api.RateLimit{Requests: 1, Window: time.Minute})(ok)
+
+			req.Req.Header.Set("X-Forwarded-For", "198.51.100.1")
+			Expect(handle(req).StatusCode).To(Equal(http.StatusOK))
+			Expect(handle(req).StatusCode).To(Equal(http.StatusTooManyRequests))
+
+			req.Req.Header.Set("X-Forwarded-For", "198.51.100.2")
+			Expect(handle(req).StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("does not let clients reset their limit by sending X-Forwarded-For", func() {
+			handle := api.RateLimited(limiter, "GET test", &api.RateLimit{Requests: 1, Window: time.Minute})(ok)
+
+			req.Req.Header.Set("X-Forwarded-For", "198.51.100.1")
+			Expect(handle(req).StatusCode).To(Equal(http.StatusOK))
+
+			req.Req.Header.Set("X-Forwarded-For", "198.51.100.2")
+			Expect(handle(req).StatusCode).To(Equal(http.StatusTooManyRequests))
+		})
+
+		It("lets requests through if the limiter fails", func() {
+			handle := api.RateLimited(&failingLimiter{}, "GET test", &api.RateLimit{Requests: 1, Window: time.Minute})(ok)
+
+			resp := handle(req)
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Header.Get("RateLimit-Limit")).To(BeEmpty())
+		})
+
+		It("refuses requests if the limiter fails and the limit fails closed", func() {
+			handle := api.RateLimited(&failingLimiter{}, "GET test", &api.RateLimit{Requests: 1, Window: time.Minute, FailClosed: true})(func(r *api.Request) *api.Response {
+				Fail("the handler should not be called")
+				return nil
+			})
+
+			resp := handle(req)
+			Expect(resp.StatusCode).To(Equal(http.StatusServiceUnavailable))
+			Expect(resp.Body).To(Equal(api.NewError(http.StatusServiceUnavailable, "rate_limit_unavailable", "Please try again later")))
+		})
+	})
+})
+
+type failingLimiter struct{}
+
+func (l *failingLimiter) Allow(key string, limit int, window time.Duration) (*ratelimit.Result, error) {
+	return nil, errors.New("redis error")
+}
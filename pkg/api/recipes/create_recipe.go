@@ -2,18 +2,16 @@ package recipes
 
 import (
 	"context"
-	"fmt"
+	"log/slog"
 	"net/http"
-
-	. "github.com/iplay88keys/my-recipe-library/pkg/helpers"
+	"time"
 
 	"github.com/iplay88keys/my-recipe-library/pkg/api"
 	"github.com/iplay88keys/my-recipe-library/pkg/services"
 )
 
 type CreateRecipeResponse struct {
-	RecipeID int64             `json:"recipe_id,omitempty"`
-	Errors   map[string]string `json:"errors,omitempty"`
+	RecipeID int64 `json:"recipe_id,omitempty"`
 }
 
 type RecipeCreator interface {
@@ -22,61 +20,25 @@ type RecipeCreator interface {
 
 func CreateRecipe(service RecipeCreator) *api.Endpoint {
 	return &api.Endpoint{
-		Path:   "recipes",
-		Method: http.MethodPost,
-		Auth:   true,
+		Path:      "recipes",
+		Method:    http.MethodPost,
+		Auth:      true,
+		RateLimit: &api.RateLimit{Requests: 60, Window: time.Minute, PerUser: true},
 		Handle: func(r *api.Request) *api.Response {
 			var recipe CreateRecipeRequest
 			if err := r.Decode(&recipe); err != nil {
-				fmt.Printf("Error decoding json body for add recipe: %s\n", err.Error())
+				slog.WarnContext(r.Req.Context(), "Error decoding json body for add recipe", "error", err)
 				return api.NewResponse(http.StatusBadRequest, nil)
 			}
 
 			validationErrors := recipe.Validate()
 			if len(validationErrors) > 0 {
-				resp := &CreateRecipeResponse{
-					Errors: validationErrors,
-				}
-
-				return api.NewResponse(http.StatusBadRequest, resp)
-			}
-
-			ingredients := make([]*services.IngredientInput, len(recipe.Ingredients))
-			for i, ingredient := range recipe.Ingredients {
-				ingredients[i] = &services.IngredientInput{
-					Name:     ingredient.Name,
-					Amount:   ingredient.Amount,
-					Unit:     ingredient.Unit,
-					Notes:    ingredient.Notes,
-					OrderNum: ingredient.OrderNum,
-				}
-			}
-
-			steps := make([]*services.StepInput, len(recipe.Steps))
-			for i, step := range recipe.Steps {
-				steps[i] = &services.StepInput{
-					Instructions: step.Instructions,
-					OrderNum:     step.OrderNum,
-					Notes:        &step.Notes,
-				}
-			}
-
-			recipeInput := &services.RecipeInput{
-				Name:        recipe.Name,
-				Description: recipe.Description,
-				Servings:    IntPointer(recipe.Servings),
-				PrepTime:    StringPointer(recipe.PrepTime),
-				CookTime:    StringPointer(recipe.CookTime),
-				CoolTime:    StringPointer(recipe.CoolTime),
-				TotalTime:   StringPointer(recipe.TotalTime),
-				Source:      StringPointer(recipe.Source),
-				Ingredients: ingredients,
-				Steps:       steps,
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(validationErrors))
 			}
 
-			recipeID, err := service.CreateRecipe(r.Req.Context(), r.UserID, recipeInput)
+			recipeID, err := service.CreateRecipe(r.Req.Context(), r.UserID, recipe.toRecipeInput())
 			if err != nil {
-				fmt.Printf("Error adding recipe: %s\n", err.Error())
+				slog.ErrorContext(r.Req.Context(), "Error adding recipe", "error", err)
 				return api.NewResponse(http.StatusInternalServerError, nil)
 			}
 
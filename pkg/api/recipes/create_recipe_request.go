@@ -1,5 +1,17 @@
 package recipes
 
+import (
+	"fmt"
+
+	. "github.com/iplay88keys/my-recipe-library/pkg/helpers"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+)
+
+// maxTagLength is the longest tag name the tags table can hold.
+const maxTagLength = 50
+
 type CreateRecipeRequest struct {
 	Name        string                     `json:"name"`
 	Description string                     `json:"description"`
@@ -11,6 +23,7 @@ type CreateRecipeRequest struct {
 	Source      string                     `json:"source"`
 	Ingredients []*CreateIngredientRequest `json:"ingredients"`
 	Steps       []*CreateStepRequest       `json:"steps"`
+	Tags        []string                   `json:"tags"`
 }
 
 type CreateIngredientRequest struct {
@@ -42,5 +55,47 @@ func (a *CreateRecipeRequest) Validate() map[string]string {
 		errors["servings"] = "Required"
 	}
 
+	for _, tag := range a.Tags {
+		if len(repositories.NormalizeName(tag)) > maxTagLength {
+			errors["tags"] = fmt.Sprintf("Must be %d characters or less", maxTagLength)
+		}
+	}
+
 	return errors
 }
+
+func (a *CreateRecipeRequest) toRecipeInput() *services.RecipeInput {
+	ingredients := make([]*services.IngredientInput, len(a.Ingredients))
+	for i, ingredient := range a.Ingredients {
+		ingredients[i] = &services.IngredientInput{
+			Name:     ingredient.Name,
+			Amount:   ingredient.Amount,
+			Unit:     ingredient.Unit,
+			Notes:    ingredient.Notes,
+			OrderNum: ingredient.OrderNum,
+		}
+	}
+
+	steps := make([]*services.StepInput, len(a.Steps))
+	for i, step := range a.Steps {
+		steps[i] = &services.StepInput{
+			Instructions: step.Instructions,
+			OrderNum:     step.OrderNum,
+			Notes:        &step.Notes,
+		}
+	}
+
+	return &services.RecipeInput{
+		Name:        a.Name,
+		Description: a.Description,
+		Servings:    IntPointer(a.Servings),
+		PrepTime:    StringPointer(a.PrepTime),
+		CookTime:    StringPointer(a.CookTime),
+		CoolTime:    StringPointer(a.CoolTime),
+		TotalTime:   StringPointer(a.TotalTime),
+		Source:      StringPointer(a.Source),
+		Ingredients: ingredients,
+		Steps:       steps,
+		Tags:        a.Tags,
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/iplay88keys/my-recipe-library/pkg/api"
 	"github.com/iplay88keys/my-recipe-library/pkg/api/recipes"
@@ -71,6 +73,8 @@ var _ = Describe("CreateRecipe", func() {
 		respBody, err := json.Marshal(resp.Body)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(respBody).To(MatchJSON(`{
+            "code": "bad_request",
+            "message": "The request has invalid fields",
             "errors": {
                 "name": "Required",
                 "description": "Required",
@@ -79,6 +83,60 @@ var _ = Describe("CreateRecipe", func() {
         }`))
 	})
 
+	It("passes the recipe's tags to the service", func() {
+		var tags []string
+		fakeService := &mockRecipeCreator{
+			createRecipe: func(ctx context.Context, userID int64, recipe *services.RecipeInput) (int64, error) {
+				tags = recipe.Tags
+				return 1, nil
+			},
+		}
+
+		body := []byte(`{
+            "name": "Root Beer Float",
+            "description": "Delicious",
+            "servings": 1,
+            "tags": ["Dessert", "drinks"]
+        }`)
+
+		req, err := http.NewRequest(http.MethodPost, "/recipes", bytes.NewBuffer(body))
+		Expect(err).ToNot(HaveOccurred())
+
+		resp := recipes.CreateRecipe(fakeService).Handle(&api.Request{
+			Req:    req,
+			UserID: 2,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(tags).To(Equal([]string{"Dessert", "drinks"}))
+	})
+
+	It("returns a validation error for a tag that is too long", func() {
+		fakeService := &mockRecipeCreator{
+			createRecipe: func(ctx context.Context, userID int64, recipe *services.RecipeInput) (int64, error) {
+				return 1, nil
+			},
+		}
+
+		body := []byte(fmt.Sprintf(`{
+            "name": "Root Beer Float",
+            "description": "Delicious",
+            "servings": 1,
+            "tags": ["%s"]
+        }`, strings.Repeat("a", 51)))
+
+		req, err := http.NewRequest(http.MethodPost, "/recipes", bytes.NewBuffer(body))
+		Expect(err).ToNot(HaveOccurred())
+
+		resp := recipes.CreateRecipe(fakeService).Handle(&api.Request{
+			Req:    req,
+			UserID: 2,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(resp.Body.(*api.Error).Fields).To(HaveKeyWithValue("tags", "Must be 50 characters or less"))
+	})
+
 	It("returns an error if the recipe repository call fails", func() {
 		fakeService := &mockRecipeCreator{
 			createRecipe: func(ctx context.Context, userID int64, recipe *services.RecipeInput) (int64, error) {
@@ -0,0 +1,47 @@
+package recipes
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+)
+
+type RecipeDeleter interface {
+	DeleteRecipe(ctx context.Context, recipeID, userID int64) error
+}
+
+func DeleteRecipe(service RecipeDeleter) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "recipes/{id}",
+		Method: http.MethodDelete,
+		Auth:   true,
+		Handle: func(r *api.Request) *api.Response {
+			recipeID, err := strconv.ParseInt(r.Req.PathValue("id"), 10, 64)
+			if err != nil {
+				slog.WarnContext(r.Req.Context(), "Delete recipe endpoint invalid id", "error", err)
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			err = service.DeleteRecipe(r.Req.Context(), recipeID, r.UserID)
+			if err != nil {
+				if errors.Is(err, services.ErrNotFound) {
+					return api.NewResponse(http.StatusNotFound, nil)
+				}
+
+				if errors.Is(err, services.ErrForbidden) {
+					return api.NewResponse(http.StatusForbidden, nil)
+				}
+
+				slog.ErrorContext(r.Req.Context(), "Error deleting recipe", "error", err)
+				return api.NewResponse(http.StatusInternalServerError, nil)
+			}
+
+			return api.NewResponse(http.StatusNoContent, nil)
+		},
+	}
+}
@@ -0,0 +1,124 @@
+package recipes_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/recipes"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DeleteRecipe", func() {
+	It("deletes the recipe", func() {
+		var (
+			deletedID     int64
+			deletedUserID int64
+		)
+
+		fakeService := &mockRecipeDeleter{
+			deleteRecipe: func(ctx context.Context, recipeID, userID int64) error {
+				deletedID = recipeID
+				deletedUserID = userID
+				return nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodDelete, "/recipes/1", nil)
+		req.SetPathValue("id", "1")
+
+		resp := recipes.DeleteRecipe(fakeService).Handle(&api.Request{
+			Req:    req,
+			UserID: 2,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+		Expect(deletedID).To(BeEquivalentTo(1))
+		Expect(deletedUserID).To(BeEquivalentTo(2))
+	})
+
+	It("returns not found if the recipe does not exist", func() {
+		fakeService := &mockRecipeDeleter{
+			deleteRecipe: func(ctx context.Context, recipeID, userID int64) error {
+				return services.ErrNotFound
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodDelete, "/recipes/1", nil)
+		req.SetPathValue("id", "1")
+
+		resp := recipes.DeleteRecipe(fakeService).Handle(&api.Request{
+			Req:    req,
+			UserID: 2,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("returns forbidden if the recipe belongs to another user", func() {
+		fakeService := &mockRecipeDeleter{
+			deleteRecipe: func(ctx context.Context, recipeID, userID int64) error {
+				return services.ErrForbidden
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodDelete, "/recipes/1", nil)
+		req.SetPathValue("id", "1")
+
+		resp := recipes.DeleteRecipe(fakeService).Handle(&api.Request{
+			Req:    req,
+			UserID: 2,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	})
+
+	It("returns an error if the service call fails", func() {
+		fakeService := &mockRecipeDeleter{
+			deleteRecipe: func(ctx context.Context, recipeID, userID int64) error {
+				return errors.New("some error")
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodDelete, "/recipes/1", nil)
+		req.SetPathValue("id", "1")
+
+		resp := recipes.DeleteRecipe(fakeService).Handle(&api.Request{
+			Req:    req,
+			UserID: 2,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+
+	It("returns an error if the provided route variable is not a number", func() {
+		fakeService := &mockRecipeDeleter{
+			deleteRecipe: func(ctx context.Context, recipeID, userID int64) error {
+				return nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodDelete, "/recipes/not-a-number", nil)
+		req.SetPathValue("id", "not-a-number")
+
+		resp := recipes.DeleteRecipe(fakeService).Handle(&api.Request{
+			Req:    req,
+			UserID: 2,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
+
+type mockRecipeDeleter struct {
+	deleteRecipe func(ctx context.Context, recipeID, userID int64) error
+}
+
+func (m *mockRecipeDeleter) DeleteRecipe(ctx context.Context, recipeID, userID int64) error {
+	return m.deleteRecipe(ctx, recipeID, userID)
+}
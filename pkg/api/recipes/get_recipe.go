@@ -2,8 +2,8 @@ package recipes
 
 import (
 	"context"
-	"database/sql"
-	"fmt"
+	"errors"
+	"log/slog"
 	"net/http"
 	"sort"
 	"strconv"
@@ -25,6 +25,7 @@ type RecipeResponse struct {
 	Source      *string               `json:"source,omitempty"`
 	Ingredients []*IngredientResponse `json:"ingredients"`
 	Steps       []*StepResponse       `json:"steps"`
+	Tags        []string              `json:"tags"`
 }
 
 type IngredientResponse struct {
@@ -33,6 +34,7 @@ type IngredientResponse struct {
 	Amount           *string `json:"amount"`
 	Measurement      *string `json:"measurement"`
 	Preparation      *string `json:"preparation"`
+	Unscaled         bool    `json:"unscaled,omitempty"`
 }
 
 type StepResponse struct {
@@ -56,8 +58,12 @@ func (a ByStepNumber) Less(i, j int) bool { return a[i].OrderNum < a[j].OrderNum
 
 type RecipeFetcher interface {
 	GetRecipe(ctx context.Context, recipeID, userID int64) (*services.RecipeDetail, error)
+	ScaleRecipe(ctx context.Context, recipeID, userID int64, servings int) (*services.RecipeDetail, error)
 }
 
+// GetRecipe returns a recipe. With a servings query parameter, its ingredient
+// amounts are scaled to make that many servings, and any amounts that could
+// not be scaled are marked unscaled.
 func GetRecipe(service RecipeFetcher) *api.Endpoint {
 	return &api.Endpoint{
 		Path:   "recipes/{id}",
@@ -66,23 +72,41 @@ func GetRecipe(service RecipeFetcher) *api.Endpoint {
 		Handle: func(r *api.Request) *api.Response {
 			idStr := r.Req.PathValue("id")
 			if idStr == "" {
-				fmt.Printf("Recipe endpoint missing id\n")
+				slog.WarnContext(r.Req.Context(), "Recipe endpoint missing id")
 				return api.NewResponse(http.StatusBadRequest, nil)
 			}
 
 			recipeID, err := strconv.ParseInt(idStr, 10, 64)
 			if err != nil {
-				fmt.Printf("Recipe endpoint invalid id: %s\n", err.Error())
+				slog.WarnContext(r.Req.Context(), "Recipe endpoint invalid id", "error", err)
 				return api.NewResponse(http.StatusBadRequest, nil)
 			}
 
-			recipeDetail, err := service.GetRecipe(r.Req.Context(), recipeID, r.UserID)
+			var recipeDetail *services.RecipeDetail
+			if value := r.Req.URL.Query().Get("servings"); value != "" {
+				servings, parseErr := strconv.Atoi(value)
+				if parseErr != nil || servings < 1 {
+					return api.ErrorResponse(r.Req.Context(), api.ValidationError(map[string]string{
+						"servings": "Must be a number greater than 0",
+					}))
+				}
+
+				recipeDetail, err = service.ScaleRecipe(r.Req.Context(), recipeID, r.UserID, servings)
+			} else {
+				recipeDetail, err = service.GetRecipe(r.Req.Context(), recipeID, r.UserID)
+			}
 			if err != nil {
-				if err == sql.ErrNoRows {
+				if errors.Is(err, services.ErrNotFound) {
 					return api.NewResponse(http.StatusNotFound, nil)
 				}
 
-				fmt.Printf("Error getting recipe: %s\n", err.Error())
+				if errors.Is(err, services.ErrNoServings) {
+					return api.ErrorResponse(r.Req.Context(), api.ValidationError(map[string]string{
+						"servings": "The recipe has no servings to scale from",
+					}))
+				}
+
+				slog.ErrorContext(r.Req.Context(), "Error getting recipe", "error", err)
 				return api.NewResponse(http.StatusInternalServerError, nil)
 			}
 
@@ -97,6 +121,7 @@ func GetRecipe(service RecipeFetcher) *api.Endpoint {
 					Amount:           ingredient.Amount,
 					Measurement:      ingredient.Unit,
 					Preparation:      ingredient.Notes,
+					Unscaled:         ingredient.Unscaled,
 				}
 			}
 
@@ -121,6 +146,7 @@ func GetRecipe(service RecipeFetcher) *api.Endpoint {
 				Source:      recipeDetail.Source,
 				Ingredients: ingredients,
 				Steps:       steps,
+				Tags:        append([]string{}, recipeDetail.Tags...),
 			}
 
 			return api.NewResponse(http.StatusOK, resp)
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 
@@ -52,6 +53,7 @@ var _ = Describe("GetRecipe", func() {
 				OrderNum:     2,
 				Notes:        nil,
 			}},
+			Tags: []string{"dessert", "drinks"},
 		}
 
 		fakeService := &mockRecipeFetcher{
@@ -102,7 +104,8 @@ var _ = Describe("GetRecipe", func() {
             }, {
                 "step_number": 2,
                 "instructions": "Top with Root Beer."
-            }]
+            }],
+            "tags": ["dessert", "drinks"]
         }`))
 	})
 
@@ -164,7 +167,8 @@ var _ = Describe("GetRecipe", func() {
                 "measurement": null,
                 "preparation": null
             }],
-            "steps": []
+            "steps": [],
+            "tags": []
         }`))
 	})
 
@@ -216,14 +220,15 @@ var _ = Describe("GetRecipe", func() {
             }, {
                 "step_number": 2,
                 "instructions": "Top with Root Beer."
-            }]
+            }],
+            "tags": []
         }`))
 	})
 
-	It("returns an error if the recipe repository returns no rows", func() {
+	It("returns not found if the recipe does not exist", func() {
 		fakeService := &mockRecipeFetcher{
 			getRecipe: func(ctx context.Context, recipeID, userID int64) (*services.RecipeDetail, error) {
-				return nil, sql.ErrNoRows
+				return nil, fmt.Errorf("%w: %w", services.ErrNotFound, sql.ErrNoRows)
 			},
 		}
 
@@ -292,6 +297,125 @@ var _ = Describe("GetRecipe", func() {
 		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
 	})
 
+	It("scales the recipe to the servings asked for", func() {
+		fakeService := &mockRecipeFetcher{
+			scaleRecipe: func(ctx context.Context, recipeID, userID int64, servings int) (*services.RecipeDetail, error) {
+				Expect(recipeID).To(BeEquivalentTo(1))
+				Expect(userID).To(BeEquivalentTo(2))
+				Expect(servings).To(Equal(4))
+
+				return &services.RecipeDetail{
+					ID:          1,
+					Name:        "Root Beer Float",
+					Description: "Delicious",
+					Creator:     "User1",
+					Servings:    IntPointer(4),
+					Ingredients: []*services.IngredientDetail{{
+						Name:     "Vanilla Ice Cream",
+						Amount:   StringPointer("2"),
+						Unit:     StringPointer("Scoops"),
+						OrderNum: 1,
+					}, {
+						Name:     "Cherry",
+						Amount:   StringPointer("a few"),
+						OrderNum: 2,
+						Unscaled: true,
+					}},
+				}, nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/recipes/1?servings=4", nil)
+		req.SetPathValue("id", "1")
+
+		resp := recipes.GetRecipe(fakeService).Handle(&api.Request{
+			Req:    req,
+			UserID: 2,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "id": 1,
+            "name": "Root Beer Float",
+            "description": "Delicious",
+            "creator": "User1",
+            "servings": 4,
+            "ingredients": [{
+                "ingredient": "Vanilla Ice Cream",
+                "ingredient_number": 1,
+                "amount": "2",
+                "measurement": "Scoops",
+                "preparation": null
+            }, {
+                "ingredient": "Cherry",
+                "ingredient_number": 2,
+                "amount": "a few",
+                "measurement": null,
+                "preparation": null,
+                "unscaled": true
+            }],
+            "steps": [],
+            "tags": []
+        }`))
+	})
+
+	It("returns a validation error if the servings are not a positive number", func() {
+		fakeService := &mockRecipeFetcher{}
+
+		for _, servings := range []string{"0", "-2", "many"} {
+			req := httptest.NewRequest(http.MethodGet, "/recipes/1?servings="+servings, nil)
+			req.SetPathValue("id", "1")
+
+			resp := recipes.GetRecipe(fakeService).Handle(&api.Request{
+				Req:    req,
+				UserID: 2,
+			})
+
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(resp.Body.(*api.Error).Fields).To(HaveKey("servings"))
+		}
+	})
+
+	It("returns a validation error if the recipe has no servings to scale from", func() {
+		fakeService := &mockRecipeFetcher{
+			scaleRecipe: func(ctx context.Context, recipeID, userID int64, servings int) (*services.RecipeDetail, error) {
+				return nil, services.ErrNoServings
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/recipes/1?servings=2", nil)
+		req.SetPathValue("id", "1")
+
+		resp := recipes.GetRecipe(fakeService).Handle(&api.Request{
+			Req:    req,
+			UserID: 2,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(resp.Body.(*api.Error).Fields).To(HaveKeyWithValue("servings", "The recipe has no servings to scale from"))
+	})
+
+	It("returns not found if the recipe to scale does not exist", func() {
+		fakeService := &mockRecipeFetcher{
+			scaleRecipe: func(ctx context.Context, recipeID, userID int64, servings int) (*services.RecipeDetail, error) {
+				return nil, services.ErrNotFound
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/recipes/1?servings=2", nil)
+		req.SetPathValue("id", "1")
+
+		resp := recipes.GetRecipe(fakeService).Handle(&api.Request{
+			Req:    req,
+			UserID: 2,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
 	It("returns an error if the provided route variable is not a number", func() {
 		fakeService := &mockRecipeFetcher{
 			getRecipe: func(ctx context.Context, recipeID, userID int64) (*services.RecipeDetail, error) {
@@ -312,9 +436,14 @@ var _ = Describe("GetRecipe", func() {
 })
 
 type mockRecipeFetcher struct {
-	getRecipe func(ctx context.Context, recipeID, userID int64) (*services.RecipeDetail, error)
+	getRecipe   func(ctx context.Context, recipeID, userID int64) (*services.RecipeDetail, error)
+	scaleRecipe func(ctx context.Context, recipeID, userID int64, servings int) (*services.RecipeDetail, error)
 }
 
 func (m *mockRecipeFetcher) GetRecipe(ctx context.Context, recipeID, userID int64) (*services.RecipeDetail, error) {
 	return m.getRecipe(ctx, recipeID, userID)
 }
+
+func (m *mockRecipeFetcher) ScaleRecipe(ctx context.Context, recipeID, userID int64, servings int) (*services.RecipeDetail, error) {
+	return m.scaleRecipe(ctx, recipeID, userID, servings)
+}
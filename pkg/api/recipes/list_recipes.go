@@ -2,16 +2,21 @@ package recipes
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/iplay88keys/my-recipe-library/pkg/api"
 	"github.com/iplay88keys/my-recipe-library/pkg/services"
 )
 
 type RecipeListResponse struct {
-	Recipes []*RecipeSummaryResponse `json:"recipes"`
+	Recipes    []*RecipeSummaryResponse `json:"recipes"`
+	NextCursor string                   `json:"next_cursor,omitempty"`
+	Total      int                      `json:"total"`
 }
 
 type RecipeSummaryResponse struct {
@@ -21,7 +26,13 @@ type RecipeSummaryResponse struct {
 }
 
 type RecipeLister interface {
-	ListRecipes(ctx context.Context, userID int64) ([]*services.RecipeSummary, error)
+	ListRecipes(ctx context.Context, userID int64, options *services.RecipeListOptions) (*services.RecipeList, error)
+}
+
+var recipeSorts = map[string]services.RecipeSort{
+	"name":       services.RecipeSortName,
+	"created_at": services.RecipeSortCreatedAt,
+	"total_time": services.RecipeSortTotalTime,
 }
 
 func ListRecipes(service RecipeLister) *api.Endpoint {
@@ -30,18 +41,23 @@ func ListRecipes(service RecipeLister) *api.Endpoint {
 		Method: http.MethodGet,
 		Auth:   true,
 		Handle: func(r *api.Request) *api.Response {
-			recipeSummaries, err := service.ListRecipes(r.Req.Context(), r.UserID)
+			options, validationErrors := listOptions(r)
+			if len(validationErrors) > 0 {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(validationErrors))
+			}
+
+			list, err := service.ListRecipes(r.Req.Context(), r.UserID, options)
 			if err != nil {
-				if err == sql.ErrNoRows {
-					return api.NewResponse(http.StatusNoContent, nil)
+				if errors.Is(err, services.ErrInvalidCursor) {
+					return api.ErrorResponse(r.Req.Context(), api.ValidationError(map[string]string{"after": "Invalid cursor"}))
 				}
 
-				fmt.Printf("Error listing recipes: %s\n", err.Error())
+				slog.ErrorContext(r.Req.Context(), "Error listing recipes", "error", err)
 				return api.NewResponse(http.StatusInternalServerError, nil)
 			}
 
-			recipes := make([]*RecipeSummaryResponse, len(recipeSummaries))
-			for i, summary := range recipeSummaries {
+			recipes := make([]*RecipeSummaryResponse, len(list.Recipes))
+			for i, summary := range list.Recipes {
 				recipes[i] = &RecipeSummaryResponse{
 					ID:          summary.ID,
 					Name:        summary.Name,
@@ -50,10 +66,108 @@ func ListRecipes(service RecipeLister) *api.Endpoint {
 			}
 
 			resp := &RecipeListResponse{
-				Recipes: recipes,
+				Recipes:    recipes,
+				NextCursor: list.NextCursor,
+				Total:      list.Total,
 			}
 
 			return api.NewResponse(http.StatusOK, resp)
 		},
 	}
 }
+
+// listOptions reads the paging, sorting and filtering query parameters. A
+// sort prefixed with "-", such as "-created_at", is descending.
+func listOptions(r *api.Request) (*services.RecipeListOptions, map[string]string) {
+	query := r.Req.URL.Query()
+	validationErrors := make(map[string]string)
+
+	options := &services.RecipeListOptions{
+		Filter: &services.RecipeFilter{},
+		Sort:   services.RecipeSortName,
+		Limit:  services.DefaultRecipePageSize,
+		After:  query.Get("after"),
+	}
+
+	var err error
+	options.Filter.CookbookID, err = optionalID(r, "cookbook_id")
+	if err != nil {
+		validationErrors["cookbook_id"] = "Must be a number"
+	}
+
+	options.Filter.SectionID, err = optionalID(r, "section_id")
+	if err != nil {
+		validationErrors["section_id"] = "Must be a number"
+	}
+
+	options.Filter.MinServings, err = optionalInt(r, "min_servings")
+	if err != nil {
+		validationErrors["min_servings"] = "Must be a number"
+	}
+
+	options.Filter.MaxServings, err = optionalInt(r, "max_servings")
+	if err != nil {
+		validationErrors["max_servings"] = "Must be a number"
+	}
+
+	if source := query.Get("source"); source != "" {
+		options.Filter.Source = &source
+	}
+
+	options.Filter.Tags = query["tag"]
+	switch query.Get("tag_match") {
+	case "", "any":
+	case "all":
+		options.Filter.MatchAllTags = true
+	default:
+		validationErrors["tag_match"] = "Must be any or all"
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > services.MaxRecipePageSize {
+			validationErrors["limit"] = fmt.Sprintf("Must be a number from 1 to %d", services.MaxRecipePageSize)
+		}
+		options.Limit = limit
+	}
+
+	if value := query.Get("sort"); value != "" {
+		options.Descending = strings.HasPrefix(value, "-")
+
+		sort, ok := recipeSorts[strings.TrimPrefix(value, "-")]
+		if !ok {
+			validationErrors["sort"] = "Must be name, created_at or total_time"
+		}
+		options.Sort = sort
+	}
+
+	return options, validationErrors
+}
+
+func optionalID(r *api.Request, param string) (*int64, error) {
+	value := r.Req.URL.Query().Get(param)
+	if value == "" {
+		return nil, nil
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &id, nil
+}
+
+func optionalInt(r *api.Request, param string) (*int, error) {
+	value := r.Req.URL.Query().Get(param)
+	if value == "" {
+		return nil, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &i, nil
+}
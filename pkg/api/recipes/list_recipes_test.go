@@ -2,7 +2,6 @@ package recipes_test
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -16,31 +15,50 @@ import (
 )
 
 var _ = Describe("ListRecipes", func() {
-	It("returns the list of recipes", func() {
-		recipeSummaries := []*services.RecipeSummary{{
-			ID:          1,
-			Name:        "First",
-			Description: "One",
-		}, {
-			ID:          2,
-			Name:        "Second",
-			Description: "Two",
-		}}
-
-		fakeService := &mockRecipeLister{
-			listRecipes: func(ctx context.Context, userID int64) ([]*services.RecipeSummary, error) {
-				return recipeSummaries, nil
+	var (
+		received    *services.RecipeListOptions
+		fakeService *mockRecipeLister
+	)
+
+	BeforeEach(func() {
+		received = nil
+		fakeService = &mockRecipeLister{
+			listRecipes: func(ctx context.Context, userID int64, options *services.RecipeListOptions) (*services.RecipeList, error) {
+				Expect(userID).To(BeEquivalentTo(2))
+				received = options
+				return &services.RecipeList{Recipes: []*services.RecipeSummary{}}, nil
 			},
 		}
+	})
 
-		req, err := http.NewRequest(http.MethodGet, "/recipes", nil)
+	list := func(url string) *api.Response {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
 		Expect(err).ToNot(HaveOccurred())
 
-		resp := recipes.ListRecipes(fakeService).Handle(&api.Request{
+		return recipes.ListRecipes(fakeService).Handle(&api.Request{
 			Req:    req,
 			UserID: 2,
 		})
+	}
+
+	It("returns a page of recipes", func() {
+		fakeService.listRecipes = func(ctx context.Context, userID int64, options *services.RecipeListOptions) (*services.RecipeList, error) {
+			return &services.RecipeList{
+				Recipes: []*services.RecipeSummary{{
+					ID:          1,
+					Name:        "First",
+					Description: "One",
+				}, {
+					ID:          2,
+					Name:        "Second",
+					Description: "Two",
+				}},
+				NextCursor: "next-page",
+				Total:      5,
+			}, nil
+		}
 
+		resp := list("/recipes")
 		Expect(resp.StatusCode).To(Equal(http.StatusOK))
 
 		respBody, err := json.Marshal(resp.Body)
@@ -54,51 +72,102 @@ var _ = Describe("ListRecipes", func() {
                 "id": 2,
                 "name": "Second",
                 "description": "Two"
-            }]
+            }],
+            "next_cursor": "next-page",
+            "total": 5
         }`))
 	})
 
-	It("returns no content if there are no recipes", func() {
-		fakeService := &mockRecipeLister{
-			listRecipes: func(ctx context.Context, userID int64) ([]*services.RecipeSummary, error) {
-				return nil, sql.ErrNoRows
-			},
-		}
+	It("lists the first page by name by default", func() {
+		resp := list("/recipes")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
 
-		req, err := http.NewRequest(http.MethodGet, "/recipes", nil)
-		Expect(err).ToNot(HaveOccurred())
+		Expect(received.Sort).To(Equal(services.RecipeSortName))
+		Expect(received.Descending).To(BeFalse())
+		Expect(received.Limit).To(Equal(services.DefaultRecipePageSize))
+		Expect(received.After).To(BeEmpty())
+	})
 
-		resp := recipes.ListRecipes(fakeService).Handle(&api.Request{
-			Req:    req,
-			UserID: 2,
-		})
+	It("passes the page and sort to the service", func() {
+		resp := list("/recipes?limit=5&after=some-cursor&sort=-total_time")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
 
-		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+		Expect(received.Sort).To(Equal(services.RecipeSortTotalTime))
+		Expect(received.Descending).To(BeTrue())
+		Expect(received.Limit).To(Equal(5))
+		Expect(received.After).To(Equal("some-cursor"))
 	})
 
-	It("returns an error if the repository call fails", func() {
-		fakeService := &mockRecipeLister{
-			listRecipes: func(ctx context.Context, userID int64) ([]*services.RecipeSummary, error) {
-				return nil, errors.New("some error")
-			},
+	It("filters the recipes", func() {
+		resp := list("/recipes?cookbook_id=3&section_id=4&min_servings=2&max_servings=6&source=Grandma")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		Expect(*received.Filter.CookbookID).To(BeEquivalentTo(3))
+		Expect(*received.Filter.SectionID).To(BeEquivalentTo(4))
+		Expect(*received.Filter.MinServings).To(Equal(2))
+		Expect(*received.Filter.MaxServings).To(Equal(6))
+		Expect(*received.Filter.Source).To(Equal("Grandma"))
+		Expect(received.Filter.Tags).To(BeEmpty())
+		Expect(received.Filter.MatchAllTags).To(BeFalse())
+	})
+
+	It("filters the recipes by tags", func() {
+		resp := list("/recipes?tag=dessert&tag=Quick&tag_match=all")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		Expect(received.Filter.Tags).To(Equal([]string{"dessert", "Quick"}))
+		Expect(received.Filter.MatchAllTags).To(BeTrue())
+	})
+
+	It("returns bad request for invalid parameters", func() {
+		resp := list("/recipes?cookbook_id=abc&min_servings=many&limit=0&sort=servings&tag_match=some")
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(received).To(BeNil())
+
+		apiErr := resp.Body.(*api.Error)
+		Expect(apiErr.Fields).To(HaveKey("cookbook_id"))
+		Expect(apiErr.Fields).To(HaveKey("min_servings"))
+		Expect(apiErr.Fields).To(HaveKey("limit"))
+		Expect(apiErr.Fields).To(HaveKey("sort"))
+		Expect(apiErr.Fields).To(HaveKey("tag_match"))
+	})
+
+	It("returns bad request if the cursor is invalid", func() {
+		fakeService.listRecipes = func(ctx context.Context, userID int64, options *services.RecipeListOptions) (*services.RecipeList, error) {
+			return nil, services.ErrInvalidCursor
 		}
 
-		req, err := http.NewRequest(http.MethodGet, "/recipes", nil)
+		resp := list("/recipes?after=bad")
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(resp.Body.(*api.Error).Fields).To(HaveKey("after"))
+	})
+
+	It("returns an empty page if there are no recipes", func() {
+		resp := list("/recipes")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		respBody, err := json.Marshal(resp.Body)
 		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "recipes": [],
+            "total": 0
+        }`))
+	})
 
-		resp := recipes.ListRecipes(fakeService).Handle(&api.Request{
-			Req:    req,
-			UserID: 2,
-		})
+	It("returns an error if the repository call fails", func() {
+		fakeService.listRecipes = func(ctx context.Context, userID int64, options *services.RecipeListOptions) (*services.RecipeList, error) {
+			return nil, errors.New("some error")
+		}
 
+		resp := list("/recipes")
 		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
 	})
 })
 
 type mockRecipeLister struct {
-	listRecipes func(ctx context.Context, userID int64) ([]*services.RecipeSummary, error)
+	listRecipes func(ctx context.Context, userID int64, options *services.RecipeListOptions) (*services.RecipeList, error)
 }
 
-func (m *mockRecipeLister) ListRecipes(ctx context.Context, userID int64) ([]*services.RecipeSummary, error) {
-	return m.listRecipes(ctx, userID)
+func (m *mockRecipeLister) ListRecipes(ctx context.Context, userID int64, options *services.RecipeListOptions) (*services.RecipeList, error) {
+	return m.listRecipes(ctx, userID, options)
 }
@@ -0,0 +1,51 @@
+package recipes
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+)
+
+type TagListResponse struct {
+	Tags []*TagResponse `json:"tags"`
+}
+
+type TagResponse struct {
+	Name        string `json:"name"`
+	RecipeCount int    `json:"recipe_count"`
+}
+
+type TagLister interface {
+	ListTags(ctx context.Context, userID int64) ([]*services.TagSummary, error)
+}
+
+func ListTags(service TagLister) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "tags",
+		Method: http.MethodGet,
+		Auth:   true,
+		Handle: func(r *api.Request) *api.Response {
+			tags, err := service.ListTags(r.Req.Context(), r.UserID)
+			if err != nil {
+				slog.ErrorContext(r.Req.Context(), "Error listing tags", "error", err)
+				return api.NewResponse(http.StatusInternalServerError, nil)
+			}
+
+			resp := &TagListResponse{
+				Tags: make([]*TagResponse, len(tags)),
+			}
+
+			for i, tag := range tags {
+				resp.Tags[i] = &TagResponse{
+					Name:        tag.Name,
+					RecipeCount: tag.RecipeCount,
+				}
+			}
+
+			return api.NewResponse(http.StatusOK, resp)
+		},
+	}
+}
@@ -0,0 +1,79 @@
+package recipes_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/recipes"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ListTags", func() {
+	listTags := func(fakeService *mockTagLister) *api.Response {
+		req, err := http.NewRequest(http.MethodGet, "/tags", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		return recipes.ListTags(fakeService).Handle(&api.Request{
+			Req:    req,
+			UserID: 2,
+		})
+	}
+
+	It("returns the user's tags with their recipe counts", func() {
+		resp := listTags(&mockTagLister{
+			listTags: func(ctx context.Context, userID int64) ([]*services.TagSummary, error) {
+				Expect(userID).To(BeEquivalentTo(2))
+				return []*services.TagSummary{
+					{Name: "dessert", RecipeCount: 3},
+					{Name: "quick", RecipeCount: 1},
+				}, nil
+			},
+		})
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "tags": [
+                {"name": "dessert", "recipe_count": 3},
+                {"name": "quick", "recipe_count": 1}
+            ]
+        }`))
+	})
+
+	It("returns an empty list if the user has no tags", func() {
+		resp := listTags(&mockTagLister{
+			listTags: func(ctx context.Context, userID int64) ([]*services.TagSummary, error) {
+				return nil, nil
+			},
+		})
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{"tags": []}`))
+	})
+
+	It("returns an error if the tags cannot be listed", func() {
+		resp := listTags(&mockTagLister{
+			listTags: func(ctx context.Context, userID int64) ([]*services.TagSummary, error) {
+				return nil, errors.New("some error")
+			},
+		})
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+})
+
+type mockTagLister struct {
+	listTags func(ctx context.Context, userID int64) ([]*services.TagSummary, error)
+}
+
+func (m *mockTagLister) ListTags(ctx context.Context, userID int64) ([]*services.TagSummary, error) {
+	return m.listTags(ctx, userID)
+}
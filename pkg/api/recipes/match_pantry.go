@@ -0,0 +1,98 @@
+package recipes
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+)
+
+type PantryMatchListResponse struct {
+	Recipes []*PantryMatchResponse `json:"recipes"`
+}
+
+type PantryMatchResponse struct {
+	ID       int64    `json:"id"`
+	Name     string   `json:"name"`
+	Required int      `json:"required"`
+	Covered  int      `json:"covered"`
+	Missing  []string `json:"missing"`
+}
+
+type PantryMatcher interface {
+	MatchPantry(ctx context.Context, userID int64, pantry *services.PantryInput) ([]*services.PantryMatch, error)
+}
+
+// MatchPantry lists the recipes the user can cook with the ingredients they
+// have, each given as an ingredient query parameter.
+func MatchPantry(service PantryMatcher) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "recipes/pantry",
+		Method: http.MethodGet,
+		Auth:   true,
+		Handle: func(r *api.Request) *api.Response {
+			query := r.Req.URL.Query()
+			validationErrors := make(map[string]string)
+
+			pantry := &services.PantryInput{
+				Limit: services.DefaultRecipePageSize,
+			}
+
+			for _, ingredient := range query["ingredient"] {
+				if ingredient = strings.TrimSpace(ingredient); ingredient != "" {
+					pantry.Ingredients = append(pantry.Ingredients, ingredient)
+				}
+			}
+			if len(pantry.Ingredients) == 0 {
+				validationErrors["ingredient"] = "Required"
+			}
+
+			if value := query.Get("ignore_staples"); value != "" {
+				var err error
+				pantry.IgnoreStaples, err = strconv.ParseBool(value)
+				if err != nil {
+					validationErrors["ignore_staples"] = "Must be true or false"
+				}
+			}
+
+			if value := query.Get("limit"); value != "" {
+				var err error
+				pantry.Limit, err = strconv.Atoi(value)
+				if err != nil || pantry.Limit < 1 || pantry.Limit > services.MaxRecipePageSize {
+					validationErrors["limit"] = fmt.Sprintf("Must be a number from 1 to %d", services.MaxRecipePageSize)
+				}
+			}
+
+			if len(validationErrors) > 0 {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(validationErrors))
+			}
+
+			matches, err := service.MatchPantry(r.Req.Context(), r.UserID, pantry)
+			if err != nil {
+				slog.ErrorContext(r.Req.Context(), "Error matching recipes to pantry", "error", err)
+				return api.NewResponse(http.StatusInternalServerError, nil)
+			}
+
+			resp := &PantryMatchListResponse{
+				Recipes: make([]*PantryMatchResponse, len(matches)),
+			}
+
+			for i, match := range matches {
+				resp.Recipes[i] = &PantryMatchResponse{
+					ID:       match.ID,
+					Name:     match.Name,
+					Required: match.Required,
+					Covered:  match.Covered,
+					Missing:  match.Missing,
+				}
+			}
+
+			return api.NewResponse(http.StatusOK, resp)
+		},
+	}
+}
@@ -0,0 +1,98 @@
+package recipes_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/recipes"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MatchPantry", func() {
+	var fakeService *mockPantryMatcher
+
+	BeforeEach(func() {
+		fakeService = &mockPantryMatcher{
+			matchPantry: func(ctx context.Context, userID int64, pantry *services.PantryInput) ([]*services.PantryMatch, error) {
+				return []*services.PantryMatch{}, nil
+			},
+		}
+	})
+
+	match := func(url string) *api.Response {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		return recipes.MatchPantry(fakeService).Handle(&api.Request{
+			Req:    req,
+			UserID: 2,
+		})
+	}
+
+	It("returns the recipes with the ingredients they are missing", func() {
+		fakeService.matchPantry = func(ctx context.Context, userID int64, pantry *services.PantryInput) ([]*services.PantryMatch, error) {
+			Expect(userID).To(BeEquivalentTo(2))
+			Expect(pantry).To(Equal(&services.PantryInput{
+				Ingredients:   []string{"eggs", "flour"},
+				IgnoreStaples: true,
+				Limit:         5,
+			}))
+
+			return []*services.PantryMatch{{
+				ID:       1,
+				Name:     "Pancakes",
+				Required: 3,
+				Covered:  2,
+				Missing:  []string{"Milk"},
+			}}, nil
+		}
+
+		resp := match("/recipes/pantry?ingredient=eggs&ingredient=flour&ingredient=+&ignore_staples=true&limit=5")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "recipes": [{
+                "id": 1,
+                "name": "Pancakes",
+                "required": 3,
+                "covered": 2,
+                "missing": ["Milk"]
+            }]
+        }`))
+	})
+
+	It("returns bad request for invalid parameters", func() {
+		resp := match("/recipes/pantry?ignore_staples=maybe&limit=1000")
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		apiErr := resp.Body.(*api.Error)
+		Expect(apiErr.Fields).To(HaveKey("ingredient"))
+		Expect(apiErr.Fields).To(HaveKey("ignore_staples"))
+		Expect(apiErr.Fields).To(HaveKey("limit"))
+	})
+
+	It("returns an error if the service call fails", func() {
+		fakeService.matchPantry = func(ctx context.Context, userID int64, pantry *services.PantryInput) ([]*services.PantryMatch, error) {
+			return nil, errors.New("some error")
+		}
+
+		resp := match("/recipes/pantry?ingredient=eggs")
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+})
+
+type mockPantryMatcher struct {
+	matchPantry func(ctx context.Context, userID int64, pantry *services.PantryInput) ([]*services.PantryMatch, error)
+}
+
+func (m *mockPantryMatcher) MatchPantry(ctx context.Context, userID int64, pantry *services.PantryInput) ([]*services.PantryMatch, error) {
+	return m.matchPantry(ctx, userID, pantry)
+}
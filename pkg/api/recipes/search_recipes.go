@@ -0,0 +1,91 @@
+package recipes
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+)
+
+type RecipeSearchResponse struct {
+	Results []*RecipeSearchResultResponse `json:"results"`
+}
+
+type RecipeSearchResultResponse struct {
+	ID          int64                `json:"id"`
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Highlights  []*HighlightResponse `json:"highlights"`
+}
+
+type HighlightResponse struct {
+	Field string `json:"field"`
+	Text  string `json:"text"`
+}
+
+type RecipeSearcher interface {
+	SearchRecipes(ctx context.Context, userID int64, query string, limit int) ([]*services.SearchResult, error)
+}
+
+func SearchRecipes(service RecipeSearcher) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "recipes/search",
+		Method: http.MethodGet,
+		Auth:   true,
+		Handle: func(r *api.Request) *api.Response {
+			query := r.Req.URL.Query()
+			validationErrors := make(map[string]string)
+
+			q := strings.TrimSpace(query.Get("q"))
+			if q == "" {
+				validationErrors["q"] = "Required"
+			}
+
+			limit := services.DefaultRecipePageSize
+			if value := query.Get("limit"); value != "" {
+				var err error
+				limit, err = strconv.Atoi(value)
+				if err != nil || limit < 1 || limit > services.MaxRecipePageSize {
+					validationErrors["limit"] = fmt.Sprintf("Must be a number from 1 to %d", services.MaxRecipePageSize)
+				}
+			}
+
+			if len(validationErrors) > 0 {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(validationErrors))
+			}
+
+			results, err := service.SearchRecipes(r.Req.Context(), r.UserID, q, limit)
+			if err != nil {
+				slog.ErrorContext(r.Req.Context(), "Error searching recipes", "error", err)
+				return api.NewResponse(http.StatusInternalServerError, nil)
+			}
+
+			resp := &RecipeSearchResponse{
+				Results: make([]*RecipeSearchResultResponse, len(results)),
+			}
+
+			for i, result := range results {
+				resp.Results[i] = &RecipeSearchResultResponse{
+					ID:          result.ID,
+					Name:        result.Name,
+					Description: result.Description,
+					Highlights:  make([]*HighlightResponse, len(result.Highlights)),
+				}
+
+				for j, highlight := range result.Highlights {
+					resp.Results[i].Highlights[j] = &HighlightResponse{
+						Field: highlight.Field,
+						Text:  highlight.Text,
+					}
+				}
+			}
+
+			return api.NewResponse(http.StatusOK, resp)
+		},
+	}
+}
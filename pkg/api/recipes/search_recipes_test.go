@@ -0,0 +1,109 @@
+package recipes_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/recipes"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SearchRecipes", func() {
+	var fakeService *mockRecipeSearcher
+
+	BeforeEach(func() {
+		fakeService = &mockRecipeSearcher{
+			searchRecipes: func(ctx context.Context, userID int64, query string, limit int) ([]*services.SearchResult, error) {
+				return []*services.SearchResult{}, nil
+			},
+		}
+	})
+
+	search := func(url string) *api.Response {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		return recipes.SearchRecipes(fakeService).Handle(&api.Request{
+			Req:    req,
+			UserID: 2,
+		})
+	}
+
+	It("returns the matching recipes with highlights", func() {
+		fakeService.searchRecipes = func(ctx context.Context, userID int64, query string, limit int) ([]*services.SearchResult, error) {
+			Expect(userID).To(BeEquivalentTo(2))
+			Expect(query).To(Equal("garlic bread"))
+			Expect(limit).To(Equal(services.DefaultRecipePageSize))
+
+			return []*services.SearchResult{{
+				ID:          1,
+				Name:        "Garlic Bread",
+				Description: "Crusty",
+				Highlights: []*services.SearchHighlight{
+					{Field: "name", Text: "<mark>Garlic</mark> <mark>Bread</mark>"},
+				},
+			}}, nil
+		}
+
+		resp := search("/recipes/search?q=garlic+bread")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "results": [{
+                "id": 1,
+                "name": "Garlic Bread",
+                "description": "Crusty",
+                "highlights": [{
+                    "field": "name",
+                    "text": "<mark>Garlic</mark> <mark>Bread</mark>"
+                }]
+            }]
+        }`))
+	})
+
+	It("passes the limit to the service", func() {
+		var received int
+		fakeService.searchRecipes = func(ctx context.Context, userID int64, query string, limit int) ([]*services.SearchResult, error) {
+			received = limit
+			return []*services.SearchResult{}, nil
+		}
+
+		resp := search("/recipes/search?q=garlic&limit=5")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(received).To(Equal(5))
+	})
+
+	It("returns bad request without a query or with an invalid limit", func() {
+		resp := search("/recipes/search?q=+&limit=abc")
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		apiErr := resp.Body.(*api.Error)
+		Expect(apiErr.Fields).To(HaveKey("q"))
+		Expect(apiErr.Fields).To(HaveKey("limit"))
+	})
+
+	It("returns an error if the search fails", func() {
+		fakeService.searchRecipes = func(ctx context.Context, userID int64, query string, limit int) ([]*services.SearchResult, error) {
+			return nil, errors.New("some error")
+		}
+
+		resp := search("/recipes/search?q=garlic")
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+})
+
+type mockRecipeSearcher struct {
+	searchRecipes func(ctx context.Context, userID int64, query string, limit int) ([]*services.SearchResult, error)
+}
+
+func (m *mockRecipeSearcher) SearchRecipes(ctx context.Context, userID int64, query string, limit int) ([]*services.SearchResult, error) {
+	return m.searchRecipes(ctx, userID, query, limit)
+}
@@ -0,0 +1,58 @@
+package recipes
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+)
+
+type RecipeUpdater interface {
+	UpdateRecipe(ctx context.Context, recipeID, userID int64, recipe *services.RecipeInput) error
+}
+
+func UpdateRecipe(service RecipeUpdater) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "recipes/{id}",
+		Method: http.MethodPut,
+		Auth:   true,
+		Handle: func(r *api.Request) *api.Response {
+			recipeID, err := strconv.ParseInt(r.Req.PathValue("id"), 10, 64)
+			if err != nil {
+				slog.WarnContext(r.Req.Context(), "Update recipe endpoint invalid id", "error", err)
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			var recipe CreateRecipeRequest
+			if err := r.Decode(&recipe); err != nil {
+				slog.WarnContext(r.Req.Context(), "Error decoding json body for update recipe", "error", err)
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			validationErrors := recipe.Validate()
+			if len(validationErrors) > 0 {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(validationErrors))
+			}
+
+			err = service.UpdateRecipe(r.Req.Context(), recipeID, r.UserID, recipe.toRecipeInput())
+			if err != nil {
+				if errors.Is(err, services.ErrNotFound) {
+					return api.NewResponse(http.StatusNotFound, nil)
+				}
+
+				if errors.Is(err, services.ErrForbidden) {
+					return api.NewResponse(http.StatusForbidden, nil)
+				}
+
+				slog.ErrorContext(r.Req.Context(), "Error updating recipe", "error", err)
+				return api.NewResponse(http.StatusInternalServerError, nil)
+			}
+
+			return api.NewResponse(http.StatusOK, nil)
+		},
+	}
+}
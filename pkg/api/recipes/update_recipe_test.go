@@ -0,0 +1,187 @@
+package recipes_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/recipes"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UpdateRecipe", func() {
+	var body []byte
+
+	BeforeEach(func() {
+		body = []byte(`{
+            "name": "Root Beer Float",
+            "description": "Delicious",
+            "servings": 2,
+            "total_time": "5 m",
+            "ingredients": [{
+                "name": "Root Beer",
+                "amount": "2",
+                "unit": "Cups",
+                "order_num": 1
+            }],
+            "steps": [{
+                "instructions": "Pour.",
+                "order_num": 1
+            }]
+        }`)
+	})
+
+	It("updates the recipe", func() {
+		var (
+			updatedID     int64
+			updatedUserID int64
+			updated       *services.RecipeInput
+		)
+
+		fakeService := &mockRecipeUpdater{
+			updateRecipe: func(ctx context.Context, recipeID, userID int64, recipe *services.RecipeInput) error {
+				updatedID = recipeID
+				updatedUserID = userID
+				updated = recipe
+				return nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPut, "/recipes/1", bytes.NewBuffer(body))
+		req.SetPathValue("id", "1")
+
+		resp := recipes.UpdateRecipe(fakeService).Handle(&api.Request{
+			Req:    req,
+			UserID: 2,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		Expect(updatedID).To(BeEquivalentTo(1))
+		Expect(updatedUserID).To(BeEquivalentTo(2))
+		Expect(updated.Name).To(Equal("Root Beer Float"))
+		Expect(*updated.Servings).To(Equal(2))
+		Expect(updated.PrepTime).To(BeNil())
+		Expect(updated.Ingredients).To(HaveLen(1))
+		Expect(updated.Ingredients[0].Name).To(Equal("Root Beer"))
+		Expect(updated.Steps).To(HaveLen(1))
+		Expect(updated.Steps[0].Instructions).To(Equal("Pour."))
+	})
+
+	It("returns any validation errors", func() {
+		fakeService := &mockRecipeUpdater{
+			updateRecipe: func(ctx context.Context, recipeID, userID int64, recipe *services.RecipeInput) error {
+				return nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPut, "/recipes/1", bytes.NewBuffer([]byte("{}")))
+		req.SetPathValue("id", "1")
+
+		resp := recipes.UpdateRecipe(fakeService).Handle(&api.Request{
+			Req:    req,
+			UserID: 2,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "code": "bad_request",
+            "message": "The request has invalid fields",
+            "errors": {
+                "name": "Required",
+                "description": "Required",
+                "servings": "Required"
+            }
+        }`))
+	})
+
+	It("returns not found if the recipe does not exist", func() {
+		fakeService := &mockRecipeUpdater{
+			updateRecipe: func(ctx context.Context, recipeID, userID int64, recipe *services.RecipeInput) error {
+				return services.ErrNotFound
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPut, "/recipes/1", bytes.NewBuffer(body))
+		req.SetPathValue("id", "1")
+
+		resp := recipes.UpdateRecipe(fakeService).Handle(&api.Request{
+			Req:    req,
+			UserID: 2,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("returns forbidden if the recipe belongs to another user", func() {
+		fakeService := &mockRecipeUpdater{
+			updateRecipe: func(ctx context.Context, recipeID, userID int64, recipe *services.RecipeInput) error {
+				return services.ErrForbidden
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPut, "/recipes/1", bytes.NewBuffer(body))
+		req.SetPathValue("id", "1")
+
+		resp := recipes.UpdateRecipe(fakeService).Handle(&api.Request{
+			Req:    req,
+			UserID: 2,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	})
+
+	It("returns an error if the service call fails", func() {
+		fakeService := &mockRecipeUpdater{
+			updateRecipe: func(ctx context.Context, recipeID, userID int64, recipe *services.RecipeInput) error {
+				return errors.New("some error")
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPut, "/recipes/1", bytes.NewBuffer(body))
+		req.SetPathValue("id", "1")
+
+		resp := recipes.UpdateRecipe(fakeService).Handle(&api.Request{
+			Req:    req,
+			UserID: 2,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+
+	It("returns an error if the provided route variable is not a number", func() {
+		fakeService := &mockRecipeUpdater{
+			updateRecipe: func(ctx context.Context, recipeID, userID int64, recipe *services.RecipeInput) error {
+				return nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPut, "/recipes/not-a-number", bytes.NewBuffer(body))
+		req.SetPathValue("id", "not-a-number")
+
+		resp := recipes.UpdateRecipe(fakeService).Handle(&api.Request{
+			Req:    req,
+			UserID: 2,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
+
+type mockRecipeUpdater struct {
+	updateRecipe func(ctx context.Context, recipeID, userID int64, recipe *services.RecipeInput) error
+}
+
+func (m *mockRecipeUpdater) UpdateRecipe(ctx context.Context, recipeID, userID int64, recipe *services.RecipeInput) error {
+	return m.updateRecipe(ctx, recipeID, userID, recipe)
+}
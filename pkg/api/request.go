@@ -2,12 +2,20 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
+	"net"
 	"net/http"
+	"net/netip"
+	"strings"
 )
 
 type Request struct {
 	Req    *http.Request
 	UserID int64
+
+	// TrustedProxies are the proxies whose X-Forwarded-For header ClientIP
+	// believes.
+	TrustedProxies []netip.Prefix
 }
 
 func (r *Request) Decode(out interface{}) error {
@@ -19,3 +27,86 @@ func (r *Request) Decode(out interface{}) error {
 
 	return nil
 }
+
+// ClientIP returns the IP address the request came from, without the port.
+// Requests from a trusted proxy come from the right-most X-Forwarded-For hop
+// that is not a trusted proxy, as the hops to its left were sent by the
+// client and could be anything.
+func (r *Request) ClientIP() string {
+	host, _, err := net.SplitHostPort(r.Req.RemoteAddr)
+	if err != nil {
+		host = r.Req.RemoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !r.trustedProxy(addr.Unmap()) {
+		return host
+	}
+
+	client := addr.Unmap()
+	hops := forwardedFor(r.Req.Header)
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(hops[i])
+		if err != nil {
+			break
+		}
+
+		client = hop.Unmap()
+		if !r.trustedProxy(client) {
+			break
+		}
+	}
+
+	return client.String()
+}
+
+func (r *Request) trustedProxy(addr netip.Addr) bool {
+	for _, proxy := range r.TrustedProxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// forwardedFor returns the hops in the request's X-Forwarded-For headers,
+// from the client to the last proxy.
+func forwardedFor(header http.Header) []string {
+	var hops []string
+	for _, value := range header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(value, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+
+	return hops
+}
+
+// ParseTrustedProxies parses proxy addresses, each either an IP address or a
+// CIDR range such as 10.0.0.0/8.
+func ParseTrustedProxies(proxies []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+
+		if strings.Contains(proxy, "/") {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy '%s': %s", proxy, err.Error())
+			}
+
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy '%s': %s", proxy, err.Error())
+		}
+
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes, nil
+}
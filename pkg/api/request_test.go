@@ -0,0 +1,117 @@
+package api_test
+
+import (
+	"net/http"
+	"net/netip"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Request", func() {
+	Describe("ClientIP", func() {
+		var (
+			httpReq *http.Request
+			proxies []netip.Prefix
+		)
+
+		BeforeEach(func() {
+			var err error
+			httpReq, err = http.NewRequest(http.MethodGet, "/api/v1/test", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			proxies, err = api.ParseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1"})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the remote address without its port", func() {
+			httpReq.RemoteAddr = "198.51.100.7:54321"
+
+			req := &api.Request{Req: httpReq, TrustedProxies: proxies}
+			Expect(req.ClientIP()).To(Equal("198.51.100.7"))
+		})
+
+		It("ignores X-Forwarded-For from a client that is not a trusted proxy", func() {
+			httpReq.RemoteAddr = "198.51.100.7:54321"
+			httpReq.Header.Set("X-Forwarded-For", "203.0.113.9")
+
+			req := &api.Request{Req: httpReq, TrustedProxies: proxies}
+			Expect(req.ClientIP()).To(Equal("198.51.100.7"))
+		})
+
+		It("ignores X-Forwarded-For when no proxies are trusted", func() {
+			httpReq.RemoteAddr = "10.0.0.2:54321"
+			httpReq.Header.Set("X-Forwarded-For", "203.0.113.9")
+
+			req := &api.Request{Req: httpReq}
+			Expect(req.ClientIP()).To(Equal("10.0.0.2"))
+		})
+
+		It("returns the hop before a trusted proxy", func() {
+			httpReq.RemoteAddr = "10.0.0.2:54321"
+			httpReq.Header.Set("X-Forwarded-For", "203.0.113.9")
+
+			req := &api.Request{Req: httpReq, TrustedProxies: proxies}
+			Expect(req.ClientIP()).To(Equal("203.0.113.9"))
+		})
+
+		It("skips every trusted proxy in a chain", func() {
+			httpReq.RemoteAddr = "10.0.0.2:54321"
+			httpReq.Header.Add("X-Forwarded-For", "203.0.113.9, 192.0.2.1")
+			httpReq.Header.Add("X-Forwarded-For", "10.1.2.3")
+
+			req := &api.Request{Req: httpReq, TrustedProxies: proxies}
+			Expect(req.ClientIP()).To(Equal("203.0.113.9"))
+		})
+
+		It("returns the right-most untrusted hop when the client spoofs earlier hops", func() {
+			httpReq.RemoteAddr = "10.0.0.2:54321"
+			httpReq.Header.Set("X-Forwarded-For", "10.9.9.9, 1.2.3.4, 203.0.113.9")
+
+			req := &api.Request{Req: httpReq, TrustedProxies: proxies}
+			Expect(req.ClientIP()).To(Equal("203.0.113.9"))
+		})
+
+		It("returns the last trusted proxy if a hop is not an IP address", func() {
+			httpReq.RemoteAddr = "10.0.0.2:54321"
+			httpReq.Header.Set("X-Forwarded-For", "203.0.113.9, unknown, 192.0.2.1")
+
+			req := &api.Request{Req: httpReq, TrustedProxies: proxies}
+			Expect(req.ClientIP()).To(Equal("192.0.2.1"))
+		})
+
+		It("returns the proxy if there is no X-Forwarded-For header", func() {
+			httpReq.RemoteAddr = "10.0.0.2:54321"
+
+			req := &api.Request{Req: httpReq, TrustedProxies: proxies}
+			Expect(req.ClientIP()).To(Equal("10.0.0.2"))
+		})
+
+		It("matches IPv4 proxies connecting over IPv6", func() {
+			httpReq.RemoteAddr = "[::ffff:10.0.0.2]:54321"
+			httpReq.Header.Set("X-Forwarded-For", "2001:db8::1")
+
+			req := &api.Request{Req: httpReq, TrustedProxies: proxies}
+			Expect(req.ClientIP()).To(Equal("2001:db8::1"))
+		})
+	})
+
+	Describe("ParseTrustedProxies", func() {
+		It("parses IP addresses and CIDR ranges", func() {
+			proxies, err := api.ParseTrustedProxies([]string{"192.0.2.1", " 10.1.2.3/8", "2001:db8::/32"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(proxies).To(Equal([]netip.Prefix{
+				netip.MustParsePrefix("192.0.2.1/32"),
+				netip.MustParsePrefix("10.0.0.0/8"),
+				netip.MustParsePrefix("2001:db8::/32"),
+			}))
+		})
+
+		It("returns an error for an invalid proxy", func() {
+			_, err := api.ParseTrustedProxies([]string{"proxy.example.com"})
+			Expect(err).To(MatchError(ContainSubstring("invalid trusted proxy 'proxy.example.com'")))
+		})
+	})
+})
@@ -0,0 +1,79 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+)
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+func (p *ChangePasswordRequest) Validate() map[string]string {
+	errors := make(map[string]string)
+
+	if len(p.CurrentPassword) == 0 {
+		errors["current_password"] = "Required"
+	}
+
+	if passwordErrors := validateNewPassword(p.NewPassword); passwordErrors != "" {
+		errors["new_password"] = passwordErrors
+	}
+
+	return errors
+}
+
+type PasswordChanger interface {
+	ChangePassword(ctx context.Context, userID int64, currentPassword, newPassword string) error
+}
+
+// ChangePassword sets a new password for the logged in user. Every session,
+// including the one that made the request, is logged out afterward.
+func ChangePassword(service PasswordChanger) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "users/password",
+		Method: http.MethodPost,
+		Auth:   true,
+		Handle: func(r *api.Request) *api.Response {
+			var passwords ChangePasswordRequest
+			if err := r.Decode(&passwords); err != nil {
+				slog.WarnContext(r.Req.Context(), "Error decoding json body for password change")
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			validationErrors := passwords.Validate()
+			if len(validationErrors) > 0 {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(validationErrors))
+			}
+
+			err := service.ChangePassword(r.Req.Context(), r.UserID, passwords.CurrentPassword, passwords.NewPassword)
+			if errors.Is(err, services.ErrIncorrectPassword) {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(map[string]string{
+					"current_password": "Incorrect password",
+				}))
+			}
+
+			if err != nil {
+				slog.ErrorContext(r.Req.Context(), "Error changing password", "error", err)
+				return api.NewResponse(http.StatusInternalServerError, nil)
+			}
+
+			return api.NewResponse(http.StatusNoContent, nil)
+		},
+	}
+}
+
+func validateNewPassword(password string) string {
+	if len(password) == 0 {
+		return "Required"
+	}
+
+	return strings.Join(validatePassword(password), ", ")
+}
@@ -0,0 +1,126 @@
+package users_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("change password", func() {
+	changePassword := func(service users.PasswordChanger, body string) *api.Response {
+		req, err := http.NewRequest(http.MethodPost, "/users/password", bytes.NewBufferString(body))
+		Expect(err).ToNot(HaveOccurred())
+
+		return users.ChangePassword(service).Handle(&api.Request{
+			Req:    req,
+			UserID: 10,
+		})
+	}
+
+	It("changes the password of the logged in user", func() {
+		var changedFor int64
+		var current, updated string
+		fakeChanger := &mockPasswordChanger{
+			changePassword: func(ctx context.Context, userID int64, currentPassword, newPassword string) error {
+				changedFor = userID
+				current = currentPassword
+				updated = newPassword
+				return nil
+			},
+		}
+
+		resp := changePassword(fakeChanger, `{
+            "current_password": "Pa3$word123",
+            "new_password": "N3w-password"
+        }`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+		Expect(changedFor).To(Equal(int64(10)))
+		Expect(current).To(Equal("Pa3$word123"))
+		Expect(updated).To(Equal("N3w-password"))
+	})
+
+	It("returns the password rules the new password breaks", func() {
+		fakeChanger := &mockPasswordChanger{
+			changePassword: func(ctx context.Context, userID int64, currentPassword, newPassword string) error {
+				Fail("password should not be changed")
+				return nil
+			},
+		}
+
+		resp := changePassword(fakeChanger, `{
+            "current_password": "",
+            "new_password": "password"
+        }`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "code": "bad_request",
+            "message": "The request has invalid fields",
+            "errors": {
+                "current_password": "Required",
+                "new_password": "Uppercase letter missing, Numeric character missing, Special character missing"
+            }
+        }`))
+	})
+
+	It("returns a bad request if the current password is incorrect", func() {
+		fakeChanger := &mockPasswordChanger{
+			changePassword: func(ctx context.Context, userID int64, currentPassword, newPassword string) error {
+				return services.ErrIncorrectPassword
+			},
+		}
+
+		resp := changePassword(fakeChanger, `{
+            "current_password": "wrong",
+            "new_password": "N3w-password"
+        }`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "code": "bad_request",
+            "message": "The request has invalid fields",
+            "errors": {
+                "current_password": "Incorrect password"
+            }
+        }`))
+	})
+
+	It("returns an internal server error if the change fails", func() {
+		fakeChanger := &mockPasswordChanger{
+			changePassword: func(ctx context.Context, userID int64, currentPassword, newPassword string) error {
+				return errors.New("db error")
+			},
+		}
+
+		resp := changePassword(fakeChanger, `{
+            "current_password": "Pa3$word123",
+            "new_password": "N3w-password"
+        }`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+})
+
+type mockPasswordChanger struct {
+	changePassword func(ctx context.Context, userID int64, currentPassword, newPassword string) error
+}
+
+func (m *mockPasswordChanger) ChangePassword(ctx context.Context, userID int64, currentPassword, newPassword string) error {
+	return m.changePassword(ctx, userID, currentPassword, newPassword)
+}
@@ -0,0 +1,63 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+)
+
+type ConfirmTwoFactorRequest struct {
+	Code string `json:"code"`
+}
+
+type TwoFactorConfirmer interface {
+	Confirm(ctx context.Context, userID int64, code string) ([]string, error)
+}
+
+// ConfirmTwoFactor turns on two factor authentication once the user enters a
+// code from their authenticator. The response holds the only copy of the
+// recovery codes.
+func ConfirmTwoFactor(service TwoFactorConfirmer) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "users/two-factor/confirm",
+		Method: http.MethodPost,
+		Auth:   true,
+		Handle: func(r *api.Request) *api.Response {
+			var confirm ConfirmTwoFactorRequest
+			if err := r.Decode(&confirm); err != nil {
+				slog.WarnContext(r.Req.Context(), "Error decoding json body for two factor confirmation")
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			if len(confirm.Code) == 0 {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(map[string]string{"code": "Required"}))
+			}
+
+			codes, err := service.Confirm(r.Req.Context(), r.UserID, confirm.Code)
+			if errors.Is(err, services.ErrInvalidTwoFactorCode) {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(map[string]string{"code": "Invalid code"}))
+			}
+
+			if errors.Is(err, services.ErrTwoFactorNotEnrolled) {
+				return api.ErrorResponse(r.Req.Context(), api.BadRequest("Two factor authentication has not been set up"))
+			}
+
+			if errors.Is(err, services.ErrTwoFactorAlreadyEnabled) {
+				return api.ErrorResponse(r.Req.Context(), api.Conflict("Two factor authentication is already enabled"))
+			}
+
+			if err != nil {
+				slog.ErrorContext(r.Req.Context(), "Error confirming two factor authentication", "error", err)
+				return api.NewResponse(http.StatusInternalServerError, nil)
+			}
+
+			return api.NewResponse(http.StatusOK, &TwoFactorResponse{
+				RecoveryCodes: codes,
+			})
+		},
+	}
+}
@@ -0,0 +1,108 @@
+package users_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("confirm two factor", func() {
+	confirm := func(service users.TwoFactorConfirmer, body string) *api.Response {
+		req, err := http.NewRequest(http.MethodPost, "/users/two-factor/confirm", bytes.NewBufferString(body))
+		Expect(err).ToNot(HaveOccurred())
+
+		return users.ConfirmTwoFactor(service).Handle(&api.Request{
+			Req:    req,
+			UserID: 10,
+		})
+	}
+
+	It("returns the recovery codes once two factor is enabled", func() {
+		fakeConfirmer := &mockTwoFactorConfirmer{
+			confirm: func(ctx context.Context, userID int64, code string) ([]string, error) {
+				Expect(userID).To(Equal(int64(10)))
+				Expect(code).To(Equal("123456"))
+				return []string{"abcd-efgh-ijkl-mnop"}, nil
+			},
+		}
+
+		resp := confirm(fakeConfirmer, `{"code": "123456"}`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{"recovery_codes": ["abcd-efgh-ijkl-mnop"]}`))
+	})
+
+	It("returns a bad request for a missing or invalid code", func() {
+		fakeConfirmer := &mockTwoFactorConfirmer{
+			confirm: func(ctx context.Context, userID int64, code string) ([]string, error) {
+				return nil, services.ErrInvalidTwoFactorCode
+			},
+		}
+
+		resp := confirm(fakeConfirmer, `{"code": ""}`)
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		resp = confirm(fakeConfirmer, `{"code": "000000"}`)
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{"code": "bad_request", "message": "The request has invalid fields", "errors": {"code": "Invalid code"}}`))
+	})
+
+	It("returns a bad request if two factor has not been set up", func() {
+		fakeConfirmer := &mockTwoFactorConfirmer{
+			confirm: func(ctx context.Context, userID int64, code string) ([]string, error) {
+				return nil, services.ErrTwoFactorNotEnrolled
+			},
+		}
+
+		resp := confirm(fakeConfirmer, `{"code": "123456"}`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("returns a conflict if two factor is already enabled", func() {
+		fakeConfirmer := &mockTwoFactorConfirmer{
+			confirm: func(ctx context.Context, userID int64, code string) ([]string, error) {
+				return nil, services.ErrTwoFactorAlreadyEnabled
+			},
+		}
+
+		resp := confirm(fakeConfirmer, `{"code": "123456"}`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+	})
+
+	It("returns an internal server error if confirmation fails", func() {
+		fakeConfirmer := &mockTwoFactorConfirmer{
+			confirm: func(ctx context.Context, userID int64, code string) ([]string, error) {
+				return nil, errors.New("db error")
+			},
+		}
+
+		resp := confirm(fakeConfirmer, `{"code": "123456"}`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+})
+
+type mockTwoFactorConfirmer struct {
+	confirm func(ctx context.Context, userID int64, code string) ([]string, error)
+}
+
+func (m *mockTwoFactorConfirmer) Confirm(ctx context.Context, userID int64, code string) ([]string, error) {
+	return m.confirm(ctx, userID, code)
+}
@@ -0,0 +1,47 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+)
+
+type TwoFactorResponse struct {
+	Secret        string   `json:"secret,omitempty"`
+	URI           string   `json:"uri,omitempty"`
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+type TwoFactorEnroller interface {
+	Enroll(ctx context.Context, userID int64) (*services.TwoFactorEnrollment, error)
+}
+
+// EnrollTwoFactor creates a new TOTP secret for the logged in user. Two factor
+// authentication stays off until the secret is confirmed with ConfirmTwoFactor.
+func EnrollTwoFactor(service TwoFactorEnroller) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "users/two-factor",
+		Method: http.MethodPost,
+		Auth:   true,
+		Handle: func(r *api.Request) *api.Response {
+			enrollment, err := service.Enroll(r.Req.Context(), r.UserID)
+			if errors.Is(err, services.ErrTwoFactorAlreadyEnabled) {
+				return api.ErrorResponse(r.Req.Context(), api.Conflict("Two factor authentication is already enabled"))
+			}
+
+			if err != nil {
+				slog.ErrorContext(r.Req.Context(), "Error enrolling in two factor authentication", "error", err)
+				return api.NewResponse(http.StatusInternalServerError, nil)
+			}
+
+			return api.NewResponse(http.StatusOK, &TwoFactorResponse{
+				Secret: enrollment.Secret,
+				URI:    enrollment.URI,
+			})
+		},
+	}
+}
@@ -0,0 +1,82 @@
+package users_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("enroll two factor", func() {
+	enroll := func(service users.TwoFactorEnroller) *api.Response {
+		req, err := http.NewRequest(http.MethodPost, "/users/two-factor", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		return users.EnrollTwoFactor(service).Handle(&api.Request{
+			Req:    req,
+			UserID: 10,
+		})
+	}
+
+	It("returns a new secret for the logged in user", func() {
+		fakeEnroller := &mockTwoFactorEnroller{
+			enroll: func(ctx context.Context, userID int64) (*services.TwoFactorEnrollment, error) {
+				Expect(userID).To(Equal(int64(10)))
+				return &services.TwoFactorEnrollment{
+					Secret: "GEZDGNBVGY3TQOJQ",
+					URI:    "otpauth://totp/My%20Recipe%20Library:cook?secret=GEZDGNBVGY3TQOJQ",
+				}, nil
+			},
+		}
+
+		resp := enroll(fakeEnroller)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "secret": "GEZDGNBVGY3TQOJQ",
+            "uri": "otpauth://totp/My%20Recipe%20Library:cook?secret=GEZDGNBVGY3TQOJQ"
+        }`))
+	})
+
+	It("returns a conflict if two factor is already enabled", func() {
+		fakeEnroller := &mockTwoFactorEnroller{
+			enroll: func(ctx context.Context, userID int64) (*services.TwoFactorEnrollment, error) {
+				return nil, services.ErrTwoFactorAlreadyEnabled
+			},
+		}
+
+		resp := enroll(fakeEnroller)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+	})
+
+	It("returns an internal server error if enrollment fails", func() {
+		fakeEnroller := &mockTwoFactorEnroller{
+			enroll: func(ctx context.Context, userID int64) (*services.TwoFactorEnrollment, error) {
+				return nil, errors.New("db error")
+			},
+		}
+
+		resp := enroll(fakeEnroller)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+})
+
+type mockTwoFactorEnroller struct {
+	enroll func(ctx context.Context, userID int64) (*services.TwoFactorEnrollment, error)
+}
+
+func (m *mockTwoFactorEnroller) Enroll(ctx context.Context, userID int64) (*services.TwoFactorEnrollment, error) {
+	return m.enroll(ctx, userID)
+}
@@ -0,0 +1,67 @@
+package users
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+	"github.com/iplay88keys/my-recipe-library/pkg/token"
+)
+
+type SessionListResponse struct {
+	Sessions []*SessionResponse `json:"sessions"`
+}
+
+type SessionResponse struct {
+	ID        string `json:"id"`
+	Device    string `json:"device"`
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+	CreatedAt string `json:"created_at"`
+	Current   bool   `json:"current"`
+}
+
+type SessionLister interface {
+	ValidateToken(r *http.Request) (*token.AccessDetails, error)
+	ListSessions(ctx context.Context, userID int64, currentAccessUuid string) ([]*services.Session, error)
+}
+
+func ListSessions(service SessionLister) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "users/sessions",
+		Method: http.MethodGet,
+		Auth:   true,
+		Handle: func(r *api.Request) *api.Response {
+			details, err := service.ValidateToken(r.Req)
+			if err != nil {
+				return api.NewResponse(http.StatusUnauthorized, nil)
+			}
+
+			sessions, err := service.ListSessions(r.Req.Context(), r.UserID, details.AccessUuid)
+			if err != nil {
+				slog.ErrorContext(r.Req.Context(), "Error listing sessions", "error", err)
+				return api.NewResponse(http.StatusInternalServerError, nil)
+			}
+
+			resp := &SessionListResponse{
+				Sessions: make([]*SessionResponse, 0, len(sessions)),
+			}
+
+			for _, session := range sessions {
+				resp.Sessions = append(resp.Sessions, &SessionResponse{
+					ID:        session.ID,
+					Device:    session.Device,
+					IP:        session.IP,
+					UserAgent: session.UserAgent,
+					CreatedAt: session.CreatedAt.UTC().Format(time.RFC3339),
+					Current:   session.Current,
+				})
+			}
+
+			return api.NewResponse(http.StatusOK, resp)
+		},
+	}
+}
@@ -0,0 +1,138 @@
+package users_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+	"github.com/iplay88keys/my-recipe-library/pkg/token"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("list sessions", func() {
+	validToken := func(r *http.Request) (*token.AccessDetails, error) {
+		return &token.AccessDetails{AccessUuid: "current-access-uuid", UserId: 10}, nil
+	}
+
+	It("lists the user's sessions", func() {
+		fakeSessionLister := &mockSessionLister{
+			validateToken: validToken,
+			listSessions: func(ctx context.Context, userID int64, currentAccessUuid string) ([]*services.Session, error) {
+				Expect(userID).To(Equal(int64(10)))
+				Expect(currentAccessUuid).To(Equal("current-access-uuid"))
+
+				return []*services.Session{{
+					ID:        "session-1",
+					Device:    "Kitchen tablet",
+					IP:        "192.0.2.10",
+					UserAgent: "Mozilla/5.0",
+					CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+					Current:   true,
+				}}, nil
+			},
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "/users/sessions", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp := users.ListSessions(fakeSessionLister).Handle(&api.Request{
+			Req:    req,
+			UserID: 10,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "sessions": [{
+                "id": "session-1",
+                "device": "Kitchen tablet",
+                "ip": "192.0.2.10",
+                "user_agent": "Mozilla/5.0",
+                "created_at": "2024-01-02T03:04:05Z",
+                "current": true
+            }]
+        }`))
+	})
+
+	It("returns an empty list if there are no sessions", func() {
+		fakeSessionLister := &mockSessionLister{
+			validateToken: validToken,
+			listSessions: func(ctx context.Context, userID int64, currentAccessUuid string) ([]*services.Session, error) {
+				return nil, nil
+			},
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "/users/sessions", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp := users.ListSessions(fakeSessionLister).Handle(&api.Request{
+			Req:    req,
+			UserID: 10,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{"sessions": []}`))
+	})
+
+	It("returns unauthorized if the token cannot be validated", func() {
+		fakeSessionLister := &mockSessionLister{
+			validateToken: func(r *http.Request) (*token.AccessDetails, error) {
+				return nil, errors.New("validation error")
+			},
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "/users/sessions", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp := users.ListSessions(fakeSessionLister).Handle(&api.Request{
+			Req:    req,
+			UserID: 10,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("returns an internal server error if the sessions cannot be listed", func() {
+		fakeSessionLister := &mockSessionLister{
+			validateToken: validToken,
+			listSessions: func(ctx context.Context, userID int64, currentAccessUuid string) ([]*services.Session, error) {
+				return nil, errors.New("redis error")
+			},
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "/users/sessions", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp := users.ListSessions(fakeSessionLister).Handle(&api.Request{
+			Req:    req,
+			UserID: 10,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+})
+
+type mockSessionLister struct {
+	validateToken func(r *http.Request) (*token.AccessDetails, error)
+	listSessions  func(ctx context.Context, userID int64, currentAccessUuid string) ([]*services.Session, error)
+}
+
+func (m *mockSessionLister) ValidateToken(r *http.Request) (*token.AccessDetails, error) {
+	return m.validateToken(r)
+}
+
+func (m *mockSessionLister) ListSessions(ctx context.Context, userID int64, currentAccessUuid string) ([]*services.Session, error) {
+	return m.listSessions(ctx, userID, currentAccessUuid)
+}
@@ -1,77 +1,174 @@
 package users
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
 	"github.com/iplay88keys/my-recipe-library/pkg/token"
 )
 
 type UserLoginRequest struct {
 	Login    string `json:"login"`
 	Password string `json:"password"`
+	Device   string `json:"device"`
 }
 
 type UserLoginResponse struct {
-	AccessToken  string            `json:"access_token,omitempty"`
-	RefreshToken string            `json:"refresh_token,omitempty"`
-	Errors       map[string]string `json:"errors,omitempty"`
+	AccessToken       string `json:"access_token,omitempty"`
+	RefreshToken      string `json:"refresh_token,omitempty"`
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
 }
 
-type LoginService interface {
-	Verify(login, password string) (bool, int64, error)
+type TokenIssuer interface {
 	CreateToken(userID int64) (*token.Details, error)
-	StoreTokenDetails(userID int64, details *token.Details) error
+	StartSession(ctx context.Context, userID int64, details *token.Details, client *services.SessionClient) error
+}
+
+type LoginFinisher interface {
+	TokenIssuer
+	StartTwoFactorChallenge(ctx context.Context, userID int64, login string) (string, error)
+}
+
+type LoginService interface {
+	LoginFinisher
+	Verify(ctx context.Context, login, password string) (bool, int64, error)
 }
 
-func Login(service LoginService) *api.Endpoint {
+type LoginThrottle interface {
+	RetryAfter(ctx context.Context, login, ip string) (time.Duration, error)
+	RecordFailure(ctx context.Context, login, ip string) error
+	RecordSuccess(ctx context.Context, login string) error
+}
+
+func Login(service LoginService, throttle LoginThrottle) *api.Endpoint {
 	return &api.Endpoint{
-		Path:   "users/login",
-		Method: http.MethodPost,
+		Path:      "users/login",
+		Method:    http.MethodPost,
+		RateLimit: &api.RateLimit{Requests: 20, Window: time.Minute},
 		Handle: func(r *api.Request) *api.Response {
 			var user UserLoginRequest
 			if err := r.Decode(&user); err != nil {
-				fmt.Println("Error decoding json body for login")
+				slog.WarnContext(r.Req.Context(), "Error decoding json body for login")
 				return api.NewResponse(http.StatusBadRequest, nil)
 			}
 
-			valid, userID, err := service.Verify(user.Login, user.Password)
+			retryAfter, err := throttle.RetryAfter(r.Req.Context(), user.Login, r.ClientIP())
 			if err != nil {
-				fmt.Println("Error logging user in")
+				slog.ErrorContext(r.Req.Context(), "Error checking for failed logins", "error", err)
 				return api.NewResponse(http.StatusInternalServerError, nil)
 			}
 
-			if !valid {
-				errors := make(map[string]string)
-				errors["alert"] = "Invalid login credentials"
-
-				resp := &UserLoginResponse{
-					Errors: errors,
-				}
+			if retryAfter > 0 {
+				return tooManyLoginAttempts(r.Req.Context(), retryAfter)
+			}
 
-				fmt.Println("Error validating user for login")
-				return api.NewResponse(http.StatusUnauthorized, resp)
+			valid, userID, err := service.Verify(r.Req.Context(), user.Login, user.Password)
+			if errors.Is(err, services.ErrEmailNotVerified) {
+				return api.ErrorResponse(r.Req.Context(), api.NewError(http.StatusForbidden, "email_not_verified", "Please verify your email address before logging in"))
 			}
 
-			tokenDetails, err := service.CreateToken(userID)
 			if err != nil {
-				fmt.Printf("Error creating token for user login: %s\n", err.Error())
+				slog.ErrorContext(r.Req.Context(), "Error logging user in", "error", err)
 				return api.NewResponse(http.StatusInternalServerError, nil)
 			}
 
-			err = service.StoreTokenDetails(userID, tokenDetails)
+			if !valid {
+				err = throttle.RecordFailure(r.Req.Context(), user.Login, r.ClientIP())
+				if err != nil {
+					slog.ErrorContext(r.Req.Context(), "Error recording failed login", "error", err)
+					return api.NewResponse(http.StatusInternalServerError, nil)
+				}
+
+				slog.ErrorContext(r.Req.Context(), "Error validating user for login")
+				return api.ErrorResponse(r.Req.Context(), api.NewError(http.StatusUnauthorized, "invalid_credentials", "Invalid login credentials"))
+			}
+
+			challenge, err := service.StartTwoFactorChallenge(r.Req.Context(), userID, user.Login)
 			if err != nil {
-				fmt.Printf("Error saving token for user login: %s\n", err.Error())
+				slog.ErrorContext(r.Req.Context(), "Error starting two factor challenge", "error", err)
 				return api.NewResponse(http.StatusInternalServerError, nil)
 			}
 
-			resp := &UserLoginResponse{
-				AccessToken:  tokenDetails.AccessToken,
-				RefreshToken: tokenDetails.RefreshToken,
+			// Failed logins are only forgotten once the second factor has
+			// been passed too, which LoginTwoFactor takes care of.
+			if challenge != "" {
+				return twoFactorRequired(challenge)
+			}
+
+			err = throttle.RecordSuccess(r.Req.Context(), user.Login)
+			if err != nil {
+				slog.ErrorContext(r.Req.Context(), "Error clearing failed logins", "error", err)
+				return api.NewResponse(http.StatusInternalServerError, nil)
 			}
 
-			return api.NewResponse(http.StatusOK, resp)
+			return issueTokens(service, r, userID, user.Device)
 		},
 	}
 }
+
+func tooManyLoginAttempts(ctx context.Context, retryAfter time.Duration) *api.Response {
+	resp := api.ErrorResponse(ctx, api.TooManyRequests("Too many failed login attempts, please try again later"))
+
+	resp.Header = http.Header{}
+	resp.Header.Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+
+	return resp
+}
+
+// finishLogin issues tokens to a user who has proven who they are without a
+// password, unless they still have to pass a two factor challenge.
+func finishLogin(service LoginFinisher, r *api.Request, userID int64, device string) *api.Response {
+	challenge, err := service.StartTwoFactorChallenge(r.Req.Context(), userID, "")
+	if err != nil {
+		slog.ErrorContext(r.Req.Context(), "Error starting two factor challenge", "error", err)
+		return api.NewResponse(http.StatusInternalServerError, nil)
+	}
+
+	if challenge != "" {
+		return twoFactorRequired(challenge)
+	}
+
+	return issueTokens(service, r, userID, device)
+}
+
+func twoFactorRequired(challenge string) *api.Response {
+	return api.NewResponse(http.StatusOK, &UserLoginResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    challenge,
+	})
+}
+
+// issueTokens creates a token pair for a user who has finished logging in and
+// starts a session for the client that made the request.
+func issueTokens(service TokenIssuer, r *api.Request, userID int64, device string) *api.Response {
+	tokenDetails, err := service.CreateToken(userID)
+	if err != nil {
+		slog.ErrorContext(r.Req.Context(), "Error creating token for user login", "error", err)
+		return api.NewResponse(http.StatusInternalServerError, nil)
+	}
+
+	err = service.StartSession(r.Req.Context(), userID, tokenDetails, &services.SessionClient{
+		Device:    device,
+		IP:        r.ClientIP(),
+		UserAgent: r.Req.UserAgent(),
+	})
+	if err != nil {
+		slog.ErrorContext(r.Req.Context(), "Error saving token for user login", "error", err)
+		return api.NewResponse(http.StatusInternalServerError, nil)
+	}
+
+	resp := &UserLoginResponse{
+		AccessToken:  tokenDetails.AccessToken,
+		RefreshToken: tokenDetails.RefreshToken,
+	}
+
+	return api.NewResponse(http.StatusOK, resp)
+}
@@ -2,12 +2,15 @@ package users_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/iplay88keys/my-recipe-library/pkg/api"
 	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
 	"github.com/iplay88keys/my-recipe-library/pkg/token"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -17,7 +20,7 @@ import (
 var _ = Describe("login", func() {
 	It("logs a user in", func() {
 		fakeLoginService := &mockLoginService{
-			verify: func(login, password string) (bool, int64, error) {
+			verify: func(ctx context.Context, login, password string) (bool, int64, error) {
 				return true, 1, nil
 			},
 			createToken: func(userID int64) (*token.Details, error) {
@@ -26,7 +29,7 @@ var _ = Describe("login", func() {
 					RefreshToken: "refresh token",
 				}, nil
 			},
-			storeTokenDetails: func(userID int64, details *token.Details) error {
+			startSession: func(ctx context.Context, userID int64, details *token.Details, client *services.SessionClient) error {
 				return nil
 			},
 		}
@@ -39,7 +42,7 @@ var _ = Describe("login", func() {
 		req, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewBuffer(body))
 		Expect(err).ToNot(HaveOccurred())
 
-		resp := users.Login(fakeLoginService).Handle(&api.Request{
+		resp := users.Login(fakeLoginService, &mockLoginThrottle{}).Handle(&api.Request{
 			Req: req,
 		})
 
@@ -53,9 +56,48 @@ var _ = Describe("login", func() {
         }`))
 	})
 
+	It("starts a session for the client that logged in", func() {
+		var client *services.SessionClient
+		fakeLoginService := &mockLoginService{
+			verify: func(ctx context.Context, login, password string) (bool, int64, error) {
+				return true, 1, nil
+			},
+			createToken: func(userID int64) (*token.Details, error) {
+				return &token.Details{}, nil
+			},
+			startSession: func(ctx context.Context, userID int64, details *token.Details, c *services.SessionClient) error {
+				client = c
+				return nil
+			},
+		}
+
+		body := []byte(`{
+            "login": "username",
+            "password": "Pa3$12345",
+            "device": "Kitchen tablet"
+        }`)
+
+		req, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewBuffer(body))
+		Expect(err).ToNot(HaveOccurred())
+
+		req.RemoteAddr = "192.0.2.10:54321"
+		req.Header.Set("User-Agent", "Mozilla/5.0")
+
+		resp := users.Login(fakeLoginService, &mockLoginThrottle{}).Handle(&api.Request{
+			Req: req,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(client).To(Equal(&services.SessionClient{
+			Device:    "Kitchen tablet",
+			IP:        "192.0.2.10",
+			UserAgent: "Mozilla/5.0",
+		}))
+	})
+
 	It("returns validation info", func() {
 		fakeLoginService := &mockLoginService{
-			verify: func(login, password string) (bool, int64, error) {
+			verify: func(ctx context.Context, login, password string) (bool, int64, error) {
 				return false, 0, nil
 			},
 			createToken: func(userID int64) (*token.Details, error) {
@@ -64,7 +106,7 @@ var _ = Describe("login", func() {
 					RefreshToken: "refresh token",
 				}, nil
 			},
-			storeTokenDetails: func(userID int64, details *token.Details) error {
+			startSession: func(ctx context.Context, userID int64, details *token.Details, client *services.SessionClient) error {
 				return nil
 			},
 		}
@@ -77,7 +119,7 @@ var _ = Describe("login", func() {
 		req, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewBuffer(body))
 		Expect(err).ToNot(HaveOccurred())
 
-		resp := users.Login(fakeLoginService).Handle(&api.Request{
+		resp := users.Login(fakeLoginService, &mockLoginThrottle{}).Handle(&api.Request{
 			Req: req,
 		})
 
@@ -86,7 +128,7 @@ var _ = Describe("login", func() {
 
 	It("returns unauthorized for invalid credentials", func() {
 		fakeLoginService := &mockLoginService{
-			verify: func(login, password string) (bool, int64, error) {
+			verify: func(ctx context.Context, login, password string) (bool, int64, error) {
 				return false, 0, nil
 			},
 			createToken: func(userID int64) (*token.Details, error) {
@@ -95,7 +137,7 @@ var _ = Describe("login", func() {
 					RefreshToken: "refresh token",
 				}, nil
 			},
-			storeTokenDetails: func(userID int64, details *token.Details) error {
+			startSession: func(ctx context.Context, userID int64, details *token.Details, client *services.SessionClient) error {
 				return nil
 			},
 		}
@@ -108,7 +150,7 @@ var _ = Describe("login", func() {
 		req, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewBuffer(body))
 		Expect(err).ToNot(HaveOccurred())
 
-		resp := users.Login(fakeLoginService).Handle(&api.Request{
+		resp := users.Login(fakeLoginService, &mockLoginThrottle{}).Handle(&api.Request{
 			Req: req,
 		})
 
@@ -117,15 +159,47 @@ var _ = Describe("login", func() {
 		respBody, err := json.Marshal(resp.Body)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(respBody).To(MatchJSON(`{
-            "errors": {
-                "alert": "Invalid login credentials"
-            }
+            "code": "invalid_credentials",
+            "message": "Invalid login credentials"
+        }`))
+	})
+
+	It("returns forbidden if the email has not been verified", func() {
+		fakeLoginService := &mockLoginService{
+			verify: func(ctx context.Context, login, password string) (bool, int64, error) {
+				return false, -1, services.ErrEmailNotVerified
+			},
+			createToken: func(userID int64) (*token.Details, error) {
+				Fail("a token should not be created")
+				return nil, nil
+			},
+		}
+
+		body := []byte(`{
+            "login": "username",
+            "password": "Pa3$12345"
+        }`)
+
+		req, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewBuffer(body))
+		Expect(err).ToNot(HaveOccurred())
+
+		resp := users.Login(fakeLoginService, &mockLoginThrottle{}).Handle(&api.Request{
+			Req: req,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "code": "email_not_verified",
+            "message": "Please verify your email address before logging in"
         }`))
 	})
 
 	It("returns internal server error if verification fails", func() {
 		fakeLoginService := &mockLoginService{
-			verify: func(login, password string) (bool, int64, error) {
+			verify: func(ctx context.Context, login, password string) (bool, int64, error) {
 				return false, 0, errors.New("verification failed")
 			},
 			createToken: func(userID int64) (*token.Details, error) {
@@ -134,7 +208,163 @@ var _ = Describe("login", func() {
 					RefreshToken: "refresh token",
 				}, nil
 			},
-			storeTokenDetails: func(userID int64, details *token.Details) error {
+			startSession: func(ctx context.Context, userID int64, details *token.Details, client *services.SessionClient) error {
+				return nil
+			},
+		}
+
+		body := []byte(`{
+            "login": "username",
+            "password": "Pa3$12345"
+        }`)
+
+		req, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewBuffer(body))
+		Expect(err).ToNot(HaveOccurred())
+
+		resp := users.Login(fakeLoginService, &mockLoginThrottle{}).Handle(&api.Request{
+			Req: req,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+
+	It("returns too many requests without checking the password if logins are locked", func() {
+		fakeLoginService := &mockLoginService{
+			verify: func(ctx context.Context, login, password string) (bool, int64, error) {
+				Fail("the password should not be checked")
+				return false, 0, nil
+			},
+		}
+
+		fakeThrottle := &mockLoginThrottle{
+			retryAfter: func(ctx context.Context, login, ip string) (time.Duration, error) {
+				Expect(login).To(Equal("username"))
+				Expect(ip).To(Equal("192.0.2.10"))
+				return 90*time.Second + time.Millisecond, nil
+			},
+		}
+
+		body := []byte(`{
+            "login": "username",
+            "password": "Pa3$12345"
+        }`)
+
+		req, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewBuffer(body))
+		Expect(err).ToNot(HaveOccurred())
+
+		req.RemoteAddr = "192.0.2.10:54321"
+
+		resp := users.Login(fakeLoginService, fakeThrottle).Handle(&api.Request{
+			Req: req,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusTooManyRequests))
+		Expect(resp.Header.Get("Retry-After")).To(Equal("91"))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "code": "too_many_requests",
+            "message": "Too many failed login attempts, please try again later"
+        }`))
+	})
+
+	It("records failed and successful logins", func() {
+		valid := false
+		fakeLoginService := &mockLoginService{
+			verify: func(ctx context.Context, login, password string) (bool, int64, error) {
+				return valid, 1, nil
+			},
+			createToken: func(userID int64) (*token.Details, error) {
+				return &token.Details{}, nil
+			},
+			startSession: func(ctx context.Context, userID int64, details *token.Details, client *services.SessionClient) error {
+				return nil
+			},
+		}
+
+		var failures, successes []string
+		fakeThrottle := &mockLoginThrottle{
+			recordFailure: func(ctx context.Context, login, ip string) error {
+				failures = append(failures, login+" "+ip)
+				return nil
+			},
+			recordSuccess: func(ctx context.Context, login string) error {
+				successes = append(successes, login)
+				return nil
+			},
+		}
+
+		login := func() int {
+			body := []byte(`{
+                "login": "username",
+                "password": "Pa3$12345"
+            }`)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewBuffer(body))
+			Expect(err).ToNot(HaveOccurred())
+
+			req.RemoteAddr = "192.0.2.10:54321"
+
+			return users.Login(fakeLoginService, fakeThrottle).Handle(&api.Request{
+				Req: req,
+			}).StatusCode
+		}
+
+		Expect(login()).To(Equal(http.StatusUnauthorized))
+		Expect(failures).To(Equal([]string{"username 192.0.2.10"}))
+		Expect(successes).To(BeEmpty())
+
+		valid = true
+
+		Expect(login()).To(Equal(http.StatusOK))
+		Expect(failures).To(HaveLen(1))
+		Expect(successes).To(Equal([]string{"username"}))
+	})
+
+	It("returns internal server error if failed logins cannot be checked", func() {
+		fakeLoginService := &mockLoginService{}
+
+		fakeThrottle := &mockLoginThrottle{
+			retryAfter: func(ctx context.Context, login, ip string) (time.Duration, error) {
+				return 0, errors.New("redis error")
+			},
+		}
+
+		body := []byte(`{
+            "login": "username",
+            "password": "Pa3$12345"
+        }`)
+
+		req, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewBuffer(body))
+		Expect(err).ToNot(HaveOccurred())
+
+		resp := users.Login(fakeLoginService, fakeThrottle).Handle(&api.Request{
+			Req: req,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+
+	It("returns a challenge instead of tokens if the user has two factor authentication", func() {
+		fakeLoginService := &mockLoginService{
+			verify: func(ctx context.Context, login, password string) (bool, int64, error) {
+				return true, 1, nil
+			},
+			startTwoFactorChallenge: func(ctx context.Context, userID int64, login string) (string, error) {
+				Expect(userID).To(Equal(int64(1)))
+				Expect(login).To(Equal("username"))
+				return "challenge", nil
+			},
+			createToken: func(userID int64) (*token.Details, error) {
+				Fail("a token should not be created")
+				return nil, nil
+			},
+		}
+
+		fakeThrottle := &mockLoginThrottle{
+			recordSuccess: func(ctx context.Context, login string) error {
+				Fail("failed logins should not be cleared before the second factor")
 				return nil
 			},
 		}
@@ -147,7 +377,39 @@ var _ = Describe("login", func() {
 		req, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewBuffer(body))
 		Expect(err).ToNot(HaveOccurred())
 
-		resp := users.Login(fakeLoginService).Handle(&api.Request{
+		resp := users.Login(fakeLoginService, fakeThrottle).Handle(&api.Request{
+			Req: req,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "two_factor_required": true,
+            "challenge_token": "challenge"
+        }`))
+	})
+
+	It("returns internal server error if the challenge cannot be started", func() {
+		fakeLoginService := &mockLoginService{
+			verify: func(ctx context.Context, login, password string) (bool, int64, error) {
+				return true, 1, nil
+			},
+			startTwoFactorChallenge: func(ctx context.Context, userID int64, login string) (string, error) {
+				return "", errors.New("redis error")
+			},
+		}
+
+		body := []byte(`{
+            "login": "username",
+            "password": "Pa3$12345"
+        }`)
+
+		req, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewBuffer(body))
+		Expect(err).ToNot(HaveOccurred())
+
+		resp := users.Login(fakeLoginService, &mockLoginThrottle{}).Handle(&api.Request{
 			Req: req,
 		})
 
@@ -156,13 +418,13 @@ var _ = Describe("login", func() {
 
 	It("returns internal server error if token creation fails", func() {
 		fakeLoginService := &mockLoginService{
-			verify: func(login, password string) (bool, int64, error) {
+			verify: func(ctx context.Context, login, password string) (bool, int64, error) {
 				return true, 1, nil
 			},
 			createToken: func(userID int64) (*token.Details, error) {
 				return nil, errors.New("token creation failed")
 			},
-			storeTokenDetails: func(userID int64, details *token.Details) error {
+			startSession: func(ctx context.Context, userID int64, details *token.Details, client *services.SessionClient) error {
 				return nil
 			},
 		}
@@ -175,7 +437,7 @@ var _ = Describe("login", func() {
 		req, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewBuffer(body))
 		Expect(err).ToNot(HaveOccurred())
 
-		resp := users.Login(fakeLoginService).Handle(&api.Request{
+		resp := users.Login(fakeLoginService, &mockLoginThrottle{}).Handle(&api.Request{
 			Req: req,
 		})
 
@@ -184,7 +446,7 @@ var _ = Describe("login", func() {
 
 	It("returns internal server error if token storage fails", func() {
 		fakeLoginService := &mockLoginService{
-			verify: func(login, password string) (bool, int64, error) {
+			verify: func(ctx context.Context, login, password string) (bool, int64, error) {
 				return true, 1, nil
 			},
 			createToken: func(userID int64) (*token.Details, error) {
@@ -193,7 +455,7 @@ var _ = Describe("login", func() {
 					RefreshToken: "refresh token",
 				}, nil
 			},
-			storeTokenDetails: func(userID int64, details *token.Details) error {
+			startSession: func(ctx context.Context, userID int64, details *token.Details, client *services.SessionClient) error {
 				return errors.New("token storage failed")
 			},
 		}
@@ -206,7 +468,7 @@ var _ = Describe("login", func() {
 		req, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewBuffer(body))
 		Expect(err).ToNot(HaveOccurred())
 
-		resp := users.Login(fakeLoginService).Handle(&api.Request{
+		resp := users.Login(fakeLoginService, &mockLoginThrottle{}).Handle(&api.Request{
 			Req: req,
 		})
 
@@ -215,19 +477,54 @@ var _ = Describe("login", func() {
 })
 
 type mockLoginService struct {
-	verify            func(login, password string) (bool, int64, error)
-	createToken       func(userID int64) (*token.Details, error)
-	storeTokenDetails func(userID int64, details *token.Details) error
+	verify                  func(ctx context.Context, login, password string) (bool, int64, error)
+	startTwoFactorChallenge func(ctx context.Context, userID int64, login string) (string, error)
+	createToken             func(userID int64) (*token.Details, error)
+	startSession            func(ctx context.Context, userID int64, details *token.Details, client *services.SessionClient) error
+}
+
+func (m *mockLoginService) Verify(ctx context.Context, login, password string) (bool, int64, error) {
+	return m.verify(ctx, login, password)
 }
 
-func (m *mockLoginService) Verify(login, password string) (bool, int64, error) {
-	return m.verify(login, password)
+func (m *mockLoginService) StartTwoFactorChallenge(ctx context.Context, userID int64, login string) (string, error) {
+	if m.startTwoFactorChallenge == nil {
+		return "", nil
+	}
+	return m.startTwoFactorChallenge(ctx, userID, login)
 }
 
 func (m *mockLoginService) CreateToken(userID int64) (*token.Details, error) {
 	return m.createToken(userID)
 }
 
-func (m *mockLoginService) StoreTokenDetails(userID int64, details *token.Details) error {
-	return m.storeTokenDetails(userID, details)
+func (m *mockLoginService) StartSession(ctx context.Context, userID int64, details *token.Details, client *services.SessionClient) error {
+	return m.startSession(ctx, userID, details, client)
+}
+
+type mockLoginThrottle struct {
+	retryAfter    func(ctx context.Context, login, ip string) (time.Duration, error)
+	recordFailure func(ctx context.Context, login, ip string) error
+	recordSuccess func(ctx context.Context, login string) error
+}
+
+func (m *mockLoginThrottle) RetryAfter(ctx context.Context, login, ip string) (time.Duration, error) {
+	if m.retryAfter == nil {
+		return 0, nil
+	}
+	return m.retryAfter(ctx, login, ip)
+}
+
+func (m *mockLoginThrottle) RecordFailure(ctx context.Context, login, ip string) error {
+	if m.recordFailure == nil {
+		return nil
+	}
+	return m.recordFailure(ctx, login, ip)
+}
+
+func (m *mockLoginThrottle) RecordSuccess(ctx context.Context, login string) error {
+	if m.recordSuccess == nil {
+		return nil
+	}
+	return m.recordSuccess(ctx, login)
 }
@@ -0,0 +1,84 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+)
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	Device         string `json:"device"`
+}
+
+type TwoFactorLoginService interface {
+	TokenIssuer
+	VerifyTwoFactorChallenge(ctx context.Context, challenge, code string) (*repositories.LoginChallenge, error)
+}
+
+// LoginTwoFactor finishes a login for a user with two factor authentication by
+// exchanging the challenge from Login and a TOTP or recovery code for tokens.
+// Wrong codes count as failed logins for the login name the challenge was
+// started with.
+func LoginTwoFactor(service TwoFactorLoginService, throttle LoginThrottle) *api.Endpoint {
+	return &api.Endpoint{
+		Path:      "users/login/two-factor",
+		Method:    http.MethodPost,
+		RateLimit: &api.RateLimit{Requests: 20, Window: time.Minute},
+		Handle: func(r *api.Request) *api.Response {
+			var login TwoFactorLoginRequest
+			if err := r.Decode(&login); err != nil {
+				slog.WarnContext(r.Req.Context(), "Error decoding json body for two factor login")
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			validationErrors := make(map[string]string)
+			if len(login.ChallengeToken) == 0 {
+				validationErrors["challenge_token"] = "Required"
+			}
+
+			if len(login.Code) == 0 {
+				validationErrors["code"] = "Required"
+			}
+
+			if len(validationErrors) > 0 {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(validationErrors))
+			}
+
+			challenge, err := service.VerifyTwoFactorChallenge(r.Req.Context(), login.ChallengeToken, login.Code)
+			if errors.Is(err, services.ErrInvalidTwoFactorCode) {
+				err = throttle.RecordFailure(r.Req.Context(), challenge.Login, r.ClientIP())
+				if err != nil {
+					slog.ErrorContext(r.Req.Context(), "Error recording failed two factor login", "error", err)
+					return api.NewResponse(http.StatusInternalServerError, nil)
+				}
+
+				return api.ErrorResponse(r.Req.Context(), api.Unauthorized("Invalid code").WithFields(map[string]string{"code": "Invalid code"}))
+			}
+
+			if errors.Is(err, services.ErrInvalidLoginChallenge) {
+				return api.ErrorResponse(r.Req.Context(), api.Unauthorized("Login has expired, please log in again"))
+			}
+
+			if err != nil {
+				slog.ErrorContext(r.Req.Context(), "Error verifying two factor challenge", "error", err)
+				return api.NewResponse(http.StatusInternalServerError, nil)
+			}
+
+			err = throttle.RecordSuccess(r.Req.Context(), challenge.Login)
+			if err != nil {
+				slog.ErrorContext(r.Req.Context(), "Error clearing failed logins", "error", err)
+				return api.NewResponse(http.StatusInternalServerError, nil)
+			}
+
+			return issueTokens(service, r, challenge.UserID, login.Device)
+		},
+	}
+}
@@ -0,0 +1,188 @@
+package users_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+	"github.com/iplay88keys/my-recipe-library/pkg/token"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("two factor login", func() {
+	var fakeThrottle *mockLoginThrottle
+
+	BeforeEach(func() {
+		fakeThrottle = &mockLoginThrottle{}
+	})
+
+	loginTwoFactor := func(service users.TwoFactorLoginService, body string) *api.Response {
+		req, err := http.NewRequest(http.MethodPost, "/users/login/two-factor", bytes.NewBufferString(body))
+		Expect(err).ToNot(HaveOccurred())
+		req.RemoteAddr = "10.0.0.1:1234"
+
+		return users.LoginTwoFactor(service, fakeThrottle).Handle(&api.Request{
+			Req: req,
+		})
+	}
+
+	It("issues tokens once the challenge is passed", func() {
+		var client *services.SessionClient
+		fakeService := &mockTwoFactorLoginService{
+			verifyTwoFactorChallenge: func(ctx context.Context, challenge, code string) (*repositories.LoginChallenge, error) {
+				Expect(challenge).To(Equal("challenge"))
+				Expect(code).To(Equal("123456"))
+				return &repositories.LoginChallenge{UserID: 10, Login: "username"}, nil
+			},
+			createToken: func(userID int64) (*token.Details, error) {
+				Expect(userID).To(Equal(int64(10)))
+				return &token.Details{
+					AccessToken:  "access token",
+					RefreshToken: "refresh token",
+				}, nil
+			},
+			startSession: func(ctx context.Context, userID int64, details *token.Details, c *services.SessionClient) error {
+				client = c
+				return nil
+			},
+		}
+
+		var cleared string
+		fakeThrottle.recordSuccess = func(ctx context.Context, login string) error {
+			cleared = login
+			return nil
+		}
+
+		resp := loginTwoFactor(fakeService, `{
+            "challenge_token": "challenge",
+            "code": "123456",
+            "device": "Kitchen tablet"
+        }`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(cleared).To(Equal("username"))
+		Expect(client.Device).To(Equal("Kitchen tablet"))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "access_token": "access token",
+            "refresh_token": "refresh token"
+        }`))
+	})
+
+	It("returns a bad request if fields are missing", func() {
+		resp := loginTwoFactor(&mockTwoFactorLoginService{}, `{}`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "code": "bad_request",
+            "message": "The request has invalid fields",
+            "errors": {
+                "challenge_token": "Required",
+                "code": "Required"
+            }
+        }`))
+	})
+
+	It("returns unauthorized for an invalid code and records a failed login", func() {
+		fakeService := &mockTwoFactorLoginService{
+			verifyTwoFactorChallenge: func(ctx context.Context, challenge, code string) (*repositories.LoginChallenge, error) {
+				return &repositories.LoginChallenge{UserID: 10, Login: "username"}, services.ErrInvalidTwoFactorCode
+			},
+		}
+
+		var failedLogin, failedIP string
+		fakeThrottle.recordFailure = func(ctx context.Context, login, ip string) error {
+			failedLogin, failedIP = login, ip
+			return nil
+		}
+		fakeThrottle.recordSuccess = func(ctx context.Context, login string) error {
+			Fail("failed logins should not be cleared")
+			return nil
+		}
+
+		resp := loginTwoFactor(fakeService, `{"challenge_token": "challenge", "code": "000000"}`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		Expect(failedLogin).To(Equal("username"))
+		Expect(failedIP).To(Equal("10.0.0.1"))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{"code": "unauthorized", "message": "Invalid code", "errors": {"code": "Invalid code"}}`))
+	})
+
+	It("returns an internal server error if the failed login cannot be recorded", func() {
+		fakeService := &mockTwoFactorLoginService{
+			verifyTwoFactorChallenge: func(ctx context.Context, challenge, code string) (*repositories.LoginChallenge, error) {
+				return &repositories.LoginChallenge{UserID: 10, Login: "username"}, services.ErrInvalidTwoFactorCode
+			},
+		}
+
+		fakeThrottle.recordFailure = func(ctx context.Context, login, ip string) error {
+			return errors.New("redis error")
+		}
+
+		resp := loginTwoFactor(fakeService, `{"challenge_token": "challenge", "code": "000000"}`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+
+	It("returns unauthorized for an unknown or expired challenge", func() {
+		fakeService := &mockTwoFactorLoginService{
+			verifyTwoFactorChallenge: func(ctx context.Context, challenge, code string) (*repositories.LoginChallenge, error) {
+				return nil, services.ErrInvalidLoginChallenge
+			},
+		}
+
+		resp := loginTwoFactor(fakeService, `{"challenge_token": "challenge", "code": "123456"}`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{"code": "unauthorized", "message": "Login has expired, please log in again"}`))
+	})
+
+	It("returns an internal server error if the challenge cannot be checked", func() {
+		fakeService := &mockTwoFactorLoginService{
+			verifyTwoFactorChallenge: func(ctx context.Context, challenge, code string) (*repositories.LoginChallenge, error) {
+				return nil, errors.New("redis error")
+			},
+		}
+
+		resp := loginTwoFactor(fakeService, `{"challenge_token": "challenge", "code": "123456"}`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+})
+
+type mockTwoFactorLoginService struct {
+	verifyTwoFactorChallenge func(ctx context.Context, challenge, code string) (*repositories.LoginChallenge, error)
+	createToken              func(userID int64) (*token.Details, error)
+	startSession             func(ctx context.Context, userID int64, details *token.Details, client *services.SessionClient) error
+}
+
+func (m *mockTwoFactorLoginService) VerifyTwoFactorChallenge(ctx context.Context, challenge, code string) (*repositories.LoginChallenge, error) {
+	return m.verifyTwoFactorChallenge(ctx, challenge, code)
+}
+
+func (m *mockTwoFactorLoginService) CreateToken(userID int64) (*token.Details, error) {
+	return m.createToken(userID)
+}
+
+func (m *mockTwoFactorLoginService) StartSession(ctx context.Context, userID int64, details *token.Details, client *services.SessionClient) error {
+	return m.startSession(ctx, userID, details, client)
+}
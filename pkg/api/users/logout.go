@@ -1,6 +1,7 @@
 package users
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/iplay88keys/my-recipe-library/pkg/api"
@@ -9,7 +10,7 @@ import (
 
 type LogoutService interface {
 	ValidateToken(r *http.Request) (*token.AccessDetails, error)
-	DeleteTokenDetails(uuid string) error
+	DeleteTokenDetails(ctx context.Context, uuid string) error
 }
 
 func Logout(service LogoutService) *api.Endpoint {
@@ -23,7 +24,7 @@ func Logout(service LogoutService) *api.Endpoint {
 				return api.NewResponse(http.StatusUnauthorized, nil)
 			}
 
-			err = service.DeleteTokenDetails(details.AccessUuid)
+			err = service.DeleteTokenDetails(r.Req.Context(), details.AccessUuid)
 			if err != nil {
 				return api.NewResponse(http.StatusUnauthorized, nil)
 			}
@@ -1,6 +1,7 @@
 package users_test
 
 import (
+	"context"
 	"errors"
 	"net/http"
 
@@ -20,7 +21,7 @@ var _ = Describe("logout", func() {
 					AccessUuid: "some-uuid",
 				}, nil
 			},
-			deleteTokenDetails: func(uuid string) error {
+			deleteTokenDetails: func(ctx context.Context, uuid string) error {
 				return nil
 			},
 		}
@@ -40,7 +41,7 @@ var _ = Describe("logout", func() {
 			validateToken: func(r *http.Request) (*token.AccessDetails, error) {
 				return nil, errors.New("validation error")
 			},
-			deleteTokenDetails: func(uuid string) error {
+			deleteTokenDetails: func(ctx context.Context, uuid string) error {
 				return nil
 			},
 		}
@@ -62,7 +63,7 @@ var _ = Describe("logout", func() {
 					AccessUuid: "some-uuid",
 				}, nil
 			},
-			deleteTokenDetails: func(uuid string) error {
+			deleteTokenDetails: func(ctx context.Context, uuid string) error {
 				return errors.New("token deletion failed")
 			},
 		}
@@ -80,13 +81,13 @@ var _ = Describe("logout", func() {
 
 type mockLogoutService struct {
 	validateToken      func(r *http.Request) (*token.AccessDetails, error)
-	deleteTokenDetails func(uuid string) error
+	deleteTokenDetails func(ctx context.Context, uuid string) error
 }
 
 func (m *mockLogoutService) ValidateToken(r *http.Request) (*token.AccessDetails, error) {
 	return m.validateToken(r)
 }
 
-func (m *mockLogoutService) DeleteTokenDetails(uuid string) error {
-	return m.deleteTokenDetails(uuid)
+func (m *mockLogoutService) DeleteTokenDetails(ctx context.Context, uuid string) error {
+	return m.deleteTokenDetails(ctx, uuid)
 }
@@ -0,0 +1,78 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+)
+
+type OIDCCallbackRequest struct {
+	State  string `json:"state"`
+	Code   string `json:"code"`
+	Device string `json:"device"`
+}
+
+func (c *OIDCCallbackRequest) Validate() map[string]string {
+	errors := make(map[string]string)
+
+	if len(c.State) == 0 {
+		errors["state"] = "Required"
+	}
+
+	if len(c.Code) == 0 {
+		errors["code"] = "Required"
+	}
+
+	return errors
+}
+
+type OIDCLoginCompleter interface {
+	CompleteLogin(ctx context.Context, state, code string) (int64, error)
+}
+
+// OIDCCallback finishes an identity provider login and logs the user in the
+// same way Login does.
+func OIDCCallback(service OIDCLoginCompleter, loginService LoginFinisher) *api.Endpoint {
+	return &api.Endpoint{
+		Path:      "users/oidc/callback",
+		Method:    http.MethodPost,
+		RateLimit: &api.RateLimit{Requests: 20, Window: time.Minute},
+		Handle: func(r *api.Request) *api.Response {
+			var callback OIDCCallbackRequest
+			if err := r.Decode(&callback); err != nil {
+				slog.WarnContext(r.Req.Context(), "Error decoding json body for oidc callback")
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			validationErrors := callback.Validate()
+			if len(validationErrors) > 0 {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(validationErrors))
+			}
+
+			userID, err := service.CompleteLogin(r.Req.Context(), callback.State, callback.Code)
+			if errors.Is(err, services.ErrInvalidOIDCState) || errors.Is(err, services.ErrOIDCLoginFailed) {
+				return api.ErrorResponse(r.Req.Context(), api.Unauthorized("Login with the identity provider failed, please try again"))
+			}
+
+			if errors.Is(err, services.ErrOIDCEmailNotVerified) {
+				return api.ErrorResponse(r.Req.Context(), api.NewError(http.StatusForbidden, "email_not_verified", "Please verify your email address with the identity provider"))
+			}
+
+			if errors.Is(err, services.ErrOIDCEmailInUse) {
+				return api.ErrorResponse(r.Req.Context(), api.NewError(http.StatusConflict, "email_in_use", "An account already uses this email, log in with your password to link it"))
+			}
+
+			if err != nil {
+				slog.ErrorContext(r.Req.Context(), "Error completing oidc login", "error", err)
+				return api.NewResponse(http.StatusInternalServerError, nil)
+			}
+
+			return finishLogin(loginService, r, userID, callback.Device)
+		},
+	}
+}
@@ -0,0 +1,150 @@
+package users_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+	"github.com/iplay88keys/my-recipe-library/pkg/token"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("oidc callback", func() {
+	var fakeLoginService *mockLoginService
+
+	BeforeEach(func() {
+		fakeLoginService = &mockLoginService{
+			createToken: func(userID int64) (*token.Details, error) {
+				return &token.Details{
+					AccessToken:  "access token",
+					RefreshToken: "refresh token",
+				}, nil
+			},
+			startSession: func(ctx context.Context, userID int64, details *token.Details, client *services.SessionClient) error {
+				return nil
+			},
+		}
+	})
+
+	callback := func(service users.OIDCLoginCompleter, loginService users.LoginFinisher, body string) *api.Response {
+		req, err := http.NewRequest(http.MethodPost, "/users/oidc/callback", bytes.NewBufferString(body))
+		Expect(err).ToNot(HaveOccurred())
+
+		return users.OIDCCallback(service, loginService).Handle(&api.Request{
+			Req: req,
+		})
+	}
+
+	It("logs in the user the identity belongs to", func() {
+		var startedFor int64
+		var client *services.SessionClient
+		fakeLoginService.startSession = func(ctx context.Context, userID int64, details *token.Details, c *services.SessionClient) error {
+			startedFor = userID
+			client = c
+			return nil
+		}
+
+		fakeCompleter := &mockOIDCLoginCompleter{
+			completeLogin: func(ctx context.Context, state, code string) (int64, error) {
+				Expect(state).To(Equal("state"))
+				Expect(code).To(Equal("code"))
+				return 10, nil
+			},
+		}
+
+		resp := callback(fakeCompleter, fakeLoginService, `{
+            "state": "state",
+            "code": "code",
+            "device": "Kitchen tablet"
+        }`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(startedFor).To(Equal(int64(10)))
+		Expect(client.Device).To(Equal("Kitchen tablet"))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "access_token": "access token",
+            "refresh_token": "refresh token"
+        }`))
+	})
+
+	It("returns a challenge if the user has two factor authentication", func() {
+		fakeLoginService.startTwoFactorChallenge = func(ctx context.Context, userID int64, login string) (string, error) {
+			return "challenge", nil
+		}
+
+		fakeCompleter := &mockOIDCLoginCompleter{
+			completeLogin: func(ctx context.Context, state, code string) (int64, error) {
+				return 10, nil
+			},
+		}
+
+		resp := callback(fakeCompleter, fakeLoginService, `{"state": "state", "code": "code"}`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "two_factor_required": true,
+            "challenge_token": "challenge"
+        }`))
+	})
+
+	It("returns a bad request if fields are missing", func() {
+		resp := callback(&mockOIDCLoginCompleter{}, fakeLoginService, `{}`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "code": "bad_request",
+            "message": "The request has invalid fields",
+            "errors": {
+                "state": "Required",
+                "code": "Required"
+            }
+        }`))
+	})
+
+	DescribeTable("maps login errors to responses",
+		func(loginErr error, status int) {
+			fakeCompleter := &mockOIDCLoginCompleter{
+				completeLogin: func(ctx context.Context, state, code string) (int64, error) {
+					return -1, loginErr
+				},
+			}
+
+			fakeLoginService.createToken = func(userID int64) (*token.Details, error) {
+				Fail("a token should not be created")
+				return nil, nil
+			}
+
+			resp := callback(fakeCompleter, fakeLoginService, `{"state": "state", "code": "code"}`)
+			Expect(resp.StatusCode).To(Equal(status))
+		},
+		Entry("an unknown state", services.ErrInvalidOIDCState, http.StatusUnauthorized),
+		Entry("a rejected code", services.ErrOIDCLoginFailed, http.StatusUnauthorized),
+		Entry("an unverified email", services.ErrOIDCEmailNotVerified, http.StatusForbidden),
+		Entry("an email used by an unlinked account", services.ErrOIDCEmailInUse, http.StatusConflict),
+		Entry("any other error", errors.New("db error"), http.StatusInternalServerError),
+	)
+})
+
+type mockOIDCLoginCompleter struct {
+	completeLogin func(ctx context.Context, state, code string) (int64, error)
+}
+
+func (m *mockOIDCLoginCompleter) CompleteLogin(ctx context.Context, state, code string) (int64, error) {
+	return m.completeLogin(ctx, state, code)
+}
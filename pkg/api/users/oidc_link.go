@@ -0,0 +1,34 @@
+package users
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+)
+
+type OIDCLinkStarter interface {
+	StartLink(ctx context.Context, userID int64) (string, error)
+}
+
+// OIDCLink returns the identity provider URL for linking an identity to the
+// logged in user. The link is finished with OIDCLinkCallback.
+func OIDCLink(service OIDCLinkStarter) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "users/oidc/link",
+		Method: http.MethodPost,
+		Auth:   true,
+		Handle: func(r *api.Request) *api.Response {
+			authURL, err := service.StartLink(r.Req.Context(), r.UserID)
+			if err != nil {
+				slog.ErrorContext(r.Req.Context(), "Error starting oidc link", "error", err)
+				return api.NewResponse(http.StatusInternalServerError, nil)
+			}
+
+			return api.NewResponse(http.StatusOK, &OIDCResponse{
+				AuthorizationURL: authURL,
+			})
+		},
+	}
+}
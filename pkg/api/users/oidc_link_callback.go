@@ -0,0 +1,51 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+)
+
+type OIDCLinkCompleter interface {
+	CompleteLink(ctx context.Context, userID int64, state, code string) error
+}
+
+func OIDCLinkCallback(service OIDCLinkCompleter) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "users/oidc/link/callback",
+		Method: http.MethodPost,
+		Auth:   true,
+		Handle: func(r *api.Request) *api.Response {
+			var callback OIDCCallbackRequest
+			if err := r.Decode(&callback); err != nil {
+				slog.WarnContext(r.Req.Context(), "Error decoding json body for oidc link callback")
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			validationErrors := callback.Validate()
+			if len(validationErrors) > 0 {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(validationErrors))
+			}
+
+			err := service.CompleteLink(r.Req.Context(), r.UserID, callback.State, callback.Code)
+			if errors.Is(err, services.ErrInvalidOIDCState) || errors.Is(err, services.ErrOIDCLoginFailed) {
+				return api.ErrorResponse(r.Req.Context(), api.BadRequest("Linking with the identity provider failed, please try again"))
+			}
+
+			if errors.Is(err, services.ErrIdentityLinkedToAnotherUser) {
+				return api.ErrorResponse(r.Req.Context(), api.Conflict("This identity is already linked to another account"))
+			}
+
+			if err != nil {
+				slog.ErrorContext(r.Req.Context(), "Error completing oidc link", "error", err)
+				return api.NewResponse(http.StatusInternalServerError, nil)
+			}
+
+			return api.NewResponse(http.StatusNoContent, nil)
+		},
+	}
+}
@@ -0,0 +1,75 @@
+package users_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("oidc link callback", func() {
+	linkCallback := func(service users.OIDCLinkCompleter, body string) *api.Response {
+		req, err := http.NewRequest(http.MethodPost, "/users/oidc/link/callback", bytes.NewBufferString(body))
+		Expect(err).ToNot(HaveOccurred())
+
+		return users.OIDCLinkCallback(service).Handle(&api.Request{
+			Req:    req,
+			UserID: 10,
+		})
+	}
+
+	It("links the identity to the logged in user", func() {
+		var linkedFor int64
+		fakeCompleter := &mockOIDCLinkCompleter{
+			completeLink: func(ctx context.Context, userID int64, state, code string) error {
+				linkedFor = userID
+				Expect(state).To(Equal("state"))
+				Expect(code).To(Equal("code"))
+				return nil
+			},
+		}
+
+		resp := linkCallback(fakeCompleter, `{"state": "state", "code": "code"}`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+		Expect(linkedFor).To(Equal(int64(10)))
+	})
+
+	It("returns a bad request if fields are missing", func() {
+		resp := linkCallback(&mockOIDCLinkCompleter{}, `{"state": "state"}`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	DescribeTable("maps link errors to responses",
+		func(linkErr error, status int) {
+			fakeCompleter := &mockOIDCLinkCompleter{
+				completeLink: func(ctx context.Context, userID int64, state, code string) error {
+					return linkErr
+				},
+			}
+
+			resp := linkCallback(fakeCompleter, `{"state": "state", "code": "code"}`)
+			Expect(resp.StatusCode).To(Equal(status))
+		},
+		Entry("an unknown state", services.ErrInvalidOIDCState, http.StatusBadRequest),
+		Entry("a rejected code", services.ErrOIDCLoginFailed, http.StatusBadRequest),
+		Entry("an identity linked to someone else", services.ErrIdentityLinkedToAnotherUser, http.StatusConflict),
+		Entry("any other error", errors.New("db error"), http.StatusInternalServerError),
+	)
+})
+
+type mockOIDCLinkCompleter struct {
+	completeLink func(ctx context.Context, userID int64, state, code string) error
+}
+
+func (m *mockOIDCLinkCompleter) CompleteLink(ctx context.Context, userID int64, state, code string) error {
+	return m.completeLink(ctx, userID, state, code)
+}
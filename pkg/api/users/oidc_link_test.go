@@ -0,0 +1,67 @@
+package users_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("oidc link", func() {
+	It("returns the identity provider URL for the logged in user", func() {
+		fakeStarter := &mockOIDCLinkStarter{
+			startLink: func(ctx context.Context, userID int64) (string, error) {
+				Expect(userID).To(Equal(int64(10)))
+				return "https://accounts.example.com/authorize?state=state", nil
+			},
+		}
+
+		req, err := http.NewRequest(http.MethodPost, "/users/oidc/link", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp := users.OIDCLink(fakeStarter).Handle(&api.Request{
+			Req:    req,
+			UserID: 10,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "authorization_url": "https://accounts.example.com/authorize?state=state"
+        }`))
+	})
+
+	It("returns an internal server error if the link cannot be started", func() {
+		fakeStarter := &mockOIDCLinkStarter{
+			startLink: func(ctx context.Context, userID int64) (string, error) {
+				return "", errors.New("redis error")
+			},
+		}
+
+		req, err := http.NewRequest(http.MethodPost, "/users/oidc/link", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp := users.OIDCLink(fakeStarter).Handle(&api.Request{
+			Req:    req,
+			UserID: 10,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+})
+
+type mockOIDCLinkStarter struct {
+	startLink func(ctx context.Context, userID int64) (string, error)
+}
+
+func (m *mockOIDCLinkStarter) StartLink(ctx context.Context, userID int64) (string, error) {
+	return m.startLink(ctx, userID)
+}
@@ -0,0 +1,38 @@
+package users
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+)
+
+type OIDCResponse struct {
+	AuthorizationURL string `json:"authorization_url,omitempty"`
+}
+
+type OIDCLoginStarter interface {
+	StartLogin(ctx context.Context) (string, error)
+}
+
+// OIDCLogin returns the identity provider URL the client should send the
+// user to. The provider sends them back to the configured redirect URL with
+// the state and code for OIDCCallback.
+func OIDCLogin(service OIDCLoginStarter) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "users/oidc/login",
+		Method: http.MethodPost,
+		Handle: func(r *api.Request) *api.Response {
+			authURL, err := service.StartLogin(r.Req.Context())
+			if err != nil {
+				slog.ErrorContext(r.Req.Context(), "Error starting oidc login", "error", err)
+				return api.NewResponse(http.StatusInternalServerError, nil)
+			}
+
+			return api.NewResponse(http.StatusOK, &OIDCResponse{
+				AuthorizationURL: authURL,
+			})
+		},
+	}
+}
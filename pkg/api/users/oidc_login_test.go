@@ -0,0 +1,64 @@
+package users_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("oidc login", func() {
+	It("returns the identity provider URL", func() {
+		fakeStarter := &mockOIDCLoginStarter{
+			startLogin: func(ctx context.Context) (string, error) {
+				return "https://accounts.example.com/authorize?state=state", nil
+			},
+		}
+
+		req, err := http.NewRequest(http.MethodPost, "/users/oidc/login", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp := users.OIDCLogin(fakeStarter).Handle(&api.Request{
+			Req: req,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "authorization_url": "https://accounts.example.com/authorize?state=state"
+        }`))
+	})
+
+	It("returns an internal server error if the login cannot be started", func() {
+		fakeStarter := &mockOIDCLoginStarter{
+			startLogin: func(ctx context.Context) (string, error) {
+				return "", errors.New("redis error")
+			},
+		}
+
+		req, err := http.NewRequest(http.MethodPost, "/users/oidc/login", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp := users.OIDCLogin(fakeStarter).Handle(&api.Request{
+			Req: req,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+})
+
+type mockOIDCLoginStarter struct {
+	startLogin func(ctx context.Context) (string, error)
+}
+
+func (m *mockOIDCLoginStarter) StartLogin(ctx context.Context) (string, error) {
+	return m.startLogin(ctx)
+}
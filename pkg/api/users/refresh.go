@@ -0,0 +1,55 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+	"github.com/iplay88keys/my-recipe-library/pkg/token"
+)
+
+type UserRefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RefreshService interface {
+	RefreshToken(ctx context.Context, refreshToken string) (*token.Details, error)
+}
+
+func Refresh(service RefreshService) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "users/refresh",
+		Method: http.MethodPost,
+		Handle: func(r *api.Request) *api.Response {
+			var refresh UserRefreshRequest
+			if err := r.Decode(&refresh); err != nil {
+				slog.WarnContext(r.Req.Context(), "Error decoding json body for token refresh")
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			if refresh.RefreshToken == "" {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(map[string]string{
+					"refresh_token": "Required",
+				}))
+			}
+
+			tokenDetails, err := service.RefreshToken(r.Req.Context(), refresh.RefreshToken)
+			if errors.Is(err, services.ErrInvalidRefreshToken) {
+				return api.NewResponse(http.StatusUnauthorized, nil)
+			}
+
+			if err != nil {
+				slog.ErrorContext(r.Req.Context(), "Error refreshing token", "error", err)
+				return api.NewResponse(http.StatusInternalServerError, nil)
+			}
+
+			return api.NewResponse(http.StatusOK, &UserLoginResponse{
+				AccessToken:  tokenDetails.AccessToken,
+				RefreshToken: tokenDetails.RefreshToken,
+			})
+		},
+	}
+}
@@ -0,0 +1,116 @@
+package users_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+	"github.com/iplay88keys/my-recipe-library/pkg/token"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("refresh", func() {
+	It("exchanges a refresh token for a new token pair", func() {
+		var refreshed string
+		fakeRefreshService := &mockRefreshService{
+			refreshToken: func(ctx context.Context, refreshToken string) (*token.Details, error) {
+				refreshed = refreshToken
+				return &token.Details{
+					AccessToken:  "new access token",
+					RefreshToken: "new refresh token",
+				}, nil
+			},
+		}
+
+		body := []byte(`{"refresh_token": "old refresh token"}`)
+
+		req, err := http.NewRequest(http.MethodPost, "/users/refresh", bytes.NewBuffer(body))
+		Expect(err).ToNot(HaveOccurred())
+
+		resp := users.Refresh(fakeRefreshService).Handle(&api.Request{
+			Req: req,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(refreshed).To(Equal("old refresh token"))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "access_token": "new access token",
+            "refresh_token": "new refresh token"
+        }`))
+	})
+
+	It("requires a refresh token", func() {
+		fakeRefreshService := &mockRefreshService{}
+
+		req, err := http.NewRequest(http.MethodPost, "/users/refresh", bytes.NewBuffer([]byte(`{}`)))
+		Expect(err).ToNot(HaveOccurred())
+
+		resp := users.Refresh(fakeRefreshService).Handle(&api.Request{
+			Req: req,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "code": "bad_request",
+            "message": "The request has invalid fields",
+            "errors": {
+                "refresh_token": "Required"
+            }
+        }`))
+	})
+
+	It("returns unauthorized for an invalid or reused refresh token", func() {
+		fakeRefreshService := &mockRefreshService{
+			refreshToken: func(ctx context.Context, refreshToken string) (*token.Details, error) {
+				return nil, services.ErrInvalidRefreshToken
+			},
+		}
+
+		req, err := http.NewRequest(http.MethodPost, "/users/refresh", bytes.NewBuffer([]byte(`{"refresh_token": "reused"}`)))
+		Expect(err).ToNot(HaveOccurred())
+
+		resp := users.Refresh(fakeRefreshService).Handle(&api.Request{
+			Req: req,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("returns an error if the refresh fails", func() {
+		fakeRefreshService := &mockRefreshService{
+			refreshToken: func(ctx context.Context, refreshToken string) (*token.Details, error) {
+				return nil, errors.New("some error")
+			},
+		}
+
+		req, err := http.NewRequest(http.MethodPost, "/users/refresh", bytes.NewBuffer([]byte(`{"refresh_token": "token"}`)))
+		Expect(err).ToNot(HaveOccurred())
+
+		resp := users.Refresh(fakeRefreshService).Handle(&api.Request{
+			Req: req,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+})
+
+type mockRefreshService struct {
+	refreshToken func(ctx context.Context, refreshToken string) (*token.Details, error)
+}
+
+func (m *mockRefreshService) RefreshToken(ctx context.Context, refreshToken string) (*token.Details, error) {
+	return m.refreshToken(ctx, refreshToken)
+}
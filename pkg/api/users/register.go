@@ -2,59 +2,58 @@ package users
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
 )
 
-type RegisterResponse struct {
-	Errors map[string]string `json:"errors,omitempty"`
-}
-
 type UserRegistrar interface {
 	RegisterUser(ctx context.Context, username, email, password string) error
 }
 
 func Register(service UserRegistrar) *api.Endpoint {
 	return &api.Endpoint{
-		Path:   "users/register",
-		Method: http.MethodPost,
+		Path:      "users/register",
+		Method:    http.MethodPost,
+		RateLimit: &api.RateLimit{Requests: 10, Window: time.Hour},
 		Handle: func(r *api.Request) *api.Response {
 			var user RegisterRequest
 			if err := r.Decode(&user); err != nil {
-				fmt.Println("Error decoding json body for registration")
+				slog.WarnContext(r.Req.Context(), "Error decoding json body for registration")
 				return api.NewResponse(http.StatusBadRequest, nil)
 			}
 
 			validationErrors := user.Validate(false, false)
 			if len(validationErrors) > 0 {
-				resp := &RegisterResponse{
-					Errors: validationErrors,
-				}
-				return api.NewResponse(http.StatusBadRequest, resp)
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(validationErrors))
 			}
 
 			err := service.RegisterUser(r.Req.Context(), user.Username, user.Email, user.Password)
-			if err != nil {
-				if err.Error() == "username already exists" {
-					resp := &RegisterResponse{
-						Errors: map[string]string{
-							"username": "Username already in use",
-						},
-					}
-					return api.NewResponse(http.StatusBadRequest, resp)
-				}
-				if err.Error() == "email already exists" {
-					resp := &RegisterResponse{
-						Errors: map[string]string{
-							"email": "Email already in use",
-						},
-					}
-					return api.NewResponse(http.StatusBadRequest, resp)
+			var conflict *services.ErrConflict
+			if errors.As(err, &conflict) {
+				switch conflict.Field {
+				case "username":
+					return api.ErrorResponse(r.Req.Context(), api.ValidationError(map[string]string{
+						"username": "Username already in use",
+					}))
+				case "email":
+					return api.ErrorResponse(r.Req.Context(), api.ValidationError(map[string]string{
+						"email": "Email already in use",
+					}))
+				default:
+					return api.ErrorResponse(r.Req.Context(), api.Conflict(fmt.Sprintf("The %s is already in use", conflict.Field)).WithFields(map[string]string{
+						conflict.Field: "Already in use",
+					}))
 				}
+			}
 
-				fmt.Println("Failed to register user:", err.Error())
+			if err != nil {
+				slog.ErrorContext(r.Req.Context(), "Failed to register user", "error", err)
 				return api.NewResponse(http.StatusInternalServerError, nil)
 			}
 
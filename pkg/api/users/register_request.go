@@ -51,7 +51,7 @@ func (u *RegisterRequest) Validate(usernameExists, emailExists bool) map[string]
 	if len(u.Password) == 0 {
 		passwordErrors = append(passwordErrors, "Required")
 	} else {
-		passwordErrors = append(passwordErrors, u.validatePassword()...)
+		passwordErrors = append(passwordErrors, validatePassword(u.Password)...)
 	}
 
 	if len(passwordErrors) > 0 {
@@ -95,7 +95,9 @@ func (u *RegisterRequest) validateUsername() []string {
 	return errors
 }
 
-func (u *RegisterRequest) validatePassword() []string {
+// validatePassword returns the ways a password breaks the password rules. It
+// is shared by every endpoint that sets a password.
+func validatePassword(password string) []string {
 	var errors []string
 
 	var uppercasePresent, lowercasePresent, numberPresent, specialCharPresent bool
@@ -103,7 +105,7 @@ func (u *RegisterRequest) validatePassword() []string {
 	const maxLength = 64
 	var passLen int
 
-	for _, ch := range u.Password {
+	for _, ch := range password {
 		switch {
 		case unicode.IsNumber(ch):
 			numberPresent = true
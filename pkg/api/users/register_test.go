@@ -9,6 +9,7 @@ import (
 
 	"github.com/iplay88keys/my-recipe-library/pkg/api"
 	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -63,6 +64,8 @@ var _ = Describe("register", func() {
 		respBody, err := json.Marshal(resp.Body)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(respBody).To(MatchJSON(`{
+            "code": "bad_request",
+            "message": "The request has invalid fields",
             "errors": {
                 "email": "Required",
                 "password": "Required",
@@ -74,7 +77,7 @@ var _ = Describe("register", func() {
 	It("returns info if the username already exists", func() {
 		fakeService := &mockUserRegistrar{
 			registerUser: func(ctx context.Context, username, email, password string) error {
-				return errors.New("username already exists")
+				return &services.ErrConflict{Field: "username"}
 			},
 		}
 
@@ -96,6 +99,8 @@ var _ = Describe("register", func() {
 		respBody, err := json.Marshal(resp.Body)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(respBody).To(MatchJSON(`{
+            "code": "bad_request",
+            "message": "The request has invalid fields",
             "errors": {
                 "username": "Username already in use"
             }
@@ -105,7 +110,7 @@ var _ = Describe("register", func() {
 	It("returns info if the email already exists", func() {
 		fakeService := &mockUserRegistrar{
 			registerUser: func(ctx context.Context, username, email, password string) error {
-				return errors.New("email already exists")
+				return &services.ErrConflict{Field: "email"}
 			},
 		}
 
@@ -127,12 +132,47 @@ var _ = Describe("register", func() {
 		respBody, err := json.Marshal(resp.Body)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(respBody).To(MatchJSON(`{
+            "code": "bad_request",
+            "message": "The request has invalid fields",
             "errors": {
                 "email": "Email already in use"
             }
         }`))
 	})
 
+	It("returns a conflict naming the field for other conflicts", func() {
+		fakeService := &mockUserRegistrar{
+			registerUser: func(ctx context.Context, username, email, password string) error {
+				return &services.ErrConflict{Field: "phone"}
+			},
+		}
+
+		body := []byte(`{
+            "username": "username",
+            "email":    "email@example.com",
+            "password": "Pa3$12345"
+        }`)
+
+		req, err := http.NewRequest(http.MethodPost, "/users/register", bytes.NewBuffer(body))
+		Expect(err).ToNot(HaveOccurred())
+
+		resp := users.Register(fakeService).Handle(&api.Request{
+			Req: req,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "code": "conflict",
+            "message": "The phone is already in use",
+            "errors": {
+                "phone": "Already in use"
+            }
+        }`))
+	})
+
 	It("returns bad request if the body is empty", func() {
 		fakeService := &mockUserRegistrar{
 			registerUser: func(ctx context.Context, username, email, password string) error {
@@ -244,6 +284,8 @@ var _ = Describe("register", func() {
 		respBody, err := json.Marshal(resp.Body)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(respBody).To(MatchJSON(`{
+            "code": "bad_request",
+            "message": "The request has invalid fields",
             "errors": {
                 "password": "Uppercase letter missing, Numeric character missing, Special character missing, Must be between 6 and 64 characters long"
             }
@@ -275,6 +317,8 @@ var _ = Describe("register", func() {
 		respBody, err := json.Marshal(resp.Body)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(respBody).To(MatchJSON(`{
+            "code": "bad_request",
+            "message": "The request has invalid fields",
             "errors": {
                 "email": "Invalid email address"
             }
@@ -0,0 +1,53 @@
+package users
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"time"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+)
+
+type PasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+type PasswordResetRequester interface {
+	RequestPasswordReset(ctx context.Context, email string) error
+}
+
+// RequestPasswordReset emails a password reset link. It responds the same way
+// whether or not the email belongs to a user.
+func RequestPasswordReset(service PasswordResetRequester) *api.Endpoint {
+	return &api.Endpoint{
+		Path:      "users/password/forgot",
+		Method:    http.MethodPost,
+		RateLimit: &api.RateLimit{Requests: 5, Window: 15 * time.Minute},
+		Handle: func(r *api.Request) *api.Response {
+			var resetRequest PasswordResetRequest
+			if err := r.Decode(&resetRequest); err != nil {
+				slog.WarnContext(r.Req.Context(), "Error decoding json body for password reset request")
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			if len(resetRequest.Email) == 0 {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(map[string]string{"email": "Required"}))
+			}
+
+			parser := mail.AddressParser{}
+			if _, err := parser.Parse(resetRequest.Email); err != nil {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(map[string]string{"email": "Invalid email address"}))
+			}
+
+			err := service.RequestPasswordReset(r.Req.Context(), resetRequest.Email)
+			if err != nil {
+				slog.ErrorContext(r.Req.Context(), "Error requesting password reset", "error", err)
+				return api.NewResponse(http.StatusInternalServerError, nil)
+			}
+
+			return api.NewResponse(http.StatusAccepted, nil)
+		},
+	}
+}
@@ -0,0 +1,78 @@
+package users_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("request password reset", func() {
+	requestReset := func(service users.PasswordResetRequester, body string) *api.Response {
+		req, err := http.NewRequest(http.MethodPost, "/users/password/forgot", bytes.NewBufferString(body))
+		Expect(err).ToNot(HaveOccurred())
+
+		return users.RequestPasswordReset(service).Handle(&api.Request{
+			Req: req,
+		})
+	}
+
+	It("requests a reset link for the email", func() {
+		var requestedFor string
+		fakeRequester := &mockPasswordResetRequester{
+			requestPasswordReset: func(ctx context.Context, email string) error {
+				requestedFor = email
+				return nil
+			},
+		}
+
+		resp := requestReset(fakeRequester, `{"email": "cook@example.com"}`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+		Expect(requestedFor).To(Equal("cook@example.com"))
+	})
+
+	It("returns a bad request if the email is invalid", func() {
+		fakeRequester := &mockPasswordResetRequester{
+			requestPasswordReset: func(ctx context.Context, email string) error {
+				Fail("reset should not be requested")
+				return nil
+			},
+		}
+
+		resp := requestReset(fakeRequester, `{"email": "not an email"}`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{"code": "bad_request", "message": "The request has invalid fields", "errors": {"email": "Invalid email address"}}`))
+	})
+
+	It("returns an internal server error if the request fails", func() {
+		fakeRequester := &mockPasswordResetRequester{
+			requestPasswordReset: func(ctx context.Context, email string) error {
+				return errors.New("smtp error")
+			},
+		}
+
+		resp := requestReset(fakeRequester, `{"email": "cook@example.com"}`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+})
+
+type mockPasswordResetRequester struct {
+	requestPasswordReset func(ctx context.Context, email string) error
+}
+
+func (m *mockPasswordResetRequester) RequestPasswordReset(ctx context.Context, email string) error {
+	return m.requestPasswordReset(ctx, email)
+}
@@ -0,0 +1,59 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"time"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+)
+
+type ResendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+type VerificationResender interface {
+	ResendVerification(ctx context.Context, email string) error
+}
+
+// ResendVerification sends another verification email. It responds the same
+// way whether or not the email belongs to an unverified user.
+func ResendVerification(service VerificationResender) *api.Endpoint {
+	return &api.Endpoint{
+		Path:      "users/verify/resend",
+		Method:    http.MethodPost,
+		RateLimit: &api.RateLimit{Requests: 5, Window: 15 * time.Minute},
+		Handle: func(r *api.Request) *api.Response {
+			var resend ResendVerificationRequest
+			if err := r.Decode(&resend); err != nil {
+				slog.WarnContext(r.Req.Context(), "Error decoding json body for resending verification")
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			if len(resend.Email) == 0 {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(map[string]string{"email": "Required"}))
+			}
+
+			parser := mail.AddressParser{}
+			if _, err := parser.Parse(resend.Email); err != nil {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(map[string]string{"email": "Invalid email address"}))
+			}
+
+			err := service.ResendVerification(r.Req.Context(), resend.Email)
+			if errors.Is(err, services.ErrTooManyVerificationEmails) {
+				return api.ErrorResponse(r.Req.Context(), api.TooManyRequests("Too many verification emails, try again later"))
+			}
+
+			if err != nil {
+				slog.ErrorContext(r.Req.Context(), "Error resending verification", "error", err)
+				return api.NewResponse(http.StatusInternalServerError, nil)
+			}
+
+			return api.NewResponse(http.StatusAccepted, nil)
+		},
+	}
+}
@@ -0,0 +1,86 @@
+package users_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("resend verification", func() {
+	resend := func(service users.VerificationResender, body string) *api.Response {
+		req, err := http.NewRequest(http.MethodPost, "/users/verify/resend", bytes.NewBufferString(body))
+		Expect(err).ToNot(HaveOccurred())
+
+		return users.ResendVerification(service).Handle(&api.Request{
+			Req: req,
+		})
+	}
+
+	It("resends the verification email", func() {
+		var resentTo string
+		fakeResender := &mockVerificationResender{
+			resendVerification: func(ctx context.Context, email string) error {
+				resentTo = email
+				return nil
+			},
+		}
+
+		resp := resend(fakeResender, `{"email": "cook@example.com"}`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+		Expect(resentTo).To(Equal("cook@example.com"))
+	})
+
+	It("returns a bad request if the email is missing", func() {
+		fakeResender := &mockVerificationResender{
+			resendVerification: func(ctx context.Context, email string) error {
+				Fail("verification should not be resent")
+				return nil
+			},
+		}
+
+		resp := resend(fakeResender, `{}`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("returns too many requests if the address has been sent too many emails", func() {
+		fakeResender := &mockVerificationResender{
+			resendVerification: func(ctx context.Context, email string) error {
+				return services.ErrTooManyVerificationEmails
+			},
+		}
+
+		resp := resend(fakeResender, `{"email": "cook@example.com"}`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusTooManyRequests))
+	})
+
+	It("returns an internal server error if the resend fails", func() {
+		fakeResender := &mockVerificationResender{
+			resendVerification: func(ctx context.Context, email string) error {
+				return errors.New("smtp error")
+			},
+		}
+
+		resp := resend(fakeResender, `{"email": "cook@example.com"}`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+})
+
+type mockVerificationResender struct {
+	resendVerification func(ctx context.Context, email string) error
+}
+
+func (m *mockVerificationResender) ResendVerification(ctx context.Context, email string) error {
+	return m.resendVerification(ctx, email)
+}
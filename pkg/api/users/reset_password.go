@@ -0,0 +1,70 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+)
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+func (p *ResetPasswordRequest) Validate() map[string]string {
+	errors := make(map[string]string)
+
+	if len(p.Token) == 0 {
+		errors["token"] = "Required"
+	}
+
+	if passwordErrors := validateNewPassword(p.NewPassword); passwordErrors != "" {
+		errors["new_password"] = passwordErrors
+	}
+
+	return errors
+}
+
+type PasswordResetter interface {
+	ResetPassword(ctx context.Context, resetToken, newPassword string) error
+}
+
+// ResetPassword sets a new password using the token from a reset email.
+func ResetPassword(service PasswordResetter) *api.Endpoint {
+	return &api.Endpoint{
+		Path:      "users/password/reset",
+		Method:    http.MethodPost,
+		RateLimit: &api.RateLimit{Requests: 10, Window: 15 * time.Minute},
+		Handle: func(r *api.Request) *api.Response {
+			var reset ResetPasswordRequest
+			if err := r.Decode(&reset); err != nil {
+				slog.WarnContext(r.Req.Context(), "Error decoding json body for password reset")
+				return api.NewResponse(http.StatusBadRequest, nil)
+			}
+
+			validationErrors := reset.Validate()
+			if len(validationErrors) > 0 {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(validationErrors))
+			}
+
+			err := service.ResetPassword(r.Req.Context(), reset.Token, reset.NewPassword)
+			if errors.Is(err, services.ErrInvalidResetToken) {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(map[string]string{
+					"token": "Reset link is invalid or has expired",
+				}))
+			}
+
+			if err != nil {
+				slog.ErrorContext(r.Req.Context(), "Error resetting password", "error", err)
+				return api.NewResponse(http.StatusInternalServerError, nil)
+			}
+
+			return api.NewResponse(http.StatusNoContent, nil)
+		},
+	}
+}
@@ -0,0 +1,119 @@
+package users_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("reset password", func() {
+	resetPassword := func(service users.PasswordResetter, body string) *api.Response {
+		req, err := http.NewRequest(http.MethodPost, "/users/password/reset", bytes.NewBufferString(body))
+		Expect(err).ToNot(HaveOccurred())
+
+		return users.ResetPassword(service).Handle(&api.Request{
+			Req: req,
+		})
+	}
+
+	It("resets the password with the token", func() {
+		var usedToken, updated string
+		fakeResetter := &mockPasswordResetter{
+			resetPassword: func(ctx context.Context, resetToken, newPassword string) error {
+				usedToken = resetToken
+				updated = newPassword
+				return nil
+			},
+		}
+
+		resp := resetPassword(fakeResetter, `{
+            "token": "reset-token",
+            "new_password": "N3w-password"
+        }`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+		Expect(usedToken).To(Equal("reset-token"))
+		Expect(updated).To(Equal("N3w-password"))
+	})
+
+	It("returns validation errors for the token and password", func() {
+		fakeResetter := &mockPasswordResetter{
+			resetPassword: func(ctx context.Context, resetToken, newPassword string) error {
+				Fail("password should not be reset")
+				return nil
+			},
+		}
+
+		resp := resetPassword(fakeResetter, `{}`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "code": "bad_request",
+            "message": "The request has invalid fields",
+            "errors": {
+                "token": "Required",
+                "new_password": "Required"
+            }
+        }`))
+	})
+
+	It("returns a bad request if the token is invalid or expired", func() {
+		fakeResetter := &mockPasswordResetter{
+			resetPassword: func(ctx context.Context, resetToken, newPassword string) error {
+				return services.ErrInvalidResetToken
+			},
+		}
+
+		resp := resetPassword(fakeResetter, `{
+            "token": "used-token",
+            "new_password": "N3w-password"
+        }`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "code": "bad_request",
+            "message": "The request has invalid fields",
+            "errors": {
+                "token": "Reset link is invalid or has expired"
+            }
+        }`))
+	})
+
+	It("returns an internal server error if the reset fails", func() {
+		fakeResetter := &mockPasswordResetter{
+			resetPassword: func(ctx context.Context, resetToken, newPassword string) error {
+				return errors.New("db error")
+			},
+		}
+
+		resp := resetPassword(fakeResetter, `{
+            "token": "reset-token",
+            "new_password": "N3w-password"
+        }`)
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+})
+
+type mockPasswordResetter struct {
+	resetPassword func(ctx context.Context, resetToken, newPassword string) error
+}
+
+func (m *mockPasswordResetter) ResetPassword(ctx context.Context, resetToken, newPassword string) error {
+	return m.resetPassword(ctx, resetToken, newPassword)
+}
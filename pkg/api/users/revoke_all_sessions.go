@@ -0,0 +1,32 @@
+package users
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+)
+
+type AllSessionsRevoker interface {
+	RevokeAllSessions(ctx context.Context, userID int64) error
+}
+
+// RevokeAllSessions logs the user out everywhere, including the session that
+// made the request.
+func RevokeAllSessions(service AllSessionsRevoker) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "users/sessions",
+		Method: http.MethodDelete,
+		Auth:   true,
+		Handle: func(r *api.Request) *api.Response {
+			err := service.RevokeAllSessions(r.Req.Context(), r.UserID)
+			if err != nil {
+				slog.ErrorContext(r.Req.Context(), "Error revoking all sessions", "error", err)
+				return api.NewResponse(http.StatusInternalServerError, nil)
+			}
+
+			return api.NewResponse(http.StatusNoContent, nil)
+		},
+	}
+}
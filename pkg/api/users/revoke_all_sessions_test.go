@@ -0,0 +1,62 @@
+package users_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("revoke all sessions", func() {
+	It("revokes every session for the user", func() {
+		var revokedFor int64
+		fakeRevoker := &mockAllSessionsRevoker{
+			revokeAllSessions: func(ctx context.Context, userID int64) error {
+				revokedFor = userID
+				return nil
+			},
+		}
+
+		req, err := http.NewRequest(http.MethodDelete, "/users/sessions", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp := users.RevokeAllSessions(fakeRevoker).Handle(&api.Request{
+			Req:    req,
+			UserID: 10,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+		Expect(revokedFor).To(Equal(int64(10)))
+	})
+
+	It("returns an internal server error if the revoke fails", func() {
+		fakeRevoker := &mockAllSessionsRevoker{
+			revokeAllSessions: func(ctx context.Context, userID int64) error {
+				return errors.New("redis error")
+			},
+		}
+
+		req, err := http.NewRequest(http.MethodDelete, "/users/sessions", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp := users.RevokeAllSessions(fakeRevoker).Handle(&api.Request{
+			Req:    req,
+			UserID: 10,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+})
+
+type mockAllSessionsRevoker struct {
+	revokeAllSessions func(ctx context.Context, userID int64) error
+}
+
+func (m *mockAllSessionsRevoker) RevokeAllSessions(ctx context.Context, userID int64) error {
+	return m.revokeAllSessions(ctx, userID)
+}
@@ -0,0 +1,36 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+)
+
+type SessionRevoker interface {
+	RevokeSession(ctx context.Context, userID int64, sessionID string) error
+}
+
+func RevokeSession(service SessionRevoker) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "users/sessions/{id}",
+		Method: http.MethodDelete,
+		Auth:   true,
+		Handle: func(r *api.Request) *api.Response {
+			err := service.RevokeSession(r.Req.Context(), r.UserID, r.Req.PathValue("id"))
+			if errors.Is(err, services.ErrSessionNotFound) {
+				return api.NewResponse(http.StatusNotFound, nil)
+			}
+
+			if err != nil {
+				slog.ErrorContext(r.Req.Context(), "Error revoking session", "error", err)
+				return api.NewResponse(http.StatusInternalServerError, nil)
+			}
+
+			return api.NewResponse(http.StatusNoContent, nil)
+		},
+	}
+}
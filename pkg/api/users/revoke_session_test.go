@@ -0,0 +1,69 @@
+package users_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("revoke session", func() {
+	revoke := func(fake *mockSessionRevoker) *api.Response {
+		req, err := http.NewRequest(http.MethodDelete, "/users/sessions/session-1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.SetPathValue("id", "session-1")
+
+		return users.RevokeSession(fake).Handle(&api.Request{
+			Req:    req,
+			UserID: 10,
+		})
+	}
+
+	It("revokes the session", func() {
+		var revoked string
+		resp := revoke(&mockSessionRevoker{
+			revokeSession: func(ctx context.Context, userID int64, sessionID string) error {
+				Expect(userID).To(Equal(int64(10)))
+				revoked = sessionID
+				return nil
+			},
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+		Expect(revoked).To(Equal("session-1"))
+	})
+
+	It("returns not found if the session does not exist", func() {
+		resp := revoke(&mockSessionRevoker{
+			revokeSession: func(ctx context.Context, userID int64, sessionID string) error {
+				return services.ErrSessionNotFound
+			},
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("returns an internal server error if the revoke fails", func() {
+		resp := revoke(&mockSessionRevoker{
+			revokeSession: func(ctx context.Context, userID int64, sessionID string) error {
+				return errors.New("redis error")
+			},
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+})
+
+type mockSessionRevoker struct {
+	revokeSession func(ctx context.Context, userID int64, sessionID string) error
+}
+
+func (m *mockSessionRevoker) RevokeSession(ctx context.Context, userID int64, sessionID string) error {
+	return m.revokeSession(ctx, userID, sessionID)
+}
@@ -1,6 +1,8 @@
 package users_test
 
 import (
+	"io"
+	"log"
 	"os"
 	"testing"
 
@@ -24,9 +26,11 @@ var _ = BeforeSuite(func() {
 
 	os.Stdout = nil
 	os.Stderr = nil
+	log.SetOutput(io.Discard)
 })
 
 var _ = AfterSuite(func() {
 	os.Stdout = osStdout
 	os.Stderr = osStderr
+	log.SetOutput(os.Stderr)
 })
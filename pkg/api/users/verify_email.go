@@ -0,0 +1,42 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+)
+
+type EmailVerifier interface {
+	VerifyEmail(ctx context.Context, verificationToken string) error
+}
+
+// VerifyEmail confirms an email address with the token from a verification
+// email. Using a token again after it has worked succeeds.
+func VerifyEmail(service EmailVerifier) *api.Endpoint {
+	return &api.Endpoint{
+		Path:   "users/verify",
+		Method: http.MethodGet,
+		Handle: func(r *api.Request) *api.Response {
+			verificationToken := r.Req.URL.Query().Get("token")
+			if verificationToken == "" {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(map[string]string{"token": "Required"}))
+			}
+
+			err := service.VerifyEmail(r.Req.Context(), verificationToken)
+			if errors.Is(err, services.ErrInvalidVerificationToken) {
+				return api.ErrorResponse(r.Req.Context(), api.ValidationError(map[string]string{"token": "Verification link is invalid or has expired"}))
+			}
+
+			if err != nil {
+				slog.ErrorContext(r.Req.Context(), "Error verifying email", "error", err)
+				return api.NewResponse(http.StatusInternalServerError, nil)
+			}
+
+			return api.NewResponse(http.StatusNoContent, nil)
+		},
+	}
+}
@@ -0,0 +1,96 @@
+package users_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("verify email", func() {
+	verifyEmail := func(service users.EmailVerifier, path string) *api.Response {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		return users.VerifyEmail(service).Handle(&api.Request{
+			Req: req,
+		})
+	}
+
+	It("verifies the email with the token", func() {
+		var usedToken string
+		fakeVerifier := &mockEmailVerifier{
+			verifyEmail: func(ctx context.Context, verificationToken string) error {
+				usedToken = verificationToken
+				return nil
+			},
+		}
+
+		resp := verifyEmail(fakeVerifier, "/users/verify?token=verification-token")
+
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+		Expect(usedToken).To(Equal("verification-token"))
+	})
+
+	It("returns a bad request if the token is missing", func() {
+		fakeVerifier := &mockEmailVerifier{
+			verifyEmail: func(ctx context.Context, verificationToken string) error {
+				Fail("email should not be verified")
+				return nil
+			},
+		}
+
+		resp := verifyEmail(fakeVerifier, "/users/verify")
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("returns a bad request if the token is invalid", func() {
+		fakeVerifier := &mockEmailVerifier{
+			verifyEmail: func(ctx context.Context, verificationToken string) error {
+				return services.ErrInvalidVerificationToken
+			},
+		}
+
+		resp := verifyEmail(fakeVerifier, "/users/verify?token=expired")
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		respBody, err := json.Marshal(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(respBody).To(MatchJSON(`{
+            "code": "bad_request",
+            "message": "The request has invalid fields",
+            "errors": {
+                "token": "Verification link is invalid or has expired"
+            }
+        }`))
+	})
+
+	It("returns an internal server error if verification fails", func() {
+		fakeVerifier := &mockEmailVerifier{
+			verifyEmail: func(ctx context.Context, verificationToken string) error {
+				return errors.New("db error")
+			},
+		}
+
+		resp := verifyEmail(fakeVerifier, "/users/verify?token=verification-token")
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+})
+
+type mockEmailVerifier struct {
+	verifyEmail func(ctx context.Context, verificationToken string) error
+}
+
+func (m *mockEmailVerifier) VerifyEmail(ctx context.Context, verificationToken string) error {
+	return m.verifyEmail(ctx, verificationToken)
+}
@@ -0,0 +1,23 @@
+package wellknown
+
+import (
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/token"
+)
+
+type KeySetService interface {
+	JWKS() *token.JWKS
+}
+
+func JWKS(service KeySetService) *api.Endpoint {
+	return &api.Endpoint{
+		Path:        ".well-known/jwks.json",
+		Method:      http.MethodGet,
+		Unversioned: true,
+		Handle: func(r *api.Request) *api.Response {
+			return api.NewResponse(http.StatusOK, service.JWKS())
+		},
+	}
+}
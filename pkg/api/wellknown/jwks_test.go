@@ -0,0 +1,56 @@
+package wellknown_test
+
+import (
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/wellknown"
+	"github.com/iplay88keys/my-recipe-library/pkg/token"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("jwks", func() {
+	It("publishes the public keys", func() {
+		jwks := &token.JWKS{
+			Keys: []*token.JWK{{
+				KeyType:   "OKP",
+				Use:       "sig",
+				Algorithm: token.AlgorithmEdDSA,
+				KeyID:     "some-kid",
+				Curve:     "Ed25519",
+				X:         "some-x",
+			}},
+		}
+
+		fakeKeySetService := &mockKeySetService{
+			jwks: func() *token.JWKS {
+				return jwks
+			},
+		}
+
+		endpoint := wellknown.JWKS(fakeKeySetService)
+		Expect(endpoint.Path).To(Equal(".well-known/jwks.json"))
+		Expect(endpoint.Unversioned).To(BeTrue())
+		Expect(endpoint.Auth).To(BeFalse())
+
+		req, err := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp := endpoint.Handle(&api.Request{
+			Req: req,
+		})
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Body).To(Equal(jwks))
+	})
+})
+
+type mockKeySetService struct {
+	jwks func() *token.JWKS
+}
+
+func (m *mockKeySetService) JWKS() *token.JWKS {
+	return m.jwks()
+}
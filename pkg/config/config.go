@@ -2,15 +2,48 @@ package config
 
 import (
 	"encoding/json"
+	"time"
 )
 
 type Config struct {
-	MySQLCreds    MySQLCreds `env:"MYSQL_CREDS,    required"`
-	RedisURL      string     `env:"REDIS_URL,      required"`
-	AccessSecret  string     `env:"ACCESS_SECRET,  required"`
-	RefreshSecret string     `env:"REFRESH_SECRET, required"`
-	Port          string     `env:"PORT"`
-	Static        string     `env:"STATIC_DIR"`
+	MySQLCreds               MySQLCreds    `env:"MYSQL_CREDS,    required"`
+	RedisURL                 string        `env:"REDIS_URL,      required"`
+	TokenAlgorithm           string        `env:"TOKEN_ALGORITHM"`
+	AccessSecret             string        `env:"ACCESS_SECRET"`
+	AccessKeyFiles           []string      `env:"ACCESS_KEY_FILES"`
+	RefreshSecret            string        `env:"REFRESH_SECRET, required"`
+	Port                     string        `env:"PORT"`
+	Static                   string        `env:"STATIC_DIR"`
+	MigrateOnStart           bool          `env:"MIGRATE_ON_START"`
+	MailFrom                 string        `env:"MAIL_FROM"`
+	SMTPHost                 string        `env:"SMTP_HOST"`
+	SMTPPort                 string        `env:"SMTP_PORT"`
+	SMTPUsername             string        `env:"SMTP_USERNAME"`
+	SMTPPassword             string        `env:"SMTP_PASSWORD"`
+	PasswordResetURL         string        `env:"PASSWORD_RESET_URL"`
+	VerifyEmailURL           string        `env:"VERIFY_EMAIL_URL"`
+	RequireEmailVerification bool          `env:"REQUIRE_EMAIL_VERIFICATION"`
+	TOTPEncryptionKey        string        `env:"TOTP_ENCRYPTION_KEY"`
+	OIDCIssuer               string        `env:"OIDC_ISSUER"`
+	OIDCClientID             string        `env:"OIDC_CLIENT_ID"`
+	OIDCClientSecret         string        `env:"OIDC_CLIENT_SECRET"`
+	OIDCRedirectURL          string        `env:"OIDC_REDIRECT_URL"`
+	RateLimitBackend         string        `env:"RATE_LIMIT_BACKEND"`
+	SearchBackend            string        `env:"SEARCH_BACKEND"`
+	LogFormat                string        `env:"LOG_FORMAT"`
+	LogLevel                 string        `env:"LOG_LEVEL"`
+	RequestTimeout           time.Duration `env:"REQUEST_TIMEOUT"`
+	CORSAllowedOrigins       []string      `env:"CORS_ALLOWED_ORIGINS"`
+	TrustedProxies           []string      `env:"TRUSTED_PROXIES"`
+}
+
+type MigrationConfig struct {
+	MySQLCreds MySQLCreds `env:"MYSQL_CREDS, required"`
+}
+
+type UnlockConfig struct {
+	MySQLCreds MySQLCreds `env:"MYSQL_CREDS, required"`
+	RedisURL   string     `env:"REDIS_URL,   required"`
 }
 
 type MySQLCreds struct {
@@ -0,0 +1,144 @@
+package integration_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api/cookbooks"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cookbooks", func() {
+	var (
+		token             string
+		anotherCookbookID int64
+		doAuthorized      func(method, path string, body []byte) *http.Response
+		readCreatedID     func(resp *http.Response) int64
+	)
+
+	BeforeEach(func() {
+		_, err := db.Exec("DELETE FROM users WHERE id IS NOT NULL")
+		Expect(err).ToNot(HaveOccurred())
+
+		username := "cookbooks_user"
+		password := "Pa3$word123"
+
+		usersRepo := repositories.NewUsersRepository(db)
+		_, err = usersRepo.Insert(ctx, username, username+"@example.com", password)
+		Expect(err).ToNot(HaveOccurred())
+
+		anotherUserID, err := usersRepo.Insert(ctx, "cookbooks_different_user", "cookbooks_different_user@example.com", password)
+		Expect(err).ToNot(HaveOccurred())
+
+		cookbooksRepo := repositories.NewCookbooksRepository(db)
+		anotherCookbookID, err = cookbooksRepo.Insert(ctx, anotherUserID, "Should not have access")
+		Expect(err).ToNot(HaveOccurred())
+
+		reqBody := []byte(fmt.Sprintf(`{
+            "login": "%s",
+            "password": "%s"
+        }`, username, password))
+
+		resp, err := http.Post(fmt.Sprintf("http://localhost:%s/api/v1/users/login", port), "application/json", bytes.NewBuffer(reqBody))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(resp.StatusCode).To(Equal(200))
+
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		var loginResponse users.UserLoginResponse
+		err = json.Unmarshal(body, &loginResponse)
+		Expect(err).ToNot(HaveOccurred())
+
+		token = loginResponse.AccessToken
+
+		doAuthorized = func(method, path string, body []byte) *http.Response {
+			req, err := http.NewRequest(method, fmt.Sprintf("http://localhost:%s/api/v1/%s", port, path), bytes.NewBuffer(body))
+			Expect(err).ToNot(HaveOccurred())
+
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", fmt.Sprintf("bearer %s", token))
+
+			resp, err := client.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+
+			return resp
+		}
+
+		readCreatedID = func(resp *http.Response) int64 {
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+
+			var created cookbooks.NameResponse
+			err = json.Unmarshal(body, &created)
+			Expect(err).ToNot(HaveOccurred())
+
+			return created.ID
+		}
+	})
+
+	It("creates, renames, lists and deletes cookbooks and sections", func() {
+		resp := doAuthorized(http.MethodPost, "cookbooks", []byte(`{"name": "Desserts"}`))
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		cookbookID := readCreatedID(resp)
+
+		resp = doAuthorized(http.MethodPost, fmt.Sprintf("cookbooks/%d/sections", cookbookID), []byte(`{"name": "Cakes"}`))
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		sectionID := readCreatedID(resp)
+
+		resp = doAuthorized(http.MethodPut, fmt.Sprintf("cookbooks/%d", cookbookID), []byte(`{"name": "Sweets"}`))
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		resp = doAuthorized(http.MethodGet, "cookbooks", nil)
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(body).To(MatchJSON(fmt.Sprintf(`{
+            "cookbooks": [{
+                "id": %d,
+                "name": "Sweets",
+                "sections": [{
+                    "id": %d,
+                    "name": "Cakes"
+                }]
+            }]
+        }`, cookbookID, sectionID)))
+
+		resp = doAuthorized(http.MethodDelete, fmt.Sprintf("cookbooks/%d/sections/%d", cookbookID, sectionID), nil)
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+
+		resp = doAuthorized(http.MethodDelete, fmt.Sprintf("cookbooks/%d", cookbookID), nil)
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+
+		var count int
+		err = db.QueryRow("SELECT COUNT(*) FROM cookbooks WHERE id=?", cookbookID).Scan(&count)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(count).To(Equal(0))
+	})
+
+	It("returns forbidden for another user's cookbook", func() {
+		resp := doAuthorized(http.MethodPut, fmt.Sprintf("cookbooks/%d", anotherCookbookID), []byte(`{"name": "Mine now"}`))
+		Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+
+		resp = doAuthorized(http.MethodDelete, fmt.Sprintf("cookbooks/%d", anotherCookbookID), nil)
+		Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	})
+
+	It("returns unauthorized when not authenticated", func() {
+		resp, err := http.Get(fmt.Sprintf("http://localhost:%s/api/v1/cookbooks", port))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+})
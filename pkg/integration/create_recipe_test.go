@@ -29,7 +29,7 @@ var _ = Describe("CreateRecipe", func() {
 		password := "Pa3$word123"
 
 		usersRepo := repositories.NewUsersRepository(db)
-		userID, err = usersRepo.Insert(username, username+"@example.com", password)
+		userID, err = usersRepo.Insert(ctx, username, username+"@example.com", password)
 		Expect(err).ToNot(HaveOccurred())
 
 		reqBody := []byte(fmt.Sprintf(`{
@@ -0,0 +1,172 @@
+package integration_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DeleteRecipe", func() {
+	var (
+		recipeID        int64
+		anotherRecipeID int64
+		ingredientID    int64
+		measurementID   int64
+		token           string
+	)
+
+	BeforeEach(func() {
+		_, err := db.Exec("DELETE FROM users WHERE id IS NOT NULL")
+		Expect(err).ToNot(HaveOccurred())
+
+		username := "delete_recipe_user"
+		password := "Pa3$word123"
+
+		usersRepo := repositories.NewUsersRepository(db)
+		userID, err := usersRepo.Insert(ctx, username, username+"@example.com", password)
+		Expect(err).ToNot(HaveOccurred())
+
+		anotherUserID, err := usersRepo.Insert(ctx, "delete_recipe_different_user", "delete_recipe_different_user@example.com", password)
+		Expect(err).ToNot(HaveOccurred())
+
+		res, err := db.Exec(`INSERT INTO recipes (
+            creator, name, description, servings, prep_time, total_time
+            ) VALUES (?, ?, ?, ?, ?, ?)`,
+			userID, "Ice Cream", "Yum.", 1, "1 m", "1 m")
+		Expect(err).ToNot(HaveOccurred())
+
+		recipeID, err = res.LastInsertId()
+		Expect(err).ToNot(HaveOccurred())
+
+		res, err = db.Exec(`INSERT INTO recipes (
+            creator, name, description, servings, prep_time, total_time
+            ) VALUES (?, ?, ?, ?, ?, ?)`,
+			anotherUserID, "Should not have access", "Hidden.", 1, "1 m", "1 m")
+		Expect(err).ToNot(HaveOccurred())
+
+		anotherRecipeID, err = res.LastInsertId()
+		Expect(err).ToNot(HaveOccurred())
+
+		res, err = db.Exec("INSERT INTO ingredients (name) VALUES (?)", "Delete Me Ice Cream")
+		Expect(err).ToNot(HaveOccurred())
+
+		ingredientID, err = res.LastInsertId()
+		Expect(err).ToNot(HaveOccurred())
+
+		res, err = db.Exec("INSERT INTO measurements (name) VALUES (?)", "Delete Me Scoop")
+		Expect(err).ToNot(HaveOccurred())
+
+		measurementID, err = res.LastInsertId()
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = db.Exec(`INSERT INTO recipe_ingredients (
+            recipe_id, ingredient_id, ingredient_no, amount, measurement_id
+            ) VALUES (?, ?, ?, ?, ?)`,
+			recipeID, ingredientID, 1, 1, measurementID)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = db.Exec(`INSERT INTO recipe_steps (
+            recipe_id, step_no, instructions
+            ) VALUES (?, ?, ?)`,
+			recipeID, 1, "Place ice cream in bowl.")
+		Expect(err).ToNot(HaveOccurred())
+
+		reqBody := []byte(fmt.Sprintf(`{
+            "login": "%s",
+            "password": "%s"
+        }`, username, password))
+
+		resp, err := http.Post(fmt.Sprintf("http://localhost:%s/api/v1/users/login", port), "application/json", bytes.NewBuffer(reqBody))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(resp.StatusCode).To(Equal(200))
+
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		var loginResponse users.UserLoginResponse
+		err = json.Unmarshal(body, &loginResponse)
+		Expect(err).ToNot(HaveOccurred())
+
+		token = loginResponse.AccessToken
+	})
+
+	Context("authenticated", func() {
+		It("deletes the recipe along with its ingredients, steps and unused lookups", func() {
+			req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("http://localhost:%s/api/v1/recipes/%d", port, recipeID), nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			req.Header.Set("Authorization", fmt.Sprintf("bearer %s", token))
+
+			resp, err := client.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+
+			var count int
+			err = db.QueryRow("SELECT COUNT(*) FROM recipes WHERE id=?", recipeID).Scan(&count)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(0))
+
+			err = db.QueryRow("SELECT COUNT(*) FROM recipe_steps WHERE recipe_id=?", recipeID).Scan(&count)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(0))
+
+			err = db.QueryRow("SELECT COUNT(*) FROM recipe_ingredients WHERE recipe_id=?", recipeID).Scan(&count)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(0))
+
+			err = db.QueryRow("SELECT COUNT(*) FROM ingredients WHERE id=?", ingredientID).Scan(&count)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(0))
+
+			err = db.QueryRow("SELECT COUNT(*) FROM measurements WHERE id=?", measurementID).Scan(&count)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(0))
+		})
+
+		It("returns forbidden if the recipe is not owned by that user", func() {
+			req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("http://localhost:%s/api/v1/recipes/%d", port, anotherRecipeID), nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			req.Header.Set("Authorization", fmt.Sprintf("bearer %s", token))
+
+			resp, err := client.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+
+			var count int
+			err = db.QueryRow("SELECT COUNT(*) FROM recipes WHERE id=?", anotherRecipeID).Scan(&count)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(1))
+		})
+
+		It("returns not found when the recipe is not found", func() {
+			req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("http://localhost:%s/api/v1/recipes/9999", port), nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			req.Header.Set("Authorization", fmt.Sprintf("bearer %s", token))
+
+			resp, err := client.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	It("returns unauthorized when not authenticated", func() {
+		req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("http://localhost:%s/api/v1/recipes/%d", port, recipeID), nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+})
@@ -34,10 +34,10 @@ var _ = Describe("GetRecipe", func() {
 		password = "Pa3$word123"
 
 		usersRepo := repositories.NewUsersRepository(db)
-		userID, err := usersRepo.Insert(username, username+"@example.com", password)
+		userID, err := usersRepo.Insert(ctx, username, username+"@example.com", password)
 		Expect(err).ToNot(HaveOccurred())
 
-		anotherUserID, err := usersRepo.Insert("get_recipe_different_user", "get_recipe_different_user@example.com", password)
+		anotherUserID, err := usersRepo.Insert(ctx, "get_recipe_different_user", "get_recipe_different_user@example.com", password)
 		Expect(err).ToNot(HaveOccurred())
 
 		res, err := db.Exec(`INSERT INTO recipes (
@@ -145,9 +145,34 @@ var _ = Describe("GetRecipe", func() {
 					StepNumber:   1,
 					Instructions: "Place ice cream in bowl.",
 				}},
+				Tags: []string{},
 			}))
 		})
 
+		It("scales the ingredient amounts to the servings asked for", func() {
+			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://localhost:%s/api/v1/recipes/%d?servings=3", port, recipeID), nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			req.Header.Set("Authorization", fmt.Sprintf("bearer %s", token))
+
+			resp, err := client.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			defer resp.Body.Close()
+			bytes, err := io.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+
+			var recipe recipes.RecipeResponse
+			err = json.Unmarshal(bytes, &recipe)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(recipe.Servings).To(Equal(IntPointer(3)))
+			Expect(recipe.Ingredients).To(HaveLen(1))
+			Expect(recipe.Ingredients[0].Amount).To(Equal(StringPointer("3")))
+			Expect(recipe.Ingredients[0].Unscaled).To(BeFalse())
+		})
+
 		It("returns not found the recipe is not owned by that user", func() {
 			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://localhost:%s/api/v1/recipes/%d", port, anotherRecipeID), nil)
 			Expect(err).ToNot(HaveOccurred())
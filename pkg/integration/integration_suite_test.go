@@ -1,8 +1,11 @@
 package integration_test
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"os"
 	"os/exec"
@@ -16,6 +19,7 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 
 	. "github.com/iplay88keys/my-recipe-library/pkg/helpers"
+	"github.com/iplay88keys/my-recipe-library/pkg/oidc/oidctest"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -31,9 +35,11 @@ var (
 	databaseURL           string
 	databaseVarsAvailable bool
 	db                    *sql.DB
+	ctx                   = context.Background()
 	port                  string
 	client                *http.Client
 	session               *gexec.Session
+	oidcProvider          *oidctest.Provider
 
 	osStdout *os.File
 	osStderr *os.File
@@ -58,6 +64,9 @@ var _ = BeforeSuite(func() {
 
 		pathToExecutable, err = gexec.Build("github.com/iplay88keys/my-recipe-library")
 		Expect(err).ToNot(HaveOccurred())
+
+		oidcProvider, err = oidctest.NewProvider("my-recipe-library", "client-secret")
+		Expect(err).ToNot(HaveOccurred())
 	}
 
 	client = &http.Client{
@@ -69,13 +78,19 @@ var _ = BeforeSuite(func() {
 
 	os.Stdout = nil
 	os.Stderr = nil
+	log.SetOutput(io.Discard)
 })
 
 var _ = AfterSuite(func() {
 	os.Stdout = osStdout
 	os.Stderr = osStderr
+	log.SetOutput(os.Stderr)
 
 	gexec.CleanupBuildArtifacts()
+
+	if oidcProvider != nil {
+		oidcProvider.Close()
+	}
 })
 
 var _ = BeforeEach(func() {
@@ -103,6 +118,18 @@ var _ = BeforeEach(func() {
 	err = os.Setenv("STATIC_DIR", staticDir)
 	Expect(err).ToNot(HaveOccurred())
 
+	// Each test starts a new server, so keeping rate limits in memory keeps
+	// one test's requests from counting against the next
+	for key, value := range map[string]string{
+		"RATE_LIMIT_BACKEND": "memory",
+		"OIDC_ISSUER":        oidcProvider.Issuer(),
+		"OIDC_CLIENT_ID":     oidcProvider.ClientID,
+		"OIDC_CLIENT_SECRET": oidcProvider.ClientSecret,
+	} {
+		err = os.Setenv(key, value)
+		Expect(err).ToNot(HaveOccurred())
+	}
+
 	cmd := exec.Command(pathToExecutable)
 
 	session, err = gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
@@ -115,6 +142,16 @@ var _ = AfterEach(func() {
 	Eventually(session, 200*time.Millisecond).Should(gexec.Exit())
 })
 
+// unlock runs the unlock command against the same database and Redis as the
+// running server.
+func unlock(target string) {
+	cmd := exec.Command(pathToExecutable, "unlock", target)
+
+	unlockSession, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+	Expect(err).ToNot(HaveOccurred())
+	Eventually(unlockSession, 5*time.Second).Should(gexec.Exit(0))
+}
+
 func findProjectRoot() (string, error) {
 	dir, err := os.Getwd()
 	if err != nil {
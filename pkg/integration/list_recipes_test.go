@@ -20,6 +20,7 @@ var _ = Describe("ListRecipes", func() {
 	var (
 		username      string
 		password      string
+		userID        int64
 		firstRecipeID int64
 		token         string
 	)
@@ -32,7 +33,7 @@ var _ = Describe("ListRecipes", func() {
 		password = "Pa3$word123"
 
 		usersRepo := repositories.NewUsersRepository(db)
-		userID, err := usersRepo.Insert(username, username+"@example.com", password)
+		userID, err = usersRepo.Insert(ctx, username, username+"@example.com", password)
 		Expect(err).ToNot(HaveOccurred())
 
 		res, err := db.Exec(`INSERT INTO recipes (
@@ -44,13 +45,13 @@ var _ = Describe("ListRecipes", func() {
 		firstRecipeID, err = res.LastInsertId()
 		Expect(err).ToNot(HaveOccurred())
 
-		userID, err = usersRepo.Insert("another_"+username, "another_"+username+"@example.com", password)
+		anotherUserID, err := usersRepo.Insert(ctx, "another_"+username, "another_"+username+"@example.com", password)
 		Expect(err).ToNot(HaveOccurred())
 
 		_, err = db.Exec(`INSERT INTO recipes (
             creator, name, description, servings, prep_time, cook_time, total_time
             ) VALUES (?, ?, ?, ?, ?, ?, ?)`,
-			userID, "Nana's Beans", "Spruced up baked beans.", 8, "10 m", "1-2 hrs", "1-2 hrs")
+			anotherUserID, "Nana's Beans", "Spruced up baked beans.", 8, "10 m", "1-2 hrs", "1-2 hrs")
 		Expect(err).ToNot(HaveOccurred())
 
 		reqBody := []byte(fmt.Sprintf(`{
@@ -74,6 +75,27 @@ var _ = Describe("ListRecipes", func() {
 		token = loginResponse.AccessToken
 	})
 
+	listRecipes := func(query string) recipes.RecipeListResponse {
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://localhost:%s/api/v1/recipes%s", port, query), nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		req.Header.Set("Authorization", fmt.Sprintf("bearer %s", token))
+
+		resp, err := client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		var recipeList recipes.RecipeListResponse
+		err = json.Unmarshal(body, &recipeList)
+		Expect(err).ToNot(HaveOccurred())
+
+		return recipeList
+	}
+
 	Context("authenticated", func() {
 		It("returns a list of recipes for the user", func() {
 			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://localhost:%s/api/v1/recipes", port), nil)
@@ -98,8 +120,59 @@ var _ = Describe("ListRecipes", func() {
 					Name:        "Root Beer Float",
 					Description: "Delicious drink for a hot summer day.",
 				}},
+				Total: 1,
 			}))
 		})
+
+		It("pages through the recipes in order", func() {
+			for _, recipe := range []struct {
+				name         string
+				servings     int
+				totalTime    string
+				totalMinutes int
+			}{
+				{"Apple Pie", 8, "2 hrs", 120},
+				{"Banana Bread", 10, "1 hr 15 m", 75},
+				{"Cobbler", 6, "50 m", 50},
+			} {
+				_, err := db.Exec(`INSERT INTO recipes (
+                    creator, name, description, servings, total_time, total_minutes
+                    ) VALUES (?, ?, ?, ?, ?, ?)`,
+					userID, recipe.name, "Dessert", recipe.servings, recipe.totalTime, recipe.totalMinutes)
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			_, err := db.Exec("UPDATE recipes SET total_minutes=5 WHERE id=?", firstRecipeID)
+			Expect(err).ToNot(HaveOccurred())
+
+			names := func(list recipes.RecipeListResponse) []string {
+				var names []string
+				for _, recipe := range list.Recipes {
+					names = append(names, recipe.Name)
+				}
+				return names
+			}
+
+			firstPage := listRecipes("?limit=3")
+			Expect(names(firstPage)).To(Equal([]string{"Apple Pie", "Banana Bread", "Cobbler"}))
+			Expect(firstPage.Total).To(Equal(4))
+			Expect(firstPage.NextCursor).ToNot(BeEmpty())
+
+			secondPage := listRecipes("?limit=3&after=" + firstPage.NextCursor)
+			Expect(names(secondPage)).To(Equal([]string{"Root Beer Float"}))
+			Expect(secondPage.NextCursor).To(BeEmpty())
+
+			byTime := listRecipes("?sort=-total_time&limit=2")
+			Expect(names(byTime)).To(Equal([]string{"Apple Pie", "Banana Bread"}))
+
+			byTime = listRecipes("?sort=-total_time&limit=2&after=" + byTime.NextCursor)
+			Expect(names(byTime)).To(Equal([]string{"Cobbler", "Root Beer Float"}))
+			Expect(byTime.NextCursor).To(BeEmpty())
+
+			filtered := listRecipes("?min_servings=7&max_servings=9")
+			Expect(names(filtered)).To(Equal([]string{"Apple Pie"}))
+			Expect(filtered.Total).To(Equal(1))
+		})
 	})
 
 	It("returns unauthorized when not authenticated", func() {
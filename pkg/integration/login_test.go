@@ -10,6 +10,7 @@ import (
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
 
 	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
 
@@ -21,6 +22,11 @@ var _ = Describe("login", func() {
 	BeforeEach(func() {
 		_, err := db.Exec("DELETE FROM users WHERE id IS NOT NULL")
 		Expect(err).ToNot(HaveOccurred())
+
+		// Failed logins are kept in Redis between runs
+		for _, target := range []string{"unauthorized_user", "locked_user", "127.0.0.1", "::1"} {
+			unlock(target)
+		}
 	})
 
 	It("logs in with valid credentials", func() {
@@ -84,9 +90,49 @@ var _ = Describe("login", func() {
 		Expect(err).ToNot(HaveOccurred())
 
 		Expect(string(bytes)).To(MatchJSON(` {
-            "errors": {
-                "alert": "Invalid login credentials"
-            }
+            "code": "invalid_credentials",
+            "message": "Invalid login credentials"
         }`))
 	})
+
+	It("locks a login after too many failures until it is unlocked", func() {
+		username := "locked_user"
+		password := "Pa3$word123"
+
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), repositories.BCRYPT_COST)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = db.Exec("INSERT INTO users (username, email, password_hash) VALUES (?, ?, ?)", username, "locked_user@example.com", string(hashedPassword))
+		Expect(err).ToNot(HaveOccurred())
+
+		login := func(password string) *http.Response {
+			reqBody := []byte(fmt.Sprintf(`{
+                "login": "%s",
+                "password": "%s"
+            }`, username, password))
+
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://localhost:%s/api/v1/users/login", port), bytes.NewBuffer(reqBody))
+			Expect(err).ToNot(HaveOccurred())
+
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := client.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+
+			return resp
+		}
+
+		for i := 0; i < services.LoginFailuresAllowed; i++ {
+			Expect(login("bad-password").StatusCode).To(Equal(http.StatusUnauthorized))
+		}
+
+		resp := login(password)
+		Expect(resp.StatusCode).To(Equal(http.StatusTooManyRequests))
+		Expect(resp.Header.Get("Retry-After")).To(BeElementOf("29", "30"))
+
+		unlock("locked_user@example.com")
+
+		Expect(login(password).StatusCode).To(Equal(http.StatusOK))
+	})
 })
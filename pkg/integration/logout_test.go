@@ -30,7 +30,7 @@ var _ = Describe("logout", func() {
 		password = "Pa3$word123"
 
 		usersRepo := repositories.NewUsersRepository(db)
-		_, err = usersRepo.Insert(username, username+"@example.com", password)
+		_, err = usersRepo.Insert(ctx, username, username+"@example.com", password)
 		Expect(err).ToNot(HaveOccurred())
 
 		reqBody := []byte(fmt.Sprintf(`{
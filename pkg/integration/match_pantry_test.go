@@ -0,0 +1,115 @@
+package integration_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api/recipes"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MatchPantry", func() {
+	var (
+		username string
+		password string
+		recipeID int64
+		token    string
+	)
+
+	insertRecipeWithIngredients := func(userID int64, name string, ingredients ...string) int64 {
+		res, err := db.Exec(`INSERT INTO recipes (creator, name, description, servings) VALUES (?, ?, ?, ?)`,
+			userID, name, name, 1)
+		Expect(err).ToNot(HaveOccurred())
+
+		id, err := res.LastInsertId()
+		Expect(err).ToNot(HaveOccurred())
+
+		ingredientsRepo := repositories.NewIngredientsRepository(db)
+		for i, ingredient := range ingredients {
+			number := i + 1
+			err = ingredientsRepo.Insert(ctx, id, &repositories.Ingredient{
+				Ingredient:       &ingredient,
+				IngredientNumber: &number,
+			})
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		return id
+	}
+
+	BeforeEach(func() {
+		_, err := db.Exec("DELETE FROM users WHERE id IS NOT NULL")
+		Expect(err).ToNot(HaveOccurred())
+
+		username = "pantry_user"
+		password = "Pa3$word123"
+
+		usersRepo := repositories.NewUsersRepository(db)
+		userID, err := usersRepo.Insert(ctx, username, username+"@example.com", password)
+		Expect(err).ToNot(HaveOccurred())
+
+		recipeID = insertRecipeWithIngredients(userID, "Pancakes", "Flour", "Eggs", "Milk", "Salt")
+		insertRecipeWithIngredients(userID, "Toast", "Bread")
+
+		anotherUserID, err := usersRepo.Insert(ctx, "another_"+username, "another_"+username+"@example.com", password)
+		Expect(err).ToNot(HaveOccurred())
+
+		insertRecipeWithIngredients(anotherUserID, "Omelette", "Eggs")
+
+		reqBody := []byte(fmt.Sprintf(`{
+            "login": "%s",
+            "password": "%s"
+        }`, username, password))
+
+		resp, err := http.Post(fmt.Sprintf("http://localhost:%s/api/v1/users/login", port), "application/json", bytes.NewBuffer(reqBody))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(resp.StatusCode).To(Equal(200))
+
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		var loginResponse users.UserLoginResponse
+		err = json.Unmarshal(body, &loginResponse)
+		Expect(err).ToNot(HaveOccurred())
+
+		token = loginResponse.AccessToken
+	})
+
+	It("lists the user's recipes that use the ingredients with what they are missing", func() {
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://localhost:%s/api/v1/recipes/pantry?ingredient=egg&ingredient=flour&ignore_staples=true", port), nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		req.Header.Set("Authorization", fmt.Sprintf("bearer %s", token))
+
+		resp, err := client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		var matches recipes.PantryMatchListResponse
+		err = json.Unmarshal(body, &matches)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(matches).To(Equal(recipes.PantryMatchListResponse{
+			Recipes: []*recipes.PantryMatchResponse{{
+				ID:       recipeID,
+				Name:     "Pancakes",
+				Required: 3,
+				Covered:  2,
+				Missing:  []string{"Milk"},
+			}},
+		}))
+	})
+})
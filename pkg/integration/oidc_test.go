@@ -0,0 +1,140 @@
+package integration_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+	"github.com/iplay88keys/my-recipe-library/pkg/oidc/oidctest"
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("oidc login", func() {
+	var (
+		post         func(path, body, accessToken string, into interface{}) int
+		signInAt     func(authURL string) url.Values
+		loginWithIdP func() (int, *users.UserLoginResponse)
+	)
+
+	BeforeEach(func() {
+		_, err := db.Exec("DELETE FROM users WHERE id IS NOT NULL")
+		Expect(err).ToNot(HaveOccurred())
+
+		post = func(path, body, accessToken string, into interface{}) int {
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://localhost:%s/api/v1/%s", port, path), bytes.NewBufferString(body))
+			Expect(err).ToNot(HaveOccurred())
+			req.Header.Set("Content-Type", "application/json")
+			if accessToken != "" {
+				req.Header.Set("Authorization", "bearer "+accessToken)
+			}
+
+			resp, err := client.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+
+			respBody, err := io.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			if into != nil && len(respBody) > 0 {
+				Expect(json.Unmarshal(respBody, into)).To(Succeed())
+			}
+
+			return resp.StatusCode
+		}
+
+		// signInAt follows the authorization URL to the provider and returns
+		// the query it redirects back with
+		signInAt = func(authURL string) url.Values {
+			noRedirects := &http.Client{
+				CheckRedirect: func(req *http.Request, via []*http.Request) error {
+					return http.ErrUseLastResponse
+				},
+			}
+
+			resp, err := noRedirects.Get(authURL)
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+
+			location, err := resp.Location()
+			Expect(err).ToNot(HaveOccurred())
+
+			return location.Query()
+		}
+
+		loginWithIdP = func() (int, *users.UserLoginResponse) {
+			var start users.OIDCResponse
+			Expect(post("users/oidc/login", "", "", &start)).To(Equal(http.StatusOK))
+
+			callback := signInAt(start.AuthorizationURL)
+
+			var login users.UserLoginResponse
+			body := fmt.Sprintf(`{"state": "%s", "code": "%s"}`, callback.Get("state"), callback.Get("code"))
+			status := post("users/oidc/callback", body, "", &login)
+
+			return status, &login
+		}
+	})
+
+	It("creates an account for a new identity and logs into it again later", func() {
+		oidcProvider.SignIn(oidctest.User{
+			Subject:           "new-subject",
+			Email:             "idp_cook@example.com",
+			EmailVerified:     true,
+			PreferredUsername: "idp_cook",
+		})
+
+		status, login := loginWithIdP()
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(login.AccessToken).ToNot(BeEmpty())
+
+		usersRepo := repositories.NewUsersRepository(db)
+		user, err := usersRepo.GetByEmail(ctx, "idp_cook@example.com")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(user.Username).To(Equal("idp_cook"))
+		Expect(user.EmailVerified).To(BeTrue())
+
+		status, _ = loginWithIdP()
+		Expect(status).To(Equal(http.StatusOK))
+
+		var count int
+		Expect(db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count)).To(Succeed())
+		Expect(count).To(Equal(1))
+	})
+
+	It("links an identity to a logged in user", func() {
+		usersRepo := repositories.NewUsersRepository(db)
+		_, err := usersRepo.Insert(ctx, "linking_user", "linking_user@example.com", "Pa3$word123")
+		Expect(err).ToNot(HaveOccurred())
+
+		var login users.UserLoginResponse
+		Expect(post("users/login", `{"login": "linking_user", "password": "Pa3$word123"}`, "", &login)).To(Equal(http.StatusOK))
+
+		oidcProvider.SignIn(oidctest.User{
+			Subject:       "linked-subject",
+			Email:         "different@example.com",
+			EmailVerified: true,
+		})
+
+		var start users.OIDCResponse
+		Expect(post("users/oidc/link", "", login.AccessToken, &start)).To(Equal(http.StatusOK))
+
+		callback := signInAt(start.AuthorizationURL)
+		body := fmt.Sprintf(`{"state": "%s", "code": "%s"}`, callback.Get("state"), callback.Get("code"))
+		Expect(post("users/oidc/link/callback", body, login.AccessToken, nil)).To(Equal(http.StatusNoContent))
+
+		// A state can only be used once
+		Expect(post("users/oidc/link/callback", body, login.AccessToken, nil)).To(Equal(http.StatusBadRequest))
+
+		status, _ := loginWithIdP()
+		Expect(status).To(Equal(http.StatusOK))
+
+		_, err = usersRepo.GetByEmail(ctx, "different@example.com")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,108 @@
+package integration_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+
+	"github.com/onsi/gomega/gbytes"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("passwords", func() {
+	var (
+		username string
+		password string
+		login    func(password string) *http.Response
+		post     func(path, body, accessToken string) *http.Response
+	)
+
+	BeforeEach(func() {
+		_, err := db.Exec("DELETE FROM users WHERE id IS NOT NULL")
+		Expect(err).ToNot(HaveOccurred())
+
+		username = "passwords_user"
+		password = "Pa3$word123"
+
+		usersRepo := repositories.NewUsersRepository(db)
+		_, err = usersRepo.Insert(ctx, username, username+"@example.com", password)
+		Expect(err).ToNot(HaveOccurred())
+
+		post = func(path, body, accessToken string) *http.Response {
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://localhost:%s/api/v1/%s", port, path), bytes.NewBufferString(body))
+			Expect(err).ToNot(HaveOccurred())
+			req.Header.Set("Content-Type", "application/json")
+			if accessToken != "" {
+				req.Header.Set("Authorization", "bearer "+accessToken)
+			}
+
+			resp, err := client.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+
+			return resp
+		}
+
+		login = func(password string) *http.Response {
+			return post("users/login", fmt.Sprintf(`{"login": "%s", "password": "%s"}`, username, password), "")
+		}
+	})
+
+	It("changes the password and logs out every session", func() {
+		resp := login(password)
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		accessToken := accessTokenFrom(resp)
+
+		resp = post("users/password", `{"current_password": "Pa3$word123", "new_password": "N3w-password"}`, accessToken)
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+
+		resp = post("users/password", `{"current_password": "N3w-password", "new_password": "0ther-password"}`, accessToken)
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+
+		Expect(login(password).StatusCode).To(Equal(http.StatusUnauthorized))
+		Expect(login("N3w-password").StatusCode).To(Equal(http.StatusOK))
+	})
+
+	It("resets the password with a single use link", func() {
+		resp := post("users/password/forgot", fmt.Sprintf(`{"email": "%s@example.com"}`, username), "")
+		Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+
+		tokenPattern := regexp.MustCompile(`reset-password\?token=([A-Za-z0-9_-]+)`)
+		Eventually(session.Out).Should(gbytes.Say(tokenPattern.String()))
+		resetToken := tokenPattern.FindStringSubmatch(string(session.Out.Contents()))[1]
+
+		body := fmt.Sprintf(`{"token": "%s", "new_password": "N3w-password"}`, resetToken)
+		resp = post("users/password/reset", body, "")
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+
+		resp = post("users/password/reset", body, "")
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		Expect(login(password).StatusCode).To(Equal(http.StatusUnauthorized))
+		Expect(login("N3w-password").StatusCode).To(Equal(http.StatusOK))
+	})
+
+	It("does not reveal whether an email is registered", func() {
+		resp := post("users/password/forgot", `{"email": "nobody@example.com"}`, "")
+		Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+	})
+})
+
+func accessTokenFrom(resp *http.Response) string {
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	Expect(err).ToNot(HaveOccurred())
+
+	var loginResponse users.UserLoginResponse
+	err = json.Unmarshal(body, &loginResponse)
+	Expect(err).ToNot(HaveOccurred())
+
+	return loginResponse.AccessToken
+}
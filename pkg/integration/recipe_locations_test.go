@@ -0,0 +1,121 @@
+package integration_test
+
+import (
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Recipe locations", func() {
+	var (
+		cookbooksRepo *repositories.CookbooksRepository
+		recipesRepo   *repositories.RecipesRepository
+		userID        int64
+		recipeID      int64
+		cookbookID    int64
+		sectionID     int64
+	)
+
+	BeforeEach(func() {
+		_, err := db.Exec("DELETE FROM users WHERE id IS NOT NULL")
+		Expect(err).ToNot(HaveOccurred())
+
+		usersRepo := repositories.NewUsersRepository(db)
+		userID, err = usersRepo.Insert(ctx, "recipe_locations_user", "recipe_locations_user@example.com", "Pa3$word123")
+		Expect(err).ToNot(HaveOccurred())
+
+		res, err := db.Exec(`INSERT INTO recipes (
+            creator, name, description, servings, prep_time, total_time
+            ) VALUES (?, ?, ?, ?, ?, ?)`,
+			userID, "Ice Cream", "Yum.", 1, "1 m", "1 m")
+		Expect(err).ToNot(HaveOccurred())
+
+		recipeID, err = res.LastInsertId()
+		Expect(err).ToNot(HaveOccurred())
+
+		cookbooksRepo = repositories.NewCookbooksRepository(db)
+		recipesRepo = repositories.NewRecipesRepository(db)
+
+		cookbookID, err = cookbooksRepo.Insert(ctx, userID, "Desserts")
+		Expect(err).ToNot(HaveOccurred())
+
+		sectionID, err = cookbooksRepo.InsertSection(ctx, cookbookID, "Frozen")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	countLocations := func() int {
+		var count int
+		err := db.QueryRow("SELECT COUNT(*) FROM recipe_locations WHERE recipe_id=?", recipeID).Scan(&count)
+		Expect(err).ToNot(HaveOccurred())
+
+		return count
+	}
+
+	It("files a recipe into a cookbook section", func() {
+		err := cookbooksRepo.AddRecipe(ctx, recipeID, cookbookID, &sectionID)
+		Expect(err).ToNot(HaveOccurred())
+
+		recipes, _, err := recipesRepo.List(ctx, userID, &repositories.RecipeFilter{
+			CookbookID: &cookbookID,
+			SectionID:  &sectionID,
+		}, &repositories.RecipePage{Sort: repositories.RecipeSortName, Limit: 20})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recipes).To(HaveLen(1))
+		Expect(*recipes[0].ID).To(Equal(recipeID))
+	})
+
+	It("moves a recipe filed into the cookbook again instead of filing it twice", func() {
+		err := cookbooksRepo.AddRecipe(ctx, recipeID, cookbookID, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		err = cookbooksRepo.AddRecipe(ctx, recipeID, cookbookID, &sectionID)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(countLocations()).To(Equal(1))
+
+		var locationSectionID int64
+		err = db.QueryRow("SELECT section_id FROM recipe_locations WHERE recipe_id=?", recipeID).Scan(&locationSectionID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(locationSectionID).To(Equal(sectionID))
+	})
+
+	It("removes the recipe from a section when the section is deleted", func() {
+		err := cookbooksRepo.AddRecipe(ctx, recipeID, cookbookID, &sectionID)
+		Expect(err).ToNot(HaveOccurred())
+
+		err = cookbooksRepo.DeleteSection(ctx, sectionID)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(countLocations()).To(Equal(0))
+
+		var count int
+		err = db.QueryRow("SELECT COUNT(*) FROM recipes WHERE id=?", recipeID).Scan(&count)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(count).To(Equal(1))
+
+		err = db.QueryRow("SELECT COUNT(*) FROM cookbooks WHERE id=?", cookbookID).Scan(&count)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(count).To(Equal(1))
+	})
+
+	It("removes the sections and locations when the cookbook is deleted", func() {
+		err := cookbooksRepo.AddRecipe(ctx, recipeID, cookbookID, &sectionID)
+		Expect(err).ToNot(HaveOccurred())
+
+		err = cookbooksRepo.Delete(ctx, cookbookID)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(countLocations()).To(Equal(0))
+
+		_, err = cookbooksRepo.GetSectionCookbook(ctx, sectionID)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("does not allow filing a recipe into a cookbook that does not exist", func() {
+		err := cookbooksRepo.AddRecipe(ctx, recipeID, cookbookID+1000, nil)
+		Expect(err).To(HaveOccurred())
+
+		Expect(countLocations()).To(Equal(0))
+	})
+})
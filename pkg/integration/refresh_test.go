@@ -0,0 +1,111 @@
+package integration_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("refresh", func() {
+	var (
+		accessToken  string
+		refreshToken string
+		refresh      func(refreshToken string) (*http.Response, users.UserLoginResponse)
+	)
+
+	BeforeEach(func() {
+		_, err := db.Exec("DELETE FROM users WHERE id IS NOT NULL")
+		Expect(err).ToNot(HaveOccurred())
+
+		username := "refresh_user"
+		password := "Pa3$word123"
+
+		usersRepo := repositories.NewUsersRepository(db)
+		_, err = usersRepo.Insert(ctx, username, username+"@example.com", password)
+		Expect(err).ToNot(HaveOccurred())
+
+		reqBody := []byte(fmt.Sprintf(`{
+            "login": "%s",
+            "password": "%s"
+        }`, username, password))
+
+		resp, err := http.Post(fmt.Sprintf("http://localhost:%s/api/v1/users/login", port), "application/json", bytes.NewBuffer(reqBody))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(resp.StatusCode).To(Equal(200))
+
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		var loginResponse users.UserLoginResponse
+		err = json.Unmarshal(body, &loginResponse)
+		Expect(err).ToNot(HaveOccurred())
+
+		accessToken = loginResponse.AccessToken
+		refreshToken = loginResponse.RefreshToken
+
+		refresh = func(refreshToken string) (*http.Response, users.UserLoginResponse) {
+			reqBody := []byte(fmt.Sprintf(`{"refresh_token": "%s"}`, refreshToken))
+
+			resp, err := http.Post(fmt.Sprintf("http://localhost:%s/api/v1/users/refresh", port), "application/json", bytes.NewBuffer(reqBody))
+			Expect(err).ToNot(HaveOccurred())
+
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+
+			var refreshResponse users.UserLoginResponse
+			if len(body) > 0 {
+				err = json.Unmarshal(body, &refreshResponse)
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			return resp, refreshResponse
+		}
+	})
+
+	It("rotates the access and refresh tokens", func() {
+		resp, refreshed := refresh(refreshToken)
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(refreshed.AccessToken).ToNot(BeEmpty())
+		Expect(refreshed.RefreshToken).ToNot(BeEmpty())
+		Expect(refreshed.RefreshToken).ToNot(Equal(refreshToken))
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://localhost:%s/api/v1/recipes", port), nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Authorization", "bearer "+refreshed.AccessToken)
+
+		resp, err = client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		req.Header.Set("Authorization", "bearer "+accessToken)
+
+		resp, err = client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("rejects a refresh token that has already been used", func() {
+		resp, _ := refresh(refreshToken)
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		resp, _ = refresh(refreshToken)
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("rejects an access token used as a refresh token", func() {
+		resp, _ := refresh(accessToken)
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+})
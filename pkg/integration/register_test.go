@@ -60,6 +60,8 @@ var _ = Describe("Register", func() {
 		Expect(err).ToNot(HaveOccurred())
 
 		Expect(string(bytes)).To(MatchJSON(` {
+            "code": "bad_request",
+            "message": "The request has invalid fields",
             "errors": {
                 "email": "Invalid email address",
                 "password": "Uppercase letter missing, Numeric character missing, Special character missing, Must be between 6 and 64 characters long",
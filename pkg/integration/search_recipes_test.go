@@ -0,0 +1,114 @@
+package integration_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api/recipes"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SearchRecipes", func() {
+	var (
+		username string
+		password string
+		recipeID int64
+		token    string
+	)
+
+	BeforeEach(func() {
+		_, err := db.Exec("DELETE FROM users WHERE id IS NOT NULL")
+		Expect(err).ToNot(HaveOccurred())
+
+		username = "search_recipes_user"
+		password = "Pa3$word123"
+
+		usersRepo := repositories.NewUsersRepository(db)
+		userID, err := usersRepo.Insert(ctx, username, username+"@example.com", password)
+		Expect(err).ToNot(HaveOccurred())
+
+		res, err := db.Exec(`INSERT INTO recipes (creator, name, description, servings) VALUES (?, ?, ?, ?)`,
+			userID, "Garlic Bread", "Crusty bread for dinner.", 4)
+		Expect(err).ToNot(HaveOccurred())
+
+		recipeID, err = res.LastInsertId()
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = db.Exec("INSERT INTO recipe_steps (recipe_id, step_no, instructions) VALUES (?, ?, ?)",
+			recipeID, 1, "Spread the garlic butter on the bread.")
+		Expect(err).ToNot(HaveOccurred())
+
+		anotherUserID, err := usersRepo.Insert(ctx, "another_"+username, "another_"+username+"@example.com", password)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = db.Exec(`INSERT INTO recipes (
+            creator, name, description, servings, prep_time, cook_time, total_time
+            ) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			anotherUserID, "Garlic Beans", "Spruced up baked beans.", 8, "10 m", "1-2 hrs", "1-2 hrs")
+		Expect(err).ToNot(HaveOccurred())
+
+		reqBody := []byte(fmt.Sprintf(`{
+            "login": "%s",
+            "password": "%s"
+        }`, username, password))
+
+		resp, err := http.Post(fmt.Sprintf("http://localhost:%s/api/v1/users/login", port), "application/json", bytes.NewBuffer(reqBody))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(resp.StatusCode).To(Equal(200))
+
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		var loginResponse users.UserLoginResponse
+		err = json.Unmarshal(body, &loginResponse)
+		Expect(err).ToNot(HaveOccurred())
+
+		token = loginResponse.AccessToken
+	})
+
+	It("finds the user's recipes and highlights where they match", func() {
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://localhost:%s/api/v1/recipes/search?q=garlic", port), nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		req.Header.Set("Authorization", fmt.Sprintf("bearer %s", token))
+
+		resp, err := client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		var searchResponse recipes.RecipeSearchResponse
+		err = json.Unmarshal(body, &searchResponse)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(searchResponse.Results).To(HaveLen(1))
+		Expect(searchResponse.Results[0].ID).To(Equal(recipeID))
+		Expect(searchResponse.Results[0].Highlights).To(ConsistOf(
+			&recipes.HighlightResponse{Field: "name", Text: "<mark>Garlic</mark> Bread"},
+			&recipes.HighlightResponse{Field: "step", Text: "Spread the <mark>garlic</mark> butter on the bread."},
+		))
+	})
+
+	It("returns bad request without a query", func() {
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://localhost:%s/api/v1/recipes/search", port), nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		req.Header.Set("Authorization", fmt.Sprintf("bearer %s", token))
+
+		resp, err := client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
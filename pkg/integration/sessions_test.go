@@ -0,0 +1,140 @@
+package integration_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("sessions", func() {
+	var (
+		login  func(device string) users.UserLoginResponse
+		doAuth func(method, path, accessToken string) *http.Response
+	)
+
+	BeforeEach(func() {
+		_, err := db.Exec("DELETE FROM users WHERE id IS NOT NULL")
+		Expect(err).ToNot(HaveOccurred())
+
+		username := "sessions_user"
+		password := "Pa3$word123"
+
+		usersRepo := repositories.NewUsersRepository(db)
+		_, err = usersRepo.Insert(ctx, username, username+"@example.com", password)
+		Expect(err).ToNot(HaveOccurred())
+
+		login = func(device string) users.UserLoginResponse {
+			reqBody := []byte(fmt.Sprintf(`{
+                "login": "%s",
+                "password": "%s",
+                "device": "%s"
+            }`, username, password, device))
+
+			resp, err := http.Post(fmt.Sprintf("http://localhost:%s/api/v1/users/login", port), "application/json", bytes.NewBuffer(reqBody))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+
+			var loginResponse users.UserLoginResponse
+			err = json.Unmarshal(body, &loginResponse)
+			Expect(err).ToNot(HaveOccurred())
+
+			return loginResponse
+		}
+
+		doAuth = func(method, path, accessToken string) *http.Response {
+			req, err := http.NewRequest(method, fmt.Sprintf("http://localhost:%s/api/v1/%s", port, path), nil)
+			Expect(err).ToNot(HaveOccurred())
+			req.Header.Set("Authorization", "bearer "+accessToken)
+
+			resp, err := client.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+
+			return resp
+		}
+	})
+
+	listSessions := func(accessToken string) []*users.SessionResponse {
+		resp := doAuth(http.MethodGet, "users/sessions", accessToken)
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		var sessionsResponse users.SessionListResponse
+		err = json.Unmarshal(body, &sessionsResponse)
+		Expect(err).ToNot(HaveOccurred())
+
+		return sessionsResponse.Sessions
+	}
+
+	It("lists the sessions for the user", func() {
+		login("Phone")
+		laptop := login("Laptop")
+
+		sessions := listSessions(laptop.AccessToken)
+		Expect(sessions).To(HaveLen(2))
+
+		devices := map[string]bool{}
+		for _, session := range sessions {
+			devices[session.Device] = session.Current
+			Expect(session.IP).ToNot(BeEmpty())
+			Expect(session.CreatedAt).ToNot(BeEmpty())
+		}
+
+		Expect(devices).To(Equal(map[string]bool{"Phone": false, "Laptop": true}))
+	})
+
+	It("revokes another session immediately", func() {
+		phone := login("Phone")
+		laptop := login("Laptop")
+
+		var phoneSessionID string
+		for _, session := range listSessions(laptop.AccessToken) {
+			if session.Device == "Phone" {
+				phoneSessionID = session.ID
+			}
+		}
+		Expect(phoneSessionID).ToNot(BeEmpty())
+
+		resp := doAuth(http.MethodDelete, "users/sessions/"+phoneSessionID, laptop.AccessToken)
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+
+		resp = doAuth(http.MethodGet, "recipes", phone.AccessToken)
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+
+		resp = doAuth(http.MethodGet, "recipes", laptop.AccessToken)
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		reqBody := []byte(fmt.Sprintf(`{"refresh_token": "%s"}`, phone.RefreshToken))
+		resp, err := http.Post(fmt.Sprintf("http://localhost:%s/api/v1/users/refresh", port), "application/json", bytes.NewBuffer(reqBody))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("logs out everywhere", func() {
+		phone := login("Phone")
+		laptop := login("Laptop")
+
+		resp := doAuth(http.MethodDelete, "users/sessions", laptop.AccessToken)
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+
+		for _, accessToken := range []string{phone.AccessToken, laptop.AccessToken} {
+			resp = doAuth(http.MethodGet, "recipes", accessToken)
+			Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		}
+	})
+})
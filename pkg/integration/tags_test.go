@@ -0,0 +1,147 @@
+package integration_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api/recipes"
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tags", func() {
+	var (
+		username string
+		password string
+		token    string
+	)
+
+	do := func(method, path string, body []byte) []byte {
+		req, err := http.NewRequest(method, fmt.Sprintf("http://localhost:%s/api/v1/%s", port, path), bytes.NewBuffer(body))
+		Expect(err).ToNot(HaveOccurred())
+
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", fmt.Sprintf("bearer %s", token))
+
+		resp, err := client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(BeNumerically("<", 300))
+
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		return respBody
+	}
+
+	createRecipe := func(name string, tags string) int64 {
+		body := do(http.MethodPost, "recipes", []byte(fmt.Sprintf(`{
+            "name": "%s",
+            "description": "Delicious",
+            "servings": 1,
+            "tags": %s
+        }`, name, tags)))
+
+		var response recipes.CreateRecipeResponse
+		err := json.Unmarshal(body, &response)
+		Expect(err).ToNot(HaveOccurred())
+
+		return response.RecipeID
+	}
+
+	listNames := func(query string) []string {
+		var list recipes.RecipeListResponse
+		err := json.Unmarshal(do(http.MethodGet, "recipes?"+query, nil), &list)
+		Expect(err).ToNot(HaveOccurred())
+
+		var names []string
+		for _, recipe := range list.Recipes {
+			names = append(names, recipe.Name)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		_, err := db.Exec("DELETE FROM users WHERE id IS NOT NULL")
+		Expect(err).ToNot(HaveOccurred())
+
+		username = "tags_user"
+		password = "Pa3$word123"
+
+		usersRepo := repositories.NewUsersRepository(db)
+		_, err = usersRepo.Insert(ctx, username, username+"@example.com", password)
+		Expect(err).ToNot(HaveOccurred())
+
+		reqBody := []byte(fmt.Sprintf(`{
+            "login": "%s",
+            "password": "%s"
+        }`, username, password))
+
+		resp, err := http.Post(fmt.Sprintf("http://localhost:%s/api/v1/users/login", port), "application/json", bytes.NewBuffer(reqBody))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(resp.StatusCode).To(Equal(200))
+
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		var loginResponse users.UserLoginResponse
+		err = json.Unmarshal(body, &loginResponse)
+		Expect(err).ToNot(HaveOccurred())
+
+		token = loginResponse.AccessToken
+	})
+
+	It("saves a recipe's tags normalized and lists the user's tags with counts", func() {
+		recipeID := createRecipe("Brownies", `["Dessert", " Quick  Bake", "dessert"]`)
+		createRecipe("Sorbet", `["DESSERT"]`)
+
+		var recipe recipes.RecipeResponse
+		err := json.Unmarshal(do(http.MethodGet, fmt.Sprintf("recipes/%d", recipeID), nil), &recipe)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recipe.Tags).To(Equal([]string{"dessert", "quick bake"}))
+
+		var tags recipes.TagListResponse
+		err = json.Unmarshal(do(http.MethodGet, "tags", nil), &tags)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tags).To(Equal(recipes.TagListResponse{
+			Tags: []*recipes.TagResponse{
+				{Name: "dessert", RecipeCount: 2},
+				{Name: "quick bake", RecipeCount: 1},
+			},
+		}))
+	})
+
+	It("removes tags that no recipe has any more", func() {
+		recipeID := createRecipe("Brownies", `["dessert", "chocolate"]`)
+
+		do(http.MethodPut, fmt.Sprintf("recipes/%d", recipeID), []byte(`{
+            "name": "Brownies",
+            "description": "Delicious",
+            "servings": 1,
+            "tags": ["dessert"]
+        }`))
+
+		var tags recipes.TagListResponse
+		err := json.Unmarshal(do(http.MethodGet, "tags", nil), &tags)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tags.Tags).To(HaveLen(1))
+		Expect(tags.Tags[0].Name).To(Equal("dessert"))
+	})
+
+	It("lists the recipes with any or all of the tags", func() {
+		createRecipe("Brownies", `["dessert", "chocolate"]`)
+		createRecipe("Sorbet", `["dessert"]`)
+		createRecipe("Chili", `["dinner"]`)
+
+		Expect(listNames("tag=Chocolate&tag=dinner")).To(Equal([]string{"Brownies", "Chili"}))
+		Expect(listNames("tag=dessert&tag=chocolate&tag_match=all")).To(Equal([]string{"Brownies"}))
+	})
+})
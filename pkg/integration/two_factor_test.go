@@ -0,0 +1,100 @@
+package integration_test
+
+import (
+	"bytes"
+	"encoding/base32"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+	"github.com/iplay88keys/my-recipe-library/pkg/totp"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("two factor authentication", func() {
+	var (
+		username string
+		password string
+		post     func(path, body, accessToken string, into interface{}) int
+	)
+
+	BeforeEach(func() {
+		_, err := db.Exec("DELETE FROM users WHERE id IS NOT NULL")
+		Expect(err).ToNot(HaveOccurred())
+
+		username = "two_factor_user"
+		password = "Pa3$word123"
+
+		usersRepo := repositories.NewUsersRepository(db)
+		_, err = usersRepo.Insert(ctx, username, username+"@example.com", password)
+		Expect(err).ToNot(HaveOccurred())
+
+		post = func(path, body, accessToken string, into interface{}) int {
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://localhost:%s/api/v1/%s", port, path), bytes.NewBufferString(body))
+			Expect(err).ToNot(HaveOccurred())
+			req.Header.Set("Content-Type", "application/json")
+			if accessToken != "" {
+				req.Header.Set("Authorization", "bearer "+accessToken)
+			}
+
+			resp, err := client.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+
+			respBody, err := io.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			if into != nil && len(respBody) > 0 {
+				Expect(json.Unmarshal(respBody, into)).To(Succeed())
+			}
+
+			return resp.StatusCode
+		}
+	})
+
+	It("requires a code after the password once two factor is enabled", func() {
+		credentials := fmt.Sprintf(`{"login": "%s", "password": "%s"}`, username, password)
+
+		var login users.UserLoginResponse
+		Expect(post("users/login", credentials, "", &login)).To(Equal(http.StatusOK))
+
+		var enrollment users.TwoFactorResponse
+		Expect(post("users/two-factor", "", login.AccessToken, &enrollment)).To(Equal(http.StatusOK))
+		Expect(enrollment.URI).To(HavePrefix("otpauth://totp/"))
+
+		secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(enrollment.Secret)
+		Expect(err).ToNot(HaveOccurred())
+
+		code := totp.Code(secret, time.Now())
+
+		var confirmation users.TwoFactorResponse
+		Expect(post("users/two-factor/confirm", fmt.Sprintf(`{"code": "%s"}`, code), login.AccessToken, &confirmation)).To(Equal(http.StatusOK))
+		Expect(confirmation.RecoveryCodes).To(HaveLen(10))
+
+		var challenge users.UserLoginResponse
+		Expect(post("users/login", credentials, "", &challenge)).To(Equal(http.StatusOK))
+		Expect(challenge.TwoFactorRequired).To(BeTrue())
+		Expect(challenge.AccessToken).To(BeEmpty())
+
+		// The code used to confirm cannot be replayed
+		body := fmt.Sprintf(`{"challenge_token": "%s", "code": "%s"}`, challenge.ChallengeToken, code)
+		Expect(post("users/login/two-factor", body, "", nil)).To(Equal(http.StatusUnauthorized))
+
+		body = fmt.Sprintf(`{"challenge_token": "%s", "code": "%s"}`, challenge.ChallengeToken, confirmation.RecoveryCodes[0])
+		var tokens users.UserLoginResponse
+		Expect(post("users/login/two-factor", body, "", &tokens)).To(Equal(http.StatusOK))
+		Expect(tokens.AccessToken).ToNot(BeEmpty())
+		Expect(tokens.RefreshToken).ToNot(BeEmpty())
+
+		Expect(post("users/login/two-factor", body, "", nil)).To(Equal(http.StatusUnauthorized))
+
+		Expect(post("users/login", credentials, "", &challenge)).To(Equal(http.StatusOK))
+		body = fmt.Sprintf(`{"challenge_token": "%s", "code": "%s"}`, challenge.ChallengeToken, confirmation.RecoveryCodes[0])
+		Expect(post("users/login/two-factor", body, "", nil)).To(Equal(http.StatusUnauthorized))
+	})
+})
@@ -0,0 +1,175 @@
+package integration_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/api/users"
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UpdateRecipe", func() {
+	var (
+		recipeID        int64
+		anotherRecipeID int64
+		token           string
+		body            []byte
+	)
+
+	BeforeEach(func() {
+		_, err := db.Exec("DELETE FROM users WHERE id IS NOT NULL")
+		Expect(err).ToNot(HaveOccurred())
+
+		username := "update_recipe_user"
+		password := "Pa3$word123"
+
+		usersRepo := repositories.NewUsersRepository(db)
+		userID, err := usersRepo.Insert(ctx, username, username+"@example.com", password)
+		Expect(err).ToNot(HaveOccurred())
+
+		anotherUserID, err := usersRepo.Insert(ctx, "update_recipe_different_user", "update_recipe_different_user@example.com", password)
+		Expect(err).ToNot(HaveOccurred())
+
+		res, err := db.Exec(`INSERT INTO recipes (
+            creator, name, description, servings, prep_time, total_time
+            ) VALUES (?, ?, ?, ?, ?, ?)`,
+			userID, "Ice Cream", "Yum.", 1, "1 m", "1 m")
+		Expect(err).ToNot(HaveOccurred())
+
+		recipeID, err = res.LastInsertId()
+		Expect(err).ToNot(HaveOccurred())
+
+		res, err = db.Exec(`INSERT INTO recipes (
+            creator, name, description, servings, prep_time, total_time
+            ) VALUES (?, ?, ?, ?, ?, ?)`,
+			anotherUserID, "Should not have access", "Hidden.", 1, "1 m", "1 m")
+		Expect(err).ToNot(HaveOccurred())
+
+		anotherRecipeID, err = res.LastInsertId()
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = db.Exec(`INSERT INTO recipe_steps (
+            recipe_id, step_no, instructions
+            ) VALUES (?, ?, ?)`,
+			recipeID, 1, "Place ice cream in bowl.")
+		Expect(err).ToNot(HaveOccurred())
+
+		reqBody := []byte(fmt.Sprintf(`{
+            "login": "%s",
+            "password": "%s"
+        }`, username, password))
+
+		resp, err := http.Post(fmt.Sprintf("http://localhost:%s/api/v1/users/login", port), "application/json", bytes.NewBuffer(reqBody))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(resp.StatusCode).To(Equal(200))
+
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		var loginResponse users.UserLoginResponse
+		err = json.Unmarshal(respBody, &loginResponse)
+		Expect(err).ToNot(HaveOccurred())
+
+		token = loginResponse.AccessToken
+
+		body = []byte(`{
+            "name": "Root Beer Float",
+            "description": "Delicious",
+            "servings": 2,
+            "total_time": "5 m",
+            "ingredients": [{
+                "name": "Root Beer",
+                "amount": "2",
+                "unit": "Cups",
+                "order_num": 1
+            }],
+            "steps": [{
+                "instructions": "Pour root beer over ice cream.",
+                "order_num": 1
+            }]
+        }`)
+	})
+
+	Context("authenticated", func() {
+		It("replaces the recipe, its ingredients and its steps", func() {
+			req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("http://localhost:%s/api/v1/recipes/%d", port, recipeID), bytes.NewBuffer(body))
+			Expect(err).ToNot(HaveOccurred())
+
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", fmt.Sprintf("bearer %s", token))
+
+			resp, err := client.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			var name string
+			var servings int
+			err = db.QueryRow("SELECT name, servings FROM recipes WHERE id=?", recipeID).Scan(&name, &servings)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(name).To(Equal("Root Beer Float"))
+			Expect(servings).To(Equal(2))
+
+			stepsRepo := repositories.NewStepsRepository(db)
+			steps, err := stepsRepo.GetForRecipe(ctx, recipeID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(steps).To(HaveLen(1))
+			Expect(*steps[0].Instructions).To(Equal("Pour root beer over ice cream."))
+
+			ingredientsRepo := repositories.NewIngredientsRepository(db)
+			ingredients, err := ingredientsRepo.GetForRecipe(ctx, recipeID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ingredients).To(HaveLen(1))
+			Expect(*ingredients[0].Ingredient).To(Equal("Root Beer"))
+		})
+
+		It("updates the recipe when none of its own fields change", func() {
+			for i := 0; i < 2; i++ {
+				req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("http://localhost:%s/api/v1/recipes/%d", port, recipeID), bytes.NewBuffer(body))
+				Expect(err).ToNot(HaveOccurred())
+
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("Authorization", fmt.Sprintf("bearer %s", token))
+
+				resp, err := client.Do(req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			}
+		})
+
+		It("returns forbidden if the recipe is not owned by that user", func() {
+			req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("http://localhost:%s/api/v1/recipes/%d", port, anotherRecipeID), bytes.NewBuffer(body))
+			Expect(err).ToNot(HaveOccurred())
+
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", fmt.Sprintf("bearer %s", token))
+
+			resp, err := client.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+
+			var name string
+			err = db.QueryRow("SELECT name FROM recipes WHERE id=?", anotherRecipeID).Scan(&name)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(name).To(Equal("Should not have access"))
+		})
+	})
+
+	It("returns unauthorized when not authenticated", func() {
+		req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("http://localhost:%s/api/v1/recipes/%d", port, recipeID), bytes.NewBuffer(body))
+		Expect(err).ToNot(HaveOccurred())
+
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+})
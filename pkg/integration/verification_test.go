@@ -0,0 +1,75 @@
+package integration_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/onsi/gomega/gbytes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("email verification", func() {
+	BeforeEach(func() {
+		_, err := db.Exec("DELETE FROM users WHERE id IS NOT NULL")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	emailVerified := func(username string) bool {
+		var verified bool
+		err := db.QueryRow("SELECT email_verified FROM users WHERE username=?", username).Scan(&verified)
+		Expect(err).ToNot(HaveOccurred())
+
+		return verified
+	}
+
+	It("verifies the email of a new account with the emailed link", func() {
+		reqBody := []byte(`{
+            "username": "verify_user",
+            "email": "verify_user@example.com",
+            "password": "Pa3$word123"
+        }`)
+
+		resp, err := http.Post(fmt.Sprintf("http://localhost:%s/api/v1/users/register", port), "application/json", bytes.NewBuffer(reqBody))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		Expect(emailVerified("verify_user")).To(BeFalse())
+
+		tokenPattern := regexp.MustCompile(`verify-email\?token=([A-Za-z0-9_.-]+)`)
+		Eventually(session.Out).Should(gbytes.Say(tokenPattern.String()))
+		verificationToken := tokenPattern.FindStringSubmatch(string(session.Out.Contents()))[1]
+
+		resp, err = http.Get(fmt.Sprintf("http://localhost:%s/api/v1/users/verify?token=%s", port, url.QueryEscape(verificationToken)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+
+		Expect(emailVerified("verify_user")).To(BeTrue())
+	})
+
+	It("rejects a tampered verification token", func() {
+		resp, err := http.Get(fmt.Sprintf("http://localhost:%s/api/v1/users/verify?token=not.a.token", port))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("limits how often verification emails are resent to an address", func() {
+		// Redis is not cleared between runs, so each run uses a fresh address
+		reqBody := []byte(fmt.Sprintf(`{"email": "nobody+%d@example.com"}`, time.Now().UnixNano()))
+
+		for i := 0; i < 3; i++ {
+			resp, err := http.Post(fmt.Sprintf("http://localhost:%s/api/v1/users/verify/resend", port), "application/json", bytes.NewBuffer(reqBody))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+		}
+
+		resp, err := http.Post(fmt.Sprintf("http://localhost:%s/api/v1/users/verify/resend", port), "application/json", bytes.NewBuffer(reqBody))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusTooManyRequests))
+	})
+})
@@ -0,0 +1,81 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/logging"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Logging", func() {
+	var (
+		buf *bytes.Buffer
+		ctx context.Context
+	)
+
+	BeforeEach(func() {
+		buf = &bytes.Buffer{}
+		ctx = logging.WithRequestID(context.Background(), "some-request-id")
+	})
+
+	It("writes json with the request id from the context", func() {
+		logger, err := logging.New(buf, "json", "info")
+		Expect(err).ToNot(HaveOccurred())
+
+		logger.With("user_id", 10).InfoContext(ctx, "Something happened", "recipe_id", 2)
+
+		var line map[string]interface{}
+		Expect(json.Unmarshal(buf.Bytes(), &line)).To(Succeed())
+		Expect(line).To(HaveKeyWithValue("level", "INFO"))
+		Expect(line).To(HaveKeyWithValue("msg", "Something happened"))
+		Expect(line).To(HaveKeyWithValue("request_id", "some-request-id"))
+		Expect(line).To(HaveKeyWithValue("user_id", BeNumerically("==", 10)))
+		Expect(line).To(HaveKeyWithValue("recipe_id", BeNumerically("==", 2)))
+	})
+
+	It("writes text", func() {
+		logger, err := logging.New(buf, "text", "info")
+		Expect(err).ToNot(HaveOccurred())
+
+		logger.InfoContext(ctx, "Something happened")
+
+		Expect(buf.String()).To(ContainSubstring(`level=INFO msg="Something happened" request_id=some-request-id`))
+	})
+
+	It("leaves out the request id if the context has none", func() {
+		logger, err := logging.New(buf, "json", "info")
+		Expect(err).ToNot(HaveOccurred())
+
+		logger.InfoContext(context.Background(), "Something happened")
+
+		Expect(buf.String()).ToNot(ContainSubstring("request_id"))
+	})
+
+	It("only writes lines at or above the level", func() {
+		logger, err := logging.New(buf, "text", "warn")
+		Expect(err).ToNot(HaveOccurred())
+
+		logger.InfoContext(ctx, "Not written")
+		logger.WarnContext(ctx, "Written")
+
+		Expect(buf.String()).ToNot(ContainSubstring("Not written"))
+		Expect(buf.String()).To(ContainSubstring("Written"))
+	})
+
+	It("returns an error for an unknown format or level", func() {
+		_, err := logging.New(buf, "xml", "info")
+		Expect(err).To(MatchError(`invalid log format "xml"`))
+
+		_, err = logging.New(buf, "json", "loud")
+		Expect(err).To(MatchError(`invalid log level "loud"`))
+	})
+
+	It("returns the request id from the context", func() {
+		Expect(logging.RequestID(ctx)).To(Equal("some-request-id"))
+		Expect(logging.RequestID(context.Background())).To(BeEmpty())
+	})
+})
@@ -0,0 +1,72 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrInvalidHeader = errors.New("mail headers cannot contain line breaks")
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// LogMailer writes messages to a writer instead of delivering them. It is
+// meant for local development, where the writer is usually stdout.
+type LogMailer struct {
+	from string
+	w    io.Writer
+	mu   sync.Mutex
+}
+
+func NewLogMailer(from string, w io.Writer) *LogMailer {
+	return &LogMailer{from: from, w: w}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg *Message) error {
+	data, err := format(m.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err = fmt.Fprintf(m.w, "----- mail -----\n%s\n----- end mail -----\n", data)
+	return err
+}
+
+// format renders a message as a plain text RFC 5322 message.
+func format(from string, msg *Message, date time.Time) ([]byte, error) {
+	for _, header := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(header, "\r\n") {
+			return nil, ErrInvalidHeader
+		}
+	}
+
+	body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+	body = strings.ReplaceAll(body, "\n", "\r\n")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(body)
+
+	return []byte(b.String()), nil
+}
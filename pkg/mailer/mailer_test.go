@@ -0,0 +1,166 @@
+package mailer_test
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/textproto"
+	"strings"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/mailer"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Mailer", func() {
+	var msg *mailer.Message
+
+	BeforeEach(func() {
+		msg = &mailer.Message{
+			To:      "cook@example.com",
+			Subject: "Reset your password",
+			Body:    "Follow this link:\nhttp://localhost/reset",
+		}
+	})
+
+	Describe("LogMailer", func() {
+		It("writes the message", func() {
+			var out bytes.Buffer
+			m := mailer.NewLogMailer("recipes@example.com", &out)
+
+			err := m.Send(context.Background(), msg)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(out.String()).To(ContainSubstring("From: recipes@example.com\r\n"))
+			Expect(out.String()).To(ContainSubstring("To: cook@example.com\r\n"))
+			Expect(out.String()).To(ContainSubstring("Subject: Reset your password\r\n"))
+			Expect(out.String()).To(ContainSubstring("\r\n\r\nFollow this link:\r\nhttp://localhost/reset"))
+		})
+
+		It("rejects headers with line breaks", func() {
+			var out bytes.Buffer
+			m := mailer.NewLogMailer("recipes@example.com", &out)
+
+			msg.Subject = "Hello\r\nBcc: victim@example.com"
+
+			err := m.Send(context.Background(), msg)
+			Expect(err).To(MatchError(mailer.ErrInvalidHeader))
+			Expect(out.Len()).To(BeZero())
+		})
+	})
+
+	Describe("SMTPMailer", func() {
+		It("delivers the message to the server", func() {
+			server := newFakeSMTPServer()
+			defer server.Close()
+
+			host, port, err := net.SplitHostPort(server.Addr())
+			Expect(err).ToNot(HaveOccurred())
+
+			m, err := mailer.NewSMTPMailer(mailer.SMTPConfig{
+				Host: host,
+				Port: port,
+				From: "recipes@example.com",
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = m.Send(context.Background(), msg)
+			Expect(err).ToNot(HaveOccurred())
+
+			var received *receivedMail
+			Eventually(server.received).Should(Receive(&received))
+			Expect(received.from).To(Equal("recipes@example.com"))
+			Expect(received.to).To(Equal([]string{"cook@example.com"}))
+			Expect(received.data).To(ContainSubstring("Subject: Reset your password\n"))
+			Expect(received.data).To(ContainSubstring("http://localhost/reset"))
+		})
+
+		It("requires a host and a from address", func() {
+			_, err := mailer.NewSMTPMailer(mailer.SMTPConfig{From: "recipes@example.com"})
+			Expect(err).To(MatchError("an SMTP host is required"))
+
+			_, err = mailer.NewSMTPMailer(mailer.SMTPConfig{Host: "localhost"})
+			Expect(err).To(MatchError("a from address is required"))
+		})
+	})
+})
+
+type receivedMail struct {
+	from string
+	to   []string
+	data string
+}
+
+// fakeSMTPServer accepts a single message without authentication.
+type fakeSMTPServer struct {
+	listener net.Listener
+	received chan *receivedMail
+}
+
+func newFakeSMTPServer() *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).ToNot(HaveOccurred())
+
+	s := &fakeSMTPServer{
+		listener: listener,
+		received: make(chan *receivedMail, 1),
+	}
+
+	go s.serve()
+
+	return s
+}
+
+func (s *fakeSMTPServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeSMTPServer) Close() {
+	s.listener.Close()
+}
+
+func (s *fakeSMTPServer) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	text := textproto.NewConn(conn)
+	mail := &receivedMail{}
+
+	text.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch command {
+		case "EHLO", "HELO":
+			text.PrintfLine("250 localhost")
+		case "MAIL":
+			mail.from = strings.Trim(strings.TrimPrefix(line, "MAIL FROM:"), "<>")
+			text.PrintfLine("250 OK")
+		case "RCPT":
+			mail.to = append(mail.to, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+			text.PrintfLine("250 OK")
+		case "DATA":
+			text.PrintfLine("354 Go ahead")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			mail.data = string(data)
+			text.PrintfLine("250 OK")
+		case "QUIT":
+			text.PrintfLine("221 Bye")
+			s.received <- mail
+			return
+		default:
+			text.PrintfLine("502 Not implemented")
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package migrations
+
+import (
+	"io/fs"
+
+	schema "github.com/iplay88keys/my-recipe-library/db"
+)
+
+// Embedded returns the migrations compiled into the binary from
+// db/migrations.
+func Embedded() fs.FS {
+	migrations, err := fs.Sub(schema.Migrations, "migrations")
+	if err != nil {
+		panic(err)
+	}
+
+	return migrations
+}
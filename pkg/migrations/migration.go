@@ -0,0 +1,140 @@
+package migrations
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var scriptPattern = regexp.MustCompile(`^V(\d+(?:[._]\d+)*)__(.+)\.sql$`)
+
+type Migration struct {
+	Version     string
+	Description string
+	Script      string
+	Checksum    int32
+	SQL         string
+
+	versionParts []int
+}
+
+// Load reads every versioned migration in the root of fsys. Scripts follow
+// the flyway naming convention, e.g. V2__Add_Tags.sql, and are returned in
+// version order.
+func Load(fsys fs.FS) ([]*Migration, error) {
+	scripts, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []*Migration
+	seen := make(map[string]string)
+	for _, script := range scripts {
+		matches := scriptPattern.FindStringSubmatch(path.Base(script))
+		if matches == nil {
+			return nil, fmt.Errorf("migration '%s' does not match the V<version>__<description>.sql naming convention", script)
+		}
+
+		contents, err := fs.ReadFile(fsys, script)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration '%s': %s", script, err.Error())
+		}
+
+		version := strings.ReplaceAll(matches[1], "_", ".")
+		parts, err := parseVersion(version)
+		if err != nil {
+			return nil, fmt.Errorf("migration '%s' has an invalid version: %s", script, err.Error())
+		}
+
+		normalized := formatVersion(parts)
+		if other, ok := seen[normalized]; ok {
+			return nil, fmt.Errorf("migrations '%s' and '%s' share version %s", other, script, version)
+		}
+		seen[normalized] = script
+
+		migrations = append(migrations, &Migration{
+			Version:      version,
+			Description:  strings.ReplaceAll(matches[2], "_", " "),
+			Script:       script,
+			Checksum:     Checksum(contents),
+			SQL:          string(contents),
+			versionParts: parts,
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return compareVersions(migrations[i].versionParts, migrations[j].versionParts) < 0
+	})
+
+	return migrations, nil
+}
+
+// Checksum matches the value flyway stores for a SQL migration: a CRC32 of
+// the script's lines joined without their line endings, ignoring any leading
+// byte order mark.
+func Checksum(contents []byte) int32 {
+	contents = bytes.TrimPrefix(contents, []byte("\ufeff"))
+
+	crc := crc32.NewIEEE()
+	for _, line := range bytes.FieldsFunc(contents, func(r rune) bool {
+		return r == '\n' || r == '\r'
+	}) {
+		crc.Write(line)
+	}
+
+	return int32(crc.Sum32())
+}
+
+func parseVersion(version string) ([]int, error) {
+	var parts []int
+	for _, part := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, n)
+	}
+
+	return parts, nil
+}
+
+func formatVersion(parts []int) string {
+	trimmed := parts
+	for len(trimmed) > 1 && trimmed[len(trimmed)-1] == 0 {
+		trimmed = trimmed[:len(trimmed)-1]
+	}
+
+	formatted := make([]string, len(trimmed))
+	for i, part := range trimmed {
+		formatted[i] = strconv.Itoa(part)
+	}
+
+	return strings.Join(formatted, ".")
+}
+
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
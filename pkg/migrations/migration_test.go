@@ -0,0 +1,80 @@
+package migrations_test
+
+import (
+	"hash/crc32"
+	"testing/fstest"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/migrations"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Migration", func() {
+	Describe("Load", func() {
+		It("loads the migrations in version order", func() {
+			fsys := fstest.MapFS{
+				"V10__Later.sql":          {Data: []byte("SELECT 10;")},
+				"V2__Add_Tags.sql":        {Data: []byte("SELECT 2;")},
+				"V2_1__Backfill_Tags.sql": {Data: []byte("SELECT 2.1;")},
+				"V1__Initial_Tables.sql":  {Data: []byte("SELECT 1;")},
+				"README.md":               {Data: []byte("not a migration")},
+			}
+
+			loaded, err := migrations.Load(fsys)
+			Expect(err).ToNot(HaveOccurred())
+
+			var versions, descriptions []string
+			for _, migration := range loaded {
+				versions = append(versions, migration.Version)
+				descriptions = append(descriptions, migration.Description)
+			}
+
+			Expect(versions).To(Equal([]string{"1", "2", "2.1", "10"}))
+			Expect(descriptions).To(Equal([]string{"Initial Tables", "Add Tags", "Backfill Tags", "Later"}))
+			Expect(loaded[1].Script).To(Equal("V2__Add_Tags.sql"))
+			Expect(loaded[1].SQL).To(Equal("SELECT 2;"))
+		})
+
+		It("returns an error for scripts that do not follow the naming convention", func() {
+			fsys := fstest.MapFS{
+				"V1_Initial_Tables.sql": {Data: []byte("SELECT 1;")},
+			}
+
+			_, err := migrations.Load(fsys)
+			Expect(err).To(MatchError(ContainSubstring("naming convention")))
+		})
+
+		It("returns an error when two scripts share a version", func() {
+			fsys := fstest.MapFS{
+				"V1__Initial_Tables.sql": {Data: []byte("SELECT 1;")},
+				"V1.0__Also_Initial.sql": {Data: []byte("SELECT 1;")},
+			}
+
+			_, err := migrations.Load(fsys)
+			Expect(err).To(MatchError(ContainSubstring("share version")))
+		})
+
+		It("loads the embedded migrations", func() {
+			loaded, err := migrations.Load(migrations.Embedded())
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(len(loaded)).To(BeNumerically(">=", 2))
+			Expect(loaded[0].Script).To(Equal("V1__Initial_Tables.sql"))
+		})
+	})
+
+	Describe("Checksum", func() {
+		It("matches a CRC32 of the lines without their line endings", func() {
+			expected := int32(crc32.ChecksumIEEE([]byte("SELECT 1;SELECT 2;")))
+
+			Expect(migrations.Checksum([]byte("SELECT 1;\nSELECT 2;\n"))).To(Equal(expected))
+			Expect(migrations.Checksum([]byte("SELECT 1;\r\nSELECT 2;\r\n"))).To(Equal(expected))
+			Expect(migrations.Checksum([]byte("\ufeffSELECT 1;\n\nSELECT 2;"))).To(Equal(expected))
+		})
+
+		It("changes when the script changes", func() {
+			Expect(migrations.Checksum([]byte("SELECT 1;"))).ToNot(Equal(migrations.Checksum([]byte("SELECT 2;"))))
+		})
+	})
+})
@@ -0,0 +1,362 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+type State string
+
+const (
+	StatePending          State = "Pending"
+	StateApplied          State = "Success"
+	StateFailed           State = "Failed"
+	StateChecksumMismatch State = "Checksum mismatch"
+	StateMissing          State = "Missing"
+	StateOutOfOrder       State = "Out of order"
+)
+
+type MigrationStatus struct {
+	Version     string
+	Description string
+	State       State
+	InstalledOn string
+}
+
+type appliedMigration struct {
+	rank        int
+	version     string
+	description string
+	checksum    sql.NullInt32
+	success     bool
+	installedOn sql.NullString
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []*Migration
+}
+
+func NewMigrator(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Up validates the history table and applies every pending migration in
+// version order. It returns the migrations that were applied.
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the database: %s", err.Error())
+	}
+	defer conn.Close()
+
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, getLockQuery).Scan(&locked); err != nil || locked.Int64 != 1 {
+		return nil, errors.New("failed to acquire the migration lock")
+	}
+	defer conn.ExecContext(context.Background(), releaseLockQuery)
+
+	if _, err := conn.ExecContext(ctx, createHistoryTableQuery); err != nil {
+		slog.ErrorContext(ctx, "Failed to create migration history table", "error", err)
+		return nil, errors.New("failed to create migration history table")
+	}
+
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := m.compare(applied)
+	if err := validate(statuses); err != nil {
+		return nil, err
+	}
+
+	rank := 0
+	for _, a := range applied {
+		if a.rank > rank {
+			rank = a.rank
+		}
+	}
+
+	var ran []*Migration
+	for _, migration := range m.migrations {
+		if statuses[migration.Version].State != StatePending {
+			continue
+		}
+
+		rank++
+		if err := m.apply(ctx, conn, migration, rank); err != nil {
+			return ran, err
+		}
+
+		ran = append(ran, migration)
+	}
+
+	return ran, nil
+}
+
+// Status reports every migration known locally or recorded in the history
+// table, in version order.
+func (m *Migrator) Status(ctx context.Context) ([]*MigrationStatus, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the database: %s", err.Error())
+	}
+	defer conn.Close()
+
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	return sortStatuses(m.compare(applied)), nil
+}
+
+// Validate returns an error if the history table has drifted from the
+// migrations that are available locally.
+func (m *Migrator) Validate(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	return validate(statusMap(statuses))
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration *Migration, rank int) error {
+	start := time.Now()
+
+	var failure error
+	for _, statement := range splitStatements(migration.SQL) {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			failure = err
+			break
+		}
+	}
+
+	elapsed := time.Since(start).Milliseconds()
+	_, err := conn.ExecContext(ctx, insertHistoryQuery,
+		rank,
+		migration.Version,
+		migration.Description,
+		migration.Script,
+		migration.Checksum,
+		elapsed,
+		failure == nil,
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to record migration", "version", migration.Version, "error", err)
+		return fmt.Errorf("failed to record migration %s", migration.Version)
+	}
+
+	if failure != nil {
+		slog.ErrorContext(ctx, "Migration failed", "version", migration.Version, "error", failure)
+		return fmt.Errorf("migration %s failed: %s", migration.Version, failure.Error())
+	}
+
+	return nil
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) ([]*appliedMigration, error) {
+	var tables int
+	if err := conn.QueryRowContext(ctx, historyTableExistsQuery).Scan(&tables); err != nil {
+		slog.ErrorContext(ctx, "Failed to check for the migration history table", "error", err)
+		return nil, errors.New("failed to check for the migration history table")
+	}
+
+	if tables == 0 {
+		return nil, nil
+	}
+
+	rows, err := conn.QueryContext(ctx, listHistoryQuery)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to fetch migration history", "error", err)
+		return nil, errors.New("failed to fetch migration history")
+	}
+	defer rows.Close()
+
+	var applied []*appliedMigration
+	for rows.Next() {
+		a := &appliedMigration{}
+		if err := rows.Scan(&a.rank, &a.version, &a.description, &a.checksum, &a.success, &a.installedOn); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan migration history", "error", err)
+			return nil, errors.New("failed to scan migration history")
+		}
+		applied = append(applied, a)
+	}
+	if rows.Err() != nil {
+		slog.ErrorContext(ctx, "Failed to loop through migration history", "error", rows.Err())
+		return nil, errors.New("failed to retrieve migration history")
+	}
+
+	return applied, nil
+}
+
+func (m *Migrator) compare(applied []*appliedMigration) map[string]*MigrationStatus {
+	statuses := make(map[string]*MigrationStatus)
+
+	var latest []int
+	local := make(map[string]*Migration)
+	for _, migration := range m.migrations {
+		local[formatVersion(migration.versionParts)] = migration
+	}
+
+	for _, a := range applied {
+		parts, err := parseVersion(a.version)
+		if err != nil {
+			continue
+		}
+
+		status := &MigrationStatus{
+			Version:     a.version,
+			Description: a.description,
+			State:       StateApplied,
+			InstalledOn: a.installedOn.String,
+		}
+
+		migration, ok := local[formatVersion(parts)]
+		switch {
+		case !a.success:
+			status.State = StateFailed
+		case !ok:
+			status.State = StateMissing
+		case !a.checksum.Valid || a.checksum.Int32 != migration.Checksum:
+			status.State = StateChecksumMismatch
+		}
+
+		if ok {
+			status.Version = migration.Version
+		}
+		statuses[status.Version] = status
+
+		if a.success && compareVersions(parts, latest) > 0 {
+			latest = parts
+		}
+	}
+
+	for _, migration := range m.migrations {
+		if _, ok := statuses[migration.Version]; ok {
+			continue
+		}
+
+		state := StatePending
+		if compareVersions(migration.versionParts, latest) < 0 {
+			state = StateOutOfOrder
+		}
+
+		statuses[migration.Version] = &MigrationStatus{
+			Version:     migration.Version,
+			Description: migration.Description,
+			State:       state,
+		}
+	}
+
+	return statuses
+}
+
+func validate(statuses map[string]*MigrationStatus) error {
+	var problems []string
+	for _, status := range sortStatuses(statuses) {
+		switch status.State {
+		case StateFailed:
+			problems = append(problems, fmt.Sprintf("migration %s failed previously and must be repaired by hand", status.Version))
+		case StateMissing:
+			problems = append(problems, fmt.Sprintf("migration %s was applied but is not available locally", status.Version))
+		case StateChecksumMismatch:
+			problems = append(problems, fmt.Sprintf("migration %s has changed since it was applied", status.Version))
+		case StateOutOfOrder:
+			problems = append(problems, fmt.Sprintf("migration %s is older than the latest applied migration", status.Version))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("migration validation failed: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+func statusMap(statuses []*MigrationStatus) map[string]*MigrationStatus {
+	m := make(map[string]*MigrationStatus, len(statuses))
+	for _, status := range statuses {
+		m[status.Version] = status
+	}
+
+	return m
+}
+
+func sortStatuses(statuses map[string]*MigrationStatus) []*MigrationStatus {
+	sorted := make([]*MigrationStatus, 0, len(statuses))
+	for _, status := range statuses {
+		sorted = append(sorted, status)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		a, _ := parseVersion(sorted[i].Version)
+		b, _ := parseVersion(sorted[j].Version)
+		return compareVersions(a, b) < 0
+	})
+
+	return sorted
+}
+
+const getLockQuery = "SELECT GET_LOCK('flyway_schema_history', 60)"
+const releaseLockQuery = "SELECT RELEASE_LOCK('flyway_schema_history')"
+
+const historyTableExistsQuery = `
+  SELECT COUNT(*)
+  FROM information_schema.tables
+  WHERE table_schema=DATABASE() AND table_name='flyway_schema_history'
+`
+
+const createHistoryTableQuery = `
+  CREATE TABLE IF NOT EXISTS flyway_schema_history
+  (
+    installed_rank INT           NOT NULL,
+    version        VARCHAR(50),
+    description    VARCHAR(200)  NOT NULL,
+    type           VARCHAR(20)   NOT NULL,
+    script         VARCHAR(1000) NOT NULL,
+    checksum       INT,
+    installed_by   VARCHAR(100)  NOT NULL,
+    installed_on   TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    execution_time INT           NOT NULL,
+    success        BOOL          NOT NULL,
+
+    CONSTRAINT flyway_schema_history_pk PRIMARY KEY (installed_rank),
+    INDEX flyway_schema_history_s_idx (success)
+  ) ENGINE = INNODB
+`
+
+const listHistoryQuery = `
+  SELECT installed_rank,
+         version,
+         description,
+         checksum,
+         success,
+         installed_on
+  FROM flyway_schema_history
+  WHERE type='SQL' AND version IS NOT NULL
+  ORDER BY installed_rank
+`
+
+const insertHistoryQuery = `
+  INSERT INTO flyway_schema_history (
+    installed_rank, version, description, type, script, checksum, installed_by, execution_time, success
+  ) VALUES (?, ?, ?, 'SQL', ?, ?, SUBSTRING_INDEX(USER(), '@', 1), ?, ?)
+`
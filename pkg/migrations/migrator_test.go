@@ -0,0 +1,242 @@
+package migrations_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing/fstest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/migrations"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Migrator", func() {
+	var (
+		db       *sql.DB
+		mock     sqlmock.Sqlmock
+		fsys     fstest.MapFS
+		migrator *migrations.Migrator
+		ctx      context.Context
+
+		historyColumns []string
+	)
+
+	BeforeEach(func() {
+		var err error
+		db, mock, err = sqlmock.New()
+		Expect(err).ToNot(HaveOccurred())
+
+		fsys = fstest.MapFS{
+			"V1__Initial_Tables.sql": {Data: []byte(
+				"CREATE TABLE a (name VARCHAR(10) DEFAULT ';');\n" +
+					"-- a comment; with a semicolon\n" +
+					"CREATE TABLE b (id INT);\n",
+			)},
+			"V2__Add_Tags.sql": {Data: []byte("CREATE TABLE tags (id INT);\n/* trailing; comment */\n")},
+		}
+
+		migrator, err = migrations.NewMigrator(db, fsys)
+		Expect(err).ToNot(HaveOccurred())
+
+		ctx = context.Background()
+		historyColumns = []string{"installed_rank", "version", "description", "checksum", "success", "installed_on"}
+	})
+
+	checksumOf := func(script string) int32 {
+		return migrations.Checksum(fsys[script].Data)
+	}
+
+	expectLock := func() {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT GET_LOCK('flyway_schema_history', 60)")).
+			WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(1))
+		mock.ExpectExec("^CREATE TABLE IF NOT EXISTS flyway_schema_history").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+
+	expectHistory := func(rows *sqlmock.Rows) {
+		mock.ExpectQuery("^SELECT COUNT\\(\\*\\) FROM information_schema.tables").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery("^SELECT installed_rank, .+ FROM flyway_schema_history").
+			WillReturnRows(rows)
+	}
+
+	expectUnlock := func() {
+		mock.ExpectExec(regexp.QuoteMeta("SELECT RELEASE_LOCK('flyway_schema_history')")).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+
+	Describe("Up", func() {
+		It("applies every pending migration one statement at a time", func() {
+			expectLock()
+			expectHistory(sqlmock.NewRows(historyColumns))
+
+			mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE a (name VARCHAR(10) DEFAULT ';')")).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(regexp.QuoteMeta("-- a comment; with a semicolon\nCREATE TABLE b (id INT)")).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("^INSERT INTO flyway_schema_history").
+				WithArgs(1, "1", "Initial Tables", "V1__Initial_Tables.sql", checksumOf("V1__Initial_Tables.sql"), sqlmock.AnyArg(), true).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE tags (id INT)")).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("^INSERT INTO flyway_schema_history").
+				WithArgs(2, "2", "Add Tags", "V2__Add_Tags.sql", checksumOf("V2__Add_Tags.sql"), sqlmock.AnyArg(), true).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			expectUnlock()
+
+			applied, err := migrator.Up(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(applied).To(HaveLen(2))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("only applies migrations that are not in the history table", func() {
+			expectLock()
+			expectHistory(sqlmock.NewRows(historyColumns).
+				AddRow(1, "1", "Initial Tables", checksumOf("V1__Initial_Tables.sql"), true, "2024-01-01 00:00:00"))
+
+			mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE tags (id INT)")).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("^INSERT INTO flyway_schema_history").
+				WithArgs(2, "2", "Add Tags", "V2__Add_Tags.sql", checksumOf("V2__Add_Tags.sql"), sqlmock.AnyArg(), true).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			expectUnlock()
+
+			applied, err := migrator.Up(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(applied).To(HaveLen(1))
+			Expect(applied[0].Version).To(Equal("2"))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("refuses to run when an applied migration has changed", func() {
+			expectLock()
+			expectHistory(sqlmock.NewRows(historyColumns).
+				AddRow(1, "1", "Initial Tables", 12345, true, "2024-01-01 00:00:00"))
+			expectUnlock()
+
+			applied, err := migrator.Up(ctx)
+			Expect(err).To(MatchError(ContainSubstring("migration 1 has changed since it was applied")))
+			Expect(applied).To(BeEmpty())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("refuses to run when a previous migration failed", func() {
+			expectLock()
+			expectHistory(sqlmock.NewRows(historyColumns).
+				AddRow(1, "1", "Initial Tables", checksumOf("V1__Initial_Tables.sql"), false, "2024-01-01 00:00:00"))
+			expectUnlock()
+
+			_, err := migrator.Up(ctx)
+			Expect(err).To(MatchError(ContainSubstring("migration 1 failed previously")))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("records a failed migration and stops", func() {
+			expectLock()
+			expectHistory(sqlmock.NewRows(historyColumns))
+
+			mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE a")).
+				WillReturnError(errors.New("some error"))
+			mock.ExpectExec("^INSERT INTO flyway_schema_history").
+				WithArgs(1, "1", "Initial Tables", "V1__Initial_Tables.sql", checksumOf("V1__Initial_Tables.sql"), sqlmock.AnyArg(), false).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			expectUnlock()
+
+			applied, err := migrator.Up(ctx)
+			Expect(err).To(MatchError(ContainSubstring("migration 1 failed: some error")))
+			Expect(applied).To(BeEmpty())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the lock cannot be acquired", func() {
+			mock.ExpectQuery("GET_LOCK").
+				WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(0))
+
+			_, err := migrator.Up(ctx)
+			Expect(err).To(MatchError("failed to acquire the migration lock"))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("Status", func() {
+		It("reports applied, pending and missing migrations in version order", func() {
+			expectHistory(sqlmock.NewRows(historyColumns).
+				AddRow(1, "1", "Initial Tables", checksumOf("V1__Initial_Tables.sql"), true, "2024-01-01 00:00:00").
+				AddRow(2, "1.5", "Removed Locally", 999, true, "2024-01-02 00:00:00"))
+
+			statuses, err := migrator.Status(ctx)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(statuses).To(Equal([]*migrations.MigrationStatus{{
+				Version:     "1",
+				Description: "Initial Tables",
+				State:       migrations.StateApplied,
+				InstalledOn: "2024-01-01 00:00:00",
+			}, {
+				Version:     "1.5",
+				Description: "Removed Locally",
+				State:       migrations.StateMissing,
+				InstalledOn: "2024-01-02 00:00:00",
+			}, {
+				Version:     "2",
+				Description: "Add Tags",
+				State:       migrations.StatePending,
+			}}))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("reports everything as pending when the history table does not exist", func() {
+			mock.ExpectQuery("^SELECT COUNT\\(\\*\\) FROM information_schema.tables").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+			statuses, err := migrator.Status(ctx)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(statuses).To(HaveLen(2))
+			Expect(statuses[0].State).To(Equal(migrations.StatePending))
+			Expect(statuses[1].State).To(Equal(migrations.StatePending))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("succeeds when the applied migrations match", func() {
+			expectHistory(sqlmock.NewRows(historyColumns).
+				AddRow(1, "1", "Initial Tables", checksumOf("V1__Initial_Tables.sql"), true, "2024-01-01 00:00:00"))
+
+			Expect(migrator.Validate(ctx)).To(Succeed())
+		})
+
+		It("fails when a migration is older than the latest applied one", func() {
+			expectHistory(sqlmock.NewRows(historyColumns).
+				AddRow(1, "2", "Add Tags", checksumOf("V2__Add_Tags.sql"), true, "2024-01-01 00:00:00"))
+
+			err := migrator.Validate(ctx)
+			Expect(err).To(MatchError(ContainSubstring("migration 1 is older than the latest applied migration")))
+		})
+
+		It("fails when an applied migration has changed", func() {
+			expectHistory(sqlmock.NewRows(historyColumns).
+				AddRow(1, "1", "Initial Tables", 12345, true, "2024-01-01 00:00:00"))
+
+			err := migrator.Validate(ctx)
+			Expect(err).To(MatchError(ContainSubstring("migration 1 has changed since it was applied")))
+		})
+	})
+})
@@ -0,0 +1,86 @@
+package migrations
+
+import "strings"
+
+// splitStatements breaks a script into the individual statements the MySQL
+// driver can execute, skipping semicolons inside quotes and comments.
+// Statements that only contain comments are dropped.
+func splitStatements(script string) []string {
+	var (
+		statements []string
+		current    strings.Builder
+		hasContent bool
+		quote      rune
+	)
+
+	runes := []rune(script)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		switch {
+		case quote != 0:
+			current.WriteRune(r)
+			if r == '\\' && quote != '`' && next != 0 {
+				current.WriteRune(next)
+				i++
+			} else if r == quote {
+				quote = 0
+			}
+			continue
+
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+			hasContent = true
+			current.WriteRune(r)
+			continue
+
+		case r == '#' || (r == '-' && next == '-' && (i+2 >= len(runes) || isSpace(runes[i+2]))):
+			end := i
+			for end < len(runes) && runes[end] != '\n' {
+				end++
+			}
+			current.WriteString(string(runes[i:end]))
+			i = end - 1
+			continue
+
+		case r == '/' && next == '*':
+			end := i + 3
+			for end < len(runes) && !(runes[end-1] == '*' && runes[end] == '/') {
+				end++
+			}
+			if end >= len(runes) {
+				end = len(runes) - 1
+			}
+			current.WriteString(string(runes[i : end+1]))
+			i = end
+			continue
+
+		case r == ';':
+			if hasContent {
+				statements = append(statements, strings.TrimSpace(current.String()))
+			}
+			current.Reset()
+			hasContent = false
+			continue
+		}
+
+		if !isSpace(r) {
+			hasContent = true
+		}
+		current.WriteRune(r)
+	}
+
+	if hasContent {
+		statements = append(statements, strings.TrimSpace(current.String()))
+	}
+
+	return statements
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
@@ -0,0 +1,70 @@
+package oidc
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/token"
+)
+
+// clockSkew is how far the provider's clock may be ahead of ours.
+const clockSkew = time.Minute
+
+type idTokenClaims struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          audience `json:"aud"`
+	AuthorizedParty   string   `json:"azp"`
+	ExpiresAt         int64    `json:"exp"`
+	IssuedAt          int64    `json:"iat"`
+	Nonce             string   `json:"nonce"`
+	Email             string   `json:"email"`
+	EmailVerified     bool     `json:"email_verified"`
+	PreferredUsername string   `json:"preferred_username"`
+}
+
+func (c *idTokenClaims) Valid() error {
+	if c.Subject == "" || c.ExpiresAt == 0 || c.IssuedAt == 0 {
+		return token.ErrMissingClaims
+	}
+
+	now := time.Now()
+	if now.Unix() >= c.ExpiresAt {
+		return token.ErrExpired
+	}
+
+	if now.Add(clockSkew).Unix() < c.IssuedAt {
+		return token.ErrIssuedInFuture
+	}
+
+	return nil
+}
+
+// audience is a single string or a list of strings in an ID token.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+
+	*a = multiple
+	return nil
+}
+
+func (a audience) contains(clientID string) bool {
+	for _, aud := range a {
+		if aud == clientID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,312 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/token"
+)
+
+var (
+	ErrInvalidGrant   = errors.New("authorization code is invalid or has expired")
+	ErrInvalidIDToken = errors.New("id token is invalid")
+)
+
+var defaultScopes = []string{"openid", "email", "profile"}
+
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+// Identity is who the provider says signed in.
+type Identity struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+}
+
+// AuthRequest holds the values for one authorization request that have to be
+// kept until the user comes back with a code.
+type AuthRequest struct {
+	State        string
+	Nonce        string
+	CodeVerifier string
+}
+
+func NewAuthRequest() (*AuthRequest, error) {
+	values := make([]string, 3)
+	for i := range values {
+		raw := make([]byte, 32)
+		_, err := rand.Read(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		values[i] = base64.RawURLEncoding.EncodeToString(raw)
+	}
+
+	return &AuthRequest{
+		State:        values[0],
+		Nonce:        values[1],
+		CodeVerifier: values[2],
+	}, nil
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider is an OpenID Connect relying party for a single identity provider
+// using the authorization code flow with PKCE.
+type Provider struct {
+	config   Config
+	client   *http.Client
+	metadata *metadata
+
+	mu   sync.RWMutex
+	keys map[string]token.Verifier
+}
+
+// NewProvider reads the provider's discovery document. A nil client uses one
+// with a short timeout.
+func NewProvider(ctx context.Context, cfg Config, client *http.Client) (*Provider, error) {
+	if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("an issuer, client id and redirect url are required")
+	}
+
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	p := &Provider{
+		config: cfg,
+		client: client,
+		keys:   make(map[string]token.Verifier),
+	}
+
+	var m metadata
+	err := p.getJSON(ctx, strings.TrimSuffix(cfg.Issuer, "/")+"/.well-known/openid-configuration", &m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read provider configuration: %s", err.Error())
+	}
+
+	if m.Issuer != cfg.Issuer {
+		return nil, fmt.Errorf("provider issuer '%s' does not match '%s'", m.Issuer, cfg.Issuer)
+	}
+
+	if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JWKSURI == "" {
+		return nil, errors.New("provider configuration is missing endpoints")
+	}
+
+	p.metadata = &m
+
+	return p, nil
+}
+
+func (p *Provider) Issuer() string {
+	return p.config.Issuer
+}
+
+// AuthCodeURL returns where to send the user to sign in with the provider.
+func (p *Provider) AuthCodeURL(req *AuthRequest) string {
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientID)
+	query.Set("redirect_uri", p.config.RedirectURL)
+	query.Set("scope", strings.Join(defaultScopes, " "))
+	query.Set("state", req.State)
+	query.Set("nonce", req.Nonce)
+	query.Set("code_challenge", codeChallenge(req.CodeVerifier))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(p.metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return p.metadata.AuthorizationEndpoint + separator + query.Encode()
+}
+
+// Exchange trades an authorization code for the identity in the provider's
+// ID token. The token has to be signed by the provider, issued for this
+// client and carry the nonce from the authorization request.
+func (p *Provider) Exchange(ctx context.Context, code string, req *AuthRequest) (*Identity, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("client_id", p.config.ClientID)
+	form.Set("code_verifier", req.CodeVerifier)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Accept", "application/json")
+
+	if p.config.ClientSecret != "" {
+		httpReq.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %s", err.Error())
+	}
+
+	if body.Error == "invalid_grant" {
+		return nil, ErrInvalidGrant
+	}
+
+	if resp.StatusCode != http.StatusOK || body.Error != "" {
+		return nil, fmt.Errorf("token request failed with status %d: %s %s", resp.StatusCode, body.Error, body.ErrorDescription)
+	}
+
+	return p.verifyIDToken(ctx, body.IDToken, req.Nonce)
+}
+
+func (p *Provider) verifyIDToken(ctx context.Context, rawIDToken, nonce string) (*Identity, error) {
+	var claims idTokenClaims
+	err := token.Verify(rawIDToken, p.keyLookup(ctx), &claims)
+	if err != nil {
+		slog.ErrorContext(ctx, "ID token could not be verified", "error", err)
+		return nil, ErrInvalidIDToken
+	}
+
+	if claims.Issuer != p.config.Issuer || !claims.Audience.contains(p.config.ClientID) {
+		return nil, ErrInvalidIDToken
+	}
+
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.config.ClientID {
+		return nil, ErrInvalidIDToken
+	}
+
+	if claims.Nonce != nonce {
+		return nil, ErrInvalidIDToken
+	}
+
+	return &Identity{
+		Subject:           claims.Subject,
+		Email:             claims.Email,
+		EmailVerified:     claims.EmailVerified,
+		PreferredUsername: claims.PreferredUsername,
+	}, nil
+}
+
+// keyLookup finds the provider's signing keys by kid. The keys are fetched
+// again the first time a kid is missing so rotated keys are picked up.
+func (p *Provider) keyLookup(ctx context.Context) func(kid string) (token.Verifier, bool) {
+	refreshed := false
+
+	return func(kid string) (token.Verifier, bool) {
+		p.mu.RLock()
+		verifier, ok := p.keys[kid]
+		p.mu.RUnlock()
+
+		if ok || refreshed {
+			return verifier, ok
+		}
+
+		refreshed = true
+		err := p.refreshKeys(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to fetch provider keys", "error", err)
+			return nil, false
+		}
+
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+		verifier, ok = p.keys[kid]
+
+		return verifier, ok
+	}
+}
+
+func (p *Provider) refreshKeys(ctx context.Context) error {
+	var jwks token.JWKS
+	err := p.getJSON(ctx, p.metadata.JWKSURI, &jwks)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]token.Verifier)
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		verifier, err := jwk.Verifier()
+		if err != nil {
+			// Providers can publish key types we don't support alongside
+			// ones we do
+			continue
+		}
+
+		kid := jwk.KeyID
+		if kid == "" {
+			kid = verifier.KeyID()
+		}
+		keys[kid] = verifier
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+
+	return nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, endpoint string, into interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, endpoint)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(into)
+}
+
+func codeChallenge(verifier string) string {
+	digest := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
@@ -0,0 +1,157 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/oidc"
+	"github.com/iplay88keys/my-recipe-library/pkg/oidc/oidctest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Provider", func() {
+	var (
+		fake     *oidctest.Provider
+		provider *oidc.Provider
+		cfg      oidc.Config
+		ctx      context.Context
+	)
+
+	noRedirects := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	// authorize visits the authorization URL like a browser would and returns
+	// the callback the provider redirects to
+	authorize := func(req *oidc.AuthRequest) url.Values {
+		resp, err := noRedirects.Get(provider.AuthCodeURL(req))
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		Expect(resp.StatusCode).To(Equal(http.StatusFound))
+
+		location, err := resp.Location()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(location.String()).To(HavePrefix(cfg.RedirectURL))
+
+		return location.Query()
+	}
+
+	BeforeEach(func() {
+		var err error
+		fake, err = oidctest.NewProvider("recipes", "client-secret")
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(fake.Close)
+
+		fake.SignIn(oidctest.User{
+			Subject:           "subject-1",
+			Email:             "cook@example.com",
+			EmailVerified:     true,
+			PreferredUsername: "cook",
+		})
+
+		cfg = oidc.Config{
+			Issuer:       fake.Issuer(),
+			ClientID:     "recipes",
+			ClientSecret: "client-secret",
+			RedirectURL:  "http://localhost:8080/oidc/callback",
+		}
+
+		ctx = context.Background()
+
+		provider, err = oidc.NewProvider(ctx, cfg, nil)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("exchanges a code for the signed in identity", func() {
+		req, err := oidc.NewAuthRequest()
+		Expect(err).ToNot(HaveOccurred())
+
+		callback := authorize(req)
+		Expect(callback.Get("state")).To(Equal(req.State))
+
+		identity, err := provider.Exchange(ctx, callback.Get("code"), req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(identity).To(Equal(&oidc.Identity{
+			Subject:           "subject-1",
+			Email:             "cook@example.com",
+			EmailVerified:     true,
+			PreferredUsername: "cook",
+		}))
+	})
+
+	It("sends a PKCE challenge instead of the verifier", func() {
+		req, err := oidc.NewAuthRequest()
+		Expect(err).ToNot(HaveOccurred())
+
+		authURL, err := url.Parse(provider.AuthCodeURL(req))
+		Expect(err).ToNot(HaveOccurred())
+
+		query := authURL.Query()
+		Expect(query.Get("code_challenge_method")).To(Equal("S256"))
+		Expect(query.Get("code_challenge")).ToNot(BeEmpty())
+		Expect(authURL.String()).ToNot(ContainSubstring(req.CodeVerifier))
+		Expect(query.Get("scope")).To(Equal("openid email profile"))
+	})
+
+	It("does not accept a code with the wrong verifier or a second time", func() {
+		req, err := oidc.NewAuthRequest()
+		Expect(err).ToNot(HaveOccurred())
+
+		code := authorize(req).Get("code")
+
+		wrongVerifier := *req
+		wrongVerifier.CodeVerifier = "not-the-verifier"
+
+		_, err = provider.Exchange(ctx, code, &wrongVerifier)
+		Expect(err).To(MatchError(oidc.ErrInvalidGrant))
+
+		code = authorize(req).Get("code")
+		_, err = provider.Exchange(ctx, code, req)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = provider.Exchange(ctx, code, req)
+		Expect(err).To(MatchError(oidc.ErrInvalidGrant))
+	})
+
+	It("rejects an ID token with a different nonce", func() {
+		fake.Nonce = "replayed-nonce"
+
+		req, err := oidc.NewAuthRequest()
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = provider.Exchange(ctx, authorize(req).Get("code"), req)
+		Expect(err).To(MatchError(oidc.ErrInvalidIDToken))
+	})
+
+	It("returns an error if the client secret is wrong", func() {
+		cfg.ClientSecret = "wrong"
+		provider, err := oidc.NewProvider(ctx, cfg, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		req, err := oidc.NewAuthRequest()
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := noRedirects.Get(provider.AuthCodeURL(req))
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+
+		location, err := resp.Location()
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = provider.Exchange(ctx, location.Query().Get("code"), req)
+		Expect(err).To(MatchError(ContainSubstring("invalid_client")))
+	})
+
+	It("returns an error if the issuer does not match the discovery document", func() {
+		cfg.Issuer = fake.Issuer() + "/"
+
+		_, err := oidc.NewProvider(ctx, cfg, nil)
+		Expect(err).To(MatchError(ContainSubstring("does not match")))
+	})
+})
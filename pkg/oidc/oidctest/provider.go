@@ -0,0 +1,243 @@
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/token"
+)
+
+// User is who signs in the next time the provider's authorization endpoint is
+// visited.
+type User struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+}
+
+type grant struct {
+	clientID      string
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+	user          User
+}
+
+// Provider is an in-process OpenID Connect provider for tests. It implements
+// discovery, the authorization and token endpoints with PKCE, and a JWKS
+// endpoint. ID tokens are signed with a new RS256 key.
+type Provider struct {
+	ClientID     string
+	ClientSecret string
+
+	// Nonce, when set, replaces the nonce in every ID token.
+	Nonce string
+
+	server *httptest.Server
+	keys   *token.KeySet
+
+	mu     sync.Mutex
+	user   *User
+	grants map[string]*grant
+}
+
+func NewProvider(clientID, clientSecret string) (*Provider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := token.NewRS256Signer(key)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := token.NewKeySet(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Provider{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		keys:         keys,
+		grants:       make(map[string]*grant),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("GET /authorize", p.authorize)
+	mux.HandleFunc("POST /token", p.token)
+	mux.HandleFunc("GET /jwks", p.jwks)
+	p.server = httptest.NewServer(mux)
+
+	return p, nil
+}
+
+func (p *Provider) Issuer() string {
+	return p.server.URL
+}
+
+// SignIn sets the user the provider signs in. Until it is called every
+// authorization request is denied.
+func (p *Provider) SignIn(user User) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.user = &user
+}
+
+func (p *Provider) Close() {
+	p.server.Close()
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 p.Issuer(),
+		"authorization_endpoint": p.Issuer() + "/authorize",
+		"token_endpoint":         p.Issuer() + "/token",
+		"jwks_uri":               p.Issuer() + "/jwks",
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, p.keys.JWKS())
+}
+
+// authorize signs the current user in without a login page and sends them
+// straight back to the redirect URI.
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || query.Get("redirect_uri") == "" || query.Get("client_id") != p.ClientID {
+		http.Error(w, "invalid client or redirect uri", http.StatusBadRequest)
+		return
+	}
+
+	callback := redirectURI.Query()
+	callback.Set("state", query.Get("state"))
+
+	p.mu.Lock()
+	user := p.user
+	p.mu.Unlock()
+
+	switch {
+	case query.Get("response_type") != "code":
+		callback.Set("error", "unsupported_response_type")
+	case query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "":
+		callback.Set("error", "invalid_request")
+	case user == nil:
+		callback.Set("error", "access_denied")
+	default:
+		code := randomString()
+
+		p.mu.Lock()
+		p.grants[code] = &grant{
+			clientID:      query.Get("client_id"),
+			redirectURI:   query.Get("redirect_uri"),
+			nonce:         query.Get("nonce"),
+			codeChallenge: query.Get("code_challenge"),
+			user:          *user,
+		}
+		p.mu.Unlock()
+
+		callback.Set("code", code)
+	}
+
+	redirectURI.RawQuery = callback.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	}
+
+	if clientID != p.ClientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(p.ClientSecret)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	code := r.PostForm.Get("code")
+
+	p.mu.Lock()
+	g, ok := p.grants[code]
+	delete(p.grants, code)
+	p.mu.Unlock()
+
+	if !ok || g.clientID != clientID || g.redirectURI != r.PostForm.Get("redirect_uri") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	digest := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(digest[:]) != g.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	nonce := g.nonce
+	if p.Nonce != "" {
+		nonce = p.Nonce
+	}
+
+	now := time.Now()
+	idToken, err := p.keys.Sign(map[string]interface{}{
+		"iss":                p.Issuer(),
+		"sub":                g.user.Subject,
+		"aud":                clientID,
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"iat":                now.Unix(),
+		"nonce":              nonce,
+		"email":              g.user.Email,
+		"email_verified":     g.user.EmailVerified,
+		"preferred_username": g.user.PreferredUsername,
+	})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func randomString() string {
+	raw := make([]byte, 16)
+	_, _ = rand.Read(raw)
+
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
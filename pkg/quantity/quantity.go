@@ -0,0 +1,223 @@
+// Package quantity reads ingredient amounts such as "1 1/2", "2-3" or "¾"
+// as exact fractions, so they can be scaled without rounding errors and
+// written back the way a recipe would.
+package quantity
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalid is returned for an amount that is not a quantity, such as
+// "a pinch" or "to taste".
+var ErrInvalid = errors.New("not a quantity")
+
+// Quantity is an amount, or a range of amounts from Min to Max. Max is nil
+// unless the quantity is a range.
+type Quantity struct {
+	Min *big.Rat
+	Max *big.Rat
+}
+
+var vulgarFractions = map[rune]string{
+	'½': "1/2",
+	'⅓': "1/3",
+	'⅔': "2/3",
+	'¼': "1/4",
+	'¾': "3/4",
+	'⅕': "1/5",
+	'⅖': "2/5",
+	'⅗': "3/5",
+	'⅘': "4/5",
+	'⅙': "1/6",
+	'⅚': "5/6",
+	'⅐': "1/7",
+	'⅛': "1/8",
+	'⅜': "3/8",
+	'⅝': "5/8",
+	'⅞': "7/8",
+	'⅑': "1/9",
+	'⅒': "1/10",
+}
+
+var (
+	rangePattern    = regexp.MustCompile(`^(.+?)\s*(?:-|–|—|\bto\b)\s*(.+)$`)
+	mixedPattern    = regexp.MustCompile(`^(?:(\d+)\s+)?(\d+)\s*/\s*(\d+)$`)
+	decimalPattern  = regexp.MustCompile(`^(?:\d+(?:\.\d*)?|\.\d+)$`)
+	hyphenatedMixed = regexp.MustCompile(`^(\d+)\s*-\s*(\d+\s*/\s*\d+)$`)
+)
+
+// Parse reads an amount written as a whole number, a decimal, a fraction, a
+// mixed number or a unicode vulgar fraction, or a range of two of those
+// separated by a dash or "to". "1-1/2" is read as the mixed number one and a
+// half, since a range from 1 down to 1/2 would make no sense.
+func Parse(amount string) (*Quantity, error) {
+	text := normalize(amount)
+	if text == "" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalid, amount)
+	}
+
+	if match := hyphenatedMixed.FindStringSubmatch(text); match != nil {
+		fraction, err := parseNumber(match[2])
+		if err == nil && fraction.Cmp(big.NewRat(1, 1)) < 0 {
+			whole, _ := new(big.Rat).SetString(match[1])
+			return &Quantity{Min: whole.Add(whole, fraction)}, nil
+		}
+	}
+
+	if match := rangePattern.FindStringSubmatch(text); match != nil {
+		low, err := parseNumber(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalid, amount)
+		}
+
+		high, err := parseNumber(match[2])
+		if err != nil || high.Cmp(low) < 0 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalid, amount)
+		}
+
+		return &Quantity{Min: low, Max: high}, nil
+	}
+
+	number, err := parseNumber(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalid, amount)
+	}
+
+	return &Quantity{Min: number}, nil
+}
+
+// Scale returns the quantity multiplied by factor.
+func (q *Quantity) Scale(factor *big.Rat) *Quantity {
+	scaled := &Quantity{Min: new(big.Rat).Mul(q.Min, factor)}
+	if q.Max != nil {
+		scaled.Max = new(big.Rat).Mul(q.Max, factor)
+	}
+
+	return scaled
+}
+
+// String writes the quantity with Format, and a range as "min-max", or as
+// "min to max" if either end is a mixed number, since "1-1 1/2" is hard to
+// read.
+func (q *Quantity) String() string {
+	if q.Max == nil || q.Max.Cmp(q.Min) == 0 {
+		return Format(q.Min)
+	}
+
+	low, high := Format(q.Min), Format(q.Max)
+	if strings.Contains(low, " ") || strings.Contains(high, " ") {
+		return low + " to " + high
+	}
+
+	return low + "-" + high
+}
+
+// friendlyDenominators are the fractions measuring cups and spoons come in.
+var friendlyDenominators = []int64{2, 3, 4, 8}
+
+// Format writes a number as a whole number and a fraction, such as "1 1/2".
+// Fractions other than halves, thirds, quarters and eighths are rounded to
+// the nearest of those, unless that would round a small amount to nothing.
+func Format(number *big.Rat) string {
+	whole := new(big.Int).Quo(number.Num(), number.Denom())
+	fraction := new(big.Rat).Sub(number, new(big.Rat).SetInt(whole))
+
+	if fraction.Sign() != 0 && !isFriendly(fraction.Denom()) {
+		rounded := nearestFriendly(fraction)
+		switch {
+		case rounded.Sign() == 0 && whole.Sign() == 0:
+			// Too small to round, so it is written exactly.
+		case rounded.Cmp(big.NewRat(1, 1)) == 0:
+			whole.Add(whole, big.NewInt(1))
+			fraction = new(big.Rat)
+		default:
+			fraction = rounded
+		}
+	}
+
+	switch {
+	case fraction.Sign() == 0:
+		return whole.String()
+	case whole.Sign() == 0:
+		return fraction.RatString()
+	default:
+		return whole.String() + " " + fraction.RatString()
+	}
+}
+
+func isFriendly(denominator *big.Int) bool {
+	for _, friendly := range friendlyDenominators {
+		if denominator.Cmp(big.NewInt(friendly)) == 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nearestFriendly rounds a fraction between 0 and 1 to the closest fraction
+// with a friendly denominator, which may be 0 or 1.
+func nearestFriendly(fraction *big.Rat) *big.Rat {
+	var nearest, smallest *big.Rat
+	for _, denominator := range friendlyDenominators {
+		// The closest multiple of 1/denominator, rounding halves up.
+		scaled := new(big.Rat).Mul(fraction, big.NewRat(denominator, 1))
+		scaled.Add(scaled, big.NewRat(1, 2))
+		numerator := new(big.Int).Quo(scaled.Num(), scaled.Denom())
+
+		candidate := new(big.Rat).SetFrac(numerator, big.NewInt(denominator))
+		difference := new(big.Rat).Sub(fraction, candidate)
+		difference.Abs(difference)
+
+		if smallest == nil || difference.Cmp(smallest) < 0 {
+			nearest, smallest = candidate, difference
+		}
+	}
+
+	return nearest
+}
+
+// normalize lower cases an amount, writes unicode vulgar fractions and the
+// fraction slash as plain fractions and collapses its spacing.
+func normalize(amount string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(amount) {
+		if fraction, ok := vulgarFractions[r]; ok {
+			b.WriteString(" " + fraction + " ")
+			continue
+		}
+		if r == '⁄' {
+			r = '/'
+		}
+		b.WriteRune(r)
+	}
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func parseNumber(text string) (*big.Rat, error) {
+	if decimalPattern.MatchString(text) {
+		number, ok := new(big.Rat).SetString(text)
+		if !ok {
+			return nil, ErrInvalid
+		}
+		return number, nil
+	}
+
+	match := mixedPattern.FindStringSubmatch(text)
+	if match == nil || strings.TrimLeft(match[3], "0") == "" {
+		return nil, ErrInvalid
+	}
+
+	number, _ := new(big.Rat).SetString(match[2] + "/" + match[3])
+	if match[1] != "" {
+		whole, _ := new(big.Rat).SetString(match[1])
+		number.Add(number, whole)
+	}
+
+	return number, nil
+}
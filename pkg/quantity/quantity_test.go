@@ -0,0 +1,141 @@
+package quantity_test
+
+import (
+	"math/big"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/quantity"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Quantity", func() {
+	rat := func(s string) *big.Rat {
+		r, ok := new(big.Rat).SetString(s)
+		Expect(ok).To(BeTrue())
+		return r
+	}
+
+	DescribeTable("Parse",
+		func(amount, min, max string) {
+			q, err := quantity.Parse(amount)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(q.Min.Cmp(rat(min))).To(BeZero(), "min was %s", q.Min.RatString())
+
+			if max == "" {
+				Expect(q.Max).To(BeNil())
+			} else {
+				Expect(q.Max).ToNot(BeNil())
+				Expect(q.Max.Cmp(rat(max))).To(BeZero(), "max was %s", q.Max.RatString())
+			}
+		},
+		Entry("a whole number", "2", "2", ""),
+		Entry("zero", "0", "0", ""),
+		Entry("surrounding spaces", "  3 ", "3", ""),
+		Entry("a decimal", "1.5", "3/2", ""),
+		Entry("a decimal without a leading zero", ".25", "1/4", ""),
+		Entry("a decimal with a trailing point", "2.", "2", ""),
+		Entry("a fraction", "1/2", "1/2", ""),
+		Entry("an improper fraction", "3/2", "3/2", ""),
+		Entry("a fraction with spaces around the slash", "3 / 4", "3/4", ""),
+		Entry("a mixed number", "1 1/2", "3/2", ""),
+		Entry("a mixed number with extra spaces", "2   3/4", "11/4", ""),
+		Entry("a hyphenated mixed number", "1-1/2", "3/2", ""),
+		Entry("a unicode fraction", "½", "1/2", ""),
+		Entry("a whole number and a unicode fraction", "1½", "3/2", ""),
+		Entry("a whole number, a space and a unicode fraction", "2 ¾", "11/4", ""),
+		Entry("a unicode eighth", "⅜", "3/8", ""),
+		Entry("a unicode tenth", "⅒", "1/10", ""),
+		Entry("the fraction slash", "1⁄3", "1/3", ""),
+		Entry("a range", "2-3", "2", "3"),
+		Entry("a range with spaces", "2 - 3", "2", "3"),
+		Entry("a range with an en dash", "2–3", "2", "3"),
+		Entry("a range with an em dash", "2—3", "2", "3"),
+		Entry("a range with to", "2 to 3", "2", "3"),
+		Entry("a range with upper case to", "2 TO 3", "2", "3"),
+		Entry("a range of fractions", "1/2-3/4", "1/2", "3/4"),
+		Entry("a range of mixed numbers", "1 1/2 - 2 1/4", "3/2", "9/4"),
+		Entry("a range of unicode fractions", "½-¾", "1/2", "3/4"),
+		Entry("a range of decimals", "0.5 to 1.5", "1/2", "3/2"),
+		Entry("a range from a fraction to a whole number", "1/2-1", "1/2", "1"),
+		Entry("a range with the same ends", "2-2", "2", "2"),
+	)
+
+	DescribeTable("Parse rejects amounts that are not quantities",
+		func(amount string) {
+			_, err := quantity.Parse(amount)
+			Expect(err).To(MatchError(quantity.ErrInvalid))
+		},
+		Entry("an empty amount", ""),
+		Entry("spaces", "   "),
+		Entry("words", "a pinch"),
+		Entry("to taste", "to taste"),
+		Entry("a number followed by words", "2 large"),
+		Entry("a negative number", "-1"),
+		Entry("an exponent", "1e3"),
+		Entry("a zero denominator", "1/0"),
+		Entry("a zero denominator in a mixed number", "1 1/00"),
+		Entry("two decimal points", "1.2.3"),
+		Entry("a fraction without a numerator", "/2"),
+		Entry("a fraction without a denominator", "2/"),
+		Entry("a range going down", "3-2"),
+		Entry("a range missing its end", "2-"),
+		Entry("a range missing its start", "to 3"),
+		Entry("a range of words", "some-more"),
+		Entry("a range with three ends", "1-2-3"),
+	)
+
+	DescribeTable("Format",
+		func(number, expected string) {
+			Expect(quantity.Format(rat(number))).To(Equal(expected))
+		},
+		Entry("zero", "0", "0"),
+		Entry("a whole number", "3", "3"),
+		Entry("a half", "1/2", "1/2"),
+		Entry("a third", "1/3", "1/3"),
+		Entry("two thirds", "2/3", "2/3"),
+		Entry("three quarters", "3/4", "3/4"),
+		Entry("an eighth", "1/8", "1/8"),
+		Entry("a mixed number", "7/2", "3 1/2"),
+		Entry("a large mixed number", "101/4", "25 1/4"),
+		Entry("a fraction reduced to lowest terms", "4/8", "1/2"),
+		Entry("a decimal half", "0.5", "1/2"),
+		Entry("fifths rounded to the nearest eighth", "2/5", "3/8"),
+		Entry("sixths rounded to the nearest eighth", "5/6", "7/8"),
+		Entry("twelfths rounded to the nearest eighth", "37/12", "3 1/8"),
+		Entry("a near whole number rounded up", "199/100", "2"),
+		Entry("a tiny fraction of a whole number dropped", "201/100", "2"),
+		Entry("a tiny amount written exactly", "1/20", "1/20"),
+		Entry("an amount close to a third", "33/100", "1/3"),
+	)
+
+	DescribeTable("Scale",
+		func(amount, factor, expected string) {
+			q, err := quantity.Parse(amount)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(q.Scale(rat(factor)).String()).To(Equal(expected))
+		},
+		Entry("doubling a whole number", "2", "2", "4"),
+		Entry("doubling a half", "1/2", "2", "1"),
+		Entry("doubling a mixed number", "1 1/2", "2", "3"),
+		Entry("halving a whole number", "3", "1/2", "1 1/2"),
+		Entry("halving a third", "1/3", "1/2", "1/8"),
+		Entry("tripling a third", "⅓", "3", "1"),
+		Entry("scaling by one and a half", "¾", "3/2", "1 1/8"),
+		Entry("scaling a decimal", "0.25", "4", "1"),
+		Entry("scaling a range", "2-3", "2", "4-6"),
+		Entry("scaling a range of fractions", "1/2 to 3/4", "2", "1 to 1 1/2"),
+		Entry("scaling a range of fractions below one", "1/4-1/2", "2", "1/2-1"),
+		Entry("scaling a hyphenated mixed number", "1-1/2", "2/3", "1"),
+		Entry("scaling by one", "1 1/2", "1", "1 1/2"),
+		Entry("scaling a range with the same ends", "2-2", "3", "6"),
+	)
+
+	It("does not change the quantity it scales", func() {
+		q, err := quantity.Parse("1/2")
+		Expect(err).ToNot(HaveOccurred())
+
+		q.Scale(big.NewRat(4, 1))
+		Expect(q.String()).To(Equal("1/2"))
+	})
+})
@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often counters for windows that have passed are
+// dropped.
+const sweepInterval = time.Minute
+
+type counter struct {
+	window   time.Duration
+	start    time.Time
+	previous int64
+	current  int64
+}
+
+// MemoryLimiter keeps counts in the process, so each instance of the app
+// limits requests on its own.
+type MemoryLimiter struct {
+	// Now returns the current time. Tests can replace it.
+	Now func() time.Time
+
+	mu        sync.Mutex
+	counters  map[string]*counter
+	lastSweep time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		Now:      time.Now,
+		counters: make(map[string]*counter),
+	}
+}
+
+func (l *MemoryLimiter) Allow(key string, limit int, window time.Duration) (*Result, error) {
+	now := l.Now()
+	start := now.Truncate(window)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	c, ok := l.counters[key]
+	if !ok || c.window != window {
+		c = &counter{window: window, start: start}
+		l.counters[key] = c
+	}
+
+	switch {
+	case c.start.Equal(start):
+	case c.start.Add(window).Equal(start):
+		c.previous, c.current = c.current, 0
+		c.start = start
+	default:
+		c.previous, c.current = 0, 0
+		c.start = start
+	}
+
+	result := slidingWindow(c.previous, c.current, limit, window, now.Sub(start))
+	if result.Allowed {
+		c.current++
+	}
+
+	return result, nil
+}
+
+// sweep drops counters that no longer affect any window. It has to be called
+// with the lock held.
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, c := range l.counters {
+		if now.Sub(c.start) >= 2*c.window {
+			delete(l.counters, key)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// Result is the outcome of asking a Limiter to allow a request.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+
+	// Reset is how long until the current window ends.
+	Reset time.Duration
+}
+
+// Limiter counts requests with a sliding window. The count for a key is its
+// count in the current window plus its count in the previous window, weighted
+// by how much of the previous window still overlaps the sliding one. Requests
+// that are not allowed are not counted.
+type Limiter interface {
+	Allow(key string, limit int, window time.Duration) (*Result, error)
+}
+
+// slidingWindow decides whether a request can be made given the counts in the
+// previous and current windows, not counting the request itself.
+func slidingWindow(previous, current int64, limit int, window, elapsed time.Duration) *Result {
+	weight := 1 - float64(elapsed)/float64(window)
+	count := int64(math.Floor(float64(previous)*weight)) + current
+
+	result := &Result{
+		Limit: limit,
+		Reset: window - elapsed,
+	}
+
+	if count >= int64(limit) {
+		return result
+	}
+
+	result.Allowed = true
+	result.Remaining = limit - int(count) - 1
+
+	return result
+}
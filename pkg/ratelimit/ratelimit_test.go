@@ -0,0 +1,176 @@
+package ratelimit_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/elliotchance/redismock"
+	"github.com/go-redis/redis"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/ratelimit"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Limiters", func() {
+	var (
+		mr  *miniredis.Miniredis
+		now time.Time
+	)
+
+	BeforeEach(func() {
+		var err error
+		mr, err = miniredis.Run()
+		Expect(err).ToNot(HaveOccurred())
+
+		now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	})
+
+	AfterEach(func() {
+		mr.Close()
+	})
+
+	limiters := map[string]func() ratelimit.Limiter{
+		"memory": func() ratelimit.Limiter {
+			limiter := ratelimit.NewMemoryLimiter()
+			limiter.Now = func() time.Time { return now }
+			return limiter
+		},
+		"redis": func() ratelimit.Limiter {
+			limiter := ratelimit.NewRedisLimiter(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+			limiter.Now = func() time.Time { return now }
+			return limiter
+		},
+	}
+
+	for name, newLimiter := range limiters {
+		newLimiter := newLimiter
+
+		Describe(name, func() {
+			var limiter ratelimit.Limiter
+
+			BeforeEach(func() {
+				limiter = newLimiter()
+			})
+
+			It("allows requests up to the limit in a window", func() {
+				for i := 2; i >= 0; i-- {
+					result, err := limiter.Allow("key", 3, time.Minute)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result).To(Equal(&ratelimit.Result{
+						Allowed:   true,
+						Limit:     3,
+						Remaining: i,
+						Reset:     time.Minute,
+					}))
+				}
+
+				now = now.Add(15 * time.Second)
+
+				result, err := limiter.Allow("key", 3, time.Minute)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(&ratelimit.Result{
+					Allowed:   false,
+					Limit:     3,
+					Remaining: 0,
+					Reset:     45 * time.Second,
+				}))
+
+				result, err = limiter.Allow("other key", 3, time.Minute)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Allowed).To(BeTrue())
+			})
+
+			It("weights the previous window by how much of it is still in the sliding window", func() {
+				for i := 0; i < 4; i++ {
+					result, err := limiter.Allow("key", 4, time.Minute)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result.Allowed).To(BeTrue())
+				}
+
+				// Half of the previous window's 4 requests still count
+				now = now.Add(90 * time.Second)
+
+				for i := 0; i < 2; i++ {
+					result, err := limiter.Allow("key", 4, time.Minute)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result.Allowed).To(BeTrue())
+				}
+
+				result, err := limiter.Allow("key", 4, time.Minute)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Allowed).To(BeFalse())
+
+				// The next window starts with the 2 requests from this one
+				// counting in full
+				now = now.Add(30 * time.Second)
+
+				result, err = limiter.Allow("key", 4, time.Minute)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Allowed).To(BeTrue())
+				Expect(result.Remaining).To(Equal(1))
+			})
+
+			It("forgets requests once the previous window has passed", func() {
+				for i := 0; i < 2; i++ {
+					_, err := limiter.Allow("key", 2, time.Minute)
+					Expect(err).ToNot(HaveOccurred())
+				}
+
+				now = now.Add(2 * time.Minute)
+
+				result, err := limiter.Allow("key", 2, time.Minute)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Allowed).To(BeTrue())
+				Expect(result.Remaining).To(Equal(1))
+			})
+
+			It("does not count requests that are not allowed", func() {
+				_, err := limiter.Allow("key", 1, time.Minute)
+				Expect(err).ToNot(HaveOccurred())
+
+				for i := 0; i < 3; i++ {
+					result, err := limiter.Allow("key", 1, time.Minute)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result.Allowed).To(BeFalse())
+				}
+
+				// With the 1 allowed request weighted at a half, one more fits in
+				// a limit of 2
+				now = now.Add(90 * time.Second)
+
+				result, err := limiter.Allow("key", 2, time.Minute)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Allowed).To(BeTrue())
+			})
+		})
+	}
+
+	Describe("redis", func() {
+		It("expires window counts once they can no longer be used", func() {
+			limiter := ratelimit.NewRedisLimiter(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+			limiter.Now = func() time.Time { return now }
+
+			_, err := limiter.Allow("key", 1, time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			keys := mr.Keys()
+			Expect(keys).To(HaveLen(1))
+			Expect(mr.TTL(keys[0])).To(Equal(2 * time.Minute))
+		})
+
+		It("returns an error if the count fails", func() {
+			redisClient := redismock.NewNiceMock(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+			redisClient.On("Incr", "ratelimit:key:1717243200000").
+				Return(redis.NewIntResult(0, errors.New("redis error")))
+
+			limiter := ratelimit.NewRedisLimiter(redisClient)
+			limiter.Now = func() time.Time { return now }
+
+			_, err := limiter.Allow("key", 1, time.Minute)
+			Expect(err).To(MatchError("redis error"))
+		})
+	})
+})
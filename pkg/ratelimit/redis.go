@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// RedisLimiter keeps counts in Redis, so every instance of the app shares the
+// same limits.
+type RedisLimiter struct {
+	// Now returns the current time. Tests can replace it.
+	Now func() time.Time
+
+	client redis.Cmdable
+}
+
+func NewRedisLimiter(client redis.Cmdable) *RedisLimiter {
+	return &RedisLimiter{
+		Now:    time.Now,
+		client: client,
+	}
+}
+
+// Allow counts the request before checking it, so concurrent requests cannot
+// all see room for one more. A request that is not allowed is uncounted
+// again.
+func (l *RedisLimiter) Allow(key string, limit int, window time.Duration) (*Result, error) {
+	now := l.Now()
+	start := now.Truncate(window)
+
+	currentKey := windowKey(key, start)
+	previousKey := windowKey(key, start.Add(-window))
+
+	current, err := l.client.Incr(currentKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if current == 1 {
+		err = l.client.Expire(currentKey, 2*window).Err()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	previous, err := l.client.Get(previousKey).Int64()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	result := slidingWindow(previous, current-1, limit, window, now.Sub(start))
+	if !result.Allowed {
+		err = l.client.Decr(currentKey).Err()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func windowKey(key string, start time.Time) string {
+	return "ratelimit:" + key + ":" + strconv.FormatInt(start.UnixMilli(), 10)
+}
@@ -0,0 +1,232 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+type Cookbook struct {
+	ID   *int64
+	Name *string
+}
+
+type Section struct {
+	ID         *int64
+	CookbookID *int64
+	Name       *string
+}
+
+type CookbooksRepository struct {
+	db *sql.DB
+}
+
+func NewCookbooksRepository(db *sql.DB) *CookbooksRepository {
+	return &CookbooksRepository{db: db}
+}
+
+func (r *CookbooksRepository) List(ctx context.Context, userID int64) ([]*Cookbook, error) {
+	rows, err := querier(ctx, r.db).QueryContext(ctx, listCookbooksQuery, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to fetch cookbooks", "error", err)
+		return nil, errors.New("failed to fetch cookbooks")
+	}
+	defer rows.Close()
+
+	var cookbooks []*Cookbook
+	for rows.Next() {
+		c := &Cookbook{}
+		if err := rows.Scan(&c.ID, &c.Name); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan cookbooks", "error", err)
+			return nil, errors.New("failed to scan cookbooks")
+		}
+		cookbooks = append(cookbooks, c)
+	}
+	if rows.Err() != nil {
+		slog.ErrorContext(ctx, "Failed to loop through cookbooks", "error", rows.Err())
+		return nil, errors.New("failed to retrieve cookbooks")
+	}
+
+	return cookbooks, nil
+}
+
+func (r *CookbooksRepository) GetOwner(ctx context.Context, id int64) (int64, error) {
+	var userID int64
+	if err := querier(ctx, r.db).QueryRowContext(ctx, getCookbookOwnerQuery, id).Scan(&userID); err != nil {
+		if err == sql.ErrNoRows {
+			return -1, err
+		}
+
+		slog.ErrorContext(ctx, "Failed to scan owner for cookbook", "cookbook_id", id, "error", err)
+		return -1, errors.New("failed to retrieve cookbook owner")
+	}
+
+	return userID, nil
+}
+
+func (r *CookbooksRepository) Insert(ctx context.Context, userID int64, name string) (int64, error) {
+	res, err := querier(ctx, r.db).ExecContext(ctx, insertCookbookQuery, userID, name)
+	if err != nil {
+		slog.ErrorContext(ctx, "Cookbook could not be saved", "error", err)
+		return 0, errors.New("cookbook could not be saved")
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		slog.ErrorContext(ctx, "Cookbook was not saved correctly", "error", err)
+		return 0, fmt.Errorf("cookbook was not saved correctly: %s", err.Error())
+	}
+
+	return id, nil
+}
+
+func (r *CookbooksRepository) Rename(ctx context.Context, id int64, name string) error {
+	_, err := querier(ctx, r.db).ExecContext(ctx, renameCookbookQuery, name, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Cookbook could not be renamed", "cookbook_id", id, "error", err)
+		return errors.New("cookbook could not be renamed")
+	}
+
+	return nil
+}
+
+func (r *CookbooksRepository) Delete(ctx context.Context, id int64) error {
+	return r.execOne(ctx, deleteCookbookQuery, "cookbook could not be deleted", id)
+}
+
+func (r *CookbooksRepository) ListSections(ctx context.Context, userID int64) ([]*Section, error) {
+	rows, err := querier(ctx, r.db).QueryContext(ctx, listSectionsQuery, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to fetch sections", "error", err)
+		return nil, errors.New("failed to fetch sections")
+	}
+	defer rows.Close()
+
+	var sections []*Section
+	for rows.Next() {
+		s := &Section{}
+		if err := rows.Scan(&s.ID, &s.CookbookID, &s.Name); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan sections", "error", err)
+			return nil, errors.New("failed to scan sections")
+		}
+		sections = append(sections, s)
+	}
+	if rows.Err() != nil {
+		slog.ErrorContext(ctx, "Failed to loop through sections", "error", rows.Err())
+		return nil, errors.New("failed to retrieve sections")
+	}
+
+	return sections, nil
+}
+
+func (r *CookbooksRepository) GetSectionCookbook(ctx context.Context, sectionID int64) (int64, error) {
+	var cookbookID int64
+	if err := querier(ctx, r.db).QueryRowContext(ctx, getSectionCookbookQuery, sectionID).Scan(&cookbookID); err != nil {
+		if err == sql.ErrNoRows {
+			return -1, err
+		}
+
+		slog.ErrorContext(ctx, "Failed to scan cookbook for section", "section_id", sectionID, "error", err)
+		return -1, errors.New("failed to retrieve section cookbook")
+	}
+
+	return cookbookID, nil
+}
+
+func (r *CookbooksRepository) InsertSection(ctx context.Context, cookbookID int64, name string) (int64, error) {
+	res, err := querier(ctx, r.db).ExecContext(ctx, insertSectionQuery, cookbookID, name)
+	if err != nil {
+		slog.ErrorContext(ctx, "Section could not be saved", "error", err)
+		return 0, errors.New("section could not be saved")
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		slog.ErrorContext(ctx, "Section was not saved correctly", "error", err)
+		return 0, fmt.Errorf("section was not saved correctly: %s", err.Error())
+	}
+
+	return id, nil
+}
+
+func (r *CookbooksRepository) RenameSection(ctx context.Context, id int64, name string) error {
+	_, err := querier(ctx, r.db).ExecContext(ctx, renameSectionQuery, name, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Section could not be renamed", "section_id", id, "error", err)
+		return errors.New("section could not be renamed")
+	}
+
+	return nil
+}
+
+func (r *CookbooksRepository) DeleteSection(ctx context.Context, id int64) error {
+	return r.execOne(ctx, deleteSectionQuery, "section could not be deleted", id)
+}
+
+// AddRecipe files a recipe into a cookbook, optionally within one of its
+// sections. Filing a recipe that is already in the cookbook moves it to the
+// given section.
+func (r *CookbooksRepository) AddRecipe(ctx context.Context, recipeID, cookbookID int64, sectionID *int64) error {
+	_, err := querier(ctx, r.db).ExecContext(ctx, insertRecipeLocationQuery, recipeID, cookbookID, sectionID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Recipe location could not be saved", "error", err)
+		return errors.New("recipe location could not be saved")
+	}
+
+	return nil
+}
+
+func (r *CookbooksRepository) RemoveRecipe(ctx context.Context, recipeID, cookbookID int64) error {
+	return r.execOne(ctx, deleteRecipeLocationQuery, "recipe location could not be deleted", recipeID, cookbookID)
+}
+
+// execOne runs a statement that is expected to touch at least one row and
+// returns sql.ErrNoRows when it did not.
+func (r *CookbooksRepository) execOne(ctx context.Context, query, failure string, args ...interface{}) error {
+	res, err := querier(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, failure, "error", err)
+		return errors.New(failure)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		slog.ErrorContext(ctx, failure, "error", err)
+		return fmt.Errorf("%s: %s", failure, err.Error())
+	}
+
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+const listCookbooksQuery = "SELECT id, name FROM cookbooks WHERE user_id=? ORDER BY name"
+const getCookbookOwnerQuery = "SELECT user_id FROM cookbooks WHERE id=?"
+const insertCookbookQuery = "INSERT INTO cookbooks (user_id, name) VALUES (?, ?)"
+const renameCookbookQuery = "UPDATE cookbooks SET name=? WHERE id=?"
+const deleteCookbookQuery = "DELETE FROM cookbooks WHERE id=?"
+
+const listSectionsQuery = `
+  SELECT s.id,
+         s.cookbook_id,
+         s.name
+  FROM sections as s
+  INNER JOIN cookbooks as c on s.cookbook_id=c.id
+  WHERE c.user_id=?
+  ORDER BY s.name
+`
+const getSectionCookbookQuery = "SELECT cookbook_id FROM sections WHERE id=?"
+const insertSectionQuery = "INSERT INTO sections (cookbook_id, name) VALUES (?, ?)"
+const renameSectionQuery = "UPDATE sections SET name=? WHERE id=?"
+const deleteSectionQuery = "DELETE FROM sections WHERE id=?"
+
+const insertRecipeLocationQuery = `
+  INSERT INTO recipe_locations (recipe_id, cookbook_id, section_id)
+  VALUES (?, ?, ?)
+  ON DUPLICATE KEY UPDATE section_id = VALUES(section_id)
+`
+const deleteRecipeLocationQuery = "DELETE FROM recipe_locations WHERE recipe_id=? AND cookbook_id=?"
@@ -0,0 +1,401 @@
+package repositories_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	. "github.com/iplay88keys/my-recipe-library/pkg/helpers"
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cookbooks Repository", func() {
+	var (
+		db   *sql.DB
+		mock sqlmock.Sqlmock
+		ctx  context.Context
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		var err error
+		db, mock, err = sqlmock.New()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("List", func() {
+		It("returns the cookbooks for the user", func() {
+			rows := sqlmock.NewRows([]string{"id", "name"}).
+				AddRow(1, "Desserts").
+				AddRow(2, "Drinks")
+
+			mock.ExpectQuery("^SELECT id, name FROM cookbooks WHERE user_id=\\? ORDER BY name$").
+				WithArgs(10).
+				WillReturnRows(rows)
+
+			repo := repositories.NewCookbooksRepository(db)
+			cookbooks, err := repo.List(ctx, 10)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cookbooks).To(Equal([]*repositories.Cookbook{{
+				ID:   Int64Pointer(1),
+				Name: StringPointer("Desserts"),
+			}, {
+				ID:   Int64Pointer(2),
+				Name: StringPointer("Drinks"),
+			}}))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("aborts the query if the context is cancelled", func() {
+			mock.ExpectQuery("^SELECT id, name FROM cookbooks WHERE user_id=\\? ORDER BY name$").
+				WithArgs(10).
+				WillDelayFor(time.Minute).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+			ctx, cancel := context.WithCancel(ctx)
+			time.AfterFunc(10*time.Millisecond, cancel)
+
+			start := time.Now()
+			repo := repositories.NewCookbooksRepository(db)
+			_, err := repo.List(ctx, 10)
+			Expect(err).To(HaveOccurred())
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		})
+
+		It("returns an error if the query fails", func() {
+			mock.ExpectQuery("^SELECT id, name FROM cookbooks").
+				WillReturnError(errors.New("some error"))
+
+			repo := repositories.NewCookbooksRepository(db)
+			_, err := repo.List(ctx, 10)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to fetch cookbooks"))
+		})
+
+		It("returns an error if the row cannot be scanned", func() {
+			rows := sqlmock.NewRows([]string{"id", "name"}).
+				AddRow("not a number", "Desserts")
+
+			mock.ExpectQuery("^SELECT id, name FROM cookbooks").
+				WillReturnRows(rows)
+
+			repo := repositories.NewCookbooksRepository(db)
+			_, err := repo.List(ctx, 10)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to scan cookbooks"))
+		})
+	})
+
+	Describe("GetOwner", func() {
+		It("returns the owner of a cookbook", func() {
+			rows := sqlmock.NewRows([]string{"user_id"}).AddRow(10)
+
+			mock.ExpectQuery("^SELECT user_id FROM cookbooks WHERE id=\\?$").
+				WithArgs(1).
+				WillReturnRows(rows)
+
+			repo := repositories.NewCookbooksRepository(db)
+			owner, err := repo.GetOwner(ctx, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(owner).To(BeEquivalentTo(10))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the cookbook cannot be found", func() {
+			mock.ExpectQuery("^SELECT user_id FROM cookbooks WHERE id=\\?$").
+				WithArgs(1).
+				WillReturnError(sql.ErrNoRows)
+
+			repo := repositories.NewCookbooksRepository(db)
+			_, err := repo.GetOwner(ctx, 1)
+			Expect(err).To(MatchError(sql.ErrNoRows))
+		})
+	})
+
+	Describe("Insert", func() {
+		It("inserts a cookbook for the user", func() {
+			mock.ExpectExec("^INSERT INTO cookbooks \\(user_id, name\\) VALUES \\(\\?, \\?\\)$").
+				WithArgs(10, "Desserts").
+				WillReturnResult(sqlmock.NewResult(3, 1))
+
+			repo := repositories.NewCookbooksRepository(db)
+			id, err := repo.Insert(ctx, 10, "Desserts")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(id).To(BeEquivalentTo(3))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the cookbook cannot be inserted", func() {
+			mock.ExpectExec("^INSERT INTO cookbooks").
+				WillReturnError(errors.New("some error"))
+
+			repo := repositories.NewCookbooksRepository(db)
+			_, err := repo.Insert(ctx, 10, "Desserts")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("cookbook could not be saved"))
+		})
+	})
+
+	Describe("Rename", func() {
+		It("renames a cookbook", func() {
+			mock.ExpectExec("^UPDATE cookbooks SET name=\\? WHERE id=\\?$").
+				WithArgs("Sweets", 1).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			repo := repositories.NewCookbooksRepository(db)
+			err := repo.Rename(ctx, 1, "Sweets")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the cookbook cannot be renamed", func() {
+			mock.ExpectExec("^UPDATE cookbooks").
+				WillReturnError(errors.New("some error"))
+
+			repo := repositories.NewCookbooksRepository(db)
+			err := repo.Rename(ctx, 1, "Sweets")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("cookbook could not be renamed"))
+		})
+	})
+
+	Describe("Delete", func() {
+		It("deletes a cookbook", func() {
+			mock.ExpectExec("^DELETE FROM cookbooks WHERE id=\\?$").
+				WithArgs(1).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			repo := repositories.NewCookbooksRepository(db)
+			err := repo.Delete(ctx, 1)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns no rows if the cookbook does not exist", func() {
+			mock.ExpectExec("^DELETE FROM cookbooks").
+				WillReturnResult(sqlmock.NewResult(0, 0))
+
+			repo := repositories.NewCookbooksRepository(db)
+			err := repo.Delete(ctx, 1)
+			Expect(err).To(MatchError(sql.ErrNoRows))
+		})
+
+		It("returns an error if the cookbook cannot be deleted", func() {
+			mock.ExpectExec("^DELETE FROM cookbooks").
+				WillReturnError(errors.New("some error"))
+
+			repo := repositories.NewCookbooksRepository(db)
+			err := repo.Delete(ctx, 1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("cookbook could not be deleted"))
+		})
+	})
+
+	Describe("ListSections", func() {
+		It("returns the sections of the user's cookbooks", func() {
+			rows := sqlmock.NewRows([]string{"id", "cookbook_id", "name"}).
+				AddRow(5, 1, "Cakes").
+				AddRow(6, 2, "Smoothies")
+
+			mock.ExpectQuery("^SELECT .+ FROM sections as s INNER JOIN cookbooks as c .+ WHERE c.user_id=\\? ORDER BY s.name$").
+				WithArgs(10).
+				WillReturnRows(rows)
+
+			repo := repositories.NewCookbooksRepository(db)
+			sections, err := repo.ListSections(ctx, 10)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(sections).To(Equal([]*repositories.Section{{
+				ID:         Int64Pointer(5),
+				CookbookID: Int64Pointer(1),
+				Name:       StringPointer("Cakes"),
+			}, {
+				ID:         Int64Pointer(6),
+				CookbookID: Int64Pointer(2),
+				Name:       StringPointer("Smoothies"),
+			}}))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the query fails", func() {
+			mock.ExpectQuery("^SELECT .+ FROM sections").
+				WillReturnError(errors.New("some error"))
+
+			repo := repositories.NewCookbooksRepository(db)
+			_, err := repo.ListSections(ctx, 10)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to fetch sections"))
+		})
+	})
+
+	Describe("GetSectionCookbook", func() {
+		It("returns the cookbook a section belongs to", func() {
+			rows := sqlmock.NewRows([]string{"cookbook_id"}).AddRow(1)
+
+			mock.ExpectQuery("^SELECT cookbook_id FROM sections WHERE id=\\?$").
+				WithArgs(5).
+				WillReturnRows(rows)
+
+			repo := repositories.NewCookbooksRepository(db)
+			cookbookID, err := repo.GetSectionCookbook(ctx, 5)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cookbookID).To(BeEquivalentTo(1))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the section cannot be found", func() {
+			mock.ExpectQuery("^SELECT cookbook_id FROM sections").
+				WillReturnError(sql.ErrNoRows)
+
+			repo := repositories.NewCookbooksRepository(db)
+			_, err := repo.GetSectionCookbook(ctx, 5)
+			Expect(err).To(MatchError(sql.ErrNoRows))
+		})
+	})
+
+	Describe("InsertSection", func() {
+		It("inserts a section into a cookbook", func() {
+			mock.ExpectExec("^INSERT INTO sections \\(cookbook_id, name\\) VALUES \\(\\?, \\?\\)$").
+				WithArgs(1, "Cakes").
+				WillReturnResult(sqlmock.NewResult(5, 1))
+
+			repo := repositories.NewCookbooksRepository(db)
+			id, err := repo.InsertSection(ctx, 1, "Cakes")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(id).To(BeEquivalentTo(5))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the section cannot be inserted", func() {
+			mock.ExpectExec("^INSERT INTO sections").
+				WillReturnError(errors.New("some error"))
+
+			repo := repositories.NewCookbooksRepository(db)
+			_, err := repo.InsertSection(ctx, 1, "Cakes")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("section could not be saved"))
+		})
+	})
+
+	Describe("RenameSection", func() {
+		It("renames a section", func() {
+			mock.ExpectExec("^UPDATE sections SET name=\\? WHERE id=\\?$").
+				WithArgs("Pies", 5).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			repo := repositories.NewCookbooksRepository(db)
+			err := repo.RenameSection(ctx, 5, "Pies")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("DeleteSection", func() {
+		It("deletes a section", func() {
+			mock.ExpectExec("^DELETE FROM sections WHERE id=\\?$").
+				WithArgs(5).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			repo := repositories.NewCookbooksRepository(db)
+			err := repo.DeleteSection(ctx, 5)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns no rows if the section does not exist", func() {
+			mock.ExpectExec("^DELETE FROM sections").
+				WillReturnResult(sqlmock.NewResult(0, 0))
+
+			repo := repositories.NewCookbooksRepository(db)
+			err := repo.DeleteSection(ctx, 5)
+			Expect(err).To(MatchError(sql.ErrNoRows))
+		})
+	})
+
+	Describe("AddRecipe", func() {
+		It("files the recipe, moving it to the section if it is already in the cookbook", func() {
+			mock.ExpectExec("^INSERT INTO recipe_locations \\(recipe_id, cookbook_id, section_id\\) VALUES \\(\\?, \\?, \\?\\) ON DUPLICATE KEY UPDATE section_id = VALUES\\(section_id\\)$").
+				WithArgs(3, 1, 5).
+				WillReturnResult(sqlmock.NewResult(0, 2))
+
+			repo := repositories.NewCookbooksRepository(db)
+			err := repo.AddRecipe(ctx, 3, 1, Int64Pointer(5))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("adds a recipe that is not yet in the cookbook without a section", func() {
+			mock.ExpectExec("^INSERT INTO recipe_locations").
+				WithArgs(3, 1, nil).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			repo := repositories.NewCookbooksRepository(db)
+			err := repo.AddRecipe(ctx, 3, 1, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the location cannot be saved", func() {
+			mock.ExpectExec("^INSERT INTO recipe_locations").
+				WillReturnError(errors.New("some error"))
+
+			repo := repositories.NewCookbooksRepository(db)
+			err := repo.AddRecipe(ctx, 3, 1, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("recipe location could not be saved"))
+		})
+	})
+
+	Describe("RemoveRecipe", func() {
+		It("removes a recipe from a cookbook", func() {
+			mock.ExpectExec("^DELETE FROM recipe_locations WHERE recipe_id=\\? AND cookbook_id=\\?$").
+				WithArgs(3, 1).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			repo := repositories.NewCookbooksRepository(db)
+			err := repo.RemoveRecipe(ctx, 3, 1)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns no rows if the recipe is not in the cookbook", func() {
+			mock.ExpectExec("^DELETE FROM recipe_locations").
+				WillReturnResult(sqlmock.NewResult(0, 0))
+
+			repo := repositories.NewCookbooksRepository(db)
+			err := repo.RemoveRecipe(ctx, 3, 1)
+			Expect(err).To(MatchError(sql.ErrNoRows))
+		})
+
+		It("returns an error if the result's RowsAffected fails", func() {
+			mock.ExpectExec("^DELETE FROM recipe_locations").
+				WillReturnResult(sqlmock.NewErrorResult(errors.New("some error")))
+
+			repo := repositories.NewCookbooksRepository(db)
+			err := repo.RemoveRecipe(ctx, 3, 1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("recipe location could not be deleted"))
+		})
+	})
+})
@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+)
+
+type IdentitiesRepository struct {
+	db *sql.DB
+}
+
+func NewIdentitiesRepository(db *sql.DB) *IdentitiesRepository {
+	return &IdentitiesRepository{db: db}
+}
+
+// GetUserID returns the user an external identity is linked to, or
+// sql.ErrNoRows if it has not been linked.
+func (i *IdentitiesRepository) GetUserID(ctx context.Context, provider, subject string) (int64, error) {
+	row := i.db.QueryRowContext(ctx, getIdentityUserQuery, provider, subject)
+
+	var userID int64
+	err := row.Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, err
+		}
+
+		slog.ErrorContext(ctx, "Failed to scan identity", "error", err)
+		return -1, errors.New("failed to retrieve identity")
+	}
+
+	return userID, nil
+}
+
+// Link connects an external identity to a user. It returns false if the
+// identity is already linked, to this user or another one.
+func (i *IdentitiesRepository) Link(ctx context.Context, userID int64, provider, subject string) (bool, error) {
+	res, err := i.db.ExecContext(ctx, linkIdentityQuery, userID, provider, subject)
+	if err != nil {
+		slog.ErrorContext(ctx, "Identity could not be linked", "error", err)
+		return false, errors.New("identity could not be linked")
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		slog.ErrorContext(ctx, "Identity was not linked correctly", "error", err)
+		return false, errors.New("identity was not linked correctly")
+	}
+
+	return affected == 1, nil
+}
+
+const getIdentityUserQuery = "SELECT user_id FROM user_identities WHERE provider=? AND subject=?"
+const linkIdentityQuery = "INSERT IGNORE INTO user_identities (user_id, provider, subject) VALUES (?, ?, ?)"
@@ -0,0 +1,100 @@
+package repositories_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Identities Repository", func() {
+	var (
+		db   *sql.DB
+		mock sqlmock.Sqlmock
+		repo *repositories.IdentitiesRepository
+		ctx  context.Context
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		var err error
+		db, mock, err = sqlmock.New()
+		Expect(err).ToNot(HaveOccurred())
+
+		repo = repositories.NewIdentitiesRepository(db)
+	})
+
+	Describe("GetUserID", func() {
+		It("returns the user an identity is linked to", func() {
+			rows := sqlmock.NewRows([]string{"user_id"}).AddRow(10)
+
+			mock.ExpectQuery("^SELECT user_id FROM user_identities WHERE provider=\\? AND subject=\\?").
+				WithArgs("https://accounts.example.com", "subject-1").
+				WillReturnRows(rows)
+
+			userID, err := repo.GetUserID(ctx, "https://accounts.example.com", "subject-1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(userID).To(Equal(int64(10)))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns sql.ErrNoRows if the identity is not linked", func() {
+			mock.ExpectQuery("^SELECT user_id FROM user_identities").
+				WithArgs("https://accounts.example.com", "subject-1").
+				WillReturnError(sql.ErrNoRows)
+
+			_, err := repo.GetUserID(ctx, "https://accounts.example.com", "subject-1")
+			Expect(err).To(Equal(sql.ErrNoRows))
+		})
+
+		It("returns an error if the query fails", func() {
+			mock.ExpectQuery("^SELECT user_id FROM user_identities").
+				WithArgs("https://accounts.example.com", "subject-1").
+				WillReturnError(errors.New("some sql error"))
+
+			_, err := repo.GetUserID(ctx, "https://accounts.example.com", "subject-1")
+			Expect(err).To(MatchError("failed to retrieve identity"))
+		})
+	})
+
+	Describe("Link", func() {
+		It("links an identity to a user", func() {
+			mock.ExpectExec("^INSERT IGNORE INTO user_identities \\(user_id, provider, subject\\) VALUES \\(\\?, \\?, \\?\\)").
+				WithArgs(10, "https://accounts.example.com", "subject-1").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+
+			linked, err := repo.Link(ctx, 10, "https://accounts.example.com", "subject-1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(linked).To(BeTrue())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns false if the identity is already linked", func() {
+			mock.ExpectExec("^INSERT IGNORE INTO user_identities").
+				WithArgs(10, "https://accounts.example.com", "subject-1").
+				WillReturnResult(sqlmock.NewResult(0, 0))
+
+			linked, err := repo.Link(ctx, 10, "https://accounts.example.com", "subject-1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(linked).To(BeFalse())
+		})
+
+		It("returns an error if the insert fails", func() {
+			mock.ExpectExec("^INSERT IGNORE INTO user_identities").
+				WithArgs(10, "https://accounts.example.com", "subject-1").
+				WillReturnError(errors.New("some sql error"))
+
+			_, err := repo.Link(ctx, 10, "https://accounts.example.com", "subject-1")
+			Expect(err).To(MatchError("identity could not be linked"))
+		})
+	})
+})
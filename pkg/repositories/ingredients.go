@@ -1,9 +1,12 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
 )
 
 type Ingredient struct {
@@ -15,6 +18,31 @@ type Ingredient struct {
 	Preparation      *string
 }
 
+// PantryQuery matches recipes to the ingredients a user has. Have and Exclude
+// are ingredient names, compared ignoring case. Ingredients in Exclude are
+// left out of what a recipe needs.
+type PantryQuery struct {
+	Have    []string
+	Exclude []string
+	Limit   int
+}
+
+// PantryRecipe is a recipe that uses some of the ingredients a user has.
+// Required is how many ingredients it needs and Covered how many of those the
+// user has.
+type PantryRecipe struct {
+	RecipeID   int64
+	RecipeName string
+	Required   int
+	Covered    int
+}
+
+// IngredientRefs are the ingredients and measurements a recipe refers to.
+type IngredientRefs struct {
+	IngredientIDs  []int64
+	MeasurementIDs []int64
+}
+
 type IngredientsRepository struct {
 	db *sql.DB
 }
@@ -23,8 +51,8 @@ func NewIngredientsRepository(db *sql.DB) *IngredientsRepository {
 	return &IngredientsRepository{db: db}
 }
 
-func (r *IngredientsRepository) GetForRecipe(recipeID int64) ([]*Ingredient, error) {
-	rows, err := r.db.Query(getIngredientsForRecipeQuery, recipeID)
+func (r *IngredientsRepository) GetForRecipe(ctx context.Context, recipeID int64) ([]*Ingredient, error) {
+	rows, err := querier(ctx, r.db).QueryContext(ctx, getIngredientsForRecipeQuery, recipeID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch recipe ingredients: %s", err.Error())
 	}
@@ -45,21 +73,102 @@ func (r *IngredientsRepository) GetForRecipe(recipeID int64) ([]*Ingredient, err
 	return recipeIngredients, nil
 }
 
-func (r *IngredientsRepository) Insert(recipeID int64, ingredient *Ingredient) error {
-	ingredientID, err := r.getOrCreateIngredient(*ingredient.Ingredient)
+// MatchPantry returns the user's recipes that use at least one of the
+// ingredients in query.Have, those covering the most first and then those
+// needing the fewest, up to query.Limit.
+func (r *IngredientsRepository) MatchPantry(ctx context.Context, userID int64, query *PantryQuery) ([]*PantryRecipe, error) {
+	if len(query.Have) == 0 {
+		return []*PantryRecipe{}, nil
+	}
+
+	args := append(stringArgs(query.Have), userID)
+	excluded := ""
+	if len(query.Exclude) > 0 {
+		excluded = " AND i.name NOT IN " + inPlaceholders(len(query.Exclude))
+		args = append(args, stringArgs(query.Exclude)...)
+	}
+	args = append(args, query.Limit)
+
+	rows, err := querier(ctx, r.db).QueryContext(ctx, fmt.Sprintf(matchPantryQuery, inPlaceholders(len(query.Have)), excluded), args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to match recipes to pantry", "error", err)
+		return nil, errors.New("failed to match recipes to pantry")
+	}
+	defer rows.Close()
+
+	recipes := []*PantryRecipe{}
+	for rows.Next() {
+		recipe := &PantryRecipe{}
+		if err := rows.Scan(&recipe.RecipeID, &recipe.RecipeName, &recipe.Required, &recipe.Covered); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan pantry recipe", "error", err)
+			return nil, errors.New("failed to match recipes to pantry")
+		}
+		recipes = append(recipes, recipe)
+	}
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Failed to loop through pantry recipes", "error", err)
+		return nil, errors.New("failed to match recipes to pantry")
+	}
+
+	return recipes, nil
+}
+
+// GetMissingForRecipes returns the names of the ingredients each recipe needs
+// that are not in query.Have or query.Exclude, in their order in the recipe.
+func (r *IngredientsRepository) GetMissingForRecipes(ctx context.Context, recipeIDs []int64, query *PantryQuery) (map[int64][]string, error) {
+	missing := make(map[int64][]string)
+	if len(recipeIDs) == 0 {
+		return missing, nil
+	}
+
+	args := int64Args(recipeIDs)
+	conditions := ""
+	for _, names := range [][]string{query.Have, query.Exclude} {
+		if len(names) > 0 {
+			conditions += " AND i.name NOT IN " + inPlaceholders(len(names))
+			args = append(args, stringArgs(names)...)
+		}
+	}
+
+	rows, err := querier(ctx, r.db).QueryContext(ctx, fmt.Sprintf(getMissingIngredientsQuery, inPlaceholders(len(recipeIDs)), conditions), args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to fetch missing ingredients", "error", err)
+		return nil, errors.New("failed to fetch missing ingredients")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var recipeID int64
+		var name string
+		if err := rows.Scan(&recipeID, &name); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan missing ingredient", "error", err)
+			return nil, errors.New("failed to fetch missing ingredients")
+		}
+		missing[recipeID] = append(missing[recipeID], name)
+	}
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Failed to loop through missing ingredients", "error", err)
+		return nil, errors.New("failed to fetch missing ingredients")
+	}
+
+	return missing, nil
+}
+
+func (r *IngredientsRepository) Insert(ctx context.Context, recipeID int64, ingredient *Ingredient) error {
+	ingredientID, err := r.getOrCreateIngredient(ctx, *ingredient.Ingredient)
 	if err != nil {
 		return err
 	}
 
 	var measurementID *int64
 	if ingredient.Measurement != nil && *ingredient.Measurement != "" {
-		measurementID, err = r.getOrCreateMeasurement(*ingredient.Measurement)
+		measurementID, err = r.getOrCreateMeasurement(ctx, *ingredient.Measurement)
 		if err != nil {
 			return err
 		}
 	}
 
-	_, err = r.db.Exec(insertRecipeIngredientQuery,
+	_, err = querier(ctx, r.db).ExecContext(ctx, insertRecipeIngredientQuery,
 		recipeID,
 		ingredientID,
 		ingredient.IngredientNumber,
@@ -69,21 +178,115 @@ func (r *IngredientsRepository) Insert(recipeID int64, ingredient *Ingredient) e
 	)
 
 	if err != nil {
-		fmt.Printf("Recipe ingredient could not be saved: %s\n", err.Error())
+		slog.ErrorContext(ctx, "Recipe ingredient could not be saved", "error", err)
 		return errors.New("recipe ingredient could not be saved")
 	}
 
 	return nil
 }
 
-func (r *IngredientsRepository) getOrCreateIngredient(name string) (int64, error) {
+func (r *IngredientsRepository) DeleteForRecipe(ctx context.Context, recipeID int64) error {
+	_, err := querier(ctx, r.db).ExecContext(ctx, deleteRecipeIngredientsQuery, recipeID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Recipe ingredients could not be deleted", "error", err)
+		return errors.New("recipe ingredients could not be deleted")
+	}
+
+	return nil
+}
+
+// GetRefsForRecipe returns the ingredients and measurements a recipe refers
+// to, so they can be cleaned up with DeleteUnused once the recipe no longer
+// does.
+func (r *IngredientsRepository) GetRefsForRecipe(ctx context.Context, recipeID int64) (*IngredientRefs, error) {
+	rows, err := querier(ctx, r.db).QueryContext(ctx, getRefsForRecipeQuery, recipeID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to fetch recipe ingredient references", "error", err)
+		return nil, errors.New("failed to fetch recipe ingredient references")
+	}
+	defer rows.Close()
+
+	refs := &IngredientRefs{}
+	for rows.Next() {
+		var ingredientID int64
+		var measurementID sql.NullInt64
+		if err := rows.Scan(&ingredientID, &measurementID); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan recipe ingredient references", "error", err)
+			return nil, errors.New("failed to scan recipe ingredient references")
+		}
+
+		refs.IngredientIDs = append(refs.IngredientIDs, ingredientID)
+		if measurementID.Valid {
+			refs.MeasurementIDs = append(refs.MeasurementIDs, measurementID.Int64)
+		}
+	}
+	if rows.Err() != nil {
+		slog.ErrorContext(ctx, "Failed to loop through recipe ingredient references", "error", rows.Err())
+		return nil, errors.New("failed to retrieve recipe ingredient references")
+	}
+
+	return refs, nil
+}
+
+// DeleteUnused removes the given ingredients and measurements if no recipe
+// refers to them any more.
+func (r *IngredientsRepository) DeleteUnused(ctx context.Context, refs *IngredientRefs) error {
+	if len(refs.IngredientIDs) > 0 {
+		_, err := querier(ctx, r.db).ExecContext(ctx, deleteUnusedIngredientsQuery+inPlaceholders(len(refs.IngredientIDs)), int64Args(refs.IngredientIDs)...)
+		if err != nil {
+			slog.ErrorContext(ctx, "Unused ingredients could not be deleted", "error", err)
+			return errors.New("unused ingredients could not be deleted")
+		}
+	}
+
+	if len(refs.MeasurementIDs) > 0 {
+		_, err := querier(ctx, r.db).ExecContext(ctx, deleteUnusedMeasurementsQuery+inPlaceholders(len(refs.MeasurementIDs)), int64Args(refs.MeasurementIDs)...)
+		if err != nil {
+			slog.ErrorContext(ctx, "Unused measurements could not be deleted", "error", err)
+			return errors.New("unused measurements could not be deleted")
+		}
+	}
+
+	return nil
+}
+
+func inPlaceholders(n int) string {
+	return "(" + strings.TrimSuffix(strings.Repeat("?, ", n), ", ") + ")"
+}
+
+func stringArgs(values []string) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+
+	return args
+}
+
+func int64Args(ids []int64) []interface{} {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	return args
+}
+
+// getOrCreateIngredient finds an ingredient by its name with the spacing
+// collapsed, and the column's collation ignores case, so "Brown  sugar" and
+// "brown sugar" are the same ingredient. A new ingredient keeps the case it
+// was first written in. The ingredient found is share locked
+// until the transaction ends, so DeleteUnused in another transaction waits
+// until the recipe refers to it instead of removing it first.
+func (r *IngredientsRepository) getOrCreateIngredient(ctx context.Context, name string) (int64, error) {
 	var id int64
-	err := r.db.QueryRow("SELECT id FROM ingredients WHERE name = ?", name).Scan(&id)
+	name = strings.Join(strings.Fields(name), " ")
+	err := querier(ctx, r.db).QueryRowContext(ctx, "SELECT id FROM ingredients WHERE name = ? LOCK IN SHARE MODE", name).Scan(&id)
 	if err == nil {
 		return id, nil
 	}
 
-	res, err := r.db.Exec("INSERT INTO ingredients (name) VALUES (?)", name)
+	res, err := querier(ctx, r.db).ExecContext(ctx, "INSERT INTO ingredients (name) VALUES (?)", name)
 	if err != nil {
 		return 0, err
 	}
@@ -91,14 +294,14 @@ func (r *IngredientsRepository) getOrCreateIngredient(name string) (int64, error
 	return res.LastInsertId()
 }
 
-func (r *IngredientsRepository) getOrCreateMeasurement(name string) (*int64, error) {
+func (r *IngredientsRepository) getOrCreateMeasurement(ctx context.Context, name string) (*int64, error) {
 	var id int64
-	err := r.db.QueryRow("SELECT id FROM measurements WHERE name = ?", name).Scan(&id)
+	err := querier(ctx, r.db).QueryRowContext(ctx, "SELECT id FROM measurements WHERE name = ? LOCK IN SHARE MODE", name).Scan(&id)
 	if err == nil {
 		return &id, nil
 	}
 
-	res, err := r.db.Exec("INSERT INTO measurements (name) VALUES (?)", name)
+	res, err := querier(ctx, r.db).ExecContext(ctx, "INSERT INTO measurements (name) VALUES (?)", name)
 	if err != nil {
 		return nil, err
 	}
@@ -122,7 +325,39 @@ const getIngredientsForRecipeQuery = `
   LEFT JOIN measurements as m on ri.measurement_id=m.id
   WHERE ri.recipe_id=?
 `
+const matchPantryQuery = `
+  SELECT r.id,
+         r.name,
+         COUNT(DISTINCT i.id) as required,
+         COUNT(DISTINCT CASE WHEN i.name IN %s THEN i.id END) as covered
+  FROM recipes as r
+  JOIN recipe_ingredients as ri on ri.recipe_id=r.id
+  JOIN ingredients as i on ri.ingredient_id=i.id
+  WHERE r.creator=?%s
+  GROUP BY r.id, r.name
+  HAVING covered > 0
+  ORDER BY covered DESC, required, r.name, r.id
+  LIMIT ?
+`
+const getMissingIngredientsQuery = `
+  SELECT ri.recipe_id,
+         i.name
+  FROM recipe_ingredients as ri
+  JOIN ingredients as i on ri.ingredient_id=i.id
+  WHERE ri.recipe_id IN %s%s
+  ORDER BY ri.recipe_id, ri.ingredient_no
+`
 const insertRecipeIngredientQuery = `
   INSERT INTO recipe_ingredients (recipe_id, ingredient_id, ingredient_no, amount, measurement_id, preparation)
   VALUES (?, ?, ?, ?, ?, ?)
 `
+const deleteRecipeIngredientsQuery = `DELETE FROM recipe_ingredients WHERE recipe_id=?`
+const getRefsForRecipeQuery = `SELECT ingredient_id, measurement_id FROM recipe_ingredients WHERE recipe_id=?`
+const deleteUnusedIngredientsQuery = `
+  DELETE i FROM ingredients as i
+  LEFT JOIN recipe_ingredients as ri on ri.ingredient_id=i.id
+  WHERE ri.ingredient_id IS NULL AND i.id IN `
+const deleteUnusedMeasurementsQuery = `
+  DELETE m FROM measurements as m
+  LEFT JOIN recipe_ingredients as ri on ri.measurement_id=m.id
+  WHERE ri.measurement_id IS NULL AND m.id IN `
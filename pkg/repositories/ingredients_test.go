@@ -1,8 +1,10 @@
 package repositories_test
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 
@@ -17,9 +19,12 @@ var _ = Describe("Ingredients Repository", func() {
 	var (
 		db   *sql.DB
 		mock sqlmock.Sqlmock
+		ctx  context.Context
 	)
 
 	BeforeEach(func() {
+		ctx = context.Background()
+
 		var err error
 		db, mock, err = sqlmock.New()
 		Expect(err).ToNot(HaveOccurred())
@@ -36,7 +41,7 @@ var _ = Describe("Ingredients Repository", func() {
 				WillReturnRows(rows)
 
 			repo := repositories.NewIngredientsRepository(db)
-			recipes, err := repo.GetForRecipe(1)
+			recipes, err := repo.GetForRecipe(ctx, 1)
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(recipes).To(Equal([]*repositories.Ingredient{{
@@ -56,13 +61,29 @@ var _ = Describe("Ingredients Repository", func() {
 			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
 		})
 
+		It("aborts the query if the context is cancelled", func() {
+			mock.ExpectQuery(`^SELECT .* FROM recipe_ingredients .* WHERE .*=?`).
+				WithArgs(1).
+				WillDelayFor(time.Minute).
+				WillReturnRows(sqlmock.NewRows([]string{"ingredient"}))
+
+			ctx, cancel := context.WithCancel(ctx)
+			time.AfterFunc(10*time.Millisecond, cancel)
+
+			start := time.Now()
+			repo := repositories.NewIngredientsRepository(db)
+			_, err := repo.GetForRecipe(ctx, 1)
+			Expect(err).To(HaveOccurred())
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		})
+
 		It("returns an error if the query fails", func() {
 			mock.ExpectQuery(`^SELECT .* FROM recipe_ingredients .* WHERE .*=?`).
 				WithArgs(1).
 				WillReturnError(errors.New("error"))
 
 			repo := repositories.NewIngredientsRepository(db)
-			_, err := repo.GetForRecipe(1)
+			_, err := repo.GetForRecipe(ctx, 1)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to fetch recipe ingredients"))
 		})
@@ -76,7 +97,7 @@ var _ = Describe("Ingredients Repository", func() {
 				WillReturnRows(rows)
 
 			repo := repositories.NewIngredientsRepository(db)
-			_, err := repo.GetForRecipe(1)
+			_, err := repo.GetForRecipe(ctx, 1)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to scan recipe ingredients"))
 		})
@@ -91,9 +112,262 @@ var _ = Describe("Ingredients Repository", func() {
 				WillReturnRows(rows)
 
 			repo := repositories.NewIngredientsRepository(db)
-			_, err := repo.GetForRecipe(1)
+			_, err := repo.GetForRecipe(ctx, 1)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to loop through recipe ingredients"))
 		})
 	})
+
+	Describe("MatchPantry", func() {
+		It("counts the ingredients each recipe needs and has in the query", func() {
+			rows := sqlmock.NewRows([]string{"id", "name", "required", "covered"}).
+				AddRow(1, "Pancakes", 4, 3).
+				AddRow(2, "Omelette", 3, 2)
+
+			mock.ExpectQuery(`^SELECT r.id, r.name, COUNT\(DISTINCT i.id\) as required, COUNT\(DISTINCT CASE WHEN i.name IN \(\?, \?\) THEN i.id END\) as covered `+
+				`FROM recipes as r .* WHERE r.creator=\? AND i.name NOT IN \(\?\) `+
+				`GROUP BY r.id, r.name HAVING covered > 0 ORDER BY covered DESC, required, r.name, r.id LIMIT \?$`).
+				WithArgs("egg", "eggs", 10, "salt", 20).
+				WillReturnRows(rows)
+
+			repo := repositories.NewIngredientsRepository(db)
+			recipes, err := repo.MatchPantry(ctx, 10, &repositories.PantryQuery{
+				Have:    []string{"egg", "eggs"},
+				Exclude: []string{"salt"},
+				Limit:   20,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(recipes).To(Equal([]*repositories.PantryRecipe{
+				{RecipeID: 1, RecipeName: "Pancakes", Required: 4, Covered: 3},
+				{RecipeID: 2, RecipeName: "Omelette", Required: 3, Covered: 2},
+			}))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("leaves out the exclusion when there is nothing to exclude", func() {
+			mock.ExpectQuery(`WHERE r.creator=\? GROUP BY`).
+				WithArgs("egg", 10, 20).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name", "required", "covered"}))
+
+			repo := repositories.NewIngredientsRepository(db)
+			recipes, err := repo.MatchPantry(ctx, 10, &repositories.PantryQuery{Have: []string{"egg"}, Limit: 20})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recipes).To(BeEmpty())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("does not query the database without any ingredients", func() {
+			repo := repositories.NewIngredientsRepository(db)
+			recipes, err := repo.MatchPantry(ctx, 10, &repositories.PantryQuery{Limit: 20})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recipes).To(BeEmpty())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the query fails", func() {
+			mock.ExpectQuery(`^SELECT r.id`).
+				WillReturnError(errors.New("some error"))
+
+			repo := repositories.NewIngredientsRepository(db)
+			_, err := repo.MatchPantry(ctx, 10, &repositories.PantryQuery{Have: []string{"egg"}, Limit: 20})
+			Expect(err).To(MatchError("failed to match recipes to pantry"))
+		})
+	})
+
+	Describe("GetMissingForRecipes", func() {
+		It("returns the ingredients of each recipe not in the query", func() {
+			rows := sqlmock.NewRows([]string{"recipe_id", "name"}).
+				AddRow(1, "Milk").
+				AddRow(2, "Butter").
+				AddRow(2, "Chives")
+
+			mock.ExpectQuery(`^SELECT ri.recipe_id, i.name FROM recipe_ingredients as ri .* `+
+				`WHERE ri.recipe_id IN \(\?, \?\) AND i.name NOT IN \(\?, \?\) AND i.name NOT IN \(\?\) `+
+				`ORDER BY ri.recipe_id, ri.ingredient_no$`).
+				WithArgs(1, 2, "egg", "eggs", "salt").
+				WillReturnRows(rows)
+
+			repo := repositories.NewIngredientsRepository(db)
+			missing, err := repo.GetMissingForRecipes(ctx, []int64{1, 2}, &repositories.PantryQuery{
+				Have:    []string{"egg", "eggs"},
+				Exclude: []string{"salt"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(missing).To(Equal(map[int64][]string{
+				1: {"Milk"},
+				2: {"Butter", "Chives"},
+			}))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("does not query the database without any recipes", func() {
+			repo := repositories.NewIngredientsRepository(db)
+			missing, err := repo.GetMissingForRecipes(ctx, nil, &repositories.PantryQuery{Have: []string{"egg"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(missing).To(BeEmpty())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the query fails", func() {
+			mock.ExpectQuery(`^SELECT ri.recipe_id`).
+				WillReturnError(errors.New("some error"))
+
+			repo := repositories.NewIngredientsRepository(db)
+			_, err := repo.GetMissingForRecipes(ctx, []int64{1}, &repositories.PantryQuery{Have: []string{"egg"}})
+			Expect(err).To(MatchError("failed to fetch missing ingredients"))
+		})
+	})
+
+	Describe("Insert", func() {
+		It("looks up the ingredient by its name with the spacing collapsed", func() {
+			mock.ExpectQuery(`^SELECT id FROM ingredients WHERE name = \? LOCK IN SHARE MODE$`).
+				WithArgs("Root BEER").
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+			mock.ExpectExec("^INSERT INTO recipe_ingredients").
+				WithArgs(1, 7, 1, nil, nil, nil).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			repo := repositories.NewIngredientsRepository(db)
+			err := repo.Insert(ctx, 1, &repositories.Ingredient{
+				Ingredient:       StringPointer("  Root   BEER "),
+				IngredientNumber: IntPointer(1),
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("saves a new ingredient with its spacing collapsed", func() {
+			mock.ExpectQuery(`^SELECT id FROM ingredients WHERE name = \? LOCK IN SHARE MODE$`).
+				WithArgs("Brown sugar").
+				WillReturnError(sql.ErrNoRows)
+			mock.ExpectExec(`^INSERT INTO ingredients \(name\) VALUES \(\?\)$`).
+				WithArgs("Brown sugar").
+				WillReturnResult(sqlmock.NewResult(8, 1))
+			mock.ExpectExec("^INSERT INTO recipe_ingredients").
+				WithArgs(1, 8, 2, nil, nil, nil).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			repo := repositories.NewIngredientsRepository(db)
+			err := repo.Insert(ctx, 1, &repositories.Ingredient{
+				Ingredient:       StringPointer("Brown  sugar"),
+				IngredientNumber: IntPointer(2),
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("DeleteForRecipe", func() {
+		It("deletes the ingredients for a recipe", func() {
+			mock.ExpectExec("^DELETE FROM recipe_ingredients WHERE recipe_id=?").
+				WithArgs(1).
+				WillReturnResult(sqlmock.NewResult(0, 2))
+
+			repo := repositories.NewIngredientsRepository(db)
+			err := repo.DeleteForRecipe(ctx, 1)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the delete fails", func() {
+			mock.ExpectExec("^DELETE FROM recipe_ingredients WHERE recipe_id=?").
+				WithArgs(1).
+				WillReturnError(errors.New("error"))
+
+			repo := repositories.NewIngredientsRepository(db)
+			err := repo.DeleteForRecipe(ctx, 1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("recipe ingredients could not be deleted"))
+		})
+	})
+
+	Describe("GetRefsForRecipe", func() {
+		It("returns the ingredients and measurements the recipe refers to", func() {
+			rows := sqlmock.NewRows([]string{"ingredient_id", "measurement_id"}).
+				AddRow(3, 4).
+				AddRow(5, nil)
+
+			mock.ExpectQuery(`^SELECT ingredient_id, measurement_id FROM recipe_ingredients WHERE recipe_id=\?$`).
+				WithArgs(1).
+				WillReturnRows(rows)
+
+			repo := repositories.NewIngredientsRepository(db)
+			refs, err := repo.GetRefsForRecipe(ctx, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(refs).To(Equal(&repositories.IngredientRefs{
+				IngredientIDs:  []int64{3, 5},
+				MeasurementIDs: []int64{4},
+			}))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the query fails", func() {
+			mock.ExpectQuery("^SELECT ingredient_id, measurement_id FROM recipe_ingredients").
+				WithArgs(1).
+				WillReturnError(errors.New("some error"))
+
+			repo := repositories.NewIngredientsRepository(db)
+			_, err := repo.GetRefsForRecipe(ctx, 1)
+			Expect(err).To(MatchError("failed to fetch recipe ingredient references"))
+		})
+	})
+
+	Describe("DeleteUnused", func() {
+		It("deletes the given ingredients and measurements no recipe refers to", func() {
+			mock.ExpectExec(`DELETE i FROM ingredients .* WHERE ri.ingredient_id IS NULL AND i.id IN \(\?, \?\)$`).
+				WithArgs(3, 5).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(`DELETE m FROM measurements .* WHERE ri.measurement_id IS NULL AND m.id IN \(\?\)$`).
+				WithArgs(4).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			repo := repositories.NewIngredientsRepository(db)
+			err := repo.DeleteUnused(ctx, &repositories.IngredientRefs{
+				IngredientIDs:  []int64{3, 5},
+				MeasurementIDs: []int64{4},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("does nothing if there is nothing to check", func() {
+			repo := repositories.NewIngredientsRepository(db)
+			err := repo.DeleteUnused(ctx, &repositories.IngredientRefs{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the ingredients cannot be deleted", func() {
+			mock.ExpectExec("DELETE i FROM ingredients").
+				WillReturnError(errors.New("error"))
+
+			repo := repositories.NewIngredientsRepository(db)
+			err := repo.DeleteUnused(ctx, &repositories.IngredientRefs{IngredientIDs: []int64{3}})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unused ingredients could not be deleted"))
+		})
+
+		It("returns an error if the measurements cannot be deleted", func() {
+			mock.ExpectExec("DELETE m FROM measurements").
+				WillReturnError(errors.New("error"))
+
+			repo := repositories.NewIngredientsRepository(db)
+			err := repo.DeleteUnused(ctx, &repositories.IngredientRefs{MeasurementIDs: []int64{4}})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unused measurements could not be deleted"))
+		})
+	})
 })
@@ -0,0 +1,9 @@
+package repositories
+
+import "strings"
+
+// NormalizeName lower cases a tag or ingredient name and collapses its
+// spacing, so names that differ only in case or whitespace are the same.
+func NormalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
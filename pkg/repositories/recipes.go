@@ -1,9 +1,12 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
 )
 
 type Recipe struct {
@@ -17,6 +20,50 @@ type Recipe struct {
 	CoolTime    *string
 	TotalTime   *string
 	Source      *string
+
+	// TotalMinutes is TotalTime in minutes, used to sort recipes by how
+	// long they take. It is nil if TotalTime could not be parsed.
+	TotalMinutes *int
+}
+
+// RecipeFilter narrows a recipe listing to the recipes filed in a cookbook
+// and/or one of its sections, with servings in a range or from a source. With
+// Tags, only recipes with any of the tags are listed, or with all of them if
+// MatchAllTags is set.
+type RecipeFilter struct {
+	CookbookID   *int64
+	SectionID    *int64
+	MinServings  *int
+	MaxServings  *int
+	Source       *string
+	Tags         []string
+	MatchAllTags bool
+}
+
+// RecipeSort is the column a recipe listing is ordered by. Recipes with the
+// same value are ordered by ID so each recipe has a fixed position.
+type RecipeSort string
+
+const (
+	RecipeSortName      RecipeSort = "name"
+	RecipeSortCreatedAt RecipeSort = "created_at"
+	RecipeSortTotalTime RecipeSort = "total_time"
+)
+
+// RecipePage selects a page of a recipe listing. After is the cursor of the
+// last recipe on the previous page, or nil for the first page.
+type RecipePage struct {
+	Sort       RecipeSort
+	Descending bool
+	Limit      int
+	After      *RecipeCursor
+}
+
+// RecipeCursor is the position of a recipe in a listing: its value for the
+// sort column, as MySQL returns it, and its ID.
+type RecipeCursor struct {
+	Value string
+	ID    int64
 }
 
 type RecipesRepository struct {
@@ -27,33 +74,150 @@ func NewRecipesRepository(db *sql.DB) *RecipesRepository {
 	return &RecipesRepository{db: db}
 }
 
-func (r *RecipesRepository) List(userID int64) ([]*Recipe, error) {
-	rows, err := r.db.Query(listRecipesQuery, userID)
+// List returns a page of the user's recipes and the cursor for the next page,
+// which is nil on the last page.
+func (r *RecipesRepository) List(ctx context.Context, userID int64, filter *RecipeFilter, page *RecipePage) ([]*Recipe, *RecipeCursor, error) {
+	sortBy, err := sortColumn(page.Sort, page.Descending)
 	if err != nil {
-		fmt.Printf("Failed to fetch recipes: %s\n", err.Error())
-		return nil, errors.New("failed to fetch recipes")
+		return nil, nil, err
+	}
+
+	conditions, args := recipeConditions(userID, filter)
+
+	direction, comparison := "ASC", ">"
+	if page.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	if page.After != nil {
+		conditions += fmt.Sprintf(" AND (%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", sortBy, comparison)
+		args = append(args, page.After.Value, page.After.Value, page.After.ID)
+	}
+
+	// One more recipe than the page holds is fetched to tell if there is a
+	// next page.
+	query := fmt.Sprintf("SELECT id, name, description, %[1]s FROM recipes WHERE %[2]s ORDER BY %[1]s %[3]s, id %[3]s LIMIT ?",
+		sortBy, conditions, direction)
+	args = append(args, page.Limit+1)
+
+	rows, err := querier(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to fetch recipes", "error", err)
+		return nil, nil, errors.New("failed to fetch recipes")
 	}
 	defer rows.Close()
 
 	var recipes []*Recipe
+	var cursors []*RecipeCursor
 	for rows.Next() {
 		r := &Recipe{}
-		if err := rows.Scan(&r.ID, &r.Name, &r.Description); err != nil {
-			fmt.Printf("Failed to scan recipes: %s\n", rows.Err())
-			return nil, errors.New("failed to scan recipes")
+		cursor := &RecipeCursor{}
+		if err := rows.Scan(&r.ID, &r.Name, &r.Description, &cursor.Value); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan recipes", "error", err)
+			return nil, nil, errors.New("failed to scan recipes")
 		}
+		cursor.ID = *r.ID
+
 		recipes = append(recipes, r)
+		cursors = append(cursors, cursor)
 	}
 	if rows.Err() != nil {
-		fmt.Printf("Failed to loop through recipes: %s\n", rows.Err())
-		return nil, errors.New("failed to retrieve recipes")
+		slog.ErrorContext(ctx, "Failed to loop through recipes", "error", rows.Err())
+		return nil, nil, errors.New("failed to retrieve recipes")
 	}
 
-	return recipes, nil
+	if len(recipes) <= page.Limit {
+		return recipes, nil, nil
+	}
+
+	return recipes[:page.Limit], cursors[page.Limit-1], nil
+}
+
+// Count returns how many of the user's recipes match the filter.
+func (r *RecipesRepository) Count(ctx context.Context, userID int64, filter *RecipeFilter) (int, error) {
+	conditions, args := recipeConditions(userID, filter)
+
+	var count int
+	if err := querier(ctx, r.db).QueryRowContext(ctx, "SELECT COUNT(*) FROM recipes WHERE "+conditions, args...).Scan(&count); err != nil {
+		slog.ErrorContext(ctx, "Failed to count recipes", "error", err)
+		return 0, errors.New("failed to count recipes")
+	}
+
+	return count, nil
 }
 
-func (r *RecipesRepository) Get(id, userID int64) (*Recipe, error) {
-	row := r.db.QueryRow(getRecipeQuery, id, userID)
+func recipeConditions(userID int64, filter *RecipeFilter) (string, []interface{}) {
+	conditions := "creator=?"
+	args := []interface{}{userID}
+
+	if filter == nil {
+		return conditions, args
+	}
+
+	if filter.CookbookID != nil {
+		conditions += " AND id IN (SELECT recipe_id FROM recipe_locations WHERE cookbook_id=?)"
+		args = append(args, *filter.CookbookID)
+	}
+
+	if filter.SectionID != nil {
+		conditions += " AND id IN (SELECT recipe_id FROM recipe_locations WHERE section_id=?)"
+		args = append(args, *filter.SectionID)
+	}
+
+	if filter.MinServings != nil {
+		conditions += " AND servings>=?"
+		args = append(args, *filter.MinServings)
+	}
+
+	if filter.MaxServings != nil {
+		conditions += " AND servings<=?"
+		args = append(args, *filter.MaxServings)
+	}
+
+	if filter.Source != nil {
+		conditions += " AND source=?"
+		args = append(args, *filter.Source)
+	}
+
+	if len(filter.Tags) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(filter.Tags)), ", ")
+		conditions += " AND id IN (SELECT rt.recipe_id FROM recipe_tags as rt JOIN tags as t on rt.tag_id=t.id" +
+			" WHERE t.user_id=? AND t.name IN (" + placeholders + ") GROUP BY rt.recipe_id"
+		args = append(args, userID)
+		for _, tag := range filter.Tags {
+			args = append(args, tag)
+		}
+
+		if filter.MatchAllTags {
+			conditions += " HAVING COUNT(*)=?"
+			args = append(args, len(filter.Tags))
+		}
+		conditions += ")"
+	}
+
+	return conditions, args
+}
+
+// sortColumn returns the expression to order recipes by. Recipes without a
+// total time are sorted after the rest in either direction.
+func sortColumn(sort RecipeSort, descending bool) (string, error) {
+	switch sort {
+	case RecipeSortName:
+		return "name", nil
+	case RecipeSortCreatedAt:
+		return "created_at", nil
+	case RecipeSortTotalTime:
+		if descending {
+			return "COALESCE(total_minutes, -1)", nil
+		}
+		return "COALESCE(total_minutes, 2147483647)", nil
+	default:
+		return "", fmt.Errorf("unknown recipe sort %q", sort)
+	}
+}
+
+func (r *RecipesRepository) Get(ctx context.Context, id, userID int64) (*Recipe, error) {
+	row := querier(ctx, r.db).QueryRowContext(ctx, getRecipeQuery, id, userID)
 
 	recipe := &Recipe{}
 	if err := row.Scan(&recipe.ID,
@@ -70,15 +234,29 @@ func (r *RecipesRepository) Get(id, userID int64) (*Recipe, error) {
 			return nil, err
 		}
 
-		fmt.Printf("Failed to scan recipe '%d': %s\n", id, err.Error())
+		slog.ErrorContext(ctx, "Failed to scan recipe", "recipe_id", id, "error", err)
 		return nil, errors.New("failed to retrieve recipe")
 	}
 
 	return recipe, nil
 }
 
-func (r *RecipesRepository) Insert(recipe *Recipe, userID int64) (int64, error) {
-	res, err := r.db.Exec(insertRecipeQuery,
+func (r *RecipesRepository) GetCreator(ctx context.Context, id int64) (int64, error) {
+	var creator int64
+	if err := querier(ctx, r.db).QueryRowContext(ctx, getRecipeCreatorQuery, id).Scan(&creator); err != nil {
+		if err == sql.ErrNoRows {
+			return -1, err
+		}
+
+		slog.ErrorContext(ctx, "Failed to scan creator for recipe", "recipe_id", id, "error", err)
+		return -1, errors.New("failed to retrieve recipe creator")
+	}
+
+	return creator, nil
+}
+
+func (r *RecipesRepository) Insert(ctx context.Context, recipe *Recipe, userID int64) (int64, error) {
+	res, err := querier(ctx, r.db).ExecContext(ctx, insertRecipeQuery,
 		userID,
 		recipe.Name,
 		recipe.Description,
@@ -87,24 +265,112 @@ func (r *RecipesRepository) Insert(recipe *Recipe, userID int64) (int64, error)
 		recipe.CookTime,
 		recipe.CoolTime,
 		recipe.TotalTime,
+		recipe.TotalMinutes,
 		recipe.Source,
 	)
 
 	if err != nil {
-		fmt.Printf("RecipeResponse could not be saved: %s\n", err.Error())
+		slog.ErrorContext(ctx, "RecipeResponse could not be saved", "error", err)
 		return 0, errors.New("recipe could not be saved")
 	}
 
 	id, err := res.LastInsertId()
 	if err != nil {
-		fmt.Printf("RecipeResponse was not saved correctly: %s\n", err.Error())
+		slog.ErrorContext(ctx, "RecipeResponse was not saved correctly", "error", err)
 		return 0, fmt.Errorf("recipe was not saved correctly: %s", err.Error())
 	}
 
 	return id, nil
 }
 
-const listRecipesQuery = "SELECT id, name, description FROM recipes WHERE creator=?"
+func (r *RecipesRepository) Update(ctx context.Context, id int64, recipe *Recipe, userID int64) error {
+	_, err := querier(ctx, r.db).ExecContext(ctx, updateRecipeQuery,
+		recipe.Name,
+		recipe.Description,
+		recipe.Servings,
+		recipe.PrepTime,
+		recipe.CookTime,
+		recipe.CoolTime,
+		recipe.TotalTime,
+		recipe.TotalMinutes,
+		recipe.Source,
+		id,
+		userID,
+	)
+
+	if err != nil {
+		slog.ErrorContext(ctx, "Recipe could not be updated", "recipe_id", id, "error", err)
+		return errors.New("recipe could not be updated")
+	}
+
+	return nil
+}
+
+func (r *RecipesRepository) Delete(ctx context.Context, id, userID int64) error {
+	res, err := querier(ctx, r.db).ExecContext(ctx, deleteRecipeQuery, id, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Recipe could not be deleted", "recipe_id", id, "error", err)
+		return errors.New("recipe could not be deleted")
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		slog.ErrorContext(ctx, "Recipe was not deleted correctly", "recipe_id", id, "error", err)
+		return fmt.Errorf("recipe was not deleted correctly: %s", err.Error())
+	}
+
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// RecipeTotalTime is a recipe's total time as it was written.
+type RecipeTotalTime struct {
+	ID        int64
+	TotalTime string
+}
+
+// ListMissingTotalMinutes returns the recipes with a total time but no
+// total_minutes, such as those saved before the column was added.
+func (r *RecipesRepository) ListMissingTotalMinutes(ctx context.Context) ([]*RecipeTotalTime, error) {
+	rows, err := querier(ctx, r.db).QueryContext(ctx, listMissingTotalMinutesQuery)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to list recipes missing total minutes", "error", err)
+		return nil, errors.New("failed to list recipes missing total minutes")
+	}
+	defer rows.Close()
+
+	var recipes []*RecipeTotalTime
+	for rows.Next() {
+		recipe := &RecipeTotalTime{}
+		if err := rows.Scan(&recipe.ID, &recipe.TotalTime); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan recipe total time", "error", err)
+			return nil, errors.New("failed to list recipes missing total minutes")
+		}
+
+		recipes = append(recipes, recipe)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Failed to list recipes missing total minutes", "error", err)
+		return nil, errors.New("failed to list recipes missing total minutes")
+	}
+
+	return recipes, nil
+}
+
+func (r *RecipesRepository) SetTotalMinutes(ctx context.Context, id int64, minutes int) error {
+	_, err := querier(ctx, r.db).ExecContext(ctx, setTotalMinutesQuery, minutes, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to set recipe total minutes", "recipe_id", id, "error", err)
+		return errors.New("failed to set recipe total minutes")
+	}
+
+	return nil
+}
+
 const getRecipeQuery = `SELECT
     r.id,
     r.name,
@@ -119,6 +385,7 @@ const getRecipeQuery = `SELECT
 LEFT JOIN users as u on r.creator=u.id
 WHERE r.id=? AND r.creator=?
 `
+const getRecipeCreatorQuery = "SELECT creator FROM recipes WHERE id=?"
 const insertRecipeQuery = `INSERT INTO recipes
     (creator,
     name,
@@ -128,6 +395,22 @@ const insertRecipeQuery = `INSERT INTO recipes
     cook_time,
     cool_time,
     total_time,
+    total_minutes,
     source)
-VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+const updateRecipeQuery = `UPDATE recipes SET
+    name=?,
+    description=?,
+    servings=?,
+    prep_time=?,
+    cook_time=?,
+    cool_time=?,
+    total_time=?,
+    total_minutes=?,
+    source=?
+WHERE id=? AND creator=?
 `
+const deleteRecipeQuery = "DELETE FROM recipes WHERE id=? AND creator=?"
+const listMissingTotalMinutesQuery = "SELECT id, total_time FROM recipes WHERE total_minutes IS NULL AND total_time IS NOT NULL"
+const setTotalMinutesQuery = "UPDATE recipes SET total_minutes=? WHERE id=?"
@@ -1,8 +1,10 @@
 package repositories_test
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 
@@ -17,27 +19,37 @@ var _ = Describe("RecipeResponse Repository", func() {
 	var (
 		db   *sql.DB
 		mock sqlmock.Sqlmock
+		ctx  context.Context
 	)
 
 	BeforeEach(func() {
+		ctx = context.Background()
+
 		var err error
 		db, mock, err = sqlmock.New()
 		Expect(err).ToNot(HaveOccurred())
 	})
 
 	Describe("List", func() {
-		It("returns the list of all recipes", func() {
-			rows := sqlmock.NewRows([]string{"id", "name", "description"}).
-				AddRow(0, "First RecipeResponse", "The First").
-				AddRow(1, "Second RecipeResponse", "The Second")
+		var firstPage *repositories.RecipePage
 
-			mock.ExpectQuery("^SELECT .+ FROM recipes WHERE .+=?$").
-				WithArgs(10).
+		BeforeEach(func() {
+			firstPage = &repositories.RecipePage{Sort: repositories.RecipeSortName, Limit: 20}
+		})
+
+		It("returns the first page of recipes", func() {
+			rows := sqlmock.NewRows([]string{"id", "name", "description", "name"}).
+				AddRow(0, "First RecipeResponse", "The First", "First RecipeResponse").
+				AddRow(1, "Second RecipeResponse", "The Second", "Second RecipeResponse")
+
+			mock.ExpectQuery("^SELECT id, name, description, name FROM recipes WHERE creator=\\? ORDER BY name ASC, id ASC LIMIT \\?$").
+				WithArgs(10, 21).
 				WillReturnRows(rows)
 
 			repo := repositories.NewRecipesRepository(db)
-			recipes, err := repo.List(10)
+			recipes, next, err := repo.List(ctx, 10, nil, firstPage)
 			Expect(err).ToNot(HaveOccurred())
+			Expect(next).To(BeNil())
 
 			Expect(recipes).To(Equal([]*repositories.Recipe{{
 				ID:          Int64Pointer(0),
@@ -52,13 +64,132 @@ var _ = Describe("RecipeResponse Repository", func() {
 			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
 		})
 
+		It("returns the cursor of the last recipe if there is another page", func() {
+			rows := sqlmock.NewRows([]string{"id", "name", "description", "created_at"}).
+				AddRow(7, "First", "The First", "2024-06-02 10:00:00").
+				AddRow(3, "Second", "The Second", "2024-06-01 10:00:00").
+				AddRow(4, "Third", "The Third", "2024-06-01 10:00:00")
+
+			mock.ExpectQuery("^SELECT id, name, description, created_at FROM recipes WHERE creator=\\? "+
+				"ORDER BY created_at DESC, id DESC LIMIT \\?$").
+				WithArgs(10, 3).
+				WillReturnRows(rows)
+
+			repo := repositories.NewRecipesRepository(db)
+			recipes, next, err := repo.List(ctx, 10, nil, &repositories.RecipePage{
+				Sort:       repositories.RecipeSortCreatedAt,
+				Descending: true,
+				Limit:      2,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recipes).To(HaveLen(2))
+			Expect(next).To(Equal(&repositories.RecipeCursor{Value: "2024-06-01 10:00:00", ID: 3}))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("starts after the cursor", func() {
+			rows := sqlmock.NewRows([]string{"id", "name", "description", "total_minutes"})
+
+			mock.ExpectQuery("^SELECT .+ FROM recipes WHERE creator=\\? "+
+				"AND \\(COALESCE\\(total_minutes, 2147483647\\) > \\? OR "+
+				"\\(COALESCE\\(total_minutes, 2147483647\\) = \\? AND id > \\?\\)\\) "+
+				"ORDER BY COALESCE\\(total_minutes, 2147483647\\) ASC, id ASC LIMIT \\?$").
+				WithArgs(10, "45", "45", 8, 21).
+				WillReturnRows(rows)
+
+			repo := repositories.NewRecipesRepository(db)
+			recipes, next, err := repo.List(ctx, 10, nil, &repositories.RecipePage{
+				Sort:  repositories.RecipeSortTotalTime,
+				Limit: 20,
+				After: &repositories.RecipeCursor{Value: "45", ID: 8},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recipes).To(BeEmpty())
+			Expect(next).To(BeNil())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("filters the recipes", func() {
+			rows := sqlmock.NewRows([]string{"id", "name", "description", "name"}).
+				AddRow(0, "First RecipeResponse", "The First", "First RecipeResponse")
+
+			mock.ExpectQuery("^SELECT .+ FROM recipes WHERE creator=\\? "+
+				"AND id IN \\(SELECT recipe_id FROM recipe_locations WHERE cookbook_id=\\?\\) "+
+				"AND id IN \\(SELECT recipe_id FROM recipe_locations WHERE section_id=\\?\\) "+
+				"AND servings>=\\? AND servings<=\\? AND source=\\? ORDER BY .+$").
+				WithArgs(10, 2, 3, 4, 6, "Grandma", 21).
+				WillReturnRows(rows)
+
+			repo := repositories.NewRecipesRepository(db)
+			recipes, _, err := repo.List(ctx, 10, &repositories.RecipeFilter{
+				CookbookID:  Int64Pointer(2),
+				SectionID:   Int64Pointer(3),
+				MinServings: IntPointer(4),
+				MaxServings: IntPointer(6),
+				Source:      StringPointer("Grandma"),
+			}, firstPage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recipes).To(HaveLen(1))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("filters the recipes to those with any of the tags", func() {
+			rows := sqlmock.NewRows([]string{"id", "name", "description", "name"}).
+				AddRow(0, "First RecipeResponse", "The First", "First RecipeResponse")
+
+			mock.ExpectQuery("^SELECT .+ FROM recipes WHERE creator=\\? "+
+				"AND id IN \\(SELECT rt.recipe_id FROM recipe_tags as rt JOIN tags as t on rt.tag_id=t.id "+
+				"WHERE t.user_id=\\? AND t.name IN \\(\\?, \\?\\) GROUP BY rt.recipe_id\\) ORDER BY .+$").
+				WithArgs(10, 10, "dessert", "quick", 21).
+				WillReturnRows(rows)
+
+			repo := repositories.NewRecipesRepository(db)
+			recipes, _, err := repo.List(ctx, 10, &repositories.RecipeFilter{
+				Tags: []string{"dessert", "quick"},
+			}, firstPage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recipes).To(HaveLen(1))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("filters the recipes to those with all of the tags", func() {
+			rows := sqlmock.NewRows([]string{"id", "name", "description", "name"})
+
+			mock.ExpectQuery("^SELECT .+ FROM recipes WHERE creator=\\? "+
+				"AND id IN \\(SELECT .+ GROUP BY rt.recipe_id HAVING COUNT\\(\\*\\)=\\?\\) ORDER BY .+$").
+				WithArgs(10, 10, "dessert", "quick", 2, 21).
+				WillReturnRows(rows)
+
+			repo := repositories.NewRecipesRepository(db)
+			recipes, _, err := repo.List(ctx, 10, &repositories.RecipeFilter{
+				Tags:         []string{"dessert", "quick"},
+				MatchAllTags: true,
+			}, firstPage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recipes).To(BeEmpty())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error for an unknown sort", func() {
+			repo := repositories.NewRecipesRepository(db)
+			_, _, err := repo.List(ctx, 10, nil, &repositories.RecipePage{Sort: "servings; DROP TABLE recipes", Limit: 20})
+			Expect(err).To(MatchError(ContainSubstring("unknown recipe sort")))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
 		It("returns an error if no recipes are found", func() {
-			mock.ExpectQuery("^SELECT .+ FROM recipes WHERE .+=?$").
-				WithArgs(20).
+			mock.ExpectQuery("^SELECT .+ FROM recipes WHERE .+$").
+				WithArgs(20, 21).
 				WillReturnError(sql.ErrNoRows)
 
 			repo := repositories.NewRecipesRepository(db)
-			_, err := repo.List(20)
+			_, _, err := repo.List(ctx, 20, nil, firstPage)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to fetch recipes"))
 		})
@@ -67,32 +198,57 @@ var _ = Describe("RecipeResponse Repository", func() {
 			rows := sqlmock.NewRows([]string{"not", "expected", "columns"}).
 				AddRow("bad", "values", "returned")
 
-			mock.ExpectQuery("^SELECT .+ FROM recipes WHERE .+=?$").
-				WithArgs(30).
+			mock.ExpectQuery("^SELECT .+ FROM recipes WHERE .+$").
+				WithArgs(30, 21).
 				WillReturnRows(rows)
 
 			repo := repositories.NewRecipesRepository(db)
-			_, err := repo.List(30)
+			_, _, err := repo.List(ctx, 30, nil, firstPage)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to scan recipes"))
 		})
 
 		It("returns an error if the rows cannot all be scanned", func() {
-			rows := sqlmock.NewRows([]string{"id", "name", "description"}).
-				AddRow(0, "First RecipeResponse", "The First").
+			rows := sqlmock.NewRows([]string{"id", "name", "description", "name"}).
+				AddRow(0, "First RecipeResponse", "The First", "First RecipeResponse").
 				RowError(0, errors.New("some error"))
 
-			mock.ExpectQuery("^SELECT .+ FROM recipes WHERE .+=?$").
-				WithArgs(40).
+			mock.ExpectQuery("^SELECT .+ FROM recipes WHERE .+$").
+				WithArgs(40, 21).
 				WillReturnRows(rows)
 
 			repo := repositories.NewRecipesRepository(db)
-			_, err := repo.List(40)
+			_, _, err := repo.List(ctx, 40, nil, firstPage)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to retrieve recipes"))
 		})
 	})
 
+	Describe("Count", func() {
+		It("counts the recipes matching the filter", func() {
+			mock.ExpectQuery("^SELECT COUNT\\(\\*\\) FROM recipes WHERE creator=\\? AND servings>=\\?$").
+				WithArgs(10, 4).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+
+			repo := repositories.NewRecipesRepository(db)
+			count, err := repo.Count(ctx, 10, &repositories.RecipeFilter{MinServings: IntPointer(4)})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(12))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the recipes cannot be counted", func() {
+			mock.ExpectQuery("^SELECT COUNT\\(\\*\\) FROM recipes WHERE creator=\\?$").
+				WithArgs(10).
+				WillReturnError(errors.New("some error"))
+
+			repo := repositories.NewRecipesRepository(db)
+			_, err := repo.Count(ctx, 10, nil)
+			Expect(err).To(MatchError("failed to count recipes"))
+		})
+	})
+
 	Describe("Get", func() {
 		It("returns a recipe by its id", func() {
 			rows := sqlmock.NewRows([]string{
@@ -124,7 +280,7 @@ var _ = Describe("RecipeResponse Repository", func() {
 				WillReturnRows(rows)
 
 			repo := repositories.NewRecipesRepository(db)
-			recipe, err := repo.Get(1, 2)
+			recipe, err := repo.Get(ctx, 1, 2)
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(recipe).To(Equal(&repositories.Recipe{
@@ -143,13 +299,29 @@ var _ = Describe("RecipeResponse Repository", func() {
 			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
 		})
 
+		It("aborts the query if the context is cancelled", func() {
+			mock.ExpectQuery("^SELECT .+ FROM recipes .+ WHERE .+=? AND .+=?$").
+				WithArgs(1, 2).
+				WillDelayFor(time.Minute).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+			ctx, cancel := context.WithCancel(ctx)
+			time.AfterFunc(10*time.Millisecond, cancel)
+
+			start := time.Now()
+			repo := repositories.NewRecipesRepository(db)
+			_, err := repo.Get(ctx, 1, 2)
+			Expect(err).To(HaveOccurred())
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		})
+
 		It("returns an error if the recipe cannot be found", func() {
 			mock.ExpectQuery("^SELECT .+ FROM recipes .+ WHERE .+=? AND .+=?$").
 				WithArgs(0, 0).
 				WillReturnError(sql.ErrNoRows)
 
 			repo := repositories.NewRecipesRepository(db)
-			_, err := repo.Get(0, 0)
+			_, err := repo.Get(ctx, 0, 0)
 			Expect(err).To(HaveOccurred())
 			Expect(err).To(MatchError(sql.ErrNoRows))
 		})
@@ -163,7 +335,7 @@ var _ = Describe("RecipeResponse Repository", func() {
 				WillReturnRows(rows)
 
 			repo := repositories.NewRecipesRepository(db)
-			_, err := repo.Get(0, 0)
+			_, err := repo.Get(ctx, 0, 0)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to retrieve recipe"))
 		})
@@ -183,11 +355,12 @@ var _ = Describe("RecipeResponse Repository", func() {
 					"2 m",
 					"3 m",
 					"1 hr 5 m",
+					65,
 					"some website",
 				).WillReturnResult(res)
 
 			repo := repositories.NewRecipesRepository(db)
-			id, err := repo.Insert(&repositories.Recipe{
+			id, err := repo.Insert(ctx, &repositories.Recipe{
 				Name:        StringPointer("RecipeResponse Name"),
 				Description: StringPointer("RecipeResponse Description"),
 				Servings:    IntPointer(3),
@@ -196,6 +369,8 @@ var _ = Describe("RecipeResponse Repository", func() {
 				CoolTime:    StringPointer("3 m"),
 				TotalTime:   StringPointer("1 hr 5 m"),
 				Source:      StringPointer("some website"),
+
+				TotalMinutes: IntPointer(65),
 			}, 1)
 			Expect(err).ToNot(HaveOccurred())
 
@@ -218,10 +393,11 @@ var _ = Describe("RecipeResponse Repository", func() {
 					nil,
 					nil,
 					nil,
+					nil,
 				).WillReturnResult(res)
 
 			repo := repositories.NewRecipesRepository(db)
-			id, err := repo.Insert(&repositories.Recipe{
+			id, err := repo.Insert(ctx, &repositories.Recipe{
 				Name:        StringPointer("RecipeResponse Name"),
 				Description: StringPointer("RecipeResponse Description"),
 				Servings:    IntPointer(3),
@@ -238,7 +414,7 @@ var _ = Describe("RecipeResponse Repository", func() {
 				WillReturnError(errors.New("constraint fails"))
 
 			repo := repositories.NewRecipesRepository(db)
-			_, err := repo.Insert(&repositories.Recipe{}, 1)
+			_, err := repo.Insert(ctx, &repositories.Recipe{}, 1)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("recipe could not be saved"))
 		})
@@ -250,9 +426,215 @@ var _ = Describe("RecipeResponse Repository", func() {
 				WillReturnResult(res)
 
 			repo := repositories.NewRecipesRepository(db)
-			_, err := repo.Insert(&repositories.Recipe{}, 1)
+			_, err := repo.Insert(ctx, &repositories.Recipe{}, 1)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("recipe was not saved correctly"))
 		})
 	})
+
+	Describe("Update", func() {
+		It("updates a recipe owned by the user", func() {
+			mock.ExpectExec("^UPDATE recipes SET .+ WHERE id=\\? AND creator=\\?").
+				WithArgs(
+					"RecipeResponse Name",
+					"RecipeResponse Description",
+					3,
+					"1 hr",
+					nil,
+					nil,
+					"1 hr",
+					60,
+					nil,
+					5,
+					1,
+				).WillReturnResult(sqlmock.NewResult(0, 1))
+
+			repo := repositories.NewRecipesRepository(db)
+			err := repo.Update(ctx, 5, &repositories.Recipe{
+				Name:        StringPointer("RecipeResponse Name"),
+				Description: StringPointer("RecipeResponse Description"),
+				Servings:    IntPointer(3),
+				PrepTime:    StringPointer("1 hr"),
+				TotalTime:   StringPointer("1 hr"),
+
+				TotalMinutes: IntPointer(60),
+			}, 1)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("succeeds when none of the recipe's values change", func() {
+			mock.ExpectExec("^UPDATE recipes SET").
+				WillReturnResult(sqlmock.NewResult(0, 0))
+
+			repo := repositories.NewRecipesRepository(db)
+			err := repo.Update(ctx, 5, &repositories.Recipe{}, 1)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the recipe cannot be updated", func() {
+			mock.ExpectExec("^UPDATE recipes SET").
+				WillReturnError(errors.New("constraint fails"))
+
+			repo := repositories.NewRecipesRepository(db)
+			err := repo.Update(ctx, 5, &repositories.Recipe{}, 1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("recipe could not be updated"))
+		})
+	})
+
+	Describe("GetCreator", func() {
+		It("returns the creator of a recipe", func() {
+			rows := sqlmock.NewRows([]string{"creator"}).AddRow(2)
+
+			mock.ExpectQuery("^SELECT creator FROM recipes WHERE id=\\?$").
+				WithArgs(1).
+				WillReturnRows(rows)
+
+			repo := repositories.NewRecipesRepository(db)
+			creator, err := repo.GetCreator(ctx, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(creator).To(BeEquivalentTo(2))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the recipe cannot be found", func() {
+			mock.ExpectQuery("^SELECT creator FROM recipes WHERE id=\\?$").
+				WithArgs(1).
+				WillReturnError(sql.ErrNoRows)
+
+			repo := repositories.NewRecipesRepository(db)
+			_, err := repo.GetCreator(ctx, 1)
+			Expect(err).To(MatchError(sql.ErrNoRows))
+		})
+
+		It("returns an error if the row cannot be scanned", func() {
+			rows := sqlmock.NewRows([]string{"creator"}).AddRow("not a number")
+
+			mock.ExpectQuery("^SELECT creator FROM recipes WHERE id=\\?$").
+				WithArgs(1).
+				WillReturnRows(rows)
+
+			repo := repositories.NewRecipesRepository(db)
+			_, err := repo.GetCreator(ctx, 1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to retrieve recipe creator"))
+		})
+	})
+
+	Describe("Delete", func() {
+		It("deletes a recipe owned by the user", func() {
+			mock.ExpectExec("^DELETE FROM recipes WHERE id=\\? AND creator=\\?$").
+				WithArgs(5, 1).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			repo := repositories.NewRecipesRepository(db)
+			err := repo.Delete(ctx, 5, 1)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("aborts the query if the context is cancelled", func() {
+			mock.ExpectExec("^DELETE FROM recipes WHERE id=\\? AND creator=\\?$").
+				WithArgs(5, 1).
+				WillDelayFor(time.Minute).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			ctx, cancel := context.WithCancel(ctx)
+			time.AfterFunc(10*time.Millisecond, cancel)
+
+			start := time.Now()
+			repo := repositories.NewRecipesRepository(db)
+			err := repo.Delete(ctx, 5, 1)
+			Expect(err).To(HaveOccurred())
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		})
+
+		It("returns no rows if the recipe does not exist for the user", func() {
+			mock.ExpectExec("^DELETE FROM recipes").
+				WillReturnResult(sqlmock.NewResult(0, 0))
+
+			repo := repositories.NewRecipesRepository(db)
+			err := repo.Delete(ctx, 5, 1)
+			Expect(err).To(MatchError(sql.ErrNoRows))
+		})
+
+		It("returns an error if the recipe cannot be deleted", func() {
+			mock.ExpectExec("^DELETE FROM recipes").
+				WillReturnError(errors.New("some error"))
+
+			repo := repositories.NewRecipesRepository(db)
+			err := repo.Delete(ctx, 5, 1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("recipe could not be deleted"))
+		})
+
+		It("returns an error if the result's RowsAffected fails", func() {
+			mock.ExpectExec("^DELETE FROM recipes").
+				WillReturnResult(sqlmock.NewErrorResult(errors.New("some error")))
+
+			repo := repositories.NewRecipesRepository(db)
+			err := repo.Delete(ctx, 5, 1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("recipe was not deleted correctly"))
+		})
+	})
+
+	Describe("ListMissingTotalMinutes", func() {
+		It("returns the recipes with a total time but no total minutes", func() {
+			rows := sqlmock.NewRows([]string{"id", "total_time"}).
+				AddRow(1, "5 m").
+				AddRow(2, "1 hr 30 mins")
+
+			mock.ExpectQuery("^SELECT id, total_time FROM recipes WHERE total_minutes IS NULL AND total_time IS NOT NULL$").
+				WillReturnRows(rows)
+
+			repo := repositories.NewRecipesRepository(db)
+			recipes, err := repo.ListMissingTotalMinutes(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recipes).To(Equal([]*repositories.RecipeTotalTime{
+				{ID: 1, TotalTime: "5 m"},
+				{ID: 2, TotalTime: "1 hr 30 mins"},
+			}))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the query fails", func() {
+			mock.ExpectQuery("^SELECT id, total_time FROM recipes").
+				WillReturnError(errors.New("some error"))
+
+			repo := repositories.NewRecipesRepository(db)
+			_, err := repo.ListMissingTotalMinutes(ctx)
+			Expect(err).To(MatchError("failed to list recipes missing total minutes"))
+		})
+	})
+
+	Describe("SetTotalMinutes", func() {
+		It("sets the recipe's total minutes", func() {
+			mock.ExpectExec("^UPDATE recipes SET total_minutes=\\? WHERE id=\\?$").
+				WithArgs(90, 2).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			repo := repositories.NewRecipesRepository(db)
+			err := repo.SetTotalMinutes(ctx, 2, 90)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the update fails", func() {
+			mock.ExpectExec("^UPDATE recipes SET total_minutes").
+				WillReturnError(errors.New("some error"))
+
+			repo := repositories.NewRecipesRepository(db)
+			err := repo.SetTotalMinutes(ctx, 2, 90)
+			Expect(err).To(MatchError("failed to set recipe total minutes"))
+		})
+	})
 })
@@ -1,8 +1,12 @@
 package repositories
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis"
@@ -10,6 +14,42 @@ import (
 	"github.com/iplay88keys/my-recipe-library/pkg/token"
 )
 
+var (
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrResetTokenNotFound = errors.New("password reset token not found")
+	ErrChallengeNotFound  = errors.New("login challenge not found")
+	ErrOIDCStateNotFound  = errors.New("oidc state not found")
+)
+
+// Session is a single login. It keeps the same ID while its tokens are
+// refreshed.
+type Session struct {
+	ID          string
+	UserID      int64
+	Device      string
+	IP          string
+	UserAgent   string
+	CreatedAt   time.Time
+	AccessUuid  string
+	RefreshUuid string
+}
+
+// OIDCState is what has to be remembered between sending a user to an
+// identity provider and them coming back. LinkUserID is set when a logged in
+// user is linking the identity rather than logging in with it.
+type OIDCState struct {
+	Nonce        string
+	CodeVerifier string
+	LinkUserID   int64
+}
+
+// LoginChallenge is a login waiting for a two factor code. Login is the name
+// the user logged in with, which wrong codes are throttled under.
+type LoginChallenge struct {
+	UserID int64
+	Login  string
+}
+
 type RedisRepository struct {
 	client redis.Cmdable
 }
@@ -18,31 +58,143 @@ func NewRedisRepository(client redis.Cmdable) *RedisRepository {
 	return &RedisRepository{client: client}
 }
 
-func (r *RedisRepository) StoreTokenDetails(userID int64, details *token.Details) error {
-	accessToken := time.Unix(details.AccessExpires, 0)
-	refreshToken := time.Unix(details.RefreshExpires, 0)
-	now := time.Now()
-
-	err := r.client.Set("access:"+details.AccessUuid, strconv.Itoa(int(userID)), accessToken.Sub(now)).Err()
+// CreateSession records a new login in the user's session index and stores
+// the tokens issued for it.
+func (r *RedisRepository) CreateSession(ctx context.Context, session *Session, details *token.Details) error {
+	err := r.client.HMSet("session:"+session.ID, map[string]interface{}{
+		"user_id":    session.UserID,
+		"device":     session.Device,
+		"ip":         session.IP,
+		"user_agent": session.UserAgent,
+		"created_at": session.CreatedAt.Unix(),
+	}).Err()
 	if err != nil {
 		return err
 	}
 
-	err = r.client.Set("refresh:"+details.RefreshUuid, strconv.Itoa(int(userID)), refreshToken.Sub(now)).Err()
+	err = r.client.SAdd(userSessionsKey(session.UserID), session.ID).Err()
 	if err != nil {
 		return err
 	}
 
-	// Store mapping from access UUID to refresh UUID for logout cleanup
-	err = r.client.Set("access_to_refresh:"+details.AccessUuid, details.RefreshUuid, accessToken.Sub(now)).Err()
+	return r.StoreTokenDetails(ctx, session.UserID, session.ID, details)
+}
+
+// storeTokenDetailsScript stores a session's tokens only if the session still
+// exists, in one step so a session revoked meanwhile is not brought back.
+//
+// KEYS: session, access, refresh, access_to_refresh, refresh_to_access,
+// access_to_session, refresh_to_session, user sessions
+// ARGV: user ID, access UUID, refresh UUID, session ID, access TTL and refresh
+// TTL in milliseconds
+var storeTokenDetailsScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+  return 0
+end
+
+redis.call("SET", KEYS[2], ARGV[1], "PX", ARGV[5])
+redis.call("SET", KEYS[3], ARGV[1], "PX", ARGV[6])
+redis.call("SET", KEYS[4], ARGV[3], "PX", ARGV[5])
+redis.call("SET", KEYS[5], ARGV[2], "PX", ARGV[6])
+redis.call("SET", KEYS[6], ARGV[4], "PX", ARGV[5])
+redis.call("SET", KEYS[7], ARGV[4], "PX", ARGV[6])
+redis.call("HSET", KEYS[1], "access_uuid", ARGV[2])
+redis.call("HSET", KEYS[1], "refresh_uuid", ARGV[3])
+
+-- A session lives as long as its latest refresh token
+redis.call("PEXPIRE", KEYS[1], ARGV[6])
+redis.call("PEXPIRE", KEYS[8], ARGV[6])
+
+return 1
+`)
+
+// StoreTokenDetails stores a token pair for an existing session. It returns
+// ErrSessionNotFound if the session has been revoked.
+func (r *RedisRepository) StoreTokenDetails(ctx context.Context, userID int64, sessionID string, details *token.Details) error {
+	now := time.Now()
+	accessTTL := time.Unix(details.AccessExpires, 0).Sub(now)
+	refreshTTL := time.Unix(details.RefreshExpires, 0).Sub(now)
+
+	stored, err := storeTokenDetailsScript.Run(r.client,
+		[]string{
+			"session:" + sessionID,
+			"access:" + details.AccessUuid,
+			"refresh:" + details.RefreshUuid,
+			"access_to_refresh:" + details.AccessUuid,
+			"refresh_to_access:" + details.RefreshUuid,
+			"access_to_session:" + details.AccessUuid,
+			"refresh_to_session:" + details.RefreshUuid,
+			userSessionsKey(userID),
+		},
+		userID,
+		details.AccessUuid,
+		details.RefreshUuid,
+		sessionID,
+		accessTTL.Milliseconds(),
+		refreshTTL.Milliseconds(),
+	).Int64()
 	if err != nil {
+		slog.ErrorContext(ctx, "Error storing token details", "error", err)
 		return err
 	}
 
+	if stored == 0 {
+		return ErrSessionNotFound
+	}
+
 	return nil
 }
 
-func (r *RedisRepository) RetrieveTokenDetails(details *token.AccessDetails) (int64, error) {
+// GetRefreshSession returns the ID of the session a refresh token belongs to.
+func (r *RedisRepository) GetRefreshSession(ctx context.Context, refreshUuid string) (string, error) {
+	sessionID, err := r.client.Get("refresh_to_session:" + refreshUuid).Result()
+	if err == redis.Nil {
+		return "", ErrSessionNotFound
+	}
+
+	return sessionID, err
+}
+
+// RevokeRefreshToken deletes a refresh token along with the access token it
+// was issued with. It returns false if the refresh token had already been
+// revoked, which means it is being reused. The refresh token still leads to
+// its session until it expires, so reusing it can end the session.
+func (r *RedisRepository) RevokeRefreshToken(ctx context.Context, refreshUuid string) (bool, error) {
+	deleted, err := r.client.Del("refresh:" + refreshUuid).Result()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error deleting refresh token", "error", err)
+		return false, err
+	}
+
+	if deleted == 0 {
+		return false, nil
+	}
+
+	accessUuid, err := r.client.Get("refresh_to_access:" + refreshUuid).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return true, nil
+		}
+
+		slog.ErrorContext(ctx, "Error retrieving access token for refresh token", "error", err)
+		return false, err
+	}
+
+	_, err = r.client.Del(
+		"access:"+accessUuid,
+		"access_to_refresh:"+accessUuid,
+		"access_to_session:"+accessUuid,
+		"refresh_to_access:"+refreshUuid,
+	).Result()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error deleting access token for refresh token", "error", err)
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *RedisRepository) RetrieveTokenDetails(ctx context.Context, details *token.AccessDetails) (int64, error) {
 	foundUserID, err := r.client.Get("access:" + details.AccessUuid).Result()
 	if err != nil {
 		return -1, err
@@ -56,14 +208,23 @@ func (r *RedisRepository) RetrieveTokenDetails(details *token.AccessDetails) (in
 	return userID, nil
 }
 
-func (r *RedisRepository) DeleteTokenDetails(uuid string) error {
+func (r *RedisRepository) DeleteTokenDetails(ctx context.Context, uuid string) error {
+	// Tokens issued for a session are removed along with the session
+	sessionID, err := r.client.Get("access_to_session:" + uuid).Result()
+	if err == nil {
+		err = r.RevokeSession(ctx, sessionID)
+		if err != ErrSessionNotFound {
+			return err
+		}
+	}
+
 	// Get the refresh UUID from the access UUID
 	refreshUuid, err := r.client.Get("access_to_refresh:" + uuid).Result()
 	if err != nil {
 		// If we can't find the refresh UUID, just delete the access token
 		_, err := r.client.Del("access:" + uuid).Result()
 		if err != nil {
-			fmt.Println("Error deleting access token:", err)
+			slog.ErrorContext(ctx, "Error deleting access token", "error", err)
 			return err
 		}
 
@@ -74,15 +235,16 @@ func (r *RedisRepository) DeleteTokenDetails(uuid string) error {
 	pipe := r.client.Pipeline()
 	pipe.Del("access:" + uuid)
 	pipe.Del("refresh:" + refreshUuid)
-	pipe.Del("access_to_refresh:" + uuid) // Clean up the mapping too
+	pipe.Del("access_to_refresh:" + uuid) // Clean up the mappings too
+	pipe.Del("refresh_to_access:" + refreshUuid)
 
 	results, err := pipe.Exec()
 	if err != nil {
-		fmt.Println("Error deleting tokens:", err)
+		slog.ErrorContext(ctx, "Error deleting tokens", "error", err)
 		return err
 	}
 
-	// Return the number of keys deleted (should be 3)
+	// Return the number of keys deleted (should be 4)
 	totalDeleted := int64(0)
 	for _, result := range results {
 		if delResult, ok := result.(*redis.IntCmd); ok {
@@ -92,3 +254,383 @@ func (r *RedisRepository) DeleteTokenDetails(uuid string) error {
 
 	return nil
 }
+
+func (r *RedisRepository) GetSession(ctx context.Context, sessionID string) (*Session, error) {
+	fields, err := r.client.HGetAll("session:" + sessionID).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fields) == 0 {
+		return nil, ErrSessionNotFound
+	}
+
+	userID, err := strconv.ParseInt(fields["user_id"], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt, err := strconv.ParseInt(fields["created_at"], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{
+		ID:          sessionID,
+		UserID:      userID,
+		Device:      fields["device"],
+		IP:          fields["ip"],
+		UserAgent:   fields["user_agent"],
+		CreatedAt:   time.Unix(createdAt, 0),
+		AccessUuid:  fields["access_uuid"],
+		RefreshUuid: fields["refresh_uuid"],
+	}, nil
+}
+
+// ListSessions returns the user's active sessions, newest first. Sessions
+// that have expired are removed from the index as they are found.
+func (r *RedisRepository) ListSessions(ctx context.Context, userID int64) ([]*Session, error) {
+	sessionIDs, err := r.client.SMembers(userSessionsKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*Session, 0, len(sessionIDs))
+	for _, sessionID := range sessionIDs {
+		session, err := r.GetSession(ctx, sessionID)
+		if err == ErrSessionNotFound {
+			r.client.SRem(userSessionsKey(userID), sessionID)
+			continue
+		}
+
+		if err != nil {
+			slog.ErrorContext(ctx, "Error retrieving session", "error", err)
+			return nil, err
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+	})
+
+	return sessions, nil
+}
+
+// RevokeSession deletes a session along with its current tokens, so the
+// access token stops working immediately.
+func (r *RedisRepository) RevokeSession(ctx context.Context, sessionID string) error {
+	session, err := r.GetSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	pipe := r.client.Pipeline()
+	pipe.Del(
+		"access:"+session.AccessUuid,
+		"access_to_refresh:"+session.AccessUuid,
+		"access_to_session:"+session.AccessUuid,
+		"refresh:"+session.RefreshUuid,
+		"refresh_to_access:"+session.RefreshUuid,
+		"refresh_to_session:"+session.RefreshUuid,
+		"session:"+sessionID,
+	)
+	pipe.SRem(userSessionsKey(session.UserID), sessionID)
+
+	_, err = pipe.Exec()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error revoking session", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *RedisRepository) RevokeAllSessions(ctx context.Context, userID int64) error {
+	sessionIDs, err := r.client.SMembers(userSessionsKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, sessionID := range sessionIDs {
+		err := r.RevokeSession(ctx, sessionID)
+		if err != nil && err != ErrSessionNotFound {
+			return err
+		}
+	}
+
+	return r.client.Del(userSessionsKey(userID)).Err()
+}
+
+// StorePasswordResetToken stores the user a password reset token belongs to
+// until it expires, and records it in the user's reset token index. Only a
+// hash of the token should be passed in so the token itself is never stored.
+func (r *RedisRepository) StorePasswordResetToken(ctx context.Context, tokenHash string, userID int64, ttl time.Duration) error {
+	pipe := r.client.Pipeline()
+	pipe.Set("password_reset:"+tokenHash, strconv.FormatInt(userID, 10), ttl)
+	pipe.SAdd(userPasswordResetsKey(userID), tokenHash)
+	// The index lives as long as the latest token
+	pipe.Expire(userPasswordResetsKey(userID), ttl)
+
+	_, err := pipe.Exec()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error storing password reset token", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// RevokePasswordResetTokens deletes every password reset token the user has
+// outstanding, so links from older reset emails stop working.
+func (r *RedisRepository) RevokePasswordResetTokens(ctx context.Context, userID int64) error {
+	tokenHashes, err := r.client.SMembers(userPasswordResetsKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := []string{userPasswordResetsKey(userID)}
+	for _, tokenHash := range tokenHashes {
+		keys = append(keys, "password_reset:"+tokenHash)
+	}
+
+	err = r.client.Del(keys...).Err()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error revoking password reset tokens", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// ConsumePasswordResetToken returns the user a password reset token belongs
+// to and deletes it. When the same token is consumed concurrently only one
+// caller succeeds, the others get ErrResetTokenNotFound.
+func (r *RedisRepository) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (int64, error) {
+	key := "password_reset:" + tokenHash
+
+	foundUserID, err := r.client.Get(key).Result()
+	if err == redis.Nil {
+		return -1, ErrResetTokenNotFound
+	}
+
+	if err != nil {
+		return -1, err
+	}
+
+	deleted, err := r.client.Del(key).Result()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error deleting password reset token", "error", err)
+		return -1, err
+	}
+
+	if deleted == 0 {
+		return -1, ErrResetTokenNotFound
+	}
+
+	return strconv.ParseInt(foundUserID, 10, 64)
+}
+
+// CountVerificationEmail records that a verification email is being sent to
+// an address and returns how many have been sent to it in the current window.
+// The window starts with the first email.
+func (r *RedisRepository) CountVerificationEmail(ctx context.Context, email string, window time.Duration) (int64, error) {
+	key := "verification_emails:" + strings.ToLower(email)
+
+	count, err := r.client.Incr(key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if count == 1 {
+		err = r.client.Expire(key, window).Err()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return count, nil
+}
+
+// StoreLoginChallenge stores who a two factor login challenge belongs to until
+// it expires. Like reset tokens, only a hash of the challenge is stored.
+func (r *RedisRepository) StoreLoginChallenge(ctx context.Context, challengeHash string, challenge *LoginChallenge, ttl time.Duration) error {
+	key := "login_challenge:" + challengeHash
+
+	err := r.client.HMSet(key, map[string]interface{}{
+		"user_id": challenge.UserID,
+		"login":   challenge.Login,
+	}).Err()
+	if err != nil {
+		return err
+	}
+
+	return r.client.Expire(key, ttl).Err()
+}
+
+func (r *RedisRepository) GetLoginChallenge(ctx context.Context, challengeHash string) (*LoginChallenge, error) {
+	fields, err := r.client.HGetAll("login_challenge:" + challengeHash).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fields) == 0 {
+		return nil, ErrChallengeNotFound
+	}
+
+	userID, err := strconv.ParseInt(fields["user_id"], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginChallenge{
+		UserID: userID,
+		Login:  fields["login"],
+	}, nil
+}
+
+// FailLoginChallenge counts a wrong code for a challenge and deletes the
+// challenge once maxAttempts wrong codes have been given.
+func (r *RedisRepository) FailLoginChallenge(ctx context.Context, challengeHash string, maxAttempts int64) error {
+	attemptsKey := "login_challenge_attempts:" + challengeHash
+
+	attempts, err := r.client.Incr(attemptsKey).Result()
+	if err != nil {
+		return err
+	}
+
+	if attempts == 1 {
+		ttl, err := r.client.TTL("login_challenge:" + challengeHash).Result()
+		if err != nil {
+			return err
+		}
+
+		if ttl > 0 {
+			r.client.Expire(attemptsKey, ttl)
+		}
+	}
+
+	if attempts < maxAttempts {
+		return nil
+	}
+
+	return r.client.Del("login_challenge:"+challengeHash, attemptsKey).Err()
+}
+
+// DeleteLoginChallenge removes a challenge once it has been passed. It returns
+// false if the challenge was already gone, so only one request can pass it.
+func (r *RedisRepository) DeleteLoginChallenge(ctx context.Context, challengeHash string) (bool, error) {
+	deleted, err := r.client.Del("login_challenge:" + challengeHash).Result()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error deleting login challenge", "error", err)
+		return false, err
+	}
+
+	r.client.Del("login_challenge_attempts:" + challengeHash)
+
+	return deleted == 1, nil
+}
+
+func (r *RedisRepository) StoreOIDCState(ctx context.Context, state string, oidcState *OIDCState, ttl time.Duration) error {
+	key := "oidc_state:" + state
+
+	err := r.client.HMSet(key, map[string]interface{}{
+		"nonce":         oidcState.Nonce,
+		"code_verifier": oidcState.CodeVerifier,
+		"link_user_id":  oidcState.LinkUserID,
+	}).Err()
+	if err != nil {
+		return err
+	}
+
+	return r.client.Expire(key, ttl).Err()
+}
+
+// ConsumeOIDCState returns and deletes the state for an authorization
+// request, so each request can only be completed once.
+func (r *RedisRepository) ConsumeOIDCState(ctx context.Context, state string) (*OIDCState, error) {
+	key := "oidc_state:" + state
+
+	fields, err := r.client.HGetAll(key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fields) == 0 {
+		return nil, ErrOIDCStateNotFound
+	}
+
+	deleted, err := r.client.Del(key).Result()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error deleting oidc state", "error", err)
+		return nil, err
+	}
+
+	if deleted == 0 {
+		return nil, ErrOIDCStateNotFound
+	}
+
+	linkUserID, err := strconv.ParseInt(fields["link_user_id"], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCState{
+		Nonce:        fields["nonce"],
+		CodeVerifier: fields["code_verifier"],
+		LinkUserID:   linkUserID,
+	}, nil
+}
+
+// RecordLoginFailure counts a failed login for a key, such as a login name or
+// an IP address, and returns how many there have been. The count is forgotten
+// once the key has gone a whole window without failing.
+func (r *RedisRepository) RecordLoginFailure(ctx context.Context, key string, window time.Duration) (int64, error) {
+	failuresKey := "login_failures:" + key
+
+	failures, err := r.client.Incr(failuresKey).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	err = r.client.Expire(failuresKey, window).Err()
+	if err != nil {
+		return 0, err
+	}
+
+	return failures, nil
+}
+
+func (r *RedisRepository) LockLogin(ctx context.Context, key string, duration time.Duration) error {
+	return r.client.Set("login_lock:"+key, "locked", duration).Err()
+}
+
+// LoginLockRemaining returns how much longer a key is locked for, or zero if
+// it is not locked.
+func (r *RedisRepository) LoginLockRemaining(ctx context.Context, key string) (time.Duration, error) {
+	remaining, err := r.client.TTL("login_lock:" + key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if remaining < 0 {
+		return 0, nil
+	}
+
+	return remaining, nil
+}
+
+// ClearLoginFailures forgets the failed logins for a key and lifts any lock
+// on it.
+func (r *RedisRepository) ClearLoginFailures(ctx context.Context, key string) error {
+	return r.client.Del("login_failures:"+key, "login_lock:"+key).Err()
+}
+
+func userSessionsKey(userID int64) string {
+	return "user_sessions:" + strconv.FormatInt(userID, 10)
+}
+
+func userPasswordResetsKey(userID int64) string {
+	return "user_password_resets:" + strconv.FormatInt(userID, 10)
+}
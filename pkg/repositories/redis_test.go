@@ -1,6 +1,7 @@
 package repositories_test
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -18,102 +19,94 @@ import (
 
 var _ = Describe("Redis Repository", func() {
 	var (
+		mr          *miniredis.Miniredis
+		client      *redis.Client
 		redisClient *redismock.ClientMock
+		ctx         context.Context
 	)
 
 	BeforeEach(func() {
-		mr, err := miniredis.Run()
+		ctx = context.Background()
+
+		var err error
+		mr, err = miniredis.Run()
 		if err != nil {
 			panic(err)
 		}
 
-		client := redis.NewClient(&redis.Options{
+		client = redis.NewClient(&redis.Options{
 			Addr: mr.Addr(),
 		})
 
 		redisClient = redismock.NewNiceMock(client)
 	})
 
-	Describe("StoreTokenDetails", func() {
-		It("stores token details", func() {
-			redisRepo := repositories.NewRedisRepository(redisClient)
-
-			accessExpiration := time.Now().Add(time.Minute).Unix()
-			refreshExpiration := time.Now().Add(time.Hour).Unix()
+	AfterEach(func() {
+		mr.Close()
+	})
 
-			redisClient.On("Set", "access:access uuid", "10", mock.AnythingOfType("time.Duration")).
-				Return(redis.NewStatusResult("", nil))
-			redisClient.On("Set", "refresh:refresh uuid", "10", mock.AnythingOfType("time.Duration")).
-				Return(redis.NewStatusResult("", nil))
-			redisClient.On("Set", "access_to_refresh:access uuid", "refresh uuid", mock.AnythingOfType("time.Duration")).
-				Return(redis.NewStatusResult("", nil))
+	Describe("StoreTokenDetails", func() {
+		var details *token.Details
 
-			details := &token.Details{
+		BeforeEach(func() {
+			details = &token.Details{
 				AccessToken:    "access token",
 				RefreshToken:   "refresh token",
 				AccessUuid:     "access uuid",
 				RefreshUuid:    "refresh uuid",
-				AccessExpires:  accessExpiration,
-				RefreshExpires: refreshExpiration,
+				AccessExpires:  time.Now().Add(time.Minute).Unix(),
+				RefreshExpires: time.Now().Add(time.Hour).Unix(),
 			}
-
-			err := redisRepo.StoreTokenDetails(10, details)
-			Expect(err).ToNot(HaveOccurred())
-
-			redisClient.AssertNumberOfCalls(GinkgoT(), "Set", 3)
 		})
 
-		It("returns an error if the access token set fails", func() {
+		It("stores token details", func() {
 			redisRepo := repositories.NewRedisRepository(redisClient)
 
-			accessExpiration := time.Now().Add(time.Minute).Unix()
-			refreshExpiration := time.Now().Add(time.Hour).Unix()
+			mr.HSet("session:session id", "user_id", "10")
+			_, err := mr.SetAdd("user_sessions:10", "session id")
+			Expect(err).ToNot(HaveOccurred())
 
-			redisClient.On("Set", "access:access uuid", "10", mock.AnythingOfType("time.Duration")).
-				Return(redis.NewStatusResult("", errors.New("some redis error")))
-			redisClient.On("Set", "refresh:refresh uuid", "10", mock.AnythingOfType("time.Duration")).
-				Return(redis.NewStatusResult("", nil))
-			redisClient.On("Set", "access_to_refresh:access uuid", "refresh uuid", mock.AnythingOfType("time.Duration")).
-				Return(redis.NewStatusResult("", nil))
+			err = redisRepo.StoreTokenDetails(ctx, 10, "session id", details)
+			Expect(err).ToNot(HaveOccurred())
 
-			details := &token.Details{
-				AccessToken:    "access token",
-				RefreshToken:   "refresh token",
-				AccessUuid:     "access uuid",
-				RefreshUuid:    "refresh uuid",
-				AccessExpires:  accessExpiration,
-				RefreshExpires: refreshExpiration,
+			for key, value := range map[string]string{
+				"access:access uuid":              "10",
+				"refresh:refresh uuid":            "10",
+				"access_to_refresh:access uuid":   "refresh uuid",
+				"refresh_to_access:refresh uuid":  "access uuid",
+				"access_to_session:access uuid":   "session id",
+				"refresh_to_session:refresh uuid": "session id",
+			} {
+				Expect(mr.Get(key)).To(Equal(value), key)
 			}
 
-			err := redisRepo.StoreTokenDetails(10, details)
-			Expect(err).To(HaveOccurred())
-			Expect(err).To(MatchError("some redis error"))
+			Expect(mr.TTL("access:access uuid")).To(BeNumerically("~", time.Minute, time.Second))
+			Expect(mr.TTL("refresh:refresh uuid")).To(BeNumerically("~", time.Hour, time.Second))
+
+			Expect(mr.HGet("session:session id", "access_uuid")).To(Equal("access uuid"))
+			Expect(mr.HGet("session:session id", "refresh_uuid")).To(Equal("refresh uuid"))
+			Expect(mr.TTL("session:session id")).To(BeNumerically("~", time.Hour, time.Second))
+			Expect(mr.TTL("user_sessions:10")).To(BeNumerically("~", time.Hour, time.Second))
 		})
 
-		It("returns an error if the refresh token set fails", func() {
+		It("returns an error if the session has been revoked", func() {
 			redisRepo := repositories.NewRedisRepository(redisClient)
 
-			accessExpiration := time.Now().Add(time.Minute).Unix()
-			refreshExpiration := time.Now().Add(time.Hour).Unix()
+			err := redisRepo.StoreTokenDetails(ctx, 10, "session id", details)
+			Expect(err).To(MatchError(repositories.ErrSessionNotFound))
 
-			redisClient.On("Set", "access:access uuid", "10", mock.AnythingOfType("time.Duration")).
-				Return(redis.NewStatusResult("", nil))
-			redisClient.On("Set", "refresh:refresh uuid", "10", mock.AnythingOfType("time.Duration")).
-				Return(redis.NewStatusResult("", errors.New("some redis error")))
-			redisClient.On("Set", "access_to_refresh:access uuid", "refresh uuid", mock.AnythingOfType("time.Duration")).
-				Return(redis.NewStatusResult("", nil))
+			Expect(mr.Exists("access:access uuid")).To(BeFalse())
+			Expect(mr.Exists("refresh:refresh uuid")).To(BeFalse())
+			Expect(mr.Exists("session:session id")).To(BeFalse())
+		})
 
-			details := &token.Details{
-				AccessToken:    "access token",
-				RefreshToken:   "refresh token",
-				AccessUuid:     "access uuid",
-				RefreshUuid:    "refresh uuid",
-				AccessExpires:  accessExpiration,
-				RefreshExpires: refreshExpiration,
-			}
+		It("returns an error if the tokens cannot be stored", func() {
+			redisRepo := repositories.NewRedisRepository(redisClient)
 
-			err := redisRepo.StoreTokenDetails(10, details)
-			Expect(err).To(HaveOccurred())
+			redisClient.On("EvalSha", mock.Anything, mock.Anything, mock.Anything).
+				Return(redis.NewCmdResult(nil, errors.New("some redis error")))
+
+			err := redisRepo.StoreTokenDetails(ctx, 10, "session id", details)
 			Expect(err).To(MatchError("some redis error"))
 		})
 	})
@@ -130,7 +123,7 @@ var _ = Describe("Redis Repository", func() {
 				UserId:     10,
 			}
 
-			userID, err := redisRepo.RetrieveTokenDetails(details)
+			userID, err := redisRepo.RetrieveTokenDetails(ctx, details)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(userID).To(Equal(details.UserId))
 
@@ -148,7 +141,7 @@ var _ = Describe("Redis Repository", func() {
 				UserId:     10,
 			}
 
-			_, err := redisRepo.RetrieveTokenDetails(details)
+			_, err := redisRepo.RetrieveTokenDetails(ctx, details)
 			Expect(err).To(HaveOccurred())
 			Expect(err).To(MatchError("some redis error"))
 		})
@@ -164,39 +157,583 @@ var _ = Describe("Redis Repository", func() {
 				UserId:     10,
 			}
 
-			_, err := redisRepo.RetrieveTokenDetails(details)
+			_, err := redisRepo.RetrieveTokenDetails(ctx, details)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("invalid syntax"))
 		})
 	})
 
+	Describe("RevokeRefreshToken", func() {
+		It("deletes the refresh token and the access token issued with it", func() {
+			redisRepo := repositories.NewRedisRepository(redisClient)
+
+			redisClient.On("Del", []string{"refresh:refresh uuid"}).
+				Return(redis.NewIntResult(1, nil))
+			redisClient.On("Get", "refresh_to_access:refresh uuid").
+				Return(redis.NewStringResult("access uuid", nil))
+			redisClient.On("Del", []string{
+				"access:access uuid",
+				"access_to_refresh:access uuid",
+				"access_to_session:access uuid",
+				"refresh_to_access:refresh uuid",
+			}).Return(redis.NewIntResult(4, nil))
+
+			revoked, err := redisRepo.RevokeRefreshToken(ctx, "refresh uuid")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(revoked).To(BeTrue())
+
+			redisClient.AssertNumberOfCalls(GinkgoT(), "Del", 2)
+		})
+
+		It("returns false if the refresh token was already revoked", func() {
+			redisRepo := repositories.NewRedisRepository(redisClient)
+
+			redisClient.On("Del", []string{"refresh:refresh uuid"}).
+				Return(redis.NewIntResult(0, nil))
+
+			revoked, err := redisRepo.RevokeRefreshToken(ctx, "refresh uuid")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(revoked).To(BeFalse())
+
+			redisClient.AssertNumberOfCalls(GinkgoT(), "Get", 0)
+		})
+
+		It("revokes the refresh token even if the access token mapping has expired", func() {
+			redisRepo := repositories.NewRedisRepository(redisClient)
+
+			redisClient.On("Del", []string{"refresh:refresh uuid"}).
+				Return(redis.NewIntResult(1, nil))
+			redisClient.On("Get", "refresh_to_access:refresh uuid").
+				Return(redis.NewStringResult("", redis.Nil))
+
+			revoked, err := redisRepo.RevokeRefreshToken(ctx, "refresh uuid")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(revoked).To(BeTrue())
+
+			redisClient.AssertNumberOfCalls(GinkgoT(), "Del", 1)
+		})
+
+		It("returns an error if the delete fails", func() {
+			redisRepo := repositories.NewRedisRepository(redisClient)
+
+			redisClient.On("Del", []string{"refresh:refresh uuid"}).
+				Return(redis.NewIntResult(0, errors.New("some redis error")))
+
+			_, err := redisRepo.RevokeRefreshToken(ctx, "refresh uuid")
+			Expect(err).To(MatchError("some redis error"))
+		})
+	})
+
 	Describe("DeleteTokenDetails", func() {
 		It("deletes access token details when refresh UUID not found", func() {
 			redisRepo := repositories.NewRedisRepository(redisClient)
 
+			redisClient.On("Get", "access_to_session:access uuid").
+				Return(redis.NewStringResult("", redis.Nil))
 			redisClient.On("Get", "access_to_refresh:access uuid").
 				Return(redis.NewStringResult("", errors.New("not found")))
 			redisClient.On("Del", []string{"access:access uuid"}).
 				Return(redis.NewIntResult(1, nil))
 
-			err := redisRepo.DeleteTokenDetails("access uuid")
+			err := redisRepo.DeleteTokenDetails(ctx, "access uuid")
 			Expect(err).ToNot(HaveOccurred())
 
-			redisClient.AssertNumberOfCalls(GinkgoT(), "Get", 1)
+			redisClient.AssertNumberOfCalls(GinkgoT(), "Get", 2)
 			redisClient.AssertNumberOfCalls(GinkgoT(), "Del", 1)
 		})
 
 		It("returns an error if the delete fails", func() {
 			redisRepo := repositories.NewRedisRepository(redisClient)
 
+			redisClient.On("Get", "access_to_session:access uuid").
+				Return(redis.NewStringResult("", redis.Nil))
 			redisClient.On("Get", "access_to_refresh:access uuid").
 				Return(redis.NewStringResult("", errors.New("not found")))
 			redisClient.On("Del", []string{"access:access uuid"}).
 				Return(redis.NewIntResult(1, errors.New("some redis error")))
 
-			err := redisRepo.DeleteTokenDetails("access uuid")
+			err := redisRepo.DeleteTokenDetails(ctx, "access uuid")
 			Expect(err).To(HaveOccurred())
 			Expect(err).To(MatchError("some redis error"))
 		})
 	})
+
+	Describe("sessions", func() {
+		var (
+			redisRepo *repositories.RedisRepository
+			createdAt time.Time
+		)
+
+		BeforeEach(func() {
+			redisRepo = repositories.NewRedisRepository(client)
+			createdAt = time.Unix(1700000000, 0)
+		})
+
+		tokenDetails := func(name string) *token.Details {
+			return &token.Details{
+				AccessUuid:     name + " access uuid",
+				RefreshUuid:    name + " refresh uuid",
+				AccessExpires:  time.Now().Add(time.Minute).Unix(),
+				RefreshExpires: time.Now().Add(time.Hour).Unix(),
+			}
+		}
+
+		createSession := func(id string, userID int64, createdAt time.Time) *token.Details {
+			details := tokenDetails(id)
+			err := redisRepo.CreateSession(ctx, &repositories.Session{
+				ID:        id,
+				UserID:    userID,
+				Device:    id + " device",
+				IP:        "127.0.0.1",
+				UserAgent: "Mozilla/5.0",
+				CreatedAt: createdAt,
+			}, details)
+			Expect(err).ToNot(HaveOccurred())
+
+			return details
+		}
+
+		It("creates a session with its tokens", func() {
+			details := createSession("session-1", 10, createdAt)
+
+			session, err := redisRepo.GetSession(ctx, "session-1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(session).To(Equal(&repositories.Session{
+				ID:          "session-1",
+				UserID:      10,
+				Device:      "session-1 device",
+				IP:          "127.0.0.1",
+				UserAgent:   "Mozilla/5.0",
+				CreatedAt:   createdAt,
+				AccessUuid:  details.AccessUuid,
+				RefreshUuid: details.RefreshUuid,
+			}))
+
+			userID, err := redisRepo.RetrieveTokenDetails(ctx, &token.AccessDetails{AccessUuid: details.AccessUuid})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(userID).To(Equal(int64(10)))
+
+			sessionID, err := redisRepo.GetRefreshSession(ctx, details.RefreshUuid)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sessionID).To(Equal("session-1"))
+		})
+
+		It("keeps the session when its tokens are refreshed", func() {
+			original := createSession("session-1", 10, createdAt)
+
+			revoked, err := redisRepo.RevokeRefreshToken(ctx, original.RefreshUuid)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(revoked).To(BeTrue())
+
+			refreshed := tokenDetails("refreshed")
+			err = redisRepo.StoreTokenDetails(ctx, 10, "session-1", refreshed)
+			Expect(err).ToNot(HaveOccurred())
+
+			session, err := redisRepo.GetSession(ctx, "session-1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(session.AccessUuid).To(Equal(refreshed.AccessUuid))
+			Expect(session.RefreshUuid).To(Equal(refreshed.RefreshUuid))
+			Expect(session.CreatedAt).To(Equal(createdAt))
+
+			sessionID, err := redisRepo.GetRefreshSession(ctx, original.RefreshUuid)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sessionID).To(Equal("session-1"))
+
+			revoked, err = redisRepo.RevokeRefreshToken(ctx, original.RefreshUuid)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(revoked).To(BeFalse())
+		})
+
+		It("lists the user's sessions newest first", func() {
+			createSession("older", 10, createdAt)
+			createSession("newer", 10, createdAt.Add(time.Hour))
+			createSession("other-user", 20, createdAt)
+
+			sessions, err := redisRepo.ListSessions(ctx, 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sessions).To(HaveLen(2))
+			Expect(sessions[0].ID).To(Equal("newer"))
+			Expect(sessions[1].ID).To(Equal("older"))
+		})
+
+		It("drops expired sessions from the index", func() {
+			createSession("session-1", 10, createdAt)
+			createSession("session-2", 10, createdAt)
+
+			mr.Del("session:session-1")
+
+			sessions, err := redisRepo.ListSessions(ctx, 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sessions).To(HaveLen(1))
+			Expect(sessions[0].ID).To(Equal("session-2"))
+
+			members, err := mr.Members("user_sessions:10")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(members).To(Equal([]string{"session-2"}))
+		})
+
+		It("revokes a session and its access token immediately", func() {
+			details := createSession("session-1", 10, createdAt)
+			other := createSession("session-2", 10, createdAt)
+
+			err := redisRepo.RevokeSession(ctx, "session-1")
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = redisRepo.RetrieveTokenDetails(ctx, &token.AccessDetails{AccessUuid: details.AccessUuid})
+			Expect(err).To(MatchError(redis.Nil))
+
+			Expect(mr.Exists("refresh:" + details.RefreshUuid)).To(BeFalse())
+			Expect(mr.Exists("session:session-1")).To(BeFalse())
+
+			_, err = redisRepo.RetrieveTokenDetails(ctx, &token.AccessDetails{AccessUuid: other.AccessUuid})
+			Expect(err).ToNot(HaveOccurred())
+
+			sessions, err := redisRepo.ListSessions(ctx, 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sessions).To(HaveLen(1))
+		})
+
+		It("returns not found when revoking a session that does not exist", func() {
+			err := redisRepo.RevokeSession(ctx, "missing")
+			Expect(err).To(MatchError(repositories.ErrSessionNotFound))
+		})
+
+		It("revokes every session for a user", func() {
+			first := createSession("session-1", 10, createdAt)
+			second := createSession("session-2", 10, createdAt)
+			other := createSession("session-3", 20, createdAt)
+
+			err := redisRepo.RevokeAllSessions(ctx, 10)
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, details := range []*token.Details{first, second} {
+				_, err = redisRepo.RetrieveTokenDetails(ctx, &token.AccessDetails{AccessUuid: details.AccessUuid})
+				Expect(err).To(HaveOccurred())
+			}
+
+			Expect(mr.Exists("user_sessions:10")).To(BeFalse())
+
+			_, err = redisRepo.RetrieveTokenDetails(ctx, &token.AccessDetails{AccessUuid: other.AccessUuid})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("removes the session when the user logs out", func() {
+			details := createSession("session-1", 10, createdAt)
+
+			err := redisRepo.DeleteTokenDetails(ctx, details.AccessUuid)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = redisRepo.GetSession(ctx, "session-1")
+			Expect(err).To(MatchError(repositories.ErrSessionNotFound))
+
+			Expect(mr.Exists("refresh:" + details.RefreshUuid)).To(BeFalse())
+		})
+	})
+
+	Describe("password reset tokens", func() {
+		var redisRepo *repositories.RedisRepository
+
+		BeforeEach(func() {
+			redisRepo = repositories.NewRedisRepository(client)
+		})
+
+		It("stores a token that expires", func() {
+			err := redisRepo.StorePasswordResetToken(ctx, "token hash", 10, time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mr.TTL("password_reset:token hash")).To(Equal(time.Hour))
+
+			mr.FastForward(time.Hour)
+
+			_, err = redisRepo.ConsumePasswordResetToken(ctx, "token hash")
+			Expect(err).To(MatchError(repositories.ErrResetTokenNotFound))
+		})
+
+		It("indexes the tokens by user until the latest expires", func() {
+			err := redisRepo.StorePasswordResetToken(ctx, "first hash", 10, time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = redisRepo.StorePasswordResetToken(ctx, "second hash", 10, time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+
+			members, err := mr.Members("user_password_resets:10")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(members).To(ConsistOf("first hash", "second hash"))
+			Expect(mr.TTL("user_password_resets:10")).To(Equal(time.Hour))
+		})
+
+		It("revokes every token for a user", func() {
+			err := redisRepo.StorePasswordResetToken(ctx, "first hash", 10, time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = redisRepo.StorePasswordResetToken(ctx, "second hash", 10, time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = redisRepo.StorePasswordResetToken(ctx, "other hash", 20, time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = redisRepo.RevokePasswordResetTokens(ctx, 10)
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, tokenHash := range []string{"first hash", "second hash"} {
+				_, err = redisRepo.ConsumePasswordResetToken(ctx, tokenHash)
+				Expect(err).To(MatchError(repositories.ErrResetTokenNotFound))
+			}
+
+			Expect(mr.Exists("user_password_resets:10")).To(BeFalse())
+
+			userID, err := redisRepo.ConsumePasswordResetToken(ctx, "other hash")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(userID).To(Equal(int64(20)))
+		})
+
+		It("revokes nothing for a user without tokens", func() {
+			err := redisRepo.RevokePasswordResetTokens(ctx, 10)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the user for a token only once", func() {
+			err := redisRepo.StorePasswordResetToken(ctx, "token hash", 10, time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+
+			userID, err := redisRepo.ConsumePasswordResetToken(ctx, "token hash")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(userID).To(Equal(int64(10)))
+
+			_, err = redisRepo.ConsumePasswordResetToken(ctx, "token hash")
+			Expect(err).To(MatchError(repositories.ErrResetTokenNotFound))
+		})
+
+		It("returns not found if the token was consumed by another request", func() {
+			redisClient.On("Get", "password_reset:token hash").
+				Return(redis.NewStringResult("10", nil))
+			redisClient.On("Del", []string{"password_reset:token hash"}).
+				Return(redis.NewIntResult(0, nil))
+
+			redisRepo := repositories.NewRedisRepository(redisClient)
+
+			_, err := redisRepo.ConsumePasswordResetToken(ctx, "token hash")
+			Expect(err).To(MatchError(repositories.ErrResetTokenNotFound))
+		})
+
+		It("returns an error if the lookup fails", func() {
+			redisClient.On("Get", "password_reset:token hash").
+				Return(redis.NewStringResult("", errors.New("redis error")))
+
+			redisRepo := repositories.NewRedisRepository(redisClient)
+
+			_, err := redisRepo.ConsumePasswordResetToken(ctx, "token hash")
+			Expect(err).To(MatchError("redis error"))
+		})
+	})
+
+	Describe("CountVerificationEmail", func() {
+		It("counts the emails sent to an address within the window", func() {
+			redisRepo := repositories.NewRedisRepository(client)
+
+			for i := int64(1); i <= 3; i++ {
+				count, err := redisRepo.CountVerificationEmail(ctx, "Cook@Example.com", time.Hour)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(count).To(Equal(i))
+			}
+
+			count, err := redisRepo.CountVerificationEmail(ctx, "other@example.com", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(int64(1)))
+
+			Expect(mr.TTL("verification_emails:cook@example.com")).To(Equal(time.Hour))
+
+			mr.FastForward(time.Hour)
+
+			count, err = redisRepo.CountVerificationEmail(ctx, "cook@example.com", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(int64(1)))
+		})
+
+		It("returns an error if the count fails", func() {
+			redisClient.On("Incr", "verification_emails:cook@example.com").
+				Return(redis.NewIntResult(0, errors.New("redis error")))
+
+			redisRepo := repositories.NewRedisRepository(redisClient)
+
+			_, err := redisRepo.CountVerificationEmail(ctx, "cook@example.com", time.Hour)
+			Expect(err).To(MatchError("redis error"))
+		})
+	})
+
+	Describe("login challenges", func() {
+		var redisRepo *repositories.RedisRepository
+
+		BeforeEach(func() {
+			redisRepo = repositories.NewRedisRepository(client)
+
+			err := redisRepo.StoreLoginChallenge(ctx, "challenge hash", &repositories.LoginChallenge{
+				UserID: 10,
+				Login:  "Some_User",
+			}, 5*time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns who a challenge belongs to until it expires", func() {
+			challenge, err := redisRepo.GetLoginChallenge(ctx, "challenge hash")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(challenge).To(Equal(&repositories.LoginChallenge{
+				UserID: 10,
+				Login:  "Some_User",
+			}))
+
+			mr.FastForward(5 * time.Minute)
+
+			_, err = redisRepo.GetLoginChallenge(ctx, "challenge hash")
+			Expect(err).To(MatchError(repositories.ErrChallengeNotFound))
+		})
+
+		It("can only be deleted once", func() {
+			deleted, err := redisRepo.DeleteLoginChallenge(ctx, "challenge hash")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deleted).To(BeTrue())
+
+			deleted, err = redisRepo.DeleteLoginChallenge(ctx, "challenge hash")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deleted).To(BeFalse())
+		})
+
+		It("deletes the challenge after too many wrong codes", func() {
+			for i := 0; i < 2; i++ {
+				err := redisRepo.FailLoginChallenge(ctx, "challenge hash", 3)
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			Expect(mr.TTL("login_challenge_attempts:challenge hash")).To(Equal(5 * time.Minute))
+
+			_, err := redisRepo.GetLoginChallenge(ctx, "challenge hash")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = redisRepo.FailLoginChallenge(ctx, "challenge hash", 3)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = redisRepo.GetLoginChallenge(ctx, "challenge hash")
+			Expect(err).To(MatchError(repositories.ErrChallengeNotFound))
+			Expect(mr.Exists("login_challenge_attempts:challenge hash")).To(BeFalse())
+		})
+	})
+
+	Describe("oidc state", func() {
+		It("returns the state for a request only once", func() {
+			redisRepo := repositories.NewRedisRepository(client)
+
+			err := redisRepo.StoreOIDCState(ctx, "state", &repositories.OIDCState{
+				Nonce:        "nonce",
+				CodeVerifier: "verifier",
+				LinkUserID:   10,
+			}, 10*time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mr.TTL("oidc_state:state")).To(Equal(10 * time.Minute))
+
+			oidcState, err := redisRepo.ConsumeOIDCState(ctx, "state")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(oidcState).To(Equal(&repositories.OIDCState{
+				Nonce:        "nonce",
+				CodeVerifier: "verifier",
+				LinkUserID:   10,
+			}))
+
+			_, err = redisRepo.ConsumeOIDCState(ctx, "state")
+			Expect(err).To(MatchError(repositories.ErrOIDCStateNotFound))
+		})
+
+		It("returns not found once the state expires", func() {
+			redisRepo := repositories.NewRedisRepository(client)
+
+			err := redisRepo.StoreOIDCState(ctx, "state", &repositories.OIDCState{Nonce: "nonce"}, 10*time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			mr.FastForward(10 * time.Minute)
+
+			_, err = redisRepo.ConsumeOIDCState(ctx, "state")
+			Expect(err).To(MatchError(repositories.ErrOIDCStateNotFound))
+		})
+
+		It("returns not found if the state was consumed by another request", func() {
+			redisClient.On("HGetAll", "oidc_state:state").
+				Return(redis.NewStringStringMapResult(map[string]string{"nonce": "nonce", "link_user_id": "0"}, nil))
+			redisClient.On("Del", []string{"oidc_state:state"}).
+				Return(redis.NewIntResult(0, nil))
+
+			redisRepo := repositories.NewRedisRepository(redisClient)
+
+			_, err := redisRepo.ConsumeOIDCState(ctx, "state")
+			Expect(err).To(MatchError(repositories.ErrOIDCStateNotFound))
+		})
+	})
+
+	Describe("login failures", func() {
+		It("counts failures until a whole window passes without one", func() {
+			redisRepo := repositories.NewRedisRepository(client)
+
+			for i := int64(1); i <= 3; i++ {
+				failures, err := redisRepo.RecordLoginFailure(ctx, "login:cook", time.Hour)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(failures).To(Equal(i))
+
+				mr.FastForward(30 * time.Minute)
+			}
+
+			Expect(mr.TTL("login_failures:login:cook")).To(Equal(30 * time.Minute))
+
+			mr.FastForward(30 * time.Minute)
+
+			failures, err := redisRepo.RecordLoginFailure(ctx, "login:cook", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(failures).To(Equal(int64(1)))
+		})
+
+		It("returns how long a key is locked for", func() {
+			redisRepo := repositories.NewRedisRepository(client)
+
+			remaining, err := redisRepo.LoginLockRemaining(ctx, "ip:192.0.2.10")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(remaining).To(BeZero())
+
+			err = redisRepo.LockLogin(ctx, "ip:192.0.2.10", time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			remaining, err = redisRepo.LoginLockRemaining(ctx, "ip:192.0.2.10")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(remaining).To(Equal(time.Minute))
+
+			mr.FastForward(time.Minute)
+
+			remaining, err = redisRepo.LoginLockRemaining(ctx, "ip:192.0.2.10")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(remaining).To(BeZero())
+		})
+
+		It("clears the failures and the lock for a key", func() {
+			redisRepo := repositories.NewRedisRepository(client)
+
+			_, err := redisRepo.RecordLoginFailure(ctx, "login:cook", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = redisRepo.LockLogin(ctx, "login:cook", time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = redisRepo.ClearLoginFailures(ctx, "login:cook")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mr.Exists("login_failures:login:cook")).To(BeFalse())
+			Expect(mr.Exists("login_lock:login:cook")).To(BeFalse())
+		})
+
+		It("returns an error if the count fails", func() {
+			redisClient.On("Incr", "login_failures:login:cook").
+				Return(redis.NewIntResult(0, errors.New("redis error")))
+
+			redisRepo := repositories.NewRedisRepository(redisClient)
+
+			_, err := redisRepo.RecordLoginFailure(ctx, "login:cook", time.Hour)
+			Expect(err).To(MatchError("redis error"))
+		})
+	})
 })
@@ -1,9 +1,11 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
 )
 
 type Step struct {
@@ -19,8 +21,8 @@ func NewStepsRepository(db *sql.DB) *StepsRepository {
 	return &StepsRepository{db: db}
 }
 
-func (r *StepsRepository) GetForRecipe(recipeID int64) ([]*Step, error) {
-	rows, err := r.db.Query(getStepsForRecipeQuery, recipeID)
+func (r *StepsRepository) GetForRecipe(ctx context.Context, recipeID int64) ([]*Step, error) {
+	rows, err := querier(ctx, r.db).QueryContext(ctx, getStepsForRecipeQuery, recipeID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch recipe steps: %s", err.Error())
 	}
@@ -41,24 +43,36 @@ func (r *StepsRepository) GetForRecipe(recipeID int64) ([]*Step, error) {
 	return recipeSteps, nil
 }
 
-func (r *StepsRepository) Insert(recipeID int64, step *Step) error {
-	_, err := r.db.Exec(insertRecipeStepQuery,
+func (r *StepsRepository) Insert(ctx context.Context, recipeID int64, step *Step) error {
+	_, err := querier(ctx, r.db).ExecContext(ctx, insertRecipeStepQuery,
 		recipeID,
 		step.StepNumber,
 		step.Instructions,
 	)
 
 	if err != nil {
-		fmt.Printf("Recipe step could not be saved: %s\n", err.Error())
+		slog.ErrorContext(ctx, "Recipe step could not be saved", "error", err)
 		return errors.New("recipe step could not be saved")
 	}
 
 	return nil
 }
 
+func (r *StepsRepository) DeleteForRecipe(ctx context.Context, recipeID int64) error {
+	_, err := querier(ctx, r.db).ExecContext(ctx, deleteRecipeStepsQuery, recipeID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Recipe steps could not be deleted", "error", err)
+		return errors.New("recipe steps could not be deleted")
+	}
+
+	return nil
+}
+
 const getStepsForRecipeQuery = `SELECT step_no, instructions FROM recipe_steps WHERE recipe_id=?`
 
 const insertRecipeStepQuery = `
   INSERT INTO recipe_steps (recipe_id, step_no, instructions)
   VALUES (?, ?, ?)
 `
+
+const deleteRecipeStepsQuery = `DELETE FROM recipe_steps WHERE recipe_id=?`
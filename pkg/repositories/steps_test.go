@@ -1,8 +1,10 @@
 package repositories_test
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 
@@ -17,9 +19,12 @@ var _ = Describe("Steps Repository", func() {
 	var (
 		db   *sql.DB
 		mock sqlmock.Sqlmock
+		ctx  context.Context
 	)
 
 	BeforeEach(func() {
+		ctx = context.Background()
+
 		var err error
 		db, mock, err = sqlmock.New()
 		Expect(err).ToNot(HaveOccurred())
@@ -36,7 +41,7 @@ var _ = Describe("Steps Repository", func() {
 				WillReturnRows(rows)
 
 			repo := repositories.NewStepsRepository(db)
-			recipes, err := repo.GetForRecipe(1)
+			recipes, err := repo.GetForRecipe(ctx, 1)
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(recipes).To(Equal([]*repositories.Step{{
@@ -50,13 +55,29 @@ var _ = Describe("Steps Repository", func() {
 			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
 		})
 
+		It("aborts the query if the context is cancelled", func() {
+			mock.ExpectQuery("^SELECT .+ FROM recipe_steps WHERE recipe_id=?").
+				WithArgs(1).
+				WillDelayFor(time.Minute).
+				WillReturnRows(sqlmock.NewRows([]string{"step_number"}))
+
+			ctx, cancel := context.WithCancel(ctx)
+			time.AfterFunc(10*time.Millisecond, cancel)
+
+			start := time.Now()
+			repo := repositories.NewStepsRepository(db)
+			_, err := repo.GetForRecipe(ctx, 1)
+			Expect(err).To(HaveOccurred())
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		})
+
 		It("returns an error if the query fails", func() {
 			mock.ExpectQuery("^SELECT .+ FROM recipe_steps WHERE recipe_id=?").
 				WithArgs(1).
 				WillReturnError(errors.New("error"))
 
 			repo := repositories.NewStepsRepository(db)
-			_, err := repo.GetForRecipe(1)
+			_, err := repo.GetForRecipe(ctx, 1)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to fetch recipe steps"))
 		})
@@ -70,7 +91,7 @@ var _ = Describe("Steps Repository", func() {
 				WillReturnRows(rows)
 
 			repo := repositories.NewStepsRepository(db)
-			_, err := repo.GetForRecipe(1)
+			_, err := repo.GetForRecipe(ctx, 1)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to scan recipe steps"))
 		})
@@ -85,9 +106,34 @@ var _ = Describe("Steps Repository", func() {
 				WillReturnRows(rows)
 
 			repo := repositories.NewStepsRepository(db)
-			_, err := repo.GetForRecipe(1)
+			_, err := repo.GetForRecipe(ctx, 1)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to loop through recipe steps"))
 		})
 	})
+
+	Describe("DeleteForRecipe", func() {
+		It("deletes the steps for a recipe", func() {
+			mock.ExpectExec("^DELETE FROM recipe_steps WHERE recipe_id=?").
+				WithArgs(1).
+				WillReturnResult(sqlmock.NewResult(0, 2))
+
+			repo := repositories.NewStepsRepository(db)
+			err := repo.DeleteForRecipe(ctx, 1)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the delete fails", func() {
+			mock.ExpectExec("^DELETE FROM recipe_steps WHERE recipe_id=?").
+				WithArgs(1).
+				WillReturnError(errors.New("error"))
+
+			repo := repositories.NewStepsRepository(db)
+			err := repo.DeleteForRecipe(ctx, 1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("recipe steps could not be deleted"))
+		})
+	})
 })
@@ -0,0 +1,153 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// Tag is one of a user's tags and how many of their recipes have it.
+type Tag struct {
+	ID          int64
+	Name        string
+	RecipeCount int
+}
+
+type TagsRepository struct {
+	db *sql.DB
+}
+
+func NewTagsRepository(db *sql.DB) *TagsRepository {
+	return &TagsRepository{db: db}
+}
+
+// List returns the user's tags with the number of recipes that have each one,
+// ordered by name.
+func (r *TagsRepository) List(ctx context.Context, userID int64) ([]*Tag, error) {
+	rows, err := querier(ctx, r.db).QueryContext(ctx, listTagsQuery, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to fetch tags", "error", err)
+		return nil, errors.New("failed to fetch tags")
+	}
+	defer rows.Close()
+
+	var tags []*Tag
+	for rows.Next() {
+		t := &Tag{}
+		if err := rows.Scan(&t.ID, &t.Name, &t.RecipeCount); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan tags", "error", err)
+			return nil, errors.New("failed to scan tags")
+		}
+		tags = append(tags, t)
+	}
+	if rows.Err() != nil {
+		slog.ErrorContext(ctx, "Failed to loop through tags", "error", rows.Err())
+		return nil, errors.New("failed to retrieve tags")
+	}
+
+	return tags, nil
+}
+
+// GetForRecipe returns the names of a recipe's tags, ordered by name.
+func (r *TagsRepository) GetForRecipe(ctx context.Context, recipeID int64) ([]string, error) {
+	rows, err := querier(ctx, r.db).QueryContext(ctx, getTagsForRecipeQuery, recipeID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to fetch recipe tags", "recipe_id", recipeID, "error", err)
+		return nil, errors.New("failed to fetch recipe tags")
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan recipe tags", "recipe_id", recipeID, "error", err)
+			return nil, errors.New("failed to scan recipe tags")
+		}
+		names = append(names, name)
+	}
+	if rows.Err() != nil {
+		slog.ErrorContext(ctx, "Failed to loop through recipe tags", "recipe_id", recipeID, "error", rows.Err())
+		return nil, errors.New("failed to retrieve recipe tags")
+	}
+
+	return names, nil
+}
+
+// SetForRecipe replaces a recipe's tags with the named tags of the user,
+// creating any the user does not have yet. The names are expected to be
+// normalized with NormalizeName.
+func (r *TagsRepository) SetForRecipe(ctx context.Context, recipeID, userID int64, names []string) error {
+	_, err := querier(ctx, r.db).ExecContext(ctx, deleteRecipeTagsQuery, recipeID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Recipe tags could not be deleted", "recipe_id", recipeID, "error", err)
+		return errors.New("recipe tags could not be deleted")
+	}
+
+	for _, name := range names {
+		res, err := querier(ctx, r.db).ExecContext(ctx, insertTagQuery, userID, name)
+		if err != nil {
+			slog.ErrorContext(ctx, "Tag could not be saved", "error", err)
+			return errors.New("tag could not be saved")
+		}
+
+		tagID, err := res.LastInsertId()
+		if err != nil {
+			slog.ErrorContext(ctx, "Tag was not saved correctly", "error", err)
+			return fmt.Errorf("tag was not saved correctly: %s", err.Error())
+		}
+
+		_, err = querier(ctx, r.db).ExecContext(ctx, insertRecipeTagQuery, recipeID, tagID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Recipe tag could not be saved", "recipe_id", recipeID, "error", err)
+			return errors.New("recipe tag could not be saved")
+		}
+	}
+
+	return nil
+}
+
+// DeleteUnused removes the user's tags that no recipe has any more.
+func (r *TagsRepository) DeleteUnused(ctx context.Context, userID int64) error {
+	_, err := querier(ctx, r.db).ExecContext(ctx, deleteUnusedTagsQuery, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Unused tags could not be deleted", "error", err)
+		return errors.New("unused tags could not be deleted")
+	}
+
+	return nil
+}
+
+const listTagsQuery = `
+  SELECT t.id,
+         t.name,
+         COUNT(rt.recipe_id)
+  FROM tags as t
+  LEFT JOIN recipe_tags as rt on rt.tag_id=t.id
+  WHERE t.user_id=?
+  GROUP BY t.id, t.name
+  ORDER BY t.name
+`
+const getTagsForRecipeQuery = `
+  SELECT t.name
+  FROM recipe_tags as rt
+  JOIN tags as t on rt.tag_id=t.id
+  WHERE rt.recipe_id=?
+  ORDER BY t.name
+`
+
+// LAST_INSERT_ID(id) makes LastInsertId return the existing tag's ID when the
+// user already has a tag with the name.
+const insertTagQuery = `
+  INSERT INTO tags (user_id, name) VALUES (?, ?)
+  ON DUPLICATE KEY UPDATE id=LAST_INSERT_ID(id)
+`
+const insertRecipeTagQuery = "INSERT IGNORE INTO recipe_tags (recipe_id, tag_id) VALUES (?, ?)"
+const deleteRecipeTagsQuery = "DELETE FROM recipe_tags WHERE recipe_id=?"
+const deleteUnusedTagsQuery = `
+  DELETE t FROM tags as t
+  LEFT JOIN recipe_tags as rt on rt.tag_id=t.id
+  WHERE t.user_id=? AND rt.tag_id IS NULL
+`
@@ -0,0 +1,193 @@
+package repositories_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tags Repository", func() {
+	var (
+		db   *sql.DB
+		mock sqlmock.Sqlmock
+		ctx  context.Context
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		var err error
+		db, mock, err = sqlmock.New()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("List", func() {
+		It("returns the user's tags with their recipe counts", func() {
+			rows := sqlmock.NewRows([]string{"id", "name", "count"}).
+				AddRow(2, "dessert", 3).
+				AddRow(1, "quick", 1)
+
+			mock.ExpectQuery(`^SELECT .* FROM tags as t .* WHERE t.user_id=\? GROUP BY t.id, t.name ORDER BY t.name$`).
+				WithArgs(10).
+				WillReturnRows(rows)
+
+			repo := repositories.NewTagsRepository(db)
+			tags, err := repo.List(ctx, 10)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(tags).To(Equal([]*repositories.Tag{
+				{ID: 2, Name: "dessert", RecipeCount: 3},
+				{ID: 1, Name: "quick", RecipeCount: 1},
+			}))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the query fails", func() {
+			mock.ExpectQuery("^SELECT .* FROM tags").
+				WithArgs(10).
+				WillReturnError(errors.New("some error"))
+
+			repo := repositories.NewTagsRepository(db)
+			_, err := repo.List(ctx, 10)
+			Expect(err).To(MatchError("failed to fetch tags"))
+		})
+
+		It("returns an error if the row cannot be scanned", func() {
+			rows := sqlmock.NewRows([]string{"id"}).AddRow(1)
+
+			mock.ExpectQuery("^SELECT .* FROM tags").
+				WithArgs(10).
+				WillReturnRows(rows)
+
+			repo := repositories.NewTagsRepository(db)
+			_, err := repo.List(ctx, 10)
+			Expect(err).To(MatchError("failed to scan tags"))
+		})
+	})
+
+	Describe("GetForRecipe", func() {
+		It("returns the names of the recipe's tags", func() {
+			rows := sqlmock.NewRows([]string{"name"}).
+				AddRow("dessert").
+				AddRow("quick")
+
+			mock.ExpectQuery(`^SELECT t.name FROM recipe_tags as rt .* WHERE rt.recipe_id=\? ORDER BY t.name$`).
+				WithArgs(1).
+				WillReturnRows(rows)
+
+			repo := repositories.NewTagsRepository(db)
+			names, err := repo.GetForRecipe(ctx, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names).To(Equal([]string{"dessert", "quick"}))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the query fails", func() {
+			mock.ExpectQuery("^SELECT t.name FROM recipe_tags").
+				WithArgs(1).
+				WillReturnError(errors.New("some error"))
+
+			repo := repositories.NewTagsRepository(db)
+			_, err := repo.GetForRecipe(ctx, 1)
+			Expect(err).To(MatchError(ContainSubstring("failed to fetch recipe tags")))
+		})
+	})
+
+	Describe("SetForRecipe", func() {
+		It("replaces the recipe's tags, reusing the user's existing tags", func() {
+			mock.ExpectExec(`^DELETE FROM recipe_tags WHERE recipe_id=\?$`).
+				WithArgs(1).
+				WillReturnResult(sqlmock.NewResult(0, 2))
+			mock.ExpectExec(`^INSERT INTO tags \(user_id, name\) VALUES \(\?, \?\) ON DUPLICATE KEY UPDATE id=LAST_INSERT_ID\(id\)$`).
+				WithArgs(10, "dessert").
+				WillReturnResult(sqlmock.NewResult(4, 0))
+			mock.ExpectExec(`^INSERT IGNORE INTO recipe_tags \(recipe_id, tag_id\) VALUES \(\?, \?\)$`).
+				WithArgs(1, 4).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec("^INSERT INTO tags").
+				WithArgs(10, "quick").
+				WillReturnResult(sqlmock.NewResult(5, 1))
+			mock.ExpectExec("^INSERT IGNORE INTO recipe_tags").
+				WithArgs(1, 5).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			repo := repositories.NewTagsRepository(db)
+			err := repo.SetForRecipe(ctx, 1, 10, []string{"dessert", "quick"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the old tags cannot be removed", func() {
+			mock.ExpectExec("^DELETE FROM recipe_tags").
+				WithArgs(1).
+				WillReturnError(errors.New("some error"))
+
+			repo := repositories.NewTagsRepository(db)
+			err := repo.SetForRecipe(ctx, 1, 10, []string{"dessert"})
+			Expect(err).To(MatchError("recipe tags could not be deleted"))
+		})
+
+		It("returns an error if a tag cannot be saved", func() {
+			mock.ExpectExec("^DELETE FROM recipe_tags").
+				WithArgs(1).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("^INSERT INTO tags").
+				WithArgs(10, "dessert").
+				WillReturnError(errors.New("some error"))
+
+			repo := repositories.NewTagsRepository(db)
+			err := repo.SetForRecipe(ctx, 1, 10, []string{"dessert"})
+			Expect(err).To(MatchError("tag could not be saved"))
+		})
+
+		It("returns an error if the tag cannot be added to the recipe", func() {
+			mock.ExpectExec("^DELETE FROM recipe_tags").
+				WithArgs(1).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("^INSERT INTO tags").
+				WithArgs(10, "dessert").
+				WillReturnResult(sqlmock.NewResult(4, 1))
+			mock.ExpectExec("^INSERT IGNORE INTO recipe_tags").
+				WithArgs(1, 4).
+				WillReturnError(errors.New("some error"))
+
+			repo := repositories.NewTagsRepository(db)
+			err := repo.SetForRecipe(ctx, 1, 10, []string{"dessert"})
+			Expect(err).To(MatchError("recipe tag could not be saved"))
+		})
+	})
+
+	Describe("DeleteUnused", func() {
+		It("deletes the user's tags no recipe has", func() {
+			mock.ExpectExec(`DELETE t FROM tags as t .* WHERE t.user_id=\? AND rt.tag_id IS NULL`).
+				WithArgs(10).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			repo := repositories.NewTagsRepository(db)
+			err := repo.DeleteUnused(ctx, 10)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the tags cannot be deleted", func() {
+			mock.ExpectExec("DELETE t FROM tags").
+				WithArgs(10).
+				WillReturnError(errors.New("some error"))
+
+			repo := repositories.NewTagsRepository(db)
+			err := repo.DeleteUnused(ctx, 10)
+			Expect(err).To(MatchError("unused tags could not be deleted"))
+		})
+	})
+})
@@ -0,0 +1,144 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+)
+
+type TwoFactor struct {
+	UserID       int64
+	Secret       string
+	Enabled      bool
+	LastUsedStep int64
+}
+
+type TwoFactorRepository struct {
+	db *sql.DB
+}
+
+func NewTwoFactorRepository(db *sql.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+// Get returns the user's TOTP settings, or sql.ErrNoRows if they have never
+// enrolled.
+func (t *TwoFactorRepository) Get(ctx context.Context, userID int64) (*TwoFactor, error) {
+	row := t.db.QueryRowContext(ctx, getTwoFactorQuery, userID)
+
+	twoFactor := &TwoFactor{}
+	err := row.Scan(&twoFactor.UserID, &twoFactor.Secret, &twoFactor.Enabled, &twoFactor.LastUsedStep)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+
+		slog.ErrorContext(ctx, "Failed to scan two factor settings", "error", err)
+		return nil, errors.New("failed to retrieve two factor settings")
+	}
+
+	return twoFactor, nil
+}
+
+// SavePending stores a new secret that is not enabled until it is confirmed,
+// replacing any earlier unconfirmed one.
+func (t *TwoFactorRepository) SavePending(ctx context.Context, userID int64, secret string) error {
+	_, err := t.db.ExecContext(ctx, savePendingTwoFactorQuery, userID, secret)
+	if err != nil {
+		slog.ErrorContext(ctx, "Two factor secret could not be saved", "error", err)
+		return errors.New("two factor secret could not be saved")
+	}
+
+	return nil
+}
+
+// Enable turns on two factor authentication with the step of the code that
+// confirmed it and replaces the user's recovery codes.
+func (t *TwoFactorRepository) Enable(ctx context.Context, userID, step int64, recoveryCodeHashes []string) (err error) {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to start transaction", "error", err)
+		return errors.New("two factor could not be enabled")
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	_, err = tx.ExecContext(ctx, enableTwoFactorQuery, step, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Two factor could not be enabled", "error", err)
+		return errors.New("two factor could not be enabled")
+	}
+
+	_, err = tx.ExecContext(ctx, deleteRecoveryCodesQuery, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Recovery codes could not be deleted", "error", err)
+		return errors.New("two factor could not be enabled")
+	}
+
+	for _, codeHash := range recoveryCodeHashes {
+		_, err = tx.ExecContext(ctx, insertRecoveryCodeQuery, userID, codeHash)
+		if err != nil {
+			slog.ErrorContext(ctx, "Recovery code could not be saved", "error", err)
+			return errors.New("two factor could not be enabled")
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to commit transaction", "error", err)
+		return errors.New("two factor could not be enabled")
+	}
+
+	return nil
+}
+
+// UseStep records that a code from the step has been accepted. It returns
+// false if the step, or a later one, has already been used.
+func (t *TwoFactorRepository) UseStep(ctx context.Context, userID, step int64) (bool, error) {
+	res, err := t.db.ExecContext(ctx, useTwoFactorStepQuery, step, userID, step)
+	if err != nil {
+		slog.ErrorContext(ctx, "Two factor step could not be saved", "error", err)
+		return false, errors.New("two factor step could not be saved")
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		slog.ErrorContext(ctx, "Two factor step was not saved correctly", "error", err)
+		return false, errors.New("two factor step was not saved correctly")
+	}
+
+	return affected == 1, nil
+}
+
+// UseRecoveryCode deletes a recovery code. It returns false if the user has
+// no such code.
+func (t *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	res, err := t.db.ExecContext(ctx, useRecoveryCodeQuery, userID, codeHash)
+	if err != nil {
+		slog.ErrorContext(ctx, "Recovery code could not be used", "error", err)
+		return false, errors.New("recovery code could not be used")
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		slog.ErrorContext(ctx, "Recovery code was not used correctly", "error", err)
+		return false, errors.New("recovery code was not used correctly")
+	}
+
+	return affected == 1, nil
+}
+
+const getTwoFactorQuery = "SELECT user_id, secret, enabled, last_used_step FROM user_totp WHERE user_id=?"
+const savePendingTwoFactorQuery = `INSERT INTO user_totp (user_id, secret, enabled, last_used_step)
+VALUES (?, ?, FALSE, 0)
+ON DUPLICATE KEY UPDATE secret=VALUES(secret), enabled=FALSE, last_used_step=0`
+const enableTwoFactorQuery = "UPDATE user_totp SET enabled=TRUE, last_used_step=? WHERE user_id=?"
+const useTwoFactorStepQuery = "UPDATE user_totp SET last_used_step=? WHERE user_id=? AND last_used_step < ?"
+const deleteRecoveryCodesQuery = "DELETE FROM user_recovery_codes WHERE user_id=?"
+const insertRecoveryCodeQuery = "INSERT INTO user_recovery_codes (user_id, code_hash) VALUES (?, ?)"
+const useRecoveryCodeQuery = "DELETE FROM user_recovery_codes WHERE user_id=? AND code_hash=?"
@@ -0,0 +1,191 @@
+package repositories_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Two Factor Repository", func() {
+	var (
+		db   *sql.DB
+		mock sqlmock.Sqlmock
+		repo *repositories.TwoFactorRepository
+		ctx  context.Context
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		var err error
+		db, mock, err = sqlmock.New()
+		Expect(err).ToNot(HaveOccurred())
+
+		repo = repositories.NewTwoFactorRepository(db)
+	})
+
+	Describe("Get", func() {
+		It("returns the user's two factor settings", func() {
+			rows := sqlmock.NewRows([]string{"user_id", "secret", "enabled", "last_used_step"}).
+				AddRow(10, "encrypted", true, 1234)
+
+			mock.ExpectQuery("^SELECT user_id, secret, enabled, last_used_step FROM user_totp WHERE user_id=?").
+				WithArgs(10).
+				WillReturnRows(rows)
+
+			twoFactor, err := repo.Get(ctx, 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(twoFactor).To(Equal(&repositories.TwoFactor{
+				UserID:       10,
+				Secret:       "encrypted",
+				Enabled:      true,
+				LastUsedStep: 1234,
+			}))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns sql.ErrNoRows if the user has not enrolled", func() {
+			mock.ExpectQuery("^SELECT .+ FROM user_totp").
+				WithArgs(10).
+				WillReturnError(sql.ErrNoRows)
+
+			_, err := repo.Get(ctx, 10)
+			Expect(err).To(Equal(sql.ErrNoRows))
+		})
+
+		It("returns an error if the query fails", func() {
+			mock.ExpectQuery("^SELECT .+ FROM user_totp").
+				WithArgs(10).
+				WillReturnError(errors.New("blah"))
+
+			_, err := repo.Get(ctx, 10)
+			Expect(err).To(MatchError("failed to retrieve two factor settings"))
+		})
+	})
+
+	Describe("SavePending", func() {
+		It("stores a secret that is not enabled yet", func() {
+			mock.ExpectExec("^INSERT INTO user_totp .+ ON DUPLICATE KEY UPDATE").
+				WithArgs(10, "encrypted").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			err := repo.SavePending(ctx, 10, "encrypted")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the secret cannot be saved", func() {
+			mock.ExpectExec("^INSERT INTO user_totp").
+				WithArgs(10, "encrypted").
+				WillReturnError(errors.New("blah"))
+
+			err := repo.SavePending(ctx, 10, "encrypted")
+			Expect(err).To(MatchError("two factor secret could not be saved"))
+		})
+	})
+
+	Describe("Enable", func() {
+		It("enables two factor and replaces the recovery codes in a transaction", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec("^UPDATE user_totp SET enabled=TRUE, last_used_step=\\? WHERE user_id=\\?").
+				WithArgs(1234, 10).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec("^DELETE FROM user_recovery_codes WHERE user_id=\\?").
+				WithArgs(10).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("^INSERT INTO user_recovery_codes").
+				WithArgs(10, "hash 1").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectExec("^INSERT INTO user_recovery_codes").
+				WithArgs(10, "hash 2").
+				WillReturnResult(sqlmock.NewResult(2, 1))
+			mock.ExpectCommit()
+
+			err := repo.Enable(ctx, 10, 1234, []string{"hash 1", "hash 2"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("rolls back if a recovery code cannot be saved", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec("^UPDATE user_totp").
+				WithArgs(1234, 10).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec("^DELETE FROM user_recovery_codes").
+				WithArgs(10).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("^INSERT INTO user_recovery_codes").
+				WithArgs(10, "hash 1").
+				WillReturnError(errors.New("blah"))
+			mock.ExpectRollback()
+
+			err := repo.Enable(ctx, 10, 1234, []string{"hash 1"})
+			Expect(err).To(MatchError("two factor could not be enabled"))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("UseStep", func() {
+		It("returns true if the step is newer than the last one used", func() {
+			mock.ExpectExec("^UPDATE user_totp SET last_used_step=\\? WHERE user_id=\\? AND last_used_step < \\?").
+				WithArgs(1235, 10, 1235).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			used, err := repo.UseStep(ctx, 10, 1235)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(used).To(BeTrue())
+		})
+
+		It("returns false if the step has already been used", func() {
+			mock.ExpectExec("^UPDATE user_totp SET last_used_step").
+				WithArgs(1234, 10, 1234).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+
+			used, err := repo.UseStep(ctx, 10, 1234)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(used).To(BeFalse())
+		})
+	})
+
+	Describe("UseRecoveryCode", func() {
+		It("deletes the code and returns true if the user has it", func() {
+			mock.ExpectExec("^DELETE FROM user_recovery_codes WHERE user_id=\\? AND code_hash=\\?").
+				WithArgs(10, "hash 1").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			used, err := repo.UseRecoveryCode(ctx, 10, "hash 1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(used).To(BeTrue())
+		})
+
+		It("returns false if the user does not have the code", func() {
+			mock.ExpectExec("^DELETE FROM user_recovery_codes").
+				WithArgs(10, "hash 1").
+				WillReturnResult(sqlmock.NewResult(0, 0))
+
+			used, err := repo.UseRecoveryCode(ctx, 10, "hash 1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(used).To(BeFalse())
+		})
+
+		It("returns an error if the delete fails", func() {
+			mock.ExpectExec("^DELETE FROM user_recovery_codes").
+				WithArgs(10, "hash 1").
+				WillReturnError(errors.New("blah"))
+
+			_, err := repo.UseRecoveryCode(ctx, 10, "hash 1")
+			Expect(err).To(MatchError("recovery code could not be used"))
+		})
+	})
+})
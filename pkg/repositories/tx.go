@@ -0,0 +1,31 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Querier runs queries against either the database or a transaction.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type txKey struct{}
+
+// WithTx returns a context that makes the repositories called with it run
+// their queries in tx, so a service can make several repository calls in one
+// transaction.
+func WithTx(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// querier returns the transaction from ctx, or db when there is none.
+func querier(ctx context.Context, db *sql.DB) Querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+
+	return db
+}
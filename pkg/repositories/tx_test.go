@@ -0,0 +1,63 @@
+package repositories_test
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WithTx", func() {
+	var (
+		db   *sql.DB
+		mock sqlmock.Sqlmock
+		ctx  context.Context
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		var err error
+		db, mock, err = sqlmock.New()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("runs the repository's queries in the transaction", func() {
+		mock.ExpectBegin()
+		mock.ExpectExec("^DELETE FROM recipe_steps WHERE recipe_id=?").
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectRollback()
+
+		tx, err := db.BeginTx(ctx, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		repo := repositories.NewStepsRepository(db)
+		err = repo.DeleteForRecipe(repositories.WithTx(ctx, tx), 1)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(tx.Rollback()).To(Succeed())
+		Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+	})
+
+	It("does not run queries once the transaction is over", func() {
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+		mock.ExpectExec("^DELETE FROM recipe_steps WHERE recipe_id=?").
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		tx, err := db.BeginTx(ctx, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tx.Rollback()).To(Succeed())
+
+		repo := repositories.NewStepsRepository(db)
+		err = repo.DeleteForRecipe(repositories.WithTx(ctx, tx), 1)
+		Expect(err).To(MatchError("recipe steps could not be deleted"))
+	})
+})
@@ -1,9 +1,10 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"errors"
-	"fmt"
+	"log/slog"
 	"net/mail"
 
 	"golang.org/x/crypto/bcrypt"
@@ -17,8 +18,10 @@ type Credentials struct {
 }
 
 type User struct {
-	Username string
-	Email    string
+	ID            int64
+	Username      string
+	Email         string
+	EmailVerified bool
 }
 
 type UsersRepository struct {
@@ -29,13 +32,13 @@ func NewUsersRepository(db *sql.DB) *UsersRepository {
 	return &UsersRepository{db: db}
 }
 
-func (u *UsersRepository) ExistsByUsername(username string) (bool, error) {
+func (u *UsersRepository) ExistsByUsername(ctx context.Context, username string) (bool, error) {
 	if username == "" {
-		fmt.Println("Could not check for user: username required")
+		slog.ErrorContext(ctx, "Could not check for user: username required")
 		return false, errors.New("could not check for user: username required")
 	}
 
-	userRow := u.db.QueryRow(existsByUsernameQuery, username)
+	userRow := u.db.QueryRowContext(ctx, existsByUsernameQuery, username)
 
 	var user User
 	err := userRow.Scan(&user.Username)
@@ -44,20 +47,20 @@ func (u *UsersRepository) ExistsByUsername(username string) (bool, error) {
 			return false, nil
 		}
 
-		fmt.Printf("Failed to scan row for user by username '%s': %s\n", username, err.Error())
+		slog.ErrorContext(ctx, "Failed to scan row for user by username", "username", username, "error", err)
 		return false, errors.New("failed to query for user by username")
 	}
 
 	return true, nil
 }
 
-func (u *UsersRepository) ExistsByEmail(email string) (bool, error) {
+func (u *UsersRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
 	if email == "" {
-		fmt.Println("Could not check for user: username required")
+		slog.ErrorContext(ctx, "Could not check for user: username required")
 		return false, errors.New("could not check for user: email required")
 	}
 
-	userRow := u.db.QueryRow(existsByEmailQuery, email)
+	userRow := u.db.QueryRowContext(ctx, existsByEmailQuery, email)
 
 	var user User
 	err := userRow.Scan(&user.Email)
@@ -66,71 +69,194 @@ func (u *UsersRepository) ExistsByEmail(email string) (bool, error) {
 			return false, nil
 		}
 
-		fmt.Printf("Failed to scan row for user by email '%s': %s\n", email, err.Error())
+		slog.ErrorContext(ctx, "Failed to scan row for user by email", "email", email, "error", err)
 		return false, errors.New("failed to query for user by email")
 	}
 
 	return true, nil
 }
 
-func (u *UsersRepository) Insert(username, email, password string) (int64, error) {
+func (u *UsersRepository) Insert(ctx context.Context, username, email, password string) (int64, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), BCRYPT_COST)
 	if err != nil {
-		fmt.Printf("Could not hash the user password")
+		slog.ErrorContext(ctx, "Could not hash the user password")
 		return -1, errors.New("could not hash password")
 	}
 
-	res, err := u.db.Exec(insertUserQuery,
+	res, err := u.db.ExecContext(ctx, insertUserQuery,
 		username,
 		email,
 		string(hashedPassword),
 	)
 
 	if err != nil {
-		fmt.Printf("User could not be saved: %s\n", err.Error())
+		slog.ErrorContext(ctx, "User could not be saved", "error", err)
 		return -1, errors.New("user could not be added")
 	}
 
 	id, err := res.LastInsertId()
 	if err != nil {
-		fmt.Printf("RecipeResponse was not saved correctly: %s\n", err.Error())
+		slog.ErrorContext(ctx, "RecipeResponse was not saved correctly", "error", err)
 		return 0, errors.New("user was not saved correctly")
 	}
 
 	return id, nil
 }
 
-func (u *UsersRepository) Verify(login, password string) (bool, int64, error) {
+func (u *UsersRepository) Verify(ctx context.Context, login, password string) (bool, int64, error) {
 	parser := mail.AddressParser{}
 	_, err := parser.Parse(login)
 
 	var result *sql.Row
 	if err == nil {
-		result = u.db.QueryRow(verifyByEmailQuery, login)
+		result = u.db.QueryRowContext(ctx, verifyByEmailQuery, login)
 	} else {
-		result = u.db.QueryRow(verifyByUsernameQuery, login)
+		result = u.db.QueryRowContext(ctx, verifyByUsernameQuery, login)
 	}
 
 	storedCreds := &Credentials{}
 	err = result.Scan(&storedCreds.ID, &storedCreds.Password)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			fmt.Println("Did not find user by email or username for verification")
+			slog.DebugContext(ctx, "Did not find user by email or username for verification")
 			return false, -1, nil
 		}
 
-		fmt.Println("Bad scan when verifying user credentials")
+		slog.ErrorContext(ctx, "Bad scan when verifying user credentials")
 		return false, -1, err
 	}
 
 	if err = bcrypt.CompareHashAndPassword([]byte(storedCreds.Password), []byte(password)); err != nil {
-		fmt.Println("User credentials comparision failed")
+		slog.DebugContext(ctx, "User credentials comparision failed")
 		return false, -1, nil
 	}
 
 	return true, storedCreds.ID, nil
 }
 
+// GetByEmail returns the user with the given email, or sql.ErrNoRows if there
+// is none.
+func (u *UsersRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	row := u.db.QueryRowContext(ctx, getByEmailQuery, email)
+
+	user := &User{}
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.EmailVerified)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+
+		slog.ErrorContext(ctx, "Failed to scan row for user by email", "email", email, "error", err)
+		return nil, errors.New("failed to query for user by email")
+	}
+
+	return user, nil
+}
+
+// GetByLogin returns the user with the given username or email, the same way
+// Verify looks the user up, or sql.ErrNoRows if there is none.
+func (u *UsersRepository) GetByLogin(ctx context.Context, login string) (*User, error) {
+	parser := mail.AddressParser{}
+	if _, err := parser.Parse(login); err == nil {
+		return u.GetByEmail(ctx, login)
+	}
+
+	row := u.db.QueryRowContext(ctx, getByUsernameQuery, login)
+
+	user := &User{}
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.EmailVerified)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+
+		slog.ErrorContext(ctx, "Failed to scan row for user by username", "username", login, "error", err)
+		return nil, errors.New("failed to query for user by username")
+	}
+
+	return user, nil
+}
+
+// GetByID returns the user with the given ID, or sql.ErrNoRows if there is
+// none.
+func (u *UsersRepository) GetByID(ctx context.Context, userID int64) (*User, error) {
+	row := u.db.QueryRowContext(ctx, getByIDQuery, userID)
+
+	user := &User{}
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.EmailVerified)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+
+		slog.ErrorContext(ctx, "Failed to scan row for user by id", "user_id", userID, "error", err)
+		return nil, errors.New("failed to query for user by id")
+	}
+
+	return user, nil
+}
+
+func (u *UsersRepository) MarkEmailVerified(ctx context.Context, userID int64) error {
+	_, err := u.db.ExecContext(ctx, markEmailVerifiedQuery, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Email could not be marked as verified", "error", err)
+		return errors.New("email could not be marked as verified")
+	}
+
+	return nil
+}
+
+// VerifyPassword checks a password against the one stored for the user ID.
+func (u *UsersRepository) VerifyPassword(ctx context.Context, userID int64, password string) (bool, error) {
+	row := u.db.QueryRowContext(ctx, verifyByIDQuery, userID)
+
+	var passwordHash string
+	err := row.Scan(&passwordHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			slog.DebugContext(ctx, "Did not find user by id for verification")
+			return false, nil
+		}
+
+		slog.ErrorContext(ctx, "Bad scan when verifying user password")
+		return false, err
+	}
+
+	if err = bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)); err != nil {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// UpdatePassword replaces the user's password. It returns sql.ErrNoRows if
+// the user does not exist.
+func (u *UsersRepository) UpdatePassword(ctx context.Context, userID int64, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), BCRYPT_COST)
+	if err != nil {
+		slog.ErrorContext(ctx, "Could not hash the user password")
+		return errors.New("could not hash password")
+	}
+
+	res, err := u.db.ExecContext(ctx, updatePasswordQuery, string(hashedPassword), userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Password could not be updated", "error", err)
+		return errors.New("password could not be updated")
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		slog.ErrorContext(ctx, "Password was not updated correctly", "error", err)
+		return errors.New("password was not updated correctly")
+	}
+
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
 const existsByUsernameQuery = "SELECT username FROM users WHERE username=?"
 const existsByEmailQuery = "SELECT email FROM users WHERE email=?"
 const insertUserQuery = `INSERT INTO users
@@ -141,3 +267,9 @@ VALUES (?, ?, ?)
 `
 const verifyByUsernameQuery = "select id, password_hash from users where username=?"
 const verifyByEmailQuery = "select id, password_hash from users where email=?"
+const verifyByIDQuery = "select password_hash from users where id=?"
+const getByEmailQuery = "SELECT id, username, email, email_verified FROM users WHERE email=?"
+const getByUsernameQuery = "SELECT id, username, email, email_verified FROM users WHERE username=?"
+const getByIDQuery = "SELECT id, username, email, email_verified FROM users WHERE id=?"
+const markEmailVerifiedQuery = "UPDATE users SET email_verified=TRUE WHERE id=?"
+const updatePasswordQuery = "UPDATE users SET password_hash=? WHERE id=?"
@@ -1,8 +1,11 @@
 package repositories_test
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"golang.org/x/crypto/bcrypt"
@@ -17,9 +20,12 @@ var _ = Describe("Users Repository", func() {
 	var (
 		db   *sql.DB
 		mock sqlmock.Sqlmock
+		ctx  context.Context
 	)
 
 	BeforeEach(func() {
+		ctx = context.Background()
+
 		var err error
 		db, mock, err = sqlmock.New()
 		Expect(err).ToNot(HaveOccurred())
@@ -35,7 +41,7 @@ var _ = Describe("Users Repository", func() {
 				WillReturnRows(usernameRow)
 
 			repo := repositories.NewUsersRepository(db)
-			userExists, err := repo.ExistsByUsername("recipeGuru")
+			userExists, err := repo.ExistsByUsername(ctx, "recipeGuru")
 			Expect(err).ToNot(HaveOccurred())
 			Expect(userExists).To(BeTrue())
 		})
@@ -47,7 +53,7 @@ var _ = Describe("Users Repository", func() {
 
 			repo := repositories.NewUsersRepository(db)
 
-			userExists, err := repo.ExistsByUsername("missing")
+			userExists, err := repo.ExistsByUsername(ctx, "missing")
 			Expect(err).ToNot(HaveOccurred())
 			Expect(userExists).To(BeFalse())
 		})
@@ -55,7 +61,7 @@ var _ = Describe("Users Repository", func() {
 		It("returns false and an error if the username is empty", func() {
 			repo := repositories.NewUsersRepository(db)
 
-			userExists, err := repo.ExistsByUsername("")
+			userExists, err := repo.ExistsByUsername(ctx, "")
 			Expect(err).To(HaveOccurred())
 			Expect(userExists).To(BeFalse())
 			Expect(err.Error()).To(Equal("could not check for user: username required"))
@@ -68,7 +74,7 @@ var _ = Describe("Users Repository", func() {
 
 			repo := repositories.NewUsersRepository(db)
 
-			userExists, err := repo.ExistsByUsername("missing")
+			userExists, err := repo.ExistsByUsername(ctx, "missing")
 			Expect(err).To(HaveOccurred())
 			Expect(userExists).To(BeFalse())
 			Expect(err.Error()).To(Equal("failed to query for user by username"))
@@ -86,7 +92,7 @@ var _ = Describe("Users Repository", func() {
 
 			repo := repositories.NewUsersRepository(db)
 
-			userExists, err := repo.ExistsByEmail("busyCook@example.com")
+			userExists, err := repo.ExistsByEmail(ctx, "busyCook@example.com")
 			Expect(err).ToNot(HaveOccurred())
 			Expect(userExists).To(BeTrue())
 		})
@@ -98,7 +104,7 @@ var _ = Describe("Users Repository", func() {
 
 			repo := repositories.NewUsersRepository(db)
 
-			userExists, err := repo.ExistsByEmail("missing@example.com")
+			userExists, err := repo.ExistsByEmail(ctx, "missing@example.com")
 			Expect(err).ToNot(HaveOccurred())
 			Expect(userExists).To(BeFalse())
 		})
@@ -106,7 +112,7 @@ var _ = Describe("Users Repository", func() {
 		It("returns false and an error if the email is empty", func() {
 			repo := repositories.NewUsersRepository(db)
 
-			userExists, err := repo.ExistsByEmail("")
+			userExists, err := repo.ExistsByEmail(ctx, "")
 			Expect(err).To(HaveOccurred())
 			Expect(userExists).To(BeFalse())
 			Expect(err.Error()).To(Equal("could not check for user: email required"))
@@ -119,7 +125,7 @@ var _ = Describe("Users Repository", func() {
 
 			repo := repositories.NewUsersRepository(db)
 
-			userExists, err := repo.ExistsByEmail("missing@example.com")
+			userExists, err := repo.ExistsByEmail(ctx, "missing@example.com")
 			Expect(err).To(HaveOccurred())
 			Expect(userExists).To(BeFalse())
 			Expect(err.Error()).To(Equal("failed to query for user by email"))
@@ -138,7 +144,7 @@ var _ = Describe("Users Repository", func() {
 				).WillReturnResult(res)
 
 			repo := repositories.NewUsersRepository(db)
-			id, err := repo.Insert("some username", "some email", "some-password")
+			id, err := repo.Insert(ctx, "some username", "some email", "some-password")
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(id).To(BeEquivalentTo(0))
@@ -151,7 +157,7 @@ var _ = Describe("Users Repository", func() {
 				WillReturnError(errors.New("constraint fails"))
 
 			repo := repositories.NewUsersRepository(db)
-			_, err := repo.Insert("some username", "some email", "some-password")
+			_, err := repo.Insert(ctx, "some username", "some email", "some-password")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("user could not be added"))
 		})
@@ -163,7 +169,7 @@ var _ = Describe("Users Repository", func() {
 				WillReturnResult(res)
 
 			repo := repositories.NewUsersRepository(db)
-			_, err := repo.Insert("some username", "some email", "some-password")
+			_, err := repo.Insert(ctx, "some username", "some email", "some-password")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("user was not saved correctly"))
 		})
@@ -183,7 +189,7 @@ var _ = Describe("Users Repository", func() {
 
 			repo := repositories.NewUsersRepository(db)
 
-			valid, userID, err := repo.Verify("some_username", "some-password")
+			valid, userID, err := repo.Verify(ctx, "some_username", "some-password")
 			Expect(err).ToNot(HaveOccurred())
 			Expect(valid).To(BeTrue())
 			Expect(userID).To(BeEquivalentTo(10))
@@ -204,7 +210,7 @@ var _ = Describe("Users Repository", func() {
 
 			repo := repositories.NewUsersRepository(db)
 
-			valid, userID, err := repo.Verify("someone@example.com", "some-password")
+			valid, userID, err := repo.Verify(ctx, "someone@example.com", "some-password")
 			Expect(err).ToNot(HaveOccurred())
 			Expect(valid).To(BeTrue())
 			Expect(userID).To(BeEquivalentTo(20))
@@ -225,7 +231,7 @@ var _ = Describe("Users Repository", func() {
 
 			repo := repositories.NewUsersRepository(db)
 
-			valid, userID, err := repo.Verify("someone@example.com", "invalid-password")
+			valid, userID, err := repo.Verify(ctx, "someone@example.com", "invalid-password")
 			Expect(err).ToNot(HaveOccurred())
 			Expect(valid).To(BeFalse())
 			Expect(userID).To(BeEquivalentTo(-1))
@@ -240,7 +246,7 @@ var _ = Describe("Users Repository", func() {
 
 			repo := repositories.NewUsersRepository(db)
 
-			valid, userID, err := repo.Verify("missing@example.com", "some-password")
+			valid, userID, err := repo.Verify(ctx, "missing@example.com", "some-password")
 			Expect(err).ToNot(HaveOccurred())
 			Expect(valid).To(BeFalse())
 			Expect(userID).To(BeEquivalentTo(-1))
@@ -255,7 +261,7 @@ var _ = Describe("Users Repository", func() {
 
 			repo := repositories.NewUsersRepository(db)
 
-			valid, userID, err := repo.Verify("someone@example.com", "some-password")
+			valid, userID, err := repo.Verify(ctx, "someone@example.com", "some-password")
 			Expect(err).To(HaveOccurred())
 			Expect(err).To(MatchError("some error"))
 			Expect(valid).To(BeFalse())
@@ -264,4 +270,269 @@ var _ = Describe("Users Repository", func() {
 			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
 		})
 	})
+
+	Describe("GetByEmail", func() {
+		It("returns the user with the email", func() {
+			userRow := sqlmock.NewRows([]string{"id", "username", "email", "email_verified"}).
+				AddRow(10, "recipeGuru", "guru@example.com", true)
+
+			mock.ExpectQuery("^SELECT id, username, email, email_verified FROM users WHERE email=?").
+				WithArgs("guru@example.com").
+				WillReturnRows(userRow)
+
+			repo := repositories.NewUsersRepository(db)
+
+			user, err := repo.GetByEmail(ctx, "guru@example.com")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(user).To(Equal(&repositories.User{
+				ID:            10,
+				Username:      "recipeGuru",
+				Email:         "guru@example.com",
+				EmailVerified: true,
+			}))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns sql.ErrNoRows if no user has the email", func() {
+			mock.ExpectQuery("^SELECT id, username, email, email_verified FROM users WHERE email=?").
+				WithArgs("missing@example.com").
+				WillReturnError(sql.ErrNoRows)
+
+			repo := repositories.NewUsersRepository(db)
+
+			_, err := repo.GetByEmail(ctx, "missing@example.com")
+			Expect(err).To(Equal(sql.ErrNoRows))
+		})
+
+		It("returns an error if the query fails", func() {
+			mock.ExpectQuery("^SELECT id, username, email, email_verified FROM users WHERE email=?").
+				WithArgs("guru@example.com").
+				WillReturnError(errors.New("blah"))
+
+			repo := repositories.NewUsersRepository(db)
+
+			_, err := repo.GetByEmail(ctx, "guru@example.com")
+			Expect(err).To(MatchError("failed to query for user by email"))
+		})
+	})
+
+	Describe("GetByLogin", func() {
+		It("returns the user with the username", func() {
+			userRow := sqlmock.NewRows([]string{"id", "username", "email", "email_verified"}).
+				AddRow(10, "recipeGuru", "guru@example.com", true)
+
+			mock.ExpectQuery("^SELECT id, username, email, email_verified FROM users WHERE username=?").
+				WithArgs("recipeGuru").
+				WillReturnRows(userRow)
+
+			repo := repositories.NewUsersRepository(db)
+
+			user, err := repo.GetByLogin(ctx, "recipeGuru")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(user.ID).To(Equal(int64(10)))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns the user with the email", func() {
+			userRow := sqlmock.NewRows([]string{"id", "username", "email", "email_verified"}).
+				AddRow(10, "recipeGuru", "guru@example.com", true)
+
+			mock.ExpectQuery("^SELECT id, username, email, email_verified FROM users WHERE email=?").
+				WithArgs("guru@example.com").
+				WillReturnRows(userRow)
+
+			repo := repositories.NewUsersRepository(db)
+
+			user, err := repo.GetByLogin(ctx, "guru@example.com")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(user.Username).To(Equal("recipeGuru"))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns sql.ErrNoRows if no user has the username", func() {
+			mock.ExpectQuery("^SELECT id, username, email, email_verified FROM users WHERE username=?").
+				WithArgs("missing").
+				WillReturnError(sql.ErrNoRows)
+
+			repo := repositories.NewUsersRepository(db)
+
+			_, err := repo.GetByLogin(ctx, "missing")
+			Expect(err).To(Equal(sql.ErrNoRows))
+		})
+	})
+
+	Describe("GetByID", func() {
+		It("returns the user with the id", func() {
+			userRow := sqlmock.NewRows([]string{"id", "username", "email", "email_verified"}).
+				AddRow(10, "recipeGuru", "guru@example.com", false)
+
+			mock.ExpectQuery("^SELECT id, username, email, email_verified FROM users WHERE id=?").
+				WithArgs(10).
+				WillReturnRows(userRow)
+
+			repo := repositories.NewUsersRepository(db)
+
+			user, err := repo.GetByID(ctx, 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(user).To(Equal(&repositories.User{
+				ID:       10,
+				Username: "recipeGuru",
+				Email:    "guru@example.com",
+			}))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("aborts the query if the context is cancelled", func() {
+			mock.ExpectQuery("^SELECT id, username, email, email_verified FROM users WHERE id=?").
+				WithArgs(10).
+				WillDelayFor(time.Minute).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+			ctx, cancel := context.WithCancel(ctx)
+			time.AfterFunc(10*time.Millisecond, cancel)
+
+			start := time.Now()
+			repo := repositories.NewUsersRepository(db)
+			_, err := repo.GetByID(ctx, 10)
+			Expect(err).To(HaveOccurred())
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		})
+
+		It("returns sql.ErrNoRows if there is no user with the id", func() {
+			mock.ExpectQuery("^SELECT id, username, email, email_verified FROM users WHERE id=?").
+				WithArgs(10).
+				WillReturnError(sql.ErrNoRows)
+
+			repo := repositories.NewUsersRepository(db)
+
+			_, err := repo.GetByID(ctx, 10)
+			Expect(err).To(Equal(sql.ErrNoRows))
+		})
+	})
+
+	Describe("MarkEmailVerified", func() {
+		It("marks the user's email as verified", func() {
+			mock.ExpectExec("^UPDATE users SET email_verified=TRUE WHERE id=?").
+				WithArgs(10).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			repo := repositories.NewUsersRepository(db)
+
+			err := repo.MarkEmailVerified(ctx, 10)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the update fails", func() {
+			mock.ExpectExec("^UPDATE users SET email_verified=TRUE WHERE id=?").
+				WithArgs(10).
+				WillReturnError(errors.New("blah"))
+
+			repo := repositories.NewUsersRepository(db)
+
+			err := repo.MarkEmailVerified(ctx, 10)
+			Expect(err).To(MatchError("email could not be marked as verified"))
+		})
+	})
+
+	Describe("VerifyPassword", func() {
+		It("returns true if the password matches", func() {
+			hashedPassword, err := bcrypt.GenerateFromPassword([]byte("some-password"), repositories.BCRYPT_COST)
+			Expect(err).ToNot(HaveOccurred())
+
+			mock.ExpectQuery("^select password_hash from users where id=?").
+				WithArgs(10).
+				WillReturnRows(sqlmock.NewRows([]string{"password_hash"}).AddRow(hashedPassword))
+
+			repo := repositories.NewUsersRepository(db)
+
+			valid, err := repo.VerifyPassword(ctx, 10, "some-password")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(valid).To(BeTrue())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns false if the password is incorrect", func() {
+			hashedPassword, err := bcrypt.GenerateFromPassword([]byte("some-password"), repositories.BCRYPT_COST)
+			Expect(err).ToNot(HaveOccurred())
+
+			mock.ExpectQuery("^select password_hash from users where id=?").
+				WithArgs(10).
+				WillReturnRows(sqlmock.NewRows([]string{"password_hash"}).AddRow(hashedPassword))
+
+			repo := repositories.NewUsersRepository(db)
+
+			valid, err := repo.VerifyPassword(ctx, 10, "invalid-password")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(valid).To(BeFalse())
+		})
+
+		It("returns false if the user cannot be found", func() {
+			mock.ExpectQuery("^select password_hash from users where id=?").
+				WithArgs(10).
+				WillReturnError(sql.ErrNoRows)
+
+			repo := repositories.NewUsersRepository(db)
+
+			valid, err := repo.VerifyPassword(ctx, 10, "some-password")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(valid).To(BeFalse())
+		})
+	})
+
+	Describe("UpdatePassword", func() {
+		It("stores a hash of the new password", func() {
+			var storedHash string
+			mock.ExpectExec("^UPDATE users SET password_hash=\\? WHERE id=\\?").
+				WithArgs(hashArg{hash: &storedHash}, 10).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			repo := repositories.NewUsersRepository(db)
+
+			err := repo.UpdatePassword(ctx, 10, "new-password")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(bcrypt.CompareHashAndPassword([]byte(storedHash), []byte("new-password"))).To(Succeed())
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns sql.ErrNoRows if the user does not exist", func() {
+			mock.ExpectExec("^UPDATE users SET password_hash=\\? WHERE id=\\?").
+				WithArgs(sqlmock.AnyArg(), 10).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+
+			repo := repositories.NewUsersRepository(db)
+
+			err := repo.UpdatePassword(ctx, 10, "new-password")
+			Expect(err).To(Equal(sql.ErrNoRows))
+		})
+
+		It("returns an error if the update fails", func() {
+			mock.ExpectExec("^UPDATE users SET password_hash=\\? WHERE id=\\?").
+				WithArgs(sqlmock.AnyArg(), 10).
+				WillReturnError(errors.New("blah"))
+
+			repo := repositories.NewUsersRepository(db)
+
+			err := repo.UpdatePassword(ctx, 10, "new-password")
+			Expect(err).To(MatchError("password could not be updated"))
+		})
+	})
 })
+
+// hashArg captures the password hash passed to a query.
+type hashArg struct {
+	hash *string
+}
+
+func (a hashArg) Match(v driver.Value) bool {
+	hash, ok := v.(string)
+	*a.hash = hash
+	return ok
+}
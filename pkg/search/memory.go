@@ -0,0 +1,159 @@
+package search
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+)
+
+// fieldWeights is how much a word counts towards a recipe's score depending
+// on where in the recipe it is.
+var fieldWeights = map[Field]float64{
+	FieldName:        3,
+	FieldIngredient:  2,
+	FieldDescription: 1,
+	FieldStep:        1,
+}
+
+// MemoryIndex is an inverted index kept in the process. It has to be filled
+// with every recipe when the app starts, and each instance of the app has its
+// own copy, so it suits small deployments and tests.
+type MemoryIndex struct {
+	mu   sync.RWMutex
+	docs map[int64]*Document
+
+	// postings maps each word to the recipes it is in and the weighted
+	// number of times it appears in each.
+	postings map[string]map[int64]float64
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		docs:     make(map[int64]*Document),
+		postings: make(map[string]map[int64]float64),
+	}
+}
+
+func (i *MemoryIndex) Put(ctx context.Context, doc *Document) error {
+	doc = &Document{
+		RecipeID:    doc.RecipeID,
+		UserID:      doc.UserID,
+		Name:        doc.Name,
+		Description: doc.Description,
+		Ingredients: append([]string(nil), doc.Ingredients...),
+		Steps:       append([]string(nil), doc.Steps...),
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.remove(doc.RecipeID)
+	i.docs[doc.RecipeID] = doc
+
+	for term, weight := range termWeights(doc) {
+		if i.postings[term] == nil {
+			i.postings[term] = make(map[int64]float64)
+		}
+		i.postings[term][doc.RecipeID] = weight
+	}
+
+	return nil
+}
+
+func (i *MemoryIndex) Delete(ctx context.Context, recipeID int64) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.remove(recipeID)
+	return nil
+}
+
+// Search scores recipes with TF-IDF: each query word adds its weighted count
+// in the recipe, scaled up the rarer the word is across all recipes.
+func (i *MemoryIndex) Search(ctx context.Context, userID int64, query string, limit int) ([]*Result, error) {
+	terms := Terms(query)
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	scores := make(map[int64]float64)
+	for _, term := range terms {
+		posting := i.postings[term]
+		if len(posting) == 0 {
+			continue
+		}
+
+		idf := 1 + math.Log(float64(len(i.docs))/float64(len(posting)))
+		for recipeID, weight := range posting {
+			if i.docs[recipeID].UserID != userID {
+				continue
+			}
+			scores[recipeID] += weight * idf
+		}
+	}
+
+	results := make([]*Result, 0, len(scores))
+	for recipeID, score := range scores {
+		doc := i.docs[recipeID]
+		results = append(results, &Result{
+			RecipeID:    recipeID,
+			Name:        doc.Name,
+			Description: doc.Description,
+			Score:       score,
+		})
+	}
+
+	sort.Slice(results, func(a, b int) bool {
+		if results[a].Score != results[b].Score {
+			return results[a].Score > results[b].Score
+		}
+		return results[a].RecipeID < results[b].RecipeID
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	for _, result := range results {
+		result.Highlights = Highlights(i.docs[result.RecipeID], terms)
+	}
+
+	return results, nil
+}
+
+func (i *MemoryIndex) remove(recipeID int64) {
+	doc, ok := i.docs[recipeID]
+	if !ok {
+		return
+	}
+
+	for term := range termWeights(doc) {
+		delete(i.postings[term], recipeID)
+		if len(i.postings[term]) == 0 {
+			delete(i.postings, term)
+		}
+	}
+	delete(i.docs, recipeID)
+}
+
+func termWeights(doc *Document) map[string]float64 {
+	weights := make(map[string]float64)
+
+	add := func(field Field, text string) {
+		for _, t := range tokenize(text) {
+			weights[t.text] += fieldWeights[field]
+		}
+	}
+
+	add(FieldName, doc.Name)
+	add(FieldDescription, doc.Description)
+	for _, ingredient := range doc.Ingredients {
+		add(FieldIngredient, ingredient)
+	}
+	for _, step := range doc.Steps {
+		add(FieldStep, step)
+	}
+
+	return weights
+}
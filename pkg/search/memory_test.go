@@ -0,0 +1,111 @@
+package search_test
+
+import (
+	"context"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/search"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MemoryIndex", func() {
+	var (
+		index *search.MemoryIndex
+		ctx   context.Context
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		index = search.NewMemoryIndex()
+
+		for _, doc := range []*search.Document{{
+			RecipeID:    1,
+			UserID:      10,
+			Name:        "Garlic Bread",
+			Description: "Crusty bread with butter",
+			Ingredients: []string{"Bread", "Garlic", "Butter"},
+			Steps:       []string{"Spread the butter on the bread."},
+		}, {
+			RecipeID:    2,
+			UserID:      10,
+			Name:        "Roast Chicken",
+			Description: "Sunday dinner",
+			Ingredients: []string{"Chicken", "Garlic"},
+			Steps:       []string{"Roast the chicken."},
+		}, {
+			RecipeID:    3,
+			UserID:      20,
+			Name:        "Garlic Soup",
+			Description: "Another user's soup",
+		}} {
+			Expect(index.Put(ctx, doc)).To(Succeed())
+		}
+	})
+
+	ids := func(results []*search.Result) []int64 {
+		var ids []int64
+		for _, result := range results {
+			ids = append(ids, result.RecipeID)
+		}
+		return ids
+	}
+
+	It("ranks recipes by where and how often the words appear", func() {
+		results, err := index.Search(ctx, 10, "garlic", 10)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ids(results)).To(Equal([]int64{1, 2}))
+		Expect(results[0].Score).To(BeNumerically(">", results[1].Score))
+
+		Expect(results[0].Name).To(Equal("Garlic Bread"))
+		Expect(results[0].Description).To(Equal("Crusty bread with butter"))
+		Expect(results[0].Highlights).To(ContainElement(&search.Highlight{
+			Field: search.FieldName,
+			Text:  "<mark>Garlic</mark> Bread",
+		}))
+	})
+
+	It("adds up the score of each word in the query", func() {
+		results, err := index.Search(ctx, 10, "roast garlic", 10)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ids(results)).To(Equal([]int64{2, 1}))
+	})
+
+	It("only returns the user's recipes", func() {
+		results, err := index.Search(ctx, 20, "garlic", 10)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ids(results)).To(Equal([]int64{3}))
+	})
+
+	It("returns at most the limit", func() {
+		results, err := index.Search(ctx, 10, "garlic", 1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ids(results)).To(Equal([]int64{1}))
+	})
+
+	It("replaces a recipe that is put again", func() {
+		Expect(index.Put(ctx, &search.Document{RecipeID: 2, UserID: 10, Name: "Roast Potatoes"})).To(Succeed())
+
+		results, err := index.Search(ctx, 10, "chicken", 10)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results).To(BeEmpty())
+
+		results, err = index.Search(ctx, 10, "potatoes", 10)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ids(results)).To(Equal([]int64{2}))
+	})
+
+	It("stops returning deleted recipes", func() {
+		Expect(index.Delete(ctx, 1)).To(Succeed())
+
+		results, err := index.Search(ctx, 10, "garlic", 10)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ids(results)).To(Equal([]int64{2}))
+	})
+
+	It("returns no results if nothing matches", func() {
+		results, err := index.Search(ctx, 10, "saffron", 10)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results).To(BeEmpty())
+	})
+})
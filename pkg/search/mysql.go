@@ -0,0 +1,205 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+)
+
+// MySQLIndex searches recipes with the FULLTEXT indexes on their name and
+// description, their steps and their ingredients' names. MySQL keeps the
+// indexes up to date, so Put and Delete do nothing.
+type MySQLIndex struct {
+	db *sql.DB
+}
+
+func NewMySQLIndex(db *sql.DB) *MySQLIndex {
+	return &MySQLIndex{db: db}
+}
+
+func (i *MySQLIndex) Put(ctx context.Context, doc *Document) error {
+	return nil
+}
+
+func (i *MySQLIndex) Delete(ctx context.Context, recipeID int64) error {
+	return nil
+}
+
+func (i *MySQLIndex) Search(ctx context.Context, userID int64, query string, limit int) ([]*Result, error) {
+	rows, err := i.db.QueryContext(ctx, searchQuery,
+		query, userID, query,
+		query, userID, query,
+		query, userID, query,
+		limit,
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to search recipes", "error", err)
+		return nil, errors.New("failed to search recipes")
+	}
+	defer rows.Close()
+
+	var results []*Result
+	var recipeIDs []int64
+	for rows.Next() {
+		result := &Result{}
+		if err := rows.Scan(&result.RecipeID, &result.Name, &result.Description, &result.Score); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan search results", "error", err)
+			return nil, errors.New("failed to scan search results")
+		}
+
+		results = append(results, result)
+		recipeIDs = append(recipeIDs, result.RecipeID)
+	}
+	if rows.Err() != nil {
+		slog.ErrorContext(ctx, "Failed to loop through search results", "error", rows.Err())
+		return nil, errors.New("failed to retrieve search results")
+	}
+
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	docs, err := i.documents(ctx, recipeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := Terms(query)
+	for _, result := range results {
+		if doc, ok := docs[result.RecipeID]; ok {
+			result.Highlights = Highlights(doc, terms)
+		}
+	}
+
+	return results, nil
+}
+
+// Documents returns every recipe, to fill an index that does not read the
+// database itself.
+func (i *MySQLIndex) Documents(ctx context.Context) ([]*Document, error) {
+	docs, err := i.documents(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	all := make([]*Document, 0, len(docs))
+	for _, doc := range docs {
+		all = append(all, doc)
+	}
+
+	return all, nil
+}
+
+// documents loads the recipes with the given IDs, or every recipe if there
+// are none, along with their ingredients and steps in order.
+func (i *MySQLIndex) documents(ctx context.Context, recipeIDs []int64) (map[int64]*Document, error) {
+	var args []interface{}
+	where := func(column string) string {
+		if len(recipeIDs) == 0 {
+			return ""
+		}
+		return " WHERE " + column + " IN (?" + strings.Repeat(", ?", len(recipeIDs)-1) + ")"
+	}
+	for _, id := range recipeIDs {
+		args = append(args, id)
+	}
+
+	docs := make(map[int64]*Document)
+
+	err := i.each(ctx, "SELECT id, creator, name, description FROM recipes"+where("id"), args, func(rows *sql.Rows) error {
+		doc := &Document{}
+		if err := rows.Scan(&doc.RecipeID, &doc.UserID, &doc.Name, &doc.Description); err != nil {
+			return err
+		}
+
+		docs[doc.RecipeID] = doc
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = i.each(ctx, "SELECT ri.recipe_id, ing.name FROM recipe_ingredients AS ri "+
+		"JOIN ingredients AS ing ON ing.id=ri.ingredient_id"+where("ri.recipe_id")+
+		" ORDER BY ri.recipe_id, ri.ingredient_no", args, func(rows *sql.Rows) error {
+		var recipeID int64
+		var name string
+		if err := rows.Scan(&recipeID, &name); err != nil {
+			return err
+		}
+
+		if doc, ok := docs[recipeID]; ok {
+			doc.Ingredients = append(doc.Ingredients, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = i.each(ctx, "SELECT recipe_id, instructions FROM recipe_steps"+where("recipe_id")+" ORDER BY recipe_id, step_no", args, func(rows *sql.Rows) error {
+		var recipeID int64
+		var instructions string
+		if err := rows.Scan(&recipeID, &instructions); err != nil {
+			return err
+		}
+
+		if doc, ok := docs[recipeID]; ok {
+			doc.Steps = append(doc.Steps, instructions)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+func (i *MySQLIndex) each(ctx context.Context, query string, args []interface{}, scan func(*sql.Rows) error) error {
+	rows, err := i.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to fetch recipes to search", "error", err)
+		return errors.New("failed to fetch recipes to search")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan recipes to search", "error", err)
+			return errors.New("failed to scan recipes to search")
+		}
+	}
+	if rows.Err() != nil {
+		slog.ErrorContext(ctx, "Failed to loop through recipes to search", "error", rows.Err())
+		return errors.New("failed to retrieve recipes to search")
+	}
+
+	return nil
+}
+
+// searchQuery adds up how well each of the user's recipes matches in its
+// name and description, which count double, its steps and its ingredients.
+const searchQuery = `SELECT r.id, r.name, r.description, SUM(m.score) AS score FROM (
+    SELECT id AS recipe_id, MATCH (name, description) AGAINST (?) * 2 AS score
+    FROM recipes
+    WHERE creator=? AND MATCH (name, description) AGAINST (?)
+    UNION ALL
+    SELECT s.recipe_id, MATCH (s.instructions) AGAINST (?)
+    FROM recipe_steps AS s
+    JOIN recipes AS sr ON sr.id=s.recipe_id
+    WHERE sr.creator=? AND MATCH (s.instructions) AGAINST (?)
+    UNION ALL
+    SELECT ri.recipe_id, MATCH (ing.name) AGAINST (?)
+    FROM recipe_ingredients AS ri
+    JOIN ingredients AS ing ON ing.id=ri.ingredient_id
+    JOIN recipes AS ir ON ir.id=ri.recipe_id
+    WHERE ir.creator=? AND MATCH (ing.name) AGAINST (?)
+) AS m
+JOIN recipes AS r ON r.id=m.recipe_id
+GROUP BY r.id, r.name, r.description
+ORDER BY score DESC, r.id
+LIMIT ?
+`
@@ -0,0 +1,144 @@
+package search_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/search"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MySQLIndex", func() {
+	var (
+		db   *sql.DB
+		mock sqlmock.Sqlmock
+		ctx  context.Context
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		var err error
+		db, mock, err = sqlmock.New()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("Search", func() {
+		It("returns the ranked matches with highlights", func() {
+			mock.ExpectQuery("^SELECT r.id, r.name, r.description, SUM\\(m.score\\) AS score FROM .+ AGAINST .+ LIMIT \\?$").
+				WithArgs("garlic", 10, "garlic", "garlic", 10, "garlic", "garlic", 10, "garlic", 5).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "score"}).
+					AddRow(2, "Garlic Bread", "Crusty", 3.5).
+					AddRow(1, "Roast Chicken", "Sunday dinner", 1.25))
+
+			mock.ExpectQuery("^SELECT id, creator, name, description FROM recipes WHERE id IN \\(\\?, \\?\\)$").
+				WithArgs(2, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "creator", "name", "description"}).
+					AddRow(2, 10, "Garlic Bread", "Crusty").
+					AddRow(1, 10, "Roast Chicken", "Sunday dinner"))
+
+			mock.ExpectQuery("^SELECT ri.recipe_id, ing.name FROM recipe_ingredients AS ri .+ WHERE ri.recipe_id IN \\(\\?, \\?\\) ORDER BY .+$").
+				WithArgs(2, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"recipe_id", "name"}).
+					AddRow(1, "Chicken").
+					AddRow(1, "Garlic"))
+
+			mock.ExpectQuery("^SELECT recipe_id, instructions FROM recipe_steps WHERE recipe_id IN \\(\\?, \\?\\) ORDER BY .+$").
+				WithArgs(2, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"recipe_id", "instructions"}).
+					AddRow(2, "Toast the bread."))
+
+			index := search.NewMySQLIndex(db)
+			results, err := index.Search(ctx, 10, "garlic", 5)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(results).To(Equal([]*search.Result{{
+				RecipeID:    2,
+				Name:        "Garlic Bread",
+				Description: "Crusty",
+				Score:       3.5,
+				Highlights: []*search.Highlight{
+					{Field: search.FieldName, Text: "<mark>Garlic</mark> Bread"},
+				},
+			}, {
+				RecipeID:    1,
+				Name:        "Roast Chicken",
+				Description: "Sunday dinner",
+				Score:       1.25,
+				Highlights: []*search.Highlight{
+					{Field: search.FieldIngredient, Text: "<mark>Garlic</mark>"},
+				},
+			}}))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns no results without loading any recipes if nothing matches", func() {
+			mock.ExpectQuery("^SELECT r.id").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "score"}))
+
+			index := search.NewMySQLIndex(db)
+			results, err := index.Search(ctx, 10, "saffron", 5)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(BeEmpty())
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the search fails", func() {
+			mock.ExpectQuery("^SELECT r.id").
+				WillReturnError(errors.New("some error"))
+
+			index := search.NewMySQLIndex(db)
+			_, err := index.Search(ctx, 10, "garlic", 5)
+			Expect(err).To(MatchError("failed to search recipes"))
+		})
+	})
+
+	Describe("Documents", func() {
+		It("loads every recipe with its ingredients and steps", func() {
+			mock.ExpectQuery("^SELECT id, creator, name, description FROM recipes$").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "creator", "name", "description"}).
+					AddRow(1, 10, "Roast Chicken", "Sunday dinner"))
+
+			mock.ExpectQuery("^SELECT ri.recipe_id, ing.name FROM recipe_ingredients AS ri JOIN ingredients AS ing ON ing.id=ri.ingredient_id ORDER BY ri.recipe_id, ri.ingredient_no$").
+				WillReturnRows(sqlmock.NewRows([]string{"recipe_id", "name"}).
+					AddRow(1, "Chicken").
+					AddRow(1, "Lemon"))
+
+			mock.ExpectQuery("^SELECT recipe_id, instructions FROM recipe_steps ORDER BY recipe_id, step_no$").
+				WillReturnRows(sqlmock.NewRows([]string{"recipe_id", "instructions"}).
+					AddRow(1, "Stuff the chicken.").
+					AddRow(1, "Roast."))
+
+			index := search.NewMySQLIndex(db)
+			docs, err := index.Documents(ctx)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(docs).To(Equal([]*search.Document{{
+				RecipeID:    1,
+				UserID:      10,
+				Name:        "Roast Chicken",
+				Description: "Sunday dinner",
+				Ingredients: []string{"Chicken", "Lemon"},
+				Steps:       []string{"Stuff the chicken.", "Roast."},
+			}}))
+
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the recipes cannot be loaded", func() {
+			mock.ExpectQuery("^SELECT id, creator, name, description FROM recipes$").
+				WillReturnError(errors.New("some error"))
+
+			index := search.NewMySQLIndex(db)
+			_, err := index.Documents(ctx)
+			Expect(err).To(MatchError("failed to fetch recipes to search"))
+		})
+	})
+})
@@ -0,0 +1,198 @@
+package search
+
+import (
+	"context"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Field names the part of a recipe a highlight comes from.
+type Field string
+
+const (
+	FieldName        Field = "name"
+	FieldDescription Field = "description"
+	FieldIngredient  Field = "ingredient"
+	FieldStep        Field = "step"
+)
+
+// snippetLength is roughly how many characters of a long text are shown
+// around its first match.
+const snippetLength = 160
+
+// Document is the searchable text of a recipe.
+type Document struct {
+	RecipeID    int64
+	UserID      int64
+	Name        string
+	Description string
+	Ingredients []string
+	Steps       []string
+}
+
+// Result is a recipe that matched a search. Results are returned best match
+// first.
+type Result struct {
+	RecipeID    int64
+	Name        string
+	Description string
+	Score       float64
+	Highlights  []*Highlight
+}
+
+// Highlight is text from a recipe that matched a search. Text is HTML
+// escaped, with the matching words wrapped in <mark> tags, and long texts are
+// cut down to a snippet around the first match.
+type Highlight struct {
+	Field Field
+	Text  string
+}
+
+// Index finds a user's recipes by the words in them. Put and Delete keep the
+// index in step with the recipes; indexes that read the database directly
+// can ignore them.
+type Index interface {
+	Search(ctx context.Context, userID int64, query string, limit int) ([]*Result, error)
+	Put(ctx context.Context, doc *Document) error
+	Delete(ctx context.Context, recipeID int64) error
+}
+
+type token struct {
+	text       string
+	start, end int
+}
+
+// tokenize splits text into lower case words made of letters and digits,
+// keeping where each word is in the text.
+func tokenize(text string) []token {
+	var tokens []token
+
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start < 0 {
+			start = i
+		}
+		if !isWord && start >= 0 {
+			tokens = append(tokens, token{text: strings.ToLower(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{text: strings.ToLower(text[start:]), start: start, end: len(text)})
+	}
+
+	return tokens
+}
+
+// Terms returns the distinct words of a query.
+func Terms(query string) []string {
+	seen := make(map[string]bool)
+
+	var terms []string
+	for _, t := range tokenize(query) {
+		if !seen[t.text] {
+			seen[t.text] = true
+			terms = append(terms, t.text)
+		}
+	}
+
+	return terms
+}
+
+// Highlights returns the highlighted text of each field of the document that
+// contains one of the terms.
+func Highlights(doc *Document, terms []string) []*Highlight {
+	var highlights []*Highlight
+
+	add := func(field Field, text string) {
+		if marked, ok := highlight(text, terms); ok {
+			highlights = append(highlights, &Highlight{Field: field, Text: marked})
+		}
+	}
+
+	add(FieldName, doc.Name)
+	add(FieldDescription, doc.Description)
+	for _, ingredient := range doc.Ingredients {
+		add(FieldIngredient, ingredient)
+	}
+	for _, step := range doc.Steps {
+		add(FieldStep, step)
+	}
+
+	return highlights
+}
+
+func highlight(text string, terms []string) (string, bool) {
+	var matches []token
+	for _, t := range tokenize(text) {
+		for _, term := range terms {
+			if t.text == term {
+				matches = append(matches, t)
+				break
+			}
+		}
+	}
+
+	if len(matches) == 0 {
+		return "", false
+	}
+
+	start, end := snippet(text, matches[0])
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+
+	last := start
+	for _, match := range matches {
+		if match.start < start || match.end > end {
+			continue
+		}
+
+		b.WriteString(html.EscapeString(text[last:match.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[match.start:match.end]))
+		b.WriteString("</mark>")
+		last = match.end
+	}
+	b.WriteString(html.EscapeString(text[last:end]))
+
+	if end < len(text) {
+		b.WriteString("…")
+	}
+
+	return b.String(), true
+}
+
+// snippet returns the part of text to show for a match, starting a little
+// before it and breaking at spaces.
+func snippet(text string, match token) (int, int) {
+	if len(text) <= snippetLength {
+		return 0, len(text)
+	}
+
+	start := match.start - snippetLength/4
+	if start <= 0 {
+		start = 0
+	} else if space := strings.IndexByte(text[start:match.start], ' '); space >= 0 {
+		start += space + 1
+	} else {
+		start = match.start
+	}
+
+	end := start + snippetLength
+	if end < match.end {
+		end = match.end
+	}
+	if end >= len(text) {
+		return start, len(text)
+	}
+	if space := strings.LastIndexByte(text[match.end:end], ' '); space >= 0 {
+		end = match.end + space
+	}
+
+	return start, end
+}
@@ -0,0 +1,67 @@
+package search_test
+
+import (
+	"strings"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/search"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Search", func() {
+	Describe("Terms", func() {
+		It("returns the distinct lower case words of a query", func() {
+			Expect(search.Terms("Crème brûlée, CRÈME & 2 eggs!")).To(Equal([]string{"crème", "brûlée", "2", "eggs"}))
+		})
+
+		It("returns no terms for a query without words", func() {
+			Expect(search.Terms(" -- ")).To(BeEmpty())
+		})
+	})
+
+	Describe("Highlights", func() {
+		It("marks the matching words in each field they are in", func() {
+			doc := &search.Document{
+				Name:        "Garlic Bread",
+				Description: "Crusty bread",
+				Ingredients: []string{"Garlic", "Butter"},
+				Steps:       []string{"Mix the butter and garlic.", "Bake."},
+			}
+
+			Expect(search.Highlights(doc, []string{"garlic"})).To(Equal([]*search.Highlight{
+				{Field: search.FieldName, Text: "<mark>Garlic</mark> Bread"},
+				{Field: search.FieldIngredient, Text: "<mark>Garlic</mark>"},
+				{Field: search.FieldStep, Text: "Mix the butter and <mark>garlic</mark>."},
+			}))
+		})
+
+		It("escapes the text around the matches", func() {
+			doc := &search.Document{Name: "Mac <b>&</b> Cheese"}
+
+			Expect(search.Highlights(doc, []string{"cheese"})).To(Equal([]*search.Highlight{
+				{Field: search.FieldName, Text: "Mac &lt;b&gt;&amp;&lt;/b&gt; <mark>Cheese</mark>"},
+			}))
+		})
+
+		It("does not match part of a word", func() {
+			doc := &search.Document{Name: "Pineapple"}
+
+			Expect(search.Highlights(doc, []string{"apple"})).To(BeEmpty())
+		})
+
+		It("cuts long text down to a snippet around the first match", func() {
+			step := strings.Repeat("stir the pot ", 30) + "then add the saffron " + strings.Repeat("and simmer ", 30)
+			doc := &search.Document{Steps: []string{step}}
+
+			highlights := search.Highlights(doc, []string{"saffron"})
+			Expect(highlights).To(HaveLen(1))
+
+			text := highlights[0].Text
+			Expect(text).To(HavePrefix("…"))
+			Expect(text).To(HaveSuffix("…"))
+			Expect(text).To(ContainSubstring("then add the <mark>saffron</mark> and simmer"))
+			Expect(len(text)).To(BeNumerically("<", len(step)/2))
+		})
+	})
+})
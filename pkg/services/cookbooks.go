@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+)
+
+type CookbooksRepositoryInterface interface {
+	List(ctx context.Context, userID int64) ([]*repositories.Cookbook, error)
+	GetOwner(ctx context.Context, id int64) (int64, error)
+	Insert(ctx context.Context, userID int64, name string) (int64, error)
+	Rename(ctx context.Context, id int64, name string) error
+	Delete(ctx context.Context, id int64) error
+	ListSections(ctx context.Context, userID int64) ([]*repositories.Section, error)
+	GetSectionCookbook(ctx context.Context, sectionID int64) (int64, error)
+	InsertSection(ctx context.Context, cookbookID int64, name string) (int64, error)
+	RenameSection(ctx context.Context, id int64, name string) error
+	DeleteSection(ctx context.Context, id int64) error
+	AddRecipe(ctx context.Context, recipeID, cookbookID int64, sectionID *int64) error
+	RemoveRecipe(ctx context.Context, recipeID, cookbookID int64) error
+}
+
+type RecipeCreatorRepositoryInterface interface {
+	GetCreator(ctx context.Context, id int64) (int64, error)
+}
+
+type CookbookService struct {
+	cookbooksRepo CookbooksRepositoryInterface
+	recipesRepo   RecipeCreatorRepositoryInterface
+}
+
+func NewCookbookService(
+	cookbooksRepo CookbooksRepositoryInterface,
+	recipesRepo RecipeCreatorRepositoryInterface,
+) *CookbookService {
+	return &CookbookService{
+		cookbooksRepo: cookbooksRepo,
+		recipesRepo:   recipesRepo,
+	}
+}
+
+type CookbookDetail struct {
+	ID       int64
+	Name     string
+	Sections []*SectionDetail
+}
+
+type SectionDetail struct {
+	ID   int64
+	Name string
+}
+
+func (s *CookbookService) ListCookbooks(ctx context.Context, userID int64) ([]*CookbookDetail, error) {
+	cookbooks, err := s.cookbooksRepo.List(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sections, err := s.cookbooksRepo.ListSections(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	details := make([]*CookbookDetail, len(cookbooks))
+	byID := make(map[int64]*CookbookDetail, len(cookbooks))
+	for i, cookbook := range cookbooks {
+		details[i] = &CookbookDetail{
+			ID:       *cookbook.ID,
+			Name:     *cookbook.Name,
+			Sections: []*SectionDetail{},
+		}
+		byID[*cookbook.ID] = details[i]
+	}
+
+	for _, section := range sections {
+		cookbook, ok := byID[*section.CookbookID]
+		if !ok {
+			continue
+		}
+
+		cookbook.Sections = append(cookbook.Sections, &SectionDetail{
+			ID:   *section.ID,
+			Name: *section.Name,
+		})
+	}
+
+	return details, nil
+}
+
+func (s *CookbookService) CreateCookbook(ctx context.Context, userID int64, name string) (int64, error) {
+	return s.cookbooksRepo.Insert(ctx, userID, name)
+}
+
+func (s *CookbookService) RenameCookbook(ctx context.Context, cookbookID, userID int64, name string) error {
+	err := s.checkCookbookOwnership(ctx, cookbookID, userID)
+	if err != nil {
+		return err
+	}
+
+	return notFound(s.cookbooksRepo.Rename(ctx, cookbookID, name))
+}
+
+func (s *CookbookService) DeleteCookbook(ctx context.Context, cookbookID, userID int64) error {
+	err := s.checkCookbookOwnership(ctx, cookbookID, userID)
+	if err != nil {
+		return err
+	}
+
+	return notFound(s.cookbooksRepo.Delete(ctx, cookbookID))
+}
+
+func (s *CookbookService) CreateSection(ctx context.Context, cookbookID, userID int64, name string) (int64, error) {
+	err := s.checkCookbookOwnership(ctx, cookbookID, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.cookbooksRepo.InsertSection(ctx, cookbookID, name)
+}
+
+func (s *CookbookService) RenameSection(ctx context.Context, cookbookID, sectionID, userID int64, name string) error {
+	err := s.checkSectionOwnership(ctx, cookbookID, sectionID, userID)
+	if err != nil {
+		return err
+	}
+
+	return notFound(s.cookbooksRepo.RenameSection(ctx, sectionID, name))
+}
+
+func (s *CookbookService) DeleteSection(ctx context.Context, cookbookID, sectionID, userID int64) error {
+	err := s.checkSectionOwnership(ctx, cookbookID, sectionID, userID)
+	if err != nil {
+		return err
+	}
+
+	return notFound(s.cookbooksRepo.DeleteSection(ctx, sectionID))
+}
+
+// AddRecipe files one of the user's recipes into one of their cookbooks,
+// optionally within a section of that cookbook.
+func (s *CookbookService) AddRecipe(ctx context.Context, cookbookID, userID, recipeID int64, sectionID *int64) error {
+	var err error
+	if sectionID != nil {
+		err = s.checkSectionOwnership(ctx, cookbookID, *sectionID, userID)
+	} else {
+		err = s.checkCookbookOwnership(ctx, cookbookID, userID)
+	}
+	if err != nil {
+		return err
+	}
+
+	creator, err := s.recipesRepo.GetCreator(ctx, recipeID)
+	if err != nil {
+		return notFound(err)
+	}
+
+	if creator != userID {
+		return ErrForbidden
+	}
+
+	return notFound(s.cookbooksRepo.AddRecipe(ctx, recipeID, cookbookID, sectionID))
+}
+
+func (s *CookbookService) RemoveRecipe(ctx context.Context, cookbookID, userID, recipeID int64) error {
+	err := s.checkCookbookOwnership(ctx, cookbookID, userID)
+	if err != nil {
+		return err
+	}
+
+	return notFound(s.cookbooksRepo.RemoveRecipe(ctx, recipeID, cookbookID))
+}
+
+func (s *CookbookService) checkCookbookOwnership(ctx context.Context, cookbookID, userID int64) error {
+	owner, err := s.cookbooksRepo.GetOwner(ctx, cookbookID)
+	if err != nil {
+		return notFound(err)
+	}
+
+	if owner != userID {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+func (s *CookbookService) checkSectionOwnership(ctx context.Context, cookbookID, sectionID, userID int64) error {
+	err := s.checkCookbookOwnership(ctx, cookbookID, userID)
+	if err != nil {
+		return err
+	}
+
+	sectionCookbookID, err := s.cookbooksRepo.GetSectionCookbook(ctx, sectionID)
+	if err != nil {
+		return notFound(err)
+	}
+
+	if sectionCookbookID != cookbookID {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,401 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/helpers"
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CookbookService", func() {
+	var (
+		cookbookService   *services.CookbookService
+		mockCookbooksRepo *MockCookbooksRepository
+		mockRecipesRepo   *MockRecipesRepository
+		ctx               context.Context
+		userID            int64
+		cookbookID        int64
+		sectionID         int64
+		recipeID          int64
+	)
+
+	BeforeEach(func() {
+		mockCookbooksRepo = &MockCookbooksRepository{}
+		mockRecipesRepo = &MockRecipesRepository{}
+		cookbookService = services.NewCookbookService(mockCookbooksRepo, mockRecipesRepo)
+
+		ctx = context.Background()
+		userID = 1
+		cookbookID = 10
+		sectionID = 20
+		recipeID = 30
+
+		mockCookbooksRepo.GetOwnerFunc = func(ctx context.Context, id int64) (int64, error) {
+			return userID, nil
+		}
+		mockCookbooksRepo.GetSectionCookbookFunc = func(ctx context.Context, id int64) (int64, error) {
+			return cookbookID, nil
+		}
+	})
+
+	Describe("ListCookbooks", func() {
+		It("groups the sections under their cookbooks", func() {
+			mockCookbooksRepo.ListFunc = func(ctx context.Context, uID int64) ([]*repositories.Cookbook, error) {
+				Expect(uID).To(Equal(userID))
+				return []*repositories.Cookbook{{
+					ID:   helpers.Int64Pointer(1),
+					Name: helpers.StringPointer("Desserts"),
+				}, {
+					ID:   helpers.Int64Pointer(2),
+					Name: helpers.StringPointer("Drinks"),
+				}}, nil
+			}
+
+			mockCookbooksRepo.ListSectionsFunc = func(ctx context.Context, uID int64) ([]*repositories.Section, error) {
+				Expect(uID).To(Equal(userID))
+				return []*repositories.Section{{
+					ID:         helpers.Int64Pointer(5),
+					CookbookID: helpers.Int64Pointer(1),
+					Name:       helpers.StringPointer("Cakes"),
+				}, {
+					ID:         helpers.Int64Pointer(6),
+					CookbookID: helpers.Int64Pointer(1),
+					Name:       helpers.StringPointer("Pies"),
+				}}, nil
+			}
+
+			cookbooks, err := cookbookService.ListCookbooks(ctx, userID)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cookbooks).To(Equal([]*services.CookbookDetail{{
+				ID:   1,
+				Name: "Desserts",
+				Sections: []*services.SectionDetail{
+					{ID: 5, Name: "Cakes"},
+					{ID: 6, Name: "Pies"},
+				},
+			}, {
+				ID:       2,
+				Name:     "Drinks",
+				Sections: []*services.SectionDetail{},
+			}}))
+		})
+
+		It("returns an error if the cookbooks cannot be listed", func() {
+			mockCookbooksRepo.ListFunc = func(ctx context.Context, uID int64) ([]*repositories.Cookbook, error) {
+				return nil, errors.New("failed to fetch cookbooks")
+			}
+
+			_, err := cookbookService.ListCookbooks(ctx, userID)
+			Expect(err).To(MatchError("failed to fetch cookbooks"))
+		})
+
+		It("returns an error if the sections cannot be listed", func() {
+			mockCookbooksRepo.ListSectionsFunc = func(ctx context.Context, uID int64) ([]*repositories.Section, error) {
+				return nil, errors.New("failed to fetch sections")
+			}
+
+			_, err := cookbookService.ListCookbooks(ctx, userID)
+			Expect(err).To(MatchError("failed to fetch sections"))
+		})
+	})
+
+	Describe("RenameCookbook", func() {
+		It("renames a cookbook owned by the user", func() {
+			var renamed string
+			mockCookbooksRepo.RenameFunc = func(ctx context.Context, id int64, name string) error {
+				Expect(id).To(Equal(cookbookID))
+				renamed = name
+				return nil
+			}
+
+			err := cookbookService.RenameCookbook(ctx, cookbookID, userID, "Sweets")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(renamed).To(Equal("Sweets"))
+		})
+
+		It("returns a forbidden error if the cookbook belongs to another user", func() {
+			mockCookbooksRepo.GetOwnerFunc = func(ctx context.Context, id int64) (int64, error) {
+				return userID + 1, nil
+			}
+			mockCookbooksRepo.RenameFunc = func(ctx context.Context, id int64, name string) error {
+				Fail("rename should not be called")
+				return nil
+			}
+
+			err := cookbookService.RenameCookbook(ctx, cookbookID, userID, "Sweets")
+			Expect(err).To(MatchError(services.ErrForbidden))
+		})
+
+		It("returns the not found error if the cookbook does not exist", func() {
+			mockCookbooksRepo.GetOwnerFunc = func(ctx context.Context, id int64) (int64, error) {
+				return -1, sql.ErrNoRows
+			}
+
+			err := cookbookService.RenameCookbook(ctx, cookbookID, userID, "Sweets")
+			Expect(err).To(MatchError(services.ErrNotFound))
+			Expect(err).To(MatchError(sql.ErrNoRows))
+		})
+	})
+
+	Describe("DeleteCookbook", func() {
+		It("deletes a cookbook owned by the user", func() {
+			deleted := false
+			mockCookbooksRepo.DeleteFunc = func(ctx context.Context, id int64) error {
+				Expect(id).To(Equal(cookbookID))
+				deleted = true
+				return nil
+			}
+
+			err := cookbookService.DeleteCookbook(ctx, cookbookID, userID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deleted).To(BeTrue())
+		})
+
+		It("returns a forbidden error if the cookbook belongs to another user", func() {
+			mockCookbooksRepo.GetOwnerFunc = func(ctx context.Context, id int64) (int64, error) {
+				return userID + 1, nil
+			}
+			mockCookbooksRepo.DeleteFunc = func(ctx context.Context, id int64) error {
+				Fail("delete should not be called")
+				return nil
+			}
+
+			err := cookbookService.DeleteCookbook(ctx, cookbookID, userID)
+			Expect(err).To(MatchError(services.ErrForbidden))
+		})
+	})
+
+	Describe("CreateSection", func() {
+		It("creates a section in a cookbook owned by the user", func() {
+			mockCookbooksRepo.InsertSectionFunc = func(ctx context.Context, cID int64, name string) (int64, error) {
+				Expect(cID).To(Equal(cookbookID))
+				Expect(name).To(Equal("Cakes"))
+				return sectionID, nil
+			}
+
+			id, err := cookbookService.CreateSection(ctx, cookbookID, userID, "Cakes")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(id).To(Equal(sectionID))
+		})
+
+		It("returns a forbidden error if the cookbook belongs to another user", func() {
+			mockCookbooksRepo.GetOwnerFunc = func(ctx context.Context, id int64) (int64, error) {
+				return userID + 1, nil
+			}
+
+			_, err := cookbookService.CreateSection(ctx, cookbookID, userID, "Cakes")
+			Expect(err).To(MatchError(services.ErrForbidden))
+		})
+	})
+
+	Describe("DeleteSection", func() {
+		It("deletes a section of a cookbook owned by the user", func() {
+			deleted := false
+			mockCookbooksRepo.DeleteSectionFunc = func(ctx context.Context, id int64) error {
+				Expect(id).To(Equal(sectionID))
+				deleted = true
+				return nil
+			}
+
+			err := cookbookService.DeleteSection(ctx, cookbookID, sectionID, userID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deleted).To(BeTrue())
+		})
+
+		It("returns the not found error if the section is in a different cookbook", func() {
+			mockCookbooksRepo.GetSectionCookbookFunc = func(ctx context.Context, id int64) (int64, error) {
+				return cookbookID + 1, nil
+			}
+			mockCookbooksRepo.DeleteSectionFunc = func(ctx context.Context, id int64) error {
+				Fail("delete should not be called")
+				return nil
+			}
+
+			err := cookbookService.DeleteSection(ctx, cookbookID, sectionID, userID)
+			Expect(err).To(MatchError(services.ErrNotFound))
+		})
+	})
+
+	Describe("AddRecipe", func() {
+		BeforeEach(func() {
+			mockRecipesRepo.GetCreatorFunc = func(ctx context.Context, id int64) (int64, error) {
+				return userID, nil
+			}
+		})
+
+		It("files the recipe into the section", func() {
+			var filedSectionID *int64
+			mockCookbooksRepo.AddRecipeFunc = func(ctx context.Context, rID, cID int64, sID *int64) error {
+				Expect(rID).To(Equal(recipeID))
+				Expect(cID).To(Equal(cookbookID))
+				filedSectionID = sID
+				return nil
+			}
+
+			err := cookbookService.AddRecipe(ctx, cookbookID, userID, recipeID, &sectionID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*filedSectionID).To(Equal(sectionID))
+		})
+
+		It("files the recipe without a section", func() {
+			mockCookbooksRepo.GetSectionCookbookFunc = func(ctx context.Context, id int64) (int64, error) {
+				Fail("section should not be looked up")
+				return -1, nil
+			}
+
+			err := cookbookService.AddRecipe(ctx, cookbookID, userID, recipeID, nil)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns a forbidden error if the recipe belongs to another user", func() {
+			mockRecipesRepo.GetCreatorFunc = func(ctx context.Context, id int64) (int64, error) {
+				return userID + 1, nil
+			}
+			mockCookbooksRepo.AddRecipeFunc = func(ctx context.Context, rID, cID int64, sID *int64) error {
+				Fail("add should not be called")
+				return nil
+			}
+
+			err := cookbookService.AddRecipe(ctx, cookbookID, userID, recipeID, nil)
+			Expect(err).To(MatchError(services.ErrForbidden))
+		})
+
+		It("returns the not found error if the recipe does not exist", func() {
+			mockRecipesRepo.GetCreatorFunc = func(ctx context.Context, id int64) (int64, error) {
+				return -1, sql.ErrNoRows
+			}
+
+			err := cookbookService.AddRecipe(ctx, cookbookID, userID, recipeID, nil)
+			Expect(err).To(MatchError(services.ErrNotFound))
+			Expect(err).To(MatchError(sql.ErrNoRows))
+		})
+	})
+
+	Describe("RemoveRecipe", func() {
+		It("removes the recipe from a cookbook owned by the user", func() {
+			mockCookbooksRepo.RemoveRecipeFunc = func(ctx context.Context, rID, cID int64) error {
+				Expect(rID).To(Equal(recipeID))
+				Expect(cID).To(Equal(cookbookID))
+				return nil
+			}
+
+			err := cookbookService.RemoveRecipe(ctx, cookbookID, userID, recipeID)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns a forbidden error if the cookbook belongs to another user", func() {
+			mockCookbooksRepo.GetOwnerFunc = func(ctx context.Context, id int64) (int64, error) {
+				return userID + 1, nil
+			}
+
+			err := cookbookService.RemoveRecipe(ctx, cookbookID, userID, recipeID)
+			Expect(err).To(MatchError(services.ErrForbidden))
+		})
+	})
+})
+
+type MockCookbooksRepository struct {
+	ListFunc               func(ctx context.Context, userID int64) ([]*repositories.Cookbook, error)
+	GetOwnerFunc           func(ctx context.Context, id int64) (int64, error)
+	InsertFunc             func(ctx context.Context, userID int64, name string) (int64, error)
+	RenameFunc             func(ctx context.Context, id int64, name string) error
+	DeleteFunc             func(ctx context.Context, id int64) error
+	ListSectionsFunc       func(ctx context.Context, userID int64) ([]*repositories.Section, error)
+	GetSectionCookbookFunc func(ctx context.Context, sectionID int64) (int64, error)
+	InsertSectionFunc      func(ctx context.Context, cookbookID int64, name string) (int64, error)
+	RenameSectionFunc      func(ctx context.Context, id int64, name string) error
+	DeleteSectionFunc      func(ctx context.Context, id int64) error
+	AddRecipeFunc          func(ctx context.Context, recipeID, cookbookID int64, sectionID *int64) error
+	RemoveRecipeFunc       func(ctx context.Context, recipeID, cookbookID int64) error
+}
+
+func (m *MockCookbooksRepository) List(ctx context.Context, userID int64) ([]*repositories.Cookbook, error) {
+	if m.ListFunc != nil {
+		return m.ListFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *MockCookbooksRepository) GetOwner(ctx context.Context, id int64) (int64, error) {
+	if m.GetOwnerFunc != nil {
+		return m.GetOwnerFunc(ctx, id)
+	}
+	return -1, nil
+}
+
+func (m *MockCookbooksRepository) Insert(ctx context.Context, userID int64, name string) (int64, error) {
+	if m.InsertFunc != nil {
+		return m.InsertFunc(ctx, userID, name)
+	}
+	return 0, nil
+}
+
+func (m *MockCookbooksRepository) Rename(ctx context.Context, id int64, name string) error {
+	if m.RenameFunc != nil {
+		return m.RenameFunc(ctx, id, name)
+	}
+	return nil
+}
+
+func (m *MockCookbooksRepository) Delete(ctx context.Context, id int64) error {
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *MockCookbooksRepository) ListSections(ctx context.Context, userID int64) ([]*repositories.Section, error) {
+	if m.ListSectionsFunc != nil {
+		return m.ListSectionsFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *MockCookbooksRepository) GetSectionCookbook(ctx context.Context, sectionID int64) (int64, error) {
+	if m.GetSectionCookbookFunc != nil {
+		return m.GetSectionCookbookFunc(ctx, sectionID)
+	}
+	return -1, nil
+}
+
+func (m *MockCookbooksRepository) InsertSection(ctx context.Context, cookbookID int64, name string) (int64, error) {
+	if m.InsertSectionFunc != nil {
+		return m.InsertSectionFunc(ctx, cookbookID, name)
+	}
+	return 0, nil
+}
+
+func (m *MockCookbooksRepository) RenameSection(ctx context.Context, id int64, name string) error {
+	if m.RenameSectionFunc != nil {
+		return m.RenameSectionFunc(ctx, id, name)
+	}
+	return nil
+}
+
+func (m *MockCookbooksRepository) DeleteSection(ctx context.Context, id int64) error {
+	if m.DeleteSectionFunc != nil {
+		return m.DeleteSectionFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *MockCookbooksRepository) AddRecipe(ctx context.Context, recipeID, cookbookID int64, sectionID *int64) error {
+	if m.AddRecipeFunc != nil {
+		return m.AddRecipeFunc(ctx, recipeID, cookbookID, sectionID)
+	}
+	return nil
+}
+
+func (m *MockCookbooksRepository) RemoveRecipe(ctx context.Context, recipeID, cookbookID int64) error {
+	if m.RemoveRecipeFunc != nil {
+		return m.RemoveRecipeFunc(ctx, recipeID, cookbookID)
+	}
+	return nil
+}
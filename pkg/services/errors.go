@@ -0,0 +1,40 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrForbidden = errors.New("forbidden")
+
+	// ErrInvalidCursor is returned for a page cursor that was not returned
+	// by the listing it is used with.
+	ErrInvalidCursor = errors.New("invalid cursor")
+
+	// ErrNoServings is returned when scaling a recipe that does not say how
+	// many servings it makes.
+	ErrNoServings = errors.New("recipe has no servings")
+)
+
+// ErrConflict is returned when a value has to be unique and is already in
+// use. Field names the value, such as "username".
+type ErrConflict struct {
+	Field string
+}
+
+func (e *ErrConflict) Error() string {
+	return fmt.Sprintf("%s already exists", e.Field)
+}
+
+// notFound wraps the sql.ErrNoRows a repository returns for a missing row in
+// ErrNotFound. Other errors are returned as they are.
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+
+	return err
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+)
+
+const (
+	LoginFailuresAllowed   = 5
+	IPLoginFailuresAllowed = 50
+	LoginFailureWindow     = time.Hour
+	LoginLockoutBase       = 30 * time.Second
+	MaxLoginLockout        = 15 * time.Minute
+)
+
+type LoginAttemptsRepositoryInterface interface {
+	RecordLoginFailure(ctx context.Context, key string, window time.Duration) (int64, error)
+	LockLogin(ctx context.Context, key string, duration time.Duration) error
+	LoginLockRemaining(ctx context.Context, key string) (time.Duration, error)
+	ClearLoginFailures(ctx context.Context, key string) error
+}
+
+type ThrottleUsersRepositoryInterface interface {
+	GetByLogin(ctx context.Context, login string) (*repositories.User, error)
+}
+
+// LoginThrottleService slows down password guessing. Failed logins are
+// counted per login name and per IP address, and once either has failed too
+// often it is locked for a time that doubles with every further failure.
+type LoginThrottleService struct {
+	attemptsRepo LoginAttemptsRepositoryInterface
+	usersRepo    ThrottleUsersRepositoryInterface
+}
+
+func NewLoginThrottleService(
+	attemptsRepo LoginAttemptsRepositoryInterface,
+	usersRepo ThrottleUsersRepositoryInterface,
+) *LoginThrottleService {
+	return &LoginThrottleService{
+		attemptsRepo: attemptsRepo,
+		usersRepo:    usersRepo,
+	}
+}
+
+// RetryAfter returns how long until a login can be tried from an IP address,
+// or zero if it can be tried now.
+func (s *LoginThrottleService) RetryAfter(ctx context.Context, login, ip string) (time.Duration, error) {
+	var longest time.Duration
+	for _, key := range []string{loginKey(login), ipKey(ip)} {
+		remaining, err := s.attemptsRepo.LoginLockRemaining(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+
+		if remaining > longest {
+			longest = remaining
+		}
+	}
+
+	return longest, nil
+}
+
+func (s *LoginThrottleService) RecordFailure(ctx context.Context, login, ip string) error {
+	err := s.fail(ctx, loginKey(login), LoginFailuresAllowed)
+	if err != nil {
+		return err
+	}
+
+	return s.fail(ctx, ipKey(ip), IPLoginFailuresAllowed)
+}
+
+// RecordSuccess forgets the failures for a login name. Failures from the IP
+// address are kept, so logging into one account does not reset guessing at
+// others.
+func (s *LoginThrottleService) RecordSuccess(ctx context.Context, login string) error {
+	return s.attemptsRepo.ClearLoginFailures(ctx, loginKey(login))
+}
+
+// Unlock lifts the lock on an account. The account can be given by username
+// or email, and both are unlocked.
+func (s *LoginThrottleService) Unlock(ctx context.Context, login string) error {
+	logins := []string{login}
+
+	user, err := s.usersRepo.GetByLogin(ctx, login)
+	if err == nil {
+		logins = append(logins, user.Username, user.Email)
+	} else if err != sql.ErrNoRows {
+		return err
+	}
+
+	for _, l := range logins {
+		err = s.attemptsRepo.ClearLoginFailures(ctx, loginKey(l))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *LoginThrottleService) UnlockIP(ctx context.Context, ip string) error {
+	return s.attemptsRepo.ClearLoginFailures(ctx, ipKey(ip))
+}
+
+func (s *LoginThrottleService) fail(ctx context.Context, key string, allowed int64) error {
+	failures, err := s.attemptsRepo.RecordLoginFailure(ctx, key, LoginFailureWindow)
+	if err != nil {
+		return err
+	}
+
+	if failures < allowed {
+		return nil
+	}
+
+	return s.attemptsRepo.LockLogin(ctx, key, lockoutFor(failures-allowed))
+}
+
+// lockoutFor returns how long to lock a key that has failed the given number
+// of times more than it is allowed.
+func lockoutFor(excess int64) time.Duration {
+	lockout := LoginLockoutBase
+	for i := int64(0); i < excess && lockout < MaxLoginLockout; i++ {
+		lockout *= 2
+	}
+
+	if lockout > MaxLoginLockout {
+		return MaxLoginLockout
+	}
+
+	return lockout
+}
+
+func loginKey(login string) string {
+	return "login:" + strings.ToLower(login)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
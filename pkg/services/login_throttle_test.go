@@ -0,0 +1,234 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LoginThrottleService", func() {
+	var (
+		throttleService  *services.LoginThrottleService
+		mockAttemptsRepo *MockLoginAttemptsRepository
+		mockUsersRepo    *MockThrottleUsersRepository
+		ctx              context.Context
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		mockAttemptsRepo = &MockLoginAttemptsRepository{}
+		mockUsersRepo = &MockThrottleUsersRepository{}
+		throttleService = services.NewLoginThrottleService(mockAttemptsRepo, mockUsersRepo)
+	})
+
+	Describe("RetryAfter", func() {
+		It("returns the longest lock on the login or the IP", func() {
+			mockAttemptsRepo.LoginLockRemainingFunc = func(ctx context.Context, key string) (time.Duration, error) {
+				if key == "ip:192.0.2.10" {
+					return 2 * time.Minute, nil
+				}
+				Expect(key).To(Equal("login:cook"))
+				return time.Minute, nil
+			}
+
+			retryAfter, err := throttleService.RetryAfter(ctx, "Cook", "192.0.2.10")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(retryAfter).To(Equal(2 * time.Minute))
+		})
+
+		It("returns zero if neither is locked", func() {
+			retryAfter, err := throttleService.RetryAfter(ctx, "cook", "192.0.2.10")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(retryAfter).To(BeZero())
+		})
+
+		It("returns an error if the lock cannot be read", func() {
+			mockAttemptsRepo.LoginLockRemainingFunc = func(ctx context.Context, key string) (time.Duration, error) {
+				return 0, errors.New("redis error")
+			}
+
+			_, err := throttleService.RetryAfter(ctx, "cook", "192.0.2.10")
+			Expect(err).To(MatchError("redis error"))
+		})
+	})
+
+	Describe("RecordFailure", func() {
+		var (
+			failures map[string]int64
+			locks    map[string]time.Duration
+		)
+
+		BeforeEach(func() {
+			failures = make(map[string]int64)
+			locks = make(map[string]time.Duration)
+
+			mockAttemptsRepo.RecordLoginFailureFunc = func(ctx context.Context, key string, window time.Duration) (int64, error) {
+				Expect(window).To(Equal(services.LoginFailureWindow))
+				failures[key]++
+				return failures[key], nil
+			}
+			mockAttemptsRepo.LockLoginFunc = func(ctx context.Context, key string, duration time.Duration) error {
+				locks[key] = duration
+				return nil
+			}
+		})
+
+		It("locks a login with a lockout that doubles with each failure", func() {
+			for i := 1; i < services.LoginFailuresAllowed; i++ {
+				err := throttleService.RecordFailure(ctx, "cook", "192.0.2.10")
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(locks).To(BeEmpty())
+
+			expected := []time.Duration{
+				30 * time.Second,
+				time.Minute,
+				2 * time.Minute,
+				4 * time.Minute,
+				8 * time.Minute,
+				services.MaxLoginLockout,
+				services.MaxLoginLockout,
+			}
+			for _, lockout := range expected {
+				err := throttleService.RecordFailure(ctx, "cook", "192.0.2.10")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(locks["login:cook"]).To(Equal(lockout))
+			}
+
+			Expect(failures["ip:192.0.2.10"]).To(Equal(int64(services.LoginFailuresAllowed + len(expected) - 1)))
+			Expect(locks).ToNot(HaveKey("ip:192.0.2.10"))
+		})
+
+		It("locks an IP that fails on many logins", func() {
+			failures["ip:192.0.2.10"] = services.IPLoginFailuresAllowed - 1
+
+			err := throttleService.RecordFailure(ctx, "someone-else", "192.0.2.10")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(locks).To(Equal(map[string]time.Duration{
+				"ip:192.0.2.10": services.LoginLockoutBase,
+			}))
+		})
+
+		It("returns an error if the failure cannot be counted", func() {
+			mockAttemptsRepo.RecordLoginFailureFunc = func(ctx context.Context, key string, window time.Duration) (int64, error) {
+				return 0, errors.New("redis error")
+			}
+
+			err := throttleService.RecordFailure(ctx, "cook", "192.0.2.10")
+			Expect(err).To(MatchError("redis error"))
+		})
+	})
+
+	Describe("RecordSuccess", func() {
+		It("clears the failures for the login", func() {
+			var cleared []string
+			mockAttemptsRepo.ClearLoginFailuresFunc = func(ctx context.Context, key string) error {
+				cleared = append(cleared, key)
+				return nil
+			}
+
+			err := throttleService.RecordSuccess(ctx, "Cook")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cleared).To(Equal([]string{"login:cook"}))
+		})
+	})
+
+	Describe("Unlock", func() {
+		var cleared []string
+
+		BeforeEach(func() {
+			cleared = nil
+			mockAttemptsRepo.ClearLoginFailuresFunc = func(ctx context.Context, key string) error {
+				cleared = append(cleared, key)
+				return nil
+			}
+		})
+
+		It("unlocks both the username and the email of the account", func() {
+			mockUsersRepo.GetByLoginFunc = func(ctx context.Context, login string) (*repositories.User, error) {
+				Expect(login).To(Equal("cook"))
+				return &repositories.User{ID: 10, Username: "Cook", Email: "cook@example.com"}, nil
+			}
+
+			err := throttleService.Unlock(ctx, "cook")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cleared).To(ConsistOf("login:cook", "login:cook", "login:cook@example.com"))
+		})
+
+		It("unlocks the login even if there is no such account", func() {
+			err := throttleService.Unlock(ctx, "missing")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cleared).To(Equal([]string{"login:missing"}))
+		})
+
+		It("returns an error if the account cannot be looked up", func() {
+			mockUsersRepo.GetByLoginFunc = func(ctx context.Context, login string) (*repositories.User, error) {
+				return nil, errors.New("db error")
+			}
+
+			err := throttleService.Unlock(ctx, "cook")
+			Expect(err).To(MatchError("db error"))
+			Expect(cleared).To(BeEmpty())
+		})
+
+		It("unlocks an IP address", func() {
+			err := throttleService.UnlockIP(ctx, "192.0.2.10")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cleared).To(Equal([]string{"ip:192.0.2.10"}))
+		})
+	})
+})
+
+type MockLoginAttemptsRepository struct {
+	RecordLoginFailureFunc func(ctx context.Context, key string, window time.Duration) (int64, error)
+	LockLoginFunc          func(ctx context.Context, key string, duration time.Duration) error
+	LoginLockRemainingFunc func(ctx context.Context, key string) (time.Duration, error)
+	ClearLoginFailuresFunc func(ctx context.Context, key string) error
+}
+
+func (m *MockLoginAttemptsRepository) RecordLoginFailure(ctx context.Context, key string, window time.Duration) (int64, error) {
+	if m.RecordLoginFailureFunc != nil {
+		return m.RecordLoginFailureFunc(ctx, key, window)
+	}
+	return 1, nil
+}
+
+func (m *MockLoginAttemptsRepository) LockLogin(ctx context.Context, key string, duration time.Duration) error {
+	if m.LockLoginFunc != nil {
+		return m.LockLoginFunc(ctx, key, duration)
+	}
+	return nil
+}
+
+func (m *MockLoginAttemptsRepository) LoginLockRemaining(ctx context.Context, key string) (time.Duration, error) {
+	if m.LoginLockRemainingFunc != nil {
+		return m.LoginLockRemainingFunc(ctx, key)
+	}
+	return 0, nil
+}
+
+func (m *MockLoginAttemptsRepository) ClearLoginFailures(ctx context.Context, key string) error {
+	if m.ClearLoginFailuresFunc != nil {
+		return m.ClearLoginFailuresFunc(ctx, key)
+	}
+	return nil
+}
+
+type MockThrottleUsersRepository struct {
+	GetByLoginFunc func(ctx context.Context, login string) (*repositories.User, error)
+}
+
+func (m *MockThrottleUsersRepository) GetByLogin(ctx context.Context, login string) (*repositories.User, error) {
+	if m.GetByLoginFunc != nil {
+		return m.GetByLoginFunc(ctx, login)
+	}
+	return nil, sql.ErrNoRows
+}
@@ -0,0 +1,314 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/oidc"
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+)
+
+const (
+	OIDCStateTTL = 10 * time.Minute
+
+	minUsernameLength    = 6
+	maxUsernameLength    = 30
+	maxUsernameAttempts  = 20
+	usernameSuffixLength = 3
+)
+
+var (
+	ErrInvalidOIDCState            = errors.New("invalid or expired oidc state")
+	ErrOIDCLoginFailed             = errors.New("identity provider login failed")
+	ErrOIDCEmailNotVerified        = errors.New("identity provider has not verified the email")
+	ErrOIDCEmailInUse              = errors.New("email belongs to an account that has not been linked")
+	ErrIdentityLinkedToAnotherUser = errors.New("identity is linked to another user")
+)
+
+type OIDCProviderInterface interface {
+	Issuer() string
+	AuthCodeURL(req *oidc.AuthRequest) string
+	Exchange(ctx context.Context, code string, req *oidc.AuthRequest) (*oidc.Identity, error)
+}
+
+type OIDCStateRepositoryInterface interface {
+	StoreOIDCState(ctx context.Context, state string, oidcState *repositories.OIDCState, ttl time.Duration) error
+	ConsumeOIDCState(ctx context.Context, state string) (*repositories.OIDCState, error)
+}
+
+type IdentitiesRepositoryInterface interface {
+	GetUserID(ctx context.Context, provider, subject string) (int64, error)
+	Link(ctx context.Context, userID int64, provider, subject string) (bool, error)
+}
+
+type OIDCUsersRepositoryInterface interface {
+	GetByEmail(ctx context.Context, email string) (*repositories.User, error)
+	ExistsByUsername(ctx context.Context, username string) (bool, error)
+	Insert(ctx context.Context, username, email, password string) (int64, error)
+	MarkEmailVerified(ctx context.Context, userID int64) error
+}
+
+type OIDCService struct {
+	provider       OIDCProviderInterface
+	stateRepo      OIDCStateRepositoryInterface
+	identitiesRepo IdentitiesRepositoryInterface
+	usersRepo      OIDCUsersRepositoryInterface
+}
+
+func NewOIDCService(
+	provider OIDCProviderInterface,
+	stateRepo OIDCStateRepositoryInterface,
+	identitiesRepo IdentitiesRepositoryInterface,
+	usersRepo OIDCUsersRepositoryInterface,
+) *OIDCService {
+	return &OIDCService{
+		provider:       provider,
+		stateRepo:      stateRepo,
+		identitiesRepo: identitiesRepo,
+		usersRepo:      usersRepo,
+	}
+}
+
+// StartLogin returns the URL to send the user to so they can log in with the
+// identity provider.
+func (s *OIDCService) StartLogin(ctx context.Context) (string, error) {
+	return s.start(ctx, 0)
+}
+
+// StartLink returns the URL to send a logged in user to so they can link an
+// identity to their account.
+func (s *OIDCService) StartLink(ctx context.Context, userID int64) (string, error) {
+	return s.start(ctx, userID)
+}
+
+// CompleteLogin exchanges the code the provider sent back for the local user
+// it belongs to. An identity that has not been seen before is linked to the
+// account with the same email if both sides have verified it, or to a new
+// account if there is no such account.
+func (s *OIDCService) CompleteLogin(ctx context.Context, state, code string) (int64, error) {
+	oidcState, identity, err := s.exchange(ctx, state, code)
+	if err != nil {
+		return -1, err
+	}
+
+	if oidcState.LinkUserID != 0 {
+		return -1, ErrInvalidOIDCState
+	}
+
+	userID, err := s.identitiesRepo.GetUserID(ctx, s.provider.Issuer(), identity.Subject)
+	if err == nil {
+		return userID, nil
+	}
+
+	if err != sql.ErrNoRows {
+		return -1, err
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return -1, ErrOIDCEmailNotVerified
+	}
+
+	user, err := s.usersRepo.GetByEmail(ctx, identity.Email)
+	if err == sql.ErrNoRows {
+		userID, err = s.createUser(ctx, identity)
+	} else if err == nil {
+		// Someone else could have registered with this email without
+		// owning it, so only an account that proved it owns the email
+		// can be linked automatically
+		if !user.EmailVerified {
+			return -1, ErrOIDCEmailInUse
+		}
+		userID = user.ID
+	}
+
+	if err != nil {
+		return -1, err
+	}
+
+	linked, err := s.identitiesRepo.Link(ctx, userID, s.provider.Issuer(), identity.Subject)
+	if err != nil {
+		return -1, err
+	}
+
+	if !linked {
+		// Another login linked the identity first
+		return s.identitiesRepo.GetUserID(ctx, s.provider.Issuer(), identity.Subject)
+	}
+
+	return userID, nil
+}
+
+// CompleteLink links the identity the provider sent back to the logged in
+// user who started the link.
+func (s *OIDCService) CompleteLink(ctx context.Context, userID int64, state, code string) error {
+	oidcState, identity, err := s.exchange(ctx, state, code)
+	if err != nil {
+		return err
+	}
+
+	if oidcState.LinkUserID != userID {
+		return ErrInvalidOIDCState
+	}
+
+	linked, err := s.identitiesRepo.Link(ctx, userID, s.provider.Issuer(), identity.Subject)
+	if err != nil {
+		return err
+	}
+
+	if linked {
+		return nil
+	}
+
+	linkedTo, err := s.identitiesRepo.GetUserID(ctx, s.provider.Issuer(), identity.Subject)
+	if err != nil {
+		return err
+	}
+
+	if linkedTo != userID {
+		return ErrIdentityLinkedToAnotherUser
+	}
+
+	return nil
+}
+
+func (s *OIDCService) start(ctx context.Context, linkUserID int64) (string, error) {
+	req, err := oidc.NewAuthRequest()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.stateRepo.StoreOIDCState(ctx, req.State, &repositories.OIDCState{
+		Nonce:        req.Nonce,
+		CodeVerifier: req.CodeVerifier,
+		LinkUserID:   linkUserID,
+	}, OIDCStateTTL)
+	if err != nil {
+		return "", err
+	}
+
+	return s.provider.AuthCodeURL(req), nil
+}
+
+func (s *OIDCService) exchange(ctx context.Context, state, code string) (*repositories.OIDCState, *oidc.Identity, error) {
+	oidcState, err := s.stateRepo.ConsumeOIDCState(ctx, state)
+	if err == repositories.ErrOIDCStateNotFound {
+		return nil, nil, ErrInvalidOIDCState
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	identity, err := s.provider.Exchange(ctx, code, &oidc.AuthRequest{
+		State:        state,
+		Nonce:        oidcState.Nonce,
+		CodeVerifier: oidcState.CodeVerifier,
+	})
+	if err == oidc.ErrInvalidGrant || err == oidc.ErrInvalidIDToken {
+		return nil, nil, ErrOIDCLoginFailed
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return oidcState, identity, nil
+}
+
+// createUser registers a new account for an identity. The account gets a
+// random password, which the user can replace with a password reset.
+func (s *OIDCService) createUser(ctx context.Context, identity *oidc.Identity) (int64, error) {
+	username, err := s.freeUsername(ctx, identity)
+	if err != nil {
+		return -1, err
+	}
+
+	password, err := randomPassword()
+	if err != nil {
+		return -1, err
+	}
+
+	userID, err := s.usersRepo.Insert(ctx, username, identity.Email, password)
+	if err != nil {
+		return -1, err
+	}
+
+	err = s.usersRepo.MarkEmailVerified(ctx, userID)
+	if err != nil {
+		return -1, err
+	}
+
+	return userID, nil
+}
+
+func (s *OIDCService) freeUsername(ctx context.Context, identity *oidc.Identity) (string, error) {
+	base := identity.PreferredUsername
+	if base == "" {
+		base = strings.SplitN(identity.Email, "@", 2)[0]
+	}
+	base = usernameFrom(base)
+
+	for attempt := 1; attempt <= maxUsernameAttempts; attempt++ {
+		candidate := base
+		if attempt > 1 {
+			candidate = base + "_" + strconv.Itoa(attempt)
+		}
+
+		exists, err := s.usersRepo.ExistsByUsername(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+
+		if !exists {
+			return candidate, nil
+		}
+	}
+
+	return "", errors.New("could not find a free username")
+}
+
+// usernameFrom turns a name from the provider into one that follows the
+// username rules, leaving room for a numeric suffix.
+func usernameFrom(name string) string {
+	var b strings.Builder
+	for _, ch := range name {
+		switch {
+		case ch < unicode.MaxASCII && (unicode.IsLetter(ch) || (b.Len() > 0 && (unicode.IsDigit(ch) || ch == '_'))):
+			b.WriteRune(ch)
+		case b.Len() > 0 && (ch == '.' || ch == '-'):
+			b.WriteRune('_')
+		}
+	}
+
+	username := b.String()
+	if len(username) > maxUsernameLength-usernameSuffixLength {
+		username = username[:maxUsernameLength-usernameSuffixLength]
+	}
+
+	if len(username) < minUsernameLength {
+		username = "user_" + username
+	}
+
+	if len(username) < minUsernameLength {
+		username += "cook"
+	}
+
+	return username
+}
+
+func randomPassword() (string, error) {
+	raw := make([]byte, 32)
+	_, err := rand.Read(raw)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
@@ -0,0 +1,399 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/oidc"
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OIDCService", func() {
+	var (
+		oidcService    *services.OIDCService
+		mockProvider   *MockOIDCProvider
+		mockStateRepo  *MockOIDCStateRepository
+		mockIdentities *MockIdentitiesRepository
+		mockUsersRepo  *MockOIDCUsersRepository
+		identity       *oidc.Identity
+		ctx            context.Context
+	)
+
+	BeforeEach(func() {
+		identity = &oidc.Identity{
+			Subject:           "subject-1",
+			Email:             "cook@example.com",
+			EmailVerified:     true,
+			PreferredUsername: "home.cook",
+		}
+
+		mockProvider = &MockOIDCProvider{
+			ExchangeFunc: func(ctx context.Context, code string, req *oidc.AuthRequest) (*oidc.Identity, error) {
+				return identity, nil
+			},
+		}
+		mockStateRepo = &MockOIDCStateRepository{
+			ConsumeOIDCStateFunc: func(ctx context.Context, state string) (*repositories.OIDCState, error) {
+				return &repositories.OIDCState{Nonce: "nonce", CodeVerifier: "verifier"}, nil
+			},
+		}
+		mockIdentities = &MockIdentitiesRepository{}
+		mockUsersRepo = &MockOIDCUsersRepository{}
+		oidcService = services.NewOIDCService(mockProvider, mockStateRepo, mockIdentities, mockUsersRepo)
+
+		ctx = context.Background()
+	})
+
+	Describe("StartLogin", func() {
+		It("stores the request state and returns the provider's URL", func() {
+			var stored *repositories.OIDCState
+			var storedKey string
+			mockStateRepo.StoreOIDCStateFunc = func(ctx context.Context, state string, oidcState *repositories.OIDCState, ttl time.Duration) error {
+				storedKey = state
+				stored = oidcState
+				Expect(ttl).To(Equal(services.OIDCStateTTL))
+				return nil
+			}
+
+			var requested *oidc.AuthRequest
+			mockProvider.AuthCodeURLFunc = func(req *oidc.AuthRequest) string {
+				requested = req
+				return "https://accounts.example.com/authorize?state=" + req.State
+			}
+
+			authURL, err := oidcService.StartLogin(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(authURL).To(Equal("https://accounts.example.com/authorize?state=" + requested.State))
+
+			Expect(storedKey).To(Equal(requested.State))
+			Expect(stored).To(Equal(&repositories.OIDCState{
+				Nonce:        requested.Nonce,
+				CodeVerifier: requested.CodeVerifier,
+			}))
+		})
+
+		It("remembers who is linking an identity", func() {
+			var stored *repositories.OIDCState
+			mockStateRepo.StoreOIDCStateFunc = func(ctx context.Context, state string, oidcState *repositories.OIDCState, ttl time.Duration) error {
+				stored = oidcState
+				return nil
+			}
+
+			_, err := oidcService.StartLink(ctx, 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stored.LinkUserID).To(Equal(int64(10)))
+		})
+	})
+
+	Describe("CompleteLogin", func() {
+		It("logs in the user an identity is linked to", func() {
+			mockProvider.ExchangeFunc = func(ctx context.Context, code string, req *oidc.AuthRequest) (*oidc.Identity, error) {
+				Expect(code).To(Equal("code"))
+				Expect(req).To(Equal(&oidc.AuthRequest{State: "state", Nonce: "nonce", CodeVerifier: "verifier"}))
+				return identity, nil
+			}
+
+			mockIdentities.GetUserIDFunc = func(ctx context.Context, provider, subject string) (int64, error) {
+				Expect(provider).To(Equal("https://accounts.example.com"))
+				Expect(subject).To(Equal("subject-1"))
+				return 10, nil
+			}
+
+			userID, err := oidcService.CompleteLogin(ctx, "state", "code")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(userID).To(Equal(int64(10)))
+		})
+
+		It("links a new identity to the verified account with the same email", func() {
+			mockUsersRepo.GetByEmailFunc = func(ctx context.Context, email string) (*repositories.User, error) {
+				return &repositories.User{ID: 10, Email: email, EmailVerified: true}, nil
+			}
+
+			var linkedTo int64
+			mockIdentities.LinkFunc = func(ctx context.Context, userID int64, provider, subject string) (bool, error) {
+				linkedTo = userID
+				return true, nil
+			}
+
+			userID, err := oidcService.CompleteLogin(ctx, "state", "code")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(userID).To(Equal(int64(10)))
+			Expect(linkedTo).To(Equal(int64(10)))
+		})
+
+		It("does not link an account that has not verified the email", func() {
+			mockUsersRepo.GetByEmailFunc = func(ctx context.Context, email string) (*repositories.User, error) {
+				return &repositories.User{ID: 10, Email: email}, nil
+			}
+
+			mockIdentities.LinkFunc = func(ctx context.Context, userID int64, provider, subject string) (bool, error) {
+				Fail("identity should not be linked")
+				return false, nil
+			}
+
+			_, err := oidcService.CompleteLogin(ctx, "state", "code")
+			Expect(err).To(MatchError(services.ErrOIDCEmailInUse))
+		})
+
+		It("creates a verified account for a new identity", func() {
+			mockUsersRepo.ExistsByUsernameFunc = func(ctx context.Context, username string) (bool, error) {
+				return username == "home_cook", nil
+			}
+
+			var username, email, password string
+			mockUsersRepo.InsertFunc = func(ctx context.Context, u, e, p string) (int64, error) {
+				username, email, password = u, e, p
+				return 20, nil
+			}
+
+			var verified int64
+			mockUsersRepo.MarkEmailVerifiedFunc = func(ctx context.Context, userID int64) error {
+				verified = userID
+				return nil
+			}
+
+			var linkedTo int64
+			mockIdentities.LinkFunc = func(ctx context.Context, userID int64, provider, subject string) (bool, error) {
+				linkedTo = userID
+				return true, nil
+			}
+
+			userID, err := oidcService.CompleteLogin(ctx, "state", "code")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(userID).To(Equal(int64(20)))
+
+			Expect(username).To(Equal("home_cook_2"))
+			Expect(email).To(Equal("cook@example.com"))
+			Expect(password).To(HaveLen(43))
+			Expect(verified).To(Equal(int64(20)))
+			Expect(linkedTo).To(Equal(int64(20)))
+		})
+
+		It("makes a username that follows the username rules", func() {
+			identity.PreferredUsername = ""
+			identity.Email = "1-al@example.com"
+
+			var username string
+			mockUsersRepo.InsertFunc = func(ctx context.Context, u, e, p string) (int64, error) {
+				username = u
+				return 20, nil
+			}
+			mockIdentities.LinkFunc = func(ctx context.Context, userID int64, provider, subject string) (bool, error) {
+				return true, nil
+			}
+
+			_, err := oidcService.CompleteLogin(ctx, "state", "code")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(username).To(Equal("user_al"))
+		})
+
+		It("uses the account another login linked the identity to first", func() {
+			calls := 0
+			mockIdentities.GetUserIDFunc = func(ctx context.Context, provider, subject string) (int64, error) {
+				calls++
+				if calls == 1 {
+					return -1, sql.ErrNoRows
+				}
+				return 30, nil
+			}
+
+			mockUsersRepo.GetByEmailFunc = func(ctx context.Context, email string) (*repositories.User, error) {
+				return &repositories.User{ID: 10, Email: email, EmailVerified: true}, nil
+			}
+
+			mockIdentities.LinkFunc = func(ctx context.Context, userID int64, provider, subject string) (bool, error) {
+				return false, nil
+			}
+
+			userID, err := oidcService.CompleteLogin(ctx, "state", "code")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(userID).To(Equal(int64(30)))
+		})
+
+		It("returns an error if the provider has not verified the email", func() {
+			identity.EmailVerified = false
+
+			_, err := oidcService.CompleteLogin(ctx, "state", "code")
+			Expect(err).To(MatchError(services.ErrOIDCEmailNotVerified))
+		})
+
+		It("returns an invalid state error for an unknown or link state", func() {
+			mockStateRepo.ConsumeOIDCStateFunc = func(ctx context.Context, state string) (*repositories.OIDCState, error) {
+				return nil, repositories.ErrOIDCStateNotFound
+			}
+
+			_, err := oidcService.CompleteLogin(ctx, "state", "code")
+			Expect(err).To(MatchError(services.ErrInvalidOIDCState))
+
+			mockStateRepo.ConsumeOIDCStateFunc = func(ctx context.Context, state string) (*repositories.OIDCState, error) {
+				return &repositories.OIDCState{LinkUserID: 10}, nil
+			}
+
+			_, err = oidcService.CompleteLogin(ctx, "state", "code")
+			Expect(err).To(MatchError(services.ErrInvalidOIDCState))
+		})
+
+		It("returns a login failed error if the provider rejects the code", func() {
+			mockProvider.ExchangeFunc = func(ctx context.Context, code string, req *oidc.AuthRequest) (*oidc.Identity, error) {
+				return nil, oidc.ErrInvalidGrant
+			}
+
+			_, err := oidcService.CompleteLogin(ctx, "state", "code")
+			Expect(err).To(MatchError(services.ErrOIDCLoginFailed))
+		})
+
+		It("returns an error if the provider cannot be reached", func() {
+			mockProvider.ExchangeFunc = func(ctx context.Context, code string, req *oidc.AuthRequest) (*oidc.Identity, error) {
+				return nil, errors.New("connection refused")
+			}
+
+			_, err := oidcService.CompleteLogin(ctx, "state", "code")
+			Expect(err).To(MatchError("connection refused"))
+		})
+	})
+
+	Describe("CompleteLink", func() {
+		BeforeEach(func() {
+			mockStateRepo.ConsumeOIDCStateFunc = func(ctx context.Context, state string) (*repositories.OIDCState, error) {
+				return &repositories.OIDCState{Nonce: "nonce", CodeVerifier: "verifier", LinkUserID: 10}, nil
+			}
+		})
+
+		It("links the identity to the user who started the link", func() {
+			var linkedTo int64
+			mockIdentities.LinkFunc = func(ctx context.Context, userID int64, provider, subject string) (bool, error) {
+				linkedTo = userID
+				return true, nil
+			}
+
+			err := oidcService.CompleteLink(ctx, 10, "state", "code")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(linkedTo).To(Equal(int64(10)))
+		})
+
+		It("succeeds if the identity is already linked to the user", func() {
+			mockIdentities.GetUserIDFunc = func(ctx context.Context, provider, subject string) (int64, error) {
+				return 10, nil
+			}
+
+			err := oidcService.CompleteLink(ctx, 10, "state", "code")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the identity is linked to another user", func() {
+			mockIdentities.GetUserIDFunc = func(ctx context.Context, provider, subject string) (int64, error) {
+				return 20, nil
+			}
+
+			err := oidcService.CompleteLink(ctx, 10, "state", "code")
+			Expect(err).To(MatchError(services.ErrIdentityLinkedToAnotherUser))
+		})
+
+		It("returns an invalid state error if another user started the link", func() {
+			err := oidcService.CompleteLink(ctx, 20, "state", "code")
+			Expect(err).To(MatchError(services.ErrInvalidOIDCState))
+		})
+	})
+})
+
+type MockOIDCProvider struct {
+	AuthCodeURLFunc func(req *oidc.AuthRequest) string
+	ExchangeFunc    func(ctx context.Context, code string, req *oidc.AuthRequest) (*oidc.Identity, error)
+}
+
+func (m *MockOIDCProvider) Issuer() string {
+	return "https://accounts.example.com"
+}
+
+func (m *MockOIDCProvider) AuthCodeURL(req *oidc.AuthRequest) string {
+	if m.AuthCodeURLFunc != nil {
+		return m.AuthCodeURLFunc(req)
+	}
+	return "https://accounts.example.com/authorize"
+}
+
+func (m *MockOIDCProvider) Exchange(ctx context.Context, code string, req *oidc.AuthRequest) (*oidc.Identity, error) {
+	if m.ExchangeFunc != nil {
+		return m.ExchangeFunc(ctx, code, req)
+	}
+	return nil, oidc.ErrInvalidGrant
+}
+
+type MockOIDCStateRepository struct {
+	StoreOIDCStateFunc   func(ctx context.Context, state string, oidcState *repositories.OIDCState, ttl time.Duration) error
+	ConsumeOIDCStateFunc func(ctx context.Context, state string) (*repositories.OIDCState, error)
+}
+
+func (m *MockOIDCStateRepository) StoreOIDCState(ctx context.Context, state string, oidcState *repositories.OIDCState, ttl time.Duration) error {
+	if m.StoreOIDCStateFunc != nil {
+		return m.StoreOIDCStateFunc(ctx, state, oidcState, ttl)
+	}
+	return nil
+}
+
+func (m *MockOIDCStateRepository) ConsumeOIDCState(ctx context.Context, state string) (*repositories.OIDCState, error) {
+	if m.ConsumeOIDCStateFunc != nil {
+		return m.ConsumeOIDCStateFunc(ctx, state)
+	}
+	return nil, repositories.ErrOIDCStateNotFound
+}
+
+type MockIdentitiesRepository struct {
+	GetUserIDFunc func(ctx context.Context, provider, subject string) (int64, error)
+	LinkFunc      func(ctx context.Context, userID int64, provider, subject string) (bool, error)
+}
+
+func (m *MockIdentitiesRepository) GetUserID(ctx context.Context, provider, subject string) (int64, error) {
+	if m.GetUserIDFunc != nil {
+		return m.GetUserIDFunc(ctx, provider, subject)
+	}
+	return -1, sql.ErrNoRows
+}
+
+func (m *MockIdentitiesRepository) Link(ctx context.Context, userID int64, provider, subject string) (bool, error) {
+	if m.LinkFunc != nil {
+		return m.LinkFunc(ctx, userID, provider, subject)
+	}
+	return false, nil
+}
+
+type MockOIDCUsersRepository struct {
+	GetByEmailFunc        func(ctx context.Context, email string) (*repositories.User, error)
+	ExistsByUsernameFunc  func(ctx context.Context, username string) (bool, error)
+	InsertFunc            func(ctx context.Context, username, email, password string) (int64, error)
+	MarkEmailVerifiedFunc func(ctx context.Context, userID int64) error
+}
+
+func (m *MockOIDCUsersRepository) GetByEmail(ctx context.Context, email string) (*repositories.User, error) {
+	if m.GetByEmailFunc != nil {
+		return m.GetByEmailFunc(ctx, email)
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *MockOIDCUsersRepository) ExistsByUsername(ctx context.Context, username string) (bool, error) {
+	if m.ExistsByUsernameFunc != nil {
+		return m.ExistsByUsernameFunc(ctx, username)
+	}
+	return false, nil
+}
+
+func (m *MockOIDCUsersRepository) Insert(ctx context.Context, username, email, password string) (int64, error) {
+	if m.InsertFunc != nil {
+		return m.InsertFunc(ctx, username, email, password)
+	}
+	return -1, errors.New("insert not expected")
+}
+
+func (m *MockOIDCUsersRepository) MarkEmailVerified(ctx context.Context, userID int64) error {
+	if m.MarkEmailVerifiedFunc != nil {
+		return m.MarkEmailVerifiedFunc(ctx, userID)
+	}
+	return nil
+}
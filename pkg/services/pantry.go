@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+)
+
+// staples are ingredients most kitchens always have, which can be left out
+// when working out what a recipe needs.
+var staples = map[string]bool{
+	"water":               true,
+	"ice":                 true,
+	"salt":                true,
+	"kosher salt":         true,
+	"sea salt":            true,
+	"pepper":              true,
+	"black pepper":        true,
+	"ground black pepper": true,
+	"salt and pepper":     true,
+	"oil":                 true,
+	"olive oil":           true,
+	"vegetable oil":       true,
+	"cooking spray":       true,
+	"nonstick spray":      true,
+	"non-stick spray":     true,
+}
+
+// PantryInput is what the user has on hand. With IgnoreStaples, staples such
+// as salt and water are not counted as ingredients a recipe needs.
+type PantryInput struct {
+	Ingredients   []string
+	IgnoreStaples bool
+	Limit         int
+}
+
+// PantryMatch is a recipe the user could cook, or nearly cook, with what they
+// have. Required is how many ingredients the recipe needs, Covered how many of
+// those the user has, and Missing names the rest.
+type PantryMatch struct {
+	ID       int64
+	Name     string
+	Required int
+	Covered  int
+	Missing  []string
+}
+
+// MatchPantry returns the user's recipes that use at least one of the
+// ingredients they have, those covering the most ingredients first. Names
+// are matched ignoring case, spacing and plurals, so "Eggs" matches "egg".
+func (s *RecipeService) MatchPantry(ctx context.Context, userID int64, pantry *PantryInput) ([]*PantryMatch, error) {
+	query := &repositories.PantryQuery{
+		Have:  ingredientSpellings(pantry.Ingredients),
+		Limit: pantry.Limit,
+	}
+	if query.Limit <= 0 {
+		query.Limit = DefaultRecipePageSize
+	}
+	if query.Limit > MaxRecipePageSize {
+		query.Limit = MaxRecipePageSize
+	}
+
+	if pantry.IgnoreStaples {
+		names := make([]string, 0, len(staples))
+		for staple := range staples {
+			names = append(names, staple)
+		}
+		sort.Strings(names)
+
+		query.Exclude = ingredientSpellings(names)
+	}
+
+	recipes, err := s.ingredientsRepo.MatchPantry(ctx, userID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	recipeIDs := make([]int64, len(recipes))
+	for i, recipe := range recipes {
+		recipeIDs[i] = recipe.RecipeID
+	}
+
+	missing, err := s.ingredientsRepo.GetMissingForRecipes(ctx, recipeIDs, query)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]*PantryMatch, len(recipes))
+	for i, recipe := range recipes {
+		match := &PantryMatch{
+			ID:       recipe.RecipeID,
+			Name:     recipe.RecipeName,
+			Required: recipe.Required,
+			Covered:  recipe.Covered,
+			Missing:  []string{},
+		}
+
+		seen := make(map[string]bool)
+		for _, name := range missing[recipe.RecipeID] {
+			if normalized := normalizeIngredient(name); !seen[normalized] {
+				seen[normalized] = true
+				match.Missing = append(match.Missing, name)
+			}
+		}
+
+		matches[i] = match
+	}
+
+	return matches, nil
+}
+
+// ingredientSpellings returns every spelling of the names, singular and
+// plural, that normalizeIngredient treats as the same ingredient, so they can
+// be matched in the database.
+func ingredientSpellings(names []string) []string {
+	var spellings []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		normalized := normalizeIngredient(name)
+		if normalized == "" {
+			continue
+		}
+
+		candidates := []string{normalized, normalized + "s", normalized + "es"}
+		if strings.HasSuffix(normalized, "y") {
+			candidates = append(candidates, strings.TrimSuffix(normalized, "y")+"ies")
+		}
+
+		for _, candidate := range candidates {
+			if !seen[candidate] && normalizeIngredient(candidate) == normalized {
+				seen[candidate] = true
+				spellings = append(spellings, candidate)
+			}
+		}
+	}
+
+	return spellings
+}
+
+// normalizeIngredient normalizes an ingredient name with
+// repositories.NormalizeName and makes its last word singular, so the same
+// ingredient written slightly differently compares equal.
+func normalizeIngredient(name string) string {
+	words := strings.Fields(repositories.NormalizeName(name))
+	if len(words) == 0 {
+		return ""
+	}
+
+	last := words[len(words)-1]
+	switch {
+	case strings.HasSuffix(last, "ies") && len(last) > 4:
+		last = strings.TrimSuffix(last, "ies") + "y"
+	case strings.HasSuffix(last, "oes") && len(last) > 4:
+		last = strings.TrimSuffix(last, "es")
+	case strings.HasSuffix(last, "s") && !strings.HasSuffix(last, "ss") && len(last) > 3:
+		last = strings.TrimSuffix(last, "s")
+	}
+	words[len(words)-1] = last
+
+	return strings.Join(words, " ")
+}
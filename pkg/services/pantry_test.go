@@ -0,0 +1,138 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+	"github.com/iplay88keys/my-recipe-library/pkg/search"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MatchPantry", func() {
+	var (
+		recipeService       *services.RecipeService
+		mockIngredientsRepo *MockIngredientsRepository
+		ctx                 context.Context
+		matched             *repositories.PantryQuery
+		missingFor          []int64
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		matched = nil
+		missingFor = nil
+
+		mockIngredientsRepo = &MockIngredientsRepository{
+			MatchPantryFunc: func(ctx context.Context, userID int64, query *repositories.PantryQuery) ([]*repositories.PantryRecipe, error) {
+				Expect(userID).To(Equal(int64(10)))
+				matched = query
+				return []*repositories.PantryRecipe{
+					{RecipeID: 1, RecipeName: "Pancakes", Required: 4, Covered: 3},
+					{RecipeID: 2, RecipeName: "Omelette", Required: 3, Covered: 2},
+					{RecipeID: 4, RecipeName: "Crepes", Required: 4, Covered: 2},
+				}, nil
+			},
+			GetMissingForRecipesFunc: func(ctx context.Context, recipeIDs []int64, query *repositories.PantryQuery) (map[int64][]string, error) {
+				Expect(query).To(BeIdenticalTo(matched))
+				missingFor = recipeIDs
+				return map[int64][]string{
+					1: {"Milk"},
+					2: {"Butter"},
+					4: {"Milk", "Sugar", "sugar"},
+				}, nil
+			},
+		}
+
+		recipeService = services.NewRecipeService(&MockRecipesRepository{}, mockIngredientsRepo, &MockStepsRepository{}, &MockTagsRepository{}, search.NewMemoryIndex(), nil)
+	})
+
+	It("returns the recipes matched to the user's ingredients with what they are missing", func() {
+		matches, err := recipeService.MatchPantry(ctx, 10, &services.PantryInput{
+			Ingredients: []string{"eggs", " FLOUR ", "salt"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(matched).To(Equal(&repositories.PantryQuery{
+			Have:  []string{"egg", "eggs", "flour", "flours", "salt", "salts"},
+			Limit: services.DefaultRecipePageSize,
+		}))
+		Expect(missingFor).To(Equal([]int64{1, 2, 4}))
+
+		Expect(matches).To(Equal([]*services.PantryMatch{
+			{ID: 1, Name: "Pancakes", Required: 4, Covered: 3, Missing: []string{"Milk"}},
+			{ID: 2, Name: "Omelette", Required: 3, Covered: 2, Missing: []string{"Butter"}},
+			{ID: 4, Name: "Crepes", Required: 4, Covered: 2, Missing: []string{"Milk", "Sugar"}},
+		}))
+	})
+
+	It("matches the singular and plural spellings of each ingredient", func() {
+		_, err := recipeService.MatchPantry(ctx, 10, &services.PantryInput{
+			Ingredients: []string{"Tomatoes", "berry", "Brown  Sugar"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(matched.Have).To(Equal([]string{
+			"tomato", "tomatos", "tomatoes",
+			"berry", "berrys", "berries",
+			"brown sugar", "brown sugars",
+		}))
+	})
+
+	It("can leave staples out of what a recipe needs", func() {
+		_, err := recipeService.MatchPantry(ctx, 10, &services.PantryInput{
+			Ingredients:   []string{"egg"},
+			IgnoreStaples: true,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(matched.Exclude).To(ContainElements("salt", "salts", "water", "olive oil", "olive oils"))
+		Expect(matched.Exclude).ToNot(ContainElement("egg"))
+	})
+
+	It("returns at most the maximum page size", func() {
+		_, err := recipeService.MatchPantry(ctx, 10, &services.PantryInput{
+			Ingredients: []string{"eggs"},
+			Limit:       services.MaxRecipePageSize + 1,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(matched.Limit).To(Equal(services.MaxRecipePageSize))
+	})
+
+	It("returns no recipes if none use the ingredients", func() {
+		mockIngredientsRepo.MatchPantryFunc = func(ctx context.Context, userID int64, query *repositories.PantryQuery) ([]*repositories.PantryRecipe, error) {
+			matched = query
+			return []*repositories.PantryRecipe{}, nil
+		}
+		mockIngredientsRepo.GetMissingForRecipesFunc = func(ctx context.Context, recipeIDs []int64, query *repositories.PantryQuery) (map[int64][]string, error) {
+			return map[int64][]string{}, nil
+		}
+
+		matches, err := recipeService.MatchPantry(ctx, 10, &services.PantryInput{
+			Ingredients: []string{"saffron"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(matches).To(BeEmpty())
+	})
+
+	It("returns an error if the recipes cannot be matched", func() {
+		mockIngredientsRepo.MatchPantryFunc = func(ctx context.Context, userID int64, query *repositories.PantryQuery) ([]*repositories.PantryRecipe, error) {
+			return nil, errors.New("some error")
+		}
+
+		_, err := recipeService.MatchPantry(ctx, 10, &services.PantryInput{Ingredients: []string{"eggs"}})
+		Expect(err).To(MatchError("some error"))
+	})
+
+	It("returns an error if the missing ingredients cannot be fetched", func() {
+		mockIngredientsRepo.GetMissingForRecipesFunc = func(ctx context.Context, recipeIDs []int64, query *repositories.PantryQuery) (map[int64][]string, error) {
+			return nil, errors.New("some error")
+		}
+
+		_, err := recipeService.MatchPantry(ctx, 10, &services.PantryInput{Ingredients: []string{"eggs"}})
+		Expect(err).To(MatchError("some error"))
+	})
+})
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/mailer"
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+)
+
+const PasswordResetTTL = time.Hour
+
+var (
+	ErrIncorrectPassword = errors.New("incorrect password")
+	ErrInvalidResetToken = errors.New("invalid password reset token")
+)
+
+type PasswordsRepositoryInterface interface {
+	GetByEmail(ctx context.Context, email string) (*repositories.User, error)
+	VerifyPassword(ctx context.Context, userID int64, password string) (bool, error)
+	UpdatePassword(ctx context.Context, userID int64, password string) error
+}
+
+type PasswordResetRepositoryInterface interface {
+	StorePasswordResetToken(ctx context.Context, tokenHash string, userID int64, ttl time.Duration) error
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string) (int64, error)
+	RevokePasswordResetTokens(ctx context.Context, userID int64) error
+	RevokeAllSessions(ctx context.Context, userID int64) error
+}
+
+type PasswordService struct {
+	usersRepo PasswordsRepositoryInterface
+	redisRepo PasswordResetRepositoryInterface
+	mailer    mailer.Mailer
+	resetURL  string
+}
+
+// NewPasswordService creates a PasswordService. Reset links are sent as
+// resetURL with the token added as the "token" query parameter.
+func NewPasswordService(
+	usersRepo PasswordsRepositoryInterface,
+	redisRepo PasswordResetRepositoryInterface,
+	m mailer.Mailer,
+	resetURL string,
+) *PasswordService {
+	return &PasswordService{
+		usersRepo: usersRepo,
+		redisRepo: redisRepo,
+		mailer:    m,
+		resetURL:  resetURL,
+	}
+}
+
+// ChangePassword replaces the password of a logged in user, invalidates any
+// reset links they have been sent and logs them out everywhere.
+func (s *PasswordService) ChangePassword(ctx context.Context, userID int64, currentPassword, newPassword string) error {
+	valid, err := s.usersRepo.VerifyPassword(ctx, userID, currentPassword)
+	if err != nil {
+		return err
+	}
+
+	if !valid {
+		return ErrIncorrectPassword
+	}
+
+	return s.setPassword(ctx, userID, newPassword)
+}
+
+// RequestPasswordReset emails a reset link to the user with the address. It
+// succeeds without sending anything if there is no such user so the response
+// does not reveal which emails are registered.
+func (s *PasswordService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.usersRepo.GetByEmail(ctx, email)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	raw := make([]byte, 32)
+	_, err = rand.Read(raw)
+	if err != nil {
+		return err
+	}
+	resetToken := base64.RawURLEncoding.EncodeToString(raw)
+
+	err = s.redisRepo.StorePasswordResetToken(ctx, hashToken(resetToken), user.ID, PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	link, err := url.Parse(s.resetURL)
+	if err != nil {
+		return err
+	}
+
+	query := link.Query()
+	query.Set("token", resetToken)
+	link.RawQuery = query.Encode()
+
+	return s.mailer.Send(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the link below to choose a new password. It expires in an hour and can only be used once.\n\n%s\n\nIf you did not ask to reset your password you can ignore this email.\n",
+			user.Username,
+			link.String(),
+		),
+	})
+}
+
+// ResetPassword sets a new password using a token from a reset email,
+// invalidates the user's other reset links and logs them out everywhere.
+func (s *PasswordService) ResetPassword(ctx context.Context, resetToken, newPassword string) error {
+	userID, err := s.redisRepo.ConsumePasswordResetToken(ctx, hashToken(resetToken))
+	if err == repositories.ErrResetTokenNotFound {
+		return ErrInvalidResetToken
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = s.setPassword(ctx, userID, newPassword)
+	if err == sql.ErrNoRows {
+		return ErrInvalidResetToken
+	}
+
+	return err
+}
+
+func (s *PasswordService) setPassword(ctx context.Context, userID int64, password string) error {
+	err := s.usersRepo.UpdatePassword(ctx, userID, password)
+	if err != nil {
+		return err
+	}
+
+	err = s.redisRepo.RevokePasswordResetTokens(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.redisRepo.RevokeAllSessions(ctx, userID)
+}
+
+// hashToken hashes a random token before it is stored so the stored value
+// cannot be used on its own.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,323 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/mailer"
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PasswordService", func() {
+	var (
+		passwordService *services.PasswordService
+		mockUsersRepo   *MockPasswordsRepository
+		mockRedisRepo   *MockPasswordResetRepository
+		mockMailer      *MockMailer
+		ctx             context.Context
+	)
+
+	BeforeEach(func() {
+		mockUsersRepo = &MockPasswordsRepository{}
+		mockRedisRepo = &MockPasswordResetRepository{}
+		mockMailer = &MockMailer{}
+		passwordService = services.NewPasswordService(mockUsersRepo, mockRedisRepo, mockMailer, "http://localhost:8080/reset-password")
+
+		ctx = context.Background()
+	})
+
+	Describe("ChangePassword", func() {
+		It("updates the password and revokes every reset token and session", func() {
+			mockUsersRepo.VerifyPasswordFunc = func(ctx context.Context, userID int64, password string) (bool, error) {
+				Expect(userID).To(Equal(int64(10)))
+				Expect(password).To(Equal("Old-pa55"))
+				return true, nil
+			}
+
+			var updated string
+			mockUsersRepo.UpdatePasswordFunc = func(ctx context.Context, userID int64, password string) error {
+				updated = password
+				return nil
+			}
+
+			var resetsRevokedFor int64
+			mockRedisRepo.RevokePasswordResetTokensFunc = func(ctx context.Context, userID int64) error {
+				resetsRevokedFor = userID
+				return nil
+			}
+
+			var revokedFor int64
+			mockRedisRepo.RevokeAllSessionsFunc = func(ctx context.Context, userID int64) error {
+				revokedFor = userID
+				return nil
+			}
+
+			err := passwordService.ChangePassword(ctx, 10, "Old-pa55", "New-pa55")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updated).To(Equal("New-pa55"))
+			Expect(resetsRevokedFor).To(Equal(int64(10)))
+			Expect(revokedFor).To(Equal(int64(10)))
+		})
+
+		It("does not change the password if the current password is incorrect", func() {
+			mockUsersRepo.VerifyPasswordFunc = func(ctx context.Context, userID int64, password string) (bool, error) {
+				return false, nil
+			}
+
+			mockUsersRepo.UpdatePasswordFunc = func(ctx context.Context, userID int64, password string) error {
+				Fail("update should not be called")
+				return nil
+			}
+
+			err := passwordService.ChangePassword(ctx, 10, "wrong", "New-pa55")
+			Expect(err).To(MatchError(services.ErrIncorrectPassword))
+		})
+
+		It("returns an error if the update fails", func() {
+			mockUsersRepo.VerifyPasswordFunc = func(ctx context.Context, userID int64, password string) (bool, error) {
+				return true, nil
+			}
+
+			mockUsersRepo.UpdatePasswordFunc = func(ctx context.Context, userID int64, password string) error {
+				return errors.New("db error")
+			}
+
+			mockRedisRepo.RevokeAllSessionsFunc = func(ctx context.Context, userID int64) error {
+				Fail("sessions should not be revoked")
+				return nil
+			}
+
+			err := passwordService.ChangePassword(ctx, 10, "Old-pa55", "New-pa55")
+			Expect(err).To(MatchError("db error"))
+		})
+
+		It("returns an error if the reset tokens cannot be revoked", func() {
+			mockUsersRepo.VerifyPasswordFunc = func(ctx context.Context, userID int64, password string) (bool, error) {
+				return true, nil
+			}
+
+			mockRedisRepo.RevokePasswordResetTokensFunc = func(ctx context.Context, userID int64) error {
+				return errors.New("redis error")
+			}
+
+			err := passwordService.ChangePassword(ctx, 10, "Old-pa55", "New-pa55")
+			Expect(err).To(MatchError("redis error"))
+		})
+	})
+
+	Describe("RequestPasswordReset", func() {
+		It("stores a hash of the token and emails a reset link", func() {
+			mockUsersRepo.GetByEmailFunc = func(ctx context.Context, email string) (*repositories.User, error) {
+				return &repositories.User{ID: 10, Username: "cook", Email: email}, nil
+			}
+
+			var storedHash string
+			mockRedisRepo.StorePasswordResetTokenFunc = func(ctx context.Context, tokenHash string, userID int64, ttl time.Duration) error {
+				storedHash = tokenHash
+				Expect(userID).To(Equal(int64(10)))
+				Expect(ttl).To(Equal(services.PasswordResetTTL))
+				return nil
+			}
+
+			var sent *mailer.Message
+			mockMailer.SendFunc = func(ctx context.Context, msg *mailer.Message) error {
+				sent = msg
+				return nil
+			}
+
+			err := passwordService.RequestPasswordReset(ctx, "cook@example.com")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(sent.To).To(Equal("cook@example.com"))
+
+			link := regexp.MustCompile(`http://\S+`).FindString(sent.Body)
+			parsed, err := url.Parse(link)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed.Path).To(Equal("/reset-password"))
+
+			resetToken := parsed.Query().Get("token")
+			Expect(resetToken).ToNot(BeEmpty())
+			Expect(storedHash).ToNot(BeEmpty())
+			Expect(storedHash).ToNot(ContainSubstring(resetToken))
+
+			var consumedHash string
+			mockRedisRepo.ConsumePasswordResetTokenFunc = func(ctx context.Context, tokenHash string) (int64, error) {
+				consumedHash = tokenHash
+				return 10, nil
+			}
+			mockUsersRepo.UpdatePasswordFunc = func(ctx context.Context, userID int64, password string) error { return nil }
+			mockRedisRepo.RevokeAllSessionsFunc = func(ctx context.Context, userID int64) error { return nil }
+
+			err = passwordService.ResetPassword(ctx, resetToken, "New-pa55")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(consumedHash).To(Equal(storedHash))
+		})
+
+		It("succeeds without sending anything if no user has the email", func() {
+			mockUsersRepo.GetByEmailFunc = func(ctx context.Context, email string) (*repositories.User, error) {
+				return nil, sql.ErrNoRows
+			}
+
+			mockMailer.SendFunc = func(ctx context.Context, msg *mailer.Message) error {
+				Fail("no mail should be sent")
+				return nil
+			}
+
+			err := passwordService.RequestPasswordReset(ctx, "missing@example.com")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the mail cannot be sent", func() {
+			mockUsersRepo.GetByEmailFunc = func(ctx context.Context, email string) (*repositories.User, error) {
+				return &repositories.User{ID: 10, Username: "cook", Email: email}, nil
+			}
+
+			mockRedisRepo.StorePasswordResetTokenFunc = func(ctx context.Context, tokenHash string, userID int64, ttl time.Duration) error {
+				return nil
+			}
+
+			mockMailer.SendFunc = func(ctx context.Context, msg *mailer.Message) error {
+				return errors.New("smtp error")
+			}
+
+			err := passwordService.RequestPasswordReset(ctx, "cook@example.com")
+			Expect(err).To(MatchError("smtp error"))
+		})
+	})
+
+	Describe("ResetPassword", func() {
+		It("updates the password and revokes every reset token and session", func() {
+			mockRedisRepo.ConsumePasswordResetTokenFunc = func(ctx context.Context, tokenHash string) (int64, error) {
+				return 10, nil
+			}
+
+			var updatedFor int64
+			mockUsersRepo.UpdatePasswordFunc = func(ctx context.Context, userID int64, password string) error {
+				updatedFor = userID
+				Expect(password).To(Equal("New-pa55"))
+				return nil
+			}
+
+			var resetsRevokedFor int64
+			mockRedisRepo.RevokePasswordResetTokensFunc = func(ctx context.Context, userID int64) error {
+				resetsRevokedFor = userID
+				return nil
+			}
+
+			var revokedFor int64
+			mockRedisRepo.RevokeAllSessionsFunc = func(ctx context.Context, userID int64) error {
+				revokedFor = userID
+				return nil
+			}
+
+			err := passwordService.ResetPassword(ctx, "reset-token", "New-pa55")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updatedFor).To(Equal(int64(10)))
+			Expect(resetsRevokedFor).To(Equal(int64(10)))
+			Expect(revokedFor).To(Equal(int64(10)))
+		})
+
+		It("returns an invalid token error if the token is unknown or used", func() {
+			mockRedisRepo.ConsumePasswordResetTokenFunc = func(ctx context.Context, tokenHash string) (int64, error) {
+				return -1, repositories.ErrResetTokenNotFound
+			}
+
+			err := passwordService.ResetPassword(ctx, "reset-token", "New-pa55")
+			Expect(err).To(MatchError(services.ErrInvalidResetToken))
+		})
+
+		It("returns an invalid token error if the user no longer exists", func() {
+			mockRedisRepo.ConsumePasswordResetTokenFunc = func(ctx context.Context, tokenHash string) (int64, error) {
+				return 10, nil
+			}
+
+			mockUsersRepo.UpdatePasswordFunc = func(ctx context.Context, userID int64, password string) error {
+				return sql.ErrNoRows
+			}
+
+			err := passwordService.ResetPassword(ctx, "reset-token", "New-pa55")
+			Expect(err).To(MatchError(services.ErrInvalidResetToken))
+		})
+	})
+})
+
+type MockPasswordsRepository struct {
+	GetByEmailFunc     func(ctx context.Context, email string) (*repositories.User, error)
+	VerifyPasswordFunc func(ctx context.Context, userID int64, password string) (bool, error)
+	UpdatePasswordFunc func(ctx context.Context, userID int64, password string) error
+}
+
+func (m *MockPasswordsRepository) GetByEmail(ctx context.Context, email string) (*repositories.User, error) {
+	if m.GetByEmailFunc != nil {
+		return m.GetByEmailFunc(ctx, email)
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *MockPasswordsRepository) VerifyPassword(ctx context.Context, userID int64, password string) (bool, error) {
+	if m.VerifyPasswordFunc != nil {
+		return m.VerifyPasswordFunc(ctx, userID, password)
+	}
+	return false, nil
+}
+
+func (m *MockPasswordsRepository) UpdatePassword(ctx context.Context, userID int64, password string) error {
+	if m.UpdatePasswordFunc != nil {
+		return m.UpdatePasswordFunc(ctx, userID, password)
+	}
+	return nil
+}
+
+type MockPasswordResetRepository struct {
+	StorePasswordResetTokenFunc   func(ctx context.Context, tokenHash string, userID int64, ttl time.Duration) error
+	ConsumePasswordResetTokenFunc func(ctx context.Context, tokenHash string) (int64, error)
+	RevokePasswordResetTokensFunc func(ctx context.Context, userID int64) error
+	RevokeAllSessionsFunc         func(ctx context.Context, userID int64) error
+}
+
+func (m *MockPasswordResetRepository) StorePasswordResetToken(ctx context.Context, tokenHash string, userID int64, ttl time.Duration) error {
+	if m.StorePasswordResetTokenFunc != nil {
+		return m.StorePasswordResetTokenFunc(ctx, tokenHash, userID, ttl)
+	}
+	return nil
+}
+
+func (m *MockPasswordResetRepository) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (int64, error) {
+	if m.ConsumePasswordResetTokenFunc != nil {
+		return m.ConsumePasswordResetTokenFunc(ctx, tokenHash)
+	}
+	return -1, repositories.ErrResetTokenNotFound
+}
+
+func (m *MockPasswordResetRepository) RevokePasswordResetTokens(ctx context.Context, userID int64) error {
+	if m.RevokePasswordResetTokensFunc != nil {
+		return m.RevokePasswordResetTokensFunc(ctx, userID)
+	}
+	return nil
+}
+
+func (m *MockPasswordResetRepository) RevokeAllSessions(ctx context.Context, userID int64) error {
+	if m.RevokeAllSessionsFunc != nil {
+		return m.RevokeAllSessionsFunc(ctx, userID)
+	}
+	return nil
+}
+
+type MockMailer struct {
+	SendFunc func(ctx context.Context, msg *mailer.Message) error
+}
+
+func (m *MockMailer) Send(ctx context.Context, msg *mailer.Message) error {
+	if m.SendFunc != nil {
+		return m.SendFunc(ctx, msg)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+)
+
+// recipeCursor is the position of a recipe in a listing. It records the sort
+// it was made for, since a position in one order means nothing in another.
+type recipeCursor struct {
+	Sort       repositories.RecipeSort `json:"s"`
+	Descending bool                    `json:"d,omitempty"`
+	Value      string                  `json:"v"`
+	ID         int64                   `json:"id"`
+}
+
+func encodeRecipeCursor(cursor *repositories.RecipeCursor, page *repositories.RecipePage) (string, error) {
+	encoded, err := json.Marshal(&recipeCursor{
+		Sort:       page.Sort,
+		Descending: page.Descending,
+		Value:      cursor.Value,
+		ID:         cursor.ID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(encoded), nil
+}
+
+func decodeRecipeCursor(value string, page *repositories.RecipePage) (*repositories.RecipeCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor := &recipeCursor{}
+	if err := json.Unmarshal(decoded, cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if cursor.Sort != page.Sort || cursor.Descending != page.Descending {
+		return nil, ErrInvalidCursor
+	}
+
+	return &repositories.RecipeCursor{
+		Value: cursor.Value,
+		ID:    cursor.ID,
+	}, nil
+}
@@ -3,10 +3,17 @@ package services
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
-
+	"log/slog"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/quantity"
 	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+	"github.com/iplay88keys/my-recipe-library/pkg/search"
 )
 
 func stringPtr(s string) *string {
@@ -14,25 +21,50 @@ func stringPtr(s string) *string {
 }
 
 type RecipesRepositoryInterface interface {
-	Insert(recipe *repositories.Recipe, userID int64) (int64, error)
-	Get(id, userID int64) (*repositories.Recipe, error)
-	List(userID int64) ([]*repositories.Recipe, error)
+	Insert(ctx context.Context, recipe *repositories.Recipe, userID int64) (int64, error)
+	Get(ctx context.Context, id, userID int64) (*repositories.Recipe, error)
+	List(ctx context.Context, userID int64, filter *repositories.RecipeFilter, page *repositories.RecipePage) ([]*repositories.Recipe, *repositories.RecipeCursor, error)
+	Count(ctx context.Context, userID int64, filter *repositories.RecipeFilter) (int, error)
+	GetCreator(ctx context.Context, id int64) (int64, error)
+	Update(ctx context.Context, id int64, recipe *repositories.Recipe, userID int64) error
+	Delete(ctx context.Context, id, userID int64) error
 }
 
 type IngredientsRepositoryInterface interface {
-	Insert(recipeID int64, ingredient *repositories.Ingredient) error
-	GetForRecipe(recipeID int64) ([]*repositories.Ingredient, error)
+	Insert(ctx context.Context, recipeID int64, ingredient *repositories.Ingredient) error
+	GetForRecipe(ctx context.Context, recipeID int64) ([]*repositories.Ingredient, error)
+	MatchPantry(ctx context.Context, userID int64, query *repositories.PantryQuery) ([]*repositories.PantryRecipe, error)
+	GetMissingForRecipes(ctx context.Context, recipeIDs []int64, query *repositories.PantryQuery) (map[int64][]string, error)
+	GetRefsForRecipe(ctx context.Context, recipeID int64) (*repositories.IngredientRefs, error)
+	DeleteForRecipe(ctx context.Context, recipeID int64) error
+	DeleteUnused(ctx context.Context, refs *repositories.IngredientRefs) error
 }
 
 type StepsRepositoryInterface interface {
-	Insert(recipeID int64, step *repositories.Step) error
-	GetForRecipe(recipeID int64) ([]*repositories.Step, error)
+	Insert(ctx context.Context, recipeID int64, step *repositories.Step) error
+	GetForRecipe(ctx context.Context, recipeID int64) ([]*repositories.Step, error)
+	DeleteForRecipe(ctx context.Context, recipeID int64) error
+}
+
+type TagsRepositoryInterface interface {
+	List(ctx context.Context, userID int64) ([]*repositories.Tag, error)
+	GetForRecipe(ctx context.Context, recipeID int64) ([]string, error)
+	SetForRecipe(ctx context.Context, recipeID, userID int64, names []string) error
+	DeleteUnused(ctx context.Context, userID int64) error
+}
+
+type SearchIndexInterface interface {
+	Search(ctx context.Context, userID int64, query string, limit int) ([]*search.Result, error)
+	Put(ctx context.Context, doc *search.Document) error
+	Delete(ctx context.Context, recipeID int64) error
 }
 
 type RecipeService struct {
 	recipesRepo     RecipesRepositoryInterface
 	ingredientsRepo IngredientsRepositoryInterface
 	stepsRepo       StepsRepositoryInterface
+	tagsRepo        TagsRepositoryInterface
+	searchIndex     SearchIndexInterface
 	db              *sql.DB
 }
 
@@ -40,12 +72,16 @@ func NewRecipeService(
 	recipesRepo RecipesRepositoryInterface,
 	ingredientsRepo IngredientsRepositoryInterface,
 	stepsRepo StepsRepositoryInterface,
+	tagsRepo TagsRepositoryInterface,
+	searchIndex SearchIndexInterface,
 	db *sql.DB,
 ) *RecipeService {
 	return &RecipeService{
 		recipesRepo:     recipesRepo,
 		ingredientsRepo: ingredientsRepo,
 		stepsRepo:       stepsRepo,
+		tagsRepo:        tagsRepo,
+		searchIndex:     searchIndex,
 		db:              db,
 	}
 }
@@ -61,6 +97,7 @@ type RecipeInput struct {
 	Source      *string
 	Ingredients []*IngredientInput
 	Steps       []*StepInput
+	Tags        []string
 }
 
 type IngredientInput struct {
@@ -90,14 +127,19 @@ type RecipeDetail struct {
 	Source      *string
 	Ingredients []*IngredientDetail
 	Steps       []*StepDetail
+	Tags        []string
 }
 
+// IngredientDetail is an ingredient of a recipe. Unscaled is set when the
+// recipe was scaled but the amount could not be read, so it was left as it
+// was written.
 type IngredientDetail struct {
 	Name     string
 	Amount   *string
 	Unit     *string
 	Notes    *string
 	OrderNum int
+	Unscaled bool
 }
 
 type StepDetail struct {
@@ -106,15 +148,79 @@ type StepDetail struct {
 	Notes        *string
 }
 
+// RecipeFilter narrows a recipe listing. Recipes with any of the Tags are
+// listed, or only those with all of them if MatchAllTags is set.
+type RecipeFilter struct {
+	CookbookID   *int64
+	SectionID    *int64
+	MinServings  *int
+	MaxServings  *int
+	Source       *string
+	Tags         []string
+	MatchAllTags bool
+}
+
+type RecipeSort string
+
+const (
+	RecipeSortName      RecipeSort = "name"
+	RecipeSortCreatedAt RecipeSort = "created_at"
+	RecipeSortTotalTime RecipeSort = "total_time"
+)
+
+const (
+	DefaultRecipePageSize = 20
+	MaxRecipePageSize     = 100
+)
+
+// RecipeListOptions selects a page of recipes. Sort defaults to the name,
+// Limit to DefaultRecipePageSize, and After is the NextCursor of the previous
+// page.
+type RecipeListOptions struct {
+	Filter     *RecipeFilter
+	Sort       RecipeSort
+	Descending bool
+	Limit      int
+	After      string
+}
+
+// RecipeList is a page of recipes. NextCursor is empty on the last page and
+// Total counts the recipes on every page.
+type RecipeList struct {
+	Recipes    []*RecipeSummary
+	NextCursor string
+	Total      int
+}
+
 type RecipeSummary struct {
 	ID          int64
 	Name        string
 	Description string
 }
 
+type TagSummary struct {
+	Name        string
+	RecipeCount int
+}
+
+type SearchResult struct {
+	ID          int64
+	Name        string
+	Description string
+	Highlights  []*SearchHighlight
+}
+
+// SearchHighlight is HTML escaped text from a recipe with the words that
+// matched the search wrapped in <mark> tags. Field is where in the recipe the
+// text is from: its name, description, an ingredient or a step.
+type SearchHighlight struct {
+	Field string
+	Text  string
+}
+
 func (s *RecipeService) CreateRecipe(ctx context.Context, userID int64, recipe *RecipeInput) (int64, error) {
-	return s.runInTransaction(ctx, func(tx *sql.Tx) (int64, error) {
-		recipeID, err := s.recipesRepo.Insert(&repositories.Recipe{
+	recipeID, err := s.runInTransaction(ctx, func(ctx context.Context) (int64, error) {
+		recipeID, err := s.recipesRepo.Insert(ctx, &repositories.Recipe{
 			Name:        &recipe.Name,
 			Description: &recipe.Description,
 			Creator:     stringPtr(fmt.Sprintf("User%d", userID)),
@@ -124,53 +230,165 @@ func (s *RecipeService) CreateRecipe(ctx context.Context, userID int64, recipe *
 			CoolTime:    recipe.CoolTime,
 			TotalTime:   recipe.TotalTime,
 			Source:      recipe.Source,
+
+			TotalMinutes: parseMinutes(recipe.TotalTime),
 		}, userID)
 		if err != nil {
 			return 0, err
 		}
 
-		for _, ingredient := range recipe.Ingredients {
-			err = s.ingredientsRepo.Insert(recipeID, &repositories.Ingredient{
-				Ingredient:       &ingredient.Name,
-				IngredientNumber: &ingredient.OrderNum,
-				Amount:           &ingredient.Amount,
-				Measurement:      &ingredient.Unit,
-				Preparation:      &ingredient.Notes,
-			})
-			if err != nil {
-				return 0, err
-			}
+		err = s.insertIngredientsAndSteps(ctx, recipeID, recipe)
+		if err != nil {
+			return 0, err
 		}
 
-		for _, step := range recipe.Steps {
-			err = s.stepsRepo.Insert(recipeID, &repositories.Step{
-				StepNumber:   &step.OrderNum,
-				Instructions: &step.Instructions,
-			})
-			if err != nil {
-				return 0, err
-			}
+		err = s.tagsRepo.SetForRecipe(ctx, recipeID, userID, normalizeTags(recipe.Tags))
+		if err != nil {
+			return 0, err
+		}
+
+		return recipeID, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.index(ctx, recipeID, userID, recipe)
+	return recipeID, nil
+}
+
+func (s *RecipeService) UpdateRecipe(ctx context.Context, recipeID, userID int64, recipe *RecipeInput) error {
+	_, err := s.runInTransaction(ctx, func(ctx context.Context) (int64, error) {
+		err := s.checkOwnership(ctx, recipeID, userID)
+		if err != nil {
+			return 0, err
+		}
+
+		err = s.recipesRepo.Update(ctx, recipeID, &repositories.Recipe{
+			Name:        &recipe.Name,
+			Description: &recipe.Description,
+			Servings:    recipe.Servings,
+			PrepTime:    recipe.PrepTime,
+			CookTime:    recipe.CookTime,
+			CoolTime:    recipe.CoolTime,
+			TotalTime:   recipe.TotalTime,
+			Source:      recipe.Source,
+
+			TotalMinutes: parseMinutes(recipe.TotalTime),
+		}, userID)
+		if err != nil {
+			return 0, err
+		}
+
+		refs, err := s.ingredientsRepo.GetRefsForRecipe(ctx, recipeID)
+		if err != nil {
+			return 0, err
+		}
+
+		err = s.ingredientsRepo.DeleteForRecipe(ctx, recipeID)
+		if err != nil {
+			return 0, err
+		}
+
+		err = s.stepsRepo.DeleteForRecipe(ctx, recipeID)
+		if err != nil {
+			return 0, err
+		}
+
+		err = s.insertIngredientsAndSteps(ctx, recipeID, recipe)
+		if err != nil {
+			return 0, err
+		}
+
+		err = s.tagsRepo.SetForRecipe(ctx, recipeID, userID, normalizeTags(recipe.Tags))
+		if err != nil {
+			return 0, err
+		}
+
+		err = s.ingredientsRepo.DeleteUnused(ctx, refs)
+		if err != nil {
+			return 0, err
+		}
+
+		err = s.tagsRepo.DeleteUnused(ctx, userID)
+		if err != nil {
+			return 0, err
 		}
 
 		return recipeID, nil
 	})
+	if err != nil {
+		return err
+	}
+
+	s.index(ctx, recipeID, userID, recipe)
+	return nil
+}
+
+// DeleteRecipe removes a recipe owned by the user. Its ingredient list, steps
+// locations and tags are removed by the ON DELETE CASCADE foreign keys, after
+// which the ingredients and measurements it used and the user's tags are
+// cleaned up if no recipe is left using them.
+func (s *RecipeService) DeleteRecipe(ctx context.Context, recipeID, userID int64) error {
+	_, err := s.runInTransaction(ctx, func(ctx context.Context) (int64, error) {
+		err := s.checkOwnership(ctx, recipeID, userID)
+		if err != nil {
+			return 0, err
+		}
+
+		refs, err := s.ingredientsRepo.GetRefsForRecipe(ctx, recipeID)
+		if err != nil {
+			return 0, err
+		}
+
+		err = s.recipesRepo.Delete(ctx, recipeID, userID)
+		if err != nil {
+			return 0, notFound(err)
+		}
+
+		err = s.ingredientsRepo.DeleteUnused(ctx, refs)
+		if err != nil {
+			return 0, err
+		}
+
+		err = s.tagsRepo.DeleteUnused(ctx, userID)
+		if err != nil {
+			return 0, err
+		}
+
+		return recipeID, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.searchIndex.Delete(ctx, recipeID); err != nil {
+		slog.WarnContext(ctx, "Failed to remove recipe from the search index", "recipe_id", recipeID, "error", err)
+	}
+
+	return nil
 }
 
 func (s *RecipeService) GetRecipe(ctx context.Context, recipeID, userID int64) (*RecipeDetail, error) {
-	recipe, err := s.recipesRepo.Get(recipeID, userID)
+	recipe, err := s.recipesRepo.Get(ctx, recipeID, userID)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
 	if recipe == nil {
-		return nil, errors.New("recipe not found")
+		return nil, ErrNotFound
+	}
+
+	ingredients, err := s.ingredientsRepo.GetForRecipe(ctx, recipeID)
+	if err != nil {
+		return nil, err
 	}
 
-	ingredients, err := s.ingredientsRepo.GetForRecipe(recipeID)
+	steps, err := s.stepsRepo.GetForRecipe(ctx, recipeID)
 	if err != nil {
 		return nil, err
 	}
 
-	steps, err := s.stepsRepo.GetForRecipe(recipeID)
+	tags, err := s.tagsRepo.GetForRecipe(ctx, recipeID)
 	if err != nil {
 		return nil, err
 	}
@@ -188,6 +406,7 @@ func (s *RecipeService) GetRecipe(ctx context.Context, recipeID, userID int64) (
 		Source:      recipe.Source,
 		Ingredients: make([]*IngredientDetail, len(ingredients)),
 		Steps:       make([]*StepDetail, len(steps)),
+		Tags:        tags,
 	}
 
 	for i, ingredient := range ingredients {
@@ -211,25 +430,292 @@ func (s *RecipeService) GetRecipe(ctx context.Context, recipeID, userID int64) (
 	return recipeDetail, nil
 }
 
-func (s *RecipeService) ListRecipes(ctx context.Context, userID int64) ([]*RecipeSummary, error) {
-	recipes, err := s.recipesRepo.List(userID)
+// ScaleRecipe returns a recipe with its ingredient amounts multiplied to make
+// the given number of servings.
+func (s *RecipeService) ScaleRecipe(ctx context.Context, recipeID, userID int64, servings int) (*RecipeDetail, error) {
+	recipe, err := s.GetRecipe(ctx, recipeID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if recipe.Servings == nil || *recipe.Servings <= 0 {
+		return nil, ErrNoServings
+	}
+
+	factor := big.NewRat(int64(servings), int64(*recipe.Servings))
+	for _, ingredient := range recipe.Ingredients {
+		if ingredient.Amount == nil || strings.TrimSpace(*ingredient.Amount) == "" {
+			continue
+		}
+
+		amount, err := quantity.Parse(*ingredient.Amount)
+		if err != nil {
+			ingredient.Unscaled = true
+			continue
+		}
+
+		ingredient.Amount = stringPtr(amount.Scale(factor).String())
+	}
+
+	recipe.Servings = &servings
+	return recipe, nil
+}
+
+func (s *RecipeService) ListRecipes(ctx context.Context, userID int64, options *RecipeListOptions) (*RecipeList, error) {
+	if options == nil {
+		options = &RecipeListOptions{}
+	}
+
+	page := &repositories.RecipePage{
+		Sort:       repositories.RecipeSort(options.Sort),
+		Descending: options.Descending,
+		Limit:      options.Limit,
+	}
+	if page.Sort == "" {
+		page.Sort = repositories.RecipeSortName
+	}
+	if page.Limit <= 0 {
+		page.Limit = DefaultRecipePageSize
+	}
+	if page.Limit > MaxRecipePageSize {
+		page.Limit = MaxRecipePageSize
+	}
+
+	if options.After != "" {
+		var err error
+		page.After, err = decodeRecipeCursor(options.After, page)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var repoFilter *repositories.RecipeFilter
+	if options.Filter != nil {
+		repoFilter = &repositories.RecipeFilter{
+			CookbookID:  options.Filter.CookbookID,
+			SectionID:   options.Filter.SectionID,
+			MinServings: options.Filter.MinServings,
+			MaxServings: options.Filter.MaxServings,
+			Source:      options.Filter.Source,
+
+			Tags:         normalizeTags(options.Filter.Tags),
+			MatchAllTags: options.Filter.MatchAllTags,
+		}
+	}
+
+	recipes, next, err := s.recipesRepo.List(ctx, userID, repoFilter, page)
 	if err != nil {
 		return nil, err
 	}
 
-	summaries := make([]*RecipeSummary, len(recipes))
+	total, err := s.recipesRepo.Count(ctx, userID, repoFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &RecipeList{
+		Recipes: make([]*RecipeSummary, len(recipes)),
+		Total:   total,
+	}
+
 	for i, recipe := range recipes {
-		summaries[i] = &RecipeSummary{
+		list.Recipes[i] = &RecipeSummary{
 			ID:          *recipe.ID,
 			Name:        *recipe.Name,
 			Description: *recipe.Description,
 		}
 	}
 
+	if next != nil {
+		list.NextCursor, err = encodeRecipeCursor(next, page)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return list, nil
+}
+
+// ListTags returns the user's tags, ordered by name, with how many recipes
+// have each one.
+func (s *RecipeService) ListTags(ctx context.Context, userID int64) ([]*TagSummary, error) {
+	tags, err := s.tagsRepo.List(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]*TagSummary, len(tags))
+	for i, tag := range tags {
+		summaries[i] = &TagSummary{
+			Name:        tag.Name,
+			RecipeCount: tag.RecipeCount,
+		}
+	}
+
 	return summaries, nil
 }
 
-func (s *RecipeService) runInTransaction(ctx context.Context, fn func(*sql.Tx) (int64, error)) (int64, error) {
+// SearchRecipes returns the user's recipes that best match the query, up to
+// limit of them.
+func (s *RecipeService) SearchRecipes(ctx context.Context, userID int64, query string, limit int) ([]*SearchResult, error) {
+	if limit <= 0 {
+		limit = DefaultRecipePageSize
+	}
+	if limit > MaxRecipePageSize {
+		limit = MaxRecipePageSize
+	}
+
+	if len(search.Terms(query)) == 0 {
+		return []*SearchResult{}, nil
+	}
+
+	results, err := s.searchIndex.Search(ctx, userID, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	searchResults := make([]*SearchResult, len(results))
+	for i, result := range results {
+		searchResults[i] = &SearchResult{
+			ID:          result.RecipeID,
+			Name:        result.Name,
+			Description: result.Description,
+			Highlights:  make([]*SearchHighlight, len(result.Highlights)),
+		}
+
+		for j, highlight := range result.Highlights {
+			searchResults[i].Highlights[j] = &SearchHighlight{
+				Field: string(highlight.Field),
+				Text:  highlight.Text,
+			}
+		}
+	}
+
+	return searchResults, nil
+}
+
+func (s *RecipeService) checkOwnership(ctx context.Context, recipeID, userID int64) error {
+	creator, err := s.recipesRepo.GetCreator(ctx, recipeID)
+	if err != nil {
+		return notFound(err)
+	}
+
+	if creator != userID {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+func (s *RecipeService) insertIngredientsAndSteps(ctx context.Context, recipeID int64, recipe *RecipeInput) error {
+	for _, ingredient := range recipe.Ingredients {
+		err := s.ingredientsRepo.Insert(ctx, recipeID, &repositories.Ingredient{
+			Ingredient:       &ingredient.Name,
+			IngredientNumber: &ingredient.OrderNum,
+			Amount:           &ingredient.Amount,
+			Measurement:      &ingredient.Unit,
+			Preparation:      &ingredient.Notes,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, step := range recipe.Steps {
+		err := s.stepsRepo.Insert(ctx, recipeID, &repositories.Step{
+			StepNumber:   &step.OrderNum,
+			Instructions: &step.Instructions,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// normalizeTags normalizes tag names with repositories.NormalizeName and drops
+// blank and repeated ones, keeping the order they were given in.
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool)
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = repositories.NormalizeName(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	return normalized
+}
+
+var durationPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)(?:\s*(?:-|to)\s*(\d+(?:\.\d+)?))?\s*([a-z]*)`)
+
+// parseMinutes reads a free text duration such as "5 m", "1 hr 30 mins" or
+// "1-2 hrs" as minutes, taking the longer end of a range. Numbers without a
+// unit are minutes. It returns nil if there is no duration in the text.
+func parseMinutes(text *string) *int {
+	if text == nil {
+		return nil
+	}
+
+	matches := durationPattern.FindAllStringSubmatch(strings.ToLower(*text), -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	var minutes float64
+	for _, match := range matches {
+		amount, _ := strconv.ParseFloat(match[1], 64)
+		if match[2] != "" {
+			amount, _ = strconv.ParseFloat(match[2], 64)
+		}
+
+		switch {
+		case strings.HasPrefix(match[3], "d"):
+			minutes += amount * 24 * 60
+		case strings.HasPrefix(match[3], "h"):
+			minutes += amount * 60
+		case strings.HasPrefix(match[3], "s"):
+			minutes += amount / 60
+		default:
+			minutes += amount
+		}
+	}
+
+	total := int(math.Round(minutes))
+	return &total
+}
+
+// index updates the recipe in the search index. The recipe has already been
+// saved, so a failure is only logged.
+func (s *RecipeService) index(ctx context.Context, recipeID, userID int64, recipe *RecipeInput) {
+	doc := &search.Document{
+		RecipeID:    recipeID,
+		UserID:      userID,
+		Name:        recipe.Name,
+		Description: recipe.Description,
+	}
+
+	for _, ingredient := range recipe.Ingredients {
+		doc.Ingredients = append(doc.Ingredients, ingredient.Name)
+	}
+
+	for _, step := range recipe.Steps {
+		doc.Steps = append(doc.Steps, step.Instructions)
+	}
+
+	if err := s.searchIndex.Put(ctx, doc); err != nil {
+		slog.WarnContext(ctx, "Failed to update recipe in the search index", "recipe_id", recipeID, "error", err)
+	}
+}
+
+// runInTransaction calls fn with a context that runs the repositories' queries
+// in a transaction, which is committed if fn succeeds and rolled back if it
+// fails.
+func (s *RecipeService) runInTransaction(ctx context.Context, fn func(ctx context.Context) (int64, error)) (id int64, err error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, err
@@ -246,5 +732,5 @@ func (s *RecipeService) runInTransaction(ctx context.Context, fn func(*sql.Tx) (
 		}
 	}()
 
-	return fn(tx)
+	return fn(repositories.WithTx(ctx, tx))
 }
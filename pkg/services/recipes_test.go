@@ -8,6 +8,7 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/iplay88keys/my-recipe-library/pkg/helpers"
 	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+	"github.com/iplay88keys/my-recipe-library/pkg/search"
 	"github.com/iplay88keys/my-recipe-library/pkg/services"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -20,6 +21,8 @@ var _ = Describe("RecipeService", func() {
 		mockRecipesRepo     *MockRecipesRepository
 		mockIngredientsRepo *MockIngredientsRepository
 		mockStepsRepo       *MockStepsRepository
+		mockTagsRepo        *MockTagsRepository
+		searchIndex         *search.MemoryIndex
 		db                  *sql.DB
 		mock                sqlmock.Sqlmock
 		ctx                 context.Context
@@ -35,13 +38,19 @@ var _ = Describe("RecipeService", func() {
 		mockRecipesRepo = &MockRecipesRepository{}
 		mockIngredientsRepo = &MockIngredientsRepository{}
 		mockStepsRepo = &MockStepsRepository{}
-		recipeService = services.NewRecipeService(mockRecipesRepo, mockIngredientsRepo, mockStepsRepo, db)
+		mockTagsRepo = &MockTagsRepository{}
+		searchIndex = search.NewMemoryIndex()
+		recipeService = services.NewRecipeService(mockRecipesRepo, mockIngredientsRepo, mockStepsRepo, mockTagsRepo, searchIndex, db)
 
 		ctx = context.Background()
 		userID = 1
 		recipeID = 123
 	})
 
+	AfterEach(func() {
+		Expect(mock.ExpectationsWereMet()).To(Succeed())
+	})
+
 	Describe("CreateRecipe", func() {
 		var recipeInput *services.RecipeInput
 
@@ -89,7 +98,7 @@ var _ = Describe("RecipeService", func() {
 			It("creates a recipe with ingredients and steps", func() {
 				mock.ExpectBegin()
 
-				mockRecipesRepo.InsertFunc = func(recipe *repositories.Recipe, userID int64) (int64, error) {
+				mockRecipesRepo.InsertFunc = func(ctx context.Context, recipe *repositories.Recipe, userID int64) (int64, error) {
 					Expect(recipe.Name).To(Equal(helpers.StringPointer("Test Recipe")))
 					Expect(recipe.Description).To(Equal(helpers.StringPointer("A test recipe")))
 					Expect(recipe.Creator).To(Equal(helpers.StringPointer("User1")))
@@ -97,13 +106,14 @@ var _ = Describe("RecipeService", func() {
 					Expect(*recipe.PrepTime).To(Equal("15 minutes"))
 					Expect(*recipe.CookTime).To(Equal("30 minutes"))
 					Expect(*recipe.TotalTime).To(Equal("45 minutes"))
+					Expect(*recipe.TotalMinutes).To(Equal(45))
 					Expect(*recipe.Source).To(Equal("Test Cookbook"))
 					Expect(userID).To(Equal(int64(1)))
 					return recipeID, nil
 				}
 
 				ingredientCallCount := 0
-				mockIngredientsRepo.InsertFunc = func(recipeID int64, ingredient *repositories.Ingredient) error {
+				mockIngredientsRepo.InsertFunc = func(ctx context.Context, recipeID int64, ingredient *repositories.Ingredient) error {
 					ingredientCallCount++
 					Expect(recipeID).To(Equal(recipeID))
 					if ingredientCallCount == 1 {
@@ -123,7 +133,7 @@ var _ = Describe("RecipeService", func() {
 				}
 
 				stepCallCount := 0
-				mockStepsRepo.InsertFunc = func(recipeID int64, step *repositories.Step) error {
+				mockStepsRepo.InsertFunc = func(ctx context.Context, recipeID int64, step *repositories.Step) error {
 					stepCallCount++
 					Expect(recipeID).To(Equal(recipeID))
 					if stepCallCount == 1 {
@@ -145,13 +155,78 @@ var _ = Describe("RecipeService", func() {
 				Expect(ingredientCallCount).To(Equal(2))
 				Expect(stepCallCount).To(Equal(2))
 			})
+
+			It("saves the recipe's tags normalized and without repeats", func() {
+				mock.ExpectBegin()
+
+				var tags []string
+				mockRecipesRepo.InsertFunc = func(ctx context.Context, recipe *repositories.Recipe, userID int64) (int64, error) {
+					return recipeID, nil
+				}
+
+				mockTagsRepo.SetForRecipeFunc = func(ctx context.Context, id, uID int64, names []string) error {
+					Expect(id).To(Equal(recipeID))
+					Expect(uID).To(Equal(userID))
+					tags = names
+					return nil
+				}
+
+				mock.ExpectCommit()
+
+				recipeInput.Tags = []string{" Quick  Dinner ", "dessert", "quick dinner", "  "}
+				_, err := recipeService.CreateRecipe(ctx, userID, recipeInput)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(tags).To(Equal([]string{"quick dinner", "dessert"}))
+			})
+		})
+
+		DescribeTable("saves the total time in minutes",
+			func(totalTime *string, expected *int) {
+				mock.ExpectBegin()
+
+				var totalMinutes *int
+				mockRecipesRepo.InsertFunc = func(ctx context.Context, recipe *repositories.Recipe, userID int64) (int64, error) {
+					totalMinutes = recipe.TotalMinutes
+					return recipeID, nil
+				}
+
+				mock.ExpectCommit()
+
+				recipeInput.TotalTime = totalTime
+				_, err := recipeService.CreateRecipe(ctx, userID, recipeInput)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(totalMinutes).To(Equal(expected))
+			},
+			Entry("minutes", helpers.StringPointer("5 m"), helpers.IntPointer(5)),
+			Entry("hours and minutes", helpers.StringPointer("1 hr 30 mins"), helpers.IntPointer(90)),
+			Entry("a range", helpers.StringPointer("1-2 hrs"), helpers.IntPointer(120)),
+			Entry("fractions of an hour", helpers.StringPointer("1.5 hours"), helpers.IntPointer(90)),
+			Entry("days", helpers.StringPointer("2 days"), helpers.IntPointer(2880)),
+			Entry("a number without a unit", helpers.StringPointer("45"), helpers.IntPointer(45)),
+			Entry("text without a duration", helpers.StringPointer("overnight"), nil),
+			Entry("no total time", nil, nil),
+		)
+
+		Context("when the tags cannot be saved", func() {
+			It("returns an error", func() {
+				mock.ExpectBegin()
+
+				mockTagsRepo.SetForRecipeFunc = func(ctx context.Context, id, uID int64, names []string) error {
+					return errors.New("tag could not be saved")
+				}
+
+				mock.ExpectRollback()
+
+				_, err := recipeService.CreateRecipe(ctx, userID, recipeInput)
+				Expect(err).To(MatchError("tag could not be saved"))
+			})
 		})
 
 		Context("when recipe insert fails", func() {
 			It("returns an error", func() {
 				mock.ExpectBegin()
 
-				mockRecipesRepo.InsertFunc = func(recipe *repositories.Recipe, userID int64) (int64, error) {
+				mockRecipesRepo.InsertFunc = func(ctx context.Context, recipe *repositories.Recipe, userID int64) (int64, error) {
 					return 0, errors.New("recipe could not be saved")
 				}
 
@@ -169,11 +244,11 @@ var _ = Describe("RecipeService", func() {
 			It("returns an error", func() {
 				mock.ExpectBegin()
 
-				mockRecipesRepo.InsertFunc = func(recipe *repositories.Recipe, userID int64) (int64, error) {
+				mockRecipesRepo.InsertFunc = func(ctx context.Context, recipe *repositories.Recipe, userID int64) (int64, error) {
 					return recipeID, nil
 				}
 
-				mockIngredientsRepo.InsertFunc = func(recipeID int64, ingredient *repositories.Ingredient) error {
+				mockIngredientsRepo.InsertFunc = func(ctx context.Context, recipeID int64, ingredient *repositories.Ingredient) error {
 					return errors.New("ingredient error")
 				}
 
@@ -191,15 +266,15 @@ var _ = Describe("RecipeService", func() {
 			It("returns an error", func() {
 				mock.ExpectBegin()
 
-				mockRecipesRepo.InsertFunc = func(recipe *repositories.Recipe, userID int64) (int64, error) {
+				mockRecipesRepo.InsertFunc = func(ctx context.Context, recipe *repositories.Recipe, userID int64) (int64, error) {
 					return recipeID, nil
 				}
 
-				mockIngredientsRepo.InsertFunc = func(recipeID int64, ingredient *repositories.Ingredient) error {
+				mockIngredientsRepo.InsertFunc = func(ctx context.Context, recipeID int64, ingredient *repositories.Ingredient) error {
 					return nil
 				}
 
-				mockStepsRepo.InsertFunc = func(recipeID int64, step *repositories.Step) error {
+				mockStepsRepo.InsertFunc = func(ctx context.Context, recipeID int64, step *repositories.Step) error {
 					return errors.New("step error")
 				}
 
@@ -214,10 +289,361 @@ var _ = Describe("RecipeService", func() {
 		})
 	})
 
+	Describe("UpdateRecipe", func() {
+		var recipeInput *services.RecipeInput
+
+		BeforeEach(func() {
+			recipeInput = &services.RecipeInput{
+				Name:        "Updated Recipe",
+				Description: "An updated recipe",
+				Servings:    helpers.IntPointer(2),
+				TotalTime:   helpers.StringPointer("20 minutes"),
+				Ingredients: []*services.IngredientInput{{
+					Name:     "Butter",
+					Amount:   "1",
+					Unit:     "stick",
+					OrderNum: 1,
+				}},
+				Steps: []*services.StepInput{{
+					Instructions: "Melt the butter",
+					OrderNum:     1,
+				}},
+			}
+
+			mockRecipesRepo.GetCreatorFunc = func(ctx context.Context, id int64) (int64, error) {
+				return userID, nil
+			}
+		})
+
+		Context("when updating a recipe successfully", func() {
+			It("updates the recipe and replaces its ingredients and steps", func() {
+				mock.ExpectBegin()
+
+				var calls []string
+				mockRecipesRepo.UpdateFunc = func(ctx context.Context, id int64, recipe *repositories.Recipe, uID int64) error {
+					calls = append(calls, "update")
+					Expect(id).To(Equal(recipeID))
+					Expect(uID).To(Equal(userID))
+					Expect(*recipe.Name).To(Equal("Updated Recipe"))
+					Expect(*recipe.Description).To(Equal("An updated recipe"))
+					Expect(*recipe.Servings).To(Equal(2))
+					Expect(*recipe.TotalTime).To(Equal("20 minutes"))
+					Expect(recipe.Source).To(BeNil())
+					return nil
+				}
+
+				refs := &repositories.IngredientRefs{IngredientIDs: []int64{3}, MeasurementIDs: []int64{4}}
+				mockIngredientsRepo.GetRefsForRecipeFunc = func(ctx context.Context, id int64) (*repositories.IngredientRefs, error) {
+					calls = append(calls, "get ingredient refs")
+					Expect(id).To(Equal(recipeID))
+					return refs, nil
+				}
+
+				mockIngredientsRepo.DeleteForRecipeFunc = func(ctx context.Context, id int64) error {
+					calls = append(calls, "delete ingredients")
+					Expect(id).To(Equal(recipeID))
+					return nil
+				}
+
+				mockStepsRepo.DeleteForRecipeFunc = func(ctx context.Context, id int64) error {
+					calls = append(calls, "delete steps")
+					Expect(id).To(Equal(recipeID))
+					return nil
+				}
+
+				mockIngredientsRepo.InsertFunc = func(ctx context.Context, id int64, ingredient *repositories.Ingredient) error {
+					calls = append(calls, "insert ingredient")
+					Expect(id).To(Equal(recipeID))
+					Expect(*ingredient.Ingredient).To(Equal("Butter"))
+					Expect(*ingredient.Measurement).To(Equal("stick"))
+					return nil
+				}
+
+				mockStepsRepo.InsertFunc = func(ctx context.Context, id int64, step *repositories.Step) error {
+					calls = append(calls, "insert step")
+					Expect(id).To(Equal(recipeID))
+					Expect(*step.Instructions).To(Equal("Melt the butter"))
+					return nil
+				}
+
+				mockTagsRepo.SetForRecipeFunc = func(ctx context.Context, id, uID int64, names []string) error {
+					calls = append(calls, "set tags")
+					Expect(id).To(Equal(recipeID))
+					Expect(names).To(Equal([]string{"weeknight"}))
+					return nil
+				}
+
+				mockIngredientsRepo.DeleteUnusedFunc = func(ctx context.Context, r *repositories.IngredientRefs) error {
+					calls = append(calls, "delete unused")
+					Expect(r).To(Equal(refs))
+					return nil
+				}
+
+				mockTagsRepo.DeleteUnusedFunc = func(ctx context.Context, uID int64) error {
+					calls = append(calls, "delete unused tags")
+					Expect(uID).To(Equal(userID))
+					return nil
+				}
+
+				mock.ExpectCommit()
+
+				recipeInput.Tags = []string{"Weeknight"}
+				err := recipeService.UpdateRecipe(ctx, recipeID, userID, recipeInput)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(calls).To(Equal([]string{
+					"update",
+					"get ingredient refs",
+					"delete ingredients",
+					"delete steps",
+					"insert ingredient",
+					"insert step",
+					"set tags",
+					"delete unused",
+					"delete unused tags",
+				}))
+			})
+		})
+
+		Context("when the recipe does not exist", func() {
+			It("returns the not found error without updating", func() {
+				mock.ExpectBegin()
+
+				mockRecipesRepo.GetCreatorFunc = func(ctx context.Context, id int64) (int64, error) {
+					return -1, sql.ErrNoRows
+				}
+
+				mockRecipesRepo.UpdateFunc = func(ctx context.Context, id int64, recipe *repositories.Recipe, userID int64) error {
+					Fail("update should not be called")
+					return nil
+				}
+
+				mock.ExpectRollback()
+
+				err := recipeService.UpdateRecipe(ctx, recipeID, userID, recipeInput)
+
+				Expect(err).To(MatchError(services.ErrNotFound))
+				Expect(err).To(MatchError(sql.ErrNoRows))
+			})
+		})
+
+		Context("when the recipe belongs to another user", func() {
+			It("returns a forbidden error without updating", func() {
+				mock.ExpectBegin()
+
+				mockRecipesRepo.GetCreatorFunc = func(ctx context.Context, id int64) (int64, error) {
+					return userID + 1, nil
+				}
+
+				mockRecipesRepo.UpdateFunc = func(ctx context.Context, id int64, recipe *repositories.Recipe, userID int64) error {
+					Fail("update should not be called")
+					return nil
+				}
+
+				mock.ExpectRollback()
+
+				err := recipeService.UpdateRecipe(ctx, recipeID, userID, recipeInput)
+
+				Expect(err).To(MatchError(services.ErrForbidden))
+			})
+		})
+
+		Context("when the recipe update fails", func() {
+			It("returns an error", func() {
+				mock.ExpectBegin()
+
+				mockRecipesRepo.UpdateFunc = func(ctx context.Context, id int64, recipe *repositories.Recipe, userID int64) error {
+					return errors.New("recipe could not be updated")
+				}
+
+				mock.ExpectRollback()
+
+				err := recipeService.UpdateRecipe(ctx, recipeID, userID, recipeInput)
+
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("recipe could not be updated"))
+			})
+		})
+
+		Context("when deleting the existing ingredients fails", func() {
+			It("returns an error", func() {
+				mock.ExpectBegin()
+
+				mockIngredientsRepo.DeleteForRecipeFunc = func(ctx context.Context, recipeID int64) error {
+					return errors.New("recipe ingredients could not be deleted")
+				}
+
+				mock.ExpectRollback()
+
+				err := recipeService.UpdateRecipe(ctx, recipeID, userID, recipeInput)
+
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("recipe ingredients could not be deleted"))
+			})
+		})
+
+		Context("when deleting the existing steps fails", func() {
+			It("returns an error", func() {
+				mock.ExpectBegin()
+
+				mockStepsRepo.DeleteForRecipeFunc = func(ctx context.Context, recipeID int64) error {
+					return errors.New("recipe steps could not be deleted")
+				}
+
+				mock.ExpectRollback()
+
+				err := recipeService.UpdateRecipe(ctx, recipeID, userID, recipeInput)
+
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("recipe steps could not be deleted"))
+			})
+		})
+	})
+
+	Describe("DeleteRecipe", func() {
+		BeforeEach(func() {
+			mockRecipesRepo.GetCreatorFunc = func(ctx context.Context, id int64) (int64, error) {
+				return userID, nil
+			}
+		})
+
+		Context("when deleting a recipe successfully", func() {
+			It("deletes the recipe and cleans up unused ingredients", func() {
+				mock.ExpectBegin()
+
+				var calls []string
+				refs := &repositories.IngredientRefs{IngredientIDs: []int64{3}}
+				mockIngredientsRepo.GetRefsForRecipeFunc = func(ctx context.Context, id int64) (*repositories.IngredientRefs, error) {
+					calls = append(calls, "get ingredient refs")
+					Expect(id).To(Equal(recipeID))
+					return refs, nil
+				}
+
+				mockRecipesRepo.DeleteFunc = func(ctx context.Context, id, uID int64) error {
+					calls = append(calls, "delete")
+					Expect(id).To(Equal(recipeID))
+					Expect(uID).To(Equal(userID))
+					return nil
+				}
+
+				mockIngredientsRepo.DeleteUnusedFunc = func(ctx context.Context, r *repositories.IngredientRefs) error {
+					calls = append(calls, "delete unused")
+					Expect(r).To(Equal(refs))
+					return nil
+				}
+
+				mock.ExpectCommit()
+
+				err := recipeService.DeleteRecipe(ctx, recipeID, userID)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(calls).To(Equal([]string{"get ingredient refs", "delete", "delete unused"}))
+			})
+		})
+
+		Context("when the recipe does not exist", func() {
+			It("returns the not found error", func() {
+				mock.ExpectBegin()
+
+				mockRecipesRepo.GetCreatorFunc = func(ctx context.Context, id int64) (int64, error) {
+					return -1, sql.ErrNoRows
+				}
+
+				mockRecipesRepo.DeleteFunc = func(ctx context.Context, id, userID int64) error {
+					Fail("delete should not be called")
+					return nil
+				}
+
+				mock.ExpectRollback()
+
+				err := recipeService.DeleteRecipe(ctx, recipeID, userID)
+
+				Expect(err).To(MatchError(services.ErrNotFound))
+				Expect(err).To(MatchError(sql.ErrNoRows))
+			})
+		})
+
+		Context("when the recipe belongs to another user", func() {
+			It("returns a forbidden error", func() {
+				mock.ExpectBegin()
+
+				mockRecipesRepo.GetCreatorFunc = func(ctx context.Context, id int64) (int64, error) {
+					return userID + 1, nil
+				}
+
+				mockRecipesRepo.DeleteFunc = func(ctx context.Context, id, userID int64) error {
+					Fail("delete should not be called")
+					return nil
+				}
+
+				mock.ExpectRollback()
+
+				err := recipeService.DeleteRecipe(ctx, recipeID, userID)
+
+				Expect(err).To(MatchError(services.ErrForbidden))
+			})
+		})
+
+		Context("when the delete fails", func() {
+			It("returns an error", func() {
+				mock.ExpectBegin()
+
+				mockRecipesRepo.DeleteFunc = func(ctx context.Context, id, userID int64) error {
+					return errors.New("recipe could not be deleted")
+				}
+
+				mock.ExpectRollback()
+
+				err := recipeService.DeleteRecipe(ctx, recipeID, userID)
+
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("recipe could not be deleted"))
+			})
+		})
+
+		Context("when the recipe's ingredients cannot be looked up", func() {
+			It("returns an error without deleting", func() {
+				mock.ExpectBegin()
+
+				mockIngredientsRepo.GetRefsForRecipeFunc = func(ctx context.Context, id int64) (*repositories.IngredientRefs, error) {
+					return nil, errors.New("failed to fetch recipe ingredient references")
+				}
+
+				mockRecipesRepo.DeleteFunc = func(ctx context.Context, id, uID int64) error {
+					Fail("delete should not be called")
+					return nil
+				}
+
+				mock.ExpectRollback()
+
+				err := recipeService.DeleteRecipe(ctx, recipeID, userID)
+
+				Expect(err).To(MatchError("failed to fetch recipe ingredient references"))
+			})
+		})
+
+		Context("when cleaning up unused ingredients fails", func() {
+			It("returns an error", func() {
+				mock.ExpectBegin()
+
+				mockIngredientsRepo.DeleteUnusedFunc = func(ctx context.Context, refs *repositories.IngredientRefs) error {
+					return errors.New("unused ingredients could not be deleted")
+				}
+
+				mock.ExpectRollback()
+
+				err := recipeService.DeleteRecipe(ctx, recipeID, userID)
+
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("unused ingredients could not be deleted"))
+			})
+		})
+	})
+
 	Describe("GetRecipe", func() {
 		Context("when recipe exists", func() {
 			It("returns the recipe with ingredients and steps", func() {
-				mockRecipesRepo.GetFunc = func(id, userID int64) (*repositories.Recipe, error) {
+				mockRecipesRepo.GetFunc = func(ctx context.Context, id, userID int64) (*repositories.Recipe, error) {
 					Expect(id).To(Equal(recipeID))
 					Expect(userID).To(Equal(userID))
 					return &repositories.Recipe{
@@ -234,7 +660,7 @@ var _ = Describe("RecipeService", func() {
 					}, nil
 				}
 
-				mockIngredientsRepo.GetForRecipeFunc = func(recipeID int64) ([]*repositories.Ingredient, error) {
+				mockIngredientsRepo.GetForRecipeFunc = func(ctx context.Context, recipeID int64) ([]*repositories.Ingredient, error) {
 					Expect(recipeID).To(Equal(recipeID))
 					return []*repositories.Ingredient{
 						{
@@ -254,7 +680,11 @@ var _ = Describe("RecipeService", func() {
 					}, nil
 				}
 
-				mockStepsRepo.GetForRecipeFunc = func(recipeID int64) ([]*repositories.Step, error) {
+				mockTagsRepo.GetForRecipeFunc = func(ctx context.Context, recipeID int64) ([]string, error) {
+					return []string{"baking", "dessert"}, nil
+				}
+
+				mockStepsRepo.GetForRecipeFunc = func(ctx context.Context, recipeID int64) ([]*repositories.Step, error) {
 					Expect(recipeID).To(Equal(recipeID))
 					return []*repositories.Step{
 						{
@@ -293,26 +723,38 @@ var _ = Describe("RecipeService", func() {
 				Expect(result.Steps[0].Instructions).To(Equal("Mix dry ingredients"))
 				Expect(result.Steps[0].OrderNum).To(Equal(1))
 				Expect(result.Steps[0].Notes).To(BeNil())
+
+				Expect(result.Tags).To(Equal([]string{"baking", "dessert"}))
 			})
 		})
 
 		Context("when recipe does not exist", func() {
-			It("returns an error", func() {
-				mockRecipesRepo.GetFunc = func(id, userID int64) (*repositories.Recipe, error) {
+			It("returns the not found error", func() {
+				mockRecipesRepo.GetFunc = func(ctx context.Context, id, userID int64) (*repositories.Recipe, error) {
 					return nil, nil
 				}
 
 				result, err := recipeService.GetRecipe(ctx, recipeID, userID)
 
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("recipe not found"))
+				Expect(err).To(MatchError(services.ErrNotFound))
 				Expect(result).To(BeNil())
 			})
+
+			It("wraps the repository's no rows error", func() {
+				mockRecipesRepo.GetFunc = func(ctx context.Context, id, userID int64) (*repositories.Recipe, error) {
+					return nil, sql.ErrNoRows
+				}
+
+				_, err := recipeService.GetRecipe(ctx, recipeID, userID)
+
+				Expect(err).To(MatchError(services.ErrNotFound))
+				Expect(err).To(MatchError(sql.ErrNoRows))
+			})
 		})
 
 		Context("when ingredients query fails", func() {
 			It("returns an error", func() {
-				mockRecipesRepo.GetFunc = func(id, userID int64) (*repositories.Recipe, error) {
+				mockRecipesRepo.GetFunc = func(ctx context.Context, id, userID int64) (*repositories.Recipe, error) {
 					return &repositories.Recipe{
 						ID:          helpers.Int64Pointer(recipeID),
 						Name:        helpers.StringPointer("Test Recipe"),
@@ -321,7 +763,7 @@ var _ = Describe("RecipeService", func() {
 					}, nil
 				}
 
-				mockIngredientsRepo.GetForRecipeFunc = func(recipeID int64) ([]*repositories.Ingredient, error) {
+				mockIngredientsRepo.GetForRecipeFunc = func(ctx context.Context, recipeID int64) ([]*repositories.Ingredient, error) {
 					return nil, errors.New("ingredients error")
 				}
 
@@ -335,7 +777,7 @@ var _ = Describe("RecipeService", func() {
 
 		Context("when steps query fails", func() {
 			It("returns an error", func() {
-				mockRecipesRepo.GetFunc = func(id, userID int64) (*repositories.Recipe, error) {
+				mockRecipesRepo.GetFunc = func(ctx context.Context, id, userID int64) (*repositories.Recipe, error) {
 					return &repositories.Recipe{
 						ID:          helpers.Int64Pointer(recipeID),
 						Name:        helpers.StringPointer("Test Recipe"),
@@ -344,11 +786,11 @@ var _ = Describe("RecipeService", func() {
 					}, nil
 				}
 
-				mockIngredientsRepo.GetForRecipeFunc = func(recipeID int64) ([]*repositories.Ingredient, error) {
+				mockIngredientsRepo.GetForRecipeFunc = func(ctx context.Context, recipeID int64) ([]*repositories.Ingredient, error) {
 					return []*repositories.Ingredient{}, nil
 				}
 
-				mockStepsRepo.GetForRecipeFunc = func(recipeID int64) ([]*repositories.Step, error) {
+				mockStepsRepo.GetForRecipeFunc = func(ctx context.Context, recipeID int64) ([]*repositories.Step, error) {
 					return nil, errors.New("steps error")
 				}
 
@@ -361,11 +803,89 @@ var _ = Describe("RecipeService", func() {
 		})
 	})
 
+	Describe("ScaleRecipe", func() {
+		var servings *int
+
+		BeforeEach(func() {
+			servings = helpers.IntPointer(4)
+
+			mockRecipesRepo.GetFunc = func(ctx context.Context, id, uID int64) (*repositories.Recipe, error) {
+				return &repositories.Recipe{
+					ID:          helpers.Int64Pointer(recipeID),
+					Name:        helpers.StringPointer("Pancakes"),
+					Description: helpers.StringPointer("Fluffy"),
+					Creator:     helpers.StringPointer("User1"),
+					Servings:    servings,
+				}, nil
+			}
+
+			mockIngredientsRepo.GetForRecipeFunc = func(ctx context.Context, id int64) ([]*repositories.Ingredient, error) {
+				return []*repositories.Ingredient{
+					{Ingredient: helpers.StringPointer("Flour"), IngredientNumber: helpers.IntPointer(1), Amount: helpers.StringPointer("1 1/2")},
+					{Ingredient: helpers.StringPointer("Eggs"), IngredientNumber: helpers.IntPointer(2), Amount: helpers.StringPointer("2-3")},
+					{Ingredient: helpers.StringPointer("Sugar"), IngredientNumber: helpers.IntPointer(3), Amount: helpers.StringPointer("⅓")},
+					{Ingredient: helpers.StringPointer("Salt"), IngredientNumber: helpers.IntPointer(4), Amount: helpers.StringPointer("a pinch")},
+					{Ingredient: helpers.StringPointer("Butter"), IngredientNumber: helpers.IntPointer(5), Amount: nil},
+				}, nil
+			}
+		})
+
+		It("scales the ingredient amounts to the servings", func() {
+			recipe, err := recipeService.ScaleRecipe(ctx, recipeID, userID, 6)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(*recipe.Servings).To(Equal(6))
+
+			amounts := make([]*string, len(recipe.Ingredients))
+			for i, ingredient := range recipe.Ingredients {
+				amounts[i] = ingredient.Amount
+			}
+			Expect(amounts).To(Equal([]*string{
+				helpers.StringPointer("2 1/4"),
+				helpers.StringPointer("3 to 4 1/2"),
+				helpers.StringPointer("1/2"),
+				helpers.StringPointer("a pinch"),
+				nil,
+			}))
+		})
+
+		It("marks the amounts that could not be scaled", func() {
+			recipe, err := recipeService.ScaleRecipe(ctx, recipeID, userID, 2)
+			Expect(err).ToNot(HaveOccurred())
+
+			var unscaled []string
+			for _, ingredient := range recipe.Ingredients {
+				if ingredient.Unscaled {
+					unscaled = append(unscaled, ingredient.Name)
+				}
+			}
+			Expect(unscaled).To(Equal([]string{"Salt"}))
+		})
+
+		It("returns ErrNoServings if the recipe has no servings", func() {
+			*servings = 0
+
+			_, err := recipeService.ScaleRecipe(ctx, recipeID, userID, 2)
+			Expect(err).To(MatchError(services.ErrNoServings))
+		})
+
+		It("returns the not found error if the recipe does not exist", func() {
+			mockRecipesRepo.GetFunc = func(ctx context.Context, id, uID int64) (*repositories.Recipe, error) {
+				return nil, sql.ErrNoRows
+			}
+
+			_, err := recipeService.ScaleRecipe(ctx, recipeID, userID, 2)
+			Expect(err).To(MatchError(services.ErrNotFound))
+		})
+	})
+
 	Describe("ListRecipes", func() {
 		Context("when recipes exist", func() {
-			It("returns a list of recipe summaries", func() {
-				mockRecipesRepo.ListFunc = func(userID int64) ([]*repositories.Recipe, error) {
-					Expect(userID).To(Equal(userID))
+			It("returns the first page of recipe summaries", func() {
+				var received *repositories.RecipePage
+				mockRecipesRepo.ListFunc = func(ctx context.Context, uID int64, filter *repositories.RecipeFilter, page *repositories.RecipePage) ([]*repositories.Recipe, *repositories.RecipeCursor, error) {
+					Expect(uID).To(Equal(userID))
+					received = page
 					return []*repositories.Recipe{
 						{
 							ID:          helpers.Int64Pointer(1),
@@ -377,123 +897,495 @@ var _ = Describe("RecipeService", func() {
 							Name:        helpers.StringPointer("Recipe 2"),
 							Description: helpers.StringPointer("Second recipe"),
 						},
-						{
-							ID:          helpers.Int64Pointer(3),
-							Name:        helpers.StringPointer("Recipe 3"),
-							Description: helpers.StringPointer("Third recipe"),
-						},
-					}, nil
+					}, nil, nil
+				}
+				mockRecipesRepo.CountFunc = func(ctx context.Context, uID int64, filter *repositories.RecipeFilter) (int, error) {
+					return 2, nil
+				}
+
+				result, err := recipeService.ListRecipes(ctx, userID, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(received).To(Equal(&repositories.RecipePage{
+					Sort:  repositories.RecipeSortName,
+					Limit: services.DefaultRecipePageSize,
+				}))
+
+				Expect(result.Total).To(Equal(2))
+				Expect(result.NextCursor).To(BeEmpty())
+				Expect(result.Recipes).To(Equal([]*services.RecipeSummary{
+					{ID: 1, Name: "Recipe 1", Description: "First recipe"},
+					{ID: 2, Name: "Recipe 2", Description: "Second recipe"},
+				}))
+			})
+		})
+
+		Context("when there is another page", func() {
+			It("returns a cursor that continues from the end of the page", func() {
+				var pages []*repositories.RecipePage
+				mockRecipesRepo.ListFunc = func(ctx context.Context, uID int64, filter *repositories.RecipeFilter, page *repositories.RecipePage) ([]*repositories.Recipe, *repositories.RecipeCursor, error) {
+					pages = append(pages, page)
+					return []*repositories.Recipe{}, &repositories.RecipeCursor{Value: "2024-06-01 10:00:00", ID: 3}, nil
+				}
+
+				options := &services.RecipeListOptions{
+					Sort:       services.RecipeSortCreatedAt,
+					Descending: true,
+					Limit:      2,
+				}
+
+				result, err := recipeService.ListRecipes(ctx, userID, options)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.NextCursor).ToNot(BeEmpty())
+
+				options.After = result.NextCursor
+				_, err = recipeService.ListRecipes(ctx, userID, options)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(pages).To(HaveLen(2))
+				Expect(pages[1]).To(Equal(&repositories.RecipePage{
+					Sort:       repositories.RecipeSortCreatedAt,
+					Descending: true,
+					Limit:      2,
+					After:      &repositories.RecipeCursor{Value: "2024-06-01 10:00:00", ID: 3},
+				}))
+			})
+
+			It("rejects the cursor if the sort has changed", func() {
+				mockRecipesRepo.ListFunc = func(ctx context.Context, uID int64, filter *repositories.RecipeFilter, page *repositories.RecipePage) ([]*repositories.Recipe, *repositories.RecipeCursor, error) {
+					return []*repositories.Recipe{}, &repositories.RecipeCursor{Value: "Recipe 2", ID: 2}, nil
 				}
 
-				result, err := recipeService.ListRecipes(ctx, userID)
+				result, err := recipeService.ListRecipes(ctx, userID, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = recipeService.ListRecipes(ctx, userID, &services.RecipeListOptions{
+					Sort:  services.RecipeSortTotalTime,
+					After: result.NextCursor,
+				})
+				Expect(err).To(MatchError(services.ErrInvalidCursor))
+			})
+		})
+
+		Context("when the cursor is not valid", func() {
+			It("returns an error without listing the recipes", func() {
+				mockRecipesRepo.ListFunc = func(ctx context.Context, uID int64, filter *repositories.RecipeFilter, page *repositories.RecipePage) ([]*repositories.Recipe, *repositories.RecipeCursor, error) {
+					Fail("recipes should not be listed")
+					return nil, nil, nil
+				}
+
+				_, err := recipeService.ListRecipes(ctx, userID, &services.RecipeListOptions{After: "not a cursor"})
+				Expect(err).To(MatchError(services.ErrInvalidCursor))
+			})
+		})
+
+		Context("when the limit is too large", func() {
+			It("lists at most the maximum page size", func() {
+				var received *repositories.RecipePage
+				mockRecipesRepo.ListFunc = func(ctx context.Context, uID int64, filter *repositories.RecipeFilter, page *repositories.RecipePage) ([]*repositories.Recipe, *repositories.RecipeCursor, error) {
+					received = page
+					return []*repositories.Recipe{}, nil, nil
+				}
 
+				_, err := recipeService.ListRecipes(ctx, userID, &services.RecipeListOptions{Limit: 1000})
 				Expect(err).ToNot(HaveOccurred())
-				Expect(result).To(HaveLen(3))
+				Expect(received.Limit).To(Equal(services.MaxRecipePageSize))
+			})
+		})
 
-				Expect(result[0].ID).To(Equal(int64(1)))
-				Expect(result[0].Name).To(Equal("Recipe 1"))
-				Expect(result[0].Description).To(Equal("First recipe"))
+		Context("when filtering", func() {
+			It("passes the filter to the repository", func() {
+				var listed, counted *repositories.RecipeFilter
+				mockRecipesRepo.ListFunc = func(ctx context.Context, uID int64, filter *repositories.RecipeFilter, page *repositories.RecipePage) ([]*repositories.Recipe, *repositories.RecipeCursor, error) {
+					listed = filter
+					return []*repositories.Recipe{}, nil, nil
+				}
+				mockRecipesRepo.CountFunc = func(ctx context.Context, uID int64, filter *repositories.RecipeFilter) (int, error) {
+					counted = filter
+					return 0, nil
+				}
 
-				Expect(result[1].ID).To(Equal(int64(2)))
-				Expect(result[1].Name).To(Equal("Recipe 2"))
-				Expect(result[1].Description).To(Equal("Second recipe"))
+				_, err := recipeService.ListRecipes(ctx, userID, &services.RecipeListOptions{
+					Filter: &services.RecipeFilter{
+						CookbookID:  helpers.Int64Pointer(2),
+						SectionID:   helpers.Int64Pointer(3),
+						MinServings: helpers.IntPointer(4),
+						MaxServings: helpers.IntPointer(6),
+						Source:      helpers.StringPointer("Grandma"),
 
-				Expect(result[2].ID).To(Equal(int64(3)))
-				Expect(result[2].Name).To(Equal("Recipe 3"))
-				Expect(result[2].Description).To(Equal("Third recipe"))
+						Tags:         []string{"Dessert", " dessert", "Quick  Bake"},
+						MatchAllTags: true,
+					},
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				expected := &repositories.RecipeFilter{
+					CookbookID:  helpers.Int64Pointer(2),
+					SectionID:   helpers.Int64Pointer(3),
+					MinServings: helpers.IntPointer(4),
+					MaxServings: helpers.IntPointer(6),
+					Source:      helpers.StringPointer("Grandma"),
+
+					Tags:         []string{"dessert", "quick bake"},
+					MatchAllTags: true,
+				}
+				Expect(listed).To(Equal(expected))
+				Expect(counted).To(Equal(expected))
 			})
 		})
 
-		Context("when no recipes exist", func() {
+		Context("when there are no recipes", func() {
 			It("returns an empty list", func() {
-				mockRecipesRepo.ListFunc = func(userID int64) ([]*repositories.Recipe, error) {
-					return []*repositories.Recipe{}, nil
+				mockRecipesRepo.ListFunc = func(ctx context.Context, uID int64, filter *repositories.RecipeFilter, page *repositories.RecipePage) ([]*repositories.Recipe, *repositories.RecipeCursor, error) {
+					return nil, nil, nil
+				}
+				mockRecipesRepo.CountFunc = func(ctx context.Context, uID int64, filter *repositories.RecipeFilter) (int, error) {
+					return 0, nil
 				}
 
-				result, err := recipeService.ListRecipes(ctx, userID)
-
+				result, err := recipeService.ListRecipes(ctx, userID, nil)
 				Expect(err).ToNot(HaveOccurred())
-				Expect(result).To(HaveLen(0))
+				Expect(result.Recipes).To(BeEmpty())
+				Expect(result.Recipes).ToNot(BeNil())
+				Expect(result.Total).To(Equal(0))
+				Expect(result.NextCursor).To(BeEmpty())
 			})
 		})
 
 		Context("when database query fails", func() {
 			It("returns an error", func() {
-				mockRecipesRepo.ListFunc = func(userID int64) ([]*repositories.Recipe, error) {
-					return nil, errors.New("database error")
+				mockRecipesRepo.ListFunc = func(ctx context.Context, uID int64, filter *repositories.RecipeFilter, page *repositories.RecipePage) ([]*repositories.Recipe, *repositories.RecipeCursor, error) {
+					return nil, nil, errors.New("database error")
 				}
 
-				result, err := recipeService.ListRecipes(ctx, userID)
+				result, err := recipeService.ListRecipes(ctx, userID, nil)
 
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("database error"))
 				Expect(result).To(BeNil())
 			})
+
+			It("returns an error if the recipes cannot be counted", func() {
+				mockRecipesRepo.CountFunc = func(ctx context.Context, uID int64, filter *repositories.RecipeFilter) (int, error) {
+					return 0, errors.New("count error")
+				}
+
+				_, err := recipeService.ListRecipes(ctx, userID, nil)
+				Expect(err).To(MatchError("count error"))
+			})
+		})
+	})
+
+	Describe("ListTags", func() {
+		It("returns the user's tags with their recipe counts", func() {
+			mockTagsRepo.ListFunc = func(ctx context.Context, uID int64) ([]*repositories.Tag, error) {
+				Expect(uID).To(Equal(userID))
+				return []*repositories.Tag{
+					{ID: 2, Name: "dessert", RecipeCount: 3},
+					{ID: 1, Name: "quick", RecipeCount: 1},
+				}, nil
+			}
+
+			tags, err := recipeService.ListTags(ctx, userID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tags).To(Equal([]*services.TagSummary{
+				{Name: "dessert", RecipeCount: 3},
+				{Name: "quick", RecipeCount: 1},
+			}))
+		})
+
+		It("returns an error if the tags cannot be listed", func() {
+			mockTagsRepo.ListFunc = func(ctx context.Context, uID int64) ([]*repositories.Tag, error) {
+				return nil, errors.New("failed to fetch tags")
+			}
+
+			_, err := recipeService.ListTags(ctx, userID)
+			Expect(err).To(MatchError("failed to fetch tags"))
+		})
+	})
+
+	Describe("SearchRecipes", func() {
+		var recipeInput *services.RecipeInput
+
+		BeforeEach(func() {
+			recipeInput = &services.RecipeInput{
+				Name:        "Banana Bread",
+				Description: "A quick bread",
+				Ingredients: []*services.IngredientInput{{Name: "Bananas", OrderNum: 1}},
+				Steps:       []*services.StepInput{{Instructions: "Mash the bananas", OrderNum: 1}},
+			}
+
+			mockRecipesRepo.InsertFunc = func(ctx context.Context, recipe *repositories.Recipe, userID int64) (int64, error) {
+				return recipeID, nil
+			}
+			mockRecipesRepo.GetCreatorFunc = func(ctx context.Context, id int64) (int64, error) {
+				return userID, nil
+			}
+		})
+
+		It("finds recipes once they are created", func() {
+			mock.ExpectBegin()
+			mock.ExpectCommit()
+
+			_, err := recipeService.CreateRecipe(ctx, userID, recipeInput)
+			Expect(err).ToNot(HaveOccurred())
+
+			results, err := recipeService.SearchRecipes(ctx, userID, "bananas", 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(Equal([]*services.SearchResult{{
+				ID:          recipeID,
+				Name:        "Banana Bread",
+				Description: "A quick bread",
+				Highlights: []*services.SearchHighlight{
+					{Field: "ingredient", Text: "<mark>Bananas</mark>"},
+					{Field: "step", Text: "Mash the <mark>bananas</mark>"},
+				},
+			}}))
+
+			results, err = recipeService.SearchRecipes(ctx, 999, "bananas", 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(BeEmpty())
+		})
+
+		It("searches the updated recipe", func() {
+			mock.ExpectBegin()
+			mock.ExpectCommit()
+			_, err := recipeService.CreateRecipe(ctx, userID, recipeInput)
+			Expect(err).ToNot(HaveOccurred())
+
+			recipeInput.Name = "Zucchini Bread"
+			recipeInput.Ingredients = []*services.IngredientInput{{Name: "Zucchini", OrderNum: 1}}
+			recipeInput.Steps = []*services.StepInput{{Instructions: "Grate the zucchini", OrderNum: 1}}
+
+			mock.ExpectBegin()
+			mock.ExpectCommit()
+			err = recipeService.UpdateRecipe(ctx, recipeID, userID, recipeInput)
+			Expect(err).ToNot(HaveOccurred())
+
+			results, err := recipeService.SearchRecipes(ctx, userID, "bananas", 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(BeEmpty())
+
+			results, err = recipeService.SearchRecipes(ctx, userID, "zucchini", 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+		})
+
+		It("stops finding recipes once they are deleted", func() {
+			mock.ExpectBegin()
+			mock.ExpectCommit()
+			_, err := recipeService.CreateRecipe(ctx, userID, recipeInput)
+			Expect(err).ToNot(HaveOccurred())
+
+			mock.ExpectBegin()
+			mock.ExpectCommit()
+			err = recipeService.DeleteRecipe(ctx, recipeID, userID)
+			Expect(err).ToNot(HaveOccurred())
+
+			results, err := recipeService.SearchRecipes(ctx, userID, "bananas", 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(BeEmpty())
+		})
+
+		It("does not index recipes that fail to save", func() {
+			mock.ExpectBegin()
+			mockRecipesRepo.InsertFunc = func(ctx context.Context, recipe *repositories.Recipe, userID int64) (int64, error) {
+				return 0, errors.New("recipe could not be saved")
+			}
+			mock.ExpectRollback()
+
+			_, err := recipeService.CreateRecipe(ctx, userID, recipeInput)
+			Expect(err).To(HaveOccurred())
+
+			results, err := recipeService.SearchRecipes(ctx, userID, "bananas", 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(BeEmpty())
+		})
+
+		It("returns no results for a query without words", func() {
+			results, err := recipeService.SearchRecipes(ctx, userID, " ?! ", 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(BeEmpty())
 		})
 	})
 })
 
 type MockRecipesRepository struct {
-	InsertFunc func(recipe *repositories.Recipe, userID int64) (int64, error)
-	GetFunc    func(id, userID int64) (*repositories.Recipe, error)
-	ListFunc   func(userID int64) ([]*repositories.Recipe, error)
+	InsertFunc     func(ctx context.Context, recipe *repositories.Recipe, userID int64) (int64, error)
+	GetFunc        func(ctx context.Context, id, userID int64) (*repositories.Recipe, error)
+	ListFunc       func(ctx context.Context, userID int64, filter *repositories.RecipeFilter, page *repositories.RecipePage) ([]*repositories.Recipe, *repositories.RecipeCursor, error)
+	CountFunc      func(ctx context.Context, userID int64, filter *repositories.RecipeFilter) (int, error)
+	GetCreatorFunc func(ctx context.Context, id int64) (int64, error)
+	UpdateFunc     func(ctx context.Context, id int64, recipe *repositories.Recipe, userID int64) error
+	DeleteFunc     func(ctx context.Context, id, userID int64) error
 }
 
-func (m *MockRecipesRepository) Insert(recipe *repositories.Recipe, userID int64) (int64, error) {
+func (m *MockRecipesRepository) Insert(ctx context.Context, recipe *repositories.Recipe, userID int64) (int64, error) {
 	if m.InsertFunc != nil {
-		return m.InsertFunc(recipe, userID)
+		return m.InsertFunc(ctx, recipe, userID)
 	}
 	return 0, nil
 }
 
-func (m *MockRecipesRepository) Get(id, userID int64) (*repositories.Recipe, error) {
+func (m *MockRecipesRepository) Get(ctx context.Context, id, userID int64) (*repositories.Recipe, error) {
 	if m.GetFunc != nil {
-		return m.GetFunc(id, userID)
+		return m.GetFunc(ctx, id, userID)
 	}
 	return nil, nil
 }
 
-func (m *MockRecipesRepository) List(userID int64) ([]*repositories.Recipe, error) {
+func (m *MockRecipesRepository) List(ctx context.Context, userID int64, filter *repositories.RecipeFilter, page *repositories.RecipePage) ([]*repositories.Recipe, *repositories.RecipeCursor, error) {
 	if m.ListFunc != nil {
-		return m.ListFunc(userID)
+		return m.ListFunc(ctx, userID, filter, page)
 	}
-	return nil, nil
+	return nil, nil, nil
+}
+
+func (m *MockRecipesRepository) Count(ctx context.Context, userID int64, filter *repositories.RecipeFilter) (int, error) {
+	if m.CountFunc != nil {
+		return m.CountFunc(ctx, userID, filter)
+	}
+	return 0, nil
+}
+
+func (m *MockRecipesRepository) GetCreator(ctx context.Context, id int64) (int64, error) {
+	if m.GetCreatorFunc != nil {
+		return m.GetCreatorFunc(ctx, id)
+	}
+	return -1, nil
+}
+
+func (m *MockRecipesRepository) Update(ctx context.Context, id int64, recipe *repositories.Recipe, userID int64) error {
+	if m.UpdateFunc != nil {
+		return m.UpdateFunc(ctx, id, recipe, userID)
+	}
+	return nil
+}
+
+func (m *MockRecipesRepository) Delete(ctx context.Context, id, userID int64) error {
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(ctx, id, userID)
+	}
+	return nil
 }
 
 type MockIngredientsRepository struct {
-	InsertFunc       func(recipeID int64, ingredient *repositories.Ingredient) error
-	GetForRecipeFunc func(recipeID int64) ([]*repositories.Ingredient, error)
+	InsertFunc               func(ctx context.Context, recipeID int64, ingredient *repositories.Ingredient) error
+	GetForRecipeFunc         func(ctx context.Context, recipeID int64) ([]*repositories.Ingredient, error)
+	MatchPantryFunc          func(ctx context.Context, userID int64, query *repositories.PantryQuery) ([]*repositories.PantryRecipe, error)
+	GetMissingForRecipesFunc func(ctx context.Context, recipeIDs []int64, query *repositories.PantryQuery) (map[int64][]string, error)
+	GetRefsForRecipeFunc     func(ctx context.Context, recipeID int64) (*repositories.IngredientRefs, error)
+	DeleteForRecipeFunc      func(ctx context.Context, recipeID int64) error
+	DeleteUnusedFunc         func(ctx context.Context, refs *repositories.IngredientRefs) error
 }
 
-func (m *MockIngredientsRepository) Insert(recipeID int64, ingredient *repositories.Ingredient) error {
+func (m *MockIngredientsRepository) Insert(ctx context.Context, recipeID int64, ingredient *repositories.Ingredient) error {
 	if m.InsertFunc != nil {
-		return m.InsertFunc(recipeID, ingredient)
+		return m.InsertFunc(ctx, recipeID, ingredient)
+	}
+	return nil
+}
+
+func (m *MockIngredientsRepository) GetForRecipe(ctx context.Context, recipeID int64) ([]*repositories.Ingredient, error) {
+	if m.GetForRecipeFunc != nil {
+		return m.GetForRecipeFunc(ctx, recipeID)
+	}
+	return nil, nil
+}
+
+func (m *MockIngredientsRepository) MatchPantry(ctx context.Context, userID int64, query *repositories.PantryQuery) ([]*repositories.PantryRecipe, error) {
+	if m.MatchPantryFunc != nil {
+		return m.MatchPantryFunc(ctx, userID, query)
+	}
+	return nil, nil
+}
+
+func (m *MockIngredientsRepository) GetMissingForRecipes(ctx context.Context, recipeIDs []int64, query *repositories.PantryQuery) (map[int64][]string, error) {
+	if m.GetMissingForRecipesFunc != nil {
+		return m.GetMissingForRecipesFunc(ctx, recipeIDs, query)
+	}
+	return nil, nil
+}
+
+func (m *MockIngredientsRepository) GetRefsForRecipe(ctx context.Context, recipeID int64) (*repositories.IngredientRefs, error) {
+	if m.GetRefsForRecipeFunc != nil {
+		return m.GetRefsForRecipeFunc(ctx, recipeID)
+	}
+	return &repositories.IngredientRefs{}, nil
+}
+
+func (m *MockIngredientsRepository) DeleteForRecipe(ctx context.Context, recipeID int64) error {
+	if m.DeleteForRecipeFunc != nil {
+		return m.DeleteForRecipeFunc(ctx, recipeID)
+	}
+	return nil
+}
+
+func (m *MockIngredientsRepository) DeleteUnused(ctx context.Context, refs *repositories.IngredientRefs) error {
+	if m.DeleteUnusedFunc != nil {
+		return m.DeleteUnusedFunc(ctx, refs)
 	}
 	return nil
 }
 
-func (m *MockIngredientsRepository) GetForRecipe(recipeID int64) ([]*repositories.Ingredient, error) {
+type MockTagsRepository struct {
+	ListFunc         func(ctx context.Context, userID int64) ([]*repositories.Tag, error)
+	GetForRecipeFunc func(ctx context.Context, recipeID int64) ([]string, error)
+	SetForRecipeFunc func(ctx context.Context, recipeID, userID int64, names []string) error
+	DeleteUnusedFunc func(ctx context.Context, userID int64) error
+}
+
+func (m *MockTagsRepository) List(ctx context.Context, userID int64) ([]*repositories.Tag, error) {
+	if m.ListFunc != nil {
+		return m.ListFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *MockTagsRepository) GetForRecipe(ctx context.Context, recipeID int64) ([]string, error) {
 	if m.GetForRecipeFunc != nil {
-		return m.GetForRecipeFunc(recipeID)
+		return m.GetForRecipeFunc(ctx, recipeID)
 	}
 	return nil, nil
 }
 
+func (m *MockTagsRepository) SetForRecipe(ctx context.Context, recipeID, userID int64, names []string) error {
+	if m.SetForRecipeFunc != nil {
+		return m.SetForRecipeFunc(ctx, recipeID, userID, names)
+	}
+	return nil
+}
+
+func (m *MockTagsRepository) DeleteUnused(ctx context.Context, userID int64) error {
+	if m.DeleteUnusedFunc != nil {
+		return m.DeleteUnusedFunc(ctx, userID)
+	}
+	return nil
+}
+
 type MockStepsRepository struct {
-	InsertFunc       func(recipeID int64, step *repositories.Step) error
-	GetForRecipeFunc func(recipeID int64) ([]*repositories.Step, error)
+	InsertFunc          func(ctx context.Context, recipeID int64, step *repositories.Step) error
+	GetForRecipeFunc    func(ctx context.Context, recipeID int64) ([]*repositories.Step, error)
+	DeleteForRecipeFunc func(ctx context.Context, recipeID int64) error
 }
 
-func (m *MockStepsRepository) Insert(recipeID int64, step *repositories.Step) error {
+func (m *MockStepsRepository) Insert(ctx context.Context, recipeID int64, step *repositories.Step) error {
 	if m.InsertFunc != nil {
-		return m.InsertFunc(recipeID, step)
+		return m.InsertFunc(ctx, recipeID, step)
 	}
 	return nil
 }
 
-func (m *MockStepsRepository) GetForRecipe(recipeID int64) ([]*repositories.Step, error) {
+func (m *MockStepsRepository) GetForRecipe(ctx context.Context, recipeID int64) ([]*repositories.Step, error) {
 	if m.GetForRecipeFunc != nil {
-		return m.GetForRecipeFunc(recipeID)
+		return m.GetForRecipeFunc(ctx, recipeID)
 	}
 	return nil, nil
 }
+
+func (m *MockStepsRepository) DeleteForRecipe(ctx context.Context, recipeID int64) error {
+	if m.DeleteForRecipeFunc != nil {
+		return m.DeleteForRecipeFunc(ctx, recipeID)
+	}
+	return nil
+}
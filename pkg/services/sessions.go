@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/twinj/uuid"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+	"github.com/iplay88keys/my-recipe-library/pkg/token"
+)
+
+var ErrSessionNotFound = fmt.Errorf("session %w", ErrNotFound)
+
+// SessionClient describes where a login came from.
+type SessionClient struct {
+	Device    string
+	IP        string
+	UserAgent string
+}
+
+type Session struct {
+	ID        string
+	Device    string
+	IP        string
+	UserAgent string
+	CreatedAt time.Time
+	Current   bool
+}
+
+// StartSession records a new login and stores the tokens issued for it.
+func (s *UserService) StartSession(ctx context.Context, userID int64, details *token.Details, client *SessionClient) error {
+	return s.redisRepo.CreateSession(ctx, &repositories.Session{
+		ID:        uuid.NewV4().String(),
+		UserID:    userID,
+		Device:    client.Device,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		CreatedAt: time.Now(),
+	}, details)
+}
+
+// ListSessions returns the user's active sessions. The session that the
+// current access token belongs to is marked as current.
+func (s *UserService) ListSessions(ctx context.Context, userID int64, currentAccessUuid string) ([]*Session, error) {
+	found, err := s.redisRepo.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*Session, 0, len(found))
+	for _, session := range found {
+		sessions = append(sessions, &Session{
+			ID:        session.ID,
+			Device:    session.Device,
+			IP:        session.IP,
+			UserAgent: session.UserAgent,
+			CreatedAt: session.CreatedAt,
+			Current:   session.AccessUuid == currentAccessUuid,
+		})
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession ends one of the user's sessions. Sessions belonging to other
+// users are reported as not found.
+func (s *UserService) RevokeSession(ctx context.Context, userID int64, sessionID string) error {
+	session, err := s.redisRepo.GetSession(ctx, sessionID)
+	if err == repositories.ErrSessionNotFound {
+		return ErrSessionNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if session.UserID != userID {
+		return ErrSessionNotFound
+	}
+
+	err = s.redisRepo.RevokeSession(ctx, sessionID)
+	if err == repositories.ErrSessionNotFound {
+		return ErrSessionNotFound
+	}
+
+	return err
+}
+
+func (s *UserService) RevokeAllSessions(ctx context.Context, userID int64) error {
+	return s.redisRepo.RevokeAllSessions(ctx, userID)
+}
@@ -0,0 +1,185 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+	"github.com/iplay88keys/my-recipe-library/pkg/token"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UserService sessions", func() {
+	var (
+		userService   *services.UserService
+		mockRedisRepo *MockRedisRepository
+		ctx           context.Context
+	)
+
+	BeforeEach(func() {
+		mockRedisRepo = &MockRedisRepository{}
+		userService = services.NewUserService(&MockUsersRepository{}, mockRedisRepo, &MockTokenService{}, &MockEmailVerifier{}, &MockTwoFactorChallenger{})
+
+		ctx = context.Background()
+	})
+
+	Describe("StartSession", func() {
+		It("creates a session for the client with the token details", func() {
+			details := &token.Details{AccessUuid: "access-uuid", RefreshUuid: "refresh-uuid"}
+
+			var created *repositories.Session
+			mockRedisRepo.CreateSessionFunc = func(ctx context.Context, session *repositories.Session, d *token.Details) error {
+				created = session
+				Expect(d).To(Equal(details))
+				return nil
+			}
+
+			err := userService.StartSession(ctx, 10, details, &services.SessionClient{
+				Device:    "Kitchen tablet",
+				IP:        "127.0.0.1",
+				UserAgent: "Mozilla/5.0",
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(created.ID).ToNot(BeEmpty())
+			Expect(created.UserID).To(Equal(int64(10)))
+			Expect(created.Device).To(Equal("Kitchen tablet"))
+			Expect(created.IP).To(Equal("127.0.0.1"))
+			Expect(created.UserAgent).To(Equal("Mozilla/5.0"))
+			Expect(created.CreatedAt).To(BeTemporally("~", time.Now(), time.Second))
+		})
+
+		It("returns an error if the session cannot be created", func() {
+			mockRedisRepo.CreateSessionFunc = func(ctx context.Context, session *repositories.Session, d *token.Details) error {
+				return errors.New("redis error")
+			}
+
+			err := userService.StartSession(ctx, 10, &token.Details{}, &services.SessionClient{})
+			Expect(err).To(MatchError("redis error"))
+		})
+	})
+
+	Describe("ListSessions", func() {
+		It("returns the sessions and marks the current one", func() {
+			createdAt := time.Unix(1700000000, 0)
+			mockRedisRepo.ListSessionsFunc = func(ctx context.Context, userID int64) ([]*repositories.Session, error) {
+				Expect(userID).To(Equal(int64(10)))
+				return []*repositories.Session{{
+					ID:         "session-1",
+					UserID:     10,
+					Device:     "Phone",
+					IP:         "10.0.0.1",
+					UserAgent:  "Phone UA",
+					CreatedAt:  createdAt,
+					AccessUuid: "other-access-uuid",
+				}, {
+					ID:         "session-2",
+					UserID:     10,
+					Device:     "Laptop",
+					IP:         "10.0.0.2",
+					UserAgent:  "Laptop UA",
+					CreatedAt:  createdAt,
+					AccessUuid: "current-access-uuid",
+				}}, nil
+			}
+
+			sessions, err := userService.ListSessions(ctx, 10, "current-access-uuid")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sessions).To(Equal([]*services.Session{{
+				ID:        "session-1",
+				Device:    "Phone",
+				IP:        "10.0.0.1",
+				UserAgent: "Phone UA",
+				CreatedAt: createdAt,
+			}, {
+				ID:        "session-2",
+				Device:    "Laptop",
+				IP:        "10.0.0.2",
+				UserAgent: "Laptop UA",
+				CreatedAt: createdAt,
+				Current:   true,
+			}}))
+		})
+
+		It("returns an error if the sessions cannot be listed", func() {
+			mockRedisRepo.ListSessionsFunc = func(ctx context.Context, userID int64) ([]*repositories.Session, error) {
+				return nil, errors.New("redis error")
+			}
+
+			_, err := userService.ListSessions(ctx, 10, "current-access-uuid")
+			Expect(err).To(MatchError("redis error"))
+		})
+	})
+
+	Describe("RevokeSession", func() {
+		It("revokes a session owned by the user", func() {
+			mockRedisRepo.GetSessionFunc = func(ctx context.Context, sessionID string) (*repositories.Session, error) {
+				return &repositories.Session{ID: sessionID, UserID: 10}, nil
+			}
+
+			var revoked string
+			mockRedisRepo.RevokeSessionFunc = func(ctx context.Context, sessionID string) error {
+				revoked = sessionID
+				return nil
+			}
+
+			err := userService.RevokeSession(ctx, 10, "session-1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(revoked).To(Equal("session-1"))
+		})
+
+		It("does not revoke a session owned by another user", func() {
+			mockRedisRepo.GetSessionFunc = func(ctx context.Context, sessionID string) (*repositories.Session, error) {
+				return &repositories.Session{ID: sessionID, UserID: 20}, nil
+			}
+
+			mockRedisRepo.RevokeSessionFunc = func(ctx context.Context, sessionID string) error {
+				Fail("revoke should not be called")
+				return nil
+			}
+
+			err := userService.RevokeSession(ctx, 10, "session-1")
+			Expect(err).To(MatchError(services.ErrSessionNotFound))
+		})
+
+		It("returns not found if the session does not exist", func() {
+			mockRedisRepo.GetSessionFunc = func(ctx context.Context, sessionID string) (*repositories.Session, error) {
+				return nil, repositories.ErrSessionNotFound
+			}
+
+			err := userService.RevokeSession(ctx, 10, "session-1")
+			Expect(err).To(MatchError(services.ErrSessionNotFound))
+		})
+
+		It("returns an error if the revoke fails", func() {
+			mockRedisRepo.GetSessionFunc = func(ctx context.Context, sessionID string) (*repositories.Session, error) {
+				return &repositories.Session{ID: sessionID, UserID: 10}, nil
+			}
+
+			mockRedisRepo.RevokeSessionFunc = func(ctx context.Context, sessionID string) error {
+				return errors.New("redis error")
+			}
+
+			err := userService.RevokeSession(ctx, 10, "session-1")
+			Expect(err).To(MatchError("redis error"))
+		})
+	})
+
+	Describe("RevokeAllSessions", func() {
+		It("revokes every session for the user", func() {
+			var revokedFor int64
+			mockRedisRepo.RevokeAllSessionsFunc = func(ctx context.Context, userID int64) error {
+				revokedFor = userID
+				return nil
+			}
+
+			err := userService.RevokeAllSessions(ctx, 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(revokedFor).To(Equal(int64(10)))
+		})
+	})
+})
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+)
+
+type TotalMinutesRepositoryInterface interface {
+	ListMissingTotalMinutes(ctx context.Context) ([]*repositories.RecipeTotalTime, error)
+	SetTotalMinutes(ctx context.Context, id int64, minutes int) error
+}
+
+// TotalMinutesService fills in total_minutes for recipes saved before it was
+// added, which would otherwise be listed last when sorting by total time
+// until they are next updated.
+type TotalMinutesService struct {
+	recipesRepo TotalMinutesRepositoryInterface
+}
+
+func NewTotalMinutesService(recipesRepo TotalMinutesRepositoryInterface) *TotalMinutesService {
+	return &TotalMinutesService{
+		recipesRepo: recipesRepo,
+	}
+}
+
+// Backfill sets total_minutes from the total time of every recipe missing it
+// and returns how many recipes were updated. Total times that cannot be read
+// are left as they are, so it is safe to run again.
+func (s *TotalMinutesService) Backfill(ctx context.Context) (int, error) {
+	recipes, err := s.recipesRepo.ListMissingTotalMinutes(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, recipe := range recipes {
+		minutes := parseMinutes(&recipe.TotalTime)
+		if minutes == nil {
+			continue
+		}
+
+		if err := s.recipesRepo.SetTotalMinutes(ctx, recipe.ID, *minutes); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+
+	return updated, nil
+}
@@ -0,0 +1,79 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TotalMinutesService", func() {
+	var (
+		ctx      context.Context
+		mockRepo *MockTotalMinutesRepository
+		set      map[int64]int
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		set = map[int64]int{}
+
+		mockRepo = &MockTotalMinutesRepository{
+			ListMissingTotalMinutesFunc: func(ctx context.Context) ([]*repositories.RecipeTotalTime, error) {
+				return []*repositories.RecipeTotalTime{
+					{ID: 1, TotalTime: "5 m"},
+					{ID: 2, TotalTime: "1 hr 30 mins"},
+					{ID: 3, TotalTime: "overnight"},
+				}, nil
+			},
+			SetTotalMinutesFunc: func(ctx context.Context, id int64, minutes int) error {
+				set[id] = minutes
+				return nil
+			},
+		}
+	})
+
+	It("sets total minutes for the recipes whose total time can be read", func() {
+		updated, err := services.NewTotalMinutesService(mockRepo).Backfill(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(updated).To(Equal(2))
+
+		Expect(set).To(Equal(map[int64]int{1: 5, 2: 90}))
+	})
+
+	It("returns an error if the recipes cannot be listed", func() {
+		mockRepo.ListMissingTotalMinutesFunc = func(ctx context.Context) ([]*repositories.RecipeTotalTime, error) {
+			return nil, errors.New("some error")
+		}
+
+		_, err := services.NewTotalMinutesService(mockRepo).Backfill(ctx)
+		Expect(err).To(MatchError("some error"))
+	})
+
+	It("returns an error if a recipe cannot be updated", func() {
+		mockRepo.SetTotalMinutesFunc = func(ctx context.Context, id int64, minutes int) error {
+			return errors.New("some error")
+		}
+
+		updated, err := services.NewTotalMinutesService(mockRepo).Backfill(ctx)
+		Expect(err).To(MatchError("some error"))
+		Expect(updated).To(Equal(0))
+	})
+})
+
+type MockTotalMinutesRepository struct {
+	ListMissingTotalMinutesFunc func(ctx context.Context) ([]*repositories.RecipeTotalTime, error)
+	SetTotalMinutesFunc         func(ctx context.Context, id int64, minutes int) error
+}
+
+func (m *MockTotalMinutesRepository) ListMissingTotalMinutes(ctx context.Context) ([]*repositories.RecipeTotalTime, error) {
+	return m.ListMissingTotalMinutesFunc(ctx)
+}
+
+func (m *MockTotalMinutesRepository) SetTotalMinutes(ctx context.Context, id int64, minutes int) error {
+	return m.SetTotalMinutesFunc(ctx, id, minutes)
+}
@@ -0,0 +1,328 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+	"github.com/iplay88keys/my-recipe-library/pkg/totp"
+)
+
+const (
+	TwoFactorIssuer         = "My Recipe Library"
+	LoginChallengeTTL       = 5 * time.Minute
+	MaxLoginChallengeFails  = 5
+	RecoveryCodeCount       = 10
+	recoveryCodeSize        = 10
+	recoveryCodeGroupLength = 4
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two factor authentication is already enabled")
+	ErrTwoFactorNotEnrolled    = errors.New("two factor authentication has not been set up")
+	ErrTwoFactorUnavailable    = errors.New("two factor authentication is not configured")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two factor code")
+	ErrInvalidLoginChallenge   = errors.New("invalid login challenge")
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type TwoFactorRepositoryInterface interface {
+	Get(ctx context.Context, userID int64) (*repositories.TwoFactor, error)
+	SavePending(ctx context.Context, userID int64, secret string) error
+	Enable(ctx context.Context, userID, step int64, recoveryCodeHashes []string) error
+	UseStep(ctx context.Context, userID, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error)
+}
+
+type LoginChallengeRepositoryInterface interface {
+	StoreLoginChallenge(ctx context.Context, challengeHash string, challenge *repositories.LoginChallenge, ttl time.Duration) error
+	GetLoginChallenge(ctx context.Context, challengeHash string) (*repositories.LoginChallenge, error)
+	FailLoginChallenge(ctx context.Context, challengeHash string, maxAttempts int64) error
+	DeleteLoginChallenge(ctx context.Context, challengeHash string) (bool, error)
+}
+
+type TwoFactorUsersRepositoryInterface interface {
+	GetByID(ctx context.Context, userID int64) (*repositories.User, error)
+}
+
+type TwoFactorEnrollment struct {
+	Secret string
+	URI    string
+}
+
+type TwoFactorService struct {
+	twoFactorRepo  TwoFactorRepositoryInterface
+	challengesRepo LoginChallengeRepositoryInterface
+	usersRepo      TwoFactorUsersRepositoryInterface
+	cipher         *totp.Cipher
+}
+
+// NewTwoFactorService creates the service for two factor authentication. The
+// cipher is nil when no encryption key is configured, in which case no one can
+// set up two factor authentication and users who already have it can only
+// pass login challenges with recovery codes.
+func NewTwoFactorService(
+	twoFactorRepo TwoFactorRepositoryInterface,
+	challengesRepo LoginChallengeRepositoryInterface,
+	usersRepo TwoFactorUsersRepositoryInterface,
+	cipher *totp.Cipher,
+) *TwoFactorService {
+	return &TwoFactorService{
+		twoFactorRepo:  twoFactorRepo,
+		challengesRepo: challengesRepo,
+		usersRepo:      usersRepo,
+		cipher:         cipher,
+	}
+}
+
+// Enroll creates a new TOTP secret for the user. It is not used for logins
+// until it is confirmed with a code.
+func (s *TwoFactorService) Enroll(ctx context.Context, userID int64) (*TwoFactorEnrollment, error) {
+	if s.cipher == nil {
+		return nil, ErrTwoFactorUnavailable
+	}
+
+	existing, err := s.twoFactorRepo.Get(ctx, userID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	if existing != nil && existing.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	user, err := s.usersRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := s.cipher.Encrypt(secret, secretOwner(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.twoFactorRepo.SavePending(ctx, userID, encrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorEnrollment{
+		Secret: totp.EncodeSecret(secret),
+		URI:    totp.URI(TwoFactorIssuer, user.Username, secret),
+	}, nil
+}
+
+// Confirm enables two factor authentication once the user proves their
+// authenticator works. It returns recovery codes, which are only ever shown
+// this once.
+func (s *TwoFactorService) Confirm(ctx context.Context, userID int64, code string) ([]string, error) {
+	twoFactor, err := s.twoFactorRepo.Get(ctx, userID)
+	if err == sql.ErrNoRows {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if twoFactor.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	step, ok, err := s.validateCode(twoFactor, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		codes[i], err = newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	err = s.twoFactorRepo.Enable(ctx, userID, step, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// StartChallenge returns a challenge token the user has to exchange along
+// with a code to finish logging in. It returns an empty token if the user
+// does not have two factor authentication enabled. Login is the name the user
+// logged in with, or empty if they did not use one, in which case their
+// username is kept with the challenge instead.
+func (s *TwoFactorService) StartChallenge(ctx context.Context, userID int64, login string) (string, error) {
+	twoFactor, err := s.twoFactorRepo.Get(ctx, userID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if !twoFactor.Enabled {
+		return "", nil
+	}
+
+	raw := make([]byte, 32)
+	_, err = rand.Read(raw)
+	if err != nil {
+		return "", err
+	}
+	challenge := base64.RawURLEncoding.EncodeToString(raw)
+
+	if login == "" {
+		user, err := s.usersRepo.GetByID(ctx, userID)
+		if err != nil {
+			return "", err
+		}
+		login = user.Username
+	}
+
+	err = s.challengesRepo.StoreLoginChallenge(ctx, hashToken(challenge), &repositories.LoginChallenge{
+		UserID: userID,
+		Login:  login,
+	}, LoginChallengeTTL)
+	if err != nil {
+		return "", err
+	}
+
+	return challenge, nil
+}
+
+// VerifyChallenge checks a TOTP or recovery code for a challenge and returns
+// who it belongs to. A challenge can only be passed once and is dropped after
+// too many wrong codes. The challenge is returned with ErrInvalidTwoFactorCode
+// as well, so wrong codes can be counted against the login.
+func (s *TwoFactorService) VerifyChallenge(ctx context.Context, challenge, code string) (*repositories.LoginChallenge, error) {
+	challengeHash := hashToken(challenge)
+
+	loginChallenge, err := s.challengesRepo.GetLoginChallenge(ctx, challengeHash)
+	if err == repositories.ErrChallengeNotFound {
+		return nil, ErrInvalidLoginChallenge
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := s.useCode(ctx, loginChallenge.UserID, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		err = s.challengesRepo.FailLoginChallenge(ctx, challengeHash, MaxLoginChallengeFails)
+		if err != nil {
+			return nil, err
+		}
+
+		return loginChallenge, ErrInvalidTwoFactorCode
+	}
+
+	deleted, err := s.challengesRepo.DeleteLoginChallenge(ctx, challengeHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if !deleted {
+		return nil, ErrInvalidLoginChallenge
+	}
+
+	return loginChallenge, nil
+}
+
+func (s *TwoFactorService) useCode(ctx context.Context, userID int64, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+
+	twoFactor, err := s.twoFactorRepo.Get(ctx, userID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	if len(code) != totp.Digits {
+		return s.twoFactorRepo.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+	}
+
+	step, ok, err := s.validateCode(twoFactor, code)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	return s.twoFactorRepo.UseStep(ctx, userID, step)
+}
+
+func (s *TwoFactorService) validateCode(twoFactor *repositories.TwoFactor, code string) (int64, bool, error) {
+	if s.cipher == nil {
+		return 0, false, ErrTwoFactorUnavailable
+	}
+
+	secret, err := s.cipher.Decrypt(twoFactor.Secret, secretOwner(twoFactor.UserID))
+	if err != nil {
+		return 0, false, err
+	}
+
+	step, ok := totp.Validate(secret, strings.TrimSpace(code), time.Now())
+	if !ok || step <= twoFactor.LastUsedStep {
+		return 0, false, nil
+	}
+
+	return step, true, nil
+}
+
+func secretOwner(userID int64) []byte {
+	return []byte(strconv.FormatInt(userID, 10))
+}
+
+// newRecoveryCode returns a random code formatted in groups, such as
+// abcd-efgh-ijkl-mnop.
+func newRecoveryCode() (string, error) {
+	raw := make([]byte, recoveryCodeSize)
+	_, err := rand.Read(raw)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := strings.ToLower(recoveryCodeEncoding.EncodeToString(raw))
+
+	var groups []string
+	for i := 0; i < len(encoded); i += recoveryCodeGroupLength {
+		groups = append(groups, encoded[i:i+recoveryCodeGroupLength])
+	}
+
+	return strings.Join(groups, "-"), nil
+}
+
+// hashRecoveryCode ignores case and separators so codes can be typed loosely.
+func hashRecoveryCode(code string) string {
+	return hashToken(strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code)))
+}
@@ -0,0 +1,433 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+	"github.com/iplay88keys/my-recipe-library/pkg/totp"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TwoFactorService", func() {
+	var (
+		twoFactorService *services.TwoFactorService
+		mockTwoFactor    *MockTwoFactorRepository
+		mockChallenges   *MockLoginChallengeRepository
+		mockUsersRepo    *MockVerificationUsersRepository
+		cipher           *totp.Cipher
+		secret           []byte
+		ctx              context.Context
+	)
+
+	encrypt := func(userID int64, secret []byte) string {
+		encrypted, err := cipher.Encrypt(secret, []byte(strconv.FormatInt(userID, 10)))
+		Expect(err).ToNot(HaveOccurred())
+
+		return encrypted
+	}
+
+	BeforeEach(func() {
+		var err error
+		cipher, err = totp.NewCipher([]byte("0123456789abcdef0123456789abcdef"))
+		Expect(err).ToNot(HaveOccurred())
+
+		secret = []byte("12345678901234567890")
+
+		mockTwoFactor = &MockTwoFactorRepository{}
+		mockChallenges = &MockLoginChallengeRepository{}
+		mockUsersRepo = &MockVerificationUsersRepository{
+			GetByIDFunc: func(ctx context.Context, userID int64) (*repositories.User, error) {
+				return &repositories.User{ID: userID, Username: "cook"}, nil
+			},
+		}
+		twoFactorService = services.NewTwoFactorService(mockTwoFactor, mockChallenges, mockUsersRepo, cipher)
+
+		ctx = context.Background()
+	})
+
+	Describe("Enroll", func() {
+		It("stores an encrypted secret and returns it with an otpauth URI", func() {
+			var stored string
+			mockTwoFactor.SavePendingFunc = func(ctx context.Context, userID int64, secret string) error {
+				Expect(userID).To(Equal(int64(10)))
+				stored = secret
+				return nil
+			}
+
+			enrollment, err := twoFactorService.Enroll(ctx, 10)
+			Expect(err).ToNot(HaveOccurred())
+
+			uri, err := url.Parse(enrollment.URI)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(uri.Path).To(Equal("/" + services.TwoFactorIssuer + ":cook"))
+			Expect(uri.Query().Get("secret")).To(Equal(enrollment.Secret))
+
+			decrypted, err := cipher.Decrypt(stored, []byte("10"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(totp.EncodeSecret(decrypted)).To(Equal(enrollment.Secret))
+		})
+
+		It("does not replace a secret that is already enabled", func() {
+			mockTwoFactor.GetFunc = func(ctx context.Context, userID int64) (*repositories.TwoFactor, error) {
+				return &repositories.TwoFactor{UserID: userID, Enabled: true}, nil
+			}
+
+			mockTwoFactor.SavePendingFunc = func(ctx context.Context, userID int64, secret string) error {
+				Fail("secret should not be replaced")
+				return nil
+			}
+
+			_, err := twoFactorService.Enroll(ctx, 10)
+			Expect(err).To(MatchError(services.ErrTwoFactorAlreadyEnabled))
+		})
+	})
+
+	Describe("Confirm", func() {
+		BeforeEach(func() {
+			mockTwoFactor.GetFunc = func(ctx context.Context, userID int64) (*repositories.TwoFactor, error) {
+				return &repositories.TwoFactor{UserID: userID, Secret: encrypt(userID, secret)}, nil
+			}
+		})
+
+		It("enables two factor with hashed recovery codes", func() {
+			var enabledStep int64
+			var hashes []string
+			mockTwoFactor.EnableFunc = func(ctx context.Context, userID, step int64, recoveryCodeHashes []string) error {
+				enabledStep = step
+				hashes = recoveryCodeHashes
+				return nil
+			}
+
+			codes, err := twoFactorService.Confirm(ctx, 10, totp.Code(secret, time.Now()))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(codes).To(HaveLen(services.RecoveryCodeCount))
+			Expect(codes[0]).To(MatchRegexp(`^[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}$`))
+			Expect(hashes).To(HaveLen(services.RecoveryCodeCount))
+			Expect(hashes).ToNot(ContainElement(codes[0]))
+			Expect(enabledStep).To(BeNumerically("~", totp.Step(time.Now()), 1))
+		})
+
+		It("returns an invalid code error for a wrong code", func() {
+			mockTwoFactor.EnableFunc = func(ctx context.Context, userID, step int64, recoveryCodeHashes []string) error {
+				Fail("two factor should not be enabled")
+				return nil
+			}
+
+			_, err := twoFactorService.Confirm(ctx, 10, "000000")
+			Expect(err).To(MatchError(services.ErrInvalidTwoFactorCode))
+		})
+
+		It("returns not enrolled if there is no pending secret", func() {
+			mockTwoFactor.GetFunc = func(ctx context.Context, userID int64) (*repositories.TwoFactor, error) {
+				return nil, sql.ErrNoRows
+			}
+
+			_, err := twoFactorService.Confirm(ctx, 10, "123456")
+			Expect(err).To(MatchError(services.ErrTwoFactorNotEnrolled))
+		})
+	})
+
+	Describe("StartChallenge", func() {
+		It("returns no challenge if two factor is not enabled", func() {
+			mockTwoFactor.GetFunc = func(ctx context.Context, userID int64) (*repositories.TwoFactor, error) {
+				return &repositories.TwoFactor{UserID: userID}, nil
+			}
+
+			challenge, err := twoFactorService.StartChallenge(ctx, 10, "cook")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(challenge).To(BeEmpty())
+		})
+
+		It("stores a hash of a new challenge", func() {
+			mockTwoFactor.GetFunc = func(ctx context.Context, userID int64) (*repositories.TwoFactor, error) {
+				return &repositories.TwoFactor{UserID: userID, Enabled: true}, nil
+			}
+
+			var storedHash string
+			mockChallenges.StoreLoginChallengeFunc = func(ctx context.Context, challengeHash string, challenge *repositories.LoginChallenge, ttl time.Duration) error {
+				storedHash = challengeHash
+				Expect(challenge).To(Equal(&repositories.LoginChallenge{UserID: 10, Login: "cook@example.com"}))
+				Expect(ttl).To(Equal(services.LoginChallengeTTL))
+				return nil
+			}
+
+			challenge, err := twoFactorService.StartChallenge(ctx, 10, "cook@example.com")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(challenge).ToNot(BeEmpty())
+			Expect(storedHash).ToNot(Equal(challenge))
+		})
+
+		It("keeps the username with the challenge if the user did not log in with a name", func() {
+			mockTwoFactor.GetFunc = func(ctx context.Context, userID int64) (*repositories.TwoFactor, error) {
+				return &repositories.TwoFactor{UserID: userID, Enabled: true}, nil
+			}
+
+			var stored *repositories.LoginChallenge
+			mockChallenges.StoreLoginChallengeFunc = func(ctx context.Context, challengeHash string, challenge *repositories.LoginChallenge, ttl time.Duration) error {
+				stored = challenge
+				return nil
+			}
+
+			_, err := twoFactorService.StartChallenge(ctx, 10, "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stored).To(Equal(&repositories.LoginChallenge{UserID: 10, Login: "cook"}))
+		})
+	})
+
+	Describe("VerifyChallenge", func() {
+		var deleted bool
+
+		BeforeEach(func() {
+			mockTwoFactor.GetFunc = func(ctx context.Context, userID int64) (*repositories.TwoFactor, error) {
+				return &repositories.TwoFactor{UserID: userID, Secret: encrypt(userID, secret), Enabled: true}, nil
+			}
+
+			mockChallenges.GetLoginChallengeFunc = func(ctx context.Context, challengeHash string) (*repositories.LoginChallenge, error) {
+				return &repositories.LoginChallenge{UserID: 10, Login: "cook"}, nil
+			}
+
+			deleted = false
+			mockChallenges.DeleteLoginChallengeFunc = func(ctx context.Context, challengeHash string) (bool, error) {
+				deleted = true
+				return true, nil
+			}
+		})
+
+		It("passes the challenge with a current code", func() {
+			var usedStep int64
+			mockTwoFactor.UseStepFunc = func(ctx context.Context, userID, step int64) (bool, error) {
+				usedStep = step
+				return true, nil
+			}
+
+			challenge, err := twoFactorService.VerifyChallenge(ctx, "challenge", totp.Code(secret, time.Now()))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(challenge).To(Equal(&repositories.LoginChallenge{UserID: 10, Login: "cook"}))
+			Expect(usedStep).To(BeNumerically("~", totp.Step(time.Now()), 1))
+			Expect(deleted).To(BeTrue())
+		})
+
+		It("does not accept a code that has already been used", func() {
+			mockTwoFactor.UseStepFunc = func(ctx context.Context, userID, step int64) (bool, error) {
+				return false, nil
+			}
+
+			failed := false
+			mockChallenges.FailLoginChallengeFunc = func(ctx context.Context, challengeHash string, maxAttempts int64) error {
+				failed = true
+				Expect(maxAttempts).To(BeEquivalentTo(services.MaxLoginChallengeFails))
+				return nil
+			}
+
+			challenge, err := twoFactorService.VerifyChallenge(ctx, "challenge", totp.Code(secret, time.Now()))
+			Expect(err).To(MatchError(services.ErrInvalidTwoFactorCode))
+			Expect(challenge.Login).To(Equal("cook"))
+			Expect(failed).To(BeTrue())
+			Expect(deleted).To(BeFalse())
+		})
+
+		It("passes the challenge with a recovery code", func() {
+			var usedHash string
+			mockTwoFactor.UseRecoveryCodeFunc = func(ctx context.Context, userID int64, codeHash string) (bool, error) {
+				usedHash = codeHash
+				return true, nil
+			}
+
+			challenge, err := twoFactorService.VerifyChallenge(ctx, "challenge", "abcd-efgh-ijkl-mnop")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(challenge.UserID).To(Equal(int64(10)))
+
+			mockTwoFactor.UseRecoveryCodeFunc = func(ctx context.Context, userID int64, codeHash string) (bool, error) {
+				Expect(codeHash).To(Equal(usedHash))
+				return false, nil
+			}
+
+			_, err = twoFactorService.VerifyChallenge(ctx, "challenge", "ABCDEFGH IJKLMNOP")
+			Expect(err).To(MatchError(services.ErrInvalidTwoFactorCode))
+		})
+
+		It("returns an invalid challenge error if the challenge is unknown", func() {
+			mockChallenges.GetLoginChallengeFunc = func(ctx context.Context, challengeHash string) (*repositories.LoginChallenge, error) {
+				return nil, repositories.ErrChallengeNotFound
+			}
+
+			_, err := twoFactorService.VerifyChallenge(ctx, "challenge", "123456")
+			Expect(err).To(MatchError(services.ErrInvalidLoginChallenge))
+		})
+
+		It("returns an invalid challenge error if another request passed it first", func() {
+			mockTwoFactor.UseStepFunc = func(ctx context.Context, userID, step int64) (bool, error) {
+				return true, nil
+			}
+
+			mockChallenges.DeleteLoginChallengeFunc = func(ctx context.Context, challengeHash string) (bool, error) {
+				return false, nil
+			}
+
+			_, err := twoFactorService.VerifyChallenge(ctx, "challenge", totp.Code(secret, time.Now()))
+			Expect(err).To(MatchError(services.ErrInvalidLoginChallenge))
+		})
+
+		It("returns an error if the secret cannot be decrypted", func() {
+			mockTwoFactor.GetFunc = func(ctx context.Context, userID int64) (*repositories.TwoFactor, error) {
+				return &repositories.TwoFactor{UserID: userID, Secret: encrypt(20, secret), Enabled: true}, nil
+			}
+
+			_, err := twoFactorService.VerifyChallenge(ctx, "challenge", "123456")
+			Expect(err).To(MatchError(totp.ErrDecrypt))
+		})
+
+		It("returns an error if the failure cannot be recorded", func() {
+			mockChallenges.FailLoginChallengeFunc = func(ctx context.Context, challengeHash string, maxAttempts int64) error {
+				return errors.New("redis error")
+			}
+
+			_, err := twoFactorService.VerifyChallenge(ctx, "challenge", "abcd-efgh-ijkl-mnop")
+			Expect(err).To(MatchError("redis error"))
+		})
+	})
+
+	Describe("without an encryption key", func() {
+		BeforeEach(func() {
+			twoFactorService = services.NewTwoFactorService(mockTwoFactor, mockChallenges, mockUsersRepo, nil)
+
+			mockTwoFactor.GetFunc = func(ctx context.Context, userID int64) (*repositories.TwoFactor, error) {
+				return &repositories.TwoFactor{UserID: userID, Secret: encrypt(userID, secret), Enabled: true}, nil
+			}
+			mockChallenges.GetLoginChallengeFunc = func(ctx context.Context, challengeHash string) (*repositories.LoginChallenge, error) {
+				return &repositories.LoginChallenge{UserID: 10, Login: "cook"}, nil
+			}
+			mockChallenges.DeleteLoginChallengeFunc = func(ctx context.Context, challengeHash string) (bool, error) {
+				return true, nil
+			}
+		})
+
+		It("does not enroll users", func() {
+			mockTwoFactor.GetFunc = nil
+
+			_, err := twoFactorService.Enroll(ctx, 10)
+			Expect(err).To(MatchError(services.ErrTwoFactorUnavailable))
+		})
+
+		It("cannot check TOTP codes", func() {
+			_, err := twoFactorService.VerifyChallenge(ctx, "challenge", totp.Code(secret, time.Now()))
+			Expect(err).To(MatchError(services.ErrTwoFactorUnavailable))
+		})
+
+		It("still passes challenges with recovery codes", func() {
+			mockTwoFactor.UseRecoveryCodeFunc = func(ctx context.Context, userID int64, codeHash string) (bool, error) {
+				return true, nil
+			}
+
+			challenge, err := twoFactorService.VerifyChallenge(ctx, "challenge", "abcd-efgh-ijkl-mnop")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(challenge.UserID).To(Equal(int64(10)))
+		})
+	})
+})
+
+type MockTwoFactorRepository struct {
+	GetFunc             func(ctx context.Context, userID int64) (*repositories.TwoFactor, error)
+	SavePendingFunc     func(ctx context.Context, userID int64, secret string) error
+	EnableFunc          func(ctx context.Context, userID, step int64, recoveryCodeHashes []string) error
+	UseStepFunc         func(ctx context.Context, userID, step int64) (bool, error)
+	UseRecoveryCodeFunc func(ctx context.Context, userID int64, codeHash string) (bool, error)
+}
+
+func (m *MockTwoFactorRepository) Get(ctx context.Context, userID int64) (*repositories.TwoFactor, error) {
+	if m.GetFunc != nil {
+		return m.GetFunc(ctx, userID)
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *MockTwoFactorRepository) SavePending(ctx context.Context, userID int64, secret string) error {
+	if m.SavePendingFunc != nil {
+		return m.SavePendingFunc(ctx, userID, secret)
+	}
+	return nil
+}
+
+func (m *MockTwoFactorRepository) Enable(ctx context.Context, userID, step int64, recoveryCodeHashes []string) error {
+	if m.EnableFunc != nil {
+		return m.EnableFunc(ctx, userID, step, recoveryCodeHashes)
+	}
+	return nil
+}
+
+func (m *MockTwoFactorRepository) UseStep(ctx context.Context, userID, step int64) (bool, error) {
+	if m.UseStepFunc != nil {
+		return m.UseStepFunc(ctx, userID, step)
+	}
+	return false, nil
+}
+
+func (m *MockTwoFactorRepository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	if m.UseRecoveryCodeFunc != nil {
+		return m.UseRecoveryCodeFunc(ctx, userID, codeHash)
+	}
+	return false, nil
+}
+
+type MockLoginChallengeRepository struct {
+	StoreLoginChallengeFunc  func(ctx context.Context, challengeHash string, challenge *repositories.LoginChallenge, ttl time.Duration) error
+	GetLoginChallengeFunc    func(ctx context.Context, challengeHash string) (*repositories.LoginChallenge, error)
+	FailLoginChallengeFunc   func(ctx context.Context, challengeHash string, maxAttempts int64) error
+	DeleteLoginChallengeFunc func(ctx context.Context, challengeHash string) (bool, error)
+}
+
+func (m *MockLoginChallengeRepository) StoreLoginChallenge(ctx context.Context, challengeHash string, challenge *repositories.LoginChallenge, ttl time.Duration) error {
+	if m.StoreLoginChallengeFunc != nil {
+		return m.StoreLoginChallengeFunc(ctx, challengeHash, challenge, ttl)
+	}
+	return nil
+}
+
+func (m *MockLoginChallengeRepository) GetLoginChallenge(ctx context.Context, challengeHash string) (*repositories.LoginChallenge, error) {
+	if m.GetLoginChallengeFunc != nil {
+		return m.GetLoginChallengeFunc(ctx, challengeHash)
+	}
+	return nil, repositories.ErrChallengeNotFound
+}
+
+func (m *MockLoginChallengeRepository) FailLoginChallenge(ctx context.Context, challengeHash string, maxAttempts int64) error {
+	if m.FailLoginChallengeFunc != nil {
+		return m.FailLoginChallengeFunc(ctx, challengeHash, maxAttempts)
+	}
+	return nil
+}
+
+func (m *MockLoginChallengeRepository) DeleteLoginChallenge(ctx context.Context, challengeHash string) (bool, error) {
+	if m.DeleteLoginChallengeFunc != nil {
+		return m.DeleteLoginChallengeFunc(ctx, challengeHash)
+	}
+	return false, nil
+}
+
+type MockTwoFactorChallenger struct {
+	StartChallengeFunc  func(ctx context.Context, userID int64, login string) (string, error)
+	VerifyChallengeFunc func(ctx context.Context, challenge, code string) (*repositories.LoginChallenge, error)
+}
+
+func (m *MockTwoFactorChallenger) StartChallenge(ctx context.Context, userID int64, login string) (string, error) {
+	if m.StartChallengeFunc != nil {
+		return m.StartChallengeFunc(ctx, userID, login)
+	}
+	return "", nil
+}
+
+func (m *MockTwoFactorChallenger) VerifyChallenge(ctx context.Context, challenge, code string) (*repositories.LoginChallenge, error) {
+	if m.VerifyChallengeFunc != nil {
+		return m.VerifyChallengeFunc(ctx, challenge, code)
+	}
+	return nil, services.ErrInvalidLoginChallenge
+}
@@ -3,83 +3,195 @@ package services
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"net/http"
 
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
 	"github.com/iplay88keys/my-recipe-library/pkg/token"
 )
 
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
 type TokenServiceInterface interface {
 	CreateToken(userID int64) (*token.Details, error)
 	ValidateToken(r *http.Request) (*token.AccessDetails, error)
+	ValidateRefreshToken(refreshToken string) (*token.RefreshDetails, error)
 }
 
 type RedisRepositoryInterface interface {
-	StoreTokenDetails(userID int64, details *token.Details) error
-	DeleteTokenDetails(uuid string) error
+	CreateSession(ctx context.Context, session *repositories.Session, details *token.Details) error
+	StoreTokenDetails(ctx context.Context, userID int64, sessionID string, details *token.Details) error
+	DeleteTokenDetails(ctx context.Context, uuid string) error
+	GetRefreshSession(ctx context.Context, refreshUuid string) (string, error)
+	RevokeRefreshToken(ctx context.Context, refreshUuid string) (bool, error)
+	GetSession(ctx context.Context, sessionID string) (*repositories.Session, error)
+	ListSessions(ctx context.Context, userID int64) ([]*repositories.Session, error)
+	RevokeSession(ctx context.Context, sessionID string) error
+	RevokeAllSessions(ctx context.Context, userID int64) error
 }
 
 type UsersRepositoryInterface interface {
-	ExistsByUsername(username string) (bool, error)
-	ExistsByEmail(email string) (bool, error)
-	Insert(username, email, password string) (int64, error)
-	Verify(login, password string) (bool, int64, error)
+	ExistsByUsername(ctx context.Context, username string) (bool, error)
+	ExistsByEmail(ctx context.Context, email string) (bool, error)
+	Insert(ctx context.Context, username, email, password string) (int64, error)
+	Verify(ctx context.Context, login, password string) (bool, int64, error)
+}
+
+type EmailVerifierInterface interface {
+	SendVerification(ctx context.Context, user *repositories.User) error
+	CheckVerified(ctx context.Context, userID int64) error
+}
+
+type TwoFactorChallengerInterface interface {
+	StartChallenge(ctx context.Context, userID int64, login string) (string, error)
+	VerifyChallenge(ctx context.Context, challenge, code string) (*repositories.LoginChallenge, error)
 }
 
 type UserService struct {
 	usersRepo    UsersRepositoryInterface
 	redisRepo    RedisRepositoryInterface
 	tokenService TokenServiceInterface
+	verifier     EmailVerifierInterface
+	twoFactor    TwoFactorChallengerInterface
 }
 
 func NewUserService(
 	usersRepo UsersRepositoryInterface,
 	redisRepo RedisRepositoryInterface,
 	tokenService TokenServiceInterface,
+	verifier EmailVerifierInterface,
+	twoFactor TwoFactorChallengerInterface,
 ) *UserService {
 	return &UserService{
 		usersRepo:    usersRepo,
 		redisRepo:    redisRepo,
 		tokenService: tokenService,
+		verifier:     verifier,
+		twoFactor:    twoFactor,
 	}
 }
 
 func (s *UserService) RegisterUser(ctx context.Context, username, email, password string) error {
-	exists, err := s.usersRepo.ExistsByUsername(username)
+	exists, err := s.usersRepo.ExistsByUsername(ctx, username)
 	if err != nil {
 		return err
 	}
 	if exists {
-		return errors.New("username already exists")
+		return &ErrConflict{Field: "username"}
 	}
 
-	exists, err = s.usersRepo.ExistsByEmail(email)
+	exists, err = s.usersRepo.ExistsByEmail(ctx, email)
 	if err != nil {
 		return err
 	}
 	if exists {
-		return errors.New("email already exists")
+		return &ErrConflict{Field: "email"}
+	}
+
+	userID, err := s.usersRepo.Insert(ctx, username, email, password)
+	if err != nil {
+		return err
+	}
+
+	// The account exists at this point, so a failed email is left for the
+	// user to resend rather than failing the registration
+	err = s.verifier.SendVerification(ctx, &repositories.User{
+		ID:       userID,
+		Username: username,
+		Email:    email,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to send verification email", "error", err)
 	}
 
-	_, err = s.usersRepo.Insert(username, email, password)
-	return err
+	return nil
 }
 
-func (s *UserService) Verify(login, password string) (bool, int64, error) {
-	return s.usersRepo.Verify(login, password)
+// Verify checks a user's login credentials. It returns ErrEmailNotVerified
+// for valid credentials when the email has to be verified first.
+func (s *UserService) Verify(ctx context.Context, login, password string) (bool, int64, error) {
+	valid, userID, err := s.usersRepo.Verify(ctx, login, password)
+	if err != nil || !valid {
+		return valid, userID, err
+	}
+
+	err = s.verifier.CheckVerified(ctx, userID)
+	if err != nil {
+		return false, -1, err
+	}
+
+	return true, userID, nil
 }
 
-func (s *UserService) CreateToken(userID int64) (*token.Details, error) {
-	return s.tokenService.CreateToken(userID)
+// StartTwoFactorChallenge returns the challenge a user with two factor
+// authentication has to pass before tokens are issued, or an empty string if
+// they do not need one.
+func (s *UserService) StartTwoFactorChallenge(ctx context.Context, userID int64, login string) (string, error) {
+	return s.twoFactor.StartChallenge(ctx, userID, login)
+}
+
+func (s *UserService) VerifyTwoFactorChallenge(ctx context.Context, challenge, code string) (*repositories.LoginChallenge, error) {
+	return s.twoFactor.VerifyChallenge(ctx, challenge, code)
 }
 
-func (s *UserService) StoreTokenDetails(userID int64, details *token.Details) error {
-	return s.redisRepo.StoreTokenDetails(userID, details)
+func (s *UserService) CreateToken(userID int64) (*token.Details, error) {
+	return s.tokenService.CreateToken(userID)
 }
 
 func (s *UserService) ValidateToken(r *http.Request) (*token.AccessDetails, error) {
 	return s.tokenService.ValidateToken(r)
 }
 
-func (s *UserService) DeleteTokenDetails(uuid string) error {
-	return s.redisRepo.DeleteTokenDetails(uuid)
+func (s *UserService) DeleteTokenDetails(ctx context.Context, uuid string) error {
+	return s.redisRepo.DeleteTokenDetails(ctx, uuid)
+}
+
+// RefreshToken exchanges a refresh token for a new access and refresh token
+// pair. The old pair is revoked so a refresh token can only be used once.
+func (s *UserService) RefreshToken(ctx context.Context, refreshToken string) (*token.Details, error) {
+	refreshDetails, err := s.tokenService.ValidateRefreshToken(refreshToken)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	sessionID, err := s.redisRepo.GetRefreshSession(ctx, refreshDetails.RefreshUuid)
+	if err == repositories.ErrSessionNotFound {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.redisRepo.RevokeRefreshToken(ctx, refreshDetails.RefreshUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !revoked {
+		// A refresh token used twice may have been stolen, so the session is
+		// ended for whoever holds either copy.
+		err = s.redisRepo.RevokeSession(ctx, sessionID)
+		if err != nil && err != repositories.ErrSessionNotFound {
+			return nil, err
+		}
+
+		return nil, ErrInvalidRefreshToken
+	}
+
+	details, err := s.tokenService.CreateToken(refreshDetails.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.redisRepo.StoreTokenDetails(ctx, refreshDetails.UserId, sessionID, details)
+	if err == repositories.ErrSessionNotFound {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return details, nil
 }
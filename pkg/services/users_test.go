@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
 	"github.com/iplay88keys/my-recipe-library/pkg/services"
 	"github.com/iplay88keys/my-recipe-library/pkg/token"
 
@@ -19,6 +20,7 @@ var _ = Describe("UserService", func() {
 		mockUsersRepo    *MockUsersRepository
 		mockTokenService *MockTokenService
 		mockRedisRepo    *MockRedisRepository
+		mockVerifier     *MockEmailVerifier
 		ctx              context.Context
 		userID           int64
 	)
@@ -27,7 +29,8 @@ var _ = Describe("UserService", func() {
 		mockUsersRepo = &MockUsersRepository{}
 		mockTokenService = &MockTokenService{}
 		mockRedisRepo = &MockRedisRepository{}
-		userService = services.NewUserService(mockUsersRepo, mockRedisRepo, mockTokenService)
+		mockVerifier = &MockEmailVerifier{}
+		userService = services.NewUserService(mockUsersRepo, mockRedisRepo, mockTokenService, mockVerifier, &MockTwoFactorChallenger{})
 
 		ctx = context.Background()
 		userID = 1
@@ -44,17 +47,17 @@ var _ = Describe("UserService", func() {
 
 		Context("when registration is successful", func() {
 			It("registers a new user", func() {
-				mockUsersRepo.ExistsByUsernameFunc = func(username string) (bool, error) {
+				mockUsersRepo.ExistsByUsernameFunc = func(ctx context.Context, username string) (bool, error) {
 					Expect(username).To(Equal(username))
 					return false, nil
 				}
 
-				mockUsersRepo.ExistsByEmailFunc = func(email string) (bool, error) {
+				mockUsersRepo.ExistsByEmailFunc = func(ctx context.Context, email string) (bool, error) {
 					Expect(email).To(Equal(email))
 					return false, nil
 				}
 
-				mockUsersRepo.InsertFunc = func(username, email, password string) (int64, error) {
+				mockUsersRepo.InsertFunc = func(ctx context.Context, username, email, password string) (int64, error) {
 					Expect(username).To(Equal(username))
 					Expect(email).To(Equal(email))
 					Expect(password).ToNot(BeEmpty())
@@ -65,43 +68,81 @@ var _ = Describe("UserService", func() {
 
 				Expect(err).ToNot(HaveOccurred())
 			})
+
+			It("sends a verification email to the new user", func() {
+				mockUsersRepo.InsertFunc = func(ctx context.Context, username, email, password string) (int64, error) {
+					return 7, nil
+				}
+
+				var sentTo *repositories.User
+				mockVerifier.SendVerificationFunc = func(ctx context.Context, user *repositories.User) error {
+					sentTo = user
+					return nil
+				}
+
+				err := userService.RegisterUser(ctx, username, email, password)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(sentTo).To(Equal(&repositories.User{
+					ID:       7,
+					Username: username,
+					Email:    email,
+				}))
+			})
+
+			It("still registers the user if the verification email fails", func() {
+				mockUsersRepo.InsertFunc = func(ctx context.Context, username, email, password string) (int64, error) {
+					return 7, nil
+				}
+
+				mockVerifier.SendVerificationFunc = func(ctx context.Context, user *repositories.User) error {
+					return errors.New("smtp error")
+				}
+
+				err := userService.RegisterUser(ctx, username, email, password)
+				Expect(err).ToNot(HaveOccurred())
+			})
 		})
 
 		Context("when username already exists", func() {
 			It("returns an error", func() {
-				mockUsersRepo.ExistsByUsernameFunc = func(username string) (bool, error) {
+				mockUsersRepo.ExistsByUsernameFunc = func(ctx context.Context, username string) (bool, error) {
 					Expect(username).To(Equal(username))
 					return true, nil
 				}
 
 				err := userService.RegisterUser(ctx, username, email, password)
 
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("username already exists"))
+				var conflict *services.ErrConflict
+				Expect(errors.As(err, &conflict)).To(BeTrue())
+				Expect(conflict.Field).To(Equal("username"))
+				Expect(err).To(MatchError("username already exists"))
 			})
 		})
 
 		Context("when email already exists", func() {
 			It("returns an error", func() {
-				mockUsersRepo.ExistsByUsernameFunc = func(username string) (bool, error) {
+				mockUsersRepo.ExistsByUsernameFunc = func(ctx context.Context, username string) (bool, error) {
 					return false, nil
 				}
 
-				mockUsersRepo.ExistsByEmailFunc = func(email string) (bool, error) {
+				mockUsersRepo.ExistsByEmailFunc = func(ctx context.Context, email string) (bool, error) {
 					Expect(email).To(Equal(email))
 					return true, nil
 				}
 
 				err := userService.RegisterUser(ctx, username, email, password)
 
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("email already exists"))
+				var conflict *services.ErrConflict
+				Expect(errors.As(err, &conflict)).To(BeTrue())
+				Expect(conflict.Field).To(Equal("email"))
+				Expect(err).To(MatchError("email already exists"))
 			})
 		})
 
 		Context("when username check fails", func() {
 			It("returns an error", func() {
-				mockUsersRepo.ExistsByUsernameFunc = func(username string) (bool, error) {
+				mockUsersRepo.ExistsByUsernameFunc = func(ctx context.Context, username string) (bool, error) {
 					return false, errors.New("failed to query for user by username")
 				}
 
@@ -114,11 +155,11 @@ var _ = Describe("UserService", func() {
 
 		Context("when email check fails", func() {
 			It("returns an error", func() {
-				mockUsersRepo.ExistsByUsernameFunc = func(username string) (bool, error) {
+				mockUsersRepo.ExistsByUsernameFunc = func(ctx context.Context, username string) (bool, error) {
 					return false, nil
 				}
 
-				mockUsersRepo.ExistsByEmailFunc = func(email string) (bool, error) {
+				mockUsersRepo.ExistsByEmailFunc = func(ctx context.Context, email string) (bool, error) {
 					return false, errors.New("failed to query for user by email")
 				}
 
@@ -131,15 +172,15 @@ var _ = Describe("UserService", func() {
 
 		Context("when user insert fails", func() {
 			It("returns an error", func() {
-				mockUsersRepo.ExistsByUsernameFunc = func(username string) (bool, error) {
+				mockUsersRepo.ExistsByUsernameFunc = func(ctx context.Context, username string) (bool, error) {
 					return false, nil
 				}
 
-				mockUsersRepo.ExistsByEmailFunc = func(email string) (bool, error) {
+				mockUsersRepo.ExistsByEmailFunc = func(ctx context.Context, email string) (bool, error) {
 					return false, nil
 				}
 
-				mockUsersRepo.InsertFunc = func(username, email, password string) (int64, error) {
+				mockUsersRepo.InsertFunc = func(ctx context.Context, username, email, password string) (int64, error) {
 					return 0, errors.New("user could not be added")
 				}
 
@@ -161,13 +202,13 @@ var _ = Describe("UserService", func() {
 
 		Context("when credentials are valid", func() {
 			It("returns true and user ID", func() {
-				mockUsersRepo.VerifyFunc = func(login, password string) (bool, int64, error) {
+				mockUsersRepo.VerifyFunc = func(ctx context.Context, login, password string) (bool, int64, error) {
 					Expect(login).To(Equal(login))
 					Expect(password).To(Equal(password))
 					return true, userID, nil
 				}
 
-				valid, returnedUserID, err := userService.Verify(login, password)
+				valid, returnedUserID, err := userService.Verify(ctx, login, password)
 
 				Expect(err).ToNot(HaveOccurred())
 				Expect(valid).To(BeTrue())
@@ -175,13 +216,32 @@ var _ = Describe("UserService", func() {
 			})
 		})
 
+		Context("when the email has to be verified first", func() {
+			It("returns an email not verified error", func() {
+				mockUsersRepo.VerifyFunc = func(ctx context.Context, login, password string) (bool, int64, error) {
+					return true, userID, nil
+				}
+
+				mockVerifier.CheckVerifiedFunc = func(ctx context.Context, id int64) error {
+					Expect(id).To(Equal(userID))
+					return services.ErrEmailNotVerified
+				}
+
+				valid, returnedUserID, err := userService.Verify(ctx, login, password)
+
+				Expect(err).To(MatchError(services.ErrEmailNotVerified))
+				Expect(valid).To(BeFalse())
+				Expect(returnedUserID).To(Equal(int64(-1)))
+			})
+		})
+
 		Context("when credentials are invalid", func() {
 			It("returns false and zero user ID", func() {
-				mockUsersRepo.VerifyFunc = func(login, password string) (bool, int64, error) {
+				mockUsersRepo.VerifyFunc = func(ctx context.Context, login, password string) (bool, int64, error) {
 					return false, -1, nil
 				}
 
-				valid, returnedUserID, err := userService.Verify(login, password)
+				valid, returnedUserID, err := userService.Verify(ctx, login, password)
 
 				Expect(err).ToNot(HaveOccurred())
 				Expect(valid).To(BeFalse())
@@ -191,11 +251,11 @@ var _ = Describe("UserService", func() {
 
 		Context("when database query fails", func() {
 			It("returns an error", func() {
-				mockUsersRepo.VerifyFunc = func(login, password string) (bool, int64, error) {
+				mockUsersRepo.VerifyFunc = func(ctx context.Context, login, password string) (bool, int64, error) {
 					return false, -1, errors.New("database error")
 				}
 
-				valid, returnedUserID, err := userService.Verify(login, password)
+				valid, returnedUserID, err := userService.Verify(ctx, login, password)
 
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("database error"))
@@ -244,46 +304,6 @@ var _ = Describe("UserService", func() {
 		})
 	})
 
-	Describe("StoreTokenDetails", func() {
-		Context("when storing token details is successful", func() {
-			It("stores token details in Redis", func() {
-				details := &token.Details{
-					AccessToken:    "access-token",
-					RefreshToken:   "refresh-token",
-					AccessUuid:     "access-uuid",
-					RefreshUuid:    "refresh-uuid",
-					AccessExpires:  1234567890,
-					RefreshExpires: 1234567890,
-				}
-
-				mockRedisRepo.StoreTokenDetailsFunc = func(userID int64, details *token.Details) error {
-					Expect(userID).To(Equal(userID))
-					Expect(details).To(Equal(details))
-					return nil
-				}
-
-				err := userService.StoreTokenDetails(userID, details)
-
-				Expect(err).ToNot(HaveOccurred())
-			})
-		})
-
-		Context("when storing token details fails", func() {
-			It("returns an error", func() {
-				details := &token.Details{}
-
-				mockRedisRepo.StoreTokenDetailsFunc = func(userID int64, details *token.Details) error {
-					return errors.New("redis error")
-				}
-
-				err := userService.StoreTokenDetails(userID, details)
-
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("redis error"))
-			})
-		})
-	})
-
 	Describe("ValidateToken", func() {
 		Context("when token validation is successful", func() {
 			It("returns access details", func() {
@@ -328,12 +348,12 @@ var _ = Describe("UserService", func() {
 			It("deletes token details from Redis", func() {
 				uuid := "access-uuid"
 
-				mockRedisRepo.DeleteTokenDetailsFunc = func(uuid string) error {
+				mockRedisRepo.DeleteTokenDetailsFunc = func(ctx context.Context, uuid string) error {
 					Expect(uuid).To(Equal(uuid))
 					return nil
 				}
 
-				err := userService.DeleteTokenDetails(uuid)
+				err := userService.DeleteTokenDetails(ctx, uuid)
 
 				Expect(err).ToNot(HaveOccurred())
 			})
@@ -343,57 +363,217 @@ var _ = Describe("UserService", func() {
 			It("returns an error", func() {
 				uuid := "access-uuid"
 
-				mockRedisRepo.DeleteTokenDetailsFunc = func(uuid string) error {
+				mockRedisRepo.DeleteTokenDetailsFunc = func(ctx context.Context, uuid string) error {
 					return errors.New("redis error")
 				}
 
-				err := userService.DeleteTokenDetails(uuid)
+				err := userService.DeleteTokenDetails(ctx, uuid)
 
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("redis error"))
 			})
 		})
 	})
+
+	Describe("RefreshToken", func() {
+		Context("when the refresh token is valid and unused", func() {
+			It("revokes the old tokens and stores a new pair", func() {
+				var calls []string
+
+				mockTokenService.ValidateRefreshTokenFunc = func(refreshToken string) (*token.RefreshDetails, error) {
+					Expect(refreshToken).To(Equal("old-refresh-token"))
+					return &token.RefreshDetails{RefreshUuid: "old-refresh-uuid", UserId: userID}, nil
+				}
+
+				mockRedisRepo.RevokeRefreshTokenFunc = func(ctx context.Context, refreshUuid string) (bool, error) {
+					calls = append(calls, "revoke")
+					Expect(refreshUuid).To(Equal("old-refresh-uuid"))
+					return true, nil
+				}
+
+				mockRedisRepo.GetRefreshSessionFunc = func(ctx context.Context, refreshUuid string) (string, error) {
+					calls = append(calls, "session")
+					Expect(refreshUuid).To(Equal("old-refresh-uuid"))
+					return "session-id", nil
+				}
+
+				mockRedisRepo.StoreTokenDetailsFunc = func(ctx context.Context, uID int64, sessionID string, details *token.Details) error {
+					calls = append(calls, "store")
+					Expect(uID).To(Equal(userID))
+					Expect(sessionID).To(Equal("session-id"))
+					Expect(details.RefreshToken).To(Equal("mock-refresh-token"))
+					return nil
+				}
+
+				details, err := userService.RefreshToken(ctx, "old-refresh-token")
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(details.AccessToken).To(Equal("mock-access-token"))
+				Expect(calls).To(Equal([]string{"session", "revoke", "store"}))
+			})
+		})
+
+		Context("when the refresh token cannot be validated", func() {
+			It("returns an invalid refresh token error", func() {
+				mockTokenService.ValidateRefreshTokenFunc = func(refreshToken string) (*token.RefreshDetails, error) {
+					return nil, errors.New("signature is invalid")
+				}
+
+				mockRedisRepo.RevokeRefreshTokenFunc = func(ctx context.Context, refreshUuid string) (bool, error) {
+					Fail("revoke should not be called")
+					return false, nil
+				}
+
+				_, err := userService.RefreshToken(ctx, "bad-token")
+
+				Expect(err).To(MatchError(services.ErrInvalidRefreshToken))
+			})
+		})
+
+		Context("when the refresh token has already been used", func() {
+			BeforeEach(func() {
+				mockRedisRepo.GetRefreshSessionFunc = func(ctx context.Context, refreshUuid string) (string, error) {
+					return "session-id", nil
+				}
+
+				mockRedisRepo.RevokeRefreshTokenFunc = func(ctx context.Context, refreshUuid string) (bool, error) {
+					return false, nil
+				}
+			})
+
+			It("revokes the session without issuing new tokens", func() {
+				var revokedSession string
+				mockRedisRepo.RevokeSessionFunc = func(ctx context.Context, sessionID string) error {
+					revokedSession = sessionID
+					return nil
+				}
+
+				mockTokenService.CreateTokenFunc = func(userID int64) (*token.Details, error) {
+					Fail("create token should not be called")
+					return nil, nil
+				}
+
+				_, err := userService.RefreshToken(ctx, "reused-token")
+
+				Expect(err).To(MatchError(services.ErrInvalidRefreshToken))
+				Expect(revokedSession).To(Equal("session-id"))
+			})
+
+			It("returns an invalid refresh token error if the session has already ended", func() {
+				mockRedisRepo.RevokeSessionFunc = func(ctx context.Context, sessionID string) error {
+					return repositories.ErrSessionNotFound
+				}
+
+				_, err := userService.RefreshToken(ctx, "reused-token")
+
+				Expect(err).To(MatchError(services.ErrInvalidRefreshToken))
+			})
+
+			It("returns an error if the session cannot be revoked", func() {
+				mockRedisRepo.RevokeSessionFunc = func(ctx context.Context, sessionID string) error {
+					return errors.New("redis error")
+				}
+
+				_, err := userService.RefreshToken(ctx, "reused-token")
+
+				Expect(err).To(MatchError("redis error"))
+			})
+		})
+
+		Context("when revoking the old tokens fails", func() {
+			It("returns an error", func() {
+				mockRedisRepo.RevokeRefreshTokenFunc = func(ctx context.Context, refreshUuid string) (bool, error) {
+					return false, errors.New("redis error")
+				}
+
+				_, err := userService.RefreshToken(ctx, "refresh-token")
+
+				Expect(err).To(MatchError("redis error"))
+			})
+		})
+
+		Context("when the refresh token does not belong to a session", func() {
+			It("returns an invalid refresh token error", func() {
+				mockRedisRepo.GetRefreshSessionFunc = func(ctx context.Context, refreshUuid string) (string, error) {
+					return "", repositories.ErrSessionNotFound
+				}
+
+				mockRedisRepo.RevokeRefreshTokenFunc = func(ctx context.Context, refreshUuid string) (bool, error) {
+					Fail("revoke should not be called")
+					return false, nil
+				}
+
+				_, err := userService.RefreshToken(ctx, "refresh-token")
+
+				Expect(err).To(MatchError(services.ErrInvalidRefreshToken))
+			})
+		})
+
+		Context("when the session is revoked during the refresh", func() {
+			It("returns an invalid refresh token error", func() {
+				mockRedisRepo.StoreTokenDetailsFunc = func(ctx context.Context, userID int64, sessionID string, details *token.Details) error {
+					return repositories.ErrSessionNotFound
+				}
+
+				_, err := userService.RefreshToken(ctx, "refresh-token")
+
+				Expect(err).To(MatchError(services.ErrInvalidRefreshToken))
+			})
+		})
+
+		Context("when storing the new tokens fails", func() {
+			It("returns an error", func() {
+				mockRedisRepo.StoreTokenDetailsFunc = func(ctx context.Context, userID int64, sessionID string, details *token.Details) error {
+					return errors.New("redis error")
+				}
+
+				_, err := userService.RefreshToken(ctx, "refresh-token")
+
+				Expect(err).To(MatchError("redis error"))
+			})
+		})
+	})
 })
 
 type MockUsersRepository struct {
-	ExistsByUsernameFunc func(username string) (bool, error)
-	ExistsByEmailFunc    func(email string) (bool, error)
-	InsertFunc           func(username, email, password string) (int64, error)
-	VerifyFunc           func(login, password string) (bool, int64, error)
+	ExistsByUsernameFunc func(ctx context.Context, username string) (bool, error)
+	ExistsByEmailFunc    func(ctx context.Context, email string) (bool, error)
+	InsertFunc           func(ctx context.Context, username, email, password string) (int64, error)
+	VerifyFunc           func(ctx context.Context, login, password string) (bool, int64, error)
 }
 
-func (m *MockUsersRepository) ExistsByUsername(username string) (bool, error) {
+func (m *MockUsersRepository) ExistsByUsername(ctx context.Context, username string) (bool, error) {
 	if m.ExistsByUsernameFunc != nil {
-		return m.ExistsByUsernameFunc(username)
+		return m.ExistsByUsernameFunc(ctx, username)
 	}
 	return false, nil
 }
 
-func (m *MockUsersRepository) ExistsByEmail(email string) (bool, error) {
+func (m *MockUsersRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
 	if m.ExistsByEmailFunc != nil {
-		return m.ExistsByEmailFunc(email)
+		return m.ExistsByEmailFunc(ctx, email)
 	}
 	return false, nil
 }
 
-func (m *MockUsersRepository) Insert(username, email, password string) (int64, error) {
+func (m *MockUsersRepository) Insert(ctx context.Context, username, email, password string) (int64, error) {
 	if m.InsertFunc != nil {
-		return m.InsertFunc(username, email, password)
+		return m.InsertFunc(ctx, username, email, password)
 	}
 	return 1, nil
 }
 
-func (m *MockUsersRepository) Verify(login, password string) (bool, int64, error) {
+func (m *MockUsersRepository) Verify(ctx context.Context, login, password string) (bool, int64, error) {
 	if m.VerifyFunc != nil {
-		return m.VerifyFunc(login, password)
+		return m.VerifyFunc(ctx, login, password)
 	}
 	return false, -1, nil
 }
 
 type MockTokenService struct {
-	CreateTokenFunc   func(userID int64) (*token.Details, error)
-	ValidateTokenFunc func(r *http.Request) (*token.AccessDetails, error)
+	CreateTokenFunc          func(userID int64) (*token.Details, error)
+	ValidateTokenFunc        func(r *http.Request) (*token.AccessDetails, error)
+	ValidateRefreshTokenFunc func(refreshToken string) (*token.RefreshDetails, error)
 }
 
 func (m *MockTokenService) CreateToken(userID int64) (*token.Details, error) {
@@ -420,21 +600,106 @@ func (m *MockTokenService) ValidateToken(r *http.Request) (*token.AccessDetails,
 	}, nil
 }
 
+func (m *MockTokenService) ValidateRefreshToken(refreshToken string) (*token.RefreshDetails, error) {
+	if m.ValidateRefreshTokenFunc != nil {
+		return m.ValidateRefreshTokenFunc(refreshToken)
+	}
+	return &token.RefreshDetails{
+		RefreshUuid: "mock-refresh-uuid",
+		UserId:      1,
+	}, nil
+}
+
 type MockRedisRepository struct {
-	StoreTokenDetailsFunc  func(userID int64, details *token.Details) error
-	DeleteTokenDetailsFunc func(uuid string) error
+	CreateSessionFunc      func(ctx context.Context, session *repositories.Session, details *token.Details) error
+	StoreTokenDetailsFunc  func(ctx context.Context, userID int64, sessionID string, details *token.Details) error
+	DeleteTokenDetailsFunc func(ctx context.Context, uuid string) error
+	GetRefreshSessionFunc  func(ctx context.Context, refreshUuid string) (string, error)
+	RevokeRefreshTokenFunc func(ctx context.Context, refreshUuid string) (bool, error)
+	GetSessionFunc         func(ctx context.Context, sessionID string) (*repositories.Session, error)
+	ListSessionsFunc       func(ctx context.Context, userID int64) ([]*repositories.Session, error)
+	RevokeSessionFunc      func(ctx context.Context, sessionID string) error
+	RevokeAllSessionsFunc  func(ctx context.Context, userID int64) error
 }
 
-func (m *MockRedisRepository) StoreTokenDetails(userID int64, details *token.Details) error {
+func (m *MockRedisRepository) CreateSession(ctx context.Context, session *repositories.Session, details *token.Details) error {
+	if m.CreateSessionFunc != nil {
+		return m.CreateSessionFunc(ctx, session, details)
+	}
+	return nil
+}
+
+func (m *MockRedisRepository) StoreTokenDetails(ctx context.Context, userID int64, sessionID string, details *token.Details) error {
 	if m.StoreTokenDetailsFunc != nil {
-		return m.StoreTokenDetailsFunc(userID, details)
+		return m.StoreTokenDetailsFunc(ctx, userID, sessionID, details)
 	}
 	return nil
 }
 
-func (m *MockRedisRepository) DeleteTokenDetails(uuid string) error {
+func (m *MockRedisRepository) DeleteTokenDetails(ctx context.Context, uuid string) error {
 	if m.DeleteTokenDetailsFunc != nil {
-		return m.DeleteTokenDetailsFunc(uuid)
+		return m.DeleteTokenDetailsFunc(ctx, uuid)
+	}
+	return nil
+}
+
+func (m *MockRedisRepository) GetRefreshSession(ctx context.Context, refreshUuid string) (string, error) {
+	if m.GetRefreshSessionFunc != nil {
+		return m.GetRefreshSessionFunc(ctx, refreshUuid)
+	}
+	return "mock-session-id", nil
+}
+
+func (m *MockRedisRepository) RevokeRefreshToken(ctx context.Context, refreshUuid string) (bool, error) {
+	if m.RevokeRefreshTokenFunc != nil {
+		return m.RevokeRefreshTokenFunc(ctx, refreshUuid)
+	}
+	return true, nil
+}
+
+func (m *MockRedisRepository) GetSession(ctx context.Context, sessionID string) (*repositories.Session, error) {
+	if m.GetSessionFunc != nil {
+		return m.GetSessionFunc(ctx, sessionID)
+	}
+	return nil, repositories.ErrSessionNotFound
+}
+
+func (m *MockRedisRepository) ListSessions(ctx context.Context, userID int64) ([]*repositories.Session, error) {
+	if m.ListSessionsFunc != nil {
+		return m.ListSessionsFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *MockRedisRepository) RevokeSession(ctx context.Context, sessionID string) error {
+	if m.RevokeSessionFunc != nil {
+		return m.RevokeSessionFunc(ctx, sessionID)
+	}
+	return nil
+}
+
+func (m *MockRedisRepository) RevokeAllSessions(ctx context.Context, userID int64) error {
+	if m.RevokeAllSessionsFunc != nil {
+		return m.RevokeAllSessionsFunc(ctx, userID)
+	}
+	return nil
+}
+
+type MockEmailVerifier struct {
+	SendVerificationFunc func(ctx context.Context, user *repositories.User) error
+	CheckVerifiedFunc    func(ctx context.Context, userID int64) error
+}
+
+func (m *MockEmailVerifier) SendVerification(ctx context.Context, user *repositories.User) error {
+	if m.SendVerificationFunc != nil {
+		return m.SendVerificationFunc(ctx, user)
+	}
+	return nil
+}
+
+func (m *MockEmailVerifier) CheckVerified(ctx context.Context, userID int64) error {
+	if m.CheckVerifiedFunc != nil {
+		return m.CheckVerifiedFunc(ctx, userID)
 	}
 	return nil
 }
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/mailer"
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+	"github.com/iplay88keys/my-recipe-library/pkg/token"
+)
+
+const (
+	VerificationEmailsPerWindow = 3
+	VerificationEmailWindow     = time.Hour
+)
+
+var (
+	ErrEmailNotVerified          = errors.New("email has not been verified")
+	ErrInvalidVerificationToken  = errors.New("invalid email verification token")
+	ErrTooManyVerificationEmails = errors.New("too many verification emails")
+)
+
+type VerificationUsersRepositoryInterface interface {
+	GetByID(ctx context.Context, userID int64) (*repositories.User, error)
+	GetByEmail(ctx context.Context, email string) (*repositories.User, error)
+	MarkEmailVerified(ctx context.Context, userID int64) error
+}
+
+type VerificationEmailCounterInterface interface {
+	CountVerificationEmail(ctx context.Context, email string, window time.Duration) (int64, error)
+}
+
+type VerificationTokenServiceInterface interface {
+	CreateVerificationToken(userID int64, email string) (string, error)
+	ValidateVerificationToken(verificationToken string) (*token.VerificationDetails, error)
+}
+
+type VerificationService struct {
+	usersRepo    VerificationUsersRepositoryInterface
+	emailCounter VerificationEmailCounterInterface
+	tokenService VerificationTokenServiceInterface
+	mailer       mailer.Mailer
+	verifyURL    string
+	required     bool
+}
+
+// NewVerificationService creates a VerificationService. Verification links
+// are sent as verifyURL with the token added as the "token" query parameter.
+// When required is true users cannot log in until their email is verified.
+func NewVerificationService(
+	usersRepo VerificationUsersRepositoryInterface,
+	emailCounter VerificationEmailCounterInterface,
+	tokenService VerificationTokenServiceInterface,
+	m mailer.Mailer,
+	verifyURL string,
+	required bool,
+) *VerificationService {
+	return &VerificationService{
+		usersRepo:    usersRepo,
+		emailCounter: emailCounter,
+		tokenService: tokenService,
+		mailer:       m,
+		verifyURL:    verifyURL,
+		required:     required,
+	}
+}
+
+// SendVerification emails the user a link that verifies their address.
+func (s *VerificationService) SendVerification(ctx context.Context, user *repositories.User) error {
+	verificationToken, err := s.tokenService.CreateVerificationToken(user.ID, user.Email)
+	if err != nil {
+		return err
+	}
+
+	link, err := url.Parse(s.verifyURL)
+	if err != nil {
+		return err
+	}
+
+	query := link.Query()
+	query.Set("token", verificationToken)
+	link.RawQuery = query.Encode()
+
+	return s.mailer.Send(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the link below to verify your email address. It expires in 24 hours.\n\n%s\n",
+			user.Username,
+			link.String(),
+		),
+	})
+}
+
+// ResendVerification sends another verification email. Only a few are sent to
+// each address per window, whether or not it belongs to a user. Like a
+// password reset it does not reveal which emails are registered.
+func (s *VerificationService) ResendVerification(ctx context.Context, email string) error {
+	count, err := s.emailCounter.CountVerificationEmail(ctx, email, VerificationEmailWindow)
+	if err != nil {
+		return err
+	}
+
+	if count > VerificationEmailsPerWindow {
+		return ErrTooManyVerificationEmails
+	}
+
+	user, err := s.usersRepo.GetByEmail(ctx, email)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerified {
+		return nil
+	}
+
+	return s.SendVerification(ctx, user)
+}
+
+// VerifyEmail marks the address in a verification token as verified. The
+// token stops working if the user changes their email.
+func (s *VerificationService) VerifyEmail(ctx context.Context, verificationToken string) error {
+	details, err := s.tokenService.ValidateVerificationToken(verificationToken)
+	if err != nil {
+		return ErrInvalidVerificationToken
+	}
+
+	user, err := s.usersRepo.GetByID(ctx, details.UserId)
+	if err == sql.ErrNoRows {
+		return ErrInvalidVerificationToken
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(user.Email, details.Email) {
+		return ErrInvalidVerificationToken
+	}
+
+	if user.EmailVerified {
+		return nil
+	}
+
+	return s.usersRepo.MarkEmailVerified(ctx, user.ID)
+}
+
+// CheckVerified returns ErrEmailNotVerified if verification is required and
+// the user has not verified their email yet.
+func (s *VerificationService) CheckVerified(ctx context.Context, userID int64) error {
+	if !s.required {
+		return nil
+	}
+
+	user, err := s.usersRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !user.EmailVerified {
+		return ErrEmailNotVerified
+	}
+
+	return nil
+}
@@ -0,0 +1,293 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/mailer"
+	"github.com/iplay88keys/my-recipe-library/pkg/repositories"
+	"github.com/iplay88keys/my-recipe-library/pkg/services"
+	"github.com/iplay88keys/my-recipe-library/pkg/token"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("VerificationService", func() {
+	var (
+		verificationService *services.VerificationService
+		mockUsersRepo       *MockVerificationUsersRepository
+		mockCounter         *MockVerificationEmailCounter
+		mockTokenService    *MockVerificationTokenService
+		mockMailer          *MockMailer
+		ctx                 context.Context
+	)
+
+	newService := func(required bool) *services.VerificationService {
+		return services.NewVerificationService(
+			mockUsersRepo,
+			mockCounter,
+			mockTokenService,
+			mockMailer,
+			"http://localhost:8080/verify-email",
+			required,
+		)
+	}
+
+	BeforeEach(func() {
+		mockUsersRepo = &MockVerificationUsersRepository{}
+		mockCounter = &MockVerificationEmailCounter{}
+		mockTokenService = &MockVerificationTokenService{}
+		mockMailer = &MockMailer{}
+		verificationService = newService(false)
+
+		ctx = context.Background()
+	})
+
+	Describe("SendVerification", func() {
+		It("emails a link with a token for the user's address", func() {
+			mockTokenService.CreateVerificationTokenFunc = func(userID int64, email string) (string, error) {
+				Expect(userID).To(Equal(int64(10)))
+				Expect(email).To(Equal("cook@example.com"))
+				return "verification-token", nil
+			}
+
+			var sent *mailer.Message
+			mockMailer.SendFunc = func(ctx context.Context, msg *mailer.Message) error {
+				sent = msg
+				return nil
+			}
+
+			err := verificationService.SendVerification(ctx, &repositories.User{
+				ID:       10,
+				Username: "cook",
+				Email:    "cook@example.com",
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(sent.To).To(Equal("cook@example.com"))
+
+			link, err := url.Parse(regexp.MustCompile(`http://\S+`).FindString(sent.Body))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(link.Path).To(Equal("/verify-email"))
+			Expect(link.Query().Get("token")).To(Equal("verification-token"))
+		})
+	})
+
+	Describe("ResendVerification", func() {
+		BeforeEach(func() {
+			mockCounter.CountVerificationEmailFunc = func(ctx context.Context, email string, window time.Duration) (int64, error) {
+				Expect(window).To(Equal(services.VerificationEmailWindow))
+				return 1, nil
+			}
+		})
+
+		It("sends another email to an unverified user", func() {
+			mockUsersRepo.GetByEmailFunc = func(ctx context.Context, email string) (*repositories.User, error) {
+				return &repositories.User{ID: 10, Username: "cook", Email: email}, nil
+			}
+
+			sent := false
+			mockMailer.SendFunc = func(ctx context.Context, msg *mailer.Message) error {
+				sent = true
+				return nil
+			}
+
+			err := verificationService.ResendVerification(ctx, "cook@example.com")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sent).To(BeTrue())
+		})
+
+		It("does not send anything to a verified user or unknown address", func() {
+			mockMailer.SendFunc = func(ctx context.Context, msg *mailer.Message) error {
+				Fail("no mail should be sent")
+				return nil
+			}
+
+			mockUsersRepo.GetByEmailFunc = func(ctx context.Context, email string) (*repositories.User, error) {
+				return &repositories.User{ID: 10, Email: email, EmailVerified: true}, nil
+			}
+
+			err := verificationService.ResendVerification(ctx, "cook@example.com")
+			Expect(err).ToNot(HaveOccurred())
+
+			mockUsersRepo.GetByEmailFunc = func(ctx context.Context, email string) (*repositories.User, error) {
+				return nil, sql.ErrNoRows
+			}
+
+			err = verificationService.ResendVerification(ctx, "missing@example.com")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("limits how many emails are sent to an address", func() {
+			mockCounter.CountVerificationEmailFunc = func(ctx context.Context, email string, window time.Duration) (int64, error) {
+				return services.VerificationEmailsPerWindow + 1, nil
+			}
+
+			mockUsersRepo.GetByEmailFunc = func(ctx context.Context, email string) (*repositories.User, error) {
+				Fail("user should not be looked up")
+				return nil, nil
+			}
+
+			err := verificationService.ResendVerification(ctx, "cook@example.com")
+			Expect(err).To(MatchError(services.ErrTooManyVerificationEmails))
+		})
+
+		It("returns an error if the emails cannot be counted", func() {
+			mockCounter.CountVerificationEmailFunc = func(ctx context.Context, email string, window time.Duration) (int64, error) {
+				return 0, errors.New("redis error")
+			}
+
+			err := verificationService.ResendVerification(ctx, "cook@example.com")
+			Expect(err).To(MatchError("redis error"))
+		})
+	})
+
+	Describe("VerifyEmail", func() {
+		BeforeEach(func() {
+			mockTokenService.ValidateVerificationTokenFunc = func(verificationToken string) (*token.VerificationDetails, error) {
+				return &token.VerificationDetails{UserId: 10, Email: "cook@example.com"}, nil
+			}
+		})
+
+		It("marks the user's email as verified", func() {
+			mockUsersRepo.GetByIDFunc = func(ctx context.Context, userID int64) (*repositories.User, error) {
+				return &repositories.User{ID: userID, Email: "Cook@example.com"}, nil
+			}
+
+			var verified int64
+			mockUsersRepo.MarkEmailVerifiedFunc = func(ctx context.Context, userID int64) error {
+				verified = userID
+				return nil
+			}
+
+			err := verificationService.VerifyEmail(ctx, "verification-token")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(verified).To(Equal(int64(10)))
+		})
+
+		It("succeeds without an update if the email is already verified", func() {
+			mockUsersRepo.GetByIDFunc = func(ctx context.Context, userID int64) (*repositories.User, error) {
+				return &repositories.User{ID: userID, Email: "cook@example.com", EmailVerified: true}, nil
+			}
+
+			mockUsersRepo.MarkEmailVerifiedFunc = func(ctx context.Context, userID int64) error {
+				Fail("email should not be marked again")
+				return nil
+			}
+
+			err := verificationService.VerifyEmail(ctx, "verification-token")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns an invalid token error if the token does not validate", func() {
+			mockTokenService.ValidateVerificationTokenFunc = func(verificationToken string) (*token.VerificationDetails, error) {
+				return nil, token.ErrExpired
+			}
+
+			err := verificationService.VerifyEmail(ctx, "verification-token")
+			Expect(err).To(MatchError(services.ErrInvalidVerificationToken))
+		})
+
+		It("returns an invalid token error if the user's email has changed", func() {
+			mockUsersRepo.GetByIDFunc = func(ctx context.Context, userID int64) (*repositories.User, error) {
+				return &repositories.User{ID: userID, Email: "new@example.com"}, nil
+			}
+
+			err := verificationService.VerifyEmail(ctx, "verification-token")
+			Expect(err).To(MatchError(services.ErrInvalidVerificationToken))
+		})
+
+		It("returns an invalid token error if the user no longer exists", func() {
+			mockUsersRepo.GetByIDFunc = func(ctx context.Context, userID int64) (*repositories.User, error) {
+				return nil, sql.ErrNoRows
+			}
+
+			err := verificationService.VerifyEmail(ctx, "verification-token")
+			Expect(err).To(MatchError(services.ErrInvalidVerificationToken))
+		})
+	})
+
+	Describe("CheckVerified", func() {
+		It("allows unverified users when verification is not required", func() {
+			mockUsersRepo.GetByIDFunc = func(ctx context.Context, userID int64) (*repositories.User, error) {
+				Fail("user should not be looked up")
+				return nil, nil
+			}
+
+			Expect(verificationService.CheckVerified(ctx, 10)).To(Succeed())
+		})
+
+		It("only allows verified users when verification is required", func() {
+			verificationService = newService(true)
+
+			mockUsersRepo.GetByIDFunc = func(ctx context.Context, userID int64) (*repositories.User, error) {
+				return &repositories.User{ID: userID, EmailVerified: userID == 10}, nil
+			}
+
+			Expect(verificationService.CheckVerified(ctx, 10)).To(Succeed())
+			Expect(verificationService.CheckVerified(ctx, 20)).To(MatchError(services.ErrEmailNotVerified))
+		})
+	})
+})
+
+type MockVerificationUsersRepository struct {
+	GetByIDFunc           func(ctx context.Context, userID int64) (*repositories.User, error)
+	GetByEmailFunc        func(ctx context.Context, email string) (*repositories.User, error)
+	MarkEmailVerifiedFunc func(ctx context.Context, userID int64) error
+}
+
+func (m *MockVerificationUsersRepository) GetByID(ctx context.Context, userID int64) (*repositories.User, error) {
+	if m.GetByIDFunc != nil {
+		return m.GetByIDFunc(ctx, userID)
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *MockVerificationUsersRepository) GetByEmail(ctx context.Context, email string) (*repositories.User, error) {
+	if m.GetByEmailFunc != nil {
+		return m.GetByEmailFunc(ctx, email)
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *MockVerificationUsersRepository) MarkEmailVerified(ctx context.Context, userID int64) error {
+	if m.MarkEmailVerifiedFunc != nil {
+		return m.MarkEmailVerifiedFunc(ctx, userID)
+	}
+	return nil
+}
+
+type MockVerificationEmailCounter struct {
+	CountVerificationEmailFunc func(ctx context.Context, email string, window time.Duration) (int64, error)
+}
+
+func (m *MockVerificationEmailCounter) CountVerificationEmail(ctx context.Context, email string, window time.Duration) (int64, error) {
+	if m.CountVerificationEmailFunc != nil {
+		return m.CountVerificationEmailFunc(ctx, email, window)
+	}
+	return 1, nil
+}
+
+type MockVerificationTokenService struct {
+	CreateVerificationTokenFunc   func(userID int64, email string) (string, error)
+	ValidateVerificationTokenFunc func(verificationToken string) (*token.VerificationDetails, error)
+}
+
+func (m *MockVerificationTokenService) CreateVerificationToken(userID int64, email string) (string, error) {
+	if m.CreateVerificationTokenFunc != nil {
+		return m.CreateVerificationTokenFunc(userID, email)
+	}
+	return "verification-token", nil
+}
+
+func (m *MockVerificationTokenService) ValidateVerificationToken(verificationToken string) (*token.VerificationDetails, error) {
+	if m.ValidateVerificationTokenFunc != nil {
+		return m.ValidateVerificationTokenFunc(verificationToken)
+	}
+	return nil, token.ErrMalformed
+}
@@ -0,0 +1,120 @@
+package token
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrMalformed  = errors.New("token is malformed")
+	ErrUnknownKey = errors.New("token was signed with an unknown key")
+)
+
+type Claims interface {
+	Valid() error
+}
+
+// RegisteredClaims are the claims from RFC 7519 that every token carries.
+type RegisteredClaims struct {
+	Audience  string `json:"aud,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	NotBefore int64  `json:"nbf,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+func encode(signer Signer, claims interface{}) (string, error) {
+	h, err := json.Marshal(&header{
+		Algorithm: signer.Algorithm(),
+		Type:      "JWT",
+		KeyID:     signer.KeyID(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := encodeSegment(h) + "." + encodeSegment(payload)
+	signature, err := signer.Sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + encodeSegment(signature), nil
+}
+
+// decode verifies a compact JWS with the key named by its kid header and only
+// then unmarshals and validates the claims. The algorithm in the header has to
+// match the key, so a token can't pick a weaker algorithm for itself.
+func decode(signed string, lookup func(kid string) (Verifier, bool), claims Claims) error {
+	parts := strings.Split(signed, ".")
+	if len(parts) != 3 {
+		return ErrMalformed
+	}
+
+	rawHeader, err := decodeSegment(parts[0])
+	if err != nil {
+		return ErrMalformed
+	}
+
+	var h header
+	if err := json.Unmarshal(rawHeader, &h); err != nil {
+		return ErrMalformed
+	}
+
+	verifier, ok := lookup(h.KeyID)
+	if !ok {
+		return ErrUnknownKey
+	}
+
+	if h.Algorithm != verifier.Algorithm() {
+		return fmt.Errorf("unexpected signing method: %s", h.Algorithm)
+	}
+
+	signature, err := decodeSegment(parts[2])
+	if err != nil {
+		return ErrMalformed
+	}
+
+	if err := verifier.Verify([]byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return err
+	}
+
+	payload, err := decodeSegment(parts[1])
+	if err != nil {
+		return ErrMalformed
+	}
+
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return ErrMalformed
+	}
+
+	return claims.Valid()
+}
+
+// Verify checks a token signed by someone else, such as an identity provider,
+// against the key lookup returns for its kid header.
+func Verify(signed string, lookup func(kid string) (Verifier, bool), claims Claims) error {
+	return decode(signed, lookup, claims)
+}
+
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSegment(segment string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(segment)
+}
@@ -0,0 +1,178 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// KeySet signs with a single active key and verifies with the active key plus
+// any previous keys, so tokens issued before a rotation stay valid until they
+// expire.
+type KeySet struct {
+	signer    Signer
+	verifiers map[string]Verifier
+	order     []string
+}
+
+func NewKeySet(signer Signer, previous ...Verifier) (*KeySet, error) {
+	k := &KeySet{
+		signer:    signer,
+		verifiers: make(map[string]Verifier),
+	}
+
+	for _, verifier := range append([]Verifier{signer}, previous...) {
+		if _, ok := k.verifiers[verifier.KeyID()]; ok {
+			return nil, fmt.Errorf("duplicate key '%s'", verifier.KeyID())
+		}
+
+		k.verifiers[verifier.KeyID()] = verifier
+		k.order = append(k.order, verifier.KeyID())
+	}
+
+	return k, nil
+}
+
+// LoadKeySet builds a KeySet for the given algorithm. HS256 uses the shared
+// secret. RS256 and EdDSA read PEM files instead: the first file is the
+// private key used for signing and any others are previous keys, private or
+// public, that are only used for verification.
+func LoadKeySet(algorithm, secret string, keyFiles []string) (*KeySet, error) {
+	switch algorithm {
+	case AlgorithmHS256:
+		if len(keyFiles) > 0 {
+			return nil, errors.New("key files can't be used with HS256")
+		}
+
+		signer, err := NewHS256Signer([]byte(secret))
+		if err != nil {
+			return nil, err
+		}
+
+		return NewKeySet(signer)
+	case AlgorithmRS256, AlgorithmEdDSA:
+		if len(keyFiles) == 0 {
+			return nil, fmt.Errorf("a private key file is required for %s", algorithm)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm '%s'", algorithm)
+	}
+
+	key, err := readPEM(keyFiles[0])
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := newSigner(algorithm, key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", keyFiles[0], err.Error())
+	}
+
+	var previous []Verifier
+	for _, file := range keyFiles[1:] {
+		key, err := readPEM(file)
+		if err != nil {
+			return nil, err
+		}
+
+		verifier, err := newVerifier(key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", file, err.Error())
+		}
+
+		previous = append(previous, verifier)
+	}
+
+	return NewKeySet(signer, previous...)
+}
+
+func (k *KeySet) Sign(claims interface{}) (string, error) {
+	return encode(k.signer, claims)
+}
+
+func (k *KeySet) Verify(signed string, claims Claims) error {
+	return decode(signed, k.lookup, claims)
+}
+
+// JWKS returns the public keys in the set, active key first. Symmetric keys
+// are left out.
+func (k *KeySet) JWKS() *JWKS {
+	jwks := &JWKS{Keys: []*JWK{}}
+	for _, kid := range k.order {
+		if jwk := k.verifiers[kid].PublicJWK(); jwk != nil {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+
+	return jwks
+}
+
+func (k *KeySet) lookup(kid string) (Verifier, bool) {
+	verifier, ok := k.verifiers[kid]
+	return verifier, ok
+}
+
+func newSigner(algorithm string, key interface{}) (Signer, error) {
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		if algorithm == AlgorithmRS256 {
+			return NewRS256Signer(key)
+		}
+	case ed25519.PrivateKey:
+		if algorithm == AlgorithmEdDSA {
+			return NewEdDSASigner(key)
+		}
+	}
+
+	return nil, fmt.Errorf("not a private key for %s", algorithm)
+}
+
+func newVerifier(key interface{}) (Verifier, error) {
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return NewRS256Verifier(&key.PublicKey)
+	case *rsa.PublicKey:
+		return NewRS256Verifier(key)
+	case ed25519.PrivateKey:
+		return NewEdDSAVerifier(key.Public().(ed25519.PublicKey))
+	case ed25519.PublicKey:
+		return NewEdDSAVerifier(key)
+	}
+
+	return nil, errors.New("unsupported key type")
+}
+
+func readPEM(file string) (interface{}, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %s", err.Error())
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", file)
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block '%s'", file, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err.Error())
+	}
+
+	return key, nil
+}
@@ -0,0 +1,318 @@
+package token_test
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/token"
+)
+
+type testClaims struct {
+	Name string `json:"name"`
+}
+
+func (c *testClaims) Valid() error {
+	return nil
+}
+
+var _ = Describe("keys", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	writePEM := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600)
+		Expect(err).ToNot(HaveOccurred())
+
+		return path
+	}
+
+	writePrivateKey := func(name string, key interface{}) string {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		Expect(err).ToNot(HaveOccurred())
+
+		return writePEM(name, "PRIVATE KEY", der)
+	}
+
+	writePublicKey := func(name string, key interface{}) string {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		Expect(err).ToNot(HaveOccurred())
+
+		return writePEM(name, "PUBLIC KEY", der)
+	}
+
+	newRSAKey := func(bits int) *rsa.PrivateKey {
+		key, err := rsa.GenerateKey(rand.Reader, bits)
+		Expect(err).ToNot(HaveOccurred())
+
+		return key
+	}
+
+	newEd25519Key := func() ed25519.PrivateKey {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		return key
+	}
+
+	headerOf := func(signed string) map[string]string {
+		data, err := base64.RawURLEncoding.DecodeString(strings.Split(signed, ".")[0])
+		Expect(err).ToNot(HaveOccurred())
+
+		header := map[string]string{}
+		Expect(json.Unmarshal(data, &header)).To(Succeed())
+
+		return header
+	}
+
+	Context("LoadKeySet", func() {
+		It("signs and verifies with an RS256 key", func() {
+			key := newRSAKey(2048)
+			keys, err := token.LoadKeySet(token.AlgorithmRS256, "", []string{writePrivateKey("access.pem", key)})
+			Expect(err).ToNot(HaveOccurred())
+
+			signed, err := keys.Sign(&testClaims{Name: "rsa"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(headerOf(signed)["alg"]).To(Equal("RS256"))
+
+			claims := &testClaims{}
+			Expect(keys.Verify(signed, claims)).To(Succeed())
+			Expect(claims.Name).To(Equal("rsa"))
+
+			jwks := keys.JWKS()
+			Expect(jwks.Keys).To(HaveLen(1))
+			Expect(jwks.Keys[0]).To(Equal(&token.JWK{
+				KeyType:   "RSA",
+				Use:       "sig",
+				Algorithm: token.AlgorithmRS256,
+				KeyID:     headerOf(signed)["kid"],
+				N:         base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}))
+		})
+
+		It("signs and verifies with an EdDSA key", func() {
+			key := newEd25519Key()
+			keys, err := token.LoadKeySet(token.AlgorithmEdDSA, "", []string{writePrivateKey("access.pem", key)})
+			Expect(err).ToNot(HaveOccurred())
+
+			signed, err := keys.Sign(&testClaims{Name: "ed25519"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(headerOf(signed)["alg"]).To(Equal("EdDSA"))
+
+			claims := &testClaims{}
+			Expect(keys.Verify(signed, claims)).To(Succeed())
+			Expect(claims.Name).To(Equal("ed25519"))
+
+			jwks := keys.JWKS()
+			Expect(jwks.Keys).To(HaveLen(1))
+			Expect(jwks.Keys[0]).To(Equal(&token.JWK{
+				KeyType:   "OKP",
+				Use:       "sig",
+				Algorithm: token.AlgorithmEdDSA,
+				KeyID:     headerOf(signed)["kid"],
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+			}))
+		})
+
+		It("reads PKCS1 RSA keys", func() {
+			key := newRSAKey(2048)
+			path := writePEM("access.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+
+			_, err := token.LoadKeySet(token.AlgorithmRS256, "", []string{path})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("does not publish HS256 secrets", func() {
+			keys, err := token.LoadKeySet(token.AlgorithmHS256, "secret value", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(keys.JWKS()).To(Equal(&token.JWKS{Keys: []*token.JWK{}}))
+		})
+
+		It("uses the RFC 7638 thumbprint as the key id", func() {
+			key := newEd25519Key()
+			keys, err := token.LoadKeySet(token.AlgorithmEdDSA, "", []string{writePrivateKey("access.pem", key)})
+			Expect(err).ToNot(HaveOccurred())
+
+			x := base64.RawURLEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+			digest := sha256.Sum256([]byte(`{"crv":"Ed25519","kty":"OKP","x":"` + x + `"}`))
+
+			Expect(keys.JWKS().Keys[0].KeyID).To(Equal(base64.RawURLEncoding.EncodeToString(digest[:])))
+		})
+
+		It("keeps verifying tokens signed with a previous key after a rotation", func() {
+			oldKey := newEd25519Key()
+			oldKeys, err := token.LoadKeySet(token.AlgorithmEdDSA, "", []string{writePrivateKey("old.pem", oldKey)})
+			Expect(err).ToNot(HaveOccurred())
+
+			oldToken, err := oldKeys.Sign(&testClaims{Name: "old"})
+			Expect(err).ToNot(HaveOccurred())
+
+			newKey := newRSAKey(2048)
+			rotated, err := token.LoadKeySet(token.AlgorithmRS256, "", []string{
+				writePrivateKey("new.pem", newKey),
+				writePublicKey("old.pub.pem", oldKey.Public()),
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			claims := &testClaims{}
+			Expect(rotated.Verify(oldToken, claims)).To(Succeed())
+			Expect(claims.Name).To(Equal("old"))
+
+			newToken, err := rotated.Sign(&testClaims{Name: "new"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(headerOf(newToken)["alg"]).To(Equal("RS256"))
+			Expect(headerOf(newToken)["kid"]).ToNot(Equal(headerOf(oldToken)["kid"]))
+
+			jwks := rotated.JWKS()
+			Expect(jwks.Keys).To(HaveLen(2))
+			Expect(jwks.Keys[0].KeyID).To(Equal(headerOf(newToken)["kid"]))
+			Expect(jwks.Keys[1].KeyID).To(Equal(headerOf(oldToken)["kid"]))
+
+			retired, err := token.LoadKeySet(token.AlgorithmRS256, "", []string{filepath.Join(dir, "new.pem")})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(retired.Verify(oldToken, &testClaims{})).To(MatchError(token.ErrUnknownKey))
+		})
+
+		It("rejects a token that claims HS256 for an RSA key", func() {
+			key := newRSAKey(2048)
+			keys, err := token.LoadKeySet(token.AlgorithmRS256, "", []string{writePrivateKey("access.pem", key)})
+			Expect(err).ToNot(HaveOccurred())
+
+			signed, err := keys.Sign(&testClaims{Name: "rsa"})
+			Expect(err).ToNot(HaveOccurred())
+
+			publicPEM, err := os.ReadFile(writePublicKey("access.pub.pem", &key.PublicKey))
+			Expect(err).ToNot(HaveOccurred())
+
+			header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT", "kid": headerOf(signed)["kid"]})
+			Expect(err).ToNot(HaveOccurred())
+
+			signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + strings.Split(signed, ".")[1]
+			mac := hmac.New(sha256.New, publicPEM)
+			mac.Write([]byte(signingInput))
+			forged := signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+			Expect(keys.Verify(forged, &testClaims{})).To(MatchError("unexpected signing method: HS256"))
+		})
+
+		It("requires a secret for HS256", func() {
+			_, err := token.LoadKeySet(token.AlgorithmHS256, "", nil)
+			Expect(err).To(MatchError("a secret is required for HS256"))
+		})
+
+		It("does not accept key files for HS256", func() {
+			_, err := token.LoadKeySet(token.AlgorithmHS256, "secret value", []string{"access.pem"})
+			Expect(err).To(MatchError("key files can't be used with HS256"))
+		})
+
+		It("requires a key file for asymmetric algorithms", func() {
+			_, err := token.LoadKeySet(token.AlgorithmRS256, "", nil)
+			Expect(err).To(MatchError("a private key file is required for RS256"))
+		})
+
+		It("returns an error for an unsupported algorithm", func() {
+			_, err := token.LoadKeySet("HS512", "secret value", nil)
+			Expect(err).To(MatchError("unsupported signing algorithm 'HS512'"))
+		})
+
+		It("returns an error if the signing key does not match the algorithm", func() {
+			path := writePrivateKey("access.pem", newEd25519Key())
+
+			_, err := token.LoadKeySet(token.AlgorithmRS256, "", []string{path})
+			Expect(err).To(MatchError(path + ": not a private key for RS256"))
+		})
+
+		It("returns an error if the signing key is only a public key", func() {
+			path := writePublicKey("access.pub.pem", newEd25519Key().Public())
+
+			_, err := token.LoadKeySet(token.AlgorithmEdDSA, "", []string{path})
+			Expect(err).To(MatchError(path + ": not a private key for EdDSA"))
+		})
+
+		It("rejects RSA keys smaller than 2048 bits", func() {
+			path := writePrivateKey("access.pem", newRSAKey(1024))
+
+			_, err := token.LoadKeySet(token.AlgorithmRS256, "", []string{path})
+			Expect(err).To(MatchError(path + ": RSA keys must be at least 2048 bits"))
+		})
+
+		It("returns an error if the file is not PEM encoded", func() {
+			path := filepath.Join(dir, "access.pem")
+			Expect(os.WriteFile(path, []byte("not a key"), 0600)).To(Succeed())
+
+			_, err := token.LoadKeySet(token.AlgorithmEdDSA, "", []string{path})
+			Expect(err).To(MatchError(path + ": no PEM data found"))
+		})
+
+		It("returns an error if the same key is listed twice", func() {
+			path := writePrivateKey("access.pem", newEd25519Key())
+
+			_, err := token.LoadKeySet(token.AlgorithmEdDSA, "", []string{path, path})
+			Expect(err).To(MatchError(ContainSubstring("duplicate key")))
+		})
+	})
+
+	Context("JWK", func() {
+		It("verifies tokens with a published RSA key", func() {
+			keys, err := token.LoadKeySet(token.AlgorithmRS256, "", []string{writePrivateKey("access.pem", newRSAKey(2048))})
+			Expect(err).ToNot(HaveOccurred())
+
+			signed, err := keys.Sign(&testClaims{Name: "published"})
+			Expect(err).ToNot(HaveOccurred())
+
+			verifier, err := keys.JWKS().Keys[0].Verifier()
+			Expect(err).ToNot(HaveOccurred())
+
+			lookup := func(kid string) (token.Verifier, bool) {
+				return verifier, kid == verifier.KeyID()
+			}
+
+			claims := &testClaims{}
+			Expect(token.Verify(signed, lookup, claims)).To(Succeed())
+			Expect(claims.Name).To(Equal("published"))
+		})
+
+		It("verifies tokens with a published Ed25519 key", func() {
+			keys, err := token.LoadKeySet(token.AlgorithmEdDSA, "", []string{writePrivateKey("access.pem", newEd25519Key())})
+			Expect(err).ToNot(HaveOccurred())
+
+			signed, err := keys.Sign(&testClaims{Name: "published"})
+			Expect(err).ToNot(HaveOccurred())
+
+			verifier, err := keys.JWKS().Keys[0].Verifier()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(verifier.Algorithm()).To(Equal(token.AlgorithmEdDSA))
+
+			claims := &testClaims{}
+			Expect(token.Verify(signed, func(string) (token.Verifier, bool) { return verifier, true }, claims)).To(Succeed())
+		})
+
+		It("returns an error for an unsupported key", func() {
+			_, err := (&token.JWK{KeyType: "EC", Curve: "P-256"}).Verifier()
+			Expect(err).To(MatchError("unsupported key type: EC"))
+
+			_, err = (&token.JWK{KeyType: "OKP", Curve: "X25519"}).Verifier()
+			Expect(err).To(MatchError("unsupported curve: X25519"))
+		})
+	})
+})
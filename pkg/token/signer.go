@@ -0,0 +1,271 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+
+	minRSABits = 2048
+)
+
+// Verifier checks signatures made with a single key. Verifiers for keys that
+// are being rotated out only need the public half of the key.
+type Verifier interface {
+	Algorithm() string
+	KeyID() string
+	Verify(signingInput, signature []byte) error
+
+	// PublicJWK returns the key in JWK form, or nil for symmetric keys that
+	// must never be published.
+	PublicJWK() *JWK
+}
+
+type Signer interface {
+	Verifier
+	Sign(signingInput []byte) ([]byte, error)
+}
+
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// OKP
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []*JWK `json:"keys"`
+}
+
+// Verifier builds a verifier for a published RS256 or EdDSA key.
+func (j *JWK) Verifier() (Verifier, error) {
+	switch j.KeyType {
+	case "RSA":
+		n, err := decodeSegment(j.N)
+		if err != nil {
+			return nil, errors.New("invalid RSA modulus")
+		}
+
+		e, err := decodeSegment(j.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+
+		return NewRS256Verifier(&rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		})
+	case "OKP":
+		if j.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve: %s", j.Curve)
+		}
+
+		x, err := decodeSegment(j.X)
+		if err != nil {
+			return nil, errors.New("invalid Ed25519 public key")
+		}
+
+		return NewEdDSAVerifier(ed25519.PublicKey(x))
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", j.KeyType)
+	}
+}
+
+type HS256Signer struct {
+	secret []byte
+	kid    string
+}
+
+func NewHS256Signer(secret []byte) (*HS256Signer, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("a secret is required for HS256")
+	}
+
+	return &HS256Signer{
+		secret: secret,
+		kid:    thumbprint(map[string]string{"kty": "oct", "k": encodeSegment(secret)}),
+	}, nil
+}
+
+func (s *HS256Signer) Algorithm() string { return AlgorithmHS256 }
+func (s *HS256Signer) KeyID() string     { return s.kid }
+func (s *HS256Signer) PublicJWK() *JWK   { return nil }
+
+func (s *HS256Signer) Sign(signingInput []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(signingInput)
+
+	return mac.Sum(nil), nil
+}
+
+func (s *HS256Signer) Verify(signingInput, signature []byte) error {
+	expected, _ := s.Sign(signingInput)
+	if !hmac.Equal(expected, signature) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+type RS256Verifier struct {
+	key *rsa.PublicKey
+	kid string
+}
+
+func NewRS256Verifier(key *rsa.PublicKey) (*RS256Verifier, error) {
+	if key.N.BitLen() < minRSABits {
+		return nil, errors.New("RSA keys must be at least 2048 bits")
+	}
+
+	return &RS256Verifier{
+		key: key,
+		kid: thumbprint(map[string]string{
+			"kty": "RSA",
+			"n":   encodeSegment(key.N.Bytes()),
+			"e":   encodeSegment(big.NewInt(int64(key.E)).Bytes()),
+		}),
+	}, nil
+}
+
+func (v *RS256Verifier) Algorithm() string { return AlgorithmRS256 }
+func (v *RS256Verifier) KeyID() string     { return v.kid }
+
+func (v *RS256Verifier) Verify(signingInput, signature []byte) error {
+	digest := sha256.Sum256(signingInput)
+	if err := rsa.VerifyPKCS1v15(v.key, crypto.SHA256, digest[:], signature); err != nil {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func (v *RS256Verifier) PublicJWK() *JWK {
+	return &JWK{
+		KeyType:   "RSA",
+		Use:       "sig",
+		Algorithm: AlgorithmRS256,
+		KeyID:     v.kid,
+		N:         encodeSegment(v.key.N.Bytes()),
+		E:         encodeSegment(big.NewInt(int64(v.key.E)).Bytes()),
+	}
+}
+
+type RS256Signer struct {
+	*RS256Verifier
+	key *rsa.PrivateKey
+}
+
+func NewRS256Signer(key *rsa.PrivateKey) (*RS256Signer, error) {
+	verifier, err := NewRS256Verifier(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RS256Signer{
+		RS256Verifier: verifier,
+		key:           key,
+	}, nil
+}
+
+func (s *RS256Signer) Sign(signingInput []byte) ([]byte, error) {
+	digest := sha256.Sum256(signingInput)
+	return rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+}
+
+type EdDSAVerifier struct {
+	key ed25519.PublicKey
+	kid string
+}
+
+func NewEdDSAVerifier(key ed25519.PublicKey) (*EdDSAVerifier, error) {
+	if len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid Ed25519 public key")
+	}
+
+	return &EdDSAVerifier{
+		key: key,
+		kid: thumbprint(map[string]string{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"x":   encodeSegment(key),
+		}),
+	}, nil
+}
+
+func (v *EdDSAVerifier) Algorithm() string { return AlgorithmEdDSA }
+func (v *EdDSAVerifier) KeyID() string     { return v.kid }
+
+func (v *EdDSAVerifier) Verify(signingInput, signature []byte) error {
+	if !ed25519.Verify(v.key, signingInput, signature) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func (v *EdDSAVerifier) PublicJWK() *JWK {
+	return &JWK{
+		KeyType:   "OKP",
+		Use:       "sig",
+		Algorithm: AlgorithmEdDSA,
+		KeyID:     v.kid,
+		Curve:     "Ed25519",
+		X:         encodeSegment(v.key),
+	}
+}
+
+type EdDSASigner struct {
+	*EdDSAVerifier
+	key ed25519.PrivateKey
+}
+
+func NewEdDSASigner(key ed25519.PrivateKey) (*EdDSASigner, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid Ed25519 private key")
+	}
+
+	verifier, err := NewEdDSAVerifier(key.Public().(ed25519.PublicKey))
+	if err != nil {
+		return nil, err
+	}
+
+	return &EdDSASigner{
+		EdDSAVerifier: verifier,
+		key:           key,
+	}, nil
+}
+
+func (s *EdDSASigner) Sign(signingInput []byte) ([]byte, error) {
+	return ed25519.Sign(s.key, signingInput), nil
+}
+
+// thumbprint derives a key ID from the required JWK members of a key as
+// described in RFC 7638. encoding/json sorts map keys, which gives the
+// canonical member order the RFC asks for.
+func thumbprint(members map[string]string) string {
+	canonical, _ := json.Marshal(members)
+	digest := sha256.Sum256(canonical)
+
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
@@ -2,24 +2,76 @@ package token
 
 import (
 	"errors"
-	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/twinj/uuid"
 )
 
-type UserClaims struct {
-	UserID      int64
-	AccessUUID  string
-	RefreshUUID string
-	jwt.StandardClaims
+const (
+	Issuer               = "my-recipe-library"
+	AccessAudience       = "my-recipe-library:access"
+	RefreshAudience      = "my-recipe-library:refresh"
+	VerificationAudience = "my-recipe-library:verify-email"
+
+	accessLifetime       = 15 * time.Minute
+	refreshLifetime      = 7 * 24 * time.Hour
+	verificationLifetime = 24 * time.Hour
+)
+
+var (
+	ErrExpired          = errors.New("token is expired")
+	ErrNotYetValid      = errors.New("token is not valid yet")
+	ErrIssuedInFuture   = errors.New("token used before issued")
+	ErrInvalidAudience  = errors.New("token has an invalid audience")
+	ErrInvalidIssuer    = errors.New("token has an invalid issuer")
+	ErrMissingClaims    = errors.New("token is missing required claims")
+	ErrInvalidSignature = errors.New("signature is invalid")
+)
+
+type AccessClaims struct {
+	UserID     int64  `json:"user_id"`
+	AccessUUID string `json:"access_uuid"`
+	RegisteredClaims
 }
 
-func (u UserClaims) Valid() error {
-	return nil
+func (c AccessClaims) Valid() error {
+	if c.UserID == 0 || c.AccessUUID == "" {
+		return ErrMissingClaims
+	}
+
+	return validateRegisteredClaims(c.RegisteredClaims, AccessAudience, time.Now())
+}
+
+type RefreshClaims struct {
+	UserID      int64  `json:"user_id"`
+	RefreshUUID string `json:"refresh_uuid"`
+	RegisteredClaims
+}
+
+func (c RefreshClaims) Valid() error {
+	if c.UserID == 0 || c.RefreshUUID == "" {
+		return ErrMissingClaims
+	}
+
+	return validateRegisteredClaims(c.RegisteredClaims, RefreshAudience, time.Now())
+}
+
+// VerificationClaims prove that whoever holds the token can read mail sent to
+// Email.
+type VerificationClaims struct {
+	UserID int64  `json:"user_id"`
+	Email  string `json:"email"`
+	RegisteredClaims
+}
+
+func (c VerificationClaims) Valid() error {
+	if c.UserID == 0 || c.Email == "" {
+		return ErrMissingClaims
+	}
+
+	return validateRegisteredClaims(c.RegisteredClaims, VerificationAudience, time.Now())
 }
 
 type Details struct {
@@ -36,51 +88,67 @@ type AccessDetails struct {
 	UserId     int64
 }
 
+type RefreshDetails struct {
+	RefreshUuid string
+	UserId      int64
+}
+
+type VerificationDetails struct {
+	UserId int64
+	Email  string
+}
+
 type Service struct {
-	accessSecret  string
-	refreshSecret string
+	accessKeys  *KeySet
+	refreshKeys *KeySet
 }
 
-func NewService(accessSecret, refreshSecret string) *Service {
-	return &Service{
-		accessSecret:  accessSecret,
-		refreshSecret: refreshSecret,
+func NewService(accessKeys, refreshKeys *KeySet) (*Service, error) {
+	if accessKeys == nil || refreshKeys == nil {
+		return nil, errors.New("access and refresh keys are required")
+	}
+
+	for kid := range accessKeys.verifiers {
+		if _, ok := refreshKeys.verifiers[kid]; ok {
+			return nil, errors.New("access and refresh tokens must use different keys")
+		}
 	}
+
+	return &Service{
+		accessKeys:  accessKeys,
+		refreshKeys: refreshKeys,
+	}, nil
 }
 
 func (s *Service) CreateToken(userID int64) (*Details, error) {
 	var err error
-	details := &Details{}
-	details.AccessExpires = time.Now().Add(time.Minute * 15).Unix()
-	details.AccessUuid = uuid.NewV4().String()
+	now := time.Now()
 
-	details.RefreshExpires = time.Now().Add(time.Hour * 24 * 7).Unix()
-	details.RefreshUuid = uuid.NewV4().String()
+	details := &Details{
+		AccessUuid:     uuid.NewV4().String(),
+		AccessExpires:  now.Add(accessLifetime).Unix(),
+		RefreshUuid:    uuid.NewV4().String(),
+		RefreshExpires: now.Add(refreshLifetime).Unix(),
+	}
 
-	accessClaims := UserClaims{
-		UserID:     userID,
-		AccessUUID: details.AccessUuid,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: details.AccessExpires,
-		},
+	accessClaims := AccessClaims{
+		UserID:           userID,
+		AccessUUID:       details.AccessUuid,
+		RegisteredClaims: registeredClaims(AccessAudience, now, details.AccessExpires),
 	}
 
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	details.AccessToken, err = accessToken.SignedString([]byte(s.accessSecret))
+	details.AccessToken, err = s.accessKeys.Sign(accessClaims)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshClaims := UserClaims{
-		UserID:      userID,
-		RefreshUUID: details.RefreshToken,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: details.RefreshExpires,
-		},
+	refreshClaims := RefreshClaims{
+		UserID:           userID,
+		RefreshUUID:      details.RefreshUuid,
+		RegisteredClaims: registeredClaims(RefreshAudience, now, details.RefreshExpires),
 	}
 
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	details.RefreshToken, err = refreshToken.SignedString([]byte(s.accessSecret))
+	details.RefreshToken, err = s.refreshKeys.Sign(refreshClaims)
 	if err != nil {
 		return nil, err
 	}
@@ -94,25 +162,98 @@ func (s *Service) ValidateToken(r *http.Request) (*AccessDetails, error) {
 		return nil, errors.New("authorization header missing token")
 	}
 
-	token, err := jwt.ParseWithClaims(auth[1], &UserClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(s.accessSecret), nil
-	})
+	claims := &AccessClaims{}
+	err := s.accessKeys.Verify(auth[1], claims)
 	if err != nil {
 		return nil, err
 	}
 
-	var claims *UserClaims
-	if tokenClaims, ok := token.Claims.(*UserClaims); !ok || !token.Valid {
-		return nil, errors.New("invalid token")
-	} else {
-		claims = tokenClaims
-	}
-
 	return &AccessDetails{
 		AccessUuid: claims.AccessUUID,
 		UserId:     claims.UserID,
 	}, nil
 }
+
+func (s *Service) ValidateRefreshToken(refreshToken string) (*RefreshDetails, error) {
+	claims := &RefreshClaims{}
+	err := s.refreshKeys.Verify(refreshToken, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RefreshDetails{
+		RefreshUuid: claims.RefreshUUID,
+		UserId:      claims.UserID,
+	}, nil
+}
+
+// CreateVerificationToken signs a token for confirming an email address.
+// Like refresh tokens it is only verified by this service, so it is signed
+// with the refresh keys and told apart by its audience.
+func (s *Service) CreateVerificationToken(userID int64, email string) (string, error) {
+	now := time.Now()
+
+	return s.refreshKeys.Sign(VerificationClaims{
+		UserID:           userID,
+		Email:            email,
+		RegisteredClaims: registeredClaims(VerificationAudience, now, now.Add(verificationLifetime).Unix()),
+	})
+}
+
+func (s *Service) ValidateVerificationToken(verificationToken string) (*VerificationDetails, error) {
+	claims := &VerificationClaims{}
+	err := s.refreshKeys.Verify(verificationToken, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VerificationDetails{
+		UserId: claims.UserID,
+		Email:  claims.Email,
+	}, nil
+}
+
+// JWKS publishes the public access token keys so other services can verify
+// access tokens. Refresh tokens are only ever verified here.
+func (s *Service) JWKS() *JWKS {
+	return s.accessKeys.JWKS()
+}
+
+func registeredClaims(audience string, now time.Time, expires int64) RegisteredClaims {
+	return RegisteredClaims{
+		Audience:  audience,
+		Issuer:    Issuer,
+		IssuedAt:  now.Unix(),
+		NotBefore: now.Unix(),
+		ExpiresAt: expires,
+	}
+}
+
+func validateRegisteredClaims(claims RegisteredClaims, audience string, now time.Time) error {
+	if claims.ExpiresAt == 0 || claims.IssuedAt == 0 || claims.NotBefore == 0 {
+		return ErrMissingClaims
+	}
+
+	unix := now.Unix()
+	if unix >= claims.ExpiresAt {
+		return ErrExpired
+	}
+
+	if unix < claims.NotBefore {
+		return ErrNotYetValid
+	}
+
+	if unix < claims.IssuedAt {
+		return ErrIssuedInFuture
+	}
+
+	if claims.Audience != audience {
+		return ErrInvalidAudience
+	}
+
+	if claims.Issuer != Issuer {
+		return ErrInvalidIssuer
+	}
+
+	return nil
+}
@@ -1,7 +1,10 @@
 package token_test
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"net/http"
+	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -11,9 +14,120 @@ import (
 )
 
 var _ = Describe("token", func() {
+	const (
+		accessSecret  = "secret value"
+		refreshSecret = "refresh value"
+	)
+
+	var (
+		s           *token.Service
+		accessKeys  *token.KeySet
+		refreshKeys *token.KeySet
+	)
+
+	hmacKeys := func(secret string) *token.KeySet {
+		keys, err := token.LoadKeySet(token.AlgorithmHS256, secret, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		return keys
+	}
+
+	BeforeEach(func() {
+		accessKeys = hmacKeys(accessSecret)
+		refreshKeys = hmacKeys(refreshSecret)
+
+		var err error
+		s, err = token.NewService(accessKeys, refreshKeys)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	bearer := func(signed string) *http.Request {
+		req, err := http.NewRequest(http.MethodPost, "example.com", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		req.Header.Set("Authorization", "bearer "+signed)
+		return req
+	}
+
+	sign := func(claims interface{}, keys *token.KeySet) string {
+		signed, err := keys.Sign(claims)
+		Expect(err).ToNot(HaveOccurred())
+
+		return signed
+	}
+
+	decodeSegment := func(signed string, segment int, out interface{}) {
+		data, err := base64.RawURLEncoding.DecodeString(strings.Split(signed, ".")[segment])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(json.Unmarshal(data, out)).To(Succeed())
+	}
+
+	// withHeader re-encodes the header of a token, keeping the original
+	// payload and signature.
+	withHeader := func(signed string, modify func(map[string]string)) string {
+		header := map[string]string{}
+		decodeSegment(signed, 0, &header)
+		modify(header)
+
+		data, err := json.Marshal(header)
+		Expect(err).ToNot(HaveOccurred())
+
+		parts := strings.Split(signed, ".")
+		parts[0] = base64.RawURLEncoding.EncodeToString(data)
+
+		return strings.Join(parts, ".")
+	}
+
+	validRegisteredClaims := func(audience string) token.RegisteredClaims {
+		now := time.Now()
+		return token.RegisteredClaims{
+			Audience:  audience,
+			Issuer:    token.Issuer,
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			ExpiresAt: now.Add(time.Minute).Unix(),
+		}
+	}
+
+	accessClaims := func(modify func(*token.RegisteredClaims)) token.AccessClaims {
+		claims := token.AccessClaims{
+			UserID:           10,
+			AccessUUID:       "access-uuid",
+			RegisteredClaims: validRegisteredClaims(token.AccessAudience),
+		}
+		modify(&claims.RegisteredClaims)
+
+		return claims
+	}
+
+	refreshClaims := func(modify func(*token.RegisteredClaims)) token.RefreshClaims {
+		claims := token.RefreshClaims{
+			UserID:           10,
+			RefreshUUID:      "refresh-uuid",
+			RegisteredClaims: validRegisteredClaims(token.RefreshAudience),
+		}
+		modify(&claims.RegisteredClaims)
+
+		return claims
+	}
+
+	Context("NewService", func() {
+		It("requires both key sets", func() {
+			_, err := token.NewService(nil, refreshKeys)
+			Expect(err).To(MatchError("access and refresh keys are required"))
+
+			_, err = token.NewService(accessKeys, nil)
+			Expect(err).To(MatchError("access and refresh keys are required"))
+		})
+
+		It("does not allow the same key for access and refresh tokens", func() {
+			_, err := token.NewService(accessKeys, hmacKeys(accessSecret))
+			Expect(err).To(MatchError("access and refresh tokens must use different keys"))
+		})
+	})
+
 	Context("CreateToken", func() {
 		It("creates a token", func() {
-			s := token.NewService("secret value", "refresh value")
 			details, err := s.CreateToken(10)
 			Expect(err).ToNot(HaveOccurred())
 
@@ -26,22 +140,35 @@ var _ = Describe("token", func() {
 			Expect(details.AccessUuid).ToNot(Equal(""))
 			Expect(details.RefreshUuid).ToNot(Equal(""))
 		})
+
+		It("sets the audience, issuer and timestamps on each token", func() {
+			details, err := s.CreateToken(10)
+			Expect(err).ToNot(HaveOccurred())
+
+			access := &token.AccessClaims{}
+			decodeSegment(details.AccessToken, 1, access)
+			Expect(access.Audience).To(Equal(token.AccessAudience))
+			Expect(access.Issuer).To(Equal(token.Issuer))
+			Expect(access.IssuedAt).ToNot(BeZero())
+			Expect(access.NotBefore).ToNot(BeZero())
+			Expect(access.ExpiresAt).To(Equal(details.AccessExpires))
+
+			refresh := &token.RefreshClaims{}
+			decodeSegment(details.RefreshToken, 1, refresh)
+			Expect(refresh.Audience).To(Equal(token.RefreshAudience))
+			Expect(refresh.Issuer).To(Equal(token.Issuer))
+			Expect(refresh.RefreshUUID).To(Equal(details.RefreshUuid))
+			Expect(refresh.ExpiresAt).To(Equal(details.RefreshExpires))
+		})
 	})
 
 	Context("ValidateToken", func() {
 		It("returns user info if the token is valid", func() {
-			s := token.NewService("secret value", "refresh value")
-
 			userID := int64(10)
 			tokenDetails, err := s.CreateToken(userID)
 			Expect(err).ToNot(HaveOccurred())
 
-			req, err := http.NewRequest(http.MethodPost, "example.com", nil)
-			Expect(err).ToNot(HaveOccurred())
-
-			req.Header.Set("Authorization", "bearer "+tokenDetails.AccessToken)
-
-			accessDetails, err := s.ValidateToken(req)
+			accessDetails, err := s.ValidateToken(bearer(tokenDetails.AccessToken))
 			Expect(err).ToNot(HaveOccurred())
 			Expect(accessDetails).To(Equal(&token.AccessDetails{
 				AccessUuid: tokenDetails.AccessUuid,
@@ -49,34 +176,160 @@ var _ = Describe("token", func() {
 			}))
 		})
 
-		It("returns false if the token is invalid", func() {
-			// Create a token with one secret
-			s1 := token.NewService("secret value", "refresh value")
-			tokenDetails, err := s1.CreateToken(0)
+		It("returns an error if the token was signed with a different secret", func() {
+			tokenDetails, err := s.CreateToken(10)
 			Expect(err).ToNot(HaveOccurred())
 
-			req, err := http.NewRequest(http.MethodPost, "example.com", nil)
+			s2, err := token.NewService(hmacKeys("wrong value"), refreshKeys)
+			Expect(err).ToNot(HaveOccurred())
+
+			accessDetails, err := s2.ValidateToken(bearer(tokenDetails.AccessToken))
+			Expect(err).To(MatchError(token.ErrUnknownKey))
+			Expect(accessDetails).To(BeNil())
+		})
+
+		It("returns an error if the payload was modified", func() {
+			tokenDetails, err := s.CreateToken(10)
+			Expect(err).ToNot(HaveOccurred())
+
+			parts := strings.Split(tokenDetails.AccessToken, ".")
+			payload, err := base64.RawURLEncoding.DecodeString(parts[1])
 			Expect(err).ToNot(HaveOccurred())
 
-			req.Header.Set("Authorization", "bearer "+tokenDetails.AccessToken)
+			payload = []byte(strings.Replace(string(payload), `"user_id":10`, `"user_id":11`, 1))
+			parts[1] = base64.RawURLEncoding.EncodeToString(payload)
 
-			// Try to validate with a different secret
-			s2 := token.NewService("wrong value", "refresh value")
-			accessDetails, err := s2.ValidateToken(req)
-			Expect(err).To(HaveOccurred())
-			Expect(err).To(MatchError("signature is invalid"))
+			accessDetails, err := s.ValidateToken(bearer(strings.Join(parts, ".")))
+			Expect(err).To(MatchError(token.ErrInvalidSignature))
 			Expect(accessDetails).To(BeNil())
 		})
 
-		It("returns error if the token is malformed", func() {
-			req, err := http.NewRequest(http.MethodPost, "example.com", nil)
+		It("returns an error if the token is not signed", func() {
+			signed := sign(accessClaims(func(*token.RegisteredClaims) {}), accessKeys)
+			unsigned := withHeader(signed, func(header map[string]string) {
+				header["alg"] = "none"
+			})
+			unsigned = unsigned[:strings.LastIndex(unsigned, ".")+1]
+
+			accessDetails, err := s.ValidateToken(bearer(unsigned))
+			Expect(err).To(MatchError("unexpected signing method: none"))
+			Expect(accessDetails).To(BeNil())
+		})
+
+		It("returns an error if the token uses a different algorithm", func() {
+			signed := withHeader(sign(accessClaims(func(*token.RegisteredClaims) {}), accessKeys), func(header map[string]string) {
+				header["alg"] = "HS512"
+			})
+
+			accessDetails, err := s.ValidateToken(bearer(signed))
+			Expect(err).To(MatchError("unexpected signing method: HS512"))
+			Expect(accessDetails).To(BeNil())
+		})
+
+		It("returns an error if the key id is unknown", func() {
+			signed := withHeader(sign(accessClaims(func(*token.RegisteredClaims) {}), accessKeys), func(header map[string]string) {
+				header["kid"] = "some-other-key"
+			})
+
+			accessDetails, err := s.ValidateToken(bearer(signed))
+			Expect(err).To(MatchError(token.ErrUnknownKey))
+			Expect(accessDetails).To(BeNil())
+		})
+
+		It("returns an error if the token has expired", func() {
+			signed := sign(accessClaims(func(c *token.RegisteredClaims) {
+				c.IssuedAt = time.Now().Add(-time.Hour).Unix()
+				c.NotBefore = c.IssuedAt
+				c.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+			}), accessKeys)
+
+			accessDetails, err := s.ValidateToken(bearer(signed))
+			Expect(err).To(MatchError(token.ErrExpired))
+			Expect(accessDetails).To(BeNil())
+		})
+
+		It("returns an error if the token is not valid yet", func() {
+			signed := sign(accessClaims(func(c *token.RegisteredClaims) {
+				c.NotBefore = time.Now().Add(time.Minute).Unix()
+			}), accessKeys)
+
+			accessDetails, err := s.ValidateToken(bearer(signed))
+			Expect(err).To(MatchError(token.ErrNotYetValid))
+			Expect(accessDetails).To(BeNil())
+		})
+
+		It("returns an error if the token was issued in the future", func() {
+			signed := sign(accessClaims(func(c *token.RegisteredClaims) {
+				c.IssuedAt = time.Now().Add(time.Minute).Unix()
+			}), accessKeys)
+
+			accessDetails, err := s.ValidateToken(bearer(signed))
+			Expect(err).To(MatchError(token.ErrIssuedInFuture))
+			Expect(accessDetails).To(BeNil())
+		})
+
+		It("returns an error if a timestamp is missing", func() {
+			for _, modify := range []func(*token.RegisteredClaims){
+				func(c *token.RegisteredClaims) { c.ExpiresAt = 0 },
+				func(c *token.RegisteredClaims) { c.IssuedAt = 0 },
+				func(c *token.RegisteredClaims) { c.NotBefore = 0 },
+			} {
+				signed := sign(accessClaims(modify), accessKeys)
+
+				accessDetails, err := s.ValidateToken(bearer(signed))
+				Expect(err).To(MatchError(token.ErrMissingClaims))
+				Expect(accessDetails).To(BeNil())
+			}
+		})
+
+		It("returns an error if the user or access uuid is missing", func() {
+			claims := accessClaims(func(*token.RegisteredClaims) {})
+			claims.AccessUUID = ""
+
+			accessDetails, err := s.ValidateToken(bearer(sign(claims, accessKeys)))
+			Expect(err).To(MatchError(token.ErrMissingClaims))
+			Expect(accessDetails).To(BeNil())
+
+			claims = accessClaims(func(*token.RegisteredClaims) {})
+			claims.UserID = 0
+
+			accessDetails, err = s.ValidateToken(bearer(sign(claims, accessKeys)))
+			Expect(err).To(MatchError(token.ErrMissingClaims))
+			Expect(accessDetails).To(BeNil())
+		})
+
+		It("returns an error if the audience is wrong", func() {
+			signed := sign(accessClaims(func(c *token.RegisteredClaims) {
+				c.Audience = token.RefreshAudience
+			}), accessKeys)
+
+			accessDetails, err := s.ValidateToken(bearer(signed))
+			Expect(err).To(MatchError(token.ErrInvalidAudience))
+			Expect(accessDetails).To(BeNil())
+		})
+
+		It("returns an error if the issuer is wrong", func() {
+			signed := sign(accessClaims(func(c *token.RegisteredClaims) {
+				c.Issuer = "someone-else"
+			}), accessKeys)
+
+			accessDetails, err := s.ValidateToken(bearer(signed))
+			Expect(err).To(MatchError(token.ErrInvalidIssuer))
+			Expect(accessDetails).To(BeNil())
+		})
+
+		It("does not accept a refresh token as an access token", func() {
+			tokenDetails, err := s.CreateToken(10)
 			Expect(err).ToNot(HaveOccurred())
 
-			req.Header.Set("Authorization", "bearer invalid.token.here")
+			accessDetails, err := s.ValidateToken(bearer(tokenDetails.RefreshToken))
+			Expect(err).To(MatchError(token.ErrUnknownKey))
+			Expect(accessDetails).To(BeNil())
+		})
 
-			s := token.NewService("secret value", "refresh value")
-			accessDetails, err := s.ValidateToken(req)
-			Expect(err).To(HaveOccurred())
+		It("returns error if the token is malformed", func() {
+			accessDetails, err := s.ValidateToken(bearer("invalid.token.here"))
+			Expect(err).To(MatchError(token.ErrMalformed))
 			Expect(accessDetails).To(BeNil())
 		})
 
@@ -84,11 +337,149 @@ var _ = Describe("token", func() {
 			req, err := http.NewRequest(http.MethodPost, "example.com", nil)
 			Expect(err).ToNot(HaveOccurred())
 
-			s := token.NewService("secret value", "refresh value")
 			accessDetails, err := s.ValidateToken(req)
-			Expect(err).To(HaveOccurred())
 			Expect(err).To(MatchError("authorization header missing token"))
 			Expect(accessDetails).To(BeNil())
 		})
 	})
+
+	Context("ValidateRefreshToken", func() {
+		It("returns the refresh details if the token is valid", func() {
+			tokenDetails, err := s.CreateToken(10)
+			Expect(err).ToNot(HaveOccurred())
+
+			refreshDetails, err := s.ValidateRefreshToken(tokenDetails.RefreshToken)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(refreshDetails).To(Equal(&token.RefreshDetails{
+				RefreshUuid: tokenDetails.RefreshUuid,
+				UserId:      10,
+			}))
+		})
+
+		It("returns an error if the token was signed with a different refresh secret", func() {
+			tokenDetails, err := s.CreateToken(10)
+			Expect(err).ToNot(HaveOccurred())
+
+			s2, err := token.NewService(accessKeys, hmacKeys("wrong value"))
+			Expect(err).ToNot(HaveOccurred())
+
+			refreshDetails, err := s2.ValidateRefreshToken(tokenDetails.RefreshToken)
+			Expect(err).To(MatchError(token.ErrUnknownKey))
+			Expect(refreshDetails).To(BeNil())
+		})
+
+		It("does not accept an access token as a refresh token", func() {
+			tokenDetails, err := s.CreateToken(10)
+			Expect(err).ToNot(HaveOccurred())
+
+			refreshDetails, err := s.ValidateRefreshToken(tokenDetails.AccessToken)
+			Expect(err).To(MatchError(token.ErrUnknownKey))
+			Expect(refreshDetails).To(BeNil())
+		})
+
+		It("returns an error if the audience is wrong even with the refresh secret", func() {
+			signed := sign(refreshClaims(func(c *token.RegisteredClaims) {
+				c.Audience = token.AccessAudience
+			}), refreshKeys)
+
+			refreshDetails, err := s.ValidateRefreshToken(signed)
+			Expect(err).To(MatchError(token.ErrInvalidAudience))
+			Expect(refreshDetails).To(BeNil())
+		})
+
+		It("returns an error if the token has expired", func() {
+			signed := sign(refreshClaims(func(c *token.RegisteredClaims) {
+				c.IssuedAt = time.Now().Add(-time.Hour).Unix()
+				c.NotBefore = c.IssuedAt
+				c.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+			}), refreshKeys)
+
+			refreshDetails, err := s.ValidateRefreshToken(signed)
+			Expect(err).To(MatchError(token.ErrExpired))
+			Expect(refreshDetails).To(BeNil())
+		})
+
+		It("returns an error if the token is not valid yet", func() {
+			signed := sign(refreshClaims(func(c *token.RegisteredClaims) {
+				c.NotBefore = time.Now().Add(time.Minute).Unix()
+			}), refreshKeys)
+
+			refreshDetails, err := s.ValidateRefreshToken(signed)
+			Expect(err).To(MatchError(token.ErrNotYetValid))
+			Expect(refreshDetails).To(BeNil())
+		})
+
+		It("returns an error if the refresh uuid is missing", func() {
+			claims := refreshClaims(func(*token.RegisteredClaims) {})
+			claims.RefreshUUID = ""
+
+			refreshDetails, err := s.ValidateRefreshToken(sign(claims, refreshKeys))
+			Expect(err).To(MatchError(token.ErrMissingClaims))
+			Expect(refreshDetails).To(BeNil())
+		})
+	})
+
+	Context("ValidateVerificationToken", func() {
+		It("returns the user and email if the token is valid", func() {
+			signed, err := s.CreateVerificationToken(10, "cook@example.com")
+			Expect(err).ToNot(HaveOccurred())
+
+			claims := map[string]interface{}{}
+			decodeSegment(signed, 1, &claims)
+			Expect(claims["aud"]).To(Equal(token.VerificationAudience))
+			Expect(claims["exp"]).To(BeNumerically("~", time.Now().Add(24*time.Hour).Unix(), 5))
+
+			details, err := s.ValidateVerificationToken(signed)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(details).To(Equal(&token.VerificationDetails{
+				UserId: 10,
+				Email:  "cook@example.com",
+			}))
+		})
+
+		It("does not accept a refresh token as a verification token", func() {
+			tokenDetails, err := s.CreateToken(10)
+			Expect(err).ToNot(HaveOccurred())
+
+			details, err := s.ValidateVerificationToken(tokenDetails.RefreshToken)
+			Expect(err).To(MatchError(token.ErrMissingClaims))
+			Expect(details).To(BeNil())
+		})
+
+		It("does not accept a verification token as a refresh token", func() {
+			signed, err := s.CreateVerificationToken(10, "cook@example.com")
+			Expect(err).ToNot(HaveOccurred())
+
+			refreshDetails, err := s.ValidateRefreshToken(signed)
+			Expect(err).To(MatchError(token.ErrMissingClaims))
+			Expect(refreshDetails).To(BeNil())
+		})
+
+		It("returns an error if the audience is wrong", func() {
+			signed := sign(token.VerificationClaims{
+				UserID:           10,
+				Email:            "cook@example.com",
+				RegisteredClaims: validRegisteredClaims(token.RefreshAudience),
+			}, refreshKeys)
+
+			details, err := s.ValidateVerificationToken(signed)
+			Expect(err).To(MatchError(token.ErrInvalidAudience))
+			Expect(details).To(BeNil())
+		})
+
+		It("returns an error if the token has expired", func() {
+			claims := token.VerificationClaims{
+				UserID:           10,
+				Email:            "cook@example.com",
+				RegisteredClaims: validRegisteredClaims(token.VerificationAudience),
+			}
+			claims.IssuedAt = time.Now().Add(-48 * time.Hour).Unix()
+			claims.NotBefore = claims.IssuedAt
+			claims.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+
+			details, err := s.ValidateVerificationToken(sign(claims, refreshKeys))
+			Expect(err).To(MatchError(token.ErrExpired))
+			Expect(details).To(BeNil())
+		})
+	})
 })
@@ -0,0 +1,127 @@
+package totp_test
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/iplay88keys/my-recipe-library/pkg/totp"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TOTP", func() {
+	// The SHA1 secret from the RFC 6238 test vectors
+	secret := []byte("12345678901234567890")
+
+	DescribeTable("generates the RFC 6238 codes",
+		func(unix int64, expected string) {
+			Expect(totp.Code(secret, time.Unix(unix, 0))).To(Equal(expected))
+		},
+		Entry("59", int64(59), "287082"),
+		Entry("1111111109", int64(1111111109), "081804"),
+		Entry("1111111111", int64(1111111111), "050471"),
+		Entry("1234567890", int64(1234567890), "005924"),
+		Entry("2000000000", int64(2000000000), "279037"),
+		Entry("20000000000", int64(20000000000), "353130"),
+	)
+
+	Describe("Validate", func() {
+		now := time.Unix(1234567890, 0)
+
+		It("accepts the current code and returns its step", func() {
+			step, ok := totp.Validate(secret, "005924", now)
+			Expect(ok).To(BeTrue())
+			Expect(step).To(Equal(totp.Step(now)))
+		})
+
+		It("accepts codes from the neighbouring steps", func() {
+			previous := totp.Code(secret, now.Add(-totp.Period))
+			step, ok := totp.Validate(secret, previous, now)
+			Expect(ok).To(BeTrue())
+			Expect(step).To(Equal(totp.Step(now) - 1))
+
+			next := totp.Code(secret, now.Add(totp.Period))
+			_, ok = totp.Validate(secret, next, now)
+			Expect(ok).To(BeTrue())
+		})
+
+		It("rejects codes from further away", func() {
+			old := totp.Code(secret, now.Add(-2*totp.Period))
+			_, ok := totp.Validate(secret, old, now)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("rejects codes of the wrong length", func() {
+			_, ok := totp.Validate(secret, "05924", now)
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	It("builds an otpauth URI", func() {
+		uri, err := url.Parse(totp.URI("My Recipe Library", "cook", secret))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(uri.Scheme).To(Equal("otpauth"))
+		Expect(uri.Host).To(Equal("totp"))
+		Expect(uri.Path).To(Equal("/My Recipe Library:cook"))
+		Expect(uri.Query().Get("secret")).To(Equal("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"))
+		Expect(uri.Query().Get("issuer")).To(Equal("My Recipe Library"))
+		Expect(uri.Query().Get("digits")).To(Equal("6"))
+		Expect(uri.Query().Get("period")).To(Equal("30"))
+	})
+
+	It("generates random secrets", func() {
+		first, err := totp.GenerateSecret()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(first).To(HaveLen(20))
+
+		second, err := totp.GenerateSecret()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(second).ToNot(Equal(first))
+	})
+})
+
+var _ = Describe("Cipher", func() {
+	var c *totp.Cipher
+
+	BeforeEach(func() {
+		var err error
+		c, err = totp.NewCipher([]byte("0123456789abcdef0123456789abcdef"))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("decrypts what it encrypted", func() {
+		encrypted, err := c.Encrypt([]byte("secret"), []byte("10"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(encrypted).ToNot(ContainSubstring("secret"))
+
+		decrypted, err := c.Decrypt(encrypted, []byte("10"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decrypted).To(Equal([]byte("secret")))
+	})
+
+	It("does not decrypt with different associated data", func() {
+		encrypted, err := c.Encrypt([]byte("secret"), []byte("10"))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = c.Decrypt(encrypted, []byte("20"))
+		Expect(err).To(MatchError(totp.ErrDecrypt))
+	})
+
+	It("does not decrypt with a different key", func() {
+		encrypted, err := c.Encrypt([]byte("secret"), []byte("10"))
+		Expect(err).ToNot(HaveOccurred())
+
+		other, err := totp.NewCipher([]byte("fedcba9876543210fedcba9876543210"))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = other.Decrypt(encrypted, []byte("10"))
+		Expect(err).To(MatchError(totp.ErrDecrypt))
+	})
+
+	It("requires a 32 byte key", func() {
+		_, err := totp.NewCipher([]byte("short"))
+		Expect(err).To(MatchError("the encryption key must be 32 bytes"))
+	})
+})
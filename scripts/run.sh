@@ -73,6 +73,7 @@ export MYSQL_CREDS="{\"url\": \"${mysql_url}\"}"
 export REDIS_URL="redis://:@127.0.0.1:6379"
 export ACCESS_SECRET="access_secret"
 export REFRESH_SECRET="refresh_secret"
+export TOTP_ENCRYPTION_KEY="ZGV2ZWxvcG1lbnQtb25seS10b3RwLWtleS0zMmJ5dGU="
 
 echo "----------------------------------------------------"
 echo "MySQL url:"
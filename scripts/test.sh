@@ -67,6 +67,7 @@ echo "Setting required env vars"
 export REDIS_URL="redis://:@127.0.0.1:6379"
 export ACCESS_SECRET="access_secret"
 export REFRESH_SECRET="refresh_secret"
+export TOTP_ENCRYPTION_KEY="ZGV2ZWxvcG1lbnQtb25seS10b3RwLWtleS0zMmJ5dGU="
 
 if [[ "${skipBackend}" = "false" ]]; then
     echo "Running ginkgo for everything except integration"